@@ -0,0 +1,536 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/filename"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tagging"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+// archiveManifestName is the file listing an archived video's files and their
+// checksums. It is written last, so a folder without it is incomplete.
+const archiveManifestName = "manifest.json"
+
+// Kinds of the files in an archive manifest.
+const (
+	artifactMedia     = "media"
+	artifactSubtitles = "subtitles"
+	artifactThumbnail = "thumbnail"
+	artifactInfo      = "info"
+)
+
+// archiveOptions holds the flags of the archive command.
+type archiveOptions struct {
+	output    string
+	quality   string
+	format    string
+	subLangs  []string
+	autoSubs  bool
+	subFormat string
+}
+
+// archiveManifest is the manifest.json of an archived video.
+type archiveManifest struct {
+	VideoID    string         `json:"video_id"`
+	Title      string         `json:"title"`
+	URL        string         `json:"url"`
+	ArchivedAt time.Time      `json:"archived_at"`
+	Generator  string         `json:"generator"`
+	Files      []archivedFile `json:"files"`
+}
+
+// archivedFile is a file of an archived video with its checksum.
+type archivedFile struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// archiveInfo is the info JSON of an archived video, its metadata as YouTube
+// reported it when it was archived.
+type archiveInfo struct {
+	ID              string             `json:"id"`
+	Title           string             `json:"title"`
+	Author          string             `json:"author"`
+	ChannelID       string             `json:"channel_id,omitempty"`
+	ChannelURL      string             `json:"channel_url,omitempty"`
+	Description     string             `json:"description,omitempty"`
+	DurationSeconds int                `json:"duration_seconds"`
+	ViewCount       int64              `json:"view_count"`
+	LikeCount       int64              `json:"like_count,omitempty"`
+	UploadDate      string             `json:"upload_date,omitempty"`
+	PublishDate     string             `json:"publish_date,omitempty"`
+	Category        string             `json:"category,omitempty"`
+	Keywords        []string           `json:"keywords,omitempty"`
+	IsLive          bool               `json:"is_live,omitempty"`
+	IsFamilySafe    bool               `json:"is_family_safe"`
+	Thumbnails      []archiveThumbnail `json:"thumbnails,omitempty"`
+	Captions        []archiveCaption   `json:"captions,omitempty"`
+	Formats         []int              `json:"formats"`
+}
+
+// archiveThumbnail is a thumbnail listed in the info JSON.
+type archiveThumbnail struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// archiveCaption is a subtitle track listed in the info JSON.
+type archiveCaption struct {
+	Language      string `json:"language"`
+	Name          string `json:"name,omitempty"`
+	AutoGenerated bool   `json:"auto_generated,omitempty"`
+}
+
+func newArchiveCmd() *cobra.Command {
+	opts := &archiveOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "archive <url>...",
+		Short: "Archive videos with their metadata and checksums",
+		Long: `Archive videos for long-term preservation. Each video is saved to a folder of
+its own, named after its title and ID, holding:
+
+  - the video in the best quality available
+  - its subtitles
+  - its thumbnail
+  - an info JSON with its metadata as YouTube reports it
+  - manifest.json, listing every file with its size and SHA-256 checksum
+
+The URLs can be videos or playlists; each video of a playlist gets its own
+folder. The manifest is written last, so a folder without one is incomplete.
+Videos whose folder already has a manifest are skipped, so an interrupted
+archive can be run again to finish it.
+
+All manually created subtitles are saved by default. --sub-lang limits them to
+some languages and --auto-subs adds YouTube's auto-generated subtitles for
+languages without manual ones.`,
+		Example: `  ytdl archive https://www.youtube.com/watch?v=dQw4w9WgXcQ
+  ytdl archive -o /mnt/archive https://www.youtube.com/playlist?list=PL...
+  ytdl archive --sub-lang en,de --auto-subs --sub-format srt dQw4w9WgXcQ`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := runArchive(cmd, args, opts); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", ".", "Directory the video folders are created in")
+	cmd.Flags().StringVarP(&opts.quality, "quality", "q", "best", "Video quality (best, 1080p, 720p, 480p, 360p)")
+	cmd.Flags().StringVarP(&opts.format, "format", "f", "mkv", "Output format (mp4, webm, mkv)")
+	cmd.Flags().StringSliceVar(&opts.subLangs, "sub-lang", nil, "Subtitle languages to save, such as en,de (default all)")
+	cmd.Flags().BoolVar(&opts.autoSubs, "auto-subs", false, "Also save auto-generated subtitles for languages without manual ones")
+	cmd.Flags().StringVar(&opts.subFormat, "sub-format", "vtt", "Subtitle format (vtt, srt)")
+
+	return cmd
+}
+
+// runArchive archives the videos of the URLs one after another.
+func runArchive(cmd *cobra.Command, urls []string, opts *archiveOptions) error {
+	if opts.subFormat != "vtt" && opts.subFormat != "srt" {
+		return fmt.Errorf("invalid --sub-format %q: use vtt or srt", opts.subFormat)
+	}
+	if opts.output == stdoutOutput {
+		return errors.New("archive cannot be written to --output -")
+	}
+
+	client, err := newHTTPClient(cmd)
+	if err != nil {
+		return err
+	}
+	poTokens, err := newPOTokenProvider(cmd, client)
+	if err != nil {
+		return err
+	}
+	timeouts, err := clientTimeouts(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	a := &archiver{
+		w:          statusWriter(cmd),
+		client:     ytdl.NewClient(ytdl.WithHTTPClient(client), ytdl.WithPOTokenProvider(poTokens, flagValue(cmd, "visitor-data")), timeouts),
+		httpClient: client,
+		opts:       opts,
+		now:        time.Now,
+	}
+	return a.archiveAll(ctx, urls)
+}
+
+// archiver saves videos with their subtitles, thumbnail and metadata into folders
+// with a checksum manifest.
+type archiver struct {
+	w          io.Writer
+	client     *ytdl.Client
+	httpClient *http.Client
+	opts       *archiveOptions
+
+	// now returns the time recorded in manifests, replaced in tests.
+	now func() time.Time
+}
+
+// archiveAll archives the videos of every URL. Failed videos are reported and
+// returned as a *PartialDownloadError once the others are archived.
+func (a *archiver) archiveAll(ctx context.Context, urls []string) error {
+	var videoIDs []string
+	for _, url := range urls {
+		ids, err := a.listVideos(ctx, url)
+		if err != nil {
+			return err
+		}
+		videoIDs = append(videoIDs, ids...)
+	}
+
+	failed := 0
+	for _, id := range videoIDs {
+		if err := a.archiveVideo(ctx, id); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if len(videoIDs) == 1 {
+				return err
+			}
+			_, _ = fmt.Fprintf(a.w, "  Failed to archive %s: %v\n", id, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return &PartialDownloadError{Failed: failed, Total: len(videoIDs)}
+	}
+	return nil
+}
+
+// listVideos returns the IDs of the videos a URL points to: the video itself, or
+// the videos of a playlist.
+func (a *archiver) listVideos(ctx context.Context, url string) ([]string, error) {
+	query, err := youtube.ResolveQueryContext(ctx, url, youtube.NewURLExpander(a.httpClient))
+	if err != nil {
+		return nil, err
+	}
+	switch query.Type {
+	case youtube.QueryTypeVideo:
+		return []string{query.VideoID.String()}, nil
+	case youtube.QueryTypePlaylist:
+		playlist, err := a.client.GetPlaylist(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, len(playlist.Videos))
+		for i := range playlist.Videos {
+			ids[i] = playlist.Videos[i].ID
+		}
+		return ids, nil
+	default:
+		return nil, fmt.Errorf("%s is a %s, not a video or playlist", url, query.Type)
+	}
+}
+
+// archiveVideo saves a video and its artifacts to its folder and writes the
+// manifest. Subtitles and the thumbnail are optional: if they can't be saved a
+// warning is printed and the archive goes on without them.
+func (a *archiver) archiveVideo(ctx context.Context, videoID string) error {
+	video, err := a.client.GetVideo(ctx, videoID)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(a.opts.output, filename.SanitizeFilename(video.Title)+" ["+video.ID+"]")
+	if _, err := os.Stat(filepath.Join(dir, archiveManifestName)); err == nil {
+		_, _ = fmt.Fprintf(a.w, "Already archived: %s\n", dir)
+		return nil
+	}
+	_, _ = fmt.Fprintf(a.w, "Archiving %s\n", video.Title)
+
+	// The subtitles and thumbnail are small, so they're fetched while the video
+	// downloads and written next to it once its file name is known
+	extrasCtx, cancelExtras := context.WithCancel(ctx)
+	defer cancelExtras()
+	extras := a.fetchExtras(extrasCtx, video)
+
+	result, err := a.client.DownloadVideo(ctx, video,
+		ytdl.WithOutputDir(dir),
+		ytdl.WithQuality(parseQualityPreference(a.opts.quality)),
+		ytdl.WithContainer(parseContainer(a.opts.format)),
+	)
+	if err != nil {
+		cancelExtras()
+		extras.wg.Wait()
+		return err
+	}
+	files := []archivedFile{{Name: filepath.Base(result.FilePath), Kind: artifactMedia}}
+	base := strings.TrimSuffix(files[0].Name, filepath.Ext(files[0].Name))
+
+	extras.wg.Wait()
+	for _, subs := range extras.subtitles {
+		name := base + "." + filename.SanitizeFilename(subs.track.LanguageCode) + "." + a.opts.subFormat
+		if subs.err == nil {
+			subs.err = writeArchiveFile(filepath.Join(dir, name), []byte(subs.content))
+		}
+		if subs.err != nil {
+			_, _ = fmt.Fprintf(a.w, "  Warning: failed to save %s subtitles: %v\n", subs.track.LanguageCode, subs.err)
+			continue
+		}
+		files = append(files, archivedFile{Name: name, Kind: artifactSubtitles})
+	}
+
+	name := base + extras.thumbnailExt
+	if extras.thumbnailErr == nil {
+		extras.thumbnailErr = writeArchiveFile(filepath.Join(dir, name), extras.thumbnail)
+	}
+	if extras.thumbnailErr != nil {
+		_, _ = fmt.Fprintf(a.w, "  Warning: failed to save thumbnail: %v\n", extras.thumbnailErr)
+	} else {
+		files = append(files, archivedFile{Name: name, Kind: artifactThumbnail})
+	}
+
+	infoName := base + ".info.json"
+	if err := writeJSONFile(filepath.Join(dir, infoName), newArchiveInfo(video, result.Selection)); err != nil {
+		return err
+	}
+	files = append(files, archivedFile{Name: infoName, Kind: artifactInfo})
+
+	for i := range files {
+		if files[i].Size, files[i].SHA256, err = hashFile(filepath.Join(dir, files[i].Name)); err != nil {
+			return err
+		}
+	}
+	manifest := &archiveManifest{
+		VideoID:    video.ID,
+		Title:      video.Title,
+		URL:        "https://www.youtube.com/watch?v=" + video.ID,
+		ArchivedAt: a.now().UTC(),
+		Generator:  "ytdl " + version,
+		Files:      files,
+	}
+	if err := writeJSONFile(filepath.Join(dir, archiveManifestName), manifest); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(a.w, "Archived: %s (%d files)\n", dir, len(files)+1)
+	return nil
+}
+
+// subtitleTracks returns the caption tracks to save: the manual ones in the
+// wanted languages and, with --auto-subs, auto-generated ones for the wanted
+// languages that have no manual track.
+func (a *archiver) subtitleTracks(captions *youtube.CaptionManifest) []youtube.CaptionTrack {
+	if captions == nil {
+		return nil
+	}
+	wanted := func(lang string) bool {
+		if len(a.opts.subLangs) == 0 {
+			return true
+		}
+		for _, l := range a.opts.subLangs {
+			if strings.EqualFold(l, lang) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var tracks []youtube.CaptionTrack
+	manual := make(map[string]bool)
+	for _, track := range captions.GetManualTracks() {
+		if wanted(track.LanguageCode) && !manual[track.LanguageCode] {
+			manual[track.LanguageCode] = true
+			tracks = append(tracks, track)
+		}
+	}
+	if a.opts.autoSubs {
+		for _, track := range captions.GetAutoGeneratedTracks() {
+			if wanted(track.LanguageCode) && !manual[track.LanguageCode] {
+				manual[track.LanguageCode] = true
+				tracks = append(tracks, track)
+			}
+		}
+	}
+	return tracks
+}
+
+// archiveExtras are the subtitles and thumbnail of a video, fetched in the
+// background. They are set once wg is done.
+type archiveExtras struct {
+	wg sync.WaitGroup
+
+	subtitles []fetchedSubtitles
+
+	thumbnail    []byte
+	thumbnailExt string
+	thumbnailErr error
+}
+
+// fetchedSubtitles is a caption track fetched in the format of --sub-format.
+type fetchedSubtitles struct {
+	track   youtube.CaptionTrack
+	content string
+	err     error
+}
+
+// fetchExtras starts fetching the subtitles and the thumbnail of a video.
+func (a *archiver) fetchExtras(ctx context.Context, video *ytdl.Video) *archiveExtras {
+	extras := &archiveExtras{}
+	tracks := a.subtitleTracks(video.Captions)
+	extras.subtitles = make([]fetchedSubtitles, len(tracks))
+	for i, track := range tracks {
+		extras.wg.Add(1)
+		go func() {
+			defer extras.wg.Done()
+			content, err := a.fetchSubtitles(ctx, &track)
+			extras.subtitles[i] = fetchedSubtitles{track: track, content: content, err: err}
+		}()
+	}
+	extras.wg.Add(1)
+	go func() {
+		defer extras.wg.Done()
+		extras.thumbnail, extras.thumbnailExt, extras.thumbnailErr = a.fetchThumbnail(ctx, video)
+	}()
+	return extras
+}
+
+// fetchSubtitles downloads a caption track in the format of --sub-format.
+func (a *archiver) fetchSubtitles(ctx context.Context, track *youtube.CaptionTrack) (string, error) {
+	downloader := youtube.NewCaptionDownloader(a.httpClient)
+	if a.opts.subFormat == "srt" {
+		return downloader.DownloadAsSRT(ctx, track)
+	}
+	return downloader.DownloadAsVTT(ctx, track)
+}
+
+// fetchThumbnail downloads the video's best thumbnail and returns it with the
+// extension of its URL.
+func (a *archiver) fetchThumbnail(ctx context.Context, video *ytdl.Video) ([]byte, string, error) {
+	thumbnailURL := tagging.GetThumbnailURL(video.ID, video.Thumbnails)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, thumbnailURL, http.NoBody)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ext := path.Ext(req.URL.Path)
+	if ext == "" {
+		ext = ".jpg"
+	}
+	return data, ext, nil
+}
+
+// writeArchiveFile writes a subtitles or thumbnail file.
+func writeArchiveFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// newArchiveInfo returns the info JSON of a video archived with selection.
+func newArchiveInfo(video *ytdl.Video, selection *ytdl.Selection) *archiveInfo {
+	info := &archiveInfo{
+		ID:              video.ID,
+		Title:           video.Title,
+		Author:          video.Author.Name,
+		ChannelID:       video.Author.ChannelID,
+		ChannelURL:      video.Author.URL,
+		Description:     video.Description,
+		DurationSeconds: int(video.Duration.Seconds()),
+		ViewCount:       video.ViewCount,
+		LikeCount:       video.LikeCount,
+		Category:        video.Category,
+		Keywords:        video.Keywords,
+		IsLive:          video.IsLive,
+		IsFamilySafe:    video.IsFamilySafe,
+		Formats:         []int{},
+	}
+	if !video.UploadDate.IsZero() {
+		info.UploadDate = video.UploadDate.Format(time.RFC3339)
+	}
+	if !video.PublishDate.IsZero() {
+		info.PublishDate = video.PublishDate.Format(time.RFC3339)
+	}
+	for _, t := range video.Thumbnails {
+		info.Thumbnails = append(info.Thumbnails, archiveThumbnail{URL: t.URL, Width: t.Width, Height: t.Height})
+	}
+	if video.Captions != nil {
+		for _, track := range video.Captions.Tracks {
+			info.Captions = append(info.Captions, archiveCaption{
+				Language:      track.LanguageCode,
+				Name:          track.LanguageName,
+				AutoGenerated: track.IsAutoGenerated,
+			})
+		}
+	}
+	if selection.Video != nil {
+		info.Formats = append(info.Formats, selection.Video.Itag)
+	}
+	if selection.Audio != nil {
+		info.Formats = append(info.Formats, selection.Audio.Itag)
+	}
+	return info
+}
+
+// writeJSONFile writes v as indented JSON to a temporary file and renames it to
+// path, so the file is never left half written.
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// hashFile returns the size and hex SHA-256 checksum of a file.
+func hashFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to hash %s: %w", filepath.Base(path), err)
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+// newArchiveTestServer serves the playlist of servePlaylistCmdTestPage and
+// watch pages whose videos have a muxed stream, English and auto-generated
+// German subtitles and a thumbnail. The watch page of jNQXAC9IVRw fails.
+func newArchiveTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/playlist":
+			servePlaylistCmdTestPage(w)
+		case r.URL.Path == "/watch":
+			id := r.URL.Query().Get("v")
+			if id == "jNQXAC9IVRw" {
+				_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"playabilityStatus":{"status":"ERROR","reason":"Video unavailable"}};</script>`))
+				return
+			}
+			_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"` + id + `","title":"Video ` + id +
+				`","author":"Test Channel","lengthSeconds":"60","thumbnail":{"thumbnails":[{"url":"` + server.URL + `/vi/` + id + `/maxresdefault.jpg","width":1280,"height":720}]}},` +
+				`"captions":{"playerCaptionsTracklistRenderer":{"captionTracks":[` +
+				`{"baseUrl":"` + server.URL + `/captions/en","languageCode":"en","name":{"simpleText":"English"}},` +
+				`{"baseUrl":"` + server.URL + `/captions/de","languageCode":"de","kind":"asr","name":{"simpleText":"German"}}]}},` +
+				`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[{"itag":18,"url":"` + server.URL + `/stream/` + id +
+				`","mimeType":"video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"","height":360,"qualityLabel":"360p"}]}};</script>`))
+		case strings.HasPrefix(r.URL.Path, "/captions/"):
+			_, _ = w.Write([]byte(`<transcript><text start="0" dur="1.5">Hello ` + strings.TrimPrefix(r.URL.Path, "/captions/") + `</text></transcript>`))
+		case strings.HasPrefix(r.URL.Path, "/vi/"):
+			_, _ = w.Write([]byte("jpeg data"))
+		case strings.HasPrefix(r.URL.Path, "/stream/"):
+			_, _ = w.Write([]byte("video data"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestArchiver(server *httptest.Server, opts *archiveOptions) (*archiver, *bytes.Buffer) {
+	buf := new(bytes.Buffer)
+	return &archiver{
+		w:          buf,
+		client:     ytdl.NewClient(ytdl.WithHTTPClient(server.Client()), ytdl.WithBaseURL(server.URL)),
+		httpClient: server.Client(),
+		opts:       opts,
+		now:        func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) },
+	}, buf
+}
+
+func readArchiveManifest(t *testing.T, dir string) *archiveManifest {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, archiveManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest archiveManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	return &manifest
+}
+
+func TestArchiver_ArchivesVideo(t *testing.T) {
+	server := newArchiveTestServer(t)
+	out := t.TempDir()
+	a, buf := newTestArchiver(server, &archiveOptions{output: out, quality: "best", format: "mp4", subFormat: "vtt"})
+
+	if err := a.archiveAll(context.Background(), []string{"dQw4w9WgXcQ"}); err != nil {
+		t.Fatalf("archiveAll() error = %v\n%s", err, buf)
+	}
+
+	dir := filepath.Join(out, "Video dQw4w9WgXcQ [dQw4w9WgXcQ]")
+	manifest := readArchiveManifest(t, dir)
+	if manifest.VideoID != "dQw4w9WgXcQ" || manifest.URL != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" {
+		t.Errorf("manifest = %+v", manifest)
+	}
+	if !manifest.ArchivedAt.Equal(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("ArchivedAt = %v", manifest.ArchivedAt)
+	}
+
+	// Auto-generated German subtitles aren't saved without --auto-subs
+	wantFiles := []struct{ name, kind string }{
+		{"Video dQw4w9WgXcQ.mp4", artifactMedia},
+		{"Video dQw4w9WgXcQ.en.vtt", artifactSubtitles},
+		{"Video dQw4w9WgXcQ.jpg", artifactThumbnail},
+		{"Video dQw4w9WgXcQ.info.json", artifactInfo},
+	}
+	if len(manifest.Files) != len(wantFiles) {
+		t.Fatalf("files = %+v, want %d", manifest.Files, len(wantFiles))
+	}
+	for i, want := range wantFiles {
+		file := manifest.Files[i]
+		if file.Name != want.name || file.Kind != want.kind {
+			t.Errorf("file %d = %s (%s), want %s (%s)", i, file.Name, file.Kind, want.name, want.kind)
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, file.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(data)
+		if file.Size != int64(len(data)) || file.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("%s: size %d sha256 %s, want %d %x", file.Name, file.Size, file.SHA256, len(data), sum)
+		}
+	}
+
+	subs, err := os.ReadFile(filepath.Join(dir, "Video dQw4w9WgXcQ.en.vtt"))
+	if err != nil || !strings.HasPrefix(string(subs), "WEBVTT") || !strings.Contains(string(subs), "Hello en") {
+		t.Errorf("subtitles = %q, %v", subs, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "Video dQw4w9WgXcQ.info.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info archiveInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "Video dQw4w9WgXcQ" || info.Author != "Test Channel" || info.DurationSeconds != 60 {
+		t.Errorf("info = %+v", info)
+	}
+	if len(info.Formats) != 1 || info.Formats[0] != 18 || len(info.Captions) != 2 || len(info.Thumbnails) != 1 {
+		t.Errorf("info formats %v, captions %+v, thumbnails %+v", info.Formats, info.Captions, info.Thumbnails)
+	}
+}
+
+func TestArchiver_SkipsArchivedVideos(t *testing.T) {
+	server := newArchiveTestServer(t)
+	out := t.TempDir()
+	a, buf := newTestArchiver(server, &archiveOptions{output: out, quality: "best", format: "mp4", subFormat: "srt"})
+
+	if err := a.archiveAll(context.Background(), []string{"dQw4w9WgXcQ"}); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(out, "Video dQw4w9WgXcQ [dQw4w9WgXcQ]")
+	if _, err := os.Stat(filepath.Join(dir, "Video dQw4w9WgXcQ.en.srt")); err != nil {
+		t.Errorf("srt subtitles not saved: %v", err)
+	}
+
+	buf.Reset()
+	if err := a.archiveAll(context.Background(), []string{"dQw4w9WgXcQ"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Already archived") {
+		t.Errorf("output = %q, want the video skipped", buf)
+	}
+}
+
+// roundTripFunc is an http.RoundTripper calling itself.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestArchiver_FetchesExtrasDuringDownload(t *testing.T) {
+	server := newArchiveTestServer(t)
+	out := t.TempDir()
+	a, buf := newTestArchiver(server, &archiveOptions{output: out, quality: "best", format: "mp4", subFormat: "vtt"})
+
+	// The stream is only served once the thumbnail and subtitles are requested
+	var extras sync.WaitGroup
+	extras.Add(2)
+	transport := server.Client().Transport
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/vi/"), strings.HasPrefix(r.URL.Path, "/captions/"):
+			extras.Done()
+		case strings.HasPrefix(r.URL.Path, "/stream/"):
+			done := make(chan struct{})
+			go func() { extras.Wait(); close(done) }()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				return nil, errors.New("the stream was requested before the thumbnail and subtitles")
+			}
+		}
+		return transport.RoundTrip(r)
+	})}
+	a.client = ytdl.NewClient(ytdl.WithHTTPClient(client), ytdl.WithBaseURL(server.URL))
+	a.httpClient = client
+
+	if err := a.archiveAll(context.Background(), []string{"dQw4w9WgXcQ"}); err != nil {
+		t.Fatalf("archiveAll() error = %v\n%s", err, buf)
+	}
+	if files := readArchiveManifest(t, filepath.Join(out, "Video dQw4w9WgXcQ [dQw4w9WgXcQ]")).Files; len(files) != 4 {
+		t.Errorf("files = %+v, want the media, subtitles, thumbnail and info", files)
+	}
+}
+
+func TestArchiver_PlaylistPartialFailure(t *testing.T) {
+	server := newArchiveTestServer(t)
+	out := t.TempDir()
+	a, buf := newTestArchiver(server, &archiveOptions{output: out, quality: "best", format: "mp4", subFormat: "vtt"})
+
+	err := a.archiveAll(context.Background(), []string{testPlaylistURL})
+	var partial *PartialDownloadError
+	if !errors.As(err, &partial) || partial.Failed != 1 || partial.Total != 2 {
+		t.Fatalf("archiveAll() error = %v, want 1 of 2 failed", err)
+	}
+	if !strings.Contains(buf.String(), "Failed to archive jNQXAC9IVRw") {
+		t.Errorf("output = %q", buf)
+	}
+	readArchiveManifest(t, filepath.Join(out, "Video dQw4w9WgXcQ [dQw4w9WgXcQ]"))
+}
+
+func TestArchiver_SubtitleTracks(t *testing.T) {
+	captions := &youtube.CaptionManifest{Tracks: []youtube.CaptionTrack{
+		{LanguageCode: "en"},
+		{LanguageCode: "en", IsAutoGenerated: true},
+		{LanguageCode: "de", IsAutoGenerated: true},
+		{LanguageCode: "fr"},
+	}}
+	tests := []struct {
+		name     string
+		opts     archiveOptions
+		wantLang []string
+	}{
+		{"manual only", archiveOptions{}, []string{"en", "fr"}},
+		{"with auto", archiveOptions{autoSubs: true}, []string{"en", "fr", "de"}},
+		{"languages", archiveOptions{subLangs: []string{"DE", "fr"}, autoSubs: true}, []string{"fr", "de"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &archiver{opts: &tt.opts}
+			var langs []string
+			for _, track := range a.subtitleTracks(captions) {
+				langs = append(langs, track.LanguageCode)
+			}
+			if strings.Join(langs, ",") != strings.Join(tt.wantLang, ",") {
+				t.Errorf("languages = %v, want %v", langs, tt.wantLang)
+			}
+		})
+	}
+}
+
+func TestArchiveCommandRejectsInvalidSubFormat(t *testing.T) {
+	rootCmd := newRootCmd()
+	rootCmd.SetOut(new(bytes.Buffer))
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"archive", "--sub-format", "ass", "dQw4w9WgXcQ"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--sub-format") {
+		t.Errorf("Execute() error = %v, want invalid --sub-format", err)
+	}
+}
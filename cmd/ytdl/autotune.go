@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+)
+
+// defaultAutoTuneInterval is how often --auto-tune measures the throughput of
+// the downloads.
+const defaultAutoTuneInterval = 30 * time.Second
+
+// errNothingToMeasure is returned by throughput probes while no download runs.
+var errNothingToMeasure = errors.New("no download is running")
+
+// autoTuneOptions holds the flags of the serve command bounding the number of
+// downloads and of chunks per stream --auto-tune runs at once.
+type autoTuneOptions struct {
+	enabled   bool
+	interval  time.Duration
+	minJobs   int
+	maxJobs   int
+	minChunks int
+	maxChunks int
+}
+
+// addAutoTuneFlags adds the --auto-tune flags to cmd, which also has --jobs.
+func addAutoTuneFlags(cmd *cobra.Command, opts *autoTuneOptions) {
+	cmd.Flags().BoolVar(&opts.enabled, "auto-tune", false, "Adjust the number of downloads and of chunks of each stream run at once to the measured throughput")
+	cmd.Flags().DurationVar(&opts.interval, "auto-tune-interval", defaultAutoTuneInterval, "How often --auto-tune measures the throughput")
+	cmd.Flags().IntVar(&opts.minJobs, "min-jobs", 1, "Fewest downloads --auto-tune runs at the same time")
+	cmd.Flags().IntVar(&opts.maxJobs, "max-jobs", 4, "Most downloads --auto-tune runs at the same time")
+	cmd.Flags().IntVar(&opts.minChunks, "min-chunks", 1, "Fewest chunks of each stream --auto-tune requests at once")
+	cmd.Flags().IntVar(&opts.maxChunks, "max-chunks", 4, "Most chunks of each stream --auto-tune requests at once")
+	cmd.MarkFlagsMutuallyExclusive("jobs", "auto-tune")
+}
+
+// validate checks the bounds of the flags when --auto-tune is set.
+func (o *autoTuneOptions) validate() error {
+	switch {
+	case !o.enabled:
+		return nil
+	case o.interval <= 0:
+		return errors.New("--auto-tune-interval must be positive")
+	case o.minJobs < 1:
+		return errors.New("--min-jobs must be at least 1")
+	case o.maxJobs < o.minJobs:
+		return errors.New("--max-jobs must be at least --min-jobs")
+	case o.minChunks < 1:
+		return errors.New("--min-chunks must be at least 1")
+	case o.maxChunks < o.minChunks:
+		return errors.New("--max-chunks must be at least --min-chunks")
+	}
+	return nil
+}
+
+// autoTuner adjusts the number of downloads a queue runs at once and the
+// number of chunks of each stream requested at once to the throughput of the
+// queue's downloads, one of them at a time.
+type autoTuner struct {
+	jobs     *download.ConcurrencyTuner
+	chunks   *download.ConcurrencyTuner
+	interval time.Duration
+}
+
+// newAutoTuner returns the tuner of the --auto-tune flags, or nil without --auto-tune.
+func newAutoTuner(opts *autoTuneOptions) *autoTuner {
+	if !opts.enabled {
+		return nil
+	}
+	return &autoTuner{
+		jobs:     download.NewConcurrencyTuner(opts.minJobs, opts.maxJobs),
+		chunks:   download.NewConcurrencyTuner(opts.minChunks, opts.maxChunks),
+		interval: opts.interval,
+	}
+}
+
+// run tunes the queue until ctx is done, reporting changes to w. The queue's
+// client must request chunks with the tuner's chunk tuner.
+func (t *autoTuner) run(ctx context.Context, q *jobQueue, w io.Writer) {
+	download.Tune(ctx, t.interval, q.throughputProbe(), func(tuner *download.ConcurrencyTuner, n int) {
+		if tuner == t.jobs {
+			q.setWorkers(n)
+			_, _ = fmt.Fprintf(w, "Auto-tune: running up to %d downloads at once\n", n)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "Auto-tune: requesting up to %d chunks of each stream at once\n", n)
+	}, t.jobs, t.chunks)
+}
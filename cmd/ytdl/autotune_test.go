@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+)
+
+func TestServe_AutoTuneFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--auto-tune", "--jobs", "2"}, "[jobs auto-tune]"},
+		{[]string{"--auto-tune", "--auto-tune-interval", "0s"}, "--auto-tune-interval must be positive"},
+		{[]string{"--auto-tune", "--min-jobs", "0"}, "--min-jobs must be at least 1"},
+		{[]string{"--auto-tune", "--min-jobs", "3", "--max-jobs", "2"}, "--max-jobs must be at least --min-jobs"},
+		{[]string{"--auto-tune", "--min-chunks", "0"}, "--min-chunks must be at least 1"},
+		{[]string{"--auto-tune", "--min-chunks", "5"}, "--max-chunks must be at least --min-chunks"},
+	}
+	for _, tt := range tests {
+		cmd := newServeCmd()
+		cmd.SetArgs(append([]string{"--addr", "127.0.0.1:0"}, tt.args...))
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("serve %v = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestNewAutoTuner(t *testing.T) {
+	if newAutoTuner(&autoTuneOptions{}) != nil {
+		t.Error("newAutoTuner() without --auto-tune isn't nil")
+	}
+	tuner := newAutoTuner(&autoTuneOptions{enabled: true, interval: defaultAutoTuneInterval, minJobs: 2, maxJobs: 5, minChunks: 3, maxChunks: 4})
+	if tuner.jobs.Concurrency() != 2 || tuner.chunks.Concurrency() != 3 {
+		t.Errorf("tuner starts at %d jobs and %d chunks, want the minimums", tuner.jobs.Concurrency(), tuner.chunks.Concurrency())
+	}
+}
+
+func TestJobQueue_SetWorkers(t *testing.T) {
+	queue, _ := newTestJobQueue(t, 1)
+	first := queue.enqueue(downloadRequest{URL: "slowAAAAAAA"})
+	second := queue.enqueue(downloadRequest{URL: "slowBBBBBBB"})
+	if view, _, _ := queue.snapshot(second.ID); view.Status != jobQueued {
+		t.Fatalf("second download is %s with one worker, want queued", view.Status)
+	}
+
+	queue.setWorkers(2)
+	for _, id := range []string{first.ID, second.ID} {
+		if view, _, _ := queue.snapshot(id); view.Status != jobDownloading {
+			t.Errorf("download %s is %s with two workers, want downloading", id, view.Status)
+		}
+	}
+}
+
+func TestJobQueue_ThroughputProbe(t *testing.T) {
+	queue := newJobQueue(context.Background(), nil, t.TempDir(), 1)
+	probe := queue.throughputProbe()
+	if _, _, err := probe(context.Background()); !errors.Is(err, errNothingToMeasure) {
+		t.Errorf("probe of an idle queue = %v, want %v", err, errNothingToMeasure)
+	}
+
+	// A job downloading a video stream of 500 bytes, then the first 200 of its audio stream
+	j := &job{changed: make(chan struct{})}
+	queue.running = 1
+	for _, downloaded := range []int64{300, 500, 200} {
+		queue.progress(j, download.Progress{Downloaded: downloaded})
+	}
+	n, elapsed, err := probe(context.Background())
+	if err != nil || n != 700 || elapsed <= 0 {
+		t.Errorf("probe = %d bytes in %v, %v, want 700 bytes", n, elapsed, err)
+	}
+	if n, _, _ := probe(context.Background()); n != 0 {
+		t.Errorf("probe again = %d bytes, want 0", n)
+	}
+}
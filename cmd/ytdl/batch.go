@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+)
+
+// batchStdin is the --batch-file value that reads URLs from stdin.
+const batchStdin = "-"
+
+// runBatchDownload downloads the URLs given as arguments followed by those in the
+// --batch-file, continuing past failures and printing a summary at the end.
+func runBatchDownload(cmd *cobra.Command, args []string, opts *downloadOptions) error {
+	if opts.output == stdoutOutput {
+		return errors.New("--output - can't be used with --batch-file")
+	}
+
+	urls, err := readBatchFileFrom(opts.batchFile, cmd.InOrStdin())
+	if err != nil {
+		return err
+	}
+	urls = slices.Concat(args, urls)
+	if len(urls) == 0 {
+		return fmt.Errorf("no URLs found in batch file %s", opts.batchFile)
+	}
+
+	client, err := newHTTPClient(cmd)
+	if err != nil {
+		return WrapError(err)
+	}
+	page, err := newWatchPageFetcher(cmd, client)
+	if err != nil {
+		return WrapError(err)
+	}
+	downloader, err := newStreamDownloader(cmd, client, download.WithEventListener(logEvents(loggerFrom(cmd.Context()))))
+	if err != nil {
+		return WrapError(err)
+	}
+	if err := openUploadStorage(opts, client); err != nil {
+		return err
+	}
+
+	return downloadBatch(cmd.Context(), statusWriter(cmd), urls, opts, newSources(page), downloader, muxStreams)
+}
+
+// readBatchFileFrom reads the batch file at path, or stdin when path is batchStdin.
+func readBatchFileFrom(path string, stdin io.Reader) ([]string, error) {
+	if path == batchStdin {
+		urls, err := readBatchFile(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read URLs from stdin: %w", err)
+		}
+		return urls, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	urls, err := readBatchFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch file: %w", err)
+	}
+	return urls, nil
+}
+
+// readBatchFile returns the URLs or IDs listed in r, one per line.
+// Blank lines and lines starting with '#' or ';' are skipped.
+func readBatchFile(r io.Reader) ([]string, error) {
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// downloadBatch downloads each URL like the download command would. A URL that
+// fails is reported and skipped, and a summary of all videos is printed at the end.
+func downloadBatch(
+	ctx context.Context,
+	w io.Writer,
+	urls []string,
+	opts *downloadOptions,
+	src *sources,
+	downloader download.StreamDownloader,
+	muxer MuxerFunc,
+) error {
+	opts, ownReport := withReport(opts)
+	if opts.dedup == nil {
+		// A video listed under several URLs is downloaded once for the whole batch
+		dedup, err := newDedup(opts)
+		if err != nil {
+			return err
+		}
+		copied := *opts
+		copied.dedup = dedup
+		opts = &copied
+	}
+	failed := 0
+	for i, url := range urls {
+		_, _ = fmt.Fprintf(w, "\n[%d/%d] %s\n", i+1, len(urls), url)
+
+		recorded := len(opts.report.results)
+		if err := runDownloadWithDeps(ctx, w, url, opts, src, downloader, muxer); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			err = WrapError(err)
+			_, _ = fmt.Fprintf(w, "Failed to download %s: %v\n", url, err)
+			failed++
+
+			// A URL that failed before any video was reached still counts as a failure
+			if len(opts.report.results) == recorded {
+				opts.report.add(download.DownloadResult{Title: url, Error: err})
+			}
+		}
+	}
+
+	if ownReport {
+		if err := finishReport(w, opts.report, opts.reportJSON); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return &PartialDownloadError{Failed: failed, Total: len(urls)}
+	}
+	return nil
+}
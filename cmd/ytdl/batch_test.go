@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+func TestReadBatchFile(t *testing.T) {
+	input := "# my list\nhttps://youtu.be/dQw4w9WgXcQ\n\n  jNQXAC9IVRw  \n; also a comment\r\nhttps://www.youtube.com/watch?v=9bZkp7q19f0\r\n"
+	urls, err := readBatchFile(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readBatchFile failed: %v", err)
+	}
+	want := []string{"https://youtu.be/dQw4w9WgXcQ", "jNQXAC9IVRw", "https://www.youtube.com/watch?v=9bZkp7q19f0"}
+	if !slices.Equal(urls, want) {
+		t.Errorf("readBatchFile = %q, want %q", urls, want)
+	}
+}
+
+func TestReadBatchFileFrom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.txt")
+	if err := os.WriteFile(path, []byte("dQw4w9WgXcQ\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if urls, err := readBatchFileFrom(path, nil); err != nil || !slices.Equal(urls, []string{"dQw4w9WgXcQ"}) {
+		t.Errorf("readBatchFileFrom(file) = %q, %v", urls, err)
+	}
+
+	if urls, err := readBatchFileFrom(batchStdin, strings.NewReader("jNQXAC9IVRw\n")); err != nil || !slices.Equal(urls, []string{"jNQXAC9IVRw"}) {
+		t.Errorf("readBatchFileFrom(stdin) = %q, %v", urls, err)
+	}
+
+	if _, err := readBatchFileFrom(filepath.Join(t.TempDir(), "missing.txt"), nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist for a missing file, got %v", err)
+	}
+}
+
+// TestDownloadBatch tests that a failing URL is reported and skipped, and that
+// the remaining URLs are still downloaded.
+func TestDownloadBatch(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("v")
+		switch {
+		case r.URL.Path == "/watch" && id == "jNQXAC9IVRw":
+			_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"playabilityStatus":{"status":"ERROR","reason":"Video unavailable"}};</script>`))
+		case r.URL.Path == "/watch":
+			_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"` + id + `","title":"Video ` + id + `","lengthSeconds":"120"},` +
+				`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[` +
+				`{"itag":18,"url":"` + server.URL + `/stream","mimeType":"video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"","height":360,"qualityLabel":"360p"}]}};</script>`))
+		default:
+			_, _ = w.Write([]byte("content"))
+		}
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	opts := &downloadOptions{output: tempDir, quality: "best", format: "mp4"}
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	downloader := download.NewDownloader(server.Client())
+
+	buf := new(bytes.Buffer)
+	urls := []string{"dQw4w9WgXcQ", "jNQXAC9IVRw", "not a url", "9bZkp7q19f0"}
+	err := downloadBatch(context.Background(), buf, urls, opts, fetcher, downloader, nil)
+
+	var partialErr *PartialDownloadError
+	if !errors.As(err, &partialErr) || partialErr.Failed != 2 || partialErr.Total != 4 {
+		t.Errorf("expected 2 of 4 failures, got %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"[1/4] dQw4w9WgXcQ", "[2/4] jNQXAC9IVRw", "Failed to download jNQXAC9IVRw", "[4/4] 9bZkp7q19f0",
+		"Summary: 2 succeeded, 2 failed, 0 skipped", "  jNQXAC9IVRw: video 'jNQXAC9IVRw' is unavailable", "  not a url: Unable to recognize the URL or ID"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, output)
+		}
+	}
+	for _, name := range []string{"Video dQw4w9WgXcQ.mp4", "Video 9bZkp7q19f0.mp4"} {
+		if _, err := os.Stat(filepath.Join(tempDir, name)); err != nil {
+			t.Errorf("expected %s to be downloaded: %v", name, err)
+		}
+	}
+}
+
+func TestDownloadCommandBatchFileFlag(t *testing.T) {
+	cmd := newDownloadCmd()
+	flag := cmd.Flags().Lookup("batch-file")
+	if flag == nil || flag.Shorthand != "a" {
+		t.Fatal("expected --batch-file flag with -a shorthand")
+	}
+
+	// The URLs come from the file, so no argument is needed
+	if err := cmd.Flags().Set("batch-file", "urls.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Args(cmd, nil); err != nil {
+		t.Errorf("expected no arguments to be accepted with --batch-file, got %v", err)
+	}
+}
+
+// TestDownloadBatch_Dedup tests that a video listed twice in a run is downloaded
+// once, and that the download archive skips it in later runs.
+func TestDownloadBatch_Dedup(t *testing.T) {
+	var server *httptest.Server
+	streams := 0
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/watch" {
+			id := r.URL.Query().Get("v")
+			_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"` + id + `","title":"Video ` + id + `","lengthSeconds":"120"},` +
+				`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[` +
+				`{"itag":18,"url":"` + server.URL + `/stream","mimeType":"video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"","height":360,"qualityLabel":"360p"}]}};</script>`))
+			return
+		}
+		streams++
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	archivePath := filepath.Join(tempDir, "archive.txt")
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	downloader := download.NewDownloader(server.Client())
+	urls := []string{"dQw4w9WgXcQ", "https://youtu.be/dQw4w9WgXcQ"}
+	run := func(opts *downloadOptions) string {
+		t.Helper()
+		buf := new(bytes.Buffer)
+		if err := downloadBatch(context.Background(), buf, urls, opts, fetcher, downloader, nil); err != nil {
+			t.Fatalf("downloadBatch failed: %v", err)
+		}
+		return buf.String()
+	}
+
+	output := run(&downloadOptions{output: tempDir, quality: "best", format: "mp4", downloadArchive: archivePath})
+	if streams != 1 || !strings.Contains(output, "Skipping dQw4w9WgXcQ: listed earlier in this run") {
+		t.Errorf("downloaded %d times, want once:\n%s", streams, output)
+	}
+	if data, err := os.ReadFile(archivePath); err != nil || string(data) != "youtube dQw4w9WgXcQ\n" {
+		t.Errorf("archive = %q, %v", data, err)
+	}
+
+	output = run(&downloadOptions{output: tempDir, quality: "best", format: "mp4", downloadArchive: archivePath})
+	if streams != 1 || !strings.Contains(output, "Skipping dQw4w9WgXcQ: in the download archive") {
+		t.Errorf("archived video downloaded again:\n%s", output)
+	}
+
+	run(&downloadOptions{output: tempDir, quality: "best", format: "mp4", noDedup: true})
+	if streams != 3 {
+		t.Errorf("downloaded %d times in all, want twice more with --no-dedup", streams)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/cache"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// defaultCacheTTL is how long fetched metadata is reused. Stream URLs in cached
+// watch pages expire after about six hours, so this stays well below that.
+const defaultCacheTTL = time.Hour
+
+// playerCacheTTL is how long parsed players are kept. A player version never
+// changes, so they only expire to clean up versions YouTube no longer serves.
+const playerCacheTTL = 30 * 24 * time.Hour
+
+// cacheIdentityFlags are the global flags that change what YouTube returns or
+// which client stream URLs are bound to, so pages fetched with different values
+// are cached separately.
+var cacheIdentityFlags = []string{
+	"proxy", "restricted", "user-agent", "accept-language", "add-header",
+	"force-ipv4", "force-ipv6", "source-address", "geo-bypass-country", "visitor-data",
+}
+
+// addCacheFlags registers the global metadata cache flags on the root command.
+func addCacheFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().Bool("no-cache", false,
+		"Always fetch watch pages, playlists and the player instead of reusing recently fetched ones")
+	cmd.PersistentFlags().String("cache-dir", "", "Directory for cached metadata (default the user cache directory)")
+	cmd.PersistentFlags().Duration("cache-ttl", defaultCacheTTL, "How long cached watch pages and playlists are reused")
+}
+
+// newMetadataCache returns the cache for watch pages and playlist listings,
+// or nil with --no-cache or a TTL of zero.
+func newMetadataCache(cmd *cobra.Command) (youtube.Cache, error) {
+	if flagValue(cmd, "no-cache") == "true" {
+		return nil, nil
+	}
+
+	ttl := defaultCacheTTL
+	if value := flagValue(cmd, "cache-ttl"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid --cache-ttl %q", value)
+		}
+		ttl = d
+	}
+	if ttl == 0 {
+		return nil, nil
+	}
+
+	return cache.New(cacheDir(cmd, "metadata"), ttl).Namespace(cacheIdentity(cmd)), nil
+}
+
+// newPlayerCache returns the cache for parsed player JavaScript, or nil with --no-cache.
+func newPlayerCache(cmd *cobra.Command) youtube.Cache {
+	if flagValue(cmd, "no-cache") == "true" {
+		return nil
+	}
+	return cache.New(cacheDir(cmd, "players"), playerCacheTTL)
+}
+
+// cacheDir returns the directory of one kind of cached data. Without a user
+// cache directory it returns "", so the cache only lasts for this run.
+func cacheDir(cmd *cobra.Command, kind string) string {
+	if dir := flagValue(cmd, "cache-dir"); dir != "" {
+		return filepath.Join(dir, kind)
+	}
+	if userDir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(userDir, "ytdl", kind)
+	}
+	return ""
+}
+
+// newWatchPageFetcher returns a watch page fetcher for a command using its
+// metadata and player caches and its PO token flags.
+func newWatchPageFetcher(cmd *cobra.Command, client *http.Client) (*youtube.WatchPageFetcher, error) {
+	metadataCache, err := newMetadataCache(cmd)
+	if err != nil {
+		return nil, err
+	}
+	poTokens, err := newPOTokenProvider(cmd, client)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := timeoutFlag(cmd, "fetch-timeout")
+	if err != nil {
+		return nil, err
+	}
+	return &youtube.WatchPageFetcher{
+		Client:          client,
+		Cache:           metadataCache,
+		Players:         &youtube.PlayerFetcher{Client: client, Cache: newPlayerCache(cmd)},
+		VisitorData:     flagValue(cmd, "visitor-data"),
+		POTokenProvider: poTokens,
+		Timeout:         timeout,
+	}, nil
+}
+
+// cacheIdentity returns a short hash of the flags in cacheIdentityFlags.
+func cacheIdentity(cmd *cobra.Command) string {
+	h := sha256.New()
+	for _, name := range cacheIdentityFlags {
+		value := flagValue(cmd, name)
+		if values := flagValues(cmd, name); values != nil {
+			value = strings.Join(values, "\n")
+		}
+		_, _ = fmt.Fprintf(h, "%s=%s\x00", name, value)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+func TestNewMetadataCache(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "default"},
+		{name: "no cache", args: []string{"--no-cache"}, wantNil: true},
+		{name: "zero ttl", args: []string{"--cache-ttl", "0"}, wantNil: true},
+		{name: "negative ttl", args: []string{"--cache-ttl", "-1h"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newRootCmd()
+			if err := cmd.ParseFlags(append([]string{"--cache-dir", t.TempDir()}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+			c, err := newMetadataCache(cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newMetadataCache() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (c == nil) != tt.wantNil {
+				t.Errorf("newMetadataCache() = %v, want nil %v", c, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestNewMetadataCache_SeparatesIdentities(t *testing.T) {
+	dir := t.TempDir()
+	newCache := func(args ...string) youtube.Cache {
+		cmd := newRootCmd()
+		if err := cmd.ParseFlags(append([]string{"--cache-dir", dir}, args...)); err != nil {
+			t.Fatal(err)
+		}
+		c, err := newMetadataCache(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	newCache().Set("watch/a", []byte("page"))
+	if _, ok := newCache().Get("watch/a"); !ok {
+		t.Error("expected a later run with the same flags to reuse the page")
+	}
+	if _, ok := newCache("--user-agent", "Other/1.0").Get("watch/a"); ok {
+		t.Error("expected a different User-Agent not to reuse the page")
+	}
+	if _, ok := newCache("--add-header", "X-Test: 1").Get("watch/a"); ok {
+		t.Error("expected different headers not to reuse the page")
+	}
+}
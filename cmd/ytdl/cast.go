@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/dlna"
+)
+
+const (
+	// defaultCastDiscoverTimeout is how long cast searches for renderers.
+	defaultCastDiscoverTimeout = 3 * time.Second
+
+	// castRequestTimeout bounds each request to a renderer.
+	castRequestTimeout = 10 * time.Second
+)
+
+// castOptions holds the flags of the cast command.
+type castOptions struct {
+	device          string
+	list            bool
+	discoverTimeout time.Duration
+	proxy           proxyOptions
+}
+
+// castTarget is a renderer playing a cast stream.
+type castTarget interface {
+	Play(ctx context.Context) error
+	Pause(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+func newCastCmd() *cobra.Command {
+	opts := &castOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "cast [url]",
+		Short: "Play a video on a DLNA renderer, such as a smart TV",
+		Long: `Play a video on a DLNA media renderer on the local network, such as a smart
+TV, receiver or speaker.
+
+Renderers are found with SSDP. The video is served to the renderer as by
+"ytdl proxy", which keeps running while it plays; type pause, play or stop
+(or p, r, s) and Enter to control playback. Ctrl+C stops playback and exits.
+
+With several renderers on the network, --device picks one by name or UDN;
+--list shows the ones found. Renderers that don't answer searches can be
+given by the URL of their device description.`,
+		Example: `  ytdl cast --list
+  ytdl cast https://www.youtube.com/watch?v=dQw4w9WgXcQ
+  ytdl cast dQw4w9WgXcQ --device "Living Room"
+  ytdl cast dQw4w9WgXcQ --device http://192.168.1.20:9197/dmr --quality audio`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !opts.list {
+				return WrapError(errors.New("a video URL is required unless --list is given"))
+			}
+			if opts.discoverTimeout <= 0 {
+				return WrapError(errors.New("--discover-timeout must be positive"))
+			}
+			var urlStr string
+			if len(args) > 0 {
+				urlStr = args[0]
+			}
+			if err := runCast(cmd, urlStr, opts); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.device, "device", "", "Renderer to play on: a name, UDN or description URL")
+	cmd.Flags().BoolVar(&opts.list, "list", false, "List the renderers found and exit")
+	cmd.Flags().DurationVar(&opts.discoverTimeout, "discover-timeout", defaultCastDiscoverTimeout, "How long to search for renderers")
+	cmd.Flags().StringVar(&opts.proxy.addr, "addr", defaultProxyAddr, "Address to serve the stream on")
+	cmd.Flags().StringVarP(&opts.proxy.quality, "quality", "q", "best", "Video quality (best, worst, 360p, audio, etc.)")
+	cmd.Flags().StringVarP(&opts.proxy.format, "format", "f", "", "Preferred container (mp4, webm)")
+
+	return cmd
+}
+
+// runCast plays the video at urlStr on the chosen renderer until it's stopped.
+func runCast(cmd *cobra.Command, urlStr string, opts *castOptions) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	w := statusWriter(cmd)
+
+	// Renderers are on the LAN, so they are reached without the --proxy of YouTube requests
+	discoverer := &dlna.Discoverer{Client: &http.Client{Timeout: castRequestTimeout}}
+	if opts.list {
+		return listRenderers(ctx, cmd.OutOrStdout(), discoverer, opts.discoverTimeout)
+	}
+	renderer, err := findRenderer(ctx, w, discoverer, opts)
+	if err != nil {
+		return err
+	}
+
+	client, err := newHTTPClient(cmd)
+	if err != nil {
+		return err
+	}
+	page, err := newWatchPageFetcher(cmd, client)
+	if err != nil {
+		return err
+	}
+	proxy, err := newStreamProxy(ctx, w, urlStr, &opts.proxy, newSources(page))
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", opts.proxy.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	host, err := localAddressFor(renderer.ControlURL)
+	if err != nil {
+		_ = listener.Close()
+		return err
+	}
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	streamURL := "http://" + net.JoinHostPort(host, port) + "/" + proxy.name
+
+	server := &http.Server{Handler: proxy.handler(), ReadHeaderTimeout: 10 * time.Second}
+	errc := make(chan error, 1)
+	go func() { errc <- server.Serve(listener) }()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	_, _ = fmt.Fprintf(w, "Casting to %s from %s\n", displayText(renderer.Name), streamURL)
+	if err := renderer.SetURI(ctx, streamURL, proxy.title, proxy.mediaType()); err != nil {
+		return err
+	}
+	if err := renderer.Play(ctx); err != nil {
+		return err
+	}
+
+	controlCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			_, _ = fmt.Fprintf(w, "Warning: stream server failed: %v\n", err)
+			cancel()
+		}
+	}()
+	return castControls(controlCtx, cmd.InOrStdin(), w, renderer)
+}
+
+// listRenderers writes the renderers found on the network.
+func listRenderers(ctx context.Context, w io.Writer, discoverer *dlna.Discoverer, wait time.Duration) error {
+	renderers, err := discoverer.Discover(ctx, wait)
+	if err != nil {
+		return err
+	}
+	if len(renderers) == 0 {
+		_, _ = fmt.Fprintln(w, "No renderers found")
+		return nil
+	}
+	for _, r := range renderers {
+		name := r.Name
+		if r.Model != "" {
+			name += " (" + r.Model + ")"
+		}
+		_, _ = fmt.Fprintf(w, "%s\n  %s\n  %s\n", displayText(name), r.UDN, r.Location)
+	}
+	return nil
+}
+
+// findRenderer returns the renderer to cast to: the one at the --device URL,
+// or the one found on the network matching --device.
+func findRenderer(ctx context.Context, w io.Writer, discoverer *dlna.Discoverer, opts *castOptions) (*dlna.Renderer, error) {
+	if strings.HasPrefix(opts.device, "http://") || strings.HasPrefix(opts.device, "https://") {
+		return discoverer.Renderer(ctx, opts.device)
+	}
+	_, _ = fmt.Fprintln(w, "Searching for renderers...")
+	renderers, err := discoverer.Discover(ctx, opts.discoverTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return chooseRenderer(renderers, opts.device)
+}
+
+// chooseRenderer returns the renderer matching device: the one with that name
+// or UDN, or else the only one whose name contains it. Without a device, there
+// must be only one renderer.
+func chooseRenderer(renderers []*dlna.Renderer, device string) (*dlna.Renderer, error) {
+	if len(renderers) == 0 {
+		return nil, errors.New("no renderers found; check that the device is on and on the same network")
+	}
+	if device == "" {
+		if len(renderers) == 1 {
+			return renderers[0], nil
+		}
+		return nil, fmt.Errorf("found %d renderers, choose one with --device: %s", len(renderers), rendererNames(renderers))
+	}
+
+	var matches []*dlna.Renderer
+	for _, r := range renderers {
+		if strings.EqualFold(r.Name, device) || strings.EqualFold(r.UDN, device) {
+			return r, nil
+		}
+		if strings.Contains(strings.ToLower(r.Name), strings.ToLower(device)) {
+			matches = append(matches, r)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no renderer matches %q; found %s", device, rendererNames(renderers))
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%q matches several renderers: %s", device, rendererNames(matches))
+	}
+}
+
+func rendererNames(renderers []*dlna.Renderer) string {
+	names := make([]string, len(renderers))
+	for i, r := range renderers {
+		names[i] = strconv.Quote(r.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// localAddressFor returns the address of this machine on the network of the
+// renderer at controlURL, which the renderer fetches the stream from.
+func localAddressFor(controlURL string) (string, error) {
+	u, err := url.Parse(controlURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid renderer URL: %w", err)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+	}
+	// Dialing UDP sends nothing; it only picks the route to the renderer
+	conn, err := net.Dial("udp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return "", fmt.Errorf("no route to the renderer: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// castControls reads playback commands from in and sends them to target
+// until a stop command or until ctx is canceled, which also stops playback.
+func castControls(ctx context.Context, in io.Reader, w io.Writer, target castTarget) error {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- strings.ToLower(strings.TrimSpace(scanner.Text())):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	_, _ = fmt.Fprintln(w, "Playing. Type pause, play or stop and Enter; Ctrl+C stops.")
+	for {
+		var line string
+		var ok bool
+		select {
+		case line, ok = <-lines:
+			if !ok {
+				// Without input, such as from a script, play until interrupted
+				lines = nil
+				continue
+			}
+		case <-ctx.Done():
+			stopCtx, cancel := context.WithTimeout(context.Background(), castRequestTimeout)
+			defer cancel()
+			return target.Stop(stopCtx)
+		}
+
+		actionCtx, cancel := context.WithTimeout(ctx, castRequestTimeout)
+		var err error
+		switch line {
+		case "":
+		case "p", "pause":
+			if err = target.Pause(actionCtx); err == nil {
+				_, _ = fmt.Fprintln(w, "Paused")
+			}
+		case "r", "play", "resume":
+			if err = target.Play(actionCtx); err == nil {
+				_, _ = fmt.Fprintln(w, "Playing")
+			}
+		case "s", "stop", "q", "quit":
+			err = target.Stop(actionCtx)
+			cancel()
+			if err == nil {
+				_, _ = fmt.Fprintln(w, "Stopped")
+			}
+			return err
+		default:
+			_, _ = fmt.Fprintf(w, "Unknown command %q; type pause, play or stop\n", displayText(line))
+		}
+		cancel()
+		if err != nil {
+			_, _ = fmt.Fprintf(w, "Warning: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/dlna"
+)
+
+// fakeCastTarget records the actions sent to it, failing those in fail.
+type fakeCastTarget struct {
+	actions []string
+	fail    map[string]error
+}
+
+func (f *fakeCastTarget) do(action string) error {
+	f.actions = append(f.actions, action)
+	return f.fail[action]
+}
+
+func (f *fakeCastTarget) Play(context.Context) error  { return f.do("play") }
+func (f *fakeCastTarget) Pause(context.Context) error { return f.do("pause") }
+func (f *fakeCastTarget) Stop(context.Context) error  { return f.do("stop") }
+
+func TestCastControls(t *testing.T) {
+	target := &fakeCastTarget{fail: map[string]error{"play": errors.New("Play failed with UPnP error 701")}}
+	buf := new(bytes.Buffer)
+
+	err := castControls(context.Background(), strings.NewReader("pause\n\nR\nrewind\ns\npause\n"), buf, target)
+	if err != nil {
+		t.Fatalf("castControls failed: %v", err)
+	}
+
+	if got := strings.Join(target.actions, ","); got != "pause,play,stop" {
+		t.Errorf("actions = %s, want pause,play,stop", got)
+	}
+	output := buf.String()
+	for _, want := range []string{"Paused\n", "Warning: Play failed with UPnP error 701\n", `Unknown command "rewind"`, "Stopped\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output is missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestCastControls_StopsWhenCanceled(t *testing.T) {
+	target := &fakeCastTarget{}
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	done := make(chan error)
+	go func() { done <- castControls(ctx, reader, io.Discard, target) }()
+	_, _ = writer.Write([]byte("pause\n"))
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("castControls failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("castControls didn't return when canceled")
+	}
+	if n := len(target.actions); n == 0 || target.actions[n-1] != "stop" {
+		t.Errorf("actions = %v, want playback stopped", target.actions)
+	}
+}
+
+func TestChooseRenderer(t *testing.T) {
+	tv := &dlna.Renderer{Name: "Living Room TV", UDN: "uuid:1"}
+	tv2 := &dlna.Renderer{Name: "Bedroom TV", UDN: "uuid:2"}
+	speaker := &dlna.Renderer{Name: "Kitchen", UDN: "uuid:3"}
+	renderers := []*dlna.Renderer{tv, tv2, speaker}
+
+	tests := []struct {
+		name      string
+		renderers []*dlna.Renderer
+		device    string
+		want      *dlna.Renderer
+		wantErr   string
+	}{
+		{"only renderer", []*dlna.Renderer{speaker}, "", speaker, ""},
+		{"none found", nil, "", nil, "no renderers found"},
+		{"several without device", renderers, "", nil, `found 3 renderers, choose one with --device: "Living Room TV", "Bedroom TV", "Kitchen"`},
+		{"by name", renderers, "kitchen", speaker, ""},
+		{"by UDN", renderers, "UUID:2", tv2, ""},
+		{"by part of the name", renderers, "living", tv, ""},
+		{"ambiguous", renderers, "TV", nil, `"TV" matches several renderers`},
+		{"no match", renderers, "garage", nil, `no renderer matches "garage"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := chooseRenderer(tt.renderers, tt.device)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("chooseRenderer = %v, %v; want %s", got, err, tt.want.Name)
+			}
+		})
+	}
+}
+
+func TestLocalAddressFor(t *testing.T) {
+	got, err := localAddressFor("http://127.0.0.1:9197/upnp/control/AVTransport1")
+	if err != nil || got != "127.0.0.1" {
+		t.Errorf("localAddressFor(loopback) = %q, %v; want 127.0.0.1", got, err)
+	}
+}
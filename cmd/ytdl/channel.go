@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// defaultChannelLimit is the number of uploads listed when --limit isn't given.
+const defaultChannelLimit = 30
+
+// channelOptions holds the flags of the channel command.
+type channelOptions struct {
+	limit        int
+	continuation string
+	json         bool
+}
+
+// channelListing is the JSON form of a channel printed with --json.
+type channelListing struct {
+	ID                  string          `json:"id"`
+	Title               string          `json:"title"`
+	Handle              string          `json:"handle,omitempty"`
+	Description         string          `json:"description,omitempty"`
+	SubscriberCount     int64           `json:"subscriber_count,omitempty"`
+	SubscriberCountText string          `json:"subscriber_count_text,omitempty"`
+	URL                 string          `json:"url"`
+	Uploads             []playlistEntry `json:"uploads"`
+	Continuation        string          `json:"continuation,omitempty"`
+}
+
+// uploadsCursor is the position to resume an uploads listing from. YouTube's
+// continuation tokens only point at page boundaries, so the cursor also records
+// how many videos of that page were already listed.
+type uploadsCursor struct {
+	// Token is YouTube's continuation token for the page, empty for the first page.
+	Token string `json:"t,omitempty"`
+
+	// Skip is the number of videos of the page already listed.
+	Skip int `json:"s,omitempty"`
+
+	// Listed is the number of uploads listed before the page, for numbering.
+	Listed int `json:"n,omitempty"`
+}
+
+// encode returns the cursor as an opaque string for --continuation.
+func (c uploadsCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeUploadsCursor parses a --continuation value.
+func decodeUploadsCursor(s string) (uploadsCursor, error) {
+	var cursor uploadsCursor
+	if s == "" {
+		return cursor, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, errors.New("invalid --continuation value")
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Skip < 0 || cursor.Listed < 0 {
+		return cursor, errors.New("invalid --continuation value")
+	}
+	return cursor, nil
+}
+
+func newChannelCmd() *cobra.Command {
+	opts := &channelOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "channel <url>",
+		Short: "Show channel info and list its uploads",
+		Long: `Show a YouTube channel's details and list its uploads, newest first.
+
+Channel URLs of every form are accepted (/channel/ID, /@handle, /c/name
+and /user/name); handles and custom names are resolved to the channel ID.
+
+Uploads are listed in pages of --limit videos. When more remain, a
+continuation value is printed; pass it with --continuation to list the next page.`,
+		Example: `  ytdl channel https://www.youtube.com/@handle
+  ytdl channel https://www.youtube.com/@handle --limit 100
+  ytdl channel https://www.youtube.com/@handle --continuation eyJ0Ijoi...
+  ytdl channel https://www.youtube.com/channel/CHANNEL_ID --json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newHTTPClient(cmd)
+			if err != nil {
+				return WrapError(err)
+			}
+			fetcher := &youtube.ChannelFetcher{Client: client}
+			if err := runChannelWithFetcher(cmd.Context(), cmd.OutOrStdout(), args[0], opts, fetcher); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&opts.limit, "limit", defaultChannelLimit, "Maximum number of uploads to list (0 lists all)")
+	cmd.Flags().StringVar(&opts.continuation, "continuation", "", "Continue a previous listing from the value it printed")
+	cmd.Flags().BoolVar(&opts.json, "json", false, "Print the channel as JSON")
+
+	return cmd
+}
+
+// runChannelWithFetcher implements the channel command logic with a configurable fetcher.
+func runChannelWithFetcher(ctx context.Context, w io.Writer, urlStr string, opts *channelOptions, fetcher *youtube.ChannelFetcher) error {
+	if opts.limit < 0 {
+		return errors.New("--limit cannot be negative")
+	}
+	cursor, err := decodeUploadsCursor(opts.continuation)
+	if err != nil {
+		return err
+	}
+
+	query, err := youtube.ResolveQueryContext(ctx, urlStr, youtube.NewURLExpander(fetcher.Client))
+	if err != nil || query.Type != youtube.QueryTypeChannel {
+		return fmt.Errorf("invalid channel URL or ID: %w", youtube.ErrInvalidChannelID)
+	}
+
+	channel, err := fetcher.Fetch(ctx, query.Channel)
+	if err != nil {
+		return fmt.Errorf("failed to fetch channel: %w", err)
+	}
+
+	uploads, next, err := listUploads(ctx, fetcher.Uploads(), channel.UploadsPlaylistID(), cursor, opts.limit)
+	if err != nil {
+		return fmt.Errorf("failed to list uploads: %w", err)
+	}
+
+	if opts.json {
+		return printChannelJSON(w, channel, uploads, next)
+	}
+
+	// The channel details were shown with the first page
+	if opts.continuation == "" {
+		_, _ = fmt.Fprintf(w, "Channel:     %s\n", channel.Title)
+		_, _ = fmt.Fprintf(w, "ID:          %s\n", channel.ID)
+		if channel.Handle != "" {
+			_, _ = fmt.Fprintf(w, "Handle:      @%s\n", channel.Handle)
+		}
+		if channel.SubscriberCountText != "" {
+			_, _ = fmt.Fprintf(w, "Subscribers: %s\n", channel.SubscriberCountText)
+		}
+		_, _ = fmt.Fprintf(w, "URL:         %s\n", channel.URL())
+		if channel.Description != "" {
+			_, _ = fmt.Fprintf(w, "\nDescription:\n  %s\n", strings.ReplaceAll(channel.Description, "\n", "\n  "))
+		}
+		_, _ = fmt.Fprintf(w, "\nUploads:\n")
+	}
+
+	if len(uploads) == 0 {
+		_, _ = fmt.Fprintf(w, "  (no uploads)\n")
+	}
+	width := len(fmt.Sprint(cursor.Listed + len(uploads)))
+	for i := range uploads {
+		v := &uploads[i]
+		_, _ = fmt.Fprintf(w, "  %*d. %s  %8s  %s\n", width, v.Index, v.ID, v.DurationString(), v.Title)
+	}
+
+	if next != "" {
+		_, _ = fmt.Fprintf(w, "\nMore uploads available. Continue with:\n  ytdl channel %s --continuation %s\n", urlStr, next)
+	}
+	return nil
+}
+
+// listUploads lists up to limit videos of the uploads playlist starting at cursor
+// (all remaining videos if limit is 0). Videos are numbered across pages.
+// Returns the encoded cursor for the next video, or "" when the listing is complete.
+func listUploads(
+	ctx context.Context,
+	fetcher *youtube.PlaylistFetcher,
+	uploadsID youtube.PlaylistID,
+	cursor uploadsCursor,
+	limit int,
+) ([]youtube.PlaylistVideo, string, error) {
+	var page *youtube.PlaylistPage
+	var err error
+	if cursor.Token == "" {
+		page, err = fetcher.FetchPage(ctx, uploadsID)
+	} else {
+		page, err = fetcher.FetchContinuation(ctx, cursor.Token)
+	}
+	if errors.Is(err, youtube.ErrPlaylistUnavailable) {
+		// Channels without public uploads have no uploads playlist
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	var videos []youtube.PlaylistVideo
+	token, skip, listed := cursor.Token, cursor.Skip, cursor.Listed
+	for {
+		for i := skip; i < len(page.Videos); i++ {
+			if limit > 0 && len(videos) == limit {
+				return videos, uploadsCursor{Token: token, Skip: i, Listed: listed}.encode(), nil
+			}
+			v := page.Videos[i]
+			listed++
+			v.Index = listed
+			videos = append(videos, v)
+		}
+
+		if !page.HasMore() {
+			return videos, "", nil
+		}
+		if limit > 0 && len(videos) == limit {
+			return videos, uploadsCursor{Token: page.Continuation, Listed: listed}.encode(), nil
+		}
+
+		token, skip = page.Continuation, 0
+		page, err = fetcher.NextPage(ctx, page)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+}
+
+// printChannelJSON writes the channel and the listed uploads to w as JSON.
+func printChannelJSON(w io.Writer, channel *youtube.Channel, uploads []youtube.PlaylistVideo, next string) error {
+	listing := channelListing{
+		ID:                  channel.ID,
+		Title:               channel.Title,
+		Handle:              channel.Handle,
+		Description:         channel.Description,
+		SubscriberCount:     channel.SubscriberCount,
+		SubscriberCountText: channel.SubscriberCountText,
+		URL:                 channel.URL(),
+		Uploads:             make([]playlistEntry, 0, len(uploads)),
+		Continuation:        next,
+	}
+	for i := range uploads {
+		v := &uploads[i]
+		listing.Uploads = append(listing.Uploads, playlistEntry{
+			Index:           v.Index,
+			ID:              v.ID,
+			Title:           v.Title,
+			Author:          v.Author.Name,
+			DurationSeconds: v.DurationSeconds,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(listing)
+}
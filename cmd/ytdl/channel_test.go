@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+const testChannelURL = "https://www.youtube.com/@testchannel"
+
+// newChannelCmdTestServer serves a channel page and an uploads playlist of five
+// videos split over two pages: three on the playlist page and two from the browse endpoint.
+func newChannelCmdTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	video := func(n int) string {
+		return fmt.Sprintf(`{"playlistVideoRenderer":{"videoId":"video%d","title":{"runs":[{"text":"Upload %d"}]},"lengthSeconds":"%d"}}`, n, n, n*60)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/@testchannel":
+			data := `{"metadata":{"channelMetadataRenderer":{"title":"Test Channel","description":"Line one\nLine two",` +
+				`"externalId":"UCuAXFkgsw1L7xaCfnd5JJOw","vanityChannelUrl":"http://www.youtube.com/@testchannel"}},` +
+				`"header":{"c4TabbedHeaderRenderer":{"subscriberCountText":{"simpleText":"1.2K subscribers"}}}}`
+			_, _ = w.Write([]byte(`<script>var ytInitialData = ` + data + `;</script>`))
+		case "/playlist":
+			if r.URL.Query().Get("list") != "UUuAXFkgsw1L7xaCfnd5JJOw" {
+				http.NotFound(w, r)
+				return
+			}
+			data := `{"header":{"playlistHeaderRenderer":{"title":{"simpleText":"Uploads from Test Channel"}}},` +
+				`"contents":{"twoColumnBrowseResultsRenderer":{"tabs":[{"tabRenderer":{"content":{"sectionListRenderer":{"contents":[{"itemSectionRenderer":{"contents":[{"playlistVideoListRenderer":{"contents":[` +
+				video(1) + `,` + video(2) + `,` + video(3) + `,{"continuationItemRenderer":{"continuationEndpoint":{"continuationCommand":{"token":"page2"}}}}` +
+				`]}}]}}]}}}}]}}}`
+			_, _ = w.Write([]byte(`<script>var ytInitialData = ` + data + `;</script>`))
+		case "/youtubei/v1/browse":
+			_, _ = w.Write([]byte(`{"onResponseReceivedActions":[{"appendContinuationItemsAction":{"continuationItems":[` +
+				video(4) + `,` + video(5) + `]}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// continuationPattern extracts the continuation value printed by the channel command.
+var continuationPattern = regexp.MustCompile(`--continuation (\S+)`)
+
+func TestChannelCommandExists(t *testing.T) {
+	rootCmd := newRootCmd()
+	channelCmd, _, err := rootCmd.Find([]string{"channel"})
+	if err != nil {
+		t.Fatalf("channel command not found: %v", err)
+	}
+	if channelCmd.Use != "channel <url>" {
+		t.Errorf("expected Use to be 'channel <url>', got %q", channelCmd.Use)
+	}
+	for _, name := range []string{"limit", "continuation", "json"} {
+		if channelCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag", name)
+		}
+	}
+}
+
+func TestChannelCommandShowsInfoAndUploads(t *testing.T) {
+	server := newChannelCmdTestServer(t)
+	fetcher := &youtube.ChannelFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	buf := new(bytes.Buffer)
+	if err := runChannelWithFetcher(context.Background(), buf, testChannelURL, &channelOptions{}, fetcher); err != nil {
+		t.Fatalf("channel command failed: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		"Channel:     Test Channel",
+		"ID:          UCuAXFkgsw1L7xaCfnd5JJOw",
+		"Handle:      @testchannel",
+		"Subscribers: 1.2K subscribers",
+		"  Line one\n  Line two",
+		"1. video1      1:00  Upload 1",
+		"5. video5      5:00  Upload 5",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "--continuation") {
+		t.Error("a complete listing should not print a continuation")
+	}
+}
+
+func TestChannelCommandPaginates(t *testing.T) {
+	server := newChannelCmdTestServer(t)
+	fetcher := &youtube.ChannelFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	// Pages of two cut YouTube's pages of three in the middle
+	var listed []string
+	continuation := ""
+	for range 4 {
+		buf := new(bytes.Buffer)
+		opts := &channelOptions{limit: 2, continuation: continuation}
+		if err := runChannelWithFetcher(context.Background(), buf, testChannelURL, opts, fetcher); err != nil {
+			t.Fatalf("channel command failed: %v", err)
+		}
+		listed = append(listed, regexp.MustCompile(`\d+\. video\d`).FindAllString(buf.String(), -1)...)
+
+		m := continuationPattern.FindStringSubmatch(buf.String())
+		if m == nil {
+			break
+		}
+		if continuation != "" && strings.Contains(buf.String(), "Channel:") {
+			t.Error("continued listings should not repeat the channel details")
+		}
+		continuation = m[1]
+	}
+
+	want := "1. video1,2. video2,3. video3,4. video4,5. video5"
+	if got := strings.Join(listed, ","); got != want {
+		t.Errorf("listed %q, want %q", got, want)
+	}
+}
+
+func TestChannelCommandJSON(t *testing.T) {
+	server := newChannelCmdTestServer(t)
+	fetcher := &youtube.ChannelFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	buf := new(bytes.Buffer)
+	if err := runChannelWithFetcher(context.Background(), buf, testChannelURL, &channelOptions{limit: 4, json: true}, fetcher); err != nil {
+		t.Fatalf("channel command failed: %v", err)
+	}
+
+	var listing channelListing
+	if err := json.Unmarshal(buf.Bytes(), &listing); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	if listing.ID != "UCuAXFkgsw1L7xaCfnd5JJOw" || listing.SubscriberCount != 1200 || listing.Handle != "testchannel" {
+		t.Errorf("listing = %+v", listing)
+	}
+	if len(listing.Uploads) != 4 || listing.Uploads[3].ID != "video4" || listing.Uploads[3].DurationSeconds != 240 {
+		t.Errorf("uploads = %+v", listing.Uploads)
+	}
+	if listing.Continuation == "" {
+		t.Error("expected a continuation for the remaining upload")
+	}
+}
+
+func TestChannelCommandRejectsInvalidInput(t *testing.T) {
+	fetcher := &youtube.ChannelFetcher{Client: http.DefaultClient}
+
+	err := runChannelWithFetcher(context.Background(), new(bytes.Buffer), "https://www.youtube.com/watch?v=dQw4w9WgXcQ", &channelOptions{}, fetcher)
+	if !errors.Is(err, youtube.ErrInvalidChannelID) {
+		t.Errorf("expected ErrInvalidChannelID, got %v", err)
+	}
+
+	err = runChannelWithFetcher(context.Background(), new(bytes.Buffer), testChannelURL, &channelOptions{continuation: "not base64!"}, fetcher)
+	if err == nil || !strings.Contains(err.Error(), "--continuation") {
+		t.Errorf("expected invalid continuation error, got %v", err)
+	}
+
+	err = runChannelWithFetcher(context.Background(), new(bytes.Buffer), testChannelURL, &channelOptions{limit: -1}, fetcher)
+	if err == nil {
+		t.Error("expected error for negative limit")
+	}
+}
+
+func TestUploadsCursor_RoundTrip(t *testing.T) {
+	cursor := uploadsCursor{Token: "abc", Skip: 2, Listed: 40}
+	got, err := decodeUploadsCursor(cursor.encode())
+	if err != nil || got != cursor {
+		t.Errorf("decode(encode()) = %+v, %v; want %+v", got, err, cursor)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// defaultCommentLimit is the number of comments fetched when --limit isn't given.
+const defaultCommentLimit = 100
+
+// commentsOptions holds the flags of the comments command.
+type commentsOptions struct {
+	limit   int
+	replies bool
+	json    bool
+}
+
+// commentEntry is the JSON form of a comment printed with --json.
+type commentEntry struct {
+	ID              string `json:"id"`
+	ParentID        string `json:"parent_id,omitempty"`
+	Author          string `json:"author"`
+	AuthorChannelID string `json:"author_channel_id,omitempty"`
+	Text            string `json:"text"`
+	Published       string `json:"published,omitempty"`
+	LikeCount       int64  `json:"like_count"`
+	ReplyCount      int    `json:"reply_count,omitempty"`
+}
+
+func newCommentsCmd() *cobra.Command {
+	opts := &commentsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "comments <url>",
+		Short: "Fetch the comments of a video",
+		Long: `Fetch the comments of a YouTube video in the order YouTube shows them.
+
+Use --replies to also fetch the replies of each comment; they are listed
+after the comment they answer. --limit counts replies too.
+
+With --json every comment is printed as a JSON object on its own line
+(JSON Lines) as soon as it is fetched, which suits archiving large comment sections.`,
+		Example: `  ytdl comments https://www.youtube.com/watch?v=VIDEO_ID
+  ytdl comments VIDEO_ID --limit 20 --replies
+  ytdl comments VIDEO_ID --limit 0 --replies --json > comments.jsonl`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newHTTPClient(cmd)
+			if err != nil {
+				return WrapError(err)
+			}
+			fetcher := &youtube.CommentFetcher{Client: client}
+			if err := runCommentsWithFetcher(cmd.Context(), cmd.OutOrStdout(), args[0], opts, fetcher); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&opts.limit, "limit", defaultCommentLimit, "Maximum number of comments to fetch, including replies (0 fetches all)")
+	cmd.Flags().BoolVar(&opts.replies, "replies", false, "Also fetch replies to comments")
+	cmd.Flags().BoolVar(&opts.json, "json", false, "Print comments as JSON Lines")
+
+	return cmd
+}
+
+// runCommentsWithFetcher implements the comments command logic with a configurable fetcher.
+func runCommentsWithFetcher(ctx context.Context, w io.Writer, urlStr string, opts *commentsOptions, fetcher *youtube.CommentFetcher) error {
+	if opts.limit < 0 {
+		return errors.New("--limit cannot be negative")
+	}
+
+	query, err := youtube.ResolveQueryContext(ctx, urlStr, youtube.NewURLExpander(fetcher.Client))
+	if err != nil || query.Type != youtube.QueryTypeVideo {
+		return fmt.Errorf("invalid video URL or ID: %w", youtube.ErrInvalidVideoID)
+	}
+
+	emit := printComment
+	if opts.json {
+		encoder := json.NewEncoder(w)
+		emit = func(_ io.Writer, c *youtube.Comment) error {
+			return encoder.Encode(newCommentEntry(c))
+		}
+	}
+
+	count := 0
+	err = fetcher.Fetch(ctx, query.VideoID, youtube.CommentOptions{Limit: opts.limit, Replies: opts.replies}, func(c youtube.Comment) error {
+		count++
+		return emit(w, &c)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch comments: %w", err)
+	}
+
+	if count == 0 && !opts.json {
+		_, _ = fmt.Fprintf(w, "No comments.\n")
+	}
+	return nil
+}
+
+// printComment writes a comment to w as text. Replies are indented under their comment.
+func printComment(w io.Writer, c *youtube.Comment) error {
+	indent := ""
+	if c.IsReply() {
+		indent = "    "
+	}
+
+	details := []string{}
+	if c.PublishedText != "" {
+		details = append(details, c.PublishedText)
+	}
+	details = append(details, fmt.Sprintf("%d likes", c.LikeCount))
+	if c.ReplyCount > 0 {
+		details = append(details, fmt.Sprintf("%d replies", c.ReplyCount))
+	}
+
+	_, err := fmt.Fprintf(w, "%s%s (%s)\n%s  %s\n\n", indent, c.Author, strings.Join(details, ", "),
+		indent, strings.ReplaceAll(c.Text, "\n", "\n  "+indent))
+	return err
+}
+
+// newCommentEntry converts a comment to its JSON form.
+func newCommentEntry(c *youtube.Comment) commentEntry {
+	return commentEntry{
+		ID:              c.ID,
+		ParentID:        c.ParentID,
+		Author:          c.Author,
+		AuthorChannelID: c.AuthorChannelID,
+		Text:            c.Text,
+		Published:       c.PublishedText,
+		LikeCount:       c.LikeCount,
+		ReplyCount:      c.ReplyCount,
+	}
+}
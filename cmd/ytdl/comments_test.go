@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// newCommentsCmdTestServer serves a video with two comments, the first of which has a reply.
+func newCommentsCmdTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	comment := func(id, text, likes string, replies int) string {
+		data, _ := json.Marshal(map[string]any{
+			"commentId":         id,
+			"contentText":       map[string]any{"runs": []map[string]string{{"text": text}}},
+			"authorText":        map[string]string{"simpleText": "@" + id},
+			"publishedTimeText": map[string]any{"runs": []map[string]string{{"text": "3 hours ago"}}},
+			"voteCount":         map[string]string{"simpleText": likes},
+			"replyCount":        replies,
+		})
+		return string(data)
+	}
+	continuation := func(token string) string {
+		return `{"continuationItemRenderer":{"continuationEndpoint":{"continuationCommand":{"token":"` + token + `"}}}}`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/watch":
+			_, _ = w.Write([]byte(`<script>var ytInitialData = {"contents":[{"itemSectionRenderer":{"sectionIdentifier":"comment-item-section","contents":[` +
+				continuation("comments") + `]}}]};</script>`))
+		case "/youtubei/v1/next":
+			var body struct {
+				Continuation string `json:"continuation"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body.Continuation == "replies" {
+				_, _ = w.Write([]byte(`{"onResponseReceivedEndpoints":[{"appendContinuationItemsAction":{"continuationItems":[` +
+					`{"commentRenderer":` + comment("reply1", "Agreed", "", 0) + `}]}}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"onResponseReceivedEndpoints":[{"reloadContinuationItemsCommand":{"continuationItems":[` +
+				`{"commentThreadRenderer":{"comment":{"commentRenderer":` + comment("first", "Great video\nThanks", "1.5K", 1) + `},` +
+				`"replies":{"commentRepliesRenderer":{"contents":[` + continuation("replies") + `]}}}},` +
+				`{"commentThreadRenderer":{"comment":{"commentRenderer":` + comment("second", "Nice", "2", 0) + `}}}]}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCommentsCommandExists(t *testing.T) {
+	rootCmd := newRootCmd()
+	commentsCmd, _, err := rootCmd.Find([]string{"comments"})
+	if err != nil {
+		t.Fatalf("comments command not found: %v", err)
+	}
+	if commentsCmd.Use != "comments <url>" {
+		t.Errorf("expected Use to be 'comments <url>', got %q", commentsCmd.Use)
+	}
+	for _, name := range []string{"limit", "replies", "json"} {
+		if commentsCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag", name)
+		}
+	}
+}
+
+func TestCommentsCommandPrintsComments(t *testing.T) {
+	server := newCommentsCmdTestServer(t)
+	fetcher := &youtube.CommentFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	buf := new(bytes.Buffer)
+	opts := &commentsOptions{replies: true}
+	if err := runCommentsWithFetcher(context.Background(), buf, "dQw4w9WgXcQ", opts, fetcher); err != nil {
+		t.Fatalf("comments command failed: %v", err)
+	}
+
+	want := "@first (3 hours ago, 1500 likes, 1 replies)\n  Great video\n  Thanks\n\n" +
+		"    @reply1 (3 hours ago, 0 likes)\n      Agreed\n\n" +
+		"@second (3 hours ago, 2 likes)\n  Nice\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestCommentsCommandJSONLines(t *testing.T) {
+	server := newCommentsCmdTestServer(t)
+	fetcher := &youtube.CommentFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	buf := new(bytes.Buffer)
+	opts := &commentsOptions{limit: 2, replies: true, json: true}
+	if err := runCommentsWithFetcher(context.Background(), buf, "https://www.youtube.com/watch?v=dQw4w9WgXcQ", opts, fetcher); err != nil {
+		t.Fatalf("comments command failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2 for --limit 2:\n%s", len(lines), buf.String())
+	}
+	var reply commentEntry
+	if err := json.Unmarshal([]byte(lines[1]), &reply); err != nil {
+		t.Fatalf("line is not JSON: %v", err)
+	}
+	if reply.ID != "reply1" || reply.ParentID != "first" || reply.Text != "Agreed" {
+		t.Errorf("reply = %+v", reply)
+	}
+}
+
+func TestCommentsCommandRejectsNonVideo(t *testing.T) {
+	fetcher := &youtube.CommentFetcher{Client: http.DefaultClient, BaseURL: "http://127.0.0.1:0"}
+	err := runCommentsWithFetcher(context.Background(), new(bytes.Buffer), "https://www.youtube.com/playlist?list=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf", &commentsOptions{}, fetcher)
+	if err == nil {
+		t.Error("expected an error for a playlist URL")
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// console describes what the terminal output goes to can display. The zero
+// console is a file or pipe: nothing is redrawn and no escape codes are written.
+type console struct {
+	// tty is set when the output is a terminal.
+	tty bool
+
+	// ansi is set when the terminal understands ANSI escape codes, which
+	// colors and redrawing lines in place need.
+	ansi bool
+
+	// color is set when colors may be used: ANSI is supported and NO_COLOR isn't set.
+	color bool
+
+	// unicode is set when the terminal can display characters beyond ASCII.
+	// Without it, output is transliterated to ASCII.
+	unicode bool
+
+	// width is the terminal's width in columns, 0 if it isn't known.
+	width int
+}
+
+// addConsoleFlags registers the global console flags on the root command.
+func addConsoleFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().Bool("ascii", false, "Print only ASCII characters, for terminals that can't display Unicode")
+}
+
+// detectConsole returns the capabilities of the terminal w writes to. Writers
+// that aren't terminals get the zero console, except that output already
+// transliterated by a consoleWriter keeps its capabilities.
+func detectConsole(w io.Writer) console {
+	if cw, ok := w.(*consoleWriter); ok {
+		return cw.console
+	}
+	f, ok := w.(interface{ Fd() uintptr })
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return console{}
+	}
+
+	c := console{tty: true}
+	c.ansi, c.unicode = setupConsole(f.Fd())
+	c.color = c.ansi && os.Getenv("NO_COLOR") == ""
+	if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+		c.width = width
+	}
+	return c
+}
+
+// barWidth returns the width of a progress bar that leaves reserved columns
+// for the text around it, at most maxWidth and at least 10 columns.
+func (c console) barWidth(maxWidth, reserved int) int {
+	if c.width == 0 {
+		return maxWidth
+	}
+	return min(maxWidth, max(10, c.width-reserved))
+}
+
+// consoleOutput returns w, wrapped to transliterate output to ASCII if it goes
+// to a terminal that can't display Unicode or --ascii is given.
+func consoleOutput(cmd *cobra.Command, w io.Writer) io.Writer {
+	if w == io.Discard {
+		return w
+	}
+	c := detectConsole(w)
+	if flagValue(cmd, "ascii") == "true" {
+		c.unicode = false
+	} else if !c.tty || c.unicode {
+		return w
+	}
+	return &consoleWriter{w: w, console: c}
+}
+
+// consoleWriter transliterates what is written to it to ASCII for a console
+// that can't display anything else. Characters split between writes are held
+// back until they are complete.
+type consoleWriter struct {
+	w       io.Writer
+	console console
+	partial []byte
+}
+
+func (cw *consoleWriter) Write(p []byte) (int, error) {
+	data := append(cw.partial, p...)
+	cw.partial = nil
+	// Keep an incomplete character at the end for the next write
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cw.partial = append([]byte(nil), data[i:]...)
+				data = data[:i]
+			}
+			break
+		}
+	}
+	if _, err := io.WriteString(cw.w, asciiText(string(data))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Fd returns the file descriptor of the underlying writer, so the output is
+// still recognized as a terminal.
+func (cw *consoleWriter) Fd() uintptr {
+	if f, ok := cw.w.(interface{ Fd() uintptr }); ok {
+		return f.Fd()
+	}
+	return ^uintptr(0)
+}
+
+// asciiReplacements spells out symbols ytdl prints, and common punctuation in
+// titles, for consoles that only display ASCII.
+var asciiReplacements = map[rune]string{
+	'≤': "<=", '≥': ">=", '…': "...", '×': "x", '•': "*", '→': "->", '←': "<-",
+	'‘': "'", '’': "'", '“': `"`, '”': `"`, '–': "-", '—': "-", '\u00a0': " ",
+	'ß': "ss", 'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE", 'ø': "o", 'Ø': "O", 'ł': "l", 'Ł': "L",
+}
+
+// asciiText transliterates s to ASCII: accents are dropped from letters, known
+// symbols are spelled out and any other character becomes a question mark, as
+// a console using a legacy code page would show it.
+func asciiText(s string) string {
+	if stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s); err == nil {
+		s = stripped
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		switch {
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+		case asciiReplacements[r] != "":
+			b.WriteString(asciiReplacements[r])
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// displayText makes a title or file name safe to print on one line: line breaks
+// and other control characters are replaced with spaces, so a carriage return in
+// a name can't overwrite the line or break the progress drawn under it.
+func displayText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
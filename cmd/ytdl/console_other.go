@@ -0,0 +1,22 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// setupConsole reports whether the terminal fd writes to supports ANSI escape
+// codes, which every terminal but TERM=dumb does, and whether the locale's
+// character set is UTF-8. Without a locale, UTF-8 is assumed.
+func setupConsole(uintptr) (ansi, unicode bool) {
+	ansi = os.Getenv("TERM") != "dumb"
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			locale = strings.ToLower(locale)
+			return ansi, strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+		}
+	}
+	return ansi, true
+}
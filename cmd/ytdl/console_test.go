@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestAsciiText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"≤ 720p", "<= 720p"},
+		{"Café – Ünïcode…", "Cafe - Unicode..."},
+		{"“Straße”", `"Strasse"`},
+		{"東京 live", "?? live"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		if got := asciiText(tt.in); got != tt.want {
+			t.Errorf("asciiText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestConsoleWriter_SplitCharacter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := &consoleWriter{w: buf}
+	glyph := []byte("≤")
+
+	for _, chunk := range [][]byte{[]byte("up to "), glyph[:1], glyph[1:], []byte(" 1080p")} {
+		if n, err := w.Write(chunk); err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	if got := buf.String(); got != "up to <= 1080p" {
+		t.Errorf("output = %q, want %q", got, "up to <= 1080p")
+	}
+}
+
+func TestConsoleOutput(t *testing.T) {
+	cmd := &cobra.Command{}
+	addConsoleFlags(cmd)
+	buf := new(bytes.Buffer)
+
+	if got := consoleOutput(cmd, buf); got != buf {
+		t.Errorf("consoleOutput() wrapped a writer that isn't a terminal")
+	}
+
+	if err := cmd.PersistentFlags().Set("ascii", "true"); err != nil {
+		t.Fatal(err)
+	}
+	w := consoleOutput(cmd, buf)
+	if _, ok := w.(*consoleWriter); !ok {
+		t.Fatalf("consoleOutput() with --ascii = %T, want *consoleWriter", w)
+	}
+	if c := detectConsole(w); c.unicode || c.tty {
+		t.Errorf("detectConsole() = %+v, want a non-terminal without Unicode", c)
+	}
+	_, _ = w.Write([]byte("Selected quality: ≥ 720p\n"))
+	if got := buf.String(); got != "Selected quality: >= 720p\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestConsole_BarWidth(t *testing.T) {
+	tests := []struct {
+		width, want int
+	}{
+		{0, 40},
+		{200, 40},
+		{80, 30},
+		{40, 10},
+	}
+	for _, tt := range tests {
+		if got := (console{width: tt.width}).barWidth(40, 50); got != tt.want {
+			t.Errorf("barWidth() at %d columns = %d, want %d", tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayText(t *testing.T) {
+	if got := displayText("line one\r\nline\ttwo\x1b[2J"); got != "line one  line two [2J" {
+		t.Errorf("displayText() = %q", got)
+	}
+}
+
+func TestSetupConsole_Locale(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows consoles don't use the locale")
+	}
+	tests := []struct {
+		term, lcAll, lang string
+		ansi, unicode     bool
+	}{
+		{"xterm-256color", "", "en_US.UTF-8", true, true},
+		{"xterm", "", "", true, true},
+		{"xterm", "C", "en_US.UTF-8", true, false},
+		{"dumb", "", "de_DE.ISO-8859-1", false, false},
+		{"linux", "ru_RU.utf8", "", true, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("TERM", tt.term)
+		t.Setenv("LC_ALL", tt.lcAll)
+		t.Setenv("LC_CTYPE", "")
+		t.Setenv("LANG", tt.lang)
+		if ansi, unicode := setupConsole(0); ansi != tt.ansi || unicode != tt.unicode {
+			t.Errorf("setupConsole() with TERM=%q LC_ALL=%q LANG=%q = %v, %v, want %v, %v",
+				tt.term, tt.lcAll, tt.lang, ansi, unicode, tt.ansi, tt.unicode)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package main
+
+import "golang.org/x/sys/windows"
+
+// utf8CodePage is the Windows code page number of UTF-8.
+const utf8CodePage = 65001
+
+// setupConsole turns on ANSI escape code processing for the console fd writes
+// to and reports whether it is supported and whether the console can display
+// Unicode. Consoles before Windows 10 support neither: their raster fonts only
+// have the characters of the console's code page.
+func setupConsole(fd uintptr) (ansi, unicode bool) {
+	h := windows.Handle(fd)
+	var mode uint32
+	if err := windows.GetConsoleMode(h, &mode); err != nil {
+		return false, false
+	}
+	ansi = mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 ||
+		windows.SetConsoleMode(h, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+
+	// Go writes to consoles in UTF-16, so the code page doesn't matter to a
+	// console with Unicode fonts, which every console supporting ANSI codes has
+	cp, err := windows.GetConsoleOutputCP()
+	return ansi, ansi || (err == nil && cp == utf8CodePage)
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// doctorVideoID is the video whose watch page and player the doctor command
+// fetches to check that streams can be deciphered.
+const doctorVideoID = "dQw4w9WgXcQ"
+
+// doctorCheckTimeout limits how long each network check may take.
+const doctorCheckTimeout = 15 * time.Second
+
+// doctorStatus is the outcome of a doctor check.
+type doctorStatus string
+
+const (
+	doctorPass doctorStatus = "PASS"
+	doctorWarn doctorStatus = "WARN"
+	doctorFail doctorStatus = "FAIL"
+	doctorSkip doctorStatus = "SKIP"
+)
+
+// doctorResult is the outcome of one check, with a hint on how to fix it
+// unless it passed.
+type doctorResult struct {
+	name   string
+	status doctorStatus
+	detail string
+	hint   string
+}
+
+// doctorHost is a host the doctor command checks it can reach.
+type doctorHost struct {
+	name string
+	url  string
+}
+
+// doctorHosts are the hosts downloads need: YouTube for pages and googlevideo.com for streams.
+var doctorHosts = []doctorHost{
+	{name: "youtube.com", url: "https://www.youtube.com/"},
+	{name: "googlevideo.com", url: "https://redirector.googlevideo.com/"},
+}
+
+// doctorOptions holds the flags of the doctor command.
+type doctorOptions struct {
+	output  string
+	cookies string
+}
+
+// doctor checks the environment downloads run in.
+type doctor struct {
+	client  *http.Client
+	fetcher *youtube.WatchPageFetcher
+	players *youtube.PlayerFetcher
+	hosts   []doctorHost
+	opts    *doctorOptions
+	now     func() time.Time
+}
+
+func newDoctorCmd() *cobra.Command {
+	opts := &doctorOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that everything downloads need is working",
+		Long: `Check the environment ytdl runs in and print what passed, what failed and
+how to fix it:
+
+  - FFmpeg is installed and runs, and whether ffprobe is there for --verify-output
+  - youtube.com and googlevideo.com, where streams come from, can be reached
+  - the --cookies file can be read and has unexpired YouTube cookies
+  - a watch page and its player can be fetched, and the signature cipher
+    extracted from the player
+  - the --output directory can be written to
+
+Network settings such as --proxy and --force-ipv4 apply to the checks, so
+they can be tried out here. The command fails if any check fails.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := newHTTPClient(cmd)
+			if err != nil {
+				return WrapError(err)
+			}
+			fetcher, err := newWatchPageFetcher(cmd, client)
+			if err != nil {
+				return WrapError(err)
+			}
+			// Cached pages and players would hide what the network returns now
+			fetcher.Cache = nil
+			d := &doctor{
+				client:  client,
+				fetcher: fetcher,
+				players: &youtube.PlayerFetcher{Client: client},
+				hosts:   doctorHosts,
+				opts:    opts,
+				now:     time.Now,
+			}
+			return d.run(cmd.Context(), cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", ".", "Output directory to check")
+	cmd.Flags().StringVar(&opts.cookies, "cookies", "", "Path to a Netscape format cookie file to check")
+
+	return cmd
+}
+
+// run runs every check, printing each result as it finishes, and returns an
+// error if any failed.
+func (d *doctor) run(ctx context.Context, w io.Writer) error {
+	checks := []func(context.Context) doctorResult{d.checkFFmpeg, d.checkFFprobe}
+	for _, host := range d.hosts {
+		checks = append(checks, func(ctx context.Context) doctorResult { return d.checkHost(ctx, host) })
+	}
+	checks = append(checks, d.checkCookies, d.checkPlayer, d.checkOutput)
+
+	failed := 0
+	for _, check := range checks {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		result := check(ctx)
+		printDoctorResult(w, result)
+		if result.status == doctorFail {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	_, _ = fmt.Fprintln(w, "\nAll checks passed")
+	return nil
+}
+
+// printDoctorResult prints a result and, unless it passed, its hint.
+func printDoctorResult(w io.Writer, r doctorResult) {
+	_, _ = fmt.Fprintf(w, "[%s] %s: %s\n", r.status, r.name, r.detail)
+	if r.status != doctorPass && r.hint != "" {
+		_, _ = fmt.Fprintf(w, "       %s\n", r.hint)
+	}
+}
+
+func (d *doctor) checkFFmpeg(ctx context.Context) doctorResult {
+	result := doctorResult{name: "FFmpeg"}
+	path, err := ffmpeg.GetCliFilePath()
+	if err != nil {
+		result.status, result.detail = doctorFail, "not found"
+		result.hint = `Run "ytdl ffmpeg install", or install FFmpeg and add it to PATH. It is needed to merge separate video and audio streams.`
+		return result
+	}
+	version, err := ffmpeg.Version(ctx)
+	if err != nil {
+		result.status, result.detail = doctorFail, fmt.Sprintf("%s doesn't run: %v", path, err)
+		result.hint = `Replace it with a working build, for example with "ytdl ffmpeg install".`
+		return result
+	}
+	result.status, result.detail = doctorPass, fmt.Sprintf("version %s (%s)", version, path)
+	return result
+}
+
+func (d *doctor) checkFFprobe(context.Context) doctorResult {
+	result := doctorResult{name: "ffprobe"}
+	path, err := ffmpeg.GetProbeFilePath()
+	if err != nil {
+		result.status, result.detail = doctorWarn, "not found"
+		result.hint = "ffprobe is only needed for --verify-output. It comes with most FFmpeg packages."
+		return result
+	}
+	result.status, result.detail = doctorPass, path
+	return result
+}
+
+// checkHost checks that host answers HTTP requests. Any response will do: the
+// stream host answers its root with an error, but only a failed connection
+// means streams can't be downloaded.
+func (d *doctor) checkHost(ctx context.Context, host doctorHost) doctorResult {
+	result := doctorResult{name: "Network (" + host.name + ")"}
+	ctx, cancel := context.WithTimeout(ctx, doctorCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, host.url, nil)
+	if err != nil {
+		result.status, result.detail = doctorFail, err.Error()
+		return result
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		result.status, result.detail = doctorFail, err.Error()
+		result.hint = "Check the internet connection, firewall and --proxy settings, or try --force-ipv4."
+		return result
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		result.status, result.detail = doctorWarn, "rate limited ("+resp.Status+")"
+		result.hint = "This IP address is being rate limited. Wait a while, use --sleep-requests, or try another network."
+		return result
+	}
+	result.status, result.detail = doctorPass, "reachable ("+resp.Status+")"
+	return result
+}
+
+func (d *doctor) checkCookies(context.Context) doctorResult {
+	result := doctorResult{name: "Cookies"}
+	if d.opts.cookies == "" {
+		result.status, result.detail = doctorSkip, "no --cookies file given"
+		return result
+	}
+
+	cookies, err := youtube.LoadCookiesFromFile(d.opts.cookies)
+	if err != nil {
+		result.status, result.detail = doctorFail, err.Error()
+		result.hint = "Export the cookies of a signed-in browser in Netscape format (cookies.txt)."
+		return result
+	}
+
+	now := d.now()
+	youtubeCookies, expired := 0, 0
+	for _, c := range cookies {
+		if !isYouTubeCookieDomain(c.Domain) {
+			continue
+		}
+		youtubeCookies++
+		if !c.Expires.IsZero() && c.Expires.Before(now) {
+			expired++
+		}
+	}
+	switch {
+	case youtubeCookies == 0:
+		result.status, result.detail = doctorFail, "no youtube.com cookies"
+		result.hint = "Export the cookies while signed in to youtube.com."
+	case expired == youtubeCookies:
+		result.status, result.detail = doctorFail, fmt.Sprintf("all %d youtube.com cookies have expired", youtubeCookies)
+		result.hint = "Sign in to YouTube in the browser again and export fresh cookies."
+	case expired > 0:
+		result.status, result.detail = doctorWarn, fmt.Sprintf("%d of %d youtube.com cookies have expired", expired, youtubeCookies)
+		result.hint = "Export fresh cookies if signed-in downloads fail."
+	default:
+		result.status, result.detail = doctorPass, fmt.Sprintf("%d youtube.com cookies", youtubeCookies)
+	}
+	return result
+}
+
+// isYouTubeCookieDomain reports whether a cookie with the given domain is sent to youtube.com.
+func isYouTubeCookieDomain(domain string) bool {
+	domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+	return domain == "youtube.com" || strings.HasSuffix(domain, ".youtube.com")
+}
+
+// checkPlayer fetches a watch page and its player and extracts the signature
+// cipher, the same steps as deciphering the streams of a protected video.
+func (d *doctor) checkPlayer(ctx context.Context) doctorResult {
+	result := doctorResult{name: "Player", status: doctorFail}
+	ctx, cancel := context.WithTimeout(ctx, doctorCheckTimeout)
+	defer cancel()
+
+	page, err := d.fetcher.Fetch(ctx, doctorVideoID)
+	if err != nil {
+		result.detail = "fetching watch page: " + err.Error()
+		result.hint = "Check the network checks above. A bot check may need --cookies or --po-token."
+		return result
+	}
+	playerURL, err := page.ExtractPlayerURL()
+	if err != nil {
+		result.detail = err.Error()
+		result.hint = "YouTube may have changed its watch page. Check for a newer version of ytdl."
+		return result
+	}
+	player, err := d.players.Fetch(ctx, playerURL)
+	if errors.Is(err, youtube.ErrSignatureFunctionNotFound) || (err == nil && len(player.SignatureOperations) == 0) {
+		result.detail = "no signature cipher found in player " + youtube.PlayerVersion(playerURL)
+		result.hint = "YouTube may have changed its player, so protected streams can't be downloaded. Check for a newer version of ytdl."
+		return result
+	}
+	if err != nil {
+		result.detail = err.Error()
+		result.hint = "Check the network checks above."
+		return result
+	}
+	result.status = doctorPass
+	result.detail = fmt.Sprintf("player %s, %d signature operations", player.Version, len(player.SignatureOperations))
+	return result
+}
+
+// checkOutput checks that a file can be created in the output directory. A
+// directory that doesn't exist yet is created by downloads, so the closest
+// existing parent is checked instead.
+func (d *doctor) checkOutput(context.Context) doctorResult {
+	result := doctorResult{name: "Output directory", status: doctorFail}
+	dir := d.opts.output
+	created := ""
+
+	info, err := os.Stat(dir)
+	for errors.Is(err, os.ErrNotExist) && filepath.Dir(dir) != dir {
+		created = d.opts.output
+		dir = filepath.Dir(dir)
+		info, err = os.Stat(dir)
+	}
+	switch {
+	case err != nil:
+		result.detail = err.Error()
+		return result
+	case !info.IsDir():
+		result.detail = dir + " isn't a directory"
+		result.hint = "Choose a directory with --output."
+		return result
+	}
+
+	f, err := os.CreateTemp(dir, ".ytdl-doctor-*")
+	if err != nil {
+		result.detail = err.Error()
+		result.hint = "Fix the directory's permissions, or choose one you can write to with --output."
+		return result
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+	result.status, result.detail = doctorPass, dir+" is writable"
+	if created != "" {
+		result.detail = fmt.Sprintf("%s will be created in %s, which is writable", created, dir)
+	}
+	return result
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// doctorTestPlayerJS is the part of a player that holds the signature cipher.
+const doctorTestPlayerJS = `var Xy={ab:function(a){a.reverse()},cd:function(a,b){a.splice(0,b)}};
+zz=function(a){a=a.split("");Xy.ab(a,17);Xy.cd(a,2);return a.join("")};`
+
+// newDoctorTestServer serves a watch page, the player it names and, if
+// withPlayer is false, a watch page without a player instead.
+func newDoctorTestServer(t *testing.T, withPlayer bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte("ok"))
+		case "/watch":
+			if !withPlayer {
+				_, _ = w.Write([]byte("<html></html>"))
+				return
+			}
+			_, _ = w.Write([]byte(`<script>ytcfg.set({"PLAYER_JS_URL":"\/s\/player\/3d3ba064\/player_ias.vflset\/en_US\/base.js"});</script>`))
+		case "/s/player/3d3ba064/player_ias.vflset/en_US/base.js":
+			_, _ = w.Write([]byte(doctorTestPlayerJS))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestDoctor(server *httptest.Server, opts *doctorOptions) *doctor {
+	return &doctor{
+		client:  server.Client(),
+		fetcher: &youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL},
+		players: &youtube.PlayerFetcher{Client: server.Client(), BaseURL: server.URL},
+		hosts:   []doctorHost{{name: "youtube.com", url: server.URL + "/"}, {name: "googlevideo.com", url: server.URL + "/videoplayback"}},
+		opts:    opts,
+		now:     func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) },
+	}
+}
+
+// installDoctorTools puts fake ffmpeg and ffprobe executables first on PATH.
+func installDoctorTools(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake FFmpeg is a shell script")
+	}
+	dir := t.TempDir()
+	for name, script := range map[string]string{
+		"ffmpeg":  "#!/bin/sh\necho 'ffmpeg version 6.1.1 Copyright (c) 2000-2023 the FFmpeg developers'\n",
+		"ffprobe": "#!/bin/sh\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func writeDoctorCookies(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(path, []byte("# Netscape HTTP Cookie File\n"+strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDoctor_AllPass(t *testing.T) {
+	installDoctorTools(t)
+	server := newDoctorTestServer(t, true)
+	cookies := writeDoctorCookies(t, ".youtube.com\tTRUE\t/\tTRUE\t1893456000\tSID\tvalue")
+	d := newTestDoctor(server, &doctorOptions{output: t.TempDir(), cookies: cookies})
+
+	buf := new(bytes.Buffer)
+	if err := d.run(context.Background(), buf); err != nil {
+		t.Fatalf("run() error = %v\n%s", err, buf)
+	}
+	for _, want := range []string{
+		"[PASS] FFmpeg: version 6.1.1",
+		"[PASS] ffprobe: ",
+		"[PASS] Network (youtube.com): reachable (200 OK)",
+		"[PASS] Network (googlevideo.com): reachable (404 Not Found)",
+		"[PASS] Cookies: 1 youtube.com cookies",
+		"[PASS] Player: player 3d3ba064, 2 signature operations",
+		"is writable",
+		"All checks passed",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output doesn't contain %q:\n%s", want, buf)
+		}
+	}
+}
+
+func TestDoctor_Failures(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	server := newDoctorTestServer(t, false)
+	cookies := writeDoctorCookies(t, ".example.com\tTRUE\t/\tTRUE\t1893456000\tSID\tvalue")
+	output := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(output, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d := newTestDoctor(server, &doctorOptions{output: output, cookies: cookies})
+	d.hosts = []doctorHost{{name: "googlevideo.com", url: "http://127.0.0.1:1/"}}
+
+	buf := new(bytes.Buffer)
+	err := d.run(context.Background(), buf)
+	if err == nil || err.Error() != "5 of 6 checks failed" {
+		t.Errorf("run() error = %v, want 5 of 6 checks failed", err)
+	}
+	for _, want := range []string{
+		"[FAIL] FFmpeg: not found",
+		"ytdl ffmpeg install",
+		"[WARN] ffprobe: not found",
+		"[FAIL] Network (googlevideo.com): ",
+		"[FAIL] Cookies: no youtube.com cookies",
+		"[FAIL] Player: " + youtube.ErrPlayerURLNotFound.Error(),
+		"[FAIL] Output directory: " + output + " isn't a directory",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output doesn't contain %q:\n%s", want, buf)
+		}
+	}
+}
+
+func TestDoctor_CheckCookiesExpired(t *testing.T) {
+	cookies := writeDoctorCookies(t,
+		".youtube.com\tTRUE\t/\tTRUE\t1700000000\tSID\told",
+		".youtube.com\tTRUE\t/\tTRUE\t1893456000\tHSID\tnew")
+	d := &doctor{opts: &doctorOptions{cookies: cookies}, now: func() time.Time { return time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC) }}
+
+	if r := d.checkCookies(context.Background()); r.status != doctorWarn || r.detail != "1 of 2 youtube.com cookies have expired" {
+		t.Errorf("checkCookies() = %+v", r)
+	}
+	d.opts.cookies = ""
+	if r := d.checkCookies(context.Background()); r.status != doctorSkip {
+		t.Errorf("checkCookies() without a file = %+v, want skipped", r)
+	}
+}
+
+func TestDoctor_CheckOutputNotCreatedYet(t *testing.T) {
+	dir := t.TempDir()
+	d := &doctor{opts: &doctorOptions{output: filepath.Join(dir, "a", "b")}}
+	if r := d.checkOutput(context.Background()); r.status != doctorPass || !strings.Contains(r.detail, "will be created in "+dir) {
+		t.Errorf("checkOutput() = %+v", r)
+	}
+}
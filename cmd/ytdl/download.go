@@ -5,28 +5,135 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/filename"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/musicmeta"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/mux"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/notify"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/postprocess"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/storage"
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
 )
 
 type downloadOptions struct {
-	output  string
-	quality string
-	format  string
+	output       string
+	quality      string
+	format       string
+	preferCodec  string
+	maxFPS       int
+	preferHDR    bool
+	splitSize    string
+	section      string
+	recodeVideo  string
+	recodeCodec  string
+	recodeCRF    int
+	recodePreset string
+	hwAccel      string
+	remixSources bool
+	mixLimit     int
+	waitForVideo time.Duration
+	exec         []string
+	printPlan    bool
+	executePlan  string
+	batchFile    string
+	reportJSON   string
+	simulate     bool
+	searchTerms  bool
+	overwrite    download.OverwritePolicy
+	template     string
+	maxTitleLen  int
+	restrictName bool
+	asciiName    bool
+	uploadTo     string
+	verifyOutput bool
+	keepSeparate bool
+	tempDir      string
+	noFallback   bool
+	notify       notifyConfig
+
+	// splitChapters writes each chapter to its own file, named with chapterTemplate.
+	splitChapters   bool
+	chapterTemplate string
+
+	// normalizeAudio normalizes the loudness of each file to loudnessTarget LUFS.
+	normalizeAudio bool
+	loudnessTarget float64
+
+	// embedLyrics embeds the lyrics of each audio or MP4 file into its tags.
+	embedLyrics bool
+
+	// replayGain measures each audio file and writes its ReplayGain to its tags.
+	replayGain bool
+
+	// writeStoryboards saves the seek preview sheets of each video next to it,
+	// or with splitStoryboards each of their frames.
+	writeStoryboards bool
+	splitStoryboards bool
+
+	// musicMetadata names the music database the tags of each audio or MP4 file
+	// are looked up in; see musicmeta.Open.
+	musicMetadata string
+
+	// report, when set, receives the result of every video downloaded.
+	report *downloadReport
+
+	// prefetch is how many playlist videos are fetched ahead of the one downloading.
+	prefetch int
+
+	// downloadArchive is the archive file of the videos downloaded by earlier
+	// runs, which are skipped, for --download-archive.
+	downloadArchive string
+
+	// noDedup downloads a video again each time a playlist of the run lists it.
+	noDedup bool
+
+	// dedup, when set, skips the videos already downloaded in the run or archived.
+	dedup *download.Dedup
+
+	// prefetched, when set, holds the videos fetched ahead of their download.
+	prefetched *videoPrefetcher
+
+	// pipe receives the media instead of a file when output is stdoutOutput.
+	pipe io.Writer
+
+	// upload, when set, receives each download instead of the output directory.
+	upload storage.Storage
+
+	// notifier, when set, is told about every video downloaded or failed.
+	notifier notify.Notifier
+
+	// lyrics, when set, looks up the lyrics --embed-lyrics embeds.
+	lyrics postprocess.LyricsFunc
+
+	// musicProvider, when set, looks up the tags --music-metadata writes.
+	musicProvider musicmeta.Provider
+
+	// storyboards, when set, downloads the sheets --write-storyboards saves.
+	storyboards *youtube.StoryboardDownloader
 }
 
+// stdoutOutput is the --output value that streams the download to stdout.
+const stdoutOutput = "-"
+
 func newDownloadCmd() *cobra.Command {
 	opts := &downloadOptions{}
+	var (
+		policy                      string
+		noOverwrite, forceOverwrite bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "download <url>",
@@ -38,17 +145,159 @@ Supports various YouTube URL formats including:
   - Video: https://youtu.be/VIDEO_ID
   - Playlist: https://www.youtube.com/playlist?list=PLAYLIST_ID
   - Channel: https://www.youtube.com/channel/CHANNEL_ID
-  - Channel: https://www.youtube.com/@handle`,
-		Args: cobra.ExactArgs(1),
+  - Channel: https://www.youtube.com/@handle
+  - Clip: https://www.youtube.com/clip/CLIP_ID
+  - Mix: https://www.youtube.com/watch?v=VIDEO_ID&list=RDVIDEO_ID
+
+Search queries start with ?, such as "?lofi hip hop". With --default-search,
+input that isn't a URL or ID is taken as a search query without the ?.
+Searches are recognized but can't be downloaded yet.
+
+Playlist videos are downloaded in order. A video that fails is reported and
+skipped, and the download fails at the end if any video failed. Mixes are
+generated endlessly, so only the first --mix-limit videos are downloaded.
+A summary of the downloaded and failed videos, the total size and the
+average speed is printed at the end; --report-json also saves it as JSON.
+
+Use --section to keep only part of a video. The full video is downloaded
+and the section is cut out with FFmpeg without re-encoding, so the cut
+starts at the nearest keyframe before the requested start. Clip URLs
+download the clipped section automatically.
+
+Premieres and live streams that haven't started can't be downloaded yet.
+Use --wait-for-video to wait for them: the video is checked again at its
+scheduled start, or at the given interval, and downloaded once available.
+
+Existing output files are overwritten by default. Use --no-overwrite to
+skip videos that were already downloaded, or --overwrite-policy rename to
+keep the old file and save the new one with a numeric suffix. The check
+runs before any stream is requested.
+
+Use --simulate to check the format selection and output file names
+cheaply: URLs are resolved and formats picked as usual, and each output
+file is printed with its estimated size, but nothing is downloaded.
+
+Use --verify-output to check each finished file with ffprobe: its duration
+must match the video's and its codecs the downloaded streams', otherwise the
+download fails so a truncated or badly muxed file isn't mistaken for a good one.
+
+Use --notify-desktop to get a desktop notification as each video finishes or
+fails, or --notify-webhook to POST its title, status, path, size and download
+duration as JSON to a URL, for chat bots or home automation. A notification
+that can't be delivered is reported as a warning and doesn't fail the download.
+
+Use --batch-file to download a list of URLs or IDs, one per line, from a
+file or from stdin with -a -. Blank lines and lines starting with # or ;
+are skipped. A URL that fails doesn't stop the others, and a summary of
+the failures is printed at the end.
+
+Use --upload-to to send downloads to remote storage instead of the output
+directory. Each video is muxed and streamed straight to the storage, under
+its usual file name, without being saved locally:
+  - s3://bucket/prefix uploads to Amazon S3 with the credentials and region
+    from the AWS_* environment variables. Set AWS_ENDPOINT_URL_S3, or add
+    ?endpoint=URL, for S3-compatible services such as MinIO.
+  - sftp://user@host:port/dir uploads with the OpenSSH sftp client and your
+    SSH keys. The file is staged in the temp directory first.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case opts.executePlan != "":
+				return cobra.NoArgs(cmd, args)
+			case opts.batchFile != "":
+				return nil
+			default:
+				return cobra.ExactArgs(1)(cmd, args)
+			}
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if opts.overwrite, err = overwritePolicy(policy, noOverwrite, forceOverwrite); err != nil {
+				return err
+			}
+			if opts.executePlan != "" {
+				return runExecutePlan(cmd, opts.executePlan)
+			}
+			if opts.batchFile != "" {
+				return runBatchDownload(cmd, args, opts)
+			}
 			url := args[0]
 			return runDownload(cmd, url, opts)
 		},
 	}
 
-	cmd.Flags().StringVarP(&opts.output, "output", "o", ".", "Output directory for downloaded files")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", ".", "Output directory for downloaded files, or - to write to stdout")
 	cmd.Flags().StringVarP(&opts.quality, "quality", "q", "best", "Video quality (best, 1080p, 720p, 480p, 360p, audio)")
-	cmd.Flags().StringVarP(&opts.format, "format", "f", "mp4", "Output format (mp4, webm, mp3)")
+	cmd.Flags().StringVarP(&opts.format, "format", "f", "mp4", "Output format (mp4, webm, mkv, mp3, opus, ogg, flac, wav)")
+	cmd.Flags().StringVar(&opts.preferCodec, "prefer-codec", "", "Video codecs to prefer between formats of the same quality, best first (default av01,vp9,avc1)")
+	cmd.Flags().IntVar(&opts.maxFPS, "max-fps", 0, "Prefer formats up to this framerate, e.g. 30 to avoid 60fps (0 for the highest)")
+	cmd.Flags().BoolVar(&opts.preferHDR, "prefer-hdr", false, "Prefer HDR formats over SDR ones of the same quality")
+	cmd.Flags().StringVar(&opts.splitSize, "split-size", "", "Split the output into parts no larger than this size (e.g. 25M, 2G)")
+	cmd.Flags().StringVar(&opts.section, "section", "", "Keep only this time range of the video, e.g. 1:30-3:00 (requires FFmpeg)")
+	cmd.Flags().StringVar(&opts.recodeVideo, "recode-video", "", "Re-encode the video into this container after download (mp4, mkv, webm, mov)")
+	cmd.Flags().StringVar(&opts.recodeCodec, "recode-codec", "", "Video codec for --recode-video (h264, h265, vp9, av1; default depends on container)")
+	cmd.Flags().IntVar(&opts.recodeCRF, "recode-crf", 0, "Constant quality factor for --recode-video (lower is better, 0 for encoder default)")
+	cmd.Flags().StringVar(&opts.recodePreset, "recode-preset", "", "Encoder preset for --recode-video (e.g. fast, medium, slow)")
+	cmd.Flags().StringVar(&opts.hwAccel, "hwaccel", "none", "Hardware encoder for --recode-video (none, auto, nvenc, videotoolbox, qsv)")
+	cmd.Flags().BoolVar(&opts.remixSources, "with-remix-source", false, "Also download the original video when a Short remixes another video")
+	cmd.Flags().DurationVar(&opts.waitForVideo, "wait-for-video", 0, "Wait for an upcoming premiere or live stream to start, checking at least this often (e.g. 1m)")
+	cmd.Flags().IntVar(&opts.mixLimit, "mix-limit", youtube.DefaultMixLimit, "Maximum number of videos to download from a mix")
+	cmd.Flags().StringArrayVar(&opts.exec, "exec", nil, "Run a shell command on each finished file; {} is replaced with the path (repeatable)")
+	cmd.Flags().BoolVar(&opts.printPlan, "print-plan", false, "Print the resolved download plan as JSON without downloading")
+	cmd.Flags().StringVar(&opts.executePlan, "execute-plan", "", "Perform a plan file written by --print-plan instead of resolving a URL")
+	cmd.Flags().StringVarP(&opts.batchFile, "batch-file", "a", "", "Download the URLs listed in this file, one per line (- for stdin)")
+	cmd.Flags().BoolVarP(&opts.simulate, "simulate", "s", false, "Resolve the URL and pick formats, printing each output file and its estimated size, without downloading")
+	cmd.MarkFlagsMutuallyExclusive("simulate", "execute-plan")
+	cmd.Flags().BoolVar(&opts.searchTerms, "default-search", false, "Treat input that isn't a URL or ID as a search query, as if it started with ?")
+	cmd.Flags().StringVar(&opts.template, "output-template", filename.DefaultTemplate,
+		"File name template: $title, $author, $id, $uploadDate, $num and $numc; slashes create folders, as in \"$author/$uploadDate - $title\"")
+	cmd.Flags().IntVar(&opts.maxTitleLen, "max-title-length", 0, "Shorten video titles in file names to this many characters (0 for no limit)")
+	cmd.Flags().BoolVar(&opts.restrictName, "restrict-filenames", false,
+		"Make file names safe for any filesystem: normalize Unicode, drop invisible characters and replace emoji")
+	cmd.Flags().BoolVar(&opts.asciiName, "ascii-filenames", false, "Transliterate file names to ASCII, replacing other characters with underscores")
+	cmd.Flags().StringVar(&policy, "overwrite-policy", "overwrite", "What to do when an output file already exists (overwrite, skip, rename)")
+	cmd.Flags().BoolVar(&noOverwrite, "no-overwrite", false, "Skip videos whose output file already exists (same as --overwrite-policy skip)")
+	cmd.Flags().BoolVar(&forceOverwrite, "force-overwrite", false, "Replace output files that already exist (same as --overwrite-policy overwrite)")
+	cmd.MarkFlagsMutuallyExclusive("overwrite-policy", "no-overwrite", "force-overwrite")
+	cmd.Flags().StringVar(&opts.uploadTo, "upload-to", "", "Stream downloads to remote storage instead of the output directory (s3://bucket/prefix, sftp://host/dir)")
+	cmd.Flags().BoolVar(&opts.verifyOutput, "verify-output", false, "Check each finished file with ffprobe and fail if its duration or codecs don't match the streams")
+	cmd.Flags().BoolVar(&opts.normalizeAudio, "normalize-audio", false,
+		"Normalize the loudness of each file with FFmpeg's two-pass loudnorm filter (EBU R128), re-encoding the audio")
+	cmd.Flags().Float64Var(&opts.loudnessTarget, "loudness-target", ffmpeg.DefaultLoudnessTarget, "Integrated loudness in LUFS for --normalize-audio, from -70 to -5")
+	cmd.Flags().StringVar(&opts.musicMetadata, "music-metadata", "",
+		"Tag songs with the artist, album and year found by their \"Artist - Title\" in this database ("+strings.Join(musicmeta.Providers, ", ")+")")
+	cmd.Flags().BoolVar(&opts.embedLyrics, "embed-lyrics", false, "Embed the lyrics caption track, or else the description, into audio and MP4 tags")
+	cmd.Flags().BoolVar(&opts.replayGain, "replaygain", false,
+		"Measure the loudness of audio downloads with FFmpeg's ebur128 filter and write ReplayGain tags, leaving the audio untouched")
+	cmd.Flags().BoolVar(&opts.writeStoryboards, "write-storyboards", false,
+		"Save the seek preview sheets of each video to a .storyboard directory next to it")
+	cmd.Flags().BoolVar(&opts.splitStoryboards, "split-storyboards", false,
+		"Cut the seek preview sheets into one image per frame, named by its time (implies --write-storyboards)")
+	cmd.Flags().BoolVar(&opts.splitChapters, "split-chapters", false, "Also write each chapter of the video to its own file (requires FFmpeg)")
+	cmd.Flags().StringVar(&opts.chapterTemplate, "chapter-template", filename.DefaultChapterTemplate,
+		"File name template for --split-chapters: $chapterIndex and $chapterTitle, plus $title, $author, $id and $uploadDate")
+	cmd.Flags().BoolVar(&opts.noFallback, "no-fallback", false,
+		"Fail when the selected formats are refused or time out instead of falling back to the next best ones")
+	cmd.Flags().IntVar(&opts.prefetch, "prefetch", defaultPrefetch,
+		"Fetch the info of this many upcoming playlist videos while one downloads (0 to fetch each right before its download)")
+	cmd.Flags().StringVar(&opts.downloadArchive, "download-archive", "",
+		"Skip the videos listed in this file and add each downloaded video to it, in the archive format of yt-dlp")
+	cmd.Flags().BoolVar(&opts.noDedup, "no-dedup", false,
+		"Download a video again when another playlist or URL of the run lists it, instead of once")
+	cmd.Flags().BoolVar(&opts.keepSeparate, "keep-separate", false, "Also keep the video-only and audio-only streams next to the muxed file (name.video.mp4, name.audio.m4a)")
+	cmd.Flags().StringVar(&opts.tempDir, "temp-dir", "",
+		"Download separate video and audio streams to this directory before muxing (default: a hidden directory next to the output)")
+	cmd.Flags().StringVar(&opts.notify.Webhook, "notify-webhook", "", "POST a JSON summary of each finished or failed download to this URL")
+	cmd.Flags().BoolVar(&opts.notify.Desktop, "notify-desktop", false, "Show a desktop notification when each download finishes or fails")
+	cmd.Flags().StringVar(&opts.reportJSON, "report-json", "", "Also write the summary of a playlist or batch download to this file as JSON")
+	cmd.MarkFlagsMutuallyExclusive("execute-plan", "batch-file")
+
+	// Accept yt-dlp's name for --section
+	cmd.Flags().SetNormalizeFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "download-sections" {
+			name = "section"
+		}
+		return pflag.NormalizedName(name)
+	})
 
 	return cmd
 }
@@ -58,13 +307,34 @@ func runDownload(cmd *cobra.Command, url string, opts *downloadOptions) error {
 		return errors.New("URL is required")
 	}
 
+	client, err := newHTTPClient(cmd)
+	if err != nil {
+		return WrapError(err)
+	}
+
 	// Create default dependencies
-	fetcher := &youtube.WatchPageFetcher{
-		Client: http.DefaultClient,
+	page, err := newWatchPageFetcher(cmd, client)
+	if err != nil {
+		return WrapError(err)
+	}
+	downloader, err := newStreamDownloader(cmd, client, download.WithEventListener(logEvents(loggerFrom(cmd.Context()))))
+	if err != nil {
+		return WrapError(err)
+	}
+	if err := openUploadStorage(opts, client); err != nil {
+		return err
+	}
+	if opts.notifier, err = newNotifier(opts.notify, client); err != nil {
+		return err
+	}
+
+	// When streaming to stdout, status output moves to stderr so it doesn't corrupt the media
+	w := statusWriter(cmd)
+	if opts.output == stdoutOutput {
+		opts.pipe = cmd.OutOrStdout()
 	}
-	downloader := download.NewDownloader(http.DefaultClient)
 
-	err := runDownloadWithDeps(cmd.Context(), cmd.OutOrStdout(), url, opts, fetcher, downloader, ffmpeg.MuxStreamsWithContext)
+	err = runDownloadWithDeps(cmd.Context(), w, url, opts, newSources(page), downloader, muxStreams)
 	if err != nil {
 		// Wrap the error with user-friendly message
 		return WrapError(err)
@@ -72,8 +342,51 @@ func runDownload(cmd *cobra.Command, url string, opts *downloadOptions) error {
 	return nil
 }
 
+func runExecutePlan(cmd *cobra.Command, path string) error {
+	client, err := newHTTPClient(cmd)
+	if err != nil {
+		return WrapError(err)
+	}
+
+	page, err := newWatchPageFetcher(cmd, client)
+	if err != nil {
+		return WrapError(err)
+	}
+	downloader, err := newStreamDownloader(cmd, client, download.WithEventListener(logEvents(loggerFrom(cmd.Context()))))
+	if err != nil {
+		return WrapError(err)
+	}
+
+	if err := executePlan(cmd.Context(), statusWriter(cmd), path, newSources(page), downloader, muxStreams); err != nil {
+		return WrapError(err)
+	}
+	return nil
+}
+
 // MuxerFunc is a function type for muxing video and audio streams.
-type MuxerFunc func(ctx context.Context, videoPath, audioPath, outputPath string) error
+// duration is the media length, used together with progress to report muxing progress.
+type MuxerFunc func(ctx context.Context, videoPath, audioPath, outputPath string, duration time.Duration, progress ffmpeg.ProgressCallback) error
+
+// muxStreams combines video and audio with the native muxer, falling back to
+// FFmpeg when the streams can't be muxed natively.
+func muxStreams(ctx context.Context, videoPath, audioPath, outputPath string, duration time.Duration, progress ffmpeg.ProgressCallback) error {
+	var nativeProgress mux.ProgressCallback
+	if progress != nil {
+		nativeProgress = func(processed time.Duration) {
+			progress(ffmpeg.Progress{Processed: processed, Total: duration})
+		}
+	}
+
+	err := mux.Mux(ctx, videoPath, audioPath, outputPath, nativeProgress)
+	if errors.Is(err, mux.ErrUnsupported) {
+		loggerFrom(ctx).DebugContext(ctx, "native muxer unsupported, falling back to FFmpeg", "reason", err)
+		return ffmpeg.MuxStreamsWithProgress(ctx, videoPath, audioPath, outputPath, duration, progress)
+	}
+	if err == nil && progress != nil {
+		progress(ffmpeg.Progress{Processed: duration, Total: duration, Done: true})
+	}
+	return err
+}
 
 // runDownloadWithDeps implements the download command logic with injectable dependencies.
 func runDownloadWithDeps(
@@ -81,284 +394,1363 @@ func runDownloadWithDeps(
 	w io.Writer,
 	urlStr string,
 	opts *downloadOptions,
-	fetcher *youtube.WatchPageFetcher,
-	downloader *download.Downloader,
+	src *sources,
+	downloader download.StreamDownloader,
 	muxer MuxerFunc,
 ) error {
-	// Resolve the query to determine content type
-	query, err := youtube.ResolveQuery(urlStr)
+	if _, err := parseByteSize(opts.splitSize); err != nil {
+		return fmt.Errorf("invalid --split-size: %w", err)
+	}
+	if _, err := parseRecodeOptions(opts); err != nil {
+		return err
+	}
+	if _, err := parseSection(opts.section); err != nil {
+		return fmt.Errorf("invalid --section: %w", err)
+	}
+	if opts.keepSeparate && opts.section != "" {
+		return errors.New("--keep-separate cannot be used with --section")
+	}
+	if opts.splitChapters && opts.section != "" {
+		return errors.New("--split-chapters cannot be used with --section")
+	}
+	if opts.splitChapters && opts.splitSize != "" {
+		return errors.New("--split-chapters cannot be used with --split-size")
+	}
+	if opts.normalizeAudio {
+		if err := loudnessOptions(opts).Validate(); err != nil {
+			return fmt.Errorf("invalid --loudness-target: %w", err)
+		}
+		if opts.splitSize != "" {
+			return errors.New("--normalize-audio cannot be used with --split-size")
+		}
+	}
+	if opts.replayGain {
+		if !isAudioOnly(opts) {
+			return errors.New("--replaygain can only be used with audio downloads")
+		}
+		if err := validateTagFormat(opts, "--replaygain"); err != nil {
+			return err
+		}
+	}
+	if opts.embedLyrics {
+		if err := validateTagFormat(opts, "--embed-lyrics"); err != nil {
+			return err
+		}
+		if opts.lyrics == nil {
+			opts.lyrics = videoLyrics(src)
+		}
+	}
+	if opts.splitStoryboards {
+		opts.writeStoryboards = true
+	}
+	if opts.writeStoryboards && opts.storyboards == nil {
+		opts.storyboards = youtube.NewStoryboardDownloader(src.client)
+	}
+	if opts.musicMetadata != "" {
+		if err := validateTagFormat(opts, "--music-metadata"); err != nil {
+			return err
+		}
+		if opts.musicProvider == nil {
+			provider, err := musicmeta.Open(opts.musicMetadata, src.client)
+			if err != nil {
+				return fmt.Errorf("invalid --music-metadata: %w", err)
+			}
+			opts.musicProvider = provider
+		}
+	}
+	if opts.dedup == nil {
+		// A batch shares the dedup of its URLs; otherwise it is the run's own
+		dedup, err := newDedup(opts)
+		if err != nil {
+			return err
+		}
+		copied := *opts
+		copied.dedup = dedup
+		opts = &copied
+	}
+	if opts.printPlan {
+		return printPlan(ctx, w, urlStr, opts, src)
+	}
+	if err := validateStdoutOutput(opts); err != nil {
+		return err
+	}
+	if err := validateUpload(opts); err != nil {
+		return err
+	}
+	if opts.verifyOutput && !opts.simulate && !ffmpeg.IsProbeAvailable() {
+		return fmt.Errorf("--verify-output requires ffprobe: %w", ffmpeg.ErrProbeNotFound)
+	}
+
+	// Resolve the query to determine content type, expanding short links if needed
+	query, err := youtube.ResolveQueryWithOptions(ctx, urlStr, youtube.ResolveOptions{
+		Expander:      youtube.NewURLExpander(src.client),
+		DefaultSearch: opts.searchTerms,
+	})
 	if err != nil {
 		return fmt.Errorf("invalid URL or ID: %w", err)
 	}
 
+	isMix := query.Type == youtube.QueryTypeVideo && youtube.IsMixPlaylistID(query.PlaylistID)
+	if opts.pipe != nil && (query.Type != youtube.QueryTypeVideo || isMix) {
+		return errors.New("--output - can only be used with a single video")
+	}
+
 	switch query.Type {
 	case youtube.QueryTypeVideo:
-		return downloadSingleVideo(ctx, w, query.VideoID, opts, fetcher, downloader, muxer, "")
+		if isMix {
+			return downloadMix(ctx, w, query.PlaylistID, query.VideoID, opts, src, downloader, muxer)
+		}
+		return downloadSingleVideo(ctx, w, query.VideoID, opts, src, downloader, muxer, nil)
+
+	case youtube.QueryTypeClip:
+		return downloadClip(ctx, w, query.ClipID, opts, src, downloader, muxer)
 
 	case youtube.QueryTypePlaylist:
-		return downloadPlaylist(ctx, w, query.PlaylistID, opts, fetcher, downloader, muxer)
+		return downloadPlaylist(ctx, w, query.PlaylistID, opts, src, downloader, muxer)
 
 	case youtube.QueryTypeChannel:
-		return downloadChannel(ctx, w, query.Channel, opts, fetcher, downloader, muxer)
+		return downloadChannel(ctx, w, query.Channel, opts, src, downloader, muxer)
 
 	case youtube.QueryTypeSearch:
-		return errors.New("search queries are not supported for download")
+		return fmt.Errorf("search queries are not supported for download: %q", query.SearchQuery)
 
 	default:
 		return errors.New("unsupported content type")
 	}
 }
 
-// downloadSingleVideo downloads a single video by its ID.
+// downloadSingleVideo downloads a single video by its ID. entry is where the video
+// is in the playlist being downloaded, or nil for videos downloaded on their own.
 func downloadSingleVideo(
 	ctx context.Context,
 	w io.Writer,
-	videoID string,
+	videoID youtube.VideoID,
+	opts *downloadOptions,
+	src *sources,
+	downloader download.StreamDownloader,
+	muxer MuxerFunc,
+	entry *postprocess.PlaylistEntry,
+) (err error) {
+	result := download.DownloadResult{Title: videoID.String()}
+	if opts.report != nil || opts.notifier != nil {
+		started := time.Now()
+		defer func() {
+			result.Error = err
+			result.Elapsed = time.Since(started)
+			if opts.report != nil {
+				opts.report.add(result)
+			}
+			if !opts.simulate {
+				notifyResult(ctx, w, opts.notifier, result)
+			}
+		}()
+	}
+
+	switch opts.dedup.Claim(videoID.String()) {
+	case download.SkipClaimed:
+		_, _ = fmt.Fprintf(w, "Skipping %s: listed earlier in this run\n", videoID)
+		result.Skipped = true
+		return nil
+	case download.SkipArchived:
+		_, _ = fmt.Fprintf(w, "Skipping %s: in the download archive\n", videoID)
+		result.Skipped = true
+		return nil
+	}
+
+	video, manifest, err := fetchVideoWhenAvailable(ctx, w, videoID, opts, src)
+	if err != nil {
+		return err
+	}
+	result.Title = video.Title
+	downloader = download.WithRefresher(downloader, ytdl.FetcherRefresher(src.videos, videoID))
+
+	outputPath := videoOutputPath(video, opts, playlistNumber(entry))
+	skip := false
+	var uploadKey string
+	switch {
+	case opts.pipe != nil:
+		outputPath = stdoutOutput
+	case opts.upload != nil:
+		// Nothing is written to the output directory, so the overwrite policy doesn't apply
+		uploadKey = storageKey(opts.output, outputPath)
+		outputPath = opts.upload.URL(uploadKey)
+	default:
+		if outputPath, skip, err = resolveOutputPath(w, outputPath, opts.overwrite); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case skip:
+		result.FilePath = outputPath
+		result.Skipped = true
+	case opts.simulate:
+		if err := simulateDownload(w, video, manifest, outputPath, opts); err != nil {
+			return err
+		}
+	case opts.upload != nil:
+		size, err := uploadSelectedStreams(ctx, w, video, manifest, uploadKey, outputPath, opts, downloader, muxer)
+		if err != nil {
+			return err
+		}
+		result.FilePath = outputPath
+		result.Size = size
+	default:
+		if err := downloadSelectedStreams(ctx, w, video, manifest, outputPath, opts, downloader, muxer); err != nil {
+			return err
+		}
+		if opts.pipe != nil {
+			return nil
+		}
+		result.FilePath = outputPath
+		result.Size = fileSize(outputPath)
+
+		if err := postProcess(ctx, w, video, entry, outputPath, opts); err != nil {
+			return err
+		}
+	}
+
+	if !opts.simulate {
+		if err := opts.dedup.Done(videoID.String()); err != nil {
+			return err
+		}
+	}
+	if opts.remixSources && video.RemixOf != nil {
+		return downloadRemixSource(ctx, w, video.RemixOf, opts, src, downloader, muxer)
+	}
+	return nil
+}
+
+// newDedup returns the dedup layer of a run: it skips the videos already
+// downloaded in the run, unless --no-dedup is given, and those listed in the
+// --download-archive.
+func newDedup(opts *downloadOptions) (*download.Dedup, error) {
+	dedup := &download.Dedup{AllowRepeats: opts.noDedup}
+	if opts.downloadArchive != "" {
+		archive, err := download.OpenArchive(opts.downloadArchive)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --download-archive: %w", err)
+		}
+		dedup.Archive = archive
+	}
+	return dedup, nil
+}
+
+// downloadClip downloads the video a clip was cut from.
+func downloadClip(
+	ctx context.Context,
+	w io.Writer,
+	clipID string,
 	opts *downloadOptions,
-	fetcher *youtube.WatchPageFetcher,
-	downloader *download.Downloader,
+	src *sources,
+	downloader download.StreamDownloader,
 	muxer MuxerFunc,
-	numberPrefix string,
 ) error {
+	clip, err := src.clips.Fetch(ctx, clipID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch clip: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(w, "Clip: %s of %s\n", clip.RangeString(), clip.VideoID)
+
+	// An explicit --section overrides the clip's range
+	clipOpts := *opts
+	if clipOpts.section == "" {
+		clipOpts.section = clip.Range().String()
+	}
+	return downloadSingleVideo(ctx, w, youtube.VideoID(clip.VideoID), &clipOpts, src, downloader, muxer, nil)
+}
+
+// fetchVideo fetches the watch page for videoID and returns the video metadata
+// and its stream manifest.
+func fetchVideo(ctx context.Context, w io.Writer, videoID youtube.VideoID, src *sources) (*youtube.Video, *youtube.StreamManifest, error) {
 	_, _ = fmt.Fprintf(w, "Fetching video info: %s\n", videoID)
 
-	// Fetch the watch page
-	watchPage, err := fetcher.Fetch(ctx, videoID)
+	result, err := src.videos.FetchVideo(ctx, videoID)
 	if err != nil {
-		return fmt.Errorf("failed to fetch video page: %w", err)
+		return nil, nil, err
 	}
+	video, manifest := showVideo(ctx, w, videoID, result)
+	return video, manifest, nil
+}
+
+// showVideo prints the info of a fetched video and returns its metadata and streams.
+func showVideo(ctx context.Context, w io.Writer, videoID youtube.VideoID, result *ytdl.Video) (*youtube.Video, *youtube.StreamManifest) {
+	video, manifest := &result.Video, result.Streams
+
+	_, _ = fmt.Fprintf(w, "Title: %s\n", displayText(video.Title))
+	_, _ = fmt.Fprintf(w, "Author: %s\n", displayText(video.Author.Name))
+	_, _ = fmt.Fprintf(w, "Duration: %s\n", video.DurationString())
+	if video.RemixOf != nil {
+		_, _ = fmt.Fprintf(w, "Remix of: %s\n", remixSourceLabel(video.RemixOf))
+	}
+
+	loggerFrom(ctx).DebugContext(ctx, "parsed stream manifest", "video_id", videoID,
+		"video_streams", len(manifest.VideoStreams), "audio_streams", len(manifest.AudioStreams),
+		"muxed_streams", len(manifest.MuxedStreams))
+	return video, manifest
+}
+
+// videoOutputPath returns the path a video is downloaded to for the options.
+func videoOutputPath(video *youtube.Video, opts *downloadOptions, numberPrefix string) string {
+	containerStr := string(parseContainer(opts.format))
+	if isAudioOnly(opts) {
+		containerStr = audioFormat(opts)
+	}
+	template := opts.template
+	if template == "" {
+		template = filename.DefaultTemplate
+	}
+	outputFilename := filename.ApplyTemplateWithOptions(template, video, containerStr, numberPrefix, filenameOptions(opts))
+	return filepath.Join(opts.output, outputFilename)
+}
+
+// resolveOutputPath applies the overwrite policy to outputPath before any stream is
+// requested, returning the path to download to and whether to skip the video.
+func resolveOutputPath(w io.Writer, outputPath string, policy download.OverwritePolicy) (string, bool, error) {
+	target, skip, err := download.ResolveTarget(outputPath, policy)
+	switch {
+	case err != nil:
+		return "", false, err
+	case skip:
+		_, _ = fmt.Fprintf(w, "Skipping existing file: %s\n", displayText(outputPath))
+	case target != outputPath:
+		_, _ = fmt.Fprintf(w, "File exists, downloading to: %s\n", target)
+	}
+	return target, skip, nil
+}
+
+// overwritePolicy returns the policy selected by --overwrite-policy, --no-overwrite
+// and --force-overwrite.
+func overwritePolicy(policy string, noOverwrite, forceOverwrite bool) (download.OverwritePolicy, error) {
+	switch {
+	case noOverwrite:
+		return download.SkipExisting, nil
+	case forceOverwrite:
+		return download.OverwriteExisting, nil
+	}
+	parsed, err := download.ParseOverwritePolicy(policy)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --overwrite-policy: %w", err)
+	}
+	return parsed, nil
+}
+
+// filenameOptions returns the file name sanitization selected by the options.
+func filenameOptions(opts *downloadOptions) filename.Options {
+	return filename.Options{
+		MaxTitleLength:   opts.maxTitleLen,
+		ASCIIOnly:        opts.asciiName,
+		Normalize:        opts.restrictName,
+		StripFormatChars: opts.restrictName,
+		ReplaceEmoji:     opts.restrictName,
+	}
+}
+
+// downloadRemixSource downloads the original video of a remix into the same output directory.
+// Only one level is followed: the source's own remix source is not queued.
+func downloadRemixSource(
+	ctx context.Context,
+	w io.Writer,
+	source *youtube.RemixSource,
+	opts *downloadOptions,
+	src *sources,
+	downloader download.StreamDownloader,
+	muxer MuxerFunc,
+) error {
+	_, _ = fmt.Fprintf(w, "\nDownloading original video: %s\n", remixSourceLabel(source))
+
+	sourceOpts := *opts
+	sourceOpts.remixSources = false
+	if err := downloadSingleVideo(ctx, w, youtube.VideoID(source.VideoID), &sourceOpts, src, downloader, muxer, nil); err != nil {
+		return fmt.Errorf("failed to download original video %s: %w", source.VideoID, err)
+	}
+	return nil
+}
+
+// remixSourceLabel formats a remix source for display.
+func remixSourceLabel(source *youtube.RemixSource) string {
+	if source.Title == "" {
+		return source.URL()
+	}
+	return fmt.Sprintf("%s (%s)", source.Title, source.URL())
+}
+
+// streamSelection is the set of streams chosen for a download.
+type streamSelection struct {
+	// quality is the selected quality label, empty when a fallback stream was used.
+	quality string
+
+	// container is the output container when video and audio are muxed.
+	container youtube.Container
+
+	// video is the video-only or muxed stream, nil for audio-only downloads.
+	video *youtube.VideoStreamInfo
+
+	// audio is a separate audio stream, nil when video already carries the audio.
+	audio *youtube.AudioStreamInfo
+
+	// section is the part of the video to keep, nil for the whole video.
+	section *youtube.TimeRange
+
+	// keepSeparate keeps the video and audio streams next to the output after
+	// muxing them, for --keep-separate.
+	keepSeparate bool
+
+	// tempDir is where the streams are downloaded before muxing, for
+	// --temp-dir. Empty means the output's directory.
+	tempDir string
+
+	// inaccessible, when set, is why a better quality wasn't selected.
+	inaccessible *youtube.InaccessibleFormatError
+}
+
+// label describes the selected formats by quality and codecs, as in
+// "720p (avc1.4d401f, opus)".
+func (s *streamSelection) label() string {
+	var codecs []string
+	if s.video != nil {
+		codecs = append(codecs, s.video.VideoCodec)
+	}
+	if s.audio != nil {
+		codecs = append(codecs, s.audio.AudioCodec)
+	}
+	label := strings.Join(codecs, ", ")
+	if s.quality != "" {
+		label = s.quality + " (" + label + ")"
+	}
+	return label
+}
+
+// warnInaccessible tells w when a better quality than the selected one is only
+// offered in a format that can't be downloaded, such as a Premium one.
+func (s *streamSelection) warnInaccessible(w io.Writer) {
+	if s.inaccessible != nil {
+		_, _ = fmt.Fprintf(w, "Warning: %v; selected %s instead\n", s.inaccessible, s.label())
+	}
+}
+
+// needsMux reports whether separate video and audio streams must be muxed.
+func (s *streamSelection) needsMux() bool {
+	return s.video != nil && s.audio != nil
+}
+
+// estimatedSize returns the combined size of the selected streams in bytes,
+// falling back to bitrate times duration when YouTube doesn't report a length.
+func (s *streamSelection) estimatedSize(duration time.Duration) int64 {
+	var total int64
+	if s.video != nil {
+		total += s.video.EstimatedSize(duration)
+	}
+	if s.audio != nil {
+		total += s.audio.EstimatedSize(duration)
+	}
+	return total
+}
+
+// sizeIsExact reports whether YouTube reported the length of every selected stream.
+func (s *streamSelection) sizeIsExact() bool {
+	return (s.video == nil || s.video.SizeIsExact()) && (s.audio == nil || s.audio.SizeIsExact())
+}
+
+// formatSize formats a stream size for display, marking estimates with "~".
+func formatSize(size int64, exact bool) string {
+	if exact {
+		return download.FormatBytes(size)
+	}
+	return "~" + download.FormatBytes(size)
+}
 
-	// Extract player response
-	playerResponse, err := watchPage.ExtractPlayerResponse()
+// selectStreams picks the streams matching the options from the manifest.
+func selectStreams(manifest *youtube.StreamManifest, opts *downloadOptions) (*streamSelection, error) {
+	prefs, err := selectionPreferences(opts)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := ytdl.SelectStreamsWithPreferences(manifest, parseQualityPreference(opts.quality), parseContainer(opts.format), isAudioOnly(opts), prefs)
 	if err != nil {
-		return fmt.Errorf("failed to extract video data: %w", err)
+		return nil, err
 	}
+	return &streamSelection{
+		quality:   selected.Quality,
+		container: selected.Container,
+		video:     selected.Video,
+		audio:     selected.Audio,
+
+		inaccessible: selected.Inaccessible,
+	}, nil
+}
 
-	// Check playability status
-	if playerResponse.PlayabilityStatus.Status != "OK" {
-		reason := playerResponse.PlayabilityStatus.Reason
-		if reason == "" {
-			reason = "unknown reason"
+// selectionPreferences returns the format preferences set by --prefer-codec,
+// --max-fps and --prefer-hdr.
+func selectionPreferences(opts *downloadOptions) (youtube.SelectionPreferences, error) {
+	prefs := youtube.SelectionPreferences{MaxFramerate: opts.maxFPS, HDR: opts.preferHDR}
+	if opts.preferCodec != "" {
+		codecs, err := youtube.ParseCodecPreference(opts.preferCodec)
+		if err != nil {
+			return prefs, fmt.Errorf("invalid --prefer-codec: %w", err)
 		}
-		return fmt.Errorf("video unavailable: %s", reason)
+		prefs.Codecs = codecs
 	}
+	return prefs, nil
+}
 
-	// Convert to Video struct
-	video, err := playerResponse.ToVideo()
+// maxStreamFallbacks is how many times a download falls back to other formats
+// before it fails.
+const maxStreamFallbacks = 3
+
+// downloadSelectedStreams selects the streams matching the options and downloads
+// them to outputPath. When the streams are refused or time out, the next best
+// formats are downloaded instead, unless --no-fallback is given; a download to a
+// pipe isn't retried, since part of it may already be written.
+func downloadSelectedStreams(
+	ctx context.Context,
+	w io.Writer,
+	video *youtube.Video,
+	manifest *youtube.StreamManifest,
+	outputPath string,
+	opts *downloadOptions,
+	downloader download.StreamDownloader,
+	muxer MuxerFunc,
+) error {
+	selection, err := selectStreams(manifest, opts)
 	if err != nil {
-		return fmt.Errorf("failed to parse video metadata: %w", err)
+		return err
+	}
+	selection.warnInaccessible(w)
+	if selection.section, err = parseSection(opts.section); err != nil {
+		return fmt.Errorf("invalid --section: %w", err)
+	}
+	selection.keepSeparate, selection.tempDir = opts.keepSeparate, opts.tempDir
+	for fallbacks := 0; ; fallbacks++ {
+		err := downloadSelection(ctx, w, video, selection, outputPath, opts.pipe, downloader, muxer)
+		if err == nil {
+			break
+		}
+		if opts.noFallback || opts.pipe != nil || fallbacks == maxStreamFallbacks || !ytdl.ShouldFallBack(ctx, err) {
+			return err
+		}
+		manifest = ytdl.WithoutSelection(manifest, &ytdl.Selection{Video: selection.video, Audio: selection.audio})
+		next, selectErr := selectStreams(manifest, opts)
+		if selectErr != nil {
+			return err
+		}
+		next.section, next.keepSeparate, next.tempDir = selection.section, selection.keepSeparate, selection.tempDir
+		loggerFrom(ctx).WarnContext(ctx, "falling back to other formats", "error", err, "formats", next.label())
+		_, _ = fmt.Fprintf(w, "%v\nFalling back to %s\n", err, next.label())
+		selection = next
 	}
+	if opts.verifyOutput && opts.pipe == nil {
+		return verifyOutput(ctx, w, selection, outputDuration(video, opts), outputPath)
+	}
+	return nil
+}
 
-	_, _ = fmt.Fprintf(w, "Title: %s\n", video.Title)
-	_, _ = fmt.Fprintf(w, "Author: %s\n", video.Author.Name)
-	_, _ = fmt.Fprintf(w, "Duration: %s\n", video.DurationString())
+// verifyOutput probes a finished file and checks its duration and codecs
+// against the selected streams, for --verify-output. A mismatch usually means
+// a stream was cut short or muxing went wrong.
+func verifyOutput(ctx context.Context, w io.Writer, selection *streamSelection, duration time.Duration, outputPath string) error {
+	result, err := ffmpeg.Probe(ctx, outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", outputPath, err)
+	}
+	if err := result.Verify(outputExpectation(selection, duration, outputPath)); err != nil {
+		return fmt.Errorf("failed to verify %s: %w", outputPath, err)
+	}
+	_, _ = fmt.Fprintf(w, "Verified %s (%s)\n", outputPath, result.Duration.Round(time.Second))
+	return nil
+}
+
+// transcodedAudioCodecs are the audio codecs of the files FFmpeg transcodes
+// audio downloads into, by extension.
+var transcodedAudioCodecs = map[string]string{
+	".mp3":  "mp3",
+	".flac": "flac",
+	".wav":  "pcm_s16le",
+}
+
+// outputExpectation returns what a file downloaded from the selected streams
+// should contain. MP3, FLAC and WAV files hold only the transcoded audio. Sections are cut at
+// the keyframe before their start, so their duration isn't checked.
+func outputExpectation(selection *streamSelection, duration time.Duration, outputPath string) ffmpeg.Expectation {
+	var want ffmpeg.Expectation
+	if selection.section == nil {
+		want.Duration = duration
+	}
+	if codec, ok := transcodedAudioCodecs[strings.ToLower(filepath.Ext(outputPath))]; ok {
+		want.AudioCodec = codec
+		return want
+	}
+	if selection.video != nil {
+		// Muxed formats list the video codec first, like "avc1.42001E, mp4a.40.2"
+		want.VideoCodec, _, _ = strings.Cut(selection.video.VideoCodec, ",")
+	}
+	if selection.audio != nil {
+		want.AudioCodec = selection.audio.AudioCodec
+	}
+	return want
+}
+
+// simulateDownload prints the formats and output file a download of the video would
+// use, with its estimated size, for --simulate.
+func simulateDownload(w io.Writer, video *youtube.Video, manifest *youtube.StreamManifest, outputPath string, opts *downloadOptions) error {
+	selection, err := selectStreams(manifest, opts)
+	if err != nil {
+		return err
+	}
+	selection.warnInaccessible(w)
+
+	if selection.quality != "" {
+		_, _ = fmt.Fprintf(w, "Selected quality: %s\n", selection.quality)
+	}
+	size := selection.estimatedSize(outputDuration(video, opts))
+	_, _ = fmt.Fprintf(w, "Would download to: %s (estimated %s)\n", displayText(outputPath), download.FormatBytes(size))
+	return nil
+}
+
+// logSelection logs the chosen formats at debug level.
+func logSelection(ctx context.Context, selection *streamSelection) {
+	attrs := []any{"quality", selection.quality, "container", selection.container, "mux", selection.needsMux()}
+	if v := selection.video; v != nil {
+		attrs = append(attrs, "video_itag", v.Itag, "video_codec", v.VideoCodec, "video_size", v.ContentLength)
+	}
+	if a := selection.audio; a != nil {
+		attrs = append(attrs, "audio_itag", a.Itag, "audio_codec", a.AudioCodec, "audio_size", a.ContentLength)
+	}
+	loggerFrom(ctx).DebugContext(ctx, "selected formats", attrs...)
+}
+
+// downloadSelection downloads the selected streams to outputPath, or to pipe when one is given.
+func downloadSelection(
+	ctx context.Context,
+	w io.Writer,
+	video *youtube.Video,
+	selection *streamSelection,
+	outputPath string,
+	pipe io.Writer,
+	downloader download.StreamDownloader,
+	muxer MuxerFunc,
+) error {
+	if selection.section != nil {
+		return downloadSection(ctx, w, video, selection, outputPath, downloader, muxer)
+	}
 
-	// Check if we have streaming data
-	if playerResponse.StreamingData == nil {
-		return errors.New("no streaming data available")
+	if selection.quality != "" {
+		_, _ = fmt.Fprintf(w, "Selected quality: %s\n", selection.quality)
 	}
+	logSelection(ctx, selection)
 
-	// Get stream manifest
-	manifest := playerResponse.StreamingData.GetStreamManifest()
+	if err := checkDiskSpace(selection, video.Duration, outputPath, pipe); err != nil {
+		return err
+	}
 
-	// Determine if audio-only mode
-	audioOnly := strings.EqualFold(opts.format, "mp3") || strings.EqualFold(opts.quality, "audio")
+	switch {
+	case selection.needsMux():
+		option := &youtube.DownloadOption{
+			Container:   selection.container,
+			VideoStream: selection.video,
+			AudioStream: selection.audio,
+		}
+		return downloadAndMux(ctx, w, video, option, outputPath, pipe, selection.keepSeparate, selection.tempDir, downloader, muxer)
+	case selection.video != nil:
+		if pipe == nil && needsConversion(selection.video.Container, outputPath) && ffmpeg.IsAvailable() {
+			return downloadAndConvert(withSegmentIndex(ctx, selection.video.IndexRange), w, selection.video.URL, outputPath, downloader, ffmpeg.ConvertStream)
+		}
+		return downloadSingleStream(withSegmentIndex(ctx, selection.video.IndexRange), w, selection.video.URL, outputPath, pipe, downloader)
+	default:
+		_, _ = fmt.Fprintf(w, "Downloading audio: %s\n", selection.audio.AudioCodec)
+		if pipe == nil && needsConversion(selection.audio.Container, outputPath) && ffmpeg.IsAvailable() {
+			return downloadAndConvert(withSegmentIndex(ctx, selection.audio.IndexRange), w, selection.audio.URL, outputPath, downloader, ffmpeg.ConvertStream)
+		}
+		return downloadSingleStream(withSegmentIndex(ctx, selection.audio.IndexRange), w, selection.audio.URL, outputPath, pipe, downloader)
+	}
+}
 
-	// Get preferred container
-	container := parseContainer(opts.format)
+// needsConversion reports whether a single stream in container has to go through
+// FFmpeg to be saved as outputPath: audio saved as MP3 is transcoded, and streams
+// in another container than the file's extension are remuxed.
+func needsConversion(container youtube.Container, outputPath string) bool {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(outputPath)), ".")
+	return ext != "" && ext != string(container)
+}
+
+// downloadSection downloads the selected streams in full next to outputPath and
+// cuts the selection's section out of them. YouTube serves streams by byte
+// rather than by time, so the whole video has to be fetched first.
+func downloadSection(
+	ctx context.Context,
+	w io.Writer,
+	video *youtube.Video,
+	selection *streamSelection,
+	outputPath string,
+	downloader download.StreamDownloader,
+	muxer MuxerFunc,
+) error {
+	section, ok := selection.section.Clamp(video.Duration)
+	if !ok {
+		return fmt.Errorf("section %s starts after the end of the video (%s)", section, video.DurationString())
+	}
+	if !ffmpeg.IsAvailable() {
+		return fmt.Errorf("--section requires FFmpeg: %w", ffmpeg.ErrNotFound)
+	}
 
-	// Determine output path
-	containerStr := string(container)
-	if audioOnly {
-		containerStr = "mp3"
+	full := *selection
+	full.section = nil
+	fullPath := sectionSourcePath(outputPath)
+	if err := downloadSelection(ctx, w, video, &full, fullPath, nil, downloader, muxer); err != nil {
+		return err
 	}
-	outputFilename := filename.ApplyTemplate(filename.DefaultTemplate, video, containerStr, numberPrefix)
-	outputPath := filepath.Join(opts.output, outputFilename)
+	defer func() { _ = os.Remove(fullPath) }()
 
-	if audioOnly {
-		return downloadAudioOnly(ctx, w, manifest, outputPath, downloader)
+	_, _ = fmt.Fprintf(w, "Cutting section %s...\n", section)
+	_, progress := ffmpegProgressBar(w, "Cutting", section.Duration())
+	if err := ffmpeg.Trim(ctx, fullPath, outputPath, section.Start, section.End, progress); err != nil {
+		return fmt.Errorf("failed to cut section: %w", err)
 	}
 
-	// Get quality preference and select best option
-	quality := parseQualityPreference(opts.quality)
-	options := manifest.GetDownloadOptions()
-	selectedOption := youtube.SelectBestOption(options, quality, container)
+	_, _ = fmt.Fprintf(w, "Section saved: %s\n", displayText(outputPath))
+	return nil
+}
+
+// sectionSourcePath returns where the full video is downloaded before a section is cut to outputPath.
+// Example: "video.mp4" -> "video.full.mp4"
+func sectionSourcePath(outputPath string) string {
+	ext := filepath.Ext(outputPath)
+	return strings.TrimSuffix(outputPath, ext) + ".full" + ext
+}
 
-	if selectedOption == nil {
-		// Try to use muxed stream if no adaptive option is available
-		if len(manifest.MuxedStreams) > 0 {
-			return downloadMuxedStream(ctx, w, &manifest.MuxedStreams[0], outputPath, downloader)
+// parseSection parses the --section value. An empty value parses to nil (whole video).
+func parseSection(s string) (*youtube.TimeRange, error) {
+	if s == "" {
+		return nil, nil
+	}
+	section, err := youtube.ParseTimeRange(s)
+	if err != nil {
+		return nil, err
+	}
+	return &section, nil
+}
+
+// outputDuration returns the length of the media a download produces:
+// the section's length with --section, and the video's otherwise.
+func outputDuration(video *youtube.Video, opts *downloadOptions) time.Duration {
+	section, err := parseSection(opts.section)
+	if err != nil || section == nil {
+		return video.Duration
+	}
+	if clamped, ok := section.Clamp(video.Duration); ok {
+		return clamped.Duration()
+	}
+	return video.Duration
+}
+
+// checkDiskSpace verifies there is room for the selected streams before downloading:
+// the output directory needs the finished file and, when muxing, the temp directory
+// needs the separate streams as well.
+func checkDiskSpace(selection *streamSelection, duration time.Duration, outputPath string, pipe io.Writer) error {
+	size := selection.estimatedSize(duration)
+
+	var requirements []download.SpaceRequirement
+	if pipe == nil {
+		requirements = append(requirements, download.SpaceRequirement{Dir: filepath.Dir(outputPath), Bytes: size})
+	}
+	if selection.needsMux() {
+		// The streams are downloaded to the temporary directory, or next to the output to be kept
+		dir := download.TempParent(selection.tempDir, outputPath)
+		if pipe != nil {
+			dir = download.TempParent(selection.tempDir, "")
+		} else if selection.keepSeparate {
+			dir = filepath.Dir(outputPath)
 		}
-		return errors.New("no suitable stream found for the requested quality")
+		requirements = append(requirements, download.SpaceRequirement{Dir: dir, Bytes: size})
 	}
 
-	_, _ = fmt.Fprintf(w, "Selected quality: %s\n", selectedOption.QualityLabel())
+	if err := download.CheckDiskSpace(requirements...); err != nil {
+		return fmt.Errorf("disk space check failed: %w", err)
+	}
+	return nil
+}
 
-	// Check if we need to mux separate streams
-	if selectedOption.VideoStream != nil && selectedOption.AudioStream != nil && selectedOption.VideoStream.URL != "" {
-		// Check if streams have separate URLs (need muxing)
-		if selectedOption.AudioStream.URL != "" && selectedOption.VideoStream.URL != selectedOption.AudioStream.URL {
-			return downloadAndMux(ctx, w, video, selectedOption, outputPath, downloader, muxer)
+// validateStdoutOutput checks that the options can be combined with --output -
+// and defaults the pipe to os.Stdout.
+func validateStdoutOutput(opts *downloadOptions) error {
+	if opts.output != stdoutOutput {
+		return nil
+	}
+	switch {
+	case opts.splitSize != "":
+		return errors.New("--split-size cannot be used with --output -")
+	case opts.section != "":
+		return errors.New("--section cannot be used with --output -")
+	case opts.recodeVideo != "":
+		return errors.New("--recode-video cannot be used with --output -")
+	case opts.remixSources:
+		return errors.New("--with-remix-source cannot be used with --output -")
+	case len(opts.exec) > 0:
+		return errors.New("--exec cannot be used with --output -")
+	case opts.verifyOutput:
+		return errors.New("--verify-output cannot be used with --output -")
+	case opts.keepSeparate:
+		return errors.New("--keep-separate cannot be used with --output -")
+	case opts.splitChapters:
+		return errors.New("--split-chapters cannot be used with --output -")
+	case opts.normalizeAudio:
+		return errors.New("--normalize-audio cannot be used with --output -")
+	case opts.embedLyrics:
+		return errors.New("--embed-lyrics cannot be used with --output -")
+	case opts.musicMetadata != "":
+		return errors.New("--music-metadata cannot be used with --output -")
+	case opts.replayGain:
+		return errors.New("--replaygain cannot be used with --output -")
+	case opts.writeStoryboards:
+		return errors.New("--write-storyboards cannot be used with --output -")
+	}
+	if opts.pipe == nil {
+		opts.pipe = os.Stdout
+	}
+	return nil
+}
+
+// postProcess runs the optional steps applied to a finished download. entry is
+// where the video is in the playlist being downloaded, if any.
+func postProcess(ctx context.Context, w io.Writer, video *youtube.Video, entry *postprocess.PlaylistEntry, outputPath string, opts *downloadOptions) error {
+	duration := outputDuration(video, opts)
+	if opts.recodeVideo != "" {
+		recoded, err := recodeOutput(ctx, w, outputPath, duration, opts)
+		if err != nil {
+			return err
 		}
+		outputPath = recoded
 	}
 
-	// Download single stream (muxed or video-only)
-	if selectedOption.VideoStream != nil && selectedOption.VideoStream.URL != "" {
-		return downloadSingleStream(ctx, w, selectedOption.VideoStream.URL, outputPath, downloader)
+	splitSize, err := parseByteSize(opts.splitSize)
+	if err != nil {
+		return fmt.Errorf("invalid --split-size: %w", err)
+	}
+	outputs := []string{outputPath}
+	if splitSize > 0 {
+		if outputs, err = splitOutput(ctx, w, outputPath, splitSize, duration); err != nil {
+			return err
+		}
 	}
 
-	// Fallback to first muxed stream
-	if len(manifest.MuxedStreams) > 0 && manifest.MuxedStreams[0].VideoStreamInfo.URL != "" {
-		return downloadMuxedStream(ctx, w, &manifest.MuxedStreams[0], outputPath, downloader)
+	pipeline := newPostProcessPipeline(w, opts, entry)
+	for _, path := range outputs {
+		if err := pipeline.Run(ctx, &postprocess.File{Path: path, Video: video, Playlist: entry}); err != nil {
+			return fmt.Errorf("post-processing failed: %w", err)
+		}
 	}
+	return nil
+}
 
-	return errors.New("no downloadable stream found")
+// newPostProcessPipeline builds the post-processing steps configured by the options.
+// Audio downloaded from a playlist, as entry says it is, is tagged as tracks
+// of an album named after it. Command output goes to w alongside the rest of the
+// download output. Chapters are split after the loudness is normalized, so the
+// chapter files are normalized too, but before the file is tagged, as the tags
+// belong to the whole file; music metadata replaces the album tags when both
+// are written. ReplayGain is measured after normalizing too, so it matches the
+// audio that ends up in the file. Commands run last, once every file exists,
+// storyboards included.
+func newPostProcessPipeline(w io.Writer, opts *downloadOptions, entry *postprocess.PlaylistEntry) *postprocess.Pipeline {
+	pipeline := postprocess.NewPipeline()
+	if opts.normalizeAudio {
+		pipeline.Add(&postprocess.LoudnessStep{Options: loudnessOptions(opts), Output: w}, postprocess.Abort)
+	}
+	if opts.splitChapters {
+		pipeline.Add(&postprocess.ChapterSplitStep{Template: opts.chapterTemplate, Options: filenameOptions(opts), Output: w}, postprocess.Abort)
+	}
+	if entry != nil && isAudioOnly(opts) && audioFormat(opts) != "wav" {
+		pipeline.Add(&postprocess.TagStep{}, postprocess.Abort)
+	}
+	if opts.musicMetadata != "" {
+		// The tags are a nicety, so a music database being unreachable doesn't stop the other steps
+		pipeline.Add(&postprocess.MusicMetadataStep{Provider: opts.musicProvider, Output: w}, postprocess.Continue)
+	}
+	if opts.embedLyrics {
+		pipeline.Add(&postprocess.LyricsStep{Lyrics: opts.lyrics, Output: w}, postprocess.Abort)
+	}
+	if opts.replayGain {
+		// Files the gain can't be written to still play, just not at a matching volume
+		pipeline.Add(&postprocess.ReplayGainStep{Output: w}, postprocess.Continue)
+	}
+	if opts.writeStoryboards {
+		// The previews are extras, so failing to fetch them keeps the download
+		pipeline.Add(&postprocess.StoryboardStep{Downloader: opts.storyboards, Split: opts.splitStoryboards, Output: w}, postprocess.Continue)
+	}
+	for _, command := range opts.exec {
+		pipeline.Add(&postprocess.ExecStep{Command: command, Stdout: w, Stderr: w}, postprocess.Abort)
+	}
+	return pipeline
 }
 
-// downloadSingleStream downloads a single stream to the output path.
-func downloadSingleStream(ctx context.Context, w io.Writer, url, outputPath string, downloader *download.Downloader) error {
-	_, _ = fmt.Fprintf(w, "Downloading to: %s\n", outputPath)
+// loudnessOptions returns the loudness targets selected by --loudness-target.
+func loudnessOptions(opts *downloadOptions) ffmpeg.LoudnessOptions {
+	return ffmpeg.LoudnessOptions{Target: opts.loudnessTarget}
+}
 
-	// Create a progress bar
-	bar := progressbar.NewOptions64(
-		-1, // Unknown size initially
-		progressbar.OptionSetWriter(w),
-		progressbar.OptionEnableColorCodes(true),
-		progressbar.OptionShowBytes(true),
-		progressbar.OptionSetWidth(40),
-		progressbar.OptionSetDescription("Downloading"),
-		progressbar.OptionSetTheme(progressbar.Theme{
-			Saucer:        "[green]=[reset]",
-			SaucerHead:    "[green]>[reset]",
-			SaucerPadding: " ",
-			BarStart:      "[",
-			BarEnd:        "]",
-		}),
-		progressbar.OptionOnCompletion(func() {
-			_, _ = fmt.Fprintln(w)
-		}),
-	)
+// recodeContainers lists the containers accepted by --recode-video.
+var recodeContainers = []string{"mp4", "mkv", "webm", "mov"}
 
-	progressCallback := func(p download.Progress) {
-		if p.Total > 0 && bar.GetMax64() != p.Total {
-			bar.ChangeMax64(p.Total)
+// parseRecodeOptions validates the recode flags and converts them to ffmpeg.RecodeOptions.
+func parseRecodeOptions(opts *downloadOptions) (ffmpeg.RecodeOptions, error) {
+	if opts.recodeVideo == "" {
+		return ffmpeg.RecodeOptions{}, nil
+	}
+
+	if !slices.Contains(recodeContainers, strings.ToLower(opts.recodeVideo)) {
+		return ffmpeg.RecodeOptions{}, fmt.Errorf("invalid --recode-video %q: must be one of %s", opts.recodeVideo, strings.Join(recodeContainers, ", "))
+	}
+	if isAudioOnly(opts) {
+		return ffmpeg.RecodeOptions{}, errors.New("--recode-video cannot be used with audio-only downloads")
+	}
+	if opts.recodeCRF < 0 {
+		return ffmpeg.RecodeOptions{}, errors.New("--recode-crf must not be negative")
+	}
+
+	hw, err := ffmpeg.ParseHWAccel(opts.hwAccel)
+	if err != nil {
+		return ffmpeg.RecodeOptions{}, fmt.Errorf("invalid --hwaccel: %w", err)
+	}
+
+	codec := opts.recodeCodec
+	if codec == "" {
+		codec = ffmpeg.DefaultCodecForContainer(opts.recodeVideo)
+	}
+	if _, err := ffmpeg.ResolveEncoder(codec, ffmpeg.HWAccelNone, nil); err != nil {
+		return ffmpeg.RecodeOptions{}, fmt.Errorf("invalid --recode-codec: %w", err)
+	}
+	if err := ffmpeg.CheckContainerCodec(opts.recodeVideo, codec); err != nil {
+		return ffmpeg.RecodeOptions{}, fmt.Errorf("invalid --recode-codec for --recode-video %s: %w", opts.recodeVideo, err)
+	}
+
+	return ffmpeg.RecodeOptions{
+		Codec:   codec,
+		CRF:     opts.recodeCRF,
+		Preset:  opts.recodePreset,
+		HWAccel: hw,
+	}, nil
+}
+
+// recodeOutput re-encodes the finished file into the --recode-video container
+// and returns the path of the recoded file, which replaces the original.
+func recodeOutput(ctx context.Context, w io.Writer, outputPath string, duration time.Duration, opts *downloadOptions) (string, error) {
+	recodeOpts, err := parseRecodeOptions(opts)
+	if err != nil {
+		return "", err
+	}
+
+	container := strings.ToLower(opts.recodeVideo)
+	target := ffmpeg.RecodePath(outputPath, container)
+	finalPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "." + container
+
+	_, _ = fmt.Fprintf(w, "Recoding video to %s (%s)...\n", container, recodeOpts.Codec)
+	recodeOpts.Duration = duration
+	_, recodeOpts.Progress = ffmpegProgressBar(w, "Recoding", duration)
+	if err := ffmpeg.Recode(ctx, outputPath, target, recodeOpts); err != nil {
+		return "", fmt.Errorf("failed to recode video: %w", err)
+	}
+
+	if target != finalPath {
+		// Same container as the source: replace the original in place
+		if err := os.Rename(target, finalPath); err != nil {
+			return "", fmt.Errorf("failed to replace original with recoded file: %w", err)
 		}
-		_ = bar.Set64(p.Downloaded)
+	} else if err := os.Remove(outputPath); err != nil {
+		return "", fmt.Errorf("failed to remove original after recode: %w", err)
 	}
 
-	err := downloader.DownloadStream(ctx, url, outputPath, progressCallback)
+	_, _ = fmt.Fprintf(w, "Recode complete: %s\n", displayText(finalPath))
+	return finalPath, nil
+}
+
+// splitOutput splits the finished file into size-limited parts and writes rejoin scripts.
+// The original file is removed once the parts have been written.
+// It returns the paths of the resulting files.
+func splitOutput(ctx context.Context, w io.Writer, outputPath string, splitSize int64, duration time.Duration) ([]string, error) {
+	parts, err := ffmpeg.SplitBySize(ctx, outputPath, splitSize, duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split output: %w", err)
+	}
+	if len(parts) == 1 && parts[0] == outputPath {
+		_, _ = fmt.Fprintf(w, "Output is within %s, not splitting\n", download.FormatBytes(splitSize))
+		return parts, nil
+	}
+
+	if _, err := ffmpeg.WriteRejoinScripts(parts, outputPath); err != nil {
+		return nil, fmt.Errorf("failed to write rejoin scripts: %w", err)
+	}
+	if err := os.Remove(outputPath); err != nil {
+		return nil, fmt.Errorf("failed to remove original after split: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(w, "Split into %d parts:\n", len(parts))
+	for _, p := range parts {
+		_, _ = fmt.Fprintf(w, "  %s\n", p)
+	}
+	return parts, nil
+}
+
+// byteSizeUnits maps size suffixes to their multipliers (binary units).
+var byteSizeUnits = map[string]int64{
+	"":  1,
+	"B": 1,
+	"K": 1 << 10, "KB": 1 << 10, "KIB": 1 << 10,
+	"M": 1 << 20, "MB": 1 << 20, "MIB": 1 << 20,
+	"G": 1 << 30, "GB": 1 << 30, "GIB": 1 << 30,
+}
+
+// parseByteSize parses a size like "25M" or "1.5G" into bytes.
+// An empty string parses to 0 (no limit).
+func parseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	i := len(s)
+	for i > 0 && (s[i-1] < '0' || s[i-1] > '9') && s[i-1] != '.' {
+		i--
+	}
+	number, unit := s[:i], strings.ToUpper(strings.TrimSpace(s[i:]))
+
+	multiplier, ok := byteSizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit %q", unit)
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return int64(value * float64(multiplier)), nil
+}
+
+// withSegmentIndex returns ctx with the segment index YouTube reported for a
+// stream, so the downloader fetches it in ranges rather than in one request.
+// Muxed formats report no index and are downloaded whole.
+func withSegmentIndex(ctx context.Context, index *youtube.ByteRange) context.Context {
+	if index == nil {
+		return ctx
+	}
+	return download.WithSegmentIndex(ctx, download.ByteRange{Start: index.Start, End: index.End})
+}
+
+// downloadSingleStream downloads a single stream to the output path,
+// or writes it to pipe when one is given.
+func downloadSingleStream(ctx context.Context, w io.Writer, url, outputPath string, pipe io.Writer, downloader download.StreamDownloader) error {
+	if pipe != nil && outputPath == stdoutOutput {
+		outputPath = "stdout"
+	}
+	_, _ = fmt.Fprintf(w, "Downloading to: %s\n", displayText(outputPath))
+
+	// Create a progress bar (unknown size initially)
+	bar, progressCallback := downloadProgressBar(w, "Downloading")
+
+	var err error
+	if pipe != nil {
+		err = downloader.DownloadStreamTo(ctx, url, pipe, progressCallback)
+	} else {
+		err = downloader.DownloadStreamResult(ctx, url, outputPath, progressCallback).Error
+	}
 	if err != nil {
 		return fmt.Errorf("download failed: %w", err)
 	}
 
 	_ = bar.Finish()
-	_, _ = fmt.Fprintf(w, "Download complete: %s\n", outputPath)
+	_, _ = fmt.Fprintf(w, "Download complete: %s\n", displayText(outputPath))
 	return nil
 }
 
-// downloadMuxedStream downloads a muxed stream.
-func downloadMuxedStream(ctx context.Context, w io.Writer, stream *youtube.MuxedStreamInfo, outputPath string, downloader *download.Downloader) error {
-	if stream.VideoStreamInfo.URL == "" {
-		return errors.New("muxed stream has no URL")
-	}
-	return downloadSingleStream(ctx, w, stream.VideoStreamInfo.URL, outputPath, downloader)
-}
+// streamConverter saves a single stream read from src as outputPath, such as
+// ffmpeg.ConvertStream.
+type streamConverter func(ctx context.Context, src io.Reader, outputPath string) error
 
-// downloadAudioOnly downloads audio-only stream.
-func downloadAudioOnly(ctx context.Context, w io.Writer, manifest *youtube.StreamManifest, outputPath string, downloader *download.Downloader) error {
-	bestAudio := manifest.GetBestAudioStream()
-	if bestAudio == nil {
-		return errors.New("no audio stream available")
+// downloadAndConvert downloads a single stream straight into convert, which saves
+// it as outputPath while it downloads, so no temporary copy is written to disk.
+func downloadAndConvert(
+	ctx context.Context,
+	w io.Writer,
+	url, outputPath string,
+	downloader download.StreamDownloader,
+	convert streamConverter,
+) error {
+	_, _ = fmt.Fprintf(w, "Downloading and converting to: %s\n", displayText(outputPath))
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	if bestAudio.URL == "" {
-		return errors.New("audio stream has no URL")
+	bar, progressCallback := downloadProgressBar(w, "Downloading")
+	err := download.DownloadPiped(ctx, downloader, url, func(r io.Reader) error {
+		return convert(ctx, r, outputPath)
+	}, progressCallback)
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
 	}
 
-	_, _ = fmt.Fprintf(w, "Downloading audio: %s\n", bestAudio.AudioCodec)
-	return downloadSingleStream(ctx, w, bestAudio.URL, outputPath, downloader)
+	_ = bar.Finish()
+	_, _ = fmt.Fprintf(w, "Download complete: %s\n", displayText(outputPath))
+	return nil
 }
 
 // downloadAndMux downloads video and audio streams separately and muxes them.
+// When pipe is given the muxed result is written to it instead of outputPath.
+// The streams are downloaded to a temporary directory in tempDir, or next to
+// outputPath when tempDir is empty, and the muxed file is moved into place.
+// With keepSeparate, the streams are downloaded next to outputPath instead and
+// kept after muxing.
 func downloadAndMux(
 	ctx context.Context,
 	w io.Writer,
 	video *youtube.Video,
 	option *youtube.DownloadOption,
 	outputPath string,
-	downloader *download.Downloader,
+	pipe io.Writer,
+	keepSeparate bool,
+	tempDir string,
+	downloader download.StreamDownloader,
 	muxer MuxerFunc,
 ) error {
-	// Create temp directory for intermediate files
-	tempDir, err := os.MkdirTemp("", "ytdl-*")
+	target := outputPath
+	if target == stdoutOutput {
+		target = "stdout"
+	}
+	if pipe != nil && ffmpeg.CanMuxReaders() {
+		// Nothing needs to be kept on disk, so the streams go straight into FFmpeg
+		_, _ = fmt.Fprintf(w, "Downloading and muxing streams to %s...\n", target)
+		return streamToMuxer(ctx, w, option, pipe, downloader, ffmpeg.MuxReadersToWriter)
+	}
+
+	// Create temp directory for intermediate files, on the output's filesystem
+	// unless --temp-dir says otherwise
+	localOutput := outputPath
+	if pipe != nil {
+		localOutput = ""
+	} else if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	tempDir, err := download.MkdirTemp(tempDir, localOutput)
 	if err != nil {
 		return fmt.Errorf("failed to create temp directory: %w", err)
 	}
 	defer func() { _ = os.RemoveAll(tempDir) }()
 
-	// Download video stream with progress bar
+	// Download video and audio streams in parallel
 	videoPath := filepath.Join(tempDir, "video."+string(option.VideoStream.Container))
-	_, _ = fmt.Fprintf(w, "Downloading video stream...\n")
-	if err := downloadStreamWithProgress(ctx, w, downloader, option.VideoStream.URL, videoPath, "Video"); err != nil {
-		return fmt.Errorf("failed to download video: %w", err)
-	}
-
-	// Download audio stream with progress bar
 	audioPath := filepath.Join(tempDir, "audio."+string(option.AudioStream.Container))
-	_, _ = fmt.Fprintf(w, "Downloading audio stream...\n")
-	if err := downloadStreamWithProgress(ctx, w, downloader, option.AudioStream.URL, audioPath, "Audio"); err != nil {
-		return fmt.Errorf("failed to download audio: %w", err)
+	if keepSeparate && pipe == nil {
+		videoPath = separateStreamPath(outputPath, "video", option.VideoStream.Container)
+		audioPath = separateStreamPath(outputPath, "audio", option.AudioStream.Container)
+	}
+	_, _ = fmt.Fprintf(w, "Downloading video and audio streams...\n")
+	if err := downloadStreamsWithProgress(ctx, w, downloader, []streamTarget{
+		{name: "Video", url: option.VideoStream.URL, path: videoPath, index: option.VideoStream.IndexRange},
+		{name: "Audio", url: option.AudioStream.URL, path: audioPath, index: option.AudioStream.IndexRange},
+	}); err != nil {
+		return err
 	}
 
 	// Mux streams together
 	if muxer == nil {
-		return errors.New("muxer not available (FFmpeg required)")
+		return errors.New("muxer not available")
+	}
+
+	if pipe != nil {
+		_, _ = fmt.Fprintf(w, "Muxing streams to %s...\n", target)
+		if err := muxToPipe(ctx, videoPath, audioPath, tempDir, option.Container, pipe, video.Duration, muxer); err != nil {
+			return fmt.Errorf("failed to mux streams: %w", err)
+		}
+		return nil
 	}
 
 	_, _ = fmt.Fprintf(w, "Muxing streams...\n")
-	if err := muxer(ctx, videoPath, audioPath, outputPath); err != nil {
+	_, muxProgress := ffmpegProgressBar(w, "Muxing", video.Duration)
+	muxedPath := filepath.Join(tempDir, "muxed"+filepath.Ext(outputPath))
+	if err := muxer(ctx, videoPath, audioPath, muxedPath, video.Duration, muxProgress); err != nil {
 		return fmt.Errorf("failed to mux streams: %w", err)
 	}
+	if err := download.MoveFile(muxedPath, outputPath); err != nil {
+		return fmt.Errorf("failed to move muxed file into place: %w", err)
+	}
+
+	if keepSeparate {
+		_, _ = fmt.Fprintf(w, "Kept streams: %s, %s\n", displayText(videoPath), displayText(audioPath))
+	}
+	_, _ = fmt.Fprintf(w, "Download complete: %s\n", displayText(outputPath))
+	return nil
+}
+
+// separateStreamPath returns where --keep-separate keeps the video or audio
+// stream of outputPath. Audio in MP4 gets the .m4a extension audio players expect.
+// Example: "video.mkv" -> "video.video.webm", "video.audio.m4a"
+func separateStreamPath(outputPath, kind string, container youtube.Container) string {
+	ext := string(container)
+	if kind == "audio" && container == youtube.ContainerMP4 {
+		ext = "m4a"
+	}
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "." + kind + "." + ext
+}
 
-	_, _ = fmt.Fprintf(w, "Download complete: %s\n", outputPath)
+// muxToPipe muxes the streams and writes the result to pipe. FFmpeg's pipe output
+// is used when available; otherwise the streams are muxed into tempDir with muxer
+// and the file is copied to pipe.
+func muxToPipe(
+	ctx context.Context,
+	videoPath, audioPath, tempDir string,
+	container youtube.Container,
+	pipe io.Writer,
+	duration time.Duration,
+	muxer MuxerFunc,
+) error {
+	if ffmpeg.IsAvailable() {
+		return ffmpeg.MuxStreamsToWriter(ctx, videoPath, audioPath, string(container), pipe)
+	}
+
+	muxedPath := filepath.Join(tempDir, "muxed."+string(container))
+	if err := muxer(ctx, videoPath, audioPath, muxedPath, duration, nil); err != nil {
+		return err
+	}
+
+	f, err := os.Open(muxedPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := io.Copy(pipe, f); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+// audioFormats lists the --format values that download only the audio: MP3
+// transcodes it, FLAC and WAV decode it losslessly at its own sample rate, and
+// Opus and Ogg copy YouTube's Opus stream as is.
+var audioFormats = []string{"mp3", "opus", "ogg", "flac", "wav"}
+
+// isAudioOnly reports whether the options request an audio-only download.
+func isAudioOnly(opts *downloadOptions) bool {
+	return slices.Contains(audioFormats, strings.ToLower(opts.format)) || strings.EqualFold(opts.quality, "audio")
+}
+
+// audioFormat returns the container audio-only downloads are saved in: the
+// --format, or MP3 when --quality audio is given with a video format.
+func audioFormat(opts *downloadOptions) string {
+	if format := strings.ToLower(opts.format); slices.Contains(audioFormats, format) {
+		return format
+	}
+	return "mp3"
+}
+
+// validateTagFormat checks that downloads end up in a container flag can write
+// tags to: audio in any format but WAV, and MP4 for videos.
+func validateTagFormat(opts *downloadOptions, flag string) error {
+	switch {
+	case isAudioOnly(opts):
+		if audioFormat(opts) == "wav" {
+			return fmt.Errorf("%s cannot be used with --format wav", flag)
+		}
+	case opts.recodeVideo != "":
+		if !strings.EqualFold(opts.recodeVideo, "mp4") {
+			return fmt.Errorf("%s cannot be used with --recode-video %s", flag, opts.recodeVideo)
+		}
+	case parseContainer(opts.format) != youtube.ContainerMP4:
+		return fmt.Errorf("%s cannot be used with --format %s", flag, opts.format)
+	}
 	return nil
 }
 
-// downloadStreamWithProgress downloads a stream with a progress bar.
-func downloadStreamWithProgress(ctx context.Context, w io.Writer, downloader *download.Downloader, url, filePath, description string) error {
-	bar := progressbar.NewOptions64(
-		-1,
+const (
+	// progressBarWidth is the width of progress bars on terminals wide enough for it.
+	progressBarWidth = 40
+
+	// progressBarReserved is the room a progress bar leaves for the percentage,
+	// counters and times around it, on top of its description.
+	progressBarReserved = 30
+
+	// downloadStatsWidth is the room a download progress bar leaves for the
+	// sizes, speed and ETA added to its description.
+	downloadStatsWidth = 45
+)
+
+var (
+	// progressBarTheme is the standard progress bar theme, with a green bar.
+	progressBarTheme = progressbar.Theme{
+		Saucer:        "[green]=[reset]",
+		SaucerHead:    "[green]>[reset]",
+		SaucerPadding: " ",
+		BarStart:      "[",
+		BarEnd:        "]",
+	}
+
+	// plainProgressBarTheme is the theme for consoles without colors, such as
+	// legacy Windows consoles that would print the escape codes.
+	plainProgressBarTheme = progressbar.Theme{
+		Saucer:        "=",
+		SaucerHead:    ">",
+		SaucerPadding: " ",
+		BarStart:      "[",
+		BarEnd:        "]",
+	}
+)
+
+// newProgressBar creates a progress bar with the CLI's standard theme, or the
+// plain one if the console can't show colors, sized to fit the console's width.
+// A max of -1 means the total is not known yet. Extra options are applied after the theme.
+func newProgressBar(w io.Writer, maxValue int64, description string, extra ...progressbar.Option) *progressbar.ProgressBar {
+	c := detectConsole(w)
+	theme := plainProgressBarTheme
+	if c.color {
+		theme = progressBarTheme
+	}
+	options := []progressbar.Option{
 		progressbar.OptionSetWriter(w),
-		progressbar.OptionEnableColorCodes(true),
-		progressbar.OptionShowBytes(true),
-		progressbar.OptionSetWidth(40),
+		progressbar.OptionEnableColorCodes(c.color),
+		progressbar.OptionSetWidth(c.barWidth(progressBarWidth, len(description)+progressBarReserved)),
 		progressbar.OptionSetDescription(description),
-		progressbar.OptionSetTheme(progressbar.Theme{
-			Saucer:        "[green]=[reset]",
-			SaucerHead:    "[green]>[reset]",
-			SaucerPadding: " ",
-			BarStart:      "[",
-			BarEnd:        "]",
-		}),
+		progressbar.OptionSetTheme(theme),
 		progressbar.OptionOnCompletion(func() {
 			_, _ = fmt.Fprintln(w)
 		}),
+	}
+	return progressbar.NewOptions64(maxValue, append(options, extra...)...)
+}
+
+// downloadProgressBar creates a progress bar for a stream download and the callback
+// that drives it. Sizes, speed and ETA come from the downloader's progress reports
+// and are shown in the description, replacing the bar's own estimates.
+func downloadProgressBar(w io.Writer, description string) (*progressbar.ProgressBar, download.ProgressCallback) {
+	bar := newProgressBar(w, -1, description,
+		progressbar.OptionSetElapsedTime(false),
+		progressbar.OptionSetPredictTime(false),
+		progressbar.OptionSetWidth(detectConsole(w).barWidth(progressBarWidth, len(description)+progressBarReserved+downloadStatsWidth)),
 	)
 
-	progressCallback := func(p download.Progress) {
+	return bar, func(p download.Progress) {
 		if p.Total > 0 && bar.GetMax64() != p.Total {
 			bar.ChangeMax64(p.Total)
 		}
+		bar.Describe(fmt.Sprintf("%s %s", description, p))
 		_ = bar.Set64(p.Downloaded)
 	}
+}
 
-	err := downloader.DownloadStream(ctx, url, filePath, progressCallback)
-	if err != nil {
-		return err
+// ffmpegProgressBar creates a progress bar for an FFmpeg step, measured in
+// milliseconds of media time, and the callback that drives it.
+func ffmpegProgressBar(w io.Writer, description string, total time.Duration) (*progressbar.ProgressBar, ffmpeg.ProgressCallback) {
+	maxValue := total.Milliseconds()
+	if maxValue <= 0 {
+		maxValue = -1
 	}
+	bar := newProgressBar(w, maxValue, description)
 
-	_ = bar.Finish()
-	return nil
+	return bar, func(p ffmpeg.Progress) {
+		_ = bar.Set64(p.Processed.Milliseconds())
+		if p.Done {
+			_ = bar.Finish()
+		}
+	}
 }
 
 // parseQualityPreference converts a quality string to VideoQualityPreference.
@@ -386,8 +1778,18 @@ func parseContainer(format string) youtube.Container {
 	switch strings.ToLower(format) {
 	case "webm":
 		return youtube.ContainerWebM
+	case "mkv":
+		return youtube.ContainerMKV
 	case "mp3":
 		return youtube.ContainerMP3
+	case "opus":
+		return youtube.ContainerOpus
+	case "ogg":
+		return youtube.ContainerOGG
+	case "flac":
+		return youtube.ContainerFLAC
+	case "wav":
+		return youtube.ContainerWAV
 	case "mp4":
 		return youtube.ContainerMP4
 	default:
@@ -395,31 +1797,117 @@ func parseContainer(format string) youtube.Container {
 	}
 }
 
-// downloadPlaylist downloads all videos from a playlist.
+// downloadPlaylist downloads all videos from a playlist. Mixes are handed to downloadMix
+// because they can't be fetched like other playlists.
 func downloadPlaylist(
 	ctx context.Context,
 	w io.Writer,
-	playlistID string,
+	playlistID youtube.PlaylistID,
+	opts *downloadOptions,
+	src *sources,
+	downloader download.StreamDownloader,
+	muxer MuxerFunc,
+) error {
+	if youtube.IsMixPlaylistID(playlistID) {
+		return downloadMix(ctx, w, playlistID, "", opts, src, downloader, muxer)
+	}
+
+	playlist, videos, err := src.playlists.Fetch(ctx, playlistID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch playlist: %w", err)
+	}
+	return downloadPlaylistVideos(ctx, w, playlist, videos, opts, src, downloader, muxer)
+}
+
+// downloadMix downloads the first opts.mixLimit videos of a mix, starting from videoID
+// or, if it is empty, from the video the mix ID was generated from.
+func downloadMix(
+	ctx context.Context,
+	w io.Writer,
+	mixID youtube.PlaylistID,
+	videoID youtube.VideoID,
 	opts *downloadOptions,
-	fetcher *youtube.WatchPageFetcher,
-	downloader *download.Downloader,
+	src *sources,
+	downloader download.StreamDownloader,
 	muxer MuxerFunc,
 ) error {
-	_, _ = fmt.Fprintf(w, "Playlist download: %s\n", playlistID)
-	_, _ = fmt.Fprintf(w, "Note: Full playlist fetching requires additional API implementation.\n")
-	_, _ = fmt.Fprintf(w, "Currently, only individual video downloads are fully supported.\n")
+	if opts.mixLimit < 1 {
+		return errors.New("--mix-limit must be at least 1")
+	}
 
-	// For now, we'll indicate this is a placeholder for future implementation
-	// A complete implementation would:
-	// 1. Fetch the playlist page
-	// 2. Parse the initial data to get video list
-	// 3. Handle pagination for playlists with many videos
-	// 4. Download each video in sequence or parallel
+	playlist, videos, err := src.mixes.Fetch(ctx, mixID, youtube.MixOptions{VideoID: videoID, Limit: opts.mixLimit})
+	if err != nil {
+		return fmt.Errorf("failed to fetch mix: %w", err)
+	}
+	return downloadPlaylistVideos(ctx, w, playlist, videos, opts, src, downloader, muxer)
+}
 
-	// The youtube package has the playlist parsing logic, but we need to add
-	// a playlist page fetcher similar to WatchPageFetcher
+// downloadPlaylistVideos downloads the videos of a playlist in order, numbering each by
+// its position. A failed video doesn't stop the others; the failures are counted in the
+// returned error and listed in the summary printed at the end.
+func downloadPlaylistVideos(
+	ctx context.Context,
+	w io.Writer,
+	playlist *youtube.Playlist,
+	videos []youtube.PlaylistVideo,
+	opts *downloadOptions,
+	src *sources,
+	downloader download.StreamDownloader,
+	muxer MuxerFunc,
+) error {
+	_, _ = fmt.Fprintf(w, "Playlist: %s (%d videos)\n", displayText(playlist.Title), len(videos))
+
+	opts, ownReport := withReport(opts)
+	if opts.prefetch > 0 && len(videos) > 1 {
+		prefetcher := newVideoPrefetcher(ctx, src.videos, opts.prefetch)
+		defer prefetcher.stop()
+		copied := *opts
+		copied.prefetched = prefetcher
+		opts = &copied
+	}
+
+	failed := 0
+	for i := range videos {
+		v := &videos[i]
+		_, _ = fmt.Fprintf(w, "\n[%d/%d] %s\n", i+1, len(videos), displayText(v.Title))
+		if opts.prefetched != nil {
+			next := videos[i+1 : min(i+1+opts.prefetch, len(videos))]
+			ids := make([]youtube.VideoID, len(next))
+			for j := range next {
+				ids[j] = youtube.VideoID(next[j].ID)
+			}
+			opts.prefetched.start(ids...)
+		}
 
-	return errors.New("playlist download requires fetching playlist page - not yet implemented")
+		entry := &postprocess.PlaylistEntry{Playlist: playlist, Index: v.Index, Count: len(videos)}
+		if err := downloadSingleVideo(ctx, w, youtube.VideoID(v.ID), opts, src, downloader, muxer, entry); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			_, _ = fmt.Fprintf(w, "Failed to download %s: %v\n", v.ID, err)
+			failed++
+		}
+	}
+
+	if ownReport {
+		if err := finishReport(w, opts.report, opts.reportJSON); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return &PartialDownloadError{Failed: failed, Total: len(videos)}
+	}
+	return nil
+}
+
+// playlistNumber returns the number a playlist video's file name starts with:
+// its position, zero-padded to the width of the largest one. Videos that
+// aren't downloaded from a playlist aren't numbered.
+func playlistNumber(entry *postprocess.PlaylistEntry) string {
+	if entry == nil {
+		return ""
+	}
+	return fmt.Sprintf("%0*d", len(strconv.Itoa(entry.Count)), entry.Index)
 }
 
 // downloadChannel downloads all videos from a channel.
@@ -428,23 +1916,18 @@ func downloadChannel(
 	w io.Writer,
 	channel youtube.ChannelIdentifier,
 	opts *downloadOptions,
-	fetcher *youtube.WatchPageFetcher,
-	downloader *download.Downloader,
+	src *sources,
+	downloader download.StreamDownloader,
 	muxer MuxerFunc,
 ) error {
 	_, _ = fmt.Fprintf(w, "Channel download: %s (%s)\n", channel.Value, channel.Type)
 
-	// For channel IDs, we can convert to uploads playlist
-	if channel.Type == youtube.ChannelTypeID {
-		uploadsPlaylistID := channel.UploadsPlaylistID()
-		if uploadsPlaylistID != "" {
-			_, _ = fmt.Fprintf(w, "Converting to uploads playlist: %s\n", uploadsPlaylistID)
-			return downloadPlaylist(ctx, w, uploadsPlaylistID, opts, fetcher, downloader, muxer)
-		}
+	// Handles, custom URLs and users take a page request to resolve to the channel ID
+	channelID, err := src.channels.ResolveID(ctx, channel)
+	if err != nil {
+		return fmt.Errorf("failed to resolve channel: %w", err)
 	}
-
-	// For handles, custom URLs, and users, we would need to resolve to channel ID first
-	_, _ = fmt.Fprintf(w, "Note: Channel handles and custom URLs require additional resolution.\n")
-
-	return errors.New("channel download requires resolving channel ID - not yet implemented")
+	uploadsPlaylistID := youtube.ChannelToUploadsPlaylistID(channelID)
+	_, _ = fmt.Fprintf(w, "Converting to uploads playlist: %s\n", uploadsPlaylistID)
+	return downloadPlaylist(ctx, w, uploadsPlaylistID, opts, src, downloader, muxer)
 }
@@ -3,14 +3,20 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/postprocess"
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
 )
 
@@ -117,9 +123,9 @@ func TestDownloadCommandInvalidVideoID(t *testing.T) {
 		format:  "mp4",
 	}
 
-	fetcher := &youtube.WatchPageFetcher{
+	fetcher := newSources(&youtube.WatchPageFetcher{
 		Client: http.DefaultClient,
-	}
+	})
 	downloader := download.NewDownloader(http.DefaultClient)
 
 	buf := new(bytes.Buffer)
@@ -129,6 +135,16 @@ func TestDownloadCommandInvalidVideoID(t *testing.T) {
 	}
 }
 
+func TestDownloadCommandDefaultSearch(t *testing.T) {
+	opts := &downloadOptions{output: t.TempDir(), quality: "best", format: "mp4", searchTerms: true}
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: http.DefaultClient})
+
+	err := runDownloadWithDeps(context.Background(), new(bytes.Buffer), "rick astley", opts, fetcher, download.NewDownloader(http.DefaultClient), nil)
+	if err == nil || !strings.Contains(err.Error(), `search queries are not supported for download: "rick astley"`) {
+		t.Errorf("error = %v, want the input taken as a search", err)
+	}
+}
+
 // TestDownloadCommandVideoUnavailable tests error handling when video is unavailable.
 func TestDownloadCommandVideoUnavailable(t *testing.T) {
 	playerResponseJSON := `{
@@ -157,10 +173,10 @@ func TestDownloadCommandVideoUnavailable(t *testing.T) {
 		format:  "mp4",
 	}
 
-	fetcher := &youtube.WatchPageFetcher{
+	fetcher := newSources(&youtube.WatchPageFetcher{
 		Client:  server.Client(),
 		BaseURL: server.URL,
-	}
+	})
 	downloader := download.NewDownloader(server.Client())
 
 	buf := new(bytes.Buffer)
@@ -173,6 +189,99 @@ func TestDownloadCommandVideoUnavailable(t *testing.T) {
 	}
 }
 
+func TestNeedsConversion(t *testing.T) {
+	tests := []struct {
+		container youtube.Container
+		output    string
+		want      bool
+	}{
+		{youtube.ContainerMP4, "song.mp3", true},
+		{youtube.ContainerWebM, "video.mkv", true},
+		{youtube.ContainerMP4, "video.MP4", false},
+		{youtube.ContainerWebM, "video.webm", false},
+		{youtube.ContainerMP4, "video", false},
+	}
+	for _, tt := range tests {
+		if got := needsConversion(tt.container, tt.output); got != tt.want {
+			t.Errorf("needsConversion(%s, %q) = %v, want %v", tt.container, tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadAndConvert(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("audio stream"))
+	}))
+	defer server.Close()
+	downloader := download.NewDownloader(server.Client())
+
+	// The fake converter saves what it reads in upper case
+	convert := func(_ context.Context, src io.Reader, outputPath string) error {
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(outputPath, bytes.ToUpper(data), 0o644)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "music", "song.mp3")
+	buf := new(bytes.Buffer)
+	if err := downloadAndConvert(context.Background(), buf, server.URL, outputPath, downloader, convert); err != nil {
+		t.Fatalf("downloadAndConvert() error = %v", err)
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil || string(data) != "AUDIO STREAM" {
+		t.Errorf("output = %q, %v", data, err)
+	}
+	if !strings.Contains(buf.String(), "Download complete: "+outputPath) {
+		t.Errorf("output should report completion, got %q", buf.String())
+	}
+
+	failing := func(context.Context, io.Reader, string) error { return errors.New("unsupported codec") }
+	err = downloadAndConvert(context.Background(), buf, server.URL, outputPath, downloader, failing)
+	if err == nil || !strings.Contains(err.Error(), "unsupported codec") {
+		t.Errorf("expected the converter's error, got %v", err)
+	}
+}
+
+func TestDownloadSelectedStreams_FallsBack(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/251" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte("audio " + r.URL.Path))
+	}))
+	defer server.Close()
+	downloader := download.NewDownloader(server.Client())
+
+	manifest := &youtube.StreamManifest{AudioStreams: []youtube.AudioStreamInfo{
+		{StreamInfo: youtube.StreamInfo{Itag: 251, URL: server.URL + "/251", Container: youtube.ContainerWebM, Bitrate: 160000}, AudioCodec: "opus"},
+		{StreamInfo: youtube.StreamInfo{Itag: 250, URL: server.URL + "/250", Container: youtube.ContainerWebM, Bitrate: 70000}, AudioCodec: "opus"},
+	}}
+	video := &youtube.Video{ID: "dQw4w9WgXcQ"}
+	outputPath := filepath.Join(t.TempDir(), "song.webm")
+
+	buf := new(bytes.Buffer)
+	opts := &downloadOptions{format: "webm", quality: "audio"}
+	if err := downloadSelectedStreams(context.Background(), buf, video, manifest, outputPath, opts, downloader, nil); err != nil {
+		t.Fatalf("downloadSelectedStreams() error = %v", err)
+	}
+	if data, _ := os.ReadFile(outputPath); string(data) != "audio /250" {
+		t.Errorf("output = %q, want the fallback stream", data)
+	}
+	if !strings.Contains(buf.String(), "403 Forbidden") || !strings.Contains(buf.String(), "Falling back to opus") {
+		t.Errorf("output should report the fallback, got:\n%s", buf)
+	}
+
+	opts.noFallback = true
+	err := downloadSelectedStreams(context.Background(), io.Discard, video, manifest, outputPath, opts, downloader, nil)
+	var httpErr *download.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusForbidden {
+		t.Errorf("downloadSelectedStreams() with --no-fallback error = %v, want the 403", err)
+	}
+}
+
 // TestDownloadCommandWithMuxedStream tests downloading a muxed stream (video+audio combined).
 func TestDownloadCommandWithMuxedStream(t *testing.T) {
 	// Create player response with muxed stream
@@ -240,10 +349,10 @@ func TestDownloadCommandWithMuxedStream(t *testing.T) {
 		format:  "mp4",
 	}
 
-	fetcher := &youtube.WatchPageFetcher{
+	fetcher := newSources(&youtube.WatchPageFetcher{
 		Client:  server.Client(),
 		BaseURL: server.URL,
-	}
+	})
 	downloader := download.NewDownloader(server.Client())
 
 	buf := new(bytes.Buffer)
@@ -342,3 +451,1058 @@ func TestDownloadChannelURL(t *testing.T) {
 		t.Errorf("expected uploads playlist ID UUuAXFkgsw1L7xaCfnd5JJOw, got %s", uploadsPlaylistID)
 	}
 }
+
+// TestParseContainer tests output format parsing.
+func TestParseContainer(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected youtube.Container
+	}{
+		{"mp4", youtube.ContainerMP4},
+		{"webm", youtube.ContainerWebM},
+		{"mkv", youtube.ContainerMKV},
+		{"MKV", youtube.ContainerMKV},
+		{"mp3", youtube.ContainerMP3},
+		{"opus", youtube.ContainerOpus},
+		{"OGG", youtube.ContainerOGG},
+		{"flac", youtube.ContainerFLAC},
+		{"wav", youtube.ContainerWAV},
+		{"unknown", youtube.ContainerMP4},
+	}
+
+	for _, tt := range tests {
+		got := parseContainer(tt.input)
+		if got != tt.expected {
+			t.Errorf("parseContainer(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestDownloadCommandHasSplitSizeFlag(t *testing.T) {
+	rootCmd := newRootCmd()
+	downloadCmd, _, _ := rootCmd.Find([]string{"download"})
+
+	flag := downloadCmd.Flags().Lookup("split-size")
+	if flag == nil {
+		t.Error("download command should have --split-size flag")
+	}
+}
+
+// TestParseByteSize tests parsing of human-readable sizes.
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"512", 512, false},
+		{"25M", 25 << 20, false},
+		{"25MB", 25 << 20, false},
+		{"1.5G", 3 << 29, false},
+		{"100k", 100 << 10, false},
+		{"8 MiB", 8 << 20, false},
+		{"10X", 0, true},
+		{"M", 0, true},
+		{"-5M", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseByteSize(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseByteSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseByteSize(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestDownloadCommandInvalidSplitSize tests that an invalid split size fails before any network work.
+func TestDownloadCommandInvalidSplitSize(t *testing.T) {
+	opts := &downloadOptions{
+		output:    t.TempDir(),
+		quality:   "best",
+		format:    "mp4",
+		splitSize: "lots",
+	}
+
+	buf := new(bytes.Buffer)
+	err := runDownloadWithDeps(context.Background(), buf, "dQw4w9WgXcQ", opts, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "split-size") {
+		t.Errorf("expected split-size error, got %v", err)
+	}
+}
+
+func TestDownloadCommandHasRecodeFlags(t *testing.T) {
+	rootCmd := newRootCmd()
+	downloadCmd, _, _ := rootCmd.Find([]string{"download"})
+
+	for _, name := range []string{"recode-video", "recode-codec", "recode-crf", "recode-preset", "hwaccel"} {
+		if downloadCmd.Flags().Lookup(name) == nil {
+			t.Errorf("download command should have --%s flag", name)
+		}
+	}
+}
+
+// TestParseRecodeOptions tests validation of the recode flags.
+func TestParseRecodeOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      downloadOptions
+		wantCodec string
+		wantErr   bool
+	}{
+		{"disabled", downloadOptions{format: "mp4"}, "", false},
+		{"default codec for mp4", downloadOptions{format: "mp4", recodeVideo: "mp4", hwAccel: "none"}, "h264", false},
+		{"default codec for webm", downloadOptions{format: "mp4", recodeVideo: "webm", hwAccel: "none"}, "vp9", false},
+		{"explicit codec", downloadOptions{format: "mp4", recodeVideo: "mkv", recodeCodec: "hevc", hwAccel: "auto"}, "hevc", false},
+		{"unknown container", downloadOptions{format: "mp4", recodeVideo: "avi"}, "", true},
+		{"unknown codec", downloadOptions{format: "mp4", recodeVideo: "mp4", recodeCodec: "mpeg2"}, "", true},
+		{"h264 in webm", downloadOptions{format: "mp4", recodeVideo: "webm", recodeCodec: "h264", hwAccel: "none"}, "", true},
+		{"h265 in webm", downloadOptions{format: "mp4", recodeVideo: "WEBM", recodeCodec: "hevc", hwAccel: "none"}, "", true},
+		{"av1 in webm", downloadOptions{format: "mp4", recodeVideo: "webm", recodeCodec: "av1", hwAccel: "none"}, "av1", false},
+		{"vp9 in mov", downloadOptions{format: "mp4", recodeVideo: "mov", recodeCodec: "vp9", hwAccel: "none"}, "", true},
+		{"unknown hwaccel", downloadOptions{format: "mp4", recodeVideo: "mp4", hwAccel: "cuda"}, "", true},
+		{"negative crf", downloadOptions{format: "mp4", recodeVideo: "mp4", recodeCRF: -1}, "", true},
+		{"audio only", downloadOptions{format: "mp3", recodeVideo: "mp4"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRecodeOptions(&tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRecodeOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Codec != tt.wantCodec {
+				t.Errorf("parseRecodeOptions() codec = %q, want %q", got.Codec, tt.wantCodec)
+			}
+		})
+	}
+}
+
+func TestMuxStreams_FallsBackToFFmpeg(t *testing.T) {
+	if ffmpeg.IsAvailable() {
+		t.Skip("FFmpeg available, fallback error can't be observed")
+	}
+
+	dir := t.TempDir()
+	videoPath := filepath.Join(dir, "video.bin")
+	audioPath := filepath.Join(dir, "audio.bin")
+	for _, p := range []string{videoPath, audioPath} {
+		if err := os.WriteFile(p, []byte("not a media file"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Unrecognized inputs can't be muxed natively, so FFmpeg is required
+	err := muxStreams(context.Background(), videoPath, audioPath, filepath.Join(dir, "out.mp4"), 0, nil)
+	if !errors.Is(err, ffmpeg.ErrNotFound) {
+		t.Errorf("expected ffmpeg.ErrNotFound from the fallback, got %v", err)
+	}
+}
+
+// TestDownloadCommandWithRemixSource tests that --with-remix-source also downloads the original video.
+func TestDownloadCommandWithRemixSource(t *testing.T) {
+	pages := map[string]string{
+		"dQw4w9WgXcQ": `{"videoDetails":{"videoId":"dQw4w9WgXcQ","title":"Remix","author":"A","lengthSeconds":"10"},` +
+			`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[` +
+			`{"itag":18,"url":"STREAM_URL","mimeType":"video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"","height":360,"qualityLabel":"360p"}]}}`,
+		"9bZkp7q19f0": `{"videoDetails":{"videoId":"9bZkp7q19f0","title":"Original","author":"B","lengthSeconds":"10"},` +
+			`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[` +
+			`{"itag":18,"url":"STREAM_URL","mimeType":"video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"","height":360,"qualityLabel":"360p"}]}}`,
+	}
+	remixData := `{"shortsRemixAttributionViewModel":{"title":{"content":"Original"},` +
+		`"onTap":{"reelWatchEndpoint":{"videoId":"9bZkp7q19f0"}}}}`
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/watch" {
+			_, _ = w.Write([]byte("content"))
+			return
+		}
+		id := r.URL.Query().Get("v")
+		html := `<script>var ytInitialPlayerResponse = ` + strings.ReplaceAll(pages[id], "STREAM_URL", server.URL+"/stream") + `;</script>`
+		if id == "dQw4w9WgXcQ" {
+			html += `<script>var ytInitialData = ` + remixData + `;</script>`
+		}
+		_, _ = w.Write([]byte(html))
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	opts := &downloadOptions{output: tempDir, quality: "best", format: "mp4", remixSources: true}
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	downloader := download.NewDownloader(server.Client())
+
+	buf := new(bytes.Buffer)
+	if err := runDownloadWithDeps(context.Background(), buf, "dQw4w9WgXcQ", opts, fetcher, downloader, nil); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "Remix of: Original") {
+		t.Errorf("output should mention the remix source, got:\n%s", buf.String())
+	}
+	for _, name := range []string{"Remix.mp4", "Original.mp4"} {
+		if _, err := os.Stat(filepath.Join(tempDir, name)); err != nil {
+			t.Errorf("expected %s to be downloaded: %v", name, err)
+		}
+	}
+}
+
+// TestDownloadCommandToStdout tests that --output - writes the stream to the pipe.
+func TestDownloadCommandToStdout(t *testing.T) {
+	streamContent := "fake video content for piping"
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/watch" {
+			_, _ = w.Write([]byte(streamContent))
+			return
+		}
+		_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"dQw4w9WgXcQ","title":"Test Video","lengthSeconds":"10"},` +
+			`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[` +
+			`{"itag":18,"url":"` + server.URL + `/stream","mimeType":"video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"","height":360,"qualityLabel":"360p"}]}};</script>`))
+	}))
+	defer server.Close()
+
+	cwd := t.TempDir()
+	t.Chdir(cwd)
+
+	var pipe bytes.Buffer
+	opts := &downloadOptions{output: stdoutOutput, quality: "best", format: "mp4", pipe: &pipe}
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	downloader := download.NewDownloader(server.Client())
+
+	status := new(bytes.Buffer)
+	if err := runDownloadWithDeps(context.Background(), status, "dQw4w9WgXcQ", opts, fetcher, downloader, nil); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	if pipe.String() != streamContent {
+		t.Errorf("pipe received %q, want %q", pipe.String(), streamContent)
+	}
+	if strings.Contains(status.String(), streamContent) {
+		t.Error("media should not be written to the status output")
+	}
+	if entries, _ := os.ReadDir(cwd); len(entries) != 0 {
+		t.Errorf("no files should be created, found %d", len(entries))
+	}
+}
+
+func TestValidateStdoutOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    downloadOptions
+		wantErr bool
+	}{
+		{"file output", downloadOptions{output: ".", splitSize: "10M"}, false},
+		{"stdout", downloadOptions{output: stdoutOutput}, false},
+		{"stdout with split", downloadOptions{output: stdoutOutput, splitSize: "10M"}, true},
+		{"stdout with recode", downloadOptions{output: stdoutOutput, recodeVideo: "mp4"}, true},
+		{"stdout with remix source", downloadOptions{output: stdoutOutput, remixSources: true}, true},
+		{"stdout with verify", downloadOptions{output: stdoutOutput, verifyOutput: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStdoutOutput(&tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateStdoutOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tt.opts.output == stdoutOutput && tt.opts.pipe == nil {
+				t.Error("pipe should default to stdout")
+			}
+		})
+	}
+}
+
+func TestMuxToPipe_CopiesNativeMuxOutput(t *testing.T) {
+	if ffmpeg.IsAvailable() {
+		t.Skip("FFmpeg is available; its pipe output is used instead")
+	}
+
+	muxer := func(_ context.Context, _, _, outputPath string, _ time.Duration, _ ffmpeg.ProgressCallback) error {
+		return os.WriteFile(outputPath, []byte("muxed"), 0o644)
+	}
+
+	var pipe bytes.Buffer
+	if err := muxToPipe(context.Background(), "video.mp4", "audio.m4a", t.TempDir(), youtube.ContainerMP4, &pipe, 0, muxer); err != nil {
+		t.Fatalf("muxToPipe failed: %v", err)
+	}
+	if pipe.String() != "muxed" {
+		t.Errorf("pipe received %q, want %q", pipe.String(), "muxed")
+	}
+}
+
+func TestDownloadAndMux_KeepSeparate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "Test Video.mkv")
+	option := &youtube.DownloadOption{
+		Container:   youtube.Container("mkv"),
+		VideoStream: &youtube.VideoStreamInfo{StreamInfo: youtube.StreamInfo{URL: server.URL + "/video", Container: youtube.ContainerWebM}},
+		AudioStream: &youtube.AudioStreamInfo{StreamInfo: youtube.StreamInfo{URL: server.URL + "/audio", Container: youtube.ContainerMP4}},
+	}
+	var muxedFrom []string
+	muxer := func(_ context.Context, videoPath, audioPath, outputPath string, _ time.Duration, _ ffmpeg.ProgressCallback) error {
+		muxedFrom = []string{videoPath, audioPath}
+		return os.WriteFile(outputPath, []byte("muxed"), 0o644)
+	}
+
+	buf := new(bytes.Buffer)
+	err := downloadAndMux(context.Background(), buf, &youtube.Video{}, option, outputPath, nil, true, "", download.NewDownloader(server.Client()), muxer)
+	if err != nil {
+		t.Fatalf("downloadAndMux failed: %v", err)
+	}
+
+	videoPath := filepath.Join(dir, "Test Video.video.webm")
+	audioPath := filepath.Join(dir, "Test Video.audio.m4a")
+	if len(muxedFrom) != 2 || muxedFrom[0] != videoPath || muxedFrom[1] != audioPath {
+		t.Errorf("muxed %v, want %s and %s", muxedFrom, videoPath, audioPath)
+	}
+	for path, want := range map[string]string{videoPath: "/video", audioPath: "/audio", outputPath: "muxed"} {
+		if data, err := os.ReadFile(path); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", path, data, err, want)
+		}
+	}
+	if !strings.Contains(buf.String(), "Kept streams: ") {
+		t.Errorf("output doesn't list the kept streams:\n%s", buf)
+	}
+}
+
+func TestDownloadAndMux_TempDir(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	// The output's folder doesn't exist yet, as with --output-template "$author/$title"
+	outputPath := filepath.Join(t.TempDir(), "Author", "Test Video.mp4")
+	tempDir := t.TempDir()
+	option := &youtube.DownloadOption{
+		Container:   youtube.ContainerMP4,
+		VideoStream: &youtube.VideoStreamInfo{StreamInfo: youtube.StreamInfo{URL: server.URL + "/video", Container: youtube.ContainerMP4}},
+		AudioStream: &youtube.AudioStreamInfo{StreamInfo: youtube.StreamInfo{URL: server.URL + "/audio", Container: youtube.ContainerMP4}},
+	}
+	var muxedFrom string
+	muxer := func(_ context.Context, videoPath, _, outputPath string, _ time.Duration, _ ffmpeg.ProgressCallback) error {
+		muxedFrom = videoPath
+		return os.WriteFile(outputPath, []byte("muxed"), 0o644)
+	}
+
+	err := downloadAndMux(context.Background(), new(bytes.Buffer), &youtube.Video{}, option, outputPath, nil, false, tempDir, download.NewDownloader(server.Client()), muxer)
+	if err != nil {
+		t.Fatalf("downloadAndMux failed: %v", err)
+	}
+	if filepath.Dir(filepath.Dir(muxedFrom)) != tempDir {
+		t.Errorf("streams downloaded to %s, want a directory in %s", muxedFrom, tempDir)
+	}
+	if data, err := os.ReadFile(outputPath); err != nil || string(data) != "muxed" {
+		t.Errorf("output = %q, %v; want the muxed file", data, err)
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Errorf("temp directory not cleaned up: %v", entries)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(outputPath)); len(entries) != 1 {
+		t.Errorf("output directory = %v, want only the output", entries)
+	}
+}
+
+func TestKeepSeparate_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		opts downloadOptions
+		want string
+	}{
+		{"stdout", downloadOptions{output: stdoutOutput, keepSeparate: true}, "--keep-separate cannot be used with --output -"},
+		{"section", downloadOptions{output: t.TempDir(), section: "1:00-2:00", keepSeparate: true}, "--keep-separate cannot be used with --section"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runDownloadWithDeps(context.Background(), io.Discard, "dQw4w9WgXcQ", &tt.opts, newSources(&youtube.WatchPageFetcher{}), nil, nil)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("runDownloadWithDeps() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitChapters_Options(t *testing.T) {
+	tests := []struct {
+		name string
+		opts downloadOptions
+		want string
+	}{
+		{"stdout", downloadOptions{output: stdoutOutput, splitChapters: true}, "--split-chapters cannot be used with --output -"},
+		{"section", downloadOptions{output: t.TempDir(), section: "1:00-2:00", splitChapters: true}, "--split-chapters cannot be used with --section"},
+		{"split size", downloadOptions{output: t.TempDir(), splitSize: "25M", splitChapters: true}, "--split-chapters cannot be used with --split-size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runDownloadWithDeps(context.Background(), io.Discard, "dQw4w9WgXcQ", &tt.opts, newSources(&youtube.WatchPageFetcher{}), nil, nil)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("runDownloadWithDeps() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	pipeline := newPostProcessPipeline(io.Discard, &downloadOptions{splitChapters: true, exec: []string{"true"}}, nil)
+	if pipeline.Len() != 2 {
+		t.Errorf("pipeline has %d steps, want chapter splitting and the command", pipeline.Len())
+	}
+}
+
+func TestNormalizeAudio_Options(t *testing.T) {
+	tests := []struct {
+		name string
+		opts downloadOptions
+		want string
+	}{
+		{"stdout", downloadOptions{output: stdoutOutput, normalizeAudio: true, loudnessTarget: -16}, "--normalize-audio cannot be used with --output -"},
+		{"split size", downloadOptions{output: t.TempDir(), splitSize: "25M", normalizeAudio: true, loudnessTarget: -16}, "--normalize-audio cannot be used with --split-size"},
+		{"target", downloadOptions{output: t.TempDir(), normalizeAudio: true, loudnessTarget: 6}, "invalid --loudness-target: loudness target 6 LUFS isn't between -70 and -5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runDownloadWithDeps(context.Background(), io.Discard, "dQw4w9WgXcQ", &tt.opts, newSources(&youtube.WatchPageFetcher{}), nil, nil)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("runDownloadWithDeps() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	opts := &downloadOptions{normalizeAudio: true, loudnessTarget: -14, splitChapters: true}
+	if got := newPostProcessPipeline(io.Discard, opts, nil).Len(); got != 2 {
+		t.Errorf("pipeline has %d steps, want loudness normalization and chapter splitting", got)
+	}
+	if got := strings.Join(planSteps(&streamSelection{}, opts), ","); got != "normalize-audio:-14,split-chapters" {
+		t.Errorf("planSteps() = %q", got)
+	}
+}
+
+func TestValidateTagFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		opts downloadOptions
+		want string
+	}{
+		{"audio", downloadOptions{format: "webm", quality: "audio"}, ""},
+		{"mp3", downloadOptions{format: "mp3"}, ""},
+		{"flac", downloadOptions{format: "flac"}, ""},
+		{"wav", downloadOptions{format: "WAV"}, "--embed-lyrics cannot be used with --format wav"},
+		{"mp4", downloadOptions{format: "mp4"}, ""},
+		{"recoded to mp4", downloadOptions{format: "webm", recodeVideo: "MP4"}, ""},
+		{"webm", downloadOptions{format: "webm"}, "--embed-lyrics cannot be used with --format webm"},
+		{"recoded to mkv", downloadOptions{format: "mp4", recodeVideo: "mkv"}, "--embed-lyrics cannot be used with --recode-video mkv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTagFormat(&tt.opts, "--embed-lyrics")
+			if (tt.want == "" && err != nil) || (tt.want != "" && (err == nil || err.Error() != tt.want)) {
+				t.Errorf("validateTagFormat() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMusicMetadata_Options(t *testing.T) {
+	tests := []struct {
+		name string
+		opts downloadOptions
+		want string
+	}{
+		{"provider", downloadOptions{output: t.TempDir(), format: "mp3", musicMetadata: "spotify"},
+			`invalid --music-metadata: unknown music metadata provider "spotify": must be one of musicbrainz, itunes`},
+		{"format", downloadOptions{output: t.TempDir(), format: "webm", musicMetadata: "itunes"}, "--music-metadata cannot be used with --format webm"},
+		{"stdout", downloadOptions{output: stdoutOutput, format: "mp3", musicMetadata: "itunes"}, "--music-metadata cannot be used with --output -"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runDownloadWithDeps(context.Background(), io.Discard, "dQw4w9WgXcQ", &tt.opts, newSources(&youtube.WatchPageFetcher{}), nil, nil)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("runDownloadWithDeps() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	opts := &downloadOptions{format: "mp3", musicMetadata: "MusicBrainz", embedLyrics: true}
+	if got := newPostProcessPipeline(io.Discard, opts, nil).Len(); got != 2 {
+		t.Errorf("pipeline has %d steps, want music metadata and lyrics", got)
+	}
+	if got := strings.Join(planSteps(&streamSelection{}, opts), ","); got != "music-metadata:musicbrainz,embed-lyrics" {
+		t.Errorf("planSteps() = %q", got)
+	}
+}
+
+func TestReplayGain_Options(t *testing.T) {
+	tests := []struct {
+		name string
+		opts downloadOptions
+		want string
+	}{
+		{"video", downloadOptions{output: t.TempDir(), format: "mp4", replayGain: true}, "--replaygain can only be used with audio downloads"},
+		{"wav", downloadOptions{output: t.TempDir(), format: "wav", replayGain: true}, "--replaygain cannot be used with --format wav"},
+		{"stdout", downloadOptions{output: stdoutOutput, format: "mp3", replayGain: true}, "--replaygain cannot be used with --output -"},
+		{"upload", downloadOptions{output: t.TempDir(), format: "mp3", upload: &memoryStorage{}, replayGain: true}, "--replaygain cannot be used with --upload-to"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runDownloadWithDeps(context.Background(), io.Discard, "dQw4w9WgXcQ", &tt.opts, newSources(&youtube.WatchPageFetcher{}), nil, nil)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("runDownloadWithDeps() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	opts := &downloadOptions{format: "mp3", normalizeAudio: true, loudnessTarget: -14, replayGain: true}
+	if got := newPostProcessPipeline(io.Discard, opts, nil).Len(); got != 2 {
+		t.Errorf("pipeline has %d steps, want loudness and ReplayGain", got)
+	}
+	if got := strings.Join(planSteps(&streamSelection{}, opts), ","); got != "normalize-audio:-14,replaygain" {
+		t.Errorf("planSteps() = %q", got)
+	}
+	if !newPlanOptions(opts).downloadOptions().replayGain {
+		t.Error("plan options lost --replaygain")
+	}
+}
+
+func TestStoryboards_Options(t *testing.T) {
+	tests := []struct {
+		name string
+		opts downloadOptions
+		want string
+	}{
+		{"stdout", downloadOptions{output: stdoutOutput, writeStoryboards: true}, "--write-storyboards cannot be used with --output -"},
+		{"upload", downloadOptions{output: t.TempDir(), upload: &memoryStorage{}, splitStoryboards: true}, "--write-storyboards cannot be used with --upload-to"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runDownloadWithDeps(context.Background(), io.Discard, "dQw4w9WgXcQ", &tt.opts, newSources(&youtube.WatchPageFetcher{}), nil, nil)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("runDownloadWithDeps() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	opts := &downloadOptions{writeStoryboards: true, splitStoryboards: true, exec: []string{"true"}}
+	if got := newPostProcessPipeline(io.Discard, opts, nil).Len(); got != 2 {
+		t.Errorf("pipeline has %d steps, want storyboards and exec", got)
+	}
+	if got := strings.Join(planSteps(&streamSelection{}, opts), ","); got != "split-storyboards,exec:true" {
+		t.Errorf("planSteps() = %q", got)
+	}
+	if !newPlanOptions(opts).downloadOptions().splitStoryboards {
+		t.Error("plan options lost --split-storyboards")
+	}
+}
+
+func TestPlaylistTagging(t *testing.T) {
+	entry := &postprocess.PlaylistEntry{Playlist: &youtube.Playlist{Title: "Best of"}, Index: 7, Count: 120}
+	if got := playlistNumber(entry); got != "007" {
+		t.Errorf("playlistNumber() = %q, want %q", got, "007")
+	}
+	if got := playlistNumber(nil); got != "" {
+		t.Errorf("playlistNumber(nil) = %q, want no number", got)
+	}
+
+	tests := []struct {
+		name  string
+		opts  *downloadOptions
+		entry *postprocess.PlaylistEntry
+		want  int
+	}{
+		{"playlist mp3", &downloadOptions{format: "mp3"}, entry, 1},
+		{"playlist audio", &downloadOptions{format: "mp4", quality: "audio"}, entry, 1},
+		{"playlist flac", &downloadOptions{format: "flac"}, entry, 1},
+		{"playlist wav", &downloadOptions{format: "wav"}, entry, 0},
+		{"playlist video", &downloadOptions{format: "mp4"}, entry, 0},
+		{"single mp3", &downloadOptions{format: "mp3"}, nil, 0},
+	}
+	for _, tt := range tests {
+		if got := newPostProcessPipeline(io.Discard, tt.opts, tt.entry).Len(); got != tt.want {
+			t.Errorf("%s: pipeline has %d steps, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPostProcess_RunsExecCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "Test Video.mp4")
+	if err := os.WriteFile(outputPath, []byte("media"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(dir, "done.txt")
+
+	opts := &downloadOptions{exec: []string{"cp {} " + marker, "echo {title}"}}
+	buf := new(bytes.Buffer)
+	if err := postProcess(context.Background(), buf, &youtube.Video{Title: "Test Video"}, nil, outputPath, opts); err != nil {
+		t.Fatalf("postProcess failed: %v", err)
+	}
+
+	if data, err := os.ReadFile(marker); err != nil || string(data) != "media" {
+		t.Errorf("exec command did not run on the output: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Test Video") {
+		t.Errorf("exec output should be shown, got %q", buf.String())
+	}
+
+	opts.exec = []string{"exit 1;"}
+	if err := postProcess(context.Background(), buf, &youtube.Video{}, nil, outputPath, opts); err == nil {
+		t.Error("expected a failing exec command to fail post-processing")
+	}
+}
+
+// TestDownloadProgressBarShowsStats tests that the download bar shows the downloader's speed and ETA.
+func TestDownloadProgressBarShowsStats(t *testing.T) {
+	var buf bytes.Buffer
+	bar, callback := downloadProgressBar(&buf, "Video")
+
+	callback(download.Progress{Downloaded: 1 << 20, Total: 4 << 20, Speed: 1 << 20, ETA: 3 * time.Second})
+	_ = bar.Finish()
+
+	output := buf.String()
+	for _, want := range []string{"Video 1.0 MiB / 4.0 MiB (25.0%)", "1.0 MiB/s", "ETA 3s"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("progress output missing %q: %q", want, output)
+		}
+	}
+}
+
+// TestDownloadCommandInsufficientDiskSpace tests that a download larger than the free space fails before downloading.
+func TestDownloadCommandInsufficientDiskSpace(t *testing.T) {
+	formats := `"adaptiveFormats":[` +
+		`{"itag":137,"url":"STREAM_URL?itag=137","mimeType":"video/mp4; codecs=\"avc1.640028\"","height":1080,"qualityLabel":"1080p","contentLength":"1125899906842624"},` +
+		`{"itag":140,"url":"STREAM_URL?itag=140","mimeType":"audio/mp4; codecs=\"mp4a.40.2\"","bitrate":128000,"contentLength":"200"}]`
+	server := newPlanTestServer(t, formats)
+	outputDir := t.TempDir()
+	opts := &downloadOptions{output: outputDir, quality: "best", format: "mp4", hwAccel: "none"}
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	muxer := func(_ context.Context, _, _, _ string, _ time.Duration, _ ffmpeg.ProgressCallback) error {
+		t.Error("muxer should not run")
+		return nil
+	}
+
+	err := runDownloadWithDeps(context.Background(), new(bytes.Buffer), "dQw4w9WgXcQ", opts, fetcher, download.NewDownloader(server.Client()), muxer)
+
+	var spaceErr *download.InsufficientSpaceError
+	if !errors.As(err, &spaceErr) {
+		t.Fatalf("expected InsufficientSpaceError, got %v", err)
+	}
+	if spaceErr.Required < 1<<50 {
+		t.Errorf("required = %d, want at least the stream size", spaceErr.Required)
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 0 {
+		t.Error("nothing should be downloaded when there isn't enough space")
+	}
+}
+
+// TestDownloadCommandSimulate tests that --simulate picks formats and prints the
+// output file without downloading anything, even when it wouldn't fit on disk.
+func TestDownloadCommandSimulate(t *testing.T) {
+	formats := `"adaptiveFormats":[` +
+		`{"itag":137,"url":"STREAM_URL?itag=137","mimeType":"video/mp4; codecs=\"avc1.640028\"","height":1080,"qualityLabel":"1080p","contentLength":"1125899906842624"},` +
+		`{"itag":140,"url":"STREAM_URL?itag=140","mimeType":"audio/mp4; codecs=\"mp4a.40.2\"","bitrate":128000,"contentLength":"200"}]`
+	server := newPlanTestServer(t, formats)
+	outputDir := t.TempDir()
+	opts := &downloadOptions{output: outputDir, quality: "best", format: "mp4", hwAccel: "none", simulate: true, exec: []string{"false"}}
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	muxer := func(_ context.Context, _, _, _ string, _ time.Duration, _ ffmpeg.ProgressCallback) error {
+		t.Error("muxer should not run")
+		return nil
+	}
+
+	buf := new(bytes.Buffer)
+	if err := runDownloadWithDeps(context.Background(), buf, "dQw4w9WgXcQ", opts, fetcher, download.NewDownloader(server.Client()), muxer); err != nil {
+		t.Fatalf("simulate failed: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"Selected quality: 1080p", "Would download to: " + filepath.Join(outputDir, "Test Video.mp4"), "(estimated 1.0 PiB)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, output)
+		}
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 0 {
+		t.Error("nothing should be downloaded with --simulate")
+	}
+}
+
+// TestDownloadCommandOverwritePolicy tests that an existing output file is skipped
+// or renamed around before any stream is requested.
+func TestDownloadCommandOverwritePolicy(t *testing.T) {
+	streamRequests := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/watch" {
+			streamRequests++
+			_, _ = w.Write([]byte("new"))
+			return
+		}
+		_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"dQw4w9WgXcQ","title":"Test Video","lengthSeconds":"60"},` +
+			`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[` +
+			`{"itag":18,"url":"` + server.URL + `/stream","mimeType":"video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"","height":360,"qualityLabel":"360p"}]}};</script>`))
+	}))
+	defer server.Close()
+
+	outputDir := t.TempDir()
+	existing := filepath.Join(outputDir, "Test Video.mp4")
+	if err := os.WriteFile(existing, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	downloader := download.NewDownloader(server.Client())
+
+	opts := &downloadOptions{output: outputDir, quality: "best", format: "mp4", overwrite: download.SkipExisting}
+	buf := new(bytes.Buffer)
+	if err := runDownloadWithDeps(context.Background(), buf, "dQw4w9WgXcQ", opts, fetcher, downloader, nil); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Skipping existing file: "+existing) || streamRequests != 0 {
+		t.Errorf("expected the video to be skipped without stream requests, got %d requests and:\n%s", streamRequests, buf.String())
+	}
+
+	opts.overwrite = download.RenameExisting
+	buf.Reset()
+	if err := runDownloadWithDeps(context.Background(), buf, "dQw4w9WgXcQ", opts, fetcher, downloader, nil); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	renamed := filepath.Join(outputDir, "Test Video (1).mp4")
+	if data, _ := os.ReadFile(renamed); string(data) != "new" {
+		t.Errorf("expected the video in %s, got %q", renamed, data)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "old" {
+		t.Errorf("existing file was changed to %q", data)
+	}
+}
+
+func TestOverwritePolicyFlags(t *testing.T) {
+	tests := []struct {
+		policy             string
+		noOverwrite, force bool
+		want               download.OverwritePolicy
+	}{
+		{"overwrite", false, false, download.OverwriteExisting},
+		{"rename", false, false, download.RenameExisting},
+		{"overwrite", true, false, download.SkipExisting},
+		{"overwrite", false, true, download.OverwriteExisting},
+	}
+	for _, tt := range tests {
+		got, err := overwritePolicy(tt.policy, tt.noOverwrite, tt.force)
+		if err != nil || got != tt.want {
+			t.Errorf("overwritePolicy(%q, %v, %v) = %v, %v; want %v", tt.policy, tt.noOverwrite, tt.force, got, err, tt.want)
+		}
+	}
+	if _, err := overwritePolicy("keep", false, false); err == nil || !strings.Contains(err.Error(), "invalid --overwrite-policy") {
+		t.Errorf("expected an invalid policy error, got %v", err)
+	}
+}
+
+// TestDownloadCommandClip tests that clip URLs download the video the clip was cut from.
+func TestDownloadCommandClip(t *testing.T) {
+	const clipID = "UgkxU2HSeGL_NvmDJ-nQJrlLwllwMDBdGZFs"
+	clipData := `{"currentVideoEndpoint":{"watchEndpoint":{"videoId":"dQw4w9WgXcQ"}},` +
+		`"engagementPanels":[{"loopCommand":{"startTimeMs":"43000","endTimeMs":"58000","postId":"` + clipID + `"}}]}`
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/clip/" + clipID:
+			_, _ = w.Write([]byte(`<script>var ytInitialData = ` + clipData + `;</script>`))
+		case "/watch":
+			_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"dQw4w9WgXcQ","title":"Source","lengthSeconds":"120"},` +
+				`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[` +
+				`{"itag":18,"url":"` + server.URL + `/stream","mimeType":"video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"","height":360,"qualityLabel":"360p"}]}};</script>`))
+		default:
+			_, _ = w.Write([]byte("content"))
+		}
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	opts := &downloadOptions{output: tempDir, quality: "best", format: "mp4"}
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	downloader := download.NewDownloader(server.Client())
+
+	buf := new(bytes.Buffer)
+	err := runDownloadWithDeps(context.Background(), buf, "https://youtube.com/clip/"+clipID, opts, fetcher, downloader, nil)
+
+	if !strings.Contains(buf.String(), "Clip: 0:43-0:58 of dQw4w9WgXcQ") {
+		t.Errorf("output should describe the clip, got:\n%s", buf.String())
+	}
+	if !ffmpeg.IsAvailable() {
+		// The clip's section is cut with FFmpeg
+		if !errors.Is(err, ffmpeg.ErrNotFound) {
+			t.Errorf("expected ffmpeg.ErrNotFound without FFmpeg, got %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Cutting section 0:43-0:58") {
+		t.Errorf("output should mention the clip's section, got:\n%s", buf.String())
+	}
+}
+
+// TestDownloadCommandMix tests that mix URLs download the videos of the mix,
+// continuing past a video that fails.
+func TestDownloadCommandMix(t *testing.T) {
+	panel := `{"contents":{"twoColumnWatchNextResults":{"playlist":{"playlist":{"title":"Mix - First","contents":[` +
+		`{"playlistPanelVideoRenderer":{"videoId":"mixvideo000","title":{"simpleText":"First"},"navigationEndpoint":{"watchEndpoint":{"index":0}}}},` +
+		`{"playlistPanelVideoRenderer":{"videoId":"mixvideo001","title":{"simpleText":"Second"},"navigationEndpoint":{"watchEndpoint":{"index":1}}}},` +
+		`{"playlistPanelVideoRenderer":{"videoId":"mixvideo002","title":{"simpleText":"Third"},"navigationEndpoint":{"watchEndpoint":{"index":2}}}}]}}}}}`
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path == "/watch" && query.Get("list") != "":
+			_, _ = w.Write([]byte(`<script>var ytInitialData = ` + panel + `;</script>`))
+		case r.URL.Path == "/watch" && query.Get("v") == "mixvideo001":
+			_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"playabilityStatus":{"status":"ERROR","reason":"Video unavailable"}};</script>`))
+		case r.URL.Path == "/watch":
+			id := query.Get("v")
+			_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"` + id + `","title":"Video ` + id + `","lengthSeconds":"120"},` +
+				`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[` +
+				`{"itag":18,"url":"` + server.URL + `/stream","mimeType":"video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"","height":360,"qualityLabel":"360p"}]}};</script>`))
+		default:
+			_, _ = w.Write([]byte("content"))
+		}
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	opts := &downloadOptions{output: tempDir, quality: "best", format: "mp4", mixLimit: 3}
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	downloader := download.NewDownloader(server.Client())
+
+	buf := new(bytes.Buffer)
+	err := runDownloadWithDeps(context.Background(), buf, "https://www.youtube.com/watch?v=mixvideo000&list=RDmixvideo000", opts, fetcher, downloader, nil)
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 videos failed to download") {
+		t.Errorf("expected one failed video to be reported, got %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"Playlist: Mix - First (3 videos)", "[1/3] First", "[2/3] Second", "Failed to download mixvideo001", "[3/3] Third"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, output)
+		}
+	}
+	for _, name := range []string{"Video mixvideo000.mp4", "Video mixvideo002.mp4"} {
+		if _, err := os.Stat(filepath.Join(tempDir, name)); err != nil {
+			t.Errorf("expected %s to be downloaded: %v", name, err)
+		}
+	}
+
+	opts.mixLimit = 0
+	err = runDownloadWithDeps(context.Background(), io.Discard, "RDmixvideo000", opts, fetcher, downloader, nil)
+	if err == nil || !strings.Contains(err.Error(), "--mix-limit must be at least 1") {
+		t.Errorf("expected --mix-limit 0 to be rejected, got %v", err)
+	}
+}
+
+// TestDownloadCommandSectionValidation tests the checks on --section before anything is downloaded.
+func TestDownloadCommandSectionValidation(t *testing.T) {
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: http.DefaultClient, BaseURL: "http://127.0.0.1:0"})
+	downloader := download.NewDownloader(http.DefaultClient)
+
+	opts := &downloadOptions{output: t.TempDir(), format: "mp4", section: "3:00-1:00"}
+	err := runDownloadWithDeps(context.Background(), io.Discard, "dQw4w9WgXcQ", opts, fetcher, downloader, nil)
+	if !errors.Is(err, youtube.ErrInvalidTimeRange) {
+		t.Errorf("expected ErrInvalidTimeRange, got %v", err)
+	}
+
+	opts = &downloadOptions{output: stdoutOutput, format: "mp4", section: "1:00-2:00", pipe: io.Discard}
+	if err := runDownloadWithDeps(context.Background(), io.Discard, "dQw4w9WgXcQ", opts, fetcher, downloader, nil); err == nil ||
+		!strings.Contains(err.Error(), "--section cannot be used with --output -") {
+		t.Errorf("expected --section to be rejected with --output -, got %v", err)
+	}
+}
+
+// TestDownloadSection_StartsAfterEnd tests that a section beyond the video fails before downloading.
+func TestDownloadSection_StartsAfterEnd(t *testing.T) {
+	video := &youtube.Video{Title: "Short", Duration: 30 * time.Second}
+	selection := &streamSelection{
+		video:   &youtube.VideoStreamInfo{StreamInfo: youtube.StreamInfo{URL: "http://127.0.0.1:0/stream"}},
+		section: &youtube.TimeRange{Start: time.Minute, End: 2 * time.Minute},
+	}
+
+	err := downloadSelection(context.Background(), io.Discard, video, selection, filepath.Join(t.TempDir(), "out.mp4"), nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "starts after the end of the video") {
+		t.Errorf("expected the section to be rejected, got %v", err)
+	}
+}
+
+func TestDownloadCommandSectionFlag(t *testing.T) {
+	cmd := newDownloadCmd()
+	if err := cmd.ParseFlags([]string{"--download-sections", "*1:30-3:00"}); err != nil {
+		t.Fatalf("--download-sections should be accepted: %v", err)
+	}
+	if got := cmd.Flag("section").Value.String(); got != "*1:30-3:00" {
+		t.Errorf("--download-sections should set --section, got %q", got)
+	}
+}
+
+func TestOutputDuration(t *testing.T) {
+	video := &youtube.Video{Duration: 2 * time.Minute}
+	tests := []struct {
+		section string
+		want    time.Duration
+	}{
+		{"", 2 * time.Minute},
+		{"0:30-1:00", 30 * time.Second},
+		{"1:30-5:00", 30 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := outputDuration(video, &downloadOptions{section: tt.section}); got != tt.want {
+			t.Errorf("outputDuration(%q) = %v, want %v", tt.section, got, tt.want)
+		}
+	}
+	if got := sectionSourcePath(filepath.Join("out", "video.mp4")); got != filepath.Join("out", "video.full.mp4") {
+		t.Errorf("sectionSourcePath = %q", got)
+	}
+}
+
+func TestVideoOutputPath_RestrictFilenames(t *testing.T) {
+	video := &youtube.Video{ID: "dQw4w9WgXcQ", Title: "Café \U0001f389 night‏"}
+
+	opts := &downloadOptions{output: "out", format: "mp4", restrictName: true}
+	if got, want := videoOutputPath(video, opts, ""), filepath.Join("out", "Café _ night.mp4"); got != want {
+		t.Errorf("videoOutputPath() = %q, want %q", got, want)
+	}
+
+	opts.asciiName = true
+	if got, want := videoOutputPath(video, opts, ""), filepath.Join("out", "Cafe _ night.mp4"); got != want {
+		t.Errorf("videoOutputPath() with --ascii-filenames = %q, want %q", got, want)
+	}
+}
+
+func TestVideoOutputPath_Template(t *testing.T) {
+	video := &youtube.Video{
+		ID:         "dQw4w9WgXcQ",
+		Title:      "Never Gonna Give You Up",
+		Author:     youtube.Author{Name: "Rick Astley"},
+		UploadDate: time.Date(2009, 10, 25, 0, 0, 0, 0, time.UTC),
+	}
+	opts := &downloadOptions{output: "out", format: "mp4", template: "$author/$uploadDate - $title"}
+	want := filepath.Join("out", "Rick Astley", "2009-10-25 - Never Gonna Give You Up.mp4")
+	if got := videoOutputPath(video, opts, ""); got != want {
+		t.Errorf("videoOutputPath() = %q, want %q", got, want)
+	}
+	if got := storageKey(opts.output, want); got != "Rick Astley/2009-10-25 - Never Gonna Give You Up.mp4" {
+		t.Errorf("storageKey() = %q, want the folders kept", got)
+	}
+}
+
+func TestVideoOutputPath_AudioFormats(t *testing.T) {
+	video := &youtube.Video{ID: "dQw4w9WgXcQ", Title: "Never Gonna Give You Up"}
+	tests := []struct {
+		opts downloadOptions
+		want string
+	}{
+		{downloadOptions{output: "out", format: "mp3"}, "Never Gonna Give You Up.mp3"},
+		{downloadOptions{output: "out", format: "Opus"}, "Never Gonna Give You Up.opus"},
+		{downloadOptions{output: "out", format: "ogg"}, "Never Gonna Give You Up.ogg"},
+		{downloadOptions{output: "out", format: "flac"}, "Never Gonna Give You Up.flac"},
+		{downloadOptions{output: "out", format: "wav"}, "Never Gonna Give You Up.wav"},
+		{downloadOptions{output: "out", format: "mp4", quality: "audio"}, "Never Gonna Give You Up.mp3"},
+	}
+	for _, tt := range tests {
+		if !isAudioOnly(&tt.opts) {
+			t.Errorf("isAudioOnly(%+v) = false", tt.opts)
+		}
+		if got, want := videoOutputPath(video, &tt.opts, ""), filepath.Join("out", tt.want); got != want {
+			t.Errorf("videoOutputPath() with --format %s = %q, want %q", tt.opts.format, got, want)
+		}
+	}
+}
+
+func TestDownloadCommandHasFormatPreferenceFlags(t *testing.T) {
+	cmd := newDownloadCmd()
+	for _, name := range []string{"prefer-codec", "max-fps", "prefer-hdr"} {
+		if cmd.Flags().Lookup(name) == nil {
+			t.Errorf("download command should have --%s flag", name)
+		}
+	}
+}
+
+func TestSelectionPreferences(t *testing.T) {
+	prefs, err := selectionPreferences(&downloadOptions{preferCodec: "vp9,h264", maxFPS: 30, preferHDR: true})
+	if err != nil {
+		t.Fatalf("selectionPreferences: %v", err)
+	}
+	if len(prefs.Codecs) != 2 || prefs.Codecs[1] != "avc1" || prefs.MaxFramerate != 30 || !prefs.HDR {
+		t.Errorf("prefs = %+v", prefs)
+	}
+
+	if _, err := selectionPreferences(&downloadOptions{preferCodec: ","}); err == nil || !strings.Contains(err.Error(), "--prefer-codec") {
+		t.Errorf("error = %v, want an invalid --prefer-codec error", err)
+	}
+}
+
+func TestOutputExpectation(t *testing.T) {
+	selection := &streamSelection{
+		video: &youtube.VideoStreamInfo{VideoCodec: "avc1.640028"},
+		audio: &youtube.AudioStreamInfo{AudioCodec: "mp4a.40.2"},
+	}
+	want := outputExpectation(selection, time.Minute, "out.mp4")
+	if want.Duration != time.Minute || want.VideoCodec != "avc1.640028" || want.AudioCodec != "mp4a.40.2" {
+		t.Errorf("muxed expectation = %+v", want)
+	}
+
+	muxed := &streamSelection{video: &youtube.VideoStreamInfo{VideoCodec: "avc1.42001E, mp4a.40.2"}}
+	if want := outputExpectation(muxed, time.Minute, "out.mp4"); want.VideoCodec != "avc1.42001E" || want.AudioCodec != "" {
+		t.Errorf("single stream expectation = %+v", want)
+	}
+
+	if want := outputExpectation(selection, time.Minute, "out.MP3"); want.VideoCodec != "" || want.AudioCodec != "mp3" {
+		t.Errorf("mp3 expectation = %+v", want)
+	}
+	if want := outputExpectation(selection, time.Minute, "out.wav"); want.VideoCodec != "" || want.AudioCodec != "pcm_s16le" {
+		t.Errorf("wav expectation = %+v", want)
+	}
+
+	selection.section = &youtube.TimeRange{Start: time.Second, End: 10 * time.Second}
+	if want := outputExpectation(selection, 9*time.Second, "out.mp4"); want.Duration != 0 {
+		t.Errorf("section expectation checks duration %v", want.Duration)
+	}
+}
+
+func TestVerifyOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffprobe is a shell script")
+	}
+	dir := t.TempDir()
+	probe := `#!/bin/sh
+echo '{"streams":[{"codec_type":"video","codec_name":"h264"},{"codec_type":"audio","codec_name":"aac"}],"format":{"duration":"59.9"}}'
+`
+	if err := os.WriteFile(filepath.Join(dir, "ffprobe"), []byte(probe), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	selection := &streamSelection{
+		video: &youtube.VideoStreamInfo{VideoCodec: "avc1.640028"},
+		audio: &youtube.AudioStreamInfo{AudioCodec: "mp4a.40.2"},
+	}
+	buf := new(bytes.Buffer)
+	if err := verifyOutput(context.Background(), buf, selection, time.Minute, "out.mp4"); err != nil {
+		t.Fatalf("verifyOutput() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "Verified out.mp4") {
+		t.Errorf("output = %q", buf)
+	}
+
+	err := verifyOutput(context.Background(), buf, selection, 10*time.Minute, "out.mp4")
+	if !errors.Is(err, ffmpeg.ErrCorruptOutput) {
+		t.Errorf("verifyOutput() error = %v, want ErrCorruptOutput for a truncated file", err)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"strings"
 	"syscall"
 
+	ytdlhttp "github.com/SakuraBurst/golang-youtube-downloader/internal/http"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
 )
@@ -64,6 +66,14 @@ func WrapError(err error) error {
 		}
 	}
 
+	if errors.Is(err, youtube.ErrPlaylistUnavailable) {
+		return &UserFriendlyError{
+			Message:    "Playlist is unavailable",
+			Suggestion: "The playlist may be private or deleted. Check the link, or ask the owner to make it public or unlisted",
+			Cause:      err,
+		}
+	}
+
 	if errors.Is(err, youtube.ErrInvalidChannelID) {
 		return &UserFriendlyError{
 			Message:    "Invalid channel URL or ID",
@@ -72,6 +82,47 @@ func WrapError(err error) error {
 		}
 	}
 
+	if errors.Is(err, youtube.ErrChannelUnavailable) {
+		return &UserFriendlyError{
+			Message:    "Channel is unavailable",
+			Suggestion: "Check the handle or link. The channel may have been renamed, terminated, or made unavailable in your region",
+			Cause:      err,
+		}
+	}
+
+	if errors.Is(err, youtube.ErrMixUnavailable) {
+		return &UserFriendlyError{
+			Message:    "Mix is unavailable",
+			Suggestion: "Open the mix from a watch URL that includes the video it starts with, like:\n  - https://www.youtube.com/watch?v=VIDEO_ID&list=MIX_ID",
+			Cause:      err,
+		}
+	}
+
+	if errors.Is(err, youtube.ErrClipUnavailable) {
+		return &UserFriendlyError{
+			Message:    "Clip is unavailable",
+			Suggestion: "The clip or the video it was cut from may have been deleted or made private",
+			Cause:      err,
+		}
+	}
+
+	var upcomingErr *youtube.UpcomingVideoError
+	if errors.As(err, &upcomingErr) {
+		return &UserFriendlyError{
+			Message:    "Video has not started yet",
+			Suggestion: "This is an upcoming premiere or live stream. Use --wait-for-video 1m to wait and download it once it starts",
+			Cause:      err,
+		}
+	}
+
+	if errors.Is(err, youtube.ErrCommentsDisabled) {
+		return &UserFriendlyError{
+			Message:    "Comments are disabled for this video",
+			Suggestion: "The uploader may have turned comments off, or the video may be private or age-restricted",
+			Cause:      err,
+		}
+	}
+
 	if errors.Is(err, youtube.ErrUnresolvableQuery) {
 		return &UserFriendlyError{
 			Message:    "Unable to recognize the URL or ID",
@@ -84,7 +135,69 @@ func WrapError(err error) error {
 	if errors.Is(err, ffmpeg.ErrNotFound) {
 		return &UserFriendlyError{
 			Message:    "FFmpeg not found",
-			Suggestion: "FFmpeg is required for muxing video and audio streams.\nPlease install FFmpeg and make sure it's in your PATH.\nDownload from: https://ffmpeg.org/download.html",
+			Suggestion: "FFmpeg is required for these streams (only MP4 with M4A and WebM with WebM can be muxed without it),\nand for --recode-video and --split-size.\nRun \"ytdl ffmpeg install\" to download a static build, or install FFmpeg\nand make sure it's in your PATH (https://ffmpeg.org/download.html).",
+			Cause:      err,
+		}
+	}
+
+	// Check for proxy and restricted mode errors
+	if errors.Is(err, ytdlhttp.ErrUnsupportedProxy) {
+		return &UserFriendlyError{
+			Message:    "Invalid proxy URL",
+			Suggestion: "Use a URL like socks5h://127.0.0.1:9050 (Tor) or http://proxy.example.com:8080.\nThe socks5h scheme resolves hostnames through the proxy, which avoids DNS leaks.",
+			Cause:      err,
+		}
+	}
+
+	if errors.Is(err, ytdlhttp.ErrStreamRefused) {
+		return &UserFriendlyError{
+			Message: "The stream host refused the request through the proxy",
+			Suggestion: "YouTube's media servers often reject ranged requests from Tor exits and shared proxies,\n" +
+				"even when the video page loaded fine. You can:\n" +
+				"  - Use 'ytdl info' in restricted mode to read metadata only\n" +
+				"  - Request a new Tor circuit (e.g. restart Tor or send NEWNYM) and retry\n" +
+				"  - Use a different proxy with --proxy, or download without --restricted",
+			Cause: err,
+		}
+	}
+
+	if errors.Is(err, ytdlhttp.ErrNonEssentialRequest) {
+		return &UserFriendlyError{
+			Message:    "Request blocked by restricted mode",
+			Suggestion: "Thumbnails and telemetry are not fetched in restricted mode. Run without --restricted if you need them",
+			Cause:      err,
+		}
+	}
+
+	if errors.Is(err, ytdlhttp.ErrNotRecorded) {
+		return &UserFriendlyError{
+			Message:    "Request not found in the replayed fixtures",
+			Suggestion: "The command made a request the cassette doesn't have. Record it again with --record-fixtures",
+			Cause:      err,
+		}
+	}
+
+	// Check for the timeouts set by flags before other network timeouts
+	if errors.Is(err, youtube.ErrFetchTimeout) {
+		return &UserFriendlyError{
+			Message:    "Fetching the video info timed out",
+			Suggestion: "YouTube took longer than --fetch-timeout to answer. Try again, or raise --fetch-timeout",
+			Cause:      err,
+		}
+	}
+
+	if errors.Is(err, download.ErrTimeout) {
+		return &UserFriendlyError{
+			Message:    "Download timed out",
+			Suggestion: "The stream took longer than --download-timeout to download. Raise it for long videos or slow connections",
+			Cause:      err,
+		}
+	}
+
+	if errors.Is(err, ytdlhttp.ErrSocketTimeout) {
+		return &UserFriendlyError{
+			Message:    "Connection stalled",
+			Suggestion: "No data was sent or received for --socket-timeout. Check your internet connection, or raise --socket-timeout",
 			Cause:      err,
 		}
 	}
@@ -112,6 +225,14 @@ func WrapError(err error) error {
 		}
 	}
 
+	if msg := err.Error(); strings.Contains(msg, "socks connect") || strings.Contains(msg, "proxyconnect") {
+		return &UserFriendlyError{
+			Message:    "Could not connect to the proxy",
+			Suggestion: "Make sure the proxy is running and reachable (Tor listens on 127.0.0.1:9050 by default)",
+			Cause:      err,
+		}
+	}
+
 	var dnsErr *net.DNSError
 	if errors.As(err, &dnsErr) {
 		return &UserFriendlyError{
@@ -138,6 +259,17 @@ func WrapError(err error) error {
 		}
 	}
 
+	var spaceErr *download.InsufficientSpaceError
+	if errors.As(err, &spaceErr) {
+		return &UserFriendlyError{
+			Message: fmt.Sprintf("Not enough disk space in %s: %s required, %s available",
+				spaceErr.Dir, download.FormatBytes(spaceErr.Required), download.FormatBytes(spaceErr.Available)),
+			Suggestion: "Free up some disk space, or choose a directory on another drive with --output.\n" +
+				"Muxed downloads also need room for temporary files; set TMPDIR to move them.",
+			Cause: err,
+		}
+	}
+
 	var pathErr *os.PathError
 	if errors.As(err, &pathErr) {
 		if errors.Is(pathErr.Err, syscall.ENOSPC) {
@@ -149,8 +281,17 @@ func WrapError(err error) error {
 		}
 	}
 
-	// Check for video unavailable errors
+	// Check for region blocks before other unavailable errors
 	errStr := err.Error()
+	if strings.Contains(strings.ToLower(errStr), "in your country") {
+		return &UserFriendlyError{
+			Message:    "Video is not available in your country",
+			Suggestion: "Try --geo-bypass-country with a country where the video is available (e.g. --geo-bypass-country US), or use --proxy",
+			Cause:      err,
+		}
+	}
+
+	// Check for video unavailable errors
 	if strings.Contains(errStr, "unavailable") {
 		return &UserFriendlyError{
 			Message:    "Video is unavailable",
@@ -163,7 +304,7 @@ func WrapError(err error) error {
 	if strings.Contains(errStr, "429") || strings.Contains(strings.ToLower(errStr), "rate limit") {
 		return &UserFriendlyError{
 			Message:    "Too many requests - rate limited by YouTube",
-			Suggestion: "Wait a few minutes before trying again",
+			Suggestion: "Wait a few minutes before trying again, and space out requests with --sleep-requests (e.g. 2s) for large jobs",
 			Cause:      err,
 		}
 	}
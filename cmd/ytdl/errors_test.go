@@ -3,11 +3,14 @@ package main
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"net"
 	"os"
 	"strings"
 	"testing"
 
+	ytdlhttp "github.com/SakuraBurst/golang-youtube-downloader/internal/http"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
 )
@@ -98,6 +101,19 @@ func TestWrapErrorVideoUnavailable(t *testing.T) {
 	}
 }
 
+func TestWrapErrorRegionBlocked(t *testing.T) {
+	err := WrapError(errors.New("video unavailable: The uploader has not made this video available in your country"))
+
+	var userErr *UserFriendlyError
+	if !errors.As(err, &userErr) {
+		t.Fatal("expected UserFriendlyError")
+	}
+
+	if !strings.Contains(userErr.Suggestion, "--geo-bypass-country") {
+		t.Errorf("suggestion should mention --geo-bypass-country, got: %s", userErr.Suggestion)
+	}
+}
+
 func TestWrapErrorUnknown(t *testing.T) {
 	originalErr := errors.New("some random error")
 	err := WrapError(originalErr)
@@ -195,3 +211,121 @@ func TestWrapErrorNetworkTimeout(t *testing.T) {
 		t.Errorf("message should mention timeout, got: %s", userErr.Message)
 	}
 }
+
+func TestWrapErrorTimeoutFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantMessage string
+		wantFlag    string
+	}{
+		{"fetch", fmt.Errorf("failed to fetch video page: %w after 10s", youtube.ErrFetchTimeout), "Fetching the video info timed out", "--fetch-timeout"},
+		{"download", fmt.Errorf("%w after 1h0m0s", download.ErrTimeout), "Download timed out", "--download-timeout"},
+		{"socket", fmt.Errorf("%w: no data for 20s: %w", ytdlhttp.ErrSocketTimeout, &mockNetError{timeout: true}), "Connection stalled", "--socket-timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var userErr *UserFriendlyError
+			if !errors.As(WrapError(tt.err), &userErr) {
+				t.Fatal("expected UserFriendlyError")
+			}
+			if userErr.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", userErr.Message, tt.wantMessage)
+			}
+			if !strings.Contains(userErr.Suggestion, tt.wantFlag) {
+				t.Errorf("suggestion should mention %s, got: %s", tt.wantFlag, userErr.Suggestion)
+			}
+		})
+	}
+}
+
+func TestWrapErrorStreamRefused(t *testing.T) {
+	cause := fmt.Errorf("executing request: %w", ytdlhttp.ErrStreamRefused)
+	err := WrapError(cause)
+
+	var userErr *UserFriendlyError
+	if !errors.As(err, &userErr) {
+		t.Fatal("expected UserFriendlyError")
+	}
+	if !strings.Contains(userErr.Message, "proxy") {
+		t.Errorf("message should mention the proxy, got: %s", userErr.Message)
+	}
+	if !strings.Contains(userErr.Suggestion, "ranged requests") {
+		t.Errorf("suggestion should explain ranged request refusal, got: %s", userErr.Suggestion)
+	}
+}
+
+func TestWrapErrorProxyConnect(t *testing.T) {
+	err := WrapError(errors.New("socks connect tcp 127.0.0.1:9050->www.youtube.com:443: connection refused"))
+
+	var userErr *UserFriendlyError
+	if !errors.As(err, &userErr) {
+		t.Fatal("expected UserFriendlyError")
+	}
+	if !strings.Contains(userErr.Message, "proxy") {
+		t.Errorf("message should mention the proxy, got: %s", userErr.Message)
+	}
+}
+
+func TestWrapErrorInsufficientSpace(t *testing.T) {
+	cause := &download.InsufficientSpaceError{Dir: "/videos", Required: 3 << 30, Available: 512 << 20}
+	err := WrapError(fmt.Errorf("disk space check failed: %w", cause))
+
+	var userErr *UserFriendlyError
+	if !errors.As(err, &userErr) {
+		t.Fatal("expected UserFriendlyError")
+	}
+	for _, want := range []string{"/videos", "3.0 GiB required", "512.0 MiB available"} {
+		if !strings.Contains(userErr.Message, want) {
+			t.Errorf("message should contain %q, got: %s", want, userErr.Message)
+		}
+	}
+}
+
+func TestWrapErrorPlaylistUnavailable(t *testing.T) {
+	err := WrapError(fmt.Errorf("failed to fetch playlist: %w", youtube.ErrPlaylistUnavailable))
+
+	var userErr *UserFriendlyError
+	if !errors.As(err, &userErr) {
+		t.Fatal("expected UserFriendlyError")
+	}
+	if userErr.Message != "Playlist is unavailable" {
+		t.Errorf("unexpected message: %s", userErr.Message)
+	}
+}
+
+func TestWrapErrorChannelUnavailable(t *testing.T) {
+	err := WrapError(fmt.Errorf("failed to fetch channel: %w", youtube.ErrChannelUnavailable))
+
+	var userErr *UserFriendlyError
+	if !errors.As(err, &userErr) {
+		t.Fatal("expected UserFriendlyError")
+	}
+	if userErr.Message != "Channel is unavailable" {
+		t.Errorf("unexpected message: %s", userErr.Message)
+	}
+}
+
+func TestWrapErrorCommentsDisabled(t *testing.T) {
+	err := WrapError(fmt.Errorf("failed to fetch comments: %w", youtube.ErrCommentsDisabled))
+
+	var userErr *UserFriendlyError
+	if !errors.As(err, &userErr) {
+		t.Fatal("expected UserFriendlyError")
+	}
+	if userErr.Message != "Comments are disabled for this video" {
+		t.Errorf("unexpected message: %s", userErr.Message)
+	}
+}
+
+func TestWrapErrorUpcomingVideo(t *testing.T) {
+	err := WrapError(&youtube.UpcomingVideoError{VideoID: "dQw4w9WgXcQ", Reason: "Premieres in 2 hours"})
+
+	var userErr *UserFriendlyError
+	if !errors.As(err, &userErr) {
+		t.Fatal("expected UserFriendlyError")
+	}
+	if !strings.Contains(userErr.Suggestion, "--wait-for-video") {
+		t.Errorf("expected suggestion to mention --wait-for-video, got: %s", userErr.Suggestion)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+
+	ytdlhttp "github.com/SakuraBurst/golang-youtube-downloader/internal/http"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// Exit codes let scripts tell failure modes apart without parsing messages.
+const (
+	ExitOK             = 0
+	ExitFailure        = 1 // any error without a more specific code
+	ExitInvalidInput   = 2 // the URL or ID could not be recognized
+	ExitUnavailable    = 3 // the video, playlist, channel or mix can't be accessed
+	ExitNetwork        = 4 // a connection, DNS, timeout or rate limit error
+	ExitFFmpegMissing  = 5 // FFmpeg is needed but not installed
+	ExitPartialFailure = 6 // some videos of a playlist or channel failed to download
+)
+
+// exitCodesHelp documents the exit codes in the root command's help.
+const exitCodesHelp = `Exit codes:
+  0  success
+  1  other error
+  2  invalid URL or ID
+  3  video, playlist, channel or mix unavailable
+  4  network error (connection, DNS, timeout or rate limit)
+  5  FFmpeg not found
+  6  some videos of a playlist or channel failed to download`
+
+// PartialDownloadError is returned when some videos of a playlist or channel
+// failed to download while the others succeeded.
+type PartialDownloadError struct {
+	Failed int
+	Total  int
+}
+
+func (e *PartialDownloadError) Error() string {
+	return fmt.Sprintf("%d of %d videos failed to download", e.Failed, e.Total)
+}
+
+// exitCode returns the process exit code for an error returned by a command.
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var partialErr *PartialDownloadError
+	if errors.As(err, &partialErr) {
+		return ExitPartialFailure
+	}
+
+	if errors.Is(err, ffmpeg.ErrNotFound) {
+		return ExitFFmpegMissing
+	}
+
+	if errors.Is(err, youtube.ErrInvalidVideoID) ||
+		errors.Is(err, youtube.ErrInvalidPlaylistID) ||
+		errors.Is(err, youtube.ErrInvalidChannelID) ||
+		errors.Is(err, youtube.ErrUnresolvableQuery) {
+		return ExitInvalidInput
+	}
+
+	var unavailableErr *youtube.VideoUnavailableError
+	var upcomingErr *youtube.UpcomingVideoError
+	if errors.As(err, &unavailableErr) ||
+		errors.As(err, &upcomingErr) ||
+		errors.Is(err, youtube.ErrPlaylistUnavailable) ||
+		errors.Is(err, youtube.ErrChannelUnavailable) ||
+		errors.Is(err, youtube.ErrMixUnavailable) ||
+		errors.Is(err, youtube.ErrClipUnavailable) ||
+		errors.Is(err, youtube.ErrCommentsDisabled) {
+		return ExitUnavailable
+	}
+
+	var rateLimitErr *youtube.RateLimitError
+	var netErr net.Error
+	var urlErr *url.Error
+	if errors.As(err, &rateLimitErr) ||
+		errors.As(err, &netErr) ||
+		errors.As(err, &urlErr) ||
+		errors.Is(err, ytdlhttp.ErrStreamRefused) ||
+		errors.Is(err, youtube.ErrFetchTimeout) ||
+		errors.Is(err, download.ErrTimeout) {
+		return ExitNetwork
+	}
+
+	return ExitFailure
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, ExitOK},
+		{"unknown", errors.New("boom"), ExitFailure},
+		{"invalid video ID", fmt.Errorf("failed to parse: %w", youtube.ErrInvalidVideoID), ExitInvalidInput},
+		{"unresolvable query", youtube.ErrUnresolvableQuery, ExitInvalidInput},
+		{"video unavailable", &youtube.VideoUnavailableError{VideoID: "dQw4w9WgXcQ", Reason: "private"}, ExitUnavailable},
+		{"upcoming", &youtube.UpcomingVideoError{VideoID: "dQw4w9WgXcQ"}, ExitUnavailable},
+		{"playlist unavailable", youtube.ErrPlaylistUnavailable, ExitUnavailable},
+		{"dns", &net.DNSError{Err: "no such host", Name: "www.youtube.com"}, ExitNetwork},
+		{"rate limit", &youtube.RateLimitError{Message: "slow down"}, ExitNetwork},
+		{"fetch timeout", fmt.Errorf("failed to fetch video page: %w", youtube.ErrFetchTimeout), ExitNetwork},
+		{"download timeout", download.ErrTimeout, ExitNetwork},
+		{"ffmpeg missing", fmt.Errorf("failed to mux: %w", ffmpeg.ErrNotFound), ExitFFmpegMissing},
+		{"partial failure", &PartialDownloadError{Failed: 1, Total: 3}, ExitPartialFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCode_WrappedUserFriendlyError(t *testing.T) {
+	err := WrapError(youtube.ErrInvalidVideoID)
+	if got := exitCode(err); got != ExitInvalidInput {
+		t.Errorf("exitCode = %d, want %d", got, ExitInvalidInput)
+	}
+}
+
+func TestRootCommandHelpListsExitCodes(t *testing.T) {
+	if !strings.Contains(newRootCmd().Long, "Exit codes:") {
+		t.Error("root help should document the exit codes")
+	}
+}
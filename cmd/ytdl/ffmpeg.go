@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+)
+
+// ffmpegInstallOptions holds the flags of the ffmpeg install command.
+type ffmpegInstallOptions struct {
+	url    string
+	sha256 string
+}
+
+func newFFmpegCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ffmpeg",
+		Short: "Manage the FFmpeg used for muxing and conversion",
+		Long: `Manage the FFmpeg ytdl uses to mux separate video and audio streams,
+convert formats and embed subtitles.
+
+Run "ytdl ffmpeg install" to download a static build when FFmpeg isn't
+installed on the system.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			_ = cmd.Help()
+		},
+	}
+	cmd.AddCommand(newFFmpegInstallCmd())
+	cmd.AddCommand(newFFmpegPathCmd())
+	return cmd
+}
+
+func newFFmpegInstallCmd() *cobra.Command {
+	opts := &ffmpegInstallOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Download a static FFmpeg build",
+		Long: `Download a static FFmpeg build for this operating system and architecture
+into ytdl's own directory. It is used in preference to any other FFmpeg.
+
+The download is verified against the SHA-256 checksum published next to it,
+or the one given with --sha256, and is only installed if it matches.
+Running the command again replaces the installed build.`,
+		Example: `  ytdl ffmpeg install
+  ytdl ffmpeg install --url https://example.com/ffmpeg.zip --sha256 CHECKSUM`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := newHTTPClient(cmd)
+			if err != nil {
+				return WrapError(err)
+			}
+			w := statusWriter(cmd)
+			_, _ = fmt.Fprintln(w, "Downloading FFmpeg...")
+			path, err := ffmpeg.Install(cmd.Context(), ffmpeg.InstallOptions{Client: client, URL: opts.url, SHA256: opts.sha256})
+			if err != nil {
+				err = fmt.Errorf("failed to install FFmpeg: %w", err)
+				if errors.Is(err, ffmpeg.ErrChecksumMismatch) {
+					// Shown as it is, since the URL in it may hold anything, such as 404
+					return err
+				}
+				return WrapError(err)
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Installed FFmpeg to %s\n", path)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.url, "url", "", "Download this build instead (a zip archive or the executable)")
+	cmd.Flags().StringVar(&opts.sha256, "sha256", "", "Expected SHA-256 checksum of the download")
+
+	return cmd
+}
+
+func newFFmpegPathCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "path",
+		Short: "Print the path of the FFmpeg ytdl uses",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			path, err := ffmpeg.GetCliFilePath()
+			if err != nil {
+				return WrapError(err)
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), path)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFFmpegInstallCommand(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the install directory is only set through XDG_CACHE_HOME on Linux")
+	}
+	cacheHome := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+
+	binary := []byte("#!/bin/sh\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(binary)
+	}))
+	defer server.Close()
+	sum := sha256.Sum256(binary)
+
+	var out bytes.Buffer
+	rootCmd := newRootCmd()
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"ffmpeg", "install", "--url", server.URL + "/ffmpeg", "--sha256", hex.EncodeToString(sum[:])})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("ffmpeg install failed: %v", err)
+	}
+	installed := filepath.Join(cacheHome, "ytdl", "ffmpeg", "ffmpeg")
+	if !strings.Contains(out.String(), "Installed FFmpeg to "+installed) {
+		t.Errorf("output = %q", out.String())
+	}
+
+	out.Reset()
+	rootCmd = newRootCmd()
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"ffmpeg", "path"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("ffmpeg path failed: %v", err)
+	}
+	if strings.TrimSpace(out.String()) != installed {
+		t.Errorf("ffmpeg path = %q, want the installed build %s", out.String(), installed)
+	}
+}
+
+func TestFFmpegInstallCommandRejectsBadChecksum(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("binary"))
+	}))
+	defer server.Close()
+
+	rootCmd := newRootCmd()
+	rootCmd.SetOut(new(bytes.Buffer))
+	rootCmd.SetErr(new(bytes.Buffer))
+	// A URL with 403 or 404 in it isn't mistaken for an HTTP error
+	rootCmd.SetArgs([]string{"ffmpeg", "install", "--url", server.URL + "/403/404/ffmpeg", "--sha256", strings.Repeat("0", 64)})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("Execute() error = %v, want a checksum mismatch", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/cookiejar"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -25,6 +26,7 @@ Shows details including:
   - Title
   - Author/Channel
   - Duration
+  - Likes, publish date and category
   - Available formats and qualities`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -54,23 +56,30 @@ func runInfo(cmd *cobra.Command, url, cookieFile string) error {
 		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Loaded %d cookies from %s\n", len(cookies), cookieFile)
 	}
 
-	// Create HTTP client with cookie jar if cookies are provided
-	client := http.DefaultClient
+	client, err := newHTTPClient(cmd)
+	if err != nil {
+		return WrapError(err)
+	}
+
+	// Attach a cookie jar if cookies are provided
 	if len(cookies) > 0 {
 		jar, err := cookiejar.New(nil)
 		if err != nil {
 			return fmt.Errorf("failed to create cookie jar: %w", err)
 		}
-		client = &http.Client{Jar: jar}
+		withJar := *client
+		withJar.Jar = jar
+		client = &withJar
 	}
 
 	// Create fetcher with cookies
-	fetcher := &youtube.WatchPageFetcher{
-		Client:  client,
-		Cookies: cookies,
+	fetcher, err := newWatchPageFetcher(cmd, client)
+	if err != nil {
+		return WrapError(err)
 	}
+	fetcher.Cookies = cookies
 
-	err := runInfoWithFetcher(cmd.Context(), cmd.OutOrStdout(), url, fetcher)
+	err = runInfoWithFetcher(cmd.Context(), cmd.OutOrStdout(), url, fetcher)
 	if err != nil {
 		// Wrap the error with user-friendly message
 		return WrapError(err)
@@ -81,11 +90,12 @@ func runInfo(cmd *cobra.Command, url, cookieFile string) error {
 // runInfoWithFetcher implements the info command logic with a configurable fetcher.
 // This allows for dependency injection in tests.
 func runInfoWithFetcher(ctx context.Context, w io.Writer, urlStr string, fetcher *youtube.WatchPageFetcher) error {
-	// Parse the video ID from the URL
-	videoID, err := youtube.ParseVideoID(urlStr)
-	if err != nil {
-		return fmt.Errorf("invalid video URL or ID: %w", err)
+	// Parse the video ID from the URL, expanding share and short links if needed
+	query, err := youtube.ResolveQueryContext(ctx, urlStr, youtube.NewURLExpander(fetcher.Client))
+	if err != nil || query.Type != youtube.QueryTypeVideo {
+		return fmt.Errorf("invalid video URL or ID: %w", youtube.ErrInvalidVideoID)
 	}
+	videoID := query.VideoID
 
 	// Fetch the watch page
 	_, _ = fmt.Fprintf(w, "Fetching info for video: %s\n\n", videoID)
@@ -101,15 +111,14 @@ func runInfoWithFetcher(ctx context.Context, w io.Writer, urlStr string, fetcher
 		return fmt.Errorf("failed to extract video data: %w", err)
 	}
 
-	// Check playability status
-
-	fmt.Println(playerResponse)
-	if playerResponse.PlayabilityStatus.Status != "OK" {
-		reason := playerResponse.PlayabilityStatus.Reason
+	// Check playability status; upcoming videos still have their details
+	status := &playerResponse.PlayabilityStatus
+	if status.Status != "OK" && !status.IsUpcoming() {
+		reason := status.Reason
 		if reason == "" {
 			reason = "unknown reason"
 		}
-		return fmt.Errorf("video unavailable: %s", reason)
+		return &youtube.VideoUnavailableError{VideoID: videoID.String(), Reason: reason}
 	}
 
 	// Convert to Video struct
@@ -124,21 +133,63 @@ func runInfoWithFetcher(ctx context.Context, w io.Writer, urlStr string, fetcher
 	_, _ = fmt.Fprintf(w, "Duration: %s\n", video.DurationString())
 	_, _ = fmt.Fprintf(w, "Views:    %d\n", video.ViewCount)
 
-	if video.IsLive {
+	if data, err := watchPage.ExtractWatchData(); err == nil {
+		video.RemixOf = data.RemixSource
+		video.Heatmap = data.Heatmap
+		video.LikeCount = data.LikeCount
+		video.Chapters = data.Chapters
+	}
+	if video.LikeCount > 0 {
+		_, _ = fmt.Fprintf(w, "Likes:    %d\n", video.LikeCount)
+	}
+	if !video.PublishDate.IsZero() {
+		_, _ = fmt.Fprintf(w, "Published: %s\n", formatPublishDate(video.PublishDate))
+	}
+	if video.Category != "" {
+		_, _ = fmt.Fprintf(w, "Category: %s\n", video.Category)
+	}
+	if playerResponse.Microformat != nil && !video.IsFamilySafe {
+		_, _ = fmt.Fprintf(w, "Rating:   Age-restricted\n")
+	}
+
+	switch {
+	case status.IsUpcoming() && !video.ScheduledStart.IsZero():
+		_, _ = fmt.Fprintf(w, "Status:   Upcoming, scheduled for %s\n", video.ScheduledStart.Local().Format("2006-01-02 15:04 MST"))
+	case status.IsUpcoming():
+		_, _ = fmt.Fprintf(w, "Status:   Upcoming\n")
+	case video.IsLive:
 		_, _ = fmt.Fprintf(w, "Status:   Live Stream\n")
 	}
 
+	if video.RemixOf != nil {
+		_, _ = fmt.Fprintf(w, "Remix of: %s\n", remixSourceLabel(video.RemixOf))
+	}
+
+	if peak := youtube.MostReplayed(video.Heatmap, 1); len(peak) > 0 {
+		_, _ = fmt.Fprintf(w, "Most replayed: %s\n", peak[0])
+	}
+
 	// Display available formats
 	if playerResponse.StreamingData != nil {
 		manifest := playerResponse.StreamingData.GetStreamManifest()
-		displayStreamInfo(w, manifest)
+		displayStreamInfo(w, manifest, video.Duration)
 	}
 
 	return nil
 }
 
-// displayStreamInfo outputs information about available streams.
-func displayStreamInfo(w io.Writer, manifest *youtube.StreamManifest) {
+// formatPublishDate formats a publish date with its time when YouTube gave one;
+// dates of older videos are at midnight UTC and shown alone.
+func formatPublishDate(t time.Time) string {
+	if t.Location() == time.UTC && t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format(time.DateOnly)
+	}
+	return t.Local().Format("2006-01-02 15:04 MST")
+}
+
+// displayStreamInfo outputs information about available streams, with their
+// sizes estimated from the bitrate and duration when YouTube doesn't report them.
+func displayStreamInfo(w io.Writer, manifest *youtube.StreamManifest, duration time.Duration) {
 	_, _ = fmt.Fprintf(w, "\nAvailable Formats:\n")
 
 	// Video streams
@@ -150,7 +201,7 @@ func displayStreamInfo(w io.Writer, manifest *youtube.StreamManifest) {
 			if quality == "" {
 				quality = youtube.QualityLabel(vs.Height)
 			}
-			_, _ = fmt.Fprintf(w, "    - %s (%s, %s)\n", quality, vs.Container, vs.VideoCodec)
+			_, _ = fmt.Fprintf(w, "    - %s (%s, %s, %s)%s\n", quality, vs.Container, vs.VideoCodec, streamSize(&vs.StreamInfo, duration), accessNote(&vs.StreamInfo))
 		}
 	}
 
@@ -159,7 +210,7 @@ func displayStreamInfo(w io.Writer, manifest *youtube.StreamManifest) {
 		_, _ = fmt.Fprintf(w, "\n  Audio:\n")
 		for i := range manifest.AudioStreams {
 			as := &manifest.AudioStreams[i]
-			_, _ = fmt.Fprintf(w, "    - %s (%s, %dkbps)\n", as.Container, as.AudioCodec, as.Bitrate/1000)
+			_, _ = fmt.Fprintf(w, "    - %s (%s, %dkbps, %s)%s\n", as.Container, as.AudioCodec, as.Bitrate/1000, streamSize(&as.StreamInfo, duration), accessNote(&as.StreamInfo))
 		}
 	}
 
@@ -172,7 +223,25 @@ func displayStreamInfo(w io.Writer, manifest *youtube.StreamManifest) {
 			if quality == "" {
 				quality = youtube.QualityLabel(ms.Height)
 			}
-			_, _ = fmt.Fprintf(w, "    - %s (%s)\n", quality, ms.VideoStreamInfo.Container)
+			_, _ = fmt.Fprintf(w, "    - %s (%s, %s)%s\n", quality, ms.VideoStreamInfo.Container, streamSize(&ms.VideoStreamInfo.StreamInfo, duration), accessNote(&ms.VideoStreamInfo.StreamInfo))
 		}
 	}
 }
+
+// accessNote marks the streams of the format list that can't be downloaded, as
+// in " - unavailable, it requires YouTube Premium".
+func accessNote(info *youtube.StreamInfo) string {
+	if reason := info.InaccessibleReason(); reason != "" {
+		return " - unavailable, it " + reason
+	}
+	return ""
+}
+
+// streamSize formats the size of a stream for the format list.
+func streamSize(info *youtube.StreamInfo, duration time.Duration) string {
+	size := info.EstimatedSize(duration)
+	if size == 0 {
+		return "unknown size"
+	}
+	return formatSize(size, info.SizeIsExact())
+}
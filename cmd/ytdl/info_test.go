@@ -5,8 +5,10 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
 )
@@ -48,6 +50,21 @@ func TestInfoCommandAcceptsURL(t *testing.T) {
 	}
 }
 
+func TestInfoCommandReplaysFixtures(t *testing.T) {
+	rootCmd := newRootCmd()
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	rootCmd.SetArgs([]string{"info", "--no-cache", "--replay-fixtures", filepath.Join("..", "..", "pkg", "ytdl", "testdata", "watch.cassette.json"), "dQw4w9WgXcQ"})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("info command failed: %v\n%s", err, buf)
+	}
+	if !strings.Contains(buf.String(), "Recorded Video") {
+		t.Errorf("output = %q, want the recorded video's title", buf.String())
+	}
+}
+
 func TestInfoCommandHelp(t *testing.T) {
 	rootCmd := newRootCmd()
 	buf := new(bytes.Buffer)
@@ -197,3 +214,68 @@ func TestInfoCommandVideoUnavailable(t *testing.T) {
 		t.Errorf("error should mention unavailable, got: %v", err)
 	}
 }
+
+func TestInfoCommandShowsMostReplayed(t *testing.T) {
+	html := `<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"dQw4w9WgXcQ","title":"Test","lengthSeconds":"212"},"playabilityStatus":{"status":"OK"}};</script>` +
+		`<script>var ytInitialData = {"frameworkUpdates":{"entityBatchUpdate":{"mutations":[{"payload":{"macroMarkersListEntity":{"markerType":"MARKER_TYPE_HEATMAP","markersList":{"markers":[` +
+		`{"startMillis":"0","durationMillis":"2120","intensityScoreNormalized":0.5},{"startMillis":"84800","durationMillis":"2120","intensityScoreNormalized":1}]}}}}]}}};</script>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(html))
+	}))
+	defer server.Close()
+
+	fetcher := &youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL}
+	buf := new(bytes.Buffer)
+	if err := runInfoWithFetcher(context.Background(), buf, "dQw4w9WgXcQ", fetcher); err != nil {
+		t.Fatalf("runInfoWithFetcher failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Most replayed: 1:24-1:26") {
+		t.Errorf("output should show the most replayed segment, got:\n%s", buf.String())
+	}
+}
+
+func TestInfoCommandShowsMicroformatDetails(t *testing.T) {
+	html := `<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"dQw4w9WgXcQ","title":"Test","lengthSeconds":"212"},"playabilityStatus":{"status":"OK"},` +
+		`"microformat":{"playerMicroformatRenderer":{"category":"Music","publishDate":"2009-10-24","isFamilySafe":false}}};</script>` +
+		`<script>var ytInitialData = {"mutations":[{"payload":{"likeCountEntity":{"likeCountIfIndifferentNumber":"18000000"}}}]};</script>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(html))
+	}))
+	defer server.Close()
+
+	fetcher := &youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL}
+	buf := new(bytes.Buffer)
+	if err := runInfoWithFetcher(context.Background(), buf, "dQw4w9WgXcQ", fetcher); err != nil {
+		t.Fatalf("runInfoWithFetcher failed: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{"Likes:    18000000", "Published: 2009-10-24", "Category: Music", "Rating:   Age-restricted"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestDisplayStreamInfoShowsSizes(t *testing.T) {
+	manifest := &youtube.StreamManifest{
+		VideoStreams: []youtube.VideoStreamInfo{
+			{StreamInfo: youtube.StreamInfo{Quality: "1080p", Container: youtube.ContainerMP4, ContentLength: 2 * 1024 * 1024}, VideoCodec: "avc1"},
+			{StreamInfo: youtube.StreamInfo{Quality: "1080p Premium", Container: youtube.ContainerMP4, ContentLength: 1024, Premium: true}, VideoCodec: "vp9"},
+		},
+		AudioStreams: []youtube.AudioStreamInfo{
+			{StreamInfo: youtube.StreamInfo{Container: youtube.ContainerWebM, Bitrate: 160000, AverageBitrate: 1024 * 1024}, AudioCodec: "opus"},
+		},
+		MuxedStreams: []youtube.MuxedStreamInfo{
+			{VideoStreamInfo: youtube.VideoStreamInfo{StreamInfo: youtube.StreamInfo{Quality: "360p", Container: youtube.ContainerMP4}}},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	displayStreamInfo(buf, manifest, 8*time.Second)
+	output := buf.String()
+	for _, want := range []string{"1080p (mp4, avc1, 2.0 MiB)", "webm (opus, 160kbps, ~1.0 MiB)", "360p (mp4, unknown size)", "1080p Premium (mp4, vp9, 1.0 KiB) - unavailable, it requires YouTube Premium"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, output)
+		}
+	}
+}
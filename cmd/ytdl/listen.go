@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultListenPort is the port the listen command uses without --port.
+const defaultListenPort = 8765
+
+// defaultAllowedOrigins are the origins that may use the listen API without
+// --allow-origin: YouTube pages, for bookmarklets, and browser extensions.
+var defaultAllowedOrigins = []string{
+	"https://www.youtube.com",
+	"https://m.youtube.com",
+	"https://music.youtube.com",
+	"chrome-extension://*",
+	"moz-extension://*",
+	"safari-web-extension://*",
+}
+
+// listenOptions holds the flags of the listen command.
+type listenOptions struct {
+	port         int
+	output       string
+	jobs         int
+	allowOrigins []string
+}
+
+func newListenCmd() *cobra.Command {
+	opts := &listenOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "listen",
+		Short: "Accept downloads from a browser extension or bookmarklet",
+		Long: `Listen on localhost for downloads sent by a browser extension or bookmarklet.
+
+This is the API of "ytdl serve" on 127.0.0.1 with CORS support, so pages and
+extensions can call it from the browser:
+
+  POST   /downloads        Queue a download. The body is a JSON object with "url"
+                           and optionally "quality", "format", "audio_only" and
+                           "priority", as for ytdl serve.
+  GET    /downloads/{id}   Get a download's status and progress.
+  GET    /ping             Check that ytdl is listening: {"name": "ytdl", ...}.
+
+Only YouTube pages and browser extensions may call the API by default, so
+other sites can't queue downloads; --allow-origin adds origins, or allows
+any with "*". Requests without an Origin header, such as curl's, are accepted.`,
+		Example: `  ytdl listen
+  ytdl listen --port 9000 -o ~/Videos --allow-origin https://example.com
+
+  # Bookmarklet sending the current tab:
+  javascript:fetch('http://127.0.0.1:8765/downloads',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify({url:location.href,quality:'1080p'})})`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if opts.port < 1 || opts.port > 65535 {
+				return errors.New("--port must be between 1 and 65535")
+			}
+			serve := &serveOptions{
+				addr:           "127.0.0.1:" + strconv.Itoa(opts.port),
+				output:         opts.output,
+				jobs:           opts.jobs,
+				watchInterval:  defaultWatchInterval,
+				allowedOrigins: append(slices.Clone(defaultAllowedOrigins), opts.allowOrigins...),
+			}
+			if err := validateServe(serve); err != nil {
+				return err
+			}
+			if err := runServe(cmd, serve); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&opts.port, "port", defaultListenPort, "Port to listen on at 127.0.0.1")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", ".", "Output directory for downloaded files")
+	cmd.Flags().IntVar(&opts.jobs, "jobs", 1, "Number of downloads to run at the same time")
+	cmd.Flags().StringSliceVar(&opts.allowOrigins, "allow-origin", nil, `Additional origin allowed to call the API, or "*" for any (repeatable)`)
+
+	return cmd
+}
+
+// companionHandler serves api to browsers from the allowed origins, adding
+// GET /ping. Requests from other origins are refused, and requests with a body
+// must be JSON, which browsers only send cross-origin after a CORS preflight.
+func companionHandler(api http.Handler, allowed []string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ping", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"name": "ytdl", "version": version})
+	})
+	mux.Handle("/", api)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" {
+			if !originAllowed(origin, allowed) {
+				writeAPIError(w, http.StatusForbidden, fmt.Errorf("origin %s is not allowed", origin))
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			// Chrome asks before public pages may reach localhost
+			if r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+				w.Header().Set("Access-Control-Allow-Private-Network", "true")
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if r.Method == http.MethodPost || r.Method == http.MethodPatch {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeAPIError(w, http.StatusUnsupportedMediaType, errors.New("the request body must be application/json"))
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// originAllowed reports whether origin matches one of allowed: "*" matches any
+// origin and "scheme://*" any origin of that scheme.
+func originAllowed(origin string, allowed []string) bool {
+	for _, pattern := range allowed {
+		pattern = strings.TrimSuffix(pattern, "/")
+		if pattern == "*" || strings.EqualFold(pattern, origin) {
+			return true
+		}
+		if scheme, ok := strings.CutSuffix(pattern, "://*"); ok && strings.HasPrefix(strings.ToLower(origin), strings.ToLower(scheme)+"://") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newListenTestServer(t *testing.T, allowed ...string) *httptest.Server {
+	t.Helper()
+	queue, _ := newTestJobQueue(t, 1)
+	server := httptest.NewServer(companionHandler(queue.handler(), append(defaultAllowedOrigins, allowed...)))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func companionRequest(t *testing.T, server *httptest.Server, method, path, origin, contentType, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestCompanion_QueueFromAllowedOrigin(t *testing.T) {
+	server := newListenTestServer(t)
+
+	resp := companionRequest(t, server, http.MethodPost, "/downloads", "https://www.youtube.com", "application/json",
+		`{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "quality": "720p"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /downloads = %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://www.youtube.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+	var view jobView
+	if err := json.NewDecoder(resp.Body).Decode(&view); err != nil || view.Request.Quality != "720p" {
+		t.Errorf("queued %+v, %v", view, err)
+	}
+
+	resp = companionRequest(t, server, http.MethodGet, "/downloads/"+view.ID, "chrome-extension://abcdef", "", "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Access-Control-Allow-Origin") != "chrome-extension://abcdef" {
+		t.Errorf("GET from an extension = %d, allowed origin %q", resp.StatusCode, resp.Header.Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestCompanion_Preflight(t *testing.T) {
+	server := newListenTestServer(t)
+
+	req, _ := http.NewRequest(http.MethodOptions, server.URL+"/downloads", http.NoBody)
+	req.Header.Set("Origin", "moz-extension://1234")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Private-Network", "true")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("preflight = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":          "moz-extension://1234",
+		"Access-Control-Allow-Headers":         "Content-Type",
+		"Access-Control-Allow-Private-Network": "true",
+	} {
+		if got := resp.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if !strings.Contains(resp.Header.Get("Access-Control-Allow-Methods"), "POST") {
+		t.Errorf("Access-Control-Allow-Methods = %q", resp.Header.Get("Access-Control-Allow-Methods"))
+	}
+}
+
+func TestCompanion_RefusesOtherOrigins(t *testing.T) {
+	server := newListenTestServer(t)
+
+	resp := companionRequest(t, server, http.MethodPost, "/downloads", "https://evil.example", "application/json", `{"url": "dQw4w9WgXcQ"}`)
+	if resp.StatusCode != http.StatusForbidden || resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("POST from another origin = %d, allowed origin %q", resp.StatusCode, resp.Header.Get("Access-Control-Allow-Origin"))
+	}
+
+	// A form post would skip the preflight, so only JSON is accepted
+	resp = companionRequest(t, server, http.MethodPost, "/downloads", "", "text/plain", `{"url": "dQw4w9WgXcQ"}`)
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("POST of text/plain = %d, want %d", resp.StatusCode, http.StatusUnsupportedMediaType)
+	}
+
+	// Without an Origin header, as from curl, requests are accepted
+	resp = companionRequest(t, server, http.MethodGet, "/ping", "", "", "")
+	var ping map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&ping)
+	if resp.StatusCode != http.StatusOK || ping["name"] != "ytdl" {
+		t.Errorf("GET /ping = %d %v", resp.StatusCode, ping)
+	}
+}
+
+func TestCompanion_AllowOrigin(t *testing.T) {
+	server := newListenTestServer(t, "https://example.com/")
+	if resp := companionRequest(t, server, http.MethodGet, "/ping", "https://example.com", "", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("GET from an added origin = %d", resp.StatusCode)
+	}
+
+	server = newListenTestServer(t, "*")
+	if resp := companionRequest(t, server, http.MethodGet, "/ping", "https://anything.example", "", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("GET with any origin allowed = %d", resp.StatusCode)
+	}
+}
+
+func TestListen_InvalidPort(t *testing.T) {
+	cmd := newListenCmd()
+	cmd.SetArgs([]string{"--port", "0"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--port") {
+		t.Errorf("listen --port 0 = %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+)
+
+// loggerKey is the context key for the command's logger.
+type loggerKey struct{}
+
+// addLoggingFlags registers the global logging flags on the root command.
+func addLoggingFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolP("verbose", "v", false, "Log debug details such as HTTP requests and selected formats")
+	cmd.PersistentFlags().Bool("quiet", false, "Print nothing but errors")
+	cmd.PersistentFlags().String("log-file", "", "Write log output to this file instead of stderr")
+}
+
+// flagValue returns the value of a flag, or "" if the command doesn't define it.
+// Global flags are only defined when the command is attached to the root command.
+func flagValue(cmd *cobra.Command, name string) string {
+	if f := cmd.Flag(name); f != nil {
+		return f.Value.String()
+	}
+	return ""
+}
+
+// flagValues returns the values of a repeatable flag, or nil if the command doesn't define it.
+func flagValues(cmd *cobra.Command, name string) []string {
+	if f := cmd.Flag(name); f != nil {
+		if values, ok := f.Value.(pflag.SliceValue); ok {
+			return values.GetSlice()
+		}
+	}
+	return nil
+}
+
+// logLevel returns the log level selected by --verbose and --quiet.
+func logLevel(verbose, quiet bool) (slog.Level, error) {
+	switch {
+	case verbose && quiet:
+		return 0, errors.New("--verbose and --quiet cannot be used together")
+	case verbose:
+		return slog.LevelDebug, nil
+	case quiet:
+		return slog.LevelError, nil
+	default:
+		return slog.LevelWarn, nil
+	}
+}
+
+// setupLogging creates the logger for a command from the logging flags and
+// attaches it to the command's context. The returned function closes the log file, if any.
+func setupLogging(cmd *cobra.Command) (func() error, error) {
+	level, err := logLevel(flagValue(cmd, "verbose") == "true", flagValue(cmd, "quiet") == "true")
+	if err != nil {
+		return nil, err
+	}
+
+	var w io.Writer = cmd.ErrOrStderr()
+	closeLog := func() error { return nil }
+	if path := flagValue(cmd, "log-file"); path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		w = f
+		closeLog = f.Close
+	}
+
+	logger := slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+	cmd.SetContext(context.WithValue(cmd.Context(), loggerKey{}, logger))
+	return closeLog, nil
+}
+
+// loggerFrom returns the logger attached to ctx, or one that discards everything.
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.New(slog.DiscardHandler)
+}
+
+// logEvents returns a download event listener that logs retries and stalls as
+// warnings and the start and end of each stream at debug level. Failures are
+// left to the caller, which reports them with more context.
+func logEvents(logger *slog.Logger) download.EventListener {
+	return download.EventListenerFunc(func(e download.Event) {
+		switch e.Type {
+		case download.EventStarted:
+			logger.Debug("stream download started", "url", e.URL)
+		case download.EventRetryScheduled:
+			attrs := []any{"reason", e.Reason, "attempt", e.Attempt, "downloaded", e.Downloaded}
+			if e.Err != nil {
+				attrs = append(attrs, "error", e.Err)
+			}
+			logger.Warn("retrying stream request", attrs...)
+		case download.EventStalled:
+			logger.Warn("stream download stalled", "downloaded", e.Downloaded, "total", e.Total)
+		case download.EventCompleted:
+			logger.Debug("stream download completed", "url", e.URL, "bytes", e.Downloaded, "elapsed", e.Elapsed)
+		case download.EventFailed:
+			logger.Debug("stream download failed", "url", e.URL, "bytes", e.Downloaded, "error", e.Err)
+		}
+	})
+}
+
+// statusWriter returns where a command prints status and progress output:
+// nowhere with --quiet, stderr when stdout carries media (--output -), and stdout otherwise.
+// Output to a console that can't display Unicode is transliterated to ASCII.
+func statusWriter(cmd *cobra.Command) io.Writer {
+	switch {
+	case flagValue(cmd, "quiet") == "true":
+		return io.Discard
+	case flagValue(cmd, "output") == stdoutOutput:
+		return consoleOutput(cmd, cmd.ErrOrStderr())
+	default:
+		return consoleOutput(cmd, cmd.OutOrStdout())
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+)
+
+// newLoggingTestCmd returns a command with the global logging flags and a download-style output flag.
+func newLoggingTestCmd(args ...string) *cobra.Command {
+	cmd := &cobra.Command{}
+	addLoggingFlags(cmd)
+	addNetworkFlags(cmd)
+	cmd.Flags().StringP("output", "o", ".", "")
+	cmd.SetContext(context.Background())
+	_ = cmd.ParseFlags(args)
+	return cmd
+}
+
+func TestRootCommandHasLoggingFlags(t *testing.T) {
+	cmd := newRootCmd()
+	for _, name := range []string{"verbose", "quiet", "log-file"} {
+		if cmd.PersistentFlags().Lookup(name) == nil {
+			t.Errorf("root command should have --%s persistent flag", name)
+		}
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	tests := []struct {
+		verbose, quiet bool
+		want           slog.Level
+		wantErr        bool
+	}{
+		{false, false, slog.LevelWarn, false},
+		{true, false, slog.LevelDebug, false},
+		{false, true, slog.LevelError, false},
+		{true, true, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := logLevel(tt.verbose, tt.quiet)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("logLevel(%v, %v) error = %v, wantErr %v", tt.verbose, tt.quiet, err, tt.wantErr)
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("logLevel(%v, %v) = %v, want %v", tt.verbose, tt.quiet, got, tt.want)
+		}
+	}
+}
+
+func TestSetupLogging_LogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ytdl.log")
+	cmd := newLoggingTestCmd("--verbose", "--log-file", path)
+
+	closeLog, err := setupLogging(cmd)
+	if err != nil {
+		t.Fatalf("setupLogging failed: %v", err)
+	}
+	loggerFrom(cmd.Context()).Debug("hello from test")
+	if err := closeLog(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "hello from test") {
+		t.Errorf("log file should contain the debug record, got %q", data)
+	}
+}
+
+func TestSetupLogging_DefaultHidesDebug(t *testing.T) {
+	cmd := newLoggingTestCmd()
+	var stderr bytes.Buffer
+	cmd.SetErr(&stderr)
+
+	if _, err := setupLogging(cmd); err != nil {
+		t.Fatalf("setupLogging failed: %v", err)
+	}
+	logger := loggerFrom(cmd.Context())
+	logger.Debug("debug record")
+	logger.Warn("warning record")
+
+	if strings.Contains(stderr.String(), "debug record") {
+		t.Error("debug records should be hidden by default")
+	}
+	if !strings.Contains(stderr.String(), "warning record") {
+		t.Error("warnings should be shown by default")
+	}
+}
+
+func TestLoggerFrom_WithoutLogger(t *testing.T) {
+	// Must not panic and must discard
+	loggerFrom(context.Background()).Error("discarded")
+	var ctx context.Context
+	loggerFrom(ctx).Error("discarded")
+}
+
+func TestLogEvents(t *testing.T) {
+	var buf bytes.Buffer
+	listener := logEvents(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	listener.HandleEvent(download.Event{Type: download.EventStarted, URL: "https://example.com/stream"})
+	listener.HandleEvent(download.Event{Type: download.EventRetryScheduled, Reason: download.RetryResume, Attempt: 1, Downloaded: 500})
+	listener.HandleEvent(download.Event{Type: download.EventStalled, Downloaded: 500, Total: 1000})
+	listener.HandleEvent(download.Event{Type: download.EventCompleted, Downloaded: 1000})
+
+	out := buf.String()
+	for _, want := range []string{`msg="retrying stream request" reason=resume attempt=1 downloaded=500`, `msg="stream download stalled" downloaded=500 total=1000`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "started") || strings.Contains(out, "completed") {
+		t.Errorf("debug events logged at warning level:\n%s", out)
+	}
+}
+
+func TestStatusWriter(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := newLoggingTestCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	if statusWriter(cmd) != io.Writer(&stdout) {
+		t.Error("status output should go to stdout by default")
+	}
+
+	cmd = newLoggingTestCmd("-o", "-")
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	if statusWriter(cmd) != io.Writer(&stderr) {
+		t.Error("status output should go to stderr when stdout carries media")
+	}
+
+	cmd = newLoggingTestCmd("--quiet")
+	if statusWriter(cmd) != io.Discard {
+		t.Error("status output should be discarded with --quiet")
+	}
+}
+
+func TestNewHTTPClient_VerboseLogsRequests(t *testing.T) {
+	cmd := newLoggingTestCmd("--verbose")
+	var stderr bytes.Buffer
+	cmd.SetErr(&stderr)
+	if _, err := setupLogging(cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := newHTTPClient(cmd)
+	if err != nil {
+		t.Fatalf("newHTTPClient failed: %v", err)
+	}
+	if client == http.DefaultClient {
+		t.Fatal("expected a logging client with --verbose")
+	}
+}
+
+func TestRootCommand_VerboseAndQuietConflict(t *testing.T) {
+	rootCmd := newRootCmd()
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	rootCmd.SetArgs([]string{"version", "--verbose", "--quiet"})
+
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected an error for --verbose with --quiet")
+	}
+}
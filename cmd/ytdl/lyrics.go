@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/postprocess"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// videoLyrics returns the lookup --embed-lyrics uses: the video's lyrics caption
+// track as LRC timed lyrics when it has one, or else its description. Caption
+// tracks aren't kept with the downloaded video, so the video is fetched again.
+func videoLyrics(src *sources) postprocess.LyricsFunc {
+	return func(ctx context.Context, video *youtube.Video) (string, error) {
+		result, err := src.videos.FetchVideo(ctx, youtube.VideoID(video.ID))
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch caption tracks: %w", err)
+		}
+		var track *youtube.CaptionTrack
+		if result.Captions != nil {
+			track = result.Captions.LyricsTrack()
+		}
+		if track == nil {
+			return video.Description, nil
+		}
+
+		data, err := youtube.NewCaptionDownloader(src.client).Download(ctx, track)
+		if err != nil {
+			return "", fmt.Errorf("failed to download lyrics: %w", err)
+		}
+		return data.ToLRC(), nil
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// newLyricsTestServer serves a watch page with a caption track named trackName
+// and the track's captions.
+func newLyricsTestServer(t *testing.T, trackName string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/timedtext" {
+			_, _ = w.Write([]byte(`<transcript><text start="1.5" dur="2">Never gonna give you up</text>` +
+				`<text start="4" dur="2">Never gonna let you down</text></transcript>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"dQw4w9WgXcQ","title":"Song","lengthSeconds":"60"},` +
+			`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[]},` +
+			`"captions":{"playerCaptionsTracklistRenderer":{"captionTracks":[{"baseUrl":"` + server.URL + `/timedtext",` +
+			`"languageCode":"en","name":{"simpleText":"` + trackName + `"}}]}}};</script>`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVideoLyrics(t *testing.T) {
+	video := &youtube.Video{ID: "dQw4w9WgXcQ", Description: "Official music video"}
+	tests := []struct {
+		name, track, want string
+	}{
+		{"lyrics track", "English (Lyrics)", "[00:01.50]Never gonna give you up\n[00:04.00]Never gonna let you down\n"},
+		{"subtitles only", "English", "Official music video"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newLyricsTestServer(t, tt.track)
+			fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+
+			got, err := videoLyrics(fetcher)(context.Background(), video)
+			if err != nil {
+				t.Fatalf("videoLyrics failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("videoLyrics() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
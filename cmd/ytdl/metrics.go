@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/metrics"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+// newDaemonClients returns the HTTP client and ytdl client of the serve and sync
+// commands, with the extra options added to the ytdl client. If m isn't nil,
+// both record their downloads and rate limits into it.
+func newDaemonClients(cmd *cobra.Command, m *metrics.Metrics, extra ...ytdl.ClientOption) (*http.Client, *ytdl.Client, error) {
+	var rateLimited func()
+	if m != nil {
+		rateLimited = m.RateLimited
+	}
+	client, err := newHTTPClientReporting(cmd, rateLimited)
+	if err != nil {
+		return nil, nil, err
+	}
+	timeouts, err := clientTimeouts(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := []ytdl.ClientOption{ytdl.WithHTTPClient(client), timeouts}
+	if m != nil {
+		opts = append(opts, ytdl.WithEventListener(m))
+	}
+	return client, ytdl.NewClient(append(opts, extra...)...), nil
+}
+
+// serveMetrics serves m at /metrics on addr in the background until ctx is done.
+// It returns once the address is listened on, or with the error if it can't be.
+func serveMetrics(ctx context.Context, w io.Writer, addr string, m *metrics.Metrics) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() { _ = server.Serve(listener) }()
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	_, _ = fmt.Fprintf(w, "Serving metrics on http://%s/metrics\n", listener.Addr())
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/metrics"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+func TestServeMetrics(t *testing.T) {
+	m := metrics.New()
+	m.HandleEvent(download.Event{Type: download.EventStarted})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	buf := new(bytes.Buffer)
+	if err := serveMetrics(ctx, buf, "127.0.0.1:0", m); err != nil {
+		t.Fatal(err)
+	}
+	url := strings.TrimSpace(strings.TrimPrefix(buf.String(), "Serving metrics on "))
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "ytdl_downloads_started_total 1\n") {
+		t.Errorf("GET %s = %d %q", url, resp.StatusCode, body)
+	}
+}
+
+func TestServeMetrics_ListenError(t *testing.T) {
+	if err := serveMetrics(context.Background(), io.Discard, "not an address", metrics.New()); err == nil {
+		t.Error("serveMetrics() error = nil, want a listen error")
+	}
+}
+
+func TestSyncerRecordsMetrics(t *testing.T) {
+	server := newSyncTestServer(t)
+	m := metrics.New()
+	dir := t.TempDir()
+	cfg := &syncConfig{
+		Archive:       filepath.Join(dir, "archive.txt"),
+		Output:        dir,
+		Subscriptions: []subscription{{URL: testPlaylistURL, Discovery: discoveryPage}},
+	}
+	s, buf := newTestSyncer(t, server, cfg)
+	s.client = ytdl.NewClient(ytdl.WithHTTPClient(server.Client()), ytdl.WithBaseURL(server.URL), ytdl.WithEventListener(m))
+	if err := s.loop(context.Background(), nil); err != nil {
+		t.Fatalf("loop() error = %v\n%s", err, buf)
+	}
+
+	var out strings.Builder
+	_, _ = m.WriteTo(&out)
+	for _, want := range []string{"ytdl_downloads_succeeded_total 2\n", "ytdl_downloaded_bytes_total 20\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, out.String())
+		}
+	}
+}
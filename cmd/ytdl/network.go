@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	ytdlhttp "github.com/SakuraBurst/golang-youtube-downloader/internal/http"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+// addNetworkFlags registers the global network flags on the root command.
+func addNetworkFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("proxy", "", "Route all traffic through this proxy (e.g. socks5h://127.0.0.1:9050)")
+	cmd.PersistentFlags().Bool("restricted", false,
+		"Restricted network mode: force all traffic through one proxy (Tor by default), skip thumbnails and telemetry, use longer timeouts")
+	cmd.PersistentFlags().String("http-version", "auto", "HTTP version to use (auto, 1.1, 2); stream throughput can differ between them")
+	cmd.PersistentFlags().Int("max-conns-per-host", 0, "Maximum number of connections to a single host (0 for no limit)")
+	cmd.PersistentFlags().String("user-agent", "", "User-Agent for all requests; stream URLs only work with the User-Agent that requested them")
+	cmd.PersistentFlags().String("accept-language", "", "Accept-Language for all requests (default en-US)")
+	cmd.PersistentFlags().StringArray("add-header", nil, `Add a header to all requests, as "Name: value" (repeatable)`)
+	cmd.PersistentFlags().BoolP("force-ipv4", "4", false, "Make all connections over IPv4")
+	cmd.PersistentFlags().BoolP("force-ipv6", "6", false, "Make all connections over IPv6")
+	cmd.PersistentFlags().String("source-address", "", "Local IP address to make connections from")
+	cmd.PersistentFlags().String("geo-bypass-country", "",
+		`Pretend to be in this country (two-letter code such as "US") to get around region blocks`)
+	cmd.PersistentFlags().Duration("sleep-requests", 0,
+		"Wait this long, plus up to half as long again at random, between metadata requests (e.g. 2s for large batch jobs)")
+	cmd.PersistentFlags().Int("rate-limit-retries", defaultRateLimitRetries,
+		"How many times to retry a request YouTube rate limits with 429, waiting as long as it asks (0 to fail at once)")
+	cmd.PersistentFlags().Duration("socket-timeout", 0,
+		"Fail a connection that sends or receives nothing for this long, such as 20s (default: 30s to connect, no limit once connected)")
+	cmd.PersistentFlags().Duration("fetch-timeout", 0, "Fail fetching a video's info, its player or its PO token when it takes longer than this (0 for no limit)")
+	cmd.PersistentFlags().Duration("download-timeout", 0, "Fail a stream download that takes longer than this in total, such as 1h (0 for no limit)")
+
+	// Development flags: record a command's traffic once and replay it offline
+	cmd.PersistentFlags().String("record-fixtures", "", "Record every HTTP response into this cassette file, with IP addresses and PO tokens scrubbed")
+	cmd.PersistentFlags().String("replay-fixtures", "", "Answer every HTTP request from this cassette file instead of the network")
+	_ = cmd.PersistentFlags().MarkHidden("record-fixtures")
+	_ = cmd.PersistentFlags().MarkHidden("replay-fixtures")
+}
+
+// defaultRateLimitRetries is how often a rate limited request is retried by default.
+const defaultRateLimitRetries = 3
+
+// newHTTPClient returns the HTTP client for a command, honoring --proxy, --restricted,
+// the connection, address, header and pacing flags. Requests are logged when debug logging is enabled.
+// The client has a connection pool tuned for parallel stream downloads and no overall timeout.
+func newHTTPClient(cmd *cobra.Command) (*http.Client, error) {
+	return newHTTPClientReporting(cmd, nil)
+}
+
+// newHTTPClientReporting returns the HTTP client for a command like newHTTPClient,
+// calling rateLimited, if set, for every metadata request answered with 429.
+func newHTTPClientReporting(cmd *cobra.Command, rateLimited func()) (*http.Client, error) {
+	proxyURL := flagValue(cmd, "proxy")
+	restricted := flagValue(cmd, "restricted") == "true"
+
+	protocol, err := ytdlhttp.ParseProtocol(flagValue(cmd, "http-version"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --http-version: %w", err)
+	}
+	var maxConnsPerHost int
+	if value := flagValue(cmd, "max-conns-per-host"); value != "" {
+		if maxConnsPerHost, err = strconv.Atoi(value); err != nil || maxConnsPerHost < 0 {
+			return nil, fmt.Errorf("invalid --max-conns-per-host %q", value)
+		}
+	}
+
+	header, err := parseHeaders(flagValues(cmd, "add-header"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --add-header: %w", err)
+	}
+
+	limiter, retries, err := requestPacing(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	ipVersion, err := ipVersion(flagValue(cmd, "force-ipv4") == "true", flagValue(cmd, "force-ipv6") == "true")
+	if err != nil {
+		return nil, err
+	}
+
+	socketTimeout, err := timeoutFlag(cmd, "socket-timeout")
+	if err != nil {
+		return nil, err
+	}
+
+	cassette, err := fixtureCassette(flagValue(cmd, "record-fixtures"), flagValue(cmd, "replay-fixtures"))
+	if err != nil {
+		return nil, err
+	}
+
+	var logger *slog.Logger
+	if l := loggerFrom(cmd.Context()); l.Enabled(cmd.Context(), slog.LevelDebug) {
+		logger = l
+	}
+
+	client, err := ytdlhttp.NewClientWithOptions(ytdlhttp.Options{
+		ProxyURL:         proxyURL,
+		Restricted:       restricted,
+		Logger:           logger,
+		MaxConnsPerHost:  maxConnsPerHost,
+		Protocol:         protocol,
+		UserAgent:        flagValue(cmd, "user-agent"),
+		AcceptLanguage:   flagValue(cmd, "accept-language"),
+		Header:           header,
+		IPVersion:        ipVersion,
+		SourceAddress:    flagValue(cmd, "source-address"),
+		GeoBypassCountry: flagValue(cmd, "geo-bypass-country"),
+		Limiter:          limiter,
+		RateLimitRetries: retries,
+		RateLimited:      rateLimited,
+		SocketTimeout:    socketTimeout,
+		Cassette:         cassette,
+	})
+	if errors.Is(err, ytdlhttp.ErrInvalidSourceAddress) {
+		return nil, fmt.Errorf("invalid --source-address: %w", err)
+	}
+	if errors.Is(err, ytdlhttp.ErrUnknownCountry) {
+		return nil, fmt.Errorf("invalid --geo-bypass-country: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure proxy: %w", err)
+	}
+
+	if restricted {
+		printRestrictedNotice(statusWriter(cmd), proxyURL)
+	}
+	return client, nil
+}
+
+// requestPacing returns the limiter and 429 retry count set by --sleep-requests
+// and --rate-limit-retries. Every fetcher of a command uses the client, so they
+// all share the limiter.
+func requestPacing(cmd *cobra.Command) (*ytdlhttp.Limiter, int, error) {
+	var sleep time.Duration
+	if value := flagValue(cmd, "sleep-requests"); value != "" {
+		var err error
+		if sleep, err = time.ParseDuration(value); err != nil || sleep < 0 {
+			return nil, 0, fmt.Errorf("invalid --sleep-requests %q", value)
+		}
+	}
+	retries := defaultRateLimitRetries
+	if value := flagValue(cmd, "rate-limit-retries"); value != "" {
+		var err error
+		if retries, err = strconv.Atoi(value); err != nil || retries < 0 {
+			return nil, 0, fmt.Errorf("invalid --rate-limit-retries %q", value)
+		}
+	}
+	return ytdlhttp.NewLimiter(sleep, sleep/2), retries, nil
+}
+
+// fixtureCassette returns the cassette --record-fixtures or --replay-fixtures
+// names, nil without either.
+func fixtureCassette(recordPath, replayPath string) (*ytdlhttp.Cassette, error) {
+	switch {
+	case recordPath != "" && replayPath != "":
+		return nil, errors.New("--record-fixtures and --replay-fixtures cannot be used together")
+	case recordPath != "":
+		return ytdlhttp.NewCassette(recordPath, ytdlhttp.CassetteRecord), nil
+	case replayPath != "":
+		return ytdlhttp.LoadCassette(replayPath)
+	default:
+		return nil, nil
+	}
+}
+
+// timeoutFlag returns the duration of a timeout flag, 0 if the command doesn't define it.
+func timeoutFlag(cmd *cobra.Command, name string) (time.Duration, error) {
+	value := flagValue(cmd, name)
+	if value == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid --%s %q", name, value)
+	}
+	return timeout, nil
+}
+
+// newStreamDownloader returns the downloader for a command's streams, bounded by
+// --download-timeout, with the given options.
+func newStreamDownloader(cmd *cobra.Command, client *http.Client, opts ...download.Option) (*download.Downloader, error) {
+	timeout, err := timeoutFlag(cmd, "download-timeout")
+	if err != nil {
+		return nil, err
+	}
+	return download.NewDownloader(client, append([]download.Option{download.WithTimeout(timeout)}, opts...)...), nil
+}
+
+// clientTimeouts returns the option that applies --fetch-timeout and
+// --download-timeout to a ytdl client.
+func clientTimeouts(cmd *cobra.Command) (ytdl.ClientOption, error) {
+	fetch, err := timeoutFlag(cmd, "fetch-timeout")
+	if err != nil {
+		return nil, err
+	}
+	downloadTimeout, err := timeoutFlag(cmd, "download-timeout")
+	if err != nil {
+		return nil, err
+	}
+	return ytdl.WithTimeouts(fetch, downloadTimeout), nil
+}
+
+// ipVersion returns the IP version selected by --force-ipv4 and --force-ipv6.
+func ipVersion(ipv4, ipv6 bool) (ytdlhttp.IPVersion, error) {
+	switch {
+	case ipv4 && ipv6:
+		return ytdlhttp.IPAny, errors.New("--force-ipv4 and --force-ipv6 cannot be used together")
+	case ipv4:
+		return ytdlhttp.IPv4, nil
+	case ipv6:
+		return ytdlhttp.IPv6, nil
+	default:
+		return ytdlhttp.IPAny, nil
+	}
+}
+
+// parseHeaders parses "Name: value" headers. Repeated names keep every value.
+func parseHeaders(values []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, value := range values {
+		name, v, err := ytdlhttp.ParseHeader(value)
+		if err != nil {
+			return nil, err
+		}
+		header.Add(name, v)
+	}
+	return header, nil
+}
+
+// printRestrictedNotice tells the user which proxy restricted mode is using.
+func printRestrictedNotice(w io.Writer, proxyURL string) {
+	if proxyURL == "" {
+		proxyURL = ytdlhttp.DefaultTorProxy
+	}
+	_, _ = fmt.Fprintf(w, "Restricted mode: routing all traffic through %s\n", proxyURL)
+}
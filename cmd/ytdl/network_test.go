@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	ytdlhttp "github.com/SakuraBurst/golang-youtube-downloader/internal/http"
+)
+
+func TestRootCommandHasNetworkFlags(t *testing.T) {
+	cmd := newRootCmd()
+
+	for _, name := range []string{"proxy", "restricted", "http-version", "max-conns-per-host", "user-agent", "accept-language", "add-header", "force-ipv4", "force-ipv6", "source-address", "geo-bypass-country", "sleep-requests", "rate-limit-retries", "socket-timeout", "fetch-timeout", "download-timeout"} {
+		if cmd.PersistentFlags().Lookup(name) == nil {
+			t.Errorf("root command should have --%s persistent flag", name)
+		}
+	}
+}
+
+func TestNewHTTPClient_TunedWithoutFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	addNetworkFlags(cmd)
+
+	client, err := newHTTPClient(cmd)
+	if err != nil {
+		t.Fatalf("newHTTPClient failed: %v", err)
+	}
+	if client == http.DefaultClient {
+		t.Error("expected a tuned client instead of http.DefaultClient")
+	}
+	if client.Timeout != 0 {
+		t.Errorf("stream downloads need no overall timeout, got %v", client.Timeout)
+	}
+}
+
+func TestNewHTTPClient_UndefinedFlags(t *testing.T) {
+	// Subcommands created on their own don't inherit the root flags
+	client, err := newHTTPClient(newInfoCmd())
+	if err != nil {
+		t.Fatalf("newHTTPClient failed: %v", err)
+	}
+	if client == nil {
+		t.Error("expected a client when network flags are not defined")
+	}
+}
+
+func TestNewHTTPClient_ConnectionFlags(t *testing.T) {
+	tests := []struct {
+		flag, value string
+		wantErr     string
+	}{
+		{"http-version", "2", ""},
+		{"http-version", "http/1.1", ""},
+		{"http-version", "3", "invalid --http-version"},
+		{"max-conns-per-host", "8", ""},
+		{"max-conns-per-host", "-1", "invalid --max-conns-per-host"},
+		{"add-header", "Referer: https://www.youtube.com/", ""},
+		{"add-header", "no-colon", "invalid --add-header"},
+		{"force-ipv4", "true", ""},
+		{"source-address", "127.0.0.1", ""},
+		{"source-address", "localhost", "invalid --source-address"},
+		{"geo-bypass-country", "de", ""},
+		{"geo-bypass-country", "XX", "invalid --geo-bypass-country"},
+		{"sleep-requests", "2s", ""},
+		{"sleep-requests", "-1s", "invalid --sleep-requests"},
+		{"rate-limit-retries", "0", ""},
+		{"rate-limit-retries", "-1", "invalid --rate-limit-retries"},
+		{"socket-timeout", "20s", ""},
+		{"socket-timeout", "-1s", "invalid --socket-timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.flag+"="+tt.value, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			addNetworkFlags(cmd)
+			if err := cmd.PersistentFlags().Set(tt.flag, tt.value); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := newHTTPClient(cmd)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("newHTTPClient failed: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTimeoutFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	addNetworkFlags(cmd)
+	if err := cmd.PersistentFlags().Set("fetch-timeout", "15s"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.PersistentFlags().Set("download-timeout", "-1m"); err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher, err := newWatchPageFetcher(cmd, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("newWatchPageFetcher failed: %v", err)
+	}
+	if fetcher.Timeout != 15*time.Second {
+		t.Errorf("fetcher timeout = %v, want 15s", fetcher.Timeout)
+	}
+
+	if _, err := newStreamDownloader(cmd, http.DefaultClient); err == nil || !strings.Contains(err.Error(), "invalid --download-timeout") {
+		t.Errorf("newStreamDownloader() error = %v, want invalid --download-timeout", err)
+	}
+	if _, err := clientTimeouts(cmd); err == nil {
+		t.Error("clientTimeouts() accepted a negative --download-timeout")
+	}
+
+	// Commands without the flags have no limits
+	if timeout, err := timeoutFlag(newInfoCmd(), "fetch-timeout"); timeout != 0 || err != nil {
+		t.Errorf("timeoutFlag() = %v, %v; want 0, nil", timeout, err)
+	}
+}
+
+func TestFixtureCassette(t *testing.T) {
+	if cassette, err := fixtureCassette("", ""); cassette != nil || err != nil {
+		t.Errorf("fixtureCassette() = %v, %v; want no cassette", cassette, err)
+	}
+	if _, err := fixtureCassette("a.json", "b.json"); err == nil || !strings.Contains(err.Error(), "cannot be used together") {
+		t.Errorf("expected a conflict error, got %v", err)
+	}
+	if cassette, err := fixtureCassette(filepath.Join(t.TempDir(), "new.json"), ""); cassette == nil || err != nil {
+		t.Errorf("recording: fixtureCassette() = %v, %v", cassette, err)
+	}
+	if _, err := fixtureCassette("", filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("replaying a missing cassette should fail")
+	}
+}
+
+func TestRequestPacing(t *testing.T) {
+	cmd := &cobra.Command{}
+	addNetworkFlags(cmd)
+	limiter, retries, err := requestPacing(cmd)
+	if err != nil || limiter.Delay != 0 || retries != defaultRateLimitRetries {
+		t.Errorf("default pacing = %+v, %d, %v", limiter, retries, err)
+	}
+
+	if err := cmd.PersistentFlags().Set("sleep-requests", "2s"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.PersistentFlags().Set("rate-limit-retries", "5"); err != nil {
+		t.Fatal(err)
+	}
+	limiter, retries, err = requestPacing(cmd)
+	if err != nil || limiter.Delay != 2*time.Second || limiter.Jitter != time.Second || retries != 5 {
+		t.Errorf("pacing = %+v, %d, %v", limiter, retries, err)
+	}
+}
+
+func TestIPVersion(t *testing.T) {
+	tests := []struct {
+		ipv4, ipv6 bool
+		want       ytdlhttp.IPVersion
+	}{
+		{false, false, ytdlhttp.IPAny},
+		{true, false, ytdlhttp.IPv4},
+		{false, true, ytdlhttp.IPv6},
+	}
+	for _, tt := range tests {
+		got, err := ipVersion(tt.ipv4, tt.ipv6)
+		if err != nil || got != tt.want {
+			t.Errorf("ipVersion(%v, %v) = %v, %v; want %v", tt.ipv4, tt.ipv6, got, err, tt.want)
+		}
+	}
+	if _, err := ipVersion(true, true); err == nil {
+		t.Error("expected --force-ipv4 and --force-ipv6 together to be rejected")
+	}
+}
+
+func TestNewHTTPClient_HeaderFlags(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	cmd := &cobra.Command{}
+	addNetworkFlags(cmd)
+	for _, flag := range [][2]string{
+		{"user-agent", "Mozilla/5.0 Test"},
+		{"accept-language", "ja"},
+		{"add-header", "X-One: 1"},
+		{"add-header", "X-Two: 2"},
+	} {
+		if err := cmd.PersistentFlags().Set(flag[0], flag[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client, err := newHTTPClient(cmd)
+	if err != nil {
+		t.Fatalf("newHTTPClient failed: %v", err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if got.Get("User-Agent") != "Mozilla/5.0 Test" || got.Get("Accept-Language") != "ja" ||
+		got.Get("X-One") != "1" || got.Get("X-Two") != "2" {
+		t.Errorf("unexpected headers: %v", got)
+	}
+}
+
+func TestNewHTTPClient_Restricted(t *testing.T) {
+	cmd := &cobra.Command{}
+	addNetworkFlags(cmd)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	if err := cmd.PersistentFlags().Set("restricted", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := newHTTPClient(cmd)
+	if err != nil {
+		t.Fatalf("newHTTPClient failed: %v", err)
+	}
+	if client == http.DefaultClient {
+		t.Error("restricted mode should use a dedicated client")
+	}
+	if !strings.Contains(out.String(), ytdlhttp.DefaultTorProxy) {
+		t.Errorf("expected notice mentioning the Tor proxy, got %q", out.String())
+	}
+}
+
+func TestNewHTTPClient_InvalidProxy(t *testing.T) {
+	cmd := &cobra.Command{}
+	addNetworkFlags(cmd)
+	if err := cmd.PersistentFlags().Set("proxy", "ftp://example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newHTTPClient(cmd); !errors.Is(err, ytdlhttp.ErrUnsupportedProxy) {
+		t.Errorf("expected ErrUnsupportedProxy, got %v", err)
+	}
+}
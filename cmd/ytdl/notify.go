@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/notify"
+)
+
+// notifyConfig is the notifications section of a config file.
+type notifyConfig struct {
+	// Webhook is a URL every finished download is posted to as JSON.
+	Webhook string `json:"webhook"`
+
+	// Desktop shows a desktop notification for every finished download.
+	Desktop bool `json:"desktop"`
+}
+
+// newNotifier returns the notifier for the configured notifications, or nil if
+// there are none.
+func newNotifier(cfg notifyConfig, client *http.Client) (notify.Notifier, error) {
+	var notifiers notify.Multi
+	if cfg.Webhook != "" {
+		u, err := url.Parse(cfg.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid notification webhook %q: want an http or https URL", cfg.Webhook)
+		}
+		notifiers = append(notifiers, &notify.Webhook{URL: cfg.Webhook, Client: client})
+	}
+	if cfg.Desktop {
+		notifiers = append(notifiers, &notify.Desktop{})
+	}
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+	return notifiers, nil
+}
+
+// notifyResult sends the notifications for a finished download. Skipped videos
+// and downloads the user interrupted aren't reported, and a notification that
+// can't be sent is only a warning.
+func notifyResult(ctx context.Context, w io.Writer, n notify.Notifier, result download.DownloadResult) {
+	if n == nil || result.Skipped || ctx.Err() != nil {
+		return
+	}
+	event := notify.Event{
+		Title:    result.Title,
+		Status:   notify.StatusSucceeded,
+		Path:     result.FilePath,
+		Size:     result.Size,
+		Duration: result.Elapsed,
+	}
+	if result.Error != nil {
+		event.Status = notify.StatusFailed
+		event.Error = result.Error.Error()
+	}
+	if err := n.Notify(ctx, event); err != nil {
+		_, _ = fmt.Fprintf(w, "Warning: failed to send notification: %v\n", err)
+	}
+}
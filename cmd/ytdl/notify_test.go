@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/notify"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+type recordingNotifier struct {
+	events []notify.Event
+	err    error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, event notify.Event) error {
+	n.events = append(n.events, event)
+	return n.err
+}
+
+func TestNewNotifier(t *testing.T) {
+	if n, err := newNotifier(notifyConfig{}, nil); n != nil || err != nil {
+		t.Errorf("newNotifier() = %v, %v, want nil without notifications", n, err)
+	}
+
+	n, err := newNotifier(notifyConfig{Webhook: "https://example.com/hook", Desktop: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if multi, ok := n.(notify.Multi); !ok || len(multi) != 2 {
+		t.Errorf("newNotifier() = %#v, want a webhook and desktop notifier", n)
+	}
+
+	for _, hook := range []string{"example.com/hook", "ftp://example.com/hook", "https://"} {
+		if _, err := newNotifier(notifyConfig{Webhook: hook}, nil); err == nil {
+			t.Errorf("newNotifier(%q) error = nil, want invalid webhook", hook)
+		}
+	}
+}
+
+func TestNotifyResult(t *testing.T) {
+	n := &recordingNotifier{}
+	buf := new(bytes.Buffer)
+	ctx := context.Background()
+
+	notifyResult(ctx, buf, n, download.DownloadResult{Title: "Skipped", Skipped: true})
+	notifyResult(ctx, buf, n, download.DownloadResult{Title: "Done", FilePath: "Done.mp4", Size: 10})
+	notifyResult(ctx, buf, n, download.DownloadResult{Title: "Broken", Error: errors.New("video unavailable")})
+
+	if len(n.events) != 2 {
+		t.Fatalf("events = %+v, want the skipped video left out", n.events)
+	}
+	if e := n.events[0]; e.Title != "Done" || e.Status != notify.StatusSucceeded || e.Path != "Done.mp4" || e.Size != 10 {
+		t.Errorf("events[0] = %+v", e)
+	}
+	if e := n.events[1]; e.Status != notify.StatusFailed || e.Error != "video unavailable" {
+		t.Errorf("events[1] = %+v", e)
+	}
+
+	n.err = errors.New("webhook returned 500")
+	notifyResult(ctx, buf, n, download.DownloadResult{Title: "Done"})
+	if !strings.Contains(buf.String(), "Warning: failed to send notification: webhook returned 500") {
+		t.Errorf("output = %q, want a warning", buf)
+	}
+}
+
+func TestDownloadCommandNotifiesWebhook(t *testing.T) {
+	server := newArchiveTestServer(t)
+
+	var mu sync.Mutex
+	var payloads []map[string]any
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		payloads = append(payloads, payload)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	out := t.TempDir()
+	opts := &downloadOptions{output: out, quality: "best", format: "mp4"}
+	var err error
+	if opts.notifier, err = newNotifier(notifyConfig{Webhook: hook.URL}, hook.Client()); err != nil {
+		t.Fatal(err)
+	}
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	downloader := download.NewDownloader(server.Client())
+
+	buf := new(bytes.Buffer)
+	if err := runDownloadWithDeps(context.Background(), buf, "dQw4w9WgXcQ", opts, fetcher, downloader, nil); err != nil {
+		t.Fatalf("download failed: %v\n%s", err, buf)
+	}
+	if err := runDownloadWithDeps(context.Background(), buf, "jNQXAC9IVRw", opts, fetcher, downloader, nil); err == nil {
+		t.Fatal("download of an unavailable video succeeded")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(payloads) != 2 {
+		t.Fatalf("payloads = %v, want one per download", payloads)
+	}
+	if p := payloads[0]; p["title"] != "Video dQw4w9WgXcQ" || p["status"] != "succeeded" ||
+		p["path"] != filepath.Join(out, "Video dQw4w9WgXcQ.mp4") || p["size"] != float64(len("video data")) {
+		t.Errorf("payloads[0] = %v", p)
+	}
+	if p := payloads[1]; p["status"] != "failed" || p["error"] == nil {
+		t.Errorf("payloads[1] = %v", p)
+	}
+}
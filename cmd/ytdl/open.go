@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// defaultPlayers are the players looked for on the PATH without --player, in order.
+var defaultPlayers = []string{"mpv", "vlc"}
+
+// playerFlags are the options of a known player that play a separate audio
+// stream alongside the video and set the window title. The values are printf
+// formats of the stream URL and the title.
+type playerFlags struct {
+	audio string
+	title string
+}
+
+// knownPlayers maps player program names to their flags. Other players are
+// given a single stream with video and audio.
+var knownPlayers = map[string]playerFlags{
+	"mpv":  {audio: "--audio-file=%s", title: "--force-media-title=%s"},
+	"vlc":  {audio: "--input-slave=%s", title: "--meta-title=%s"},
+	"cvlc": {audio: "--input-slave=%s", title: "--meta-title=%s"},
+}
+
+// openOptions holds the flags of the open command.
+type openOptions struct {
+	player     string
+	playerArgs []string
+	quality    string
+	format     string
+}
+
+// playerRunner runs the player at path with args until it exits.
+type playerRunner func(ctx context.Context, path string, args []string) error
+
+func newOpenCmd() *cobra.Command {
+	opts := &openOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "open <url>",
+		Short: "Play a video in an external player without downloading it",
+		Long: `Resolve the streams of a video and play them in an external player, such
+as mpv or VLC, without writing anything to disk.
+
+Streams are selected as by the download command. mpv and VLC are given the
+separate video and audio streams of the selected quality; other players are
+given the best stream carrying both, which YouTube only offers up to 360p.
+
+Without --player, the first of mpv and vlc found on the PATH is used.`,
+		Example: `  ytdl open https://www.youtube.com/watch?v=dQw4w9WgXcQ
+  ytdl open dQw4w9WgXcQ --quality 720p --player vlc
+  ytdl open dQw4w9WgXcQ --quality audio --player-arg=--no-video`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newHTTPClient(cmd)
+			if err != nil {
+				return WrapError(err)
+			}
+			page, err := newWatchPageFetcher(cmd, client)
+			if err != nil {
+				return WrapError(err)
+			}
+			run := func(ctx context.Context, path string, args []string) error {
+				player := exec.CommandContext(ctx, path, args...)
+				player.Stdin, player.Stdout, player.Stderr = cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr()
+				return player.Run()
+			}
+			if err := runOpenWithDeps(cmd.Context(), statusWriter(cmd), args[0], opts, newSources(page), exec.LookPath, run); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.player, "player", "", "Player program to run (default: mpv or vlc on the PATH)")
+	cmd.Flags().StringArrayVar(&opts.playerArgs, "player-arg", nil, "Extra argument for the player (repeatable)")
+	cmd.Flags().StringVarP(&opts.quality, "quality", "q", "best", "Video quality (best, worst, 1080p, 720p, audio, etc.)")
+	cmd.Flags().StringVarP(&opts.format, "format", "f", "", "Preferred container (mp4, webm)")
+
+	return cmd
+}
+
+// runOpenWithDeps resolves the streams of the video at urlStr and plays them
+// with the player, found with lookPath and run with run.
+func runOpenWithDeps(
+	ctx context.Context,
+	w io.Writer,
+	urlStr string,
+	opts *openOptions,
+	src *sources,
+	lookPath func(string) (string, error),
+	run playerRunner,
+) error {
+	path, err := findPlayer(opts.player, lookPath)
+	if err != nil {
+		return err
+	}
+	flags, known := knownPlayers[playerName(path)]
+
+	query, err := youtube.ResolveQueryContext(ctx, urlStr, youtube.NewURLExpander(src.client))
+	if err != nil || query.Type != youtube.QueryTypeVideo {
+		return fmt.Errorf("invalid video URL or ID: %w", youtube.ErrInvalidVideoID)
+	}
+	video, manifest, err := fetchVideo(ctx, w, query.VideoID, src)
+	if err != nil {
+		return err
+	}
+
+	selection, err := selectStreams(manifest, &downloadOptions{quality: opts.quality, format: opts.format})
+	if err != nil {
+		return err
+	}
+	if selection.needsMux() && !known {
+		muxed := bestMuxedStream(manifest)
+		if muxed == nil {
+			return fmt.Errorf("%s can't play separate video and audio streams and the video has no stream with both", playerName(path))
+		}
+		selection = &streamSelection{quality: youtube.QualityLabel(muxed.Height), video: muxed}
+		_, _ = fmt.Fprintf(w, "Note: %s can't play separate video and audio streams; playing %s instead\n", playerName(path), selection.quality)
+	}
+	selection.warnInaccessible(w)
+
+	args := append(slices.Clone(opts.playerArgs), playerArgs(flags, known, video.Title, selection)...)
+	_, _ = fmt.Fprintf(w, "Playing %s in %s\n", selection.label(), playerName(path))
+	if err := run(ctx, path, args); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("%s failed: %w", playerName(path), err)
+	}
+	return nil
+}
+
+// findPlayer returns the path of the player named by --player, or of the first
+// default player on the PATH.
+func findPlayer(player string, lookPath func(string) (string, error)) (string, error) {
+	if player != "" {
+		path, err := lookPath(player)
+		if err != nil {
+			return "", fmt.Errorf("player %q not found: %w", player, err)
+		}
+		return path, nil
+	}
+	for _, name := range defaultPlayers {
+		if path, err := lookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.New("no player found; install mpv or VLC, or choose a player with --player")
+}
+
+// playerName returns the program name of a player path, such as "mpv" for
+// "/usr/bin/mpv" or "vlc" for `C:\Program Files\VideoLAN\VLC\vlc.exe`.
+func playerName(path string) string {
+	name := filepath.Base(strings.ReplaceAll(path, `\`, "/"))
+	return strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+}
+
+// playerArgs returns the arguments that play the selection titled title: the
+// stream URL, with the audio stream and title passed with the flags of known players.
+func playerArgs(flags playerFlags, known bool, title string, selection *streamSelection) []string {
+	var args []string
+	if known {
+		args = append(args, fmt.Sprintf(flags.title, title))
+	}
+	switch {
+	case selection.needsMux():
+		args = append(args, fmt.Sprintf(flags.audio, selection.audio.URL), selection.video.URL)
+	case selection.video != nil:
+		args = append(args, selection.video.URL)
+	default:
+		args = append(args, selection.audio.URL)
+	}
+	return args
+}
+
+// bestMuxedStream returns the highest quality stream with both video and
+// audio that can be played, or nil if there is none.
+func bestMuxedStream(manifest *youtube.StreamManifest) *youtube.VideoStreamInfo {
+	var best *youtube.VideoStreamInfo
+	for i := range manifest.MuxedStreams {
+		stream := &manifest.MuxedStreams[i].VideoStreamInfo
+		if !stream.IsDownloadable() || stream.URL == "" {
+			continue
+		}
+		if best == nil || stream.Height > best.Height {
+			best = stream
+		}
+	}
+	return best
+}
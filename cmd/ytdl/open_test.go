@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+const openTestFormats = `"formats":[` +
+	`{"itag":18,"url":"STREAM_URL?itag=18","mimeType":"video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"","height":360,"qualityLabel":"360p"}],` +
+	planTestFormats
+
+// runTestOpen opens the test video with the player at playerPath and returns
+// the arguments it was run with and the output.
+func runTestOpen(t *testing.T, opts *openOptions, playerPath string) ([]string, string, error) {
+	t.Helper()
+	server := newPlanTestServer(t, openTestFormats)
+	src := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	lookPath := func(name string) (string, error) {
+		if name == playerPath || strings.HasSuffix(playerPath, "/"+name) {
+			return playerPath, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	var args []string
+	run := func(_ context.Context, path string, playerArgs []string) error {
+		if path != playerPath {
+			t.Errorf("ran %s, want %s", path, playerPath)
+		}
+		args = playerArgs
+		return nil
+	}
+
+	buf := new(bytes.Buffer)
+	err := runOpenWithDeps(context.Background(), buf, "dQw4w9WgXcQ", opts, src, lookPath, run)
+	return args, buf.String(), err
+}
+
+func TestOpen_KnownPlayerGetsSeparateStreams(t *testing.T) {
+	args, output, err := runTestOpen(t, &openOptions{quality: "best", playerArgs: []string{"--fs"}}, "/usr/bin/mpv")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	got := strings.Join(args, " ")
+	for _, want := range []string{"--fs ", "--force-media-title=Test Video", "--audio-file=http", "/stream?itag=140", "/stream?itag=137"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("args = %q, want %q", got, want)
+		}
+	}
+	if !strings.HasSuffix(got, "/stream?itag=137") {
+		t.Errorf("args = %q, want the video URL last", got)
+	}
+	if !strings.Contains(output, "Playing 1080p (avc1.640028, mp4a.40.2) in mpv") {
+		t.Errorf("output = %q", output)
+	}
+}
+
+func TestOpen_OtherPlayerGetsMuxedStream(t *testing.T) {
+	args, output, err := runTestOpen(t, &openOptions{quality: "best", player: "/opt/ffplay"}, "/opt/ffplay")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	if len(args) != 1 || !strings.HasSuffix(args[0], "/stream?itag=18") {
+		t.Errorf("args = %q, want only the muxed stream", args)
+	}
+	if !strings.Contains(output, "ffplay can't play separate video and audio streams; playing 360p instead") {
+		t.Errorf("output = %q", output)
+	}
+}
+
+func TestOpen_AudioOnly(t *testing.T) {
+	args, _, err := runTestOpen(t, &openOptions{quality: "audio"}, "/usr/bin/vlc")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	if len(args) != 2 || args[0] != "--meta-title=Test Video" || !strings.HasSuffix(args[1], "/stream?itag=140") {
+		t.Errorf("args = %q, want the title and the audio stream", args)
+	}
+}
+
+func TestOpen_NoPlayer(t *testing.T) {
+	_, _, err := runTestOpen(t, &openOptions{quality: "best"}, "/usr/bin/totem")
+	if err == nil || !strings.Contains(err.Error(), "no player found") {
+		t.Errorf("err = %v, want no player found", err)
+	}
+
+	_, _, err = runTestOpen(t, &openOptions{quality: "best", player: "iina"}, "/usr/bin/totem")
+	if !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("err = %v, want exec.ErrNotFound", err)
+	}
+}
+
+func TestPlayerName(t *testing.T) {
+	tests := map[string]string{
+		"mpv":                                   "mpv",
+		"/usr/bin/cvlc":                         "cvlc",
+		`C:\Program Files\VideoLAN\VLC\vlc.exe`: "vlc",
+		"/Applications/MPV.app/Contents/MacOS/MPV": "mpv",
+	}
+	for path, want := range tests {
+		if got := playerName(path); got != want {
+			t.Errorf("playerName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
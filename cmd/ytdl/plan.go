@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/musicmeta"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+// planVersion is the version of the plan file format written by --print-plan.
+const planVersion = 1
+
+// downloadPlan is the fully resolved work of a download run. It is written by
+// --print-plan and performed by --execute-plan, so a plan can be reviewed and
+// approved before anything is downloaded.
+type downloadPlan struct {
+	Version int         `json:"version"`
+	Options planOptions `json:"options"`
+	Items   []planItem  `json:"items"`
+}
+
+// planOptions are the download options that affect how plan items are performed.
+type planOptions struct {
+	Format       string   `json:"format"`
+	Quality      string   `json:"quality"`
+	SplitSize    string   `json:"split_size,omitempty"`
+	Section      string   `json:"section,omitempty"`
+	RecodeVideo  string   `json:"recode_video,omitempty"`
+	RecodeCodec  string   `json:"recode_codec,omitempty"`
+	RecodeCRF    int      `json:"recode_crf,omitempty"`
+	RecodePreset string   `json:"recode_preset,omitempty"`
+	HWAccel      string   `json:"hwaccel,omitempty"`
+	Exec         []string `json:"exec,omitempty"`
+	KeepSeparate bool     `json:"keep_separate,omitempty"`
+
+	SplitChapters   bool   `json:"split_chapters,omitempty"`
+	ChapterTemplate string `json:"chapter_template,omitempty"`
+
+	NormalizeAudio bool    `json:"normalize_audio,omitempty"`
+	LoudnessTarget float64 `json:"loudness_target,omitempty"`
+
+	EmbedLyrics   bool   `json:"embed_lyrics,omitempty"`
+	MusicMetadata string `json:"music_metadata,omitempty"`
+	ReplayGain    bool   `json:"replaygain,omitempty"`
+
+	WriteStoryboards bool `json:"write_storyboards,omitempty"`
+	SplitStoryboards bool `json:"split_storyboards,omitempty"`
+}
+
+// planItem is a single video in a plan with the exact formats chosen for it.
+type planItem struct {
+	VideoID         string   `json:"video_id"`
+	Title           string   `json:"title"`
+	DurationSeconds float64  `json:"duration_seconds"`
+	Quality         string   `json:"quality,omitempty"`
+	Container       string   `json:"container"`
+	VideoItag       int      `json:"video_itag,omitempty"`
+	AudioItag       int      `json:"audio_itag,omitempty"`
+	Target          string   `json:"target"`
+	EstimatedSize   int64    `json:"estimated_size"`
+	PostProcessing  []string `json:"post_processing"`
+}
+
+// newPlanOptions captures the plan-relevant download options.
+func newPlanOptions(opts *downloadOptions) planOptions {
+	return planOptions{
+		Format:       opts.format,
+		Quality:      opts.quality,
+		SplitSize:    opts.splitSize,
+		Section:      opts.section,
+		RecodeVideo:  opts.recodeVideo,
+		RecodeCodec:  opts.recodeCodec,
+		RecodeCRF:    opts.recodeCRF,
+		RecodePreset: opts.recodePreset,
+		HWAccel:      opts.hwAccel,
+		Exec:         opts.exec,
+		KeepSeparate: opts.keepSeparate,
+
+		SplitChapters:   opts.splitChapters,
+		ChapterTemplate: opts.chapterTemplate,
+
+		NormalizeAudio: opts.normalizeAudio,
+		LoudnessTarget: opts.loudnessTarget,
+
+		EmbedLyrics:   opts.embedLyrics,
+		MusicMetadata: opts.musicMetadata,
+		ReplayGain:    opts.replayGain,
+
+		WriteStoryboards: opts.writeStoryboards,
+		SplitStoryboards: opts.splitStoryboards,
+	}
+}
+
+// downloadOptions converts the plan options back to download options.
+func (p planOptions) downloadOptions() *downloadOptions {
+	hwAccel := p.HWAccel
+	if hwAccel == "" {
+		hwAccel = "none"
+	}
+	return &downloadOptions{
+		format:       p.Format,
+		quality:      p.Quality,
+		splitSize:    p.SplitSize,
+		section:      p.Section,
+		recodeVideo:  p.RecodeVideo,
+		recodeCodec:  p.RecodeCodec,
+		recodeCRF:    p.RecodeCRF,
+		recodePreset: p.RecodePreset,
+		hwAccel:      hwAccel,
+		exec:         p.Exec,
+		keepSeparate: p.KeepSeparate,
+
+		splitChapters:   p.SplitChapters,
+		chapterTemplate: p.ChapterTemplate,
+
+		normalizeAudio: p.NormalizeAudio,
+		loudnessTarget: p.LoudnessTarget,
+
+		embedLyrics:   p.EmbedLyrics,
+		musicMetadata: p.MusicMetadata,
+		replayGain:    p.ReplayGain,
+
+		writeStoryboards: p.WriteStoryboards,
+		splitStoryboards: p.SplitStoryboards,
+	}
+}
+
+// printPlan resolves urlStr into a plan and writes it to w as JSON without downloading.
+func printPlan(ctx context.Context, w io.Writer, urlStr string, opts *downloadOptions, src *sources) error {
+	if opts.output == stdoutOutput {
+		return errors.New("--print-plan cannot be used with --output -")
+	}
+
+	query, err := youtube.ResolveQueryContext(ctx, urlStr, youtube.NewURLExpander(src.client))
+	if err != nil {
+		return fmt.Errorf("invalid URL or ID: %w", err)
+	}
+	if query.Type != youtube.QueryTypeVideo {
+		return errors.New("plans can currently only be built for single videos")
+	}
+
+	plan := &downloadPlan{Version: planVersion, Options: newPlanOptions(opts)}
+
+	item, video, err := buildPlanItem(ctx, query.VideoID, opts, src)
+	if err != nil {
+		return err
+	}
+	plan.Items = append(plan.Items, *item)
+
+	if opts.remixSources && video.RemixOf != nil {
+		source, _, err := buildPlanItem(ctx, youtube.VideoID(video.RemixOf.VideoID), opts, src)
+		if err != nil {
+			return fmt.Errorf("failed to plan original video %s: %w", video.RemixOf.VideoID, err)
+		}
+		plan.Items = append(plan.Items, *source)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
+
+// buildPlanItem resolves a video into a plan item.
+func buildPlanItem(ctx context.Context, videoID youtube.VideoID, opts *downloadOptions, src *sources) (*planItem, *youtube.Video, error) {
+	video, manifest, err := fetchVideo(ctx, io.Discard, videoID, src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	selection, err := selectStreams(manifest, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target, err := filepath.Abs(videoOutputPath(video, opts, ""))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve target path: %w", err)
+	}
+
+	item := &planItem{
+		VideoID:         video.ID,
+		Title:           video.Title,
+		DurationSeconds: video.Duration.Seconds(),
+		Quality:         selection.quality,
+		Container:       strings.TrimPrefix(filepath.Ext(target), "."),
+		Target:          target,
+		EstimatedSize:   selection.estimatedSize(video.Duration),
+		PostProcessing:  planSteps(selection, opts),
+	}
+	if selection.video != nil {
+		item.VideoItag = selection.video.Itag
+	}
+	if selection.audio != nil {
+		item.AudioItag = selection.audio.Itag
+	}
+
+	return item, video, nil
+}
+
+// planSteps lists the post-processing steps a download will run, in order.
+func planSteps(selection *streamSelection, opts *downloadOptions) []string {
+	steps := []string{}
+	if selection.needsMux() {
+		steps = append(steps, "mux")
+		if opts.keepSeparate {
+			steps = append(steps, "keep-separate")
+		}
+	}
+	if opts.section != "" {
+		steps = append(steps, "section:"+opts.section)
+	}
+	if opts.recodeVideo != "" {
+		codec := opts.recodeCodec
+		if codec == "" {
+			codec = "default"
+		}
+		steps = append(steps, fmt.Sprintf("recode:%s:%s", strings.ToLower(opts.recodeVideo), codec))
+	}
+	if opts.splitSize != "" {
+		steps = append(steps, "split:"+opts.splitSize)
+	}
+	if opts.normalizeAudio {
+		steps = append(steps, fmt.Sprintf("normalize-audio:%g", opts.loudnessTarget))
+	}
+	if opts.splitChapters {
+		steps = append(steps, "split-chapters")
+	}
+	if opts.musicMetadata != "" {
+		steps = append(steps, "music-metadata:"+strings.ToLower(opts.musicMetadata))
+	}
+	if opts.embedLyrics {
+		steps = append(steps, "embed-lyrics")
+	}
+	if opts.replayGain {
+		steps = append(steps, "replaygain")
+	}
+	if opts.splitStoryboards {
+		steps = append(steps, "split-storyboards")
+	} else if opts.writeStoryboards {
+		steps = append(steps, "write-storyboards")
+	}
+	for _, command := range opts.exec {
+		steps = append(steps, "exec:"+command)
+	}
+	return steps
+}
+
+// readPlan loads and validates a plan file.
+func readPlan(path string) (*downloadPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	var plan downloadPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	if plan.Version != planVersion {
+		return nil, fmt.Errorf("unsupported plan version %d (expected %d)", plan.Version, planVersion)
+	}
+	return &plan, nil
+}
+
+// executePlan performs every item of the plan at path exactly as planned.
+// Formats are looked up again by itag because stream URLs expire; an item
+// whose format is no longer offered fails instead of silently changing quality.
+func executePlan(
+	ctx context.Context,
+	w io.Writer,
+	path string,
+	src *sources,
+	downloader download.StreamDownloader,
+	muxer MuxerFunc,
+) error {
+	plan, err := readPlan(path)
+	if err != nil {
+		return err
+	}
+
+	opts := plan.Options.downloadOptions()
+	if _, err := parseByteSize(opts.splitSize); err != nil {
+		return fmt.Errorf("invalid split_size in plan: %w", err)
+	}
+	if _, err := parseRecodeOptions(opts); err != nil {
+		return fmt.Errorf("invalid recode options in plan: %w", err)
+	}
+	section, err := parseSection(opts.section)
+	if err != nil {
+		return fmt.Errorf("invalid section in plan: %w", err)
+	}
+	if opts.embedLyrics {
+		opts.lyrics = videoLyrics(src)
+	}
+	if opts.writeStoryboards {
+		opts.storyboards = youtube.NewStoryboardDownloader(src.client)
+	}
+	if opts.musicMetadata != "" {
+		if opts.musicProvider, err = musicmeta.Open(opts.musicMetadata, src.client); err != nil {
+			return fmt.Errorf("invalid music_metadata in plan: %w", err)
+		}
+	}
+
+	for i := range plan.Items {
+		item := &plan.Items[i]
+		_, _ = fmt.Fprintf(w, "[%d/%d] %s\n", i+1, len(plan.Items), item.Title)
+		if err := executePlanItem(ctx, w, item, opts, section, src, downloader, muxer); err != nil {
+			return fmt.Errorf("plan item %d (%s): %w", i+1, item.VideoID, err)
+		}
+	}
+	return nil
+}
+
+// executePlanItem downloads and post-processes a single plan item.
+func executePlanItem(
+	ctx context.Context,
+	w io.Writer,
+	item *planItem,
+	opts *downloadOptions,
+	section *youtube.TimeRange,
+	src *sources,
+	downloader download.StreamDownloader,
+	muxer MuxerFunc,
+) error {
+	if item.Target == "" {
+		return errors.New("plan item has no target")
+	}
+
+	// Plans can be edited by hand, so the ID is checked before fetching anything
+	videoID, err := youtube.NewVideoID(item.VideoID)
+	if err != nil {
+		return err
+	}
+	video, manifest, err := fetchVideo(ctx, w, videoID, src)
+	if err != nil {
+		return err
+	}
+	downloader = download.WithRefresher(downloader, ytdl.FetcherRefresher(src.videos, videoID))
+
+	selection, err := planSelection(manifest, item)
+	if err != nil {
+		return err
+	}
+	selection.section = section
+	selection.keepSeparate = opts.keepSeparate
+
+	if err := downloadSelection(ctx, w, video, selection, item.Target, nil, downloader, muxer); err != nil {
+		return err
+	}
+	return postProcess(ctx, w, video, nil, item.Target, opts)
+}
+
+// planSelection finds the streams recorded in a plan item.
+func planSelection(manifest *youtube.StreamManifest, item *planItem) (*streamSelection, error) {
+	selection := &streamSelection{quality: item.Quality, container: youtube.Container(item.Container)}
+
+	if item.VideoItag != 0 {
+		selection.video = manifest.VideoStreamByItag(item.VideoItag)
+		if selection.video == nil {
+			return nil, fmt.Errorf("format %d is no longer available", item.VideoItag)
+		}
+	}
+	if item.AudioItag != 0 {
+		selection.audio = manifest.AudioStreamByItag(item.AudioItag)
+		if selection.audio == nil {
+			return nil, fmt.Errorf("format %d is no longer available", item.AudioItag)
+		}
+	}
+
+	if selection.video == nil && selection.audio == nil {
+		return nil, errors.New("plan item has no formats")
+	}
+	return selection, nil
+}
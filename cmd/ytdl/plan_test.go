@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// newPlanTestServer serves a watch page whose adaptive formats come from formats,
+// with STREAM_URL replaced by the server's stream endpoint.
+func newPlanTestServer(t *testing.T, formats string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/watch" {
+			_, _ = w.Write([]byte("stream " + r.URL.Query().Get("itag")))
+			return
+		}
+		response := `{"videoDetails":{"videoId":"dQw4w9WgXcQ","title":"Test Video","author":"Test Channel","lengthSeconds":"60"},` +
+			`"playabilityStatus":{"status":"OK"},"streamingData":{` + formats + `}}`
+		response = strings.ReplaceAll(response, "STREAM_URL", server.URL+"/stream")
+		_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = ` + response + `;</script>`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+const planTestFormats = `"adaptiveFormats":[` +
+	`{"itag":137,"url":"STREAM_URL?itag=137","mimeType":"video/mp4; codecs=\"avc1.640028\"","height":1080,"qualityLabel":"1080p","contentLength":"1000"},` +
+	`{"itag":140,"url":"STREAM_URL?itag=140","mimeType":"audio/mp4; codecs=\"mp4a.40.2\"","bitrate":128000,"contentLength":"200"}]`
+
+func TestPrintPlan(t *testing.T) {
+	server := newPlanTestServer(t, planTestFormats)
+	outputDir := t.TempDir()
+	opts := &downloadOptions{output: outputDir, quality: "best", format: "mp4", recodeVideo: "mkv", splitSize: "25M", hwAccel: "none", printPlan: true}
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+
+	buf := new(bytes.Buffer)
+	if err := runDownloadWithDeps(context.Background(), buf, "dQw4w9WgXcQ", opts, fetcher, download.NewDownloader(server.Client()), nil); err != nil {
+		t.Fatalf("print plan failed: %v", err)
+	}
+
+	var plan downloadPlan
+	if err := json.Unmarshal(buf.Bytes(), &plan); err != nil {
+		t.Fatalf("output is not a JSON plan: %v\n%s", err, buf.String())
+	}
+	if plan.Version != planVersion {
+		t.Errorf("version = %d, want %d", plan.Version, planVersion)
+	}
+	if plan.Options.RecodeVideo != "mkv" || plan.Options.SplitSize != "25M" {
+		t.Errorf("options not recorded: %+v", plan.Options)
+	}
+	if len(plan.Items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(plan.Items))
+	}
+
+	item := plan.Items[0]
+	if item.VideoItag != 137 || item.AudioItag != 140 {
+		t.Errorf("itags = %d/%d, want 137/140", item.VideoItag, item.AudioItag)
+	}
+	if item.Target != filepath.Join(outputDir, "Test Video.mp4") {
+		t.Errorf("target = %q", item.Target)
+	}
+	if item.EstimatedSize != 1200 {
+		t.Errorf("estimated size = %d, want 1200", item.EstimatedSize)
+	}
+	if got := strings.Join(item.PostProcessing, ","); got != "mux,recode:mkv:default,split:25M" {
+		t.Errorf("post-processing = %q", got)
+	}
+
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 0 {
+		t.Error("printing a plan should not download anything")
+	}
+}
+
+func TestExecutePlan(t *testing.T) {
+	server := newPlanTestServer(t, planTestFormats)
+	target := filepath.Join(t.TempDir(), "planned.mp4")
+	plan := downloadPlan{
+		Version: planVersion,
+		Options: planOptions{Format: "mp4", Quality: "best"},
+		Items:   []planItem{{VideoID: "dQw4w9WgXcQ", Title: "Test Video", Container: "mp4", VideoItag: 137, AudioItag: 140, Target: target}},
+	}
+	planPath := writePlan(t, plan)
+
+	var muxedVideo, muxedAudio string
+	muxer := func(_ context.Context, videoPath, audioPath, outputPath string, _ time.Duration, _ ffmpeg.ProgressCallback) error {
+		v, _ := os.ReadFile(videoPath)
+		a, _ := os.ReadFile(audioPath)
+		muxedVideo, muxedAudio = string(v), string(a)
+		return os.WriteFile(outputPath, []byte("muxed"), 0o644)
+	}
+
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	buf := new(bytes.Buffer)
+	if err := executePlan(context.Background(), buf, planPath, fetcher, download.NewDownloader(server.Client()), muxer); err != nil {
+		t.Fatalf("executePlan failed: %v", err)
+	}
+
+	if muxedVideo != "stream 137" || muxedAudio != "stream 140" {
+		t.Errorf("muxed %q and %q, want the planned itags", muxedVideo, muxedAudio)
+	}
+	if _, err := os.Stat(target); err != nil {
+		t.Errorf("expected planned target to exist: %v", err)
+	}
+}
+
+func TestExecutePlan_FormatNoLongerAvailable(t *testing.T) {
+	server := newPlanTestServer(t, planTestFormats)
+	plan := downloadPlan{
+		Version: planVersion,
+		Options: planOptions{Format: "mp4", Quality: "best"},
+		Items:   []planItem{{VideoID: "dQw4w9WgXcQ", Container: "mp4", VideoItag: 248, Target: filepath.Join(t.TempDir(), "out.mp4")}},
+	}
+
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	err := executePlan(context.Background(), new(bytes.Buffer), writePlan(t, plan), fetcher, download.NewDownloader(server.Client()), nil)
+	if err == nil || !strings.Contains(err.Error(), "format 248 is no longer available") {
+		t.Errorf("expected missing format error, got %v", err)
+	}
+}
+
+func TestExecutePlan_InvalidVideoID(t *testing.T) {
+	server := newPlanTestServer(t, planTestFormats)
+	plan := downloadPlan{
+		Version: planVersion,
+		Options: planOptions{Format: "mp4", Quality: "best"},
+		Items:   []planItem{{VideoID: "../watch", Container: "mp4", VideoItag: 137, Target: filepath.Join(t.TempDir(), "out.mp4")}},
+	}
+
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	err := executePlan(context.Background(), new(bytes.Buffer), writePlan(t, plan), fetcher, download.NewDownloader(server.Client()), nil)
+	if !errors.Is(err, youtube.ErrInvalidVideoID) {
+		t.Errorf("executePlan() error = %v, want ErrInvalidVideoID", err)
+	}
+}
+
+func TestReadPlan_UnsupportedVersion(t *testing.T) {
+	path := writePlan(t, downloadPlan{Version: planVersion + 1})
+	if _, err := readPlan(path); err == nil {
+		t.Error("expected error for unsupported plan version")
+	}
+}
+
+func TestDownloadCommand_ExecutePlanTakesNoURL(t *testing.T) {
+	rootCmd := newRootCmd()
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	rootCmd.SetArgs([]string{"download", "--execute-plan", "plan.json", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"})
+
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected an error when both a URL and --execute-plan are given")
+	}
+}
+
+func writePlan(t *testing.T, plan downloadPlan) string {
+	t.Helper()
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPlanSteps_Section(t *testing.T) {
+	selection := &streamSelection{video: &youtube.VideoStreamInfo{}, audio: &youtube.AudioStreamInfo{}}
+	opts := &downloadOptions{section: "1:30-3:00", splitSize: "25M"}
+	if got := strings.Join(planSteps(selection, opts), ","); got != "mux,section:1:30-3:00,split:25M" {
+		t.Errorf("planSteps = %q", got)
+	}
+	if got := newPlanOptions(opts).downloadOptions().section; got != "1:30-3:00" {
+		t.Errorf("section does not round-trip through the plan: %q", got)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// playlistOptions holds the flags of the playlist command.
+type playlistOptions struct {
+	json  bool
+	flat  bool
+	limit int
+}
+
+// playlistListing is the JSON form of a playlist printed with --json.
+type playlistListing struct {
+	ID          string          `json:"id"`
+	Title       string          `json:"title"`
+	Author      string          `json:"author"`
+	ChannelID   string          `json:"channel_id,omitempty"`
+	Description string          `json:"description,omitempty"`
+	VideoCount  int             `json:"video_count"`
+	Videos      []playlistEntry `json:"videos"`
+}
+
+// playlistEntry is a video in a playlistListing. With --flat only the index and ID are set.
+type playlistEntry struct {
+	Index           int    `json:"index"`
+	ID              string `json:"id"`
+	Title           string `json:"title,omitempty"`
+	Author          string `json:"author,omitempty"`
+	DurationSeconds int    `json:"duration_seconds,omitempty"`
+}
+
+func newPlaylistCmd() *cobra.Command {
+	opts := &playlistOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "playlist <url>",
+		Short: "List the videos in a playlist",
+		Long: `List the contents of a YouTube playlist.
+
+Shows the playlist title, author and a numbered listing of every video
+with its ID, duration and title. All pages of the playlist are fetched.
+Mixes (lists starting with RD) are endless, so only their first videos are listed.
+
+Use --flat to print only the video IDs, one per line, for use in scripts.
+Use --limit to list only the first videos; later pages aren't fetched.`,
+		Example: `  ytdl playlist https://www.youtube.com/playlist?list=PLAYLIST_ID
+  ytdl playlist PLAYLIST_ID --json
+  ytdl playlist PLAYLIST_ID --flat | xargs -n1 ytdl download
+  ytdl playlist PLAYLIST_ID --limit 10`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.limit < 0 {
+				return WrapError(fmt.Errorf("invalid --limit: %d", opts.limit))
+			}
+			client, err := newHTTPClient(cmd)
+			if err != nil {
+				return WrapError(err)
+			}
+			metadataCache, err := newMetadataCache(cmd)
+			if err != nil {
+				return WrapError(err)
+			}
+			fetcher := &youtube.PlaylistFetcher{Client: client, Cache: metadataCache}
+			if err := runPlaylistWithFetcher(cmd.Context(), cmd.OutOrStdout(), args[0], opts, fetcher); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.json, "json", false, "Print the playlist as JSON")
+	cmd.Flags().BoolVar(&opts.flat, "flat", false, "Print only video IDs, without titles and durations")
+	cmd.Flags().IntVar(&opts.limit, "limit", 0, "List at most this many videos (0 for all)")
+
+	return cmd
+}
+
+// runPlaylistWithFetcher implements the playlist command logic with a configurable fetcher.
+func runPlaylistWithFetcher(ctx context.Context, w io.Writer, urlStr string, opts *playlistOptions, fetcher *youtube.PlaylistFetcher) error {
+	query, err := youtube.ResolveQueryContext(ctx, urlStr, youtube.NewURLExpander(fetcher.Client))
+	if err != nil || query.PlaylistID == "" {
+		return fmt.Errorf("invalid playlist URL or ID: %w", youtube.ErrInvalidPlaylistID)
+	}
+
+	var playlist *youtube.Playlist
+	var videos []youtube.PlaylistVideo
+	if youtube.IsMixPlaylistID(query.PlaylistID) {
+		mixFetcher := &youtube.MixFetcher{Client: fetcher.Client, BaseURL: fetcher.BaseURL, Cookies: fetcher.Cookies}
+		playlist, videos, err = mixFetcher.Fetch(ctx, query.PlaylistID, youtube.MixOptions{VideoID: query.VideoID, Limit: opts.limit})
+	} else if opts.limit > 0 {
+		playlist, videos, err = fetchPlaylistHead(ctx, fetcher, query.PlaylistID, opts.limit)
+	} else {
+		playlist, videos, err = fetcher.Fetch(ctx, query.PlaylistID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch playlist: %w", err)
+	}
+
+	if opts.json {
+		return printPlaylistJSON(w, playlist, videos, opts.flat)
+	}
+
+	if opts.flat {
+		for i := range videos {
+			_, _ = fmt.Fprintln(w, videos[i].ID)
+		}
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(w, "Playlist: %s\n", playlist.Title)
+	_, _ = fmt.Fprintf(w, "Author:   %s\n", playlist.Author.Name)
+	_, _ = fmt.Fprintf(w, "Videos:   %d\n", playlist.VideoCount)
+	_, _ = fmt.Fprintf(w, "URL:      %s\n\n", youtube.PlaylistURL(playlist.ID))
+
+	width := len(fmt.Sprint(len(videos)))
+	for i := range videos {
+		v := &videos[i]
+		_, _ = fmt.Fprintf(w, "%*d. %s  %8s  %s\n", width, v.Index, v.ID, v.DurationString(), v.Title)
+	}
+	return nil
+}
+
+// fetchPlaylistHead fetches the playlist's metadata and its first limit videos,
+// requesting only the pages needed for them.
+func fetchPlaylistHead(ctx context.Context, fetcher *youtube.PlaylistFetcher, playlistID youtube.PlaylistID, limit int) (*youtube.Playlist, []youtube.PlaylistVideo, error) {
+	var playlist *youtube.Playlist
+	var videos []youtube.PlaylistVideo
+	for page, err := range fetcher.Pages(ctx, playlistID) {
+		if err != nil {
+			return nil, nil, err
+		}
+		playlist = &page.Playlist
+		for _, video := range page.Videos {
+			if video.Index == 0 {
+				video.Index = len(videos) + 1
+			}
+			videos = append(videos, video)
+			if len(videos) == limit {
+				return playlist, videos, nil
+			}
+		}
+	}
+	return playlist, videos, nil
+}
+
+// printPlaylistJSON writes the playlist and its videos to w as JSON.
+func printPlaylistJSON(w io.Writer, playlist *youtube.Playlist, videos []youtube.PlaylistVideo, flat bool) error {
+	listing := playlistListing{
+		ID:          playlist.ID,
+		Title:       playlist.Title,
+		Author:      playlist.Author.Name,
+		ChannelID:   playlist.Author.ChannelID,
+		Description: playlist.Description,
+		VideoCount:  playlist.VideoCount,
+		Videos:      make([]playlistEntry, 0, len(videos)),
+	}
+	for i := range videos {
+		v := &videos[i]
+		entry := playlistEntry{Index: v.Index, ID: v.ID}
+		if !flat {
+			entry.Title = v.Title
+			entry.Author = v.Author.Name
+			entry.DurationSeconds = v.DurationSeconds
+		}
+		listing.Videos = append(listing.Videos, entry)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(listing)
+}
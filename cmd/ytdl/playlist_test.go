@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// newPlaylistCmdTestServer serves a single-page playlist with two videos.
+func newPlaylistCmdTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/playlist" {
+			http.NotFound(w, r)
+			return
+		}
+		servePlaylistCmdTestPage(w)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// servePlaylistCmdTestPage writes the page of a playlist with two videos.
+func servePlaylistCmdTestPage(w http.ResponseWriter) {
+	video := func(id, title, seconds, index string) string {
+		return `{"playlistVideoRenderer":{"videoId":"` + id + `","title":{"runs":[{"text":"` + title + `"}]},"lengthSeconds":"` + seconds +
+			`","index":{"simpleText":"` + index + `"},"shortBylineText":{"runs":[{"text":"Uploader"}]}}}`
+	}
+	data := `{"header":{"playlistHeaderRenderer":{"title":{"simpleText":"Test Playlist"},"numVideosText":{"runs":[{"text":"2 videos"}]},` +
+		`"ownerText":{"runs":[{"text":"Test Channel","navigationEndpoint":{"browseEndpoint":{"browseId":"UCtest"}}}]}}},` +
+		`"contents":{"twoColumnBrowseResultsRenderer":{"tabs":[{"tabRenderer":{"content":{"sectionListRenderer":{"contents":[{"itemSectionRenderer":{"contents":[{"playlistVideoListRenderer":{"contents":[` +
+		video("dQw4w9WgXcQ", "First Video", "212", "1") + `,` + video("jNQXAC9IVRw", "Second Video", "19", "2") +
+		`]}}]}}]}}}}]}}}`
+	_, _ = w.Write([]byte(`<script>var ytInitialData = ` + data + `;</script>`))
+}
+
+const testPlaylistURL = "https://www.youtube.com/playlist?list=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"
+
+func TestPlaylistCommandExists(t *testing.T) {
+	rootCmd := newRootCmd()
+	playlistCmd, _, err := rootCmd.Find([]string{"playlist"})
+	if err != nil {
+		t.Fatalf("playlist command not found: %v", err)
+	}
+	if playlistCmd.Use != "playlist <url>" {
+		t.Errorf("expected Use to be 'playlist <url>', got %q", playlistCmd.Use)
+	}
+	for _, name := range []string{"json", "flat", "limit"} {
+		if playlistCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag", name)
+		}
+	}
+}
+
+func TestPlaylistCommandListsVideos(t *testing.T) {
+	server := newPlaylistCmdTestServer(t)
+	fetcher := &youtube.PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	buf := new(bytes.Buffer)
+	if err := runPlaylistWithFetcher(context.Background(), buf, testPlaylistURL, &playlistOptions{}, fetcher); err != nil {
+		t.Fatalf("playlist command failed: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		"Playlist: Test Playlist",
+		"Author:   Test Channel",
+		"Videos:   2",
+		"1. dQw4w9WgXcQ      3:32  First Video",
+		"2. jNQXAC9IVRw      0:19  Second Video",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestPlaylistCommandFlat(t *testing.T) {
+	server := newPlaylistCmdTestServer(t)
+	fetcher := &youtube.PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	buf := new(bytes.Buffer)
+	if err := runPlaylistWithFetcher(context.Background(), buf, testPlaylistURL, &playlistOptions{flat: true}, fetcher); err != nil {
+		t.Fatalf("playlist command failed: %v", err)
+	}
+
+	if got := buf.String(); got != "dQw4w9WgXcQ\njNQXAC9IVRw\n" {
+		t.Errorf("flat output = %q, want one ID per line", got)
+	}
+}
+
+func TestPlaylistCommandLimit(t *testing.T) {
+	server := newPlaylistCmdTestServer(t)
+	fetcher := &youtube.PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	buf := new(bytes.Buffer)
+	if err := runPlaylistWithFetcher(context.Background(), buf, testPlaylistURL, &playlistOptions{flat: true, limit: 1}, fetcher); err != nil {
+		t.Fatalf("playlist command failed: %v", err)
+	}
+
+	if got := buf.String(); got != "dQw4w9WgXcQ\n" {
+		t.Errorf("limited output = %q, want the first video", got)
+	}
+}
+
+func TestPlaylistCommandJSON(t *testing.T) {
+	server := newPlaylistCmdTestServer(t)
+	fetcher := &youtube.PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	for _, flat := range []bool{false, true} {
+		buf := new(bytes.Buffer)
+		if err := runPlaylistWithFetcher(context.Background(), buf, testPlaylistURL, &playlistOptions{json: true, flat: flat}, fetcher); err != nil {
+			t.Fatalf("playlist command failed: %v", err)
+		}
+
+		var listing playlistListing
+		if err := json.Unmarshal(buf.Bytes(), &listing); err != nil {
+			t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+		}
+		if listing.ID != "PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf" || listing.Title != "Test Playlist" || listing.ChannelID != "UCtest" {
+			t.Errorf("listing = %+v", listing)
+		}
+		if len(listing.Videos) != 2 || listing.Videos[1].ID != "jNQXAC9IVRw" || listing.Videos[1].Index != 2 {
+			t.Fatalf("videos = %+v", listing.Videos)
+		}
+
+		first := listing.Videos[0]
+		if flat && (first.Title != "" || first.DurationSeconds != 0) {
+			t.Errorf("flat JSON should omit video details, got %+v", first)
+		}
+		if !flat && (first.Title != "First Video" || first.DurationSeconds != 212 || first.Author != "Uploader") {
+			t.Errorf("video details = %+v", first)
+		}
+	}
+}
+
+func TestPlaylistCommandAcceptsWatchURLWithList(t *testing.T) {
+	server := newPlaylistCmdTestServer(t)
+	fetcher := &youtube.PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	urlStr := "https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"
+	if err := runPlaylistWithFetcher(context.Background(), new(bytes.Buffer), urlStr, &playlistOptions{flat: true}, fetcher); err != nil {
+		t.Errorf("playlist command failed for watch URL with list: %v", err)
+	}
+}
+
+// TestPlaylistCommandListsMix tests listing a mix whose "next" panel has no further videos.
+func TestPlaylistCommandListsMix(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data := `{"contents":{"twoColumnWatchNextResults":{"playlist":{"playlist":{"title":"Mix - First Video","contents":[` +
+			`{"playlistPanelVideoRenderer":{"videoId":"dQw4w9WgXcQ","title":{"simpleText":"First Video"},"lengthText":{"simpleText":"3:32"}}},` +
+			`{"playlistPanelVideoRenderer":{"videoId":"jNQXAC9IVRw","title":{"simpleText":"Second Video"},"lengthText":{"simpleText":"0:19"}}}]}}}}}`
+		switch {
+		case r.URL.Path == "/watch" && r.URL.Query().Get("list") == "RDdQw4w9WgXcQ":
+			_, _ = w.Write([]byte(`<script>var ytInitialData = ` + data + `;</script>`))
+		case r.URL.Path == "/youtubei/v1/next":
+			_, _ = w.Write([]byte(data))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	fetcher := &youtube.PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	out := new(bytes.Buffer)
+	if err := runPlaylistWithFetcher(context.Background(), out, "RDdQw4w9WgXcQ", &playlistOptions{flat: true}, fetcher); err != nil {
+		t.Fatalf("playlist command failed for mix: %v", err)
+	}
+	if got := out.String(); got != "dQw4w9WgXcQ\njNQXAC9IVRw\n" {
+		t.Errorf("unexpected mix listing:\n%s", got)
+	}
+}
+
+func TestPlaylistCommandRejectsVideoURL(t *testing.T) {
+	fetcher := &youtube.PlaylistFetcher{Client: http.DefaultClient}
+
+	err := runPlaylistWithFetcher(context.Background(), new(bytes.Buffer), "https://www.youtube.com/watch?v=dQw4w9WgXcQ", &playlistOptions{}, fetcher)
+	if !errors.Is(err, youtube.ErrInvalidPlaylistID) {
+		t.Errorf("expected ErrInvalidPlaylistID, got %v", err)
+	}
+}
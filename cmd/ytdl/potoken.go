@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// addPOTokenFlags registers the global PO token flags on the root command.
+func addPOTokenFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("po-token", "",
+		"PO token to add to stream URLs, for when downloads fail with 403 Forbidden (use with --visitor-data)")
+	cmd.PersistentFlags().String("po-token-provider", "",
+		"URL of a PO token server to request a token from for each video (e.g. http://127.0.0.1:4416/get_pot)")
+	cmd.PersistentFlags().String("visitor-data", "", "YouTube visitor data of the session a --po-token was minted for")
+}
+
+// newPOTokenProvider returns the PO token provider set by --po-token or
+// --po-token-provider, or nil if neither is set. Token servers are requested
+// with client, so they are reached through the same proxy.
+func newPOTokenProvider(cmd *cobra.Command, client *http.Client) (youtube.POTokenProvider, error) {
+	token := flagValue(cmd, "po-token")
+	providerURL := flagValue(cmd, "po-token-provider")
+	switch {
+	case token != "" && providerURL != "":
+		return nil, errors.New("--po-token and --po-token-provider cannot be used together")
+	case token != "":
+		return youtube.StaticPOToken(token), nil
+	case providerURL != "":
+		if u, err := url.Parse(providerURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid --po-token-provider %q: must be an http or https URL", providerURL)
+		}
+		return &youtube.HTTPPOTokenProvider{Client: client, URL: providerURL}, nil
+	}
+	return nil, nil
+}
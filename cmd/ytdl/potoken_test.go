@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+func TestNewPOTokenProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    youtube.POTokenProvider
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "static", args: []string{"--po-token", "tok"}, want: youtube.StaticPOToken("tok")},
+		{name: "provider", args: []string{"--po-token-provider", "http://127.0.0.1:4416/get_pot"},
+			want: &youtube.HTTPPOTokenProvider{Client: http.DefaultClient, URL: "http://127.0.0.1:4416/get_pot"}},
+		{name: "both", args: []string{"--po-token", "tok", "--po-token-provider", "http://127.0.0.1:4416"}, wantErr: true},
+		{name: "not http", args: []string{"--po-token-provider", "127.0.0.1:4416"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newRootCmd()
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			got, err := newPOTokenProvider(cmd, http.DefaultClient)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newPOTokenProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			switch want := tt.want.(type) {
+			case *youtube.HTTPPOTokenProvider:
+				if p, ok := got.(*youtube.HTTPPOTokenProvider); !ok || *p != *want {
+					t.Errorf("provider = %#v, want %#v", got, want)
+				}
+			default:
+				if got != tt.want {
+					t.Errorf("provider = %#v, want %#v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewWatchPageFetcher_POTokenFlags(t *testing.T) {
+	cmd := newRootCmd()
+	if err := cmd.ParseFlags([]string{"--no-cache", "--po-token", "tok", "--visitor-data", "CgtWaXNpdG9y"}); err != nil {
+		t.Fatal(err)
+	}
+	fetcher, err := newWatchPageFetcher(cmd, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("newWatchPageFetcher: %v", err)
+	}
+	if fetcher.POTokenProvider != youtube.StaticPOToken("tok") || fetcher.VisitorData != "CgtWaXNpdG9y" {
+		t.Errorf("fetcher provider %#v, visitor data %q", fetcher.POTokenProvider, fetcher.VisitorData)
+	}
+}
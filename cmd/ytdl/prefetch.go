@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+// defaultPrefetch is how many playlist videos are fetched ahead of the one downloading.
+const defaultPrefetch = 3
+
+// videoPrefetcher fetches the watch pages of upcoming playlist videos in the
+// background, at most limit at a time, so a video's info is ready by the time
+// its download starts.
+type videoPrefetcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	videos ytdl.VideoFetcher
+	sem    chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	pending map[youtube.VideoID]*prefetchedVideo
+}
+
+// prefetchedVideo is the result of one background fetch; done is closed once it is set.
+type prefetchedVideo struct {
+	done   chan struct{}
+	result *ytdl.Video
+	err    error
+}
+
+// newVideoPrefetcher returns a prefetcher that runs at most limit fetches at a
+// time. Its fetches are canceled with ctx or by stop.
+func newVideoPrefetcher(ctx context.Context, videos ytdl.VideoFetcher, limit int) *videoPrefetcher {
+	ctx, cancel := context.WithCancel(ctx)
+	return &videoPrefetcher{
+		ctx:     ctx,
+		cancel:  cancel,
+		videos:  videos,
+		sem:     make(chan struct{}, limit),
+		pending: make(map[youtube.VideoID]*prefetchedVideo),
+	}
+}
+
+// start begins fetching the videos that aren't fetched or being fetched already.
+func (p *videoPrefetcher) start(ids ...youtube.VideoID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := p.pending[id]; ok {
+			continue
+		}
+		video := &prefetchedVideo{done: make(chan struct{})}
+		p.pending[id] = video
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			defer close(video.done)
+			select {
+			case p.sem <- struct{}{}:
+			case <-p.ctx.Done():
+				video.err = p.ctx.Err()
+				return
+			}
+			defer func() { <-p.sem }()
+			video.result, video.err = p.videos.FetchVideo(p.ctx, id)
+		}()
+	}
+}
+
+// take waits for the prefetched result of a video and forgets it, so fetching
+// the video again, such as while waiting for a premiere, makes a new request.
+// ok is false if the video wasn't prefetched, or its fetch was canceled.
+func (p *videoPrefetcher) take(ctx context.Context, id youtube.VideoID) (result *ytdl.Video, ok bool, err error) {
+	if p == nil {
+		return nil, false, nil
+	}
+	p.mu.Lock()
+	video, found := p.pending[id]
+	delete(p.pending, id)
+	p.mu.Unlock()
+	if !found {
+		return nil, false, nil
+	}
+
+	select {
+	case <-video.done:
+	case <-ctx.Done():
+		return nil, true, ctx.Err()
+	}
+	if video.err != nil && p.ctx.Err() != nil {
+		return nil, false, nil
+	}
+	return video.result, true, video.err
+}
+
+// stop cancels the fetches still running and waits for them to return.
+func (p *videoPrefetcher) stop() {
+	p.cancel()
+	p.wg.Wait()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// newWatchPageTestServer serves a playable watch page titled after the requested
+// video, taking delay per request, and records the most requests served at once.
+func newWatchPageTestServer(t *testing.T, delay time.Duration) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+	t.Helper()
+	var requests, inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			highest := maxInFlight.Load()
+			if n <= highest || maxInFlight.CompareAndSwap(highest, n) {
+				break
+			}
+		}
+		time.Sleep(delay)
+		id := r.URL.Query().Get("v")
+		_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"` + id + `","title":"Video ` + id + `","lengthSeconds":"60"},` +
+			`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[]}};</script>`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, &maxInFlight
+}
+
+func TestVideoPrefetcher_LimitsConcurrentFetches(t *testing.T) {
+	server, requests, maxInFlight := newWatchPageTestServer(t, 20*time.Millisecond)
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+
+	prefetcher := newVideoPrefetcher(context.Background(), fetcher.videos, 2)
+	defer prefetcher.stop()
+	ids := []youtube.VideoID{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc", "ddddddddddd", "eeeeeeeeeee"}
+	prefetcher.start(ids...)
+	prefetcher.start(ids[0]) // already started, not fetched twice
+
+	for _, id := range ids {
+		result, ok, err := prefetcher.take(context.Background(), id)
+		if !ok || err != nil {
+			t.Fatalf("take(%s) = ok %v, err %v", id, ok, err)
+		}
+		if want := "Video " + id.String(); result.Video.Title != want {
+			t.Errorf("take(%s) title = %q, want %q", id, result.Video.Title, want)
+		}
+	}
+	if got := requests.Load(); got != int32(len(ids)) {
+		t.Errorf("requests = %d, want %d", got, len(ids))
+	}
+	if got := maxInFlight.Load(); got > 2 {
+		t.Errorf("concurrent requests = %d, want at most 2", got)
+	}
+}
+
+func TestVideoPrefetcher_TakeNotPrefetched(t *testing.T) {
+	fetcher := newSources(&youtube.WatchPageFetcher{})
+	prefetcher := newVideoPrefetcher(context.Background(), fetcher.videos, 1)
+	defer prefetcher.stop()
+
+	if _, ok, _ := prefetcher.take(context.Background(), "aaaaaaaaaaa"); ok {
+		t.Error("take() of a video that wasn't started reported ok")
+	}
+
+	var nilPrefetcher *videoPrefetcher
+	if _, ok, _ := nilPrefetcher.take(context.Background(), "aaaaaaaaaaa"); ok {
+		t.Error("take() on a nil prefetcher reported ok")
+	}
+}
+
+func TestVideoPrefetcher_TakeAfterStop(t *testing.T) {
+	server, _, _ := newWatchPageTestServer(t, 200*time.Millisecond)
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+
+	prefetcher := newVideoPrefetcher(context.Background(), fetcher.videos, 1)
+	prefetcher.start("aaaaaaaaaaa")
+	prefetcher.stop()
+
+	if _, ok, _ := prefetcher.take(context.Background(), "aaaaaaaaaaa"); ok {
+		t.Error("take() of a canceled fetch reported ok")
+	}
+}
+
+func TestFetchVideoWhenAvailable_UsesPrefetched(t *testing.T) {
+	server, requests, _ := newWatchPageTestServer(t, 0)
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+
+	prefetcher := newVideoPrefetcher(context.Background(), fetcher.videos, 1)
+	defer prefetcher.stop()
+	prefetcher.start("aaaaaaaaaaa")
+
+	buf := new(bytes.Buffer)
+	opts := &downloadOptions{prefetched: prefetcher}
+	video, _, err := fetchVideoWhenAvailable(context.Background(), buf, "aaaaaaaaaaa", opts, fetcher)
+	if err != nil {
+		t.Fatalf("fetchVideoWhenAvailable() error = %v", err)
+	}
+	if video.Title != "Video aaaaaaaaaaa" {
+		t.Errorf("title = %q", video.Title)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+	if !strings.Contains(buf.String(), "Fetched video info ahead: aaaaaaaaaaa") {
+		t.Errorf("output = %q, want it to say the video was prefetched", buf.String())
+	}
+}
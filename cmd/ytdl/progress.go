@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+const (
+	// multiProgressBarWidth is the width of each bar drawn by multiProgress.
+	multiProgressBarWidth = 30
+
+	// multiProgressReserved is the room multiProgress leaves next to its bars
+	// for the stream names and progress.
+	multiProgressReserved = 55
+
+	// multiProgressRedraw is the minimum interval between terminal redraws.
+	multiProgressRedraw = 100 * time.Millisecond
+
+	// multiProgressLogInterval is the interval between progress lines when the
+	// output isn't a terminal and can't be redrawn in place.
+	multiProgressLogInterval = 5 * time.Second
+)
+
+// multiProgress renders the progress of streams downloading in parallel: one bar
+// per stream plus a combined line, redrawn in place on a terminal. Other outputs,
+// such as log files, pipes and consoles without ANSI escape codes, get a plain
+// line at regular intervals instead.
+type multiProgress struct {
+	mu       sync.Mutex
+	w        io.Writer
+	tty      bool
+	barWidth int
+	now      func() time.Time
+	names    []string
+	progress []download.Progress
+
+	// drawn is the number of lines drawn on the terminal by the last redraw.
+	drawn    int
+	lastDraw time.Time
+}
+
+// newMultiProgress creates a renderer for streams with the given names, drawing
+// bars on w if it is a terminal that can redraw them.
+func newMultiProgress(w io.Writer, names ...string) *multiProgress {
+	c := detectConsole(w)
+	return &multiProgress{
+		w:        w,
+		tty:      c.tty && c.ansi,
+		barWidth: c.barWidth(multiProgressBarWidth, multiProgressReserved),
+		now:      time.Now,
+		names:    names,
+		progress: make([]download.Progress, len(names)),
+		lastDraw: time.Now(),
+	}
+}
+
+// callback returns the progress callback of the i-th stream.
+func (m *multiProgress) callback(i int) download.ProgressCallback {
+	return func(p download.Progress) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.progress[i] = p
+
+		interval := multiProgressLogInterval
+		if m.tty {
+			interval = multiProgressRedraw
+		}
+		if now := m.now(); now.Sub(m.lastDraw) >= interval {
+			m.lastDraw = now
+			m.draw()
+		}
+	}
+}
+
+// finish draws the final state of all streams.
+func (m *multiProgress) finish() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.draw()
+}
+
+// draw writes the current progress; the caller must hold m.mu.
+func (m *multiProgress) draw() {
+	total := m.combined()
+	if !m.tty {
+		parts := make([]string, 0, len(m.names)+1)
+		for i, name := range m.names {
+			parts = append(parts, name+" "+m.progress[i].String())
+		}
+		parts = append(parts, "Total "+total.String())
+		_, _ = fmt.Fprintf(m.w, "Progress: %s\n", strings.Join(parts, "; "))
+		return
+	}
+
+	var b strings.Builder
+	if m.drawn > 0 {
+		// Move back up to redraw the previous lines in place
+		fmt.Fprintf(&b, "\x1b[%dA", m.drawn)
+	}
+	width := len("Total")
+	for _, name := range m.names {
+		width = max(width, len(name))
+	}
+	writeLine := func(name string, p download.Progress) {
+		fmt.Fprintf(&b, "\r\x1b[2K%-*s %s %s\n", width, name, progressBar(p, m.barWidth), p)
+	}
+	for i, name := range m.names {
+		writeLine(name, m.progress[i])
+	}
+	writeLine("Total", total)
+	m.drawn = len(m.names) + 1
+	_, _ = io.WriteString(m.w, b.String())
+}
+
+// combined returns the progress of all streams together.
+func (m *multiProgress) combined() download.Progress {
+	return combineProgress(m.progress)
+}
+
+// combineProgress adds up the progress of streams downloading at the same time.
+func combineProgress(streams []download.Progress) download.Progress {
+	var total download.Progress
+	for _, p := range streams {
+		total.Downloaded += p.Downloaded
+		total.Total += p.Total
+		total.Speed += p.Speed
+		total.Elapsed = max(total.Elapsed, p.Elapsed)
+	}
+	if total.Total > total.Downloaded && total.Speed > 0 {
+		total.ETA = time.Duration(float64(total.Total-total.Downloaded) / total.Speed * float64(time.Second))
+	}
+	return total
+}
+
+// progressBar draws a bar of the given width for p, empty while the total is unknown.
+func progressBar(p download.Progress, width int) string {
+	filled := 0
+	if p.Total > 0 {
+		filled = int(min(p.Downloaded, p.Total) * int64(width) / p.Total)
+	}
+	bar := strings.Repeat("=", filled)
+	if filled < width {
+		if filled > 0 {
+			bar = bar[:filled-1] + ">"
+		}
+		bar += strings.Repeat(" ", width-filled)
+	}
+	return "[" + bar + "]"
+}
+
+// streamTarget is a stream to download in parallel with others.
+type streamTarget struct {
+	name  string
+	url   string
+	path  string
+	index *youtube.ByteRange // segment index of the stream, if reported
+}
+
+// downloadStreamsWithProgress downloads the streams in parallel, showing one
+// progress line per stream and a combined line.
+func downloadStreamsWithProgress(ctx context.Context, w io.Writer, downloader download.StreamDownloader, streams []streamTarget) error {
+	names := make([]string, len(streams))
+	for i, s := range streams {
+		names[i] = s.name
+	}
+	progress := newMultiProgress(w, names...)
+	err := downloadStreams(ctx, downloader, streams, progress.callback)
+	progress.finish()
+	return err
+}
+
+// downloadStreams downloads the streams in parallel, reporting the progress of the
+// i-th stream to progress(i). If one stream fails, the others are canceled and the
+// first failure is returned.
+func downloadStreams(
+	ctx context.Context,
+	downloader download.StreamDownloader,
+	streams []streamTarget,
+	progress func(i int) download.ProgressCallback,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu    sync.Mutex
+		first error
+		wg    sync.WaitGroup
+	)
+	for i, s := range streams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := downloader.DownloadStreamResult(withSegmentIndex(ctx, s.index), s.url, s.path, progress(i)).Error; err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				// Streams canceled because of the first failure fail too; keep the cause
+				if first == nil {
+					first = fmt.Errorf("failed to download %s: %w", strings.ToLower(s.name), err)
+					cancel()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return first
+}
+
+// readerMuxer muxes a video and an audio stream read as they download into w,
+// such as ffmpeg.MuxReadersToWriter.
+type readerMuxer func(ctx context.Context, video, audio io.Reader, container string, w io.Writer) error
+
+// streamToMuxer downloads the video and audio streams of option in parallel into
+// mux, which writes the muxed result to pipe as the streams arrive, showing the
+// progress of both streams and a combined line. A failed download stops the mux
+// and is returned rather than the mux error it causes.
+func streamToMuxer(
+	ctx context.Context,
+	w io.Writer,
+	option *youtube.DownloadOption,
+	pipe io.Writer,
+	downloader download.StreamDownloader,
+	mux readerMuxer,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	streams := []streamTarget{
+		{name: "Video", url: option.VideoStream.URL, index: option.VideoStream.IndexRange},
+		{name: "Audio", url: option.AudioStream.URL, index: option.AudioStream.IndexRange},
+	}
+	progress := newMultiProgress(w, "Video", "Audio")
+
+	var (
+		mu    sync.Mutex
+		first error
+		wg    sync.WaitGroup
+	)
+	readers := make([]*io.PipeReader, len(streams))
+	for i, s := range streams {
+		r, pw := io.Pipe()
+		readers[i] = r
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := downloader.DownloadStreamTo(withSegmentIndex(ctx, s.index), s.url, pw, progress.callback(i))
+			_ = pw.CloseWithError(err)
+			if err != nil && !errors.Is(err, io.ErrClosedPipe) {
+				mu.Lock()
+				defer mu.Unlock()
+				if first == nil {
+					first = fmt.Errorf("failed to download %s: %w", strings.ToLower(s.name), err)
+					cancel()
+				}
+			}
+		}()
+	}
+
+	muxErr := mux(ctx, readers[0], readers[1], string(option.Container), pipe)
+	// The muxer may stop before reading everything; unblock the downloads so they end
+	for _, r := range readers {
+		_ = r.CloseWithError(io.ErrClosedPipe)
+	}
+	wg.Wait()
+	progress.finish()
+
+	if first != nil {
+		return first
+	}
+	if muxErr != nil {
+		return fmt.Errorf("failed to mux streams: %w", muxErr)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// fakeClock returns a clock for multiProgress that advances by step on every call.
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Now()
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestMultiProgress_Terminal(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMultiProgress(&buf, "Video", "Audio")
+	m.tty = true
+	m.now = fakeClock(time.Second)
+
+	m.callback(0)(download.Progress{Downloaded: 50, Total: 100})
+	m.callback(1)(download.Progress{Downloaded: 25, Total: 100})
+	m.finish()
+
+	out := buf.String()
+	if strings.Count(out, "\x1b[3A") != 2 {
+		t.Errorf("expected later redraws to move up over the 3 previous lines, got %q", out)
+	}
+
+	// The last redraw holds the final state of both streams and the total
+	last := out[strings.LastIndex(out, "\x1b[3A"):]
+	lines := strings.Split(strings.TrimSuffix(last, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", lines)
+	}
+	for i, want := range []string{"Video [" + strings.Repeat("=", 14) + ">", "Audio [" + strings.Repeat("=", 6) + ">", "Total ["} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("line %d = %q, want it to contain %q", i, lines[i], want)
+		}
+	}
+	if !strings.Contains(lines[2], "(37.5%)") {
+		t.Errorf("expected combined percentage in %q", lines[2])
+	}
+}
+
+func TestMultiProgress_TerminalThrottle(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMultiProgress(&buf, "Video")
+	m.tty = true
+	m.now = fakeClock(time.Millisecond)
+
+	for i := range 10 {
+		m.callback(0)(download.Progress{Downloaded: int64(i), Total: 10})
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no redraw within %s, got %q", multiProgressRedraw, buf.String())
+	}
+}
+
+func TestMultiProgress_Log(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMultiProgress(&buf, "Video", "Audio")
+	if m.tty {
+		t.Fatal("a buffer should not be detected as a terminal")
+	}
+	m.now = fakeClock(multiProgressLogInterval / 2)
+
+	m.callback(0)(download.Progress{Downloaded: 512, Total: 1024})
+	m.callback(1)(download.Progress{Downloaded: 1024, Total: 1024})
+	m.finish()
+
+	out := buf.String()
+	if strings.Contains(out, "\x1b") {
+		t.Errorf("expected no escape sequences, got %q", out)
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one periodic line and one final line, got %q", lines)
+	}
+	want := "Progress: Video 512 B / 1.0 KiB (50.0%); Audio 1.0 KiB / 1.0 KiB (100.0%); Total 1.5 KiB / 2.0 KiB (75.0%)"
+	if lines[1] != want {
+		t.Errorf("final line = %q, want %q", lines[1], want)
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	tests := []struct {
+		progress download.Progress
+		want     string
+	}{
+		{download.Progress{Downloaded: 0, Total: 10}, "[          ]"},
+		{download.Progress{Downloaded: 5, Total: 10}, "[====>     ]"},
+		{download.Progress{Downloaded: 10, Total: 10}, "[==========]"},
+		{download.Progress{Downloaded: 5}, "[          ]"},
+	}
+	for _, tt := range tests {
+		if got := progressBar(tt.progress, 10); got != tt.want {
+			t.Errorf("progressBar(%+v) = %q, want %q", tt.progress, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadStreamsWithProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	downloader := download.NewDownloader(server.Client())
+
+	var buf bytes.Buffer
+	err := downloadStreamsWithProgress(context.Background(), &buf, downloader, []streamTarget{
+		{name: "Video", url: server.URL + "/video", path: filepath.Join(dir, "video")},
+		{name: "Audio", url: server.URL + "/audio", path: filepath.Join(dir, "audio")},
+	})
+	if err != nil {
+		t.Fatalf("downloadStreamsWithProgress() error = %v", err)
+	}
+	for _, name := range []string{"video", "audio"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != "/"+name {
+			t.Errorf("%s stream = %q, %v", name, data, err)
+		}
+	}
+
+	err = downloadStreamsWithProgress(context.Background(), &buf, downloader, []streamTarget{
+		{name: "Video", url: server.URL + "/video", path: filepath.Join(dir, "video2")},
+		{name: "Audio", url: server.URL + "/missing", path: filepath.Join(dir, "audio2")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to download audio") {
+		t.Errorf("expected audio failure, got %v", err)
+	}
+}
+
+func TestStreamToMuxer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+	downloader := download.NewDownloader(server.Client())
+
+	option := func(videoPath, audioPath string) *youtube.DownloadOption {
+		return &youtube.DownloadOption{
+			Container:   youtube.ContainerMKV,
+			VideoStream: &youtube.VideoStreamInfo{StreamInfo: youtube.StreamInfo{URL: server.URL + videoPath}},
+			AudioStream: &youtube.AudioStreamInfo{StreamInfo: youtube.StreamInfo{URL: server.URL + audioPath}},
+		}
+	}
+	// The fake muxer reads both streams to the end and writes them one after the other
+	concat := func(_ context.Context, video, audio io.Reader, container string, w io.Writer) error {
+		v, err := io.ReadAll(video)
+		if err != nil {
+			return err
+		}
+		a, err := io.ReadAll(audio)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s:%s+%s", container, v, a)
+		return err
+	}
+
+	var buf, pipe bytes.Buffer
+	if err := streamToMuxer(context.Background(), &buf, option("/video", "/audio"), &pipe, downloader, concat); err != nil {
+		t.Fatalf("streamToMuxer() error = %v", err)
+	}
+	if pipe.String() != "mkv:/video+/audio" {
+		t.Errorf("pipe = %q, want %q", pipe.String(), "mkv:/video+/audio")
+	}
+
+	// A failed download is reported instead of the muxer's error about its input
+	err := streamToMuxer(context.Background(), &buf, option("/video", "/missing"), io.Discard, downloader, concat)
+	if err == nil || !strings.Contains(err.Error(), "failed to download audio") {
+		t.Errorf("expected audio failure, got %v", err)
+	}
+
+	// A muxer that gives up early doesn't leave the downloads blocked
+	failing := func(context.Context, io.Reader, io.Reader, string, io.Writer) error {
+		return errors.New("invalid data")
+	}
+	err = streamToMuxer(context.Background(), &buf, option("/video", "/audio"), io.Discard, downloader, failing)
+	if err == nil || !strings.Contains(err.Error(), "failed to mux streams: invalid data") {
+		t.Errorf("expected mux failure, got %v", err)
+	}
+}
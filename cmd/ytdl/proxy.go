@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// defaultProxyAddr is the address the proxy command listens on without --addr.
+const defaultProxyAddr = ":8766"
+
+// proxiedHeaders are the response headers of YouTube passed on to players.
+var proxiedHeaders = []string{"Content-Length", "Content-Range", "Last-Modified", "ETag"}
+
+// dlnaContentFeatures tells DLNA renderers the stream can be played as it
+// arrives and seeked with range requests.
+const dlnaContentFeatures = "DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=01700000000000000000000000000000"
+
+// proxyOptions holds the flags of the proxy command.
+type proxyOptions struct {
+	addr    string
+	quality string
+	format  string
+}
+
+func newProxyCmd() *cobra.Command {
+	opts := &proxyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "proxy <url>",
+		Short: "Serve a video over local HTTP for TVs and other players",
+		Long: `Serve the stream of a video over HTTP, as in http://localhost:8766/video.mp4,
+for smart TVs, media players and other devices that can't reach YouTube
+themselves or need a plain file URL.
+
+Nothing is written to disk: the stream is fetched from YouTube as devices
+request it, and range requests are passed through so they can seek. When
+YouTube's stream URL expires, a new one is fetched.
+
+Devices need a single stream with video and audio, which YouTube only offers
+up to 360p; with --quality audio the audio stream is served instead.`,
+		Example: `  ytdl proxy https://www.youtube.com/watch?v=dQw4w9WgXcQ
+  ytdl proxy dQw4w9WgXcQ --addr 127.0.0.1:9000
+  ytdl proxy dQw4w9WgXcQ --quality audio`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := runProxy(cmd, args[0], opts); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.addr, "addr", defaultProxyAddr, "Address to listen on")
+	cmd.Flags().StringVarP(&opts.quality, "quality", "q", "best", "Video quality (best, worst, 360p, audio, etc.)")
+	cmd.Flags().StringVarP(&opts.format, "format", "f", "", "Preferred container (mp4, webm)")
+
+	return cmd
+}
+
+// runProxy serves the stream of the video at urlStr until interrupted.
+func runProxy(cmd *cobra.Command, urlStr string, opts *proxyOptions) error {
+	client, err := newHTTPClient(cmd)
+	if err != nil {
+		return err
+	}
+	page, err := newWatchPageFetcher(cmd, client)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	w := statusWriter(cmd)
+	proxy, err := newStreamProxy(ctx, w, urlStr, opts, newSources(page))
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	server := &http.Server{Handler: proxy.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	errc := make(chan error, 1)
+	go func() { errc <- server.Serve(listener) }()
+	_, _ = fmt.Fprintln(w, "Serving at:")
+	for _, u := range proxyURLs(listener.Addr(), proxy.name) {
+		_, _ = fmt.Fprintf(w, "  %s\n", u)
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// streamProxy serves a YouTube stream at /name, passing range requests
+// through so players can seek. YouTube's stream URLs expire after some hours,
+// so the stream is resolved again when YouTube refuses its URL.
+type streamProxy struct {
+	client  *http.Client
+	name    string
+	title   string
+	resolve func(ctx context.Context) (*youtube.StreamInfo, error)
+
+	mu     sync.Mutex
+	stream *youtube.StreamInfo
+}
+
+// newStreamProxy resolves the stream of the video at urlStr to serve.
+func newStreamProxy(ctx context.Context, w io.Writer, urlStr string, opts *proxyOptions, src *sources) (*streamProxy, error) {
+	query, err := youtube.ResolveQueryContext(ctx, urlStr, youtube.NewURLExpander(src.client))
+	if err != nil || query.Type != youtube.QueryTypeVideo {
+		return nil, fmt.Errorf("invalid video URL or ID: %w", youtube.ErrInvalidVideoID)
+	}
+
+	video, manifest, err := fetchVideo(ctx, w, query.VideoID, src)
+	if err != nil {
+		return nil, err
+	}
+	selection, err := proxySelection(w, manifest, opts)
+	if err != nil {
+		return nil, err
+	}
+	stream, name := selectionStream(selection)
+	_, _ = fmt.Fprintf(w, "Selected %s\n", selection.label())
+
+	return &streamProxy{
+		client: src.client,
+		name:   name,
+		title:  video.Title,
+		stream: stream,
+		resolve: func(ctx context.Context) (*youtube.StreamInfo, error) {
+			_, manifest, err := fetchVideo(ctx, io.Discard, query.VideoID, src)
+			if err != nil {
+				return nil, err
+			}
+			selection, err := proxySelection(io.Discard, manifest, opts)
+			if err != nil {
+				return nil, err
+			}
+			stream, _ := selectionStream(selection)
+			return stream, nil
+		},
+	}, nil
+}
+
+// proxySelection selects the single stream the proxy serves: the stream
+// matching the options, or the best stream with video and audio when they
+// are separate at the selected quality.
+func proxySelection(w io.Writer, manifest *youtube.StreamManifest, opts *proxyOptions) (*streamSelection, error) {
+	selection, err := selectStreams(manifest, &downloadOptions{quality: opts.quality, format: opts.format})
+	if err != nil {
+		return nil, err
+	}
+	if selection.needsMux() {
+		muxed := bestMuxedStream(manifest)
+		if muxed == nil {
+			return nil, errors.New("the video has no stream with both video and audio to serve")
+		}
+		selection = &streamSelection{quality: youtube.QualityLabel(muxed.Height), video: muxed}
+		_, _ = fmt.Fprintf(w, "Note: devices need a stream with both video and audio; serving %s instead\n", selection.quality)
+	}
+	selection.warnInaccessible(w)
+	return selection, nil
+}
+
+// selectionStream returns the stream of a single-stream selection and the
+// file name it's served as, such as "video.mp4" or "audio.webm".
+func selectionStream(selection *streamSelection) (*youtube.StreamInfo, string) {
+	if selection.video != nil {
+		return &selection.video.StreamInfo, "video." + string(selection.video.Container)
+	}
+	return &selection.audio.StreamInfo, "audio." + string(selection.audio.Container)
+}
+
+// handler returns the handler serving the stream.
+func (p *streamProxy) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /"+p.name, p)
+	return mux
+}
+
+// ServeHTTP serves a GET or HEAD request for the stream.
+func (p *streamProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	stream := p.stream
+	p.mu.Unlock()
+
+	resp, err := p.fetch(r, stream.URL)
+	if err == nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusGone) {
+		_ = resp.Body.Close()
+		if stream, err = p.refresh(r.Context(), stream); err == nil {
+			resp, err = p.fetch(r, stream.URL)
+		}
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= http.StatusBadRequest && resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		http.Error(w, "YouTube responded "+resp.Status, http.StatusBadGateway)
+		return
+	}
+
+	for _, name := range proxiedHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			w.Header().Set(name, value)
+		}
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType := streamMediaType(stream); mediaType != "" {
+		contentType = mediaType
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Accept-Ranges", "bytes")
+	// DLNA renderers ask how they may stream the file before playing it
+	if r.Header.Get("getcontentFeatures.dlna.org") == "1" || r.Header.Get("transferMode.dlna.org") != "" {
+		w.Header().Set("contentFeatures.dlna.org", dlnaContentFeatures)
+		w.Header().Set("transferMode.dlna.org", "Streaming")
+	}
+	w.WriteHeader(resp.StatusCode)
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, resp.Body)
+	}
+}
+
+// mediaType returns the MIME type of the stream without parameters, such as "video/mp4".
+func (p *streamProxy) mediaType() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return streamMediaType(p.stream)
+}
+
+// streamMediaType returns the MIME type of stream without parameters, or "" if
+// YouTube didn't report a valid one.
+func streamMediaType(stream *youtube.StreamInfo) string {
+	mediaType, _, err := mime.ParseMediaType(stream.MimeType)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// fetch requests the part of the stream at url that r asks for.
+func (p *streamProxy) fetch(r *http.Request, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"Range", "If-Range"} {
+		if value := r.Header.Get(name); value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+	return p.client.Do(req)
+}
+
+// refresh resolves the stream again unless another request already replaced
+// the stale one, and returns the current stream.
+func (p *streamProxy) refresh(ctx context.Context, stale *youtube.StreamInfo) (*youtube.StreamInfo, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stream != stale {
+		return p.stream, nil
+	}
+	stream, err := p.resolve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh the stream URL: %w", err)
+	}
+	p.stream = stream
+	return stream, nil
+}
+
+// proxyURLs returns the URLs of the file name served on addr. Listening on
+// all interfaces, it is reachable at localhost and every LAN address.
+func proxyURLs(addr net.Addr, name string) []string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return []string{"http://" + addr.String() + "/" + name}
+	}
+	port := strconv.Itoa(tcp.Port)
+	if !tcp.IP.IsUnspecified() {
+		return []string{"http://" + net.JoinHostPort(tcp.IP.String(), port) + "/" + name}
+	}
+	urls := []string{"http://" + net.JoinHostPort("localhost", port) + "/" + name}
+	for _, ip := range lanAddresses() {
+		urls = append(urls, "http://"+net.JoinHostPort(ip.String(), port)+"/"+name)
+	}
+	return urls
+}
+
+// lanAddresses returns the IPv4 addresses of this machine's network
+// interfaces that other devices on the LAN can reach.
+func lanAddresses() []net.IP {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		ips = append(ips, ipNet.IP)
+	}
+	return ips
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// newProxyTestServer returns a fake YouTube whose watch page offers a muxed
+// stream at /stream/N, where N counts the watch page fetches. Only the latest
+// stream serves the content, with range support; older ones are refused as
+// expired, and so is the first one when expireFirst is set.
+func newProxyTestServer(t *testing.T, content []byte, expireFirst bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var fetches atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/watch" {
+			n := fetches.Add(1)
+			response := `{"videoDetails":{"videoId":"dQw4w9WgXcQ","title":"Test Video","author":"Test Channel","lengthSeconds":"60"},` +
+				`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[` +
+				`{"itag":18,"url":"` + server.URL + `/stream/` + strconv.Itoa(int(n)) + `","mimeType":"video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"","height":360,"qualityLabel":"360p"}]}}`
+			_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = ` + response + `;</script>`))
+			return
+		}
+		latest := "/stream/" + strconv.Itoa(int(fetches.Load()))
+		if r.URL.Path != latest || (expireFirst && latest == "/stream/1") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server, &fetches
+}
+
+func newTestProxy(t *testing.T, youtubeServer *httptest.Server) *httptest.Server {
+	t.Helper()
+	src := newSources(&youtube.WatchPageFetcher{Client: youtubeServer.Client(), BaseURL: youtubeServer.URL})
+	proxy, err := newStreamProxy(context.Background(), io.Discard, "dQw4w9WgXcQ", &proxyOptions{quality: "best"}, src)
+	if err != nil {
+		t.Fatalf("newStreamProxy failed: %v", err)
+	}
+	if proxy.name != "video.mp4" {
+		t.Errorf("name = %q, want video.mp4", proxy.name)
+	}
+	server := httptest.NewServer(proxy.handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func getRange(t *testing.T, url, byteRange string, headers ...string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if byteRange != "" {
+		req.Header.Set("Range", "bytes="+byteRange)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestStreamProxy_PassesRangesThrough(t *testing.T) {
+	content := []byte("0123456789abcdef")
+	youtubeServer, _ := newProxyTestServer(t, content, false)
+	proxy := newTestProxy(t, youtubeServer)
+
+	resp, body := getRange(t, proxy.URL+"/video.mp4", "")
+	if resp.StatusCode != http.StatusOK || body != string(content) {
+		t.Errorf("GET = %d %q, want the whole stream", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "video/mp4" {
+		t.Errorf("Content-Type = %q, want video/mp4", got)
+	}
+	if got := resp.Header.Get("Accept-Ranges"); got != "bytes" {
+		t.Errorf("Accept-Ranges = %q, want bytes", got)
+	}
+
+	resp, body = getRange(t, proxy.URL+"/video.mp4", "10-")
+	if resp.StatusCode != http.StatusPartialContent || body != "abcdef" {
+		t.Errorf("ranged GET = %d %q, want 206 abcdef", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Range"); got != "bytes 10-15/16" {
+		t.Errorf("Content-Range = %q", got)
+	}
+	if got := resp.Header.Get("contentFeatures.dlna.org"); got != "" {
+		t.Errorf("contentFeatures.dlna.org = %q without a DLNA request", got)
+	}
+
+	resp, _ = getRange(t, proxy.URL+"/video.mp4", "", "getcontentFeatures.dlna.org", "1")
+	if got := resp.Header.Get("contentFeatures.dlna.org"); !strings.HasPrefix(got, "DLNA.ORG_OP=01") {
+		t.Errorf("contentFeatures.dlna.org = %q, want seekable", got)
+	}
+
+	if resp, _ := getRange(t, proxy.URL+"/other.mp4", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET of another path = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestStreamProxy_RefreshesExpiredURL(t *testing.T) {
+	youtubeServer, fetches := newProxyTestServer(t, []byte("content"), true)
+	proxy := newTestProxy(t, youtubeServer)
+
+	resp, body := getRange(t, proxy.URL+"/video.mp4", "0-3")
+	if resp.StatusCode != http.StatusPartialContent || body != "cont" {
+		t.Errorf("GET = %d %q, want the stream from the new URL", resp.StatusCode, body)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("watch page fetched %d times, want 2", got)
+	}
+
+	if resp, _ := getRange(t, proxy.URL+"/video.mp4", "0-3"); resp.StatusCode != http.StatusPartialContent {
+		t.Errorf("second GET = %d", resp.StatusCode)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("watch page fetched %d times after a second request, want 2", got)
+	}
+}
+
+func TestProxyURLs(t *testing.T) {
+	got := proxyURLs(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9000}, "video.mp4")
+	if len(got) != 1 || got[0] != "http://127.0.0.1:9000/video.mp4" {
+		t.Errorf("proxyURLs(127.0.0.1:9000) = %q", got)
+	}
+
+	got = proxyURLs(&net.TCPAddr{IP: net.IPv6unspecified, Port: 8766}, "video.mp4")
+	if len(got) == 0 || got[0] != "http://localhost:8766/video.mp4" {
+		t.Errorf("proxyURLs([::]:8766) = %q, want localhost first", got)
+	}
+}
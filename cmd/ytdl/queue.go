@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// queueRequestTimeout bounds each request to the API of ytdl serve.
+const queueRequestTimeout = 10 * time.Second
+
+func newQueueCmd() *cobra.Command {
+	var addr string
+
+	cmd := &cobra.Command{
+		Use:   "queue",
+		Short: "List and reorder the downloads queued in ytdl serve",
+		Long: `List and reorder the downloads queued in a running "ytdl serve".
+
+The next free worker always takes the queued download of the highest
+priority. "ytdl queue move" puts a download at a position of the queue,
+giving it the priority of its new neighbours; "ytdl queue priority" sets the
+priority and lets the download fall into place.`,
+		Example: `  ytdl queue list
+  ytdl queue move 7 1
+  ytdl queue priority 7 10 --addr localhost:9000`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			_ = cmd.Help()
+		},
+	}
+	cmd.PersistentFlags().StringVar(&addr, "addr", defaultServeAddr, "Address ytdl serve listens on")
+
+	api := func() *queueAPI {
+		return &queueAPI{base: queueAPIURL(addr), client: &http.Client{Timeout: queueRequestTimeout}}
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the downloads with their status and queue position",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := api().list(cmd.Context(), cmd.OutOrStdout()); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "move ID POSITION",
+		Short: "Move a queued download to a position of the queue (1 runs next)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			position, err := strconv.Atoi(args[1])
+			if err != nil || position < 1 {
+				return WrapError(fmt.Errorf("invalid position %q: must be a number of at least 1", args[1]))
+			}
+			if err := api().change(cmd.Context(), cmd.OutOrStdout(), args[0], jobChange{Position: &position}); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "priority ID PRIORITY",
+		Short: "Set the priority of a queued download (higher runs first)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			priority, err := strconv.Atoi(args[1])
+			if err != nil {
+				return WrapError(fmt.Errorf("invalid priority %q: must be a number", args[1]))
+			}
+			if err := api().change(cmd.Context(), cmd.OutOrStdout(), args[0], jobChange{Priority: &priority}); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	})
+	return cmd
+}
+
+// queueAPIURL returns the base URL of the API of ytdl serve listening on addr.
+// Addresses without a host, such as ":9000", are reached on the loopback.
+func queueAPIURL(addr string) string {
+	if strings.Contains(addr, "://") {
+		return strings.TrimSuffix(addr, "/")
+	}
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		addr = net.JoinHostPort("127.0.0.1", port)
+	}
+	return "http://" + addr
+}
+
+// queueAPI is a client of the download queue API of ytdl serve.
+type queueAPI struct {
+	base   string
+	client *http.Client
+}
+
+// list writes the downloads in the order they were queued.
+func (a *queueAPI) list(ctx context.Context, w io.Writer) error {
+	var views []jobView
+	if err := a.do(ctx, http.MethodGet, "/downloads", nil, &views); err != nil {
+		return err
+	}
+	if len(views) == 0 {
+		_, _ = fmt.Fprintln(w, "No downloads")
+		return nil
+	}
+	for _, v := range views {
+		position := "-"
+		if v.Position > 0 {
+			position = strconv.Itoa(v.Position)
+		}
+		name := v.Title
+		if name == "" {
+			name = v.Request.URL
+		}
+		_, _ = fmt.Fprintf(w, "%-4s %-11s %4s  priority %-3d %s\n", v.ID, v.Status, position, v.Priority, displayText(name))
+	}
+	return nil
+}
+
+// change applies change to the queued download id and reports where it is now.
+func (a *queueAPI) change(ctx context.Context, w io.Writer, id string, change jobChange) error {
+	var view jobView
+	if err := a.do(ctx, http.MethodPatch, "/downloads/"+id, change, &view); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "Download %s is at position %d with priority %d\n", view.ID, view.Position, view.Priority)
+	return nil
+}
+
+// do sends a request with body as JSON and decodes the response into out,
+// returning the API's error message for unsuccessful responses.
+func (a *queueAPI) do(ctx context.Context, method, path string, body, out any) error {
+	var reqBody io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.base+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach ytdl serve at %s: %w", a.base, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error == "" {
+			return fmt.Errorf("ytdl serve responded %s", resp.Status)
+		}
+		return errors.New(apiErr.Error)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response from ytdl serve: %w", err)
+	}
+	return nil
+}
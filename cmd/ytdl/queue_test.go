@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestQueueAPIURL(t *testing.T) {
+	tests := map[string]string{
+		"127.0.0.1:8080":          "http://127.0.0.1:8080",
+		":9000":                   "http://127.0.0.1:9000",
+		"localhost:9000":          "http://localhost:9000",
+		"https://ytdl.example/":   "https://ytdl.example",
+		"http://10.0.0.2:8080/v1": "http://10.0.0.2:8080/v1",
+	}
+	for addr, want := range tests {
+		if got := queueAPIURL(addr); got != want {
+			t.Errorf("queueAPIURL(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
+
+func TestQueueAPI(t *testing.T) {
+	server, _ := newServeTestQueue(t, 1)
+	api := &queueAPI{base: server.URL, client: server.Client()}
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	if err := api.list(ctx, &buf); err != nil || buf.String() != "No downloads\n" {
+		t.Fatalf("list() = %q, %v", buf.String(), err)
+	}
+
+	_, running := postDownload(t, server, `{"url": "slowAAAAAAA"}`)
+	waitForStatus(t, server, running.ID, jobDownloading)
+	postDownload(t, server, `{"url": "slowBBBBBBB", "priority": 3}`)
+	_, moved := postDownload(t, server, `{"url": "slowCCCCCCC"}`)
+
+	buf.Reset()
+	position := 1
+	if err := api.change(ctx, &buf, moved.ID, jobChange{Position: &position}); err != nil {
+		t.Fatalf("change() failed: %v", err)
+	}
+	if want := "Download 3 is at position 1 with priority 3\n"; buf.String() != want {
+		t.Errorf("change() wrote %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := api.list(ctx, &buf); err != nil {
+		t.Fatalf("list() failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "downloading") || !strings.Contains(lines[2], "   1  priority 3") {
+		t.Errorf("list() wrote:\n%s", buf.String())
+	}
+
+	if err := api.change(ctx, &buf, running.ID, jobChange{Position: &position}); err == nil || !strings.Contains(err.Error(), "not queued") {
+		t.Errorf("change() of a running download = %v, want the API error", err)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+)
+
+// downloadReport collects the result of every video downloaded by a playlist,
+// mix or batch download, for the summary printed when it finishes.
+type downloadReport struct {
+	started time.Time
+	results []download.DownloadResult
+}
+
+// add records the result of one video.
+func (r *downloadReport) add(result download.DownloadResult) {
+	r.results = append(r.results, result)
+}
+
+// withReport returns options that record into a report. If opts already has one,
+// because the download is part of a larger batch, it is shared and owned is false;
+// otherwise a new report is started and the caller is responsible for finishing it.
+func withReport(opts *downloadOptions) (reportOpts *downloadOptions, owned bool) {
+	if opts.report != nil {
+		return opts, false
+	}
+	copied := *opts
+	copied.report = &downloadReport{started: time.Now()}
+	return &copied, true
+}
+
+// finishReport prints the summary of a report and, with --report-json, writes it as JSON.
+func finishReport(w io.Writer, report *downloadReport, jsonPath string) error {
+	summary := download.NewReport(report.results, time.Since(report.started))
+	printReport(w, summary)
+
+	if jsonPath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := os.WriteFile(jsonPath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	_, _ = fmt.Fprintf(w, "Report written to %s\n", jsonPath)
+	return nil
+}
+
+// printReport prints the counts, transfer totals and failures of a report.
+func printReport(w io.Writer, r *download.Report) {
+	_, _ = fmt.Fprintf(w, "\nSummary: %d succeeded, %d failed, %d skipped\n", r.Succeeded, r.Failed, r.Skipped)
+	_, _ = fmt.Fprintf(w, "Downloaded %s in %s", download.FormatBytes(r.TotalBytes), r.Elapsed.Round(time.Second))
+	if r.AverageSpeed > 0 {
+		_, _ = fmt.Fprintf(w, " (average %s)", download.FormatSpeed(r.AverageSpeed))
+	}
+	_, _ = fmt.Fprintln(w)
+
+	if r.Failed == 0 {
+		return
+	}
+	_, _ = fmt.Fprintln(w, "Failed:")
+	for _, item := range r.Items {
+		if item.Status == download.StatusFailed {
+			_, _ = fmt.Fprintf(w, "  %s: %s\n", item.Title, item.Error)
+		}
+	}
+}
+
+// fileSize returns the size of the file at path, or 0 if it can't be read.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
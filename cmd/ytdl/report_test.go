@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+)
+
+func TestWithReport(t *testing.T) {
+	opts := &downloadOptions{output: "out"}
+	reportOpts, owned := withReport(opts)
+	if !owned || reportOpts.report == nil || reportOpts.output != "out" {
+		t.Fatalf("expected a new report on a copy of the options, got %+v (owned %v)", reportOpts, owned)
+	}
+	if opts.report != nil {
+		t.Error("withReport should not modify the caller's options")
+	}
+
+	// A download inside a batch shares the batch's report
+	nested, owned := withReport(reportOpts)
+	if owned || nested.report != reportOpts.report {
+		t.Error("expected the existing report to be shared")
+	}
+}
+
+func TestFinishReport(t *testing.T) {
+	report := &downloadReport{started: time.Now()}
+	report.add(download.DownloadResult{Title: "First", FilePath: "first.mp4", Size: 2048})
+	report.add(download.DownloadResult{Title: "Second", Error: errors.New("video unavailable")})
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	buf := new(bytes.Buffer)
+	if err := finishReport(buf, report, path); err != nil {
+		t.Fatalf("finishReport failed: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"Summary: 1 succeeded, 1 failed, 0 skipped", "Downloaded 2.0 KiB", "Failed:\n  Second: video unavailable", "Report written to " + path} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output should contain %q, got:\n%s", want, output)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading report: %v", err)
+	}
+	var decoded struct {
+		Succeeded int `json:"succeeded"`
+		Failed    int `json:"failed"`
+		Items     []struct {
+			Error string `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("invalid report JSON: %v", err)
+	}
+	if decoded.Succeeded != 1 || decoded.Failed != 1 || len(decoded.Items) != 2 || decoded.Items[1].Error != "video unavailable" {
+		t.Errorf("unexpected report JSON:\n%s", data)
+	}
+}
@@ -5,21 +5,56 @@ import (
 )
 
 func newRootCmd() *cobra.Command {
+	var closeLog func() error
+
 	cmd := &cobra.Command{
 		Use:   "ytdl",
 		Short: "YouTube downloader CLI",
 		Long: `ytdl - A CLI tool for downloading YouTube videos, playlists, and channel content.
 
 This is a Go port of YoutubeDownloader (https://github.com/Tyrrrz/YoutubeDownloader).
-It supports downloading videos in various formats and qualities.`,
+It supports downloading videos in various formats and qualities.
+
+` + exitCodesHelp,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			var err error
+			closeLog, err = setupLogging(cmd)
+			return err
+		},
+		PersistentPostRunE: func(*cobra.Command, []string) error {
+			if closeLog == nil {
+				return nil
+			}
+			return closeLog()
+		},
 		Run: func(cmd *cobra.Command, _ []string) {
 			_ = cmd.Help()
 		},
 	}
 
+	addNetworkFlags(cmd)
+	addLoggingFlags(cmd)
+	addConsoleFlags(cmd)
+	addCacheFlags(cmd)
+	addPOTokenFlags(cmd)
+
 	cmd.AddCommand(newVersionCmd())
 	cmd.AddCommand(newDownloadCmd())
 	cmd.AddCommand(newInfoCmd())
+	cmd.AddCommand(newPlaylistCmd())
+	cmd.AddCommand(newChannelCmd())
+	cmd.AddCommand(newCommentsCmd())
+	cmd.AddCommand(newTUICmd())
+	cmd.AddCommand(newServeCmd())
+	cmd.AddCommand(newQueueCmd())
+	cmd.AddCommand(newListenCmd())
+	cmd.AddCommand(newOpenCmd())
+	cmd.AddCommand(newProxyCmd())
+	cmd.AddCommand(newCastCmd())
+	cmd.AddCommand(newSyncCmd())
+	cmd.AddCommand(newArchiveCmd())
+	cmd.AddCommand(newFFmpegCmd())
+	cmd.AddCommand(newDoctorCmd())
 
 	return cmd
 }
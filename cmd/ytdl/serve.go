@@ -0,0 +1,688 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/metrics"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+const (
+	// defaultServeAddr is the address the serve command listens on without --addr.
+	// Only local clients can reach it, since the API has no authentication.
+	defaultServeAddr = "127.0.0.1:8080"
+
+	// jobEventInterval is the minimum interval between progress events of a job.
+	jobEventInterval = 500 * time.Millisecond
+
+	// serveShutdownTimeout is how long open connections get to finish on shutdown.
+	serveShutdownTimeout = 5 * time.Second
+)
+
+// serveOptions holds the flags of the serve command.
+type serveOptions struct {
+	addr    string
+	output  string
+	jobs    int
+	metrics bool
+
+	// watch is a directory whose trigger files queue the URLs they list.
+	watch string
+	// watchArchive is where finished trigger files are moved; they're removed without it.
+	watchArchive  string
+	watchInterval time.Duration
+
+	autoTune autoTuneOptions
+
+	// allowedOrigins enables the browser companion API of the listen command
+	// for these origins (see companionHandler).
+	allowedOrigins []string
+}
+
+func newServeCmd() *cobra.Command {
+	opts := &serveOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run an HTTP API for queueing downloads",
+		Long: `Run an HTTP server that downloads videos queued through a JSON API.
+
+Endpoints:
+  POST   /downloads        Queue a download. The body is a JSON object with "url"
+                           and optionally "quality" (as for download --quality),
+                           "format" (mp4, webm or mkv), "audio_only" and
+                           "priority" (higher runs first, 0 by default).
+  GET    /downloads        List all downloads.
+  GET    /downloads/{id}   Get a download's status and progress. With
+                           "Accept: text/event-stream" the status is streamed as
+                           Server-Sent Events until the download finishes.
+  PATCH  /downloads/{id}   Reorder a queued download. The body is a JSON object
+                           with "priority", "position" (1 runs next) or both.
+  DELETE /downloads/{id}   Cancel a queued or running download, or remove a
+                           finished one from the list.
+  GET    /metrics          Download counters and durations in the Prometheus
+                           text format, with --metrics.
+
+The next free worker always takes the queued download of the highest
+priority, and of those the one queued first. Moving a download gives it the
+priority of its new neighbours. "ytdl queue" lists and reorders the queue.
+
+With --watch, files dropped into a directory queue the URLs they list, one
+per line as in a --batch-file, or the URL of an Internet shortcut (.url).
+Once all of a file's downloads are done it's removed, or moved to the
+--watch-archive directory; if any fails, ".failed" is appended to its name.
+Hidden files and files still being written (.tmp, .part, .crdownload) are
+skipped.
+
+With --auto-tune, the throughput of the downloads is measured every
+--auto-tune-interval. Each time, either the number of downloads run at once
+or the number of chunks requested at once from each stream tries one more or
+one less, in turn, and keeps it only if the throughput rises, within
+--min-jobs and --max-jobs and --min-chunks and --max-chunks. The chunks of a stream are held in memory until written, up to
+--max-chunks of about 10 MB each per download.
+
+Files are saved to the --output directory; clients can't choose other paths.
+The API has no authentication, so only expose it on trusted networks.`,
+		Example: `  ytdl serve
+  ytdl serve --addr :9000 -o ~/Videos --jobs 3
+  curl -d '{"url": "https://youtu.be/dQw4w9WgXcQ"}' localhost:8080/downloads
+  curl -H 'Accept: text/event-stream' localhost:8080/downloads/1
+  ytdl serve --watch ~/Downloads/ytdl-links --watch-archive ~/Downloads/ytdl-links/done
+  ytdl serve --auto-tune --max-jobs 6 --max-chunks 3`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// Flag errors are shown as they are, not matched against download errors
+			if err := validateServe(opts); err != nil {
+				return err
+			}
+			if err := runServe(cmd, opts); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.addr, "addr", defaultServeAddr, "Address to listen on")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", ".", "Output directory for downloaded files")
+	cmd.Flags().IntVar(&opts.jobs, "jobs", 1, "Number of downloads to run at the same time")
+	cmd.Flags().BoolVar(&opts.metrics, "metrics", false, "Serve Prometheus metrics of the downloads at /metrics")
+	cmd.Flags().StringVar(&opts.watch, "watch", "", "Queue the URLs of files dropped into this directory")
+	cmd.Flags().StringVar(&opts.watchArchive, "watch-archive", "", "Move finished --watch files to this directory instead of removing them")
+	cmd.Flags().DurationVar(&opts.watchInterval, "watch-interval", defaultWatchInterval, "How often to look for new --watch files")
+	addAutoTuneFlags(cmd, &opts.autoTune)
+
+	return cmd
+}
+
+// validateServe checks the flags of the serve command.
+func validateServe(opts *serveOptions) error {
+	if opts.jobs < 1 {
+		return errors.New("--jobs must be at least 1")
+	}
+	if opts.watch == "" && opts.watchArchive != "" {
+		return errors.New("--watch-archive requires --watch")
+	}
+	if opts.watchInterval <= 0 {
+		return errors.New("--watch-interval must be positive")
+	}
+	if opts.watch != "" {
+		if info, err := os.Stat(opts.watch); err != nil || !info.IsDir() {
+			return fmt.Errorf("--watch must be an existing directory: %s", opts.watch)
+		}
+	}
+	return opts.autoTune.validate()
+}
+
+// runServe serves the API until the command is interrupted.
+func runServe(cmd *cobra.Command, opts *serveOptions) error {
+	var m *metrics.Metrics
+	if opts.metrics {
+		m = metrics.New()
+	}
+	tuner := newAutoTuner(&opts.autoTune)
+	workers := opts.jobs
+	var clientOpts []ytdl.ClientOption
+	if tuner != nil {
+		workers = tuner.jobs.Concurrency()
+		clientOpts = append(clientOpts, ytdl.WithChunkTuner(tuner.chunks))
+	}
+	_, client, err := newDaemonClients(cmd, m, clientOpts...)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	queue := newJobQueue(ctx, client, opts.output, workers)
+	if tuner != nil {
+		go tuner.run(ctx, queue, statusWriter(cmd))
+	}
+	handler := queue.handler()
+	if m != nil {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", m)
+		mux.Handle("/", handler)
+		handler = mux
+	}
+	if opts.allowedOrigins != nil {
+		handler = companionHandler(handler, opts.allowedOrigins)
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	errc := make(chan error, 1)
+	go func() { errc <- server.Serve(listener) }()
+	_, _ = fmt.Fprintf(statusWriter(cmd), "Listening on http://%s\n", listener.Addr())
+	if opts.watch != "" {
+		_, _ = fmt.Fprintf(statusWriter(cmd), "Watching %s for URLs\n", opts.watch)
+		watcher := newFolderWatcher(opts.watch, opts.watchArchive, queue, opts.watchInterval, statusWriter(cmd))
+		go watcher.run(ctx)
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// jobStatus is the state of a queued download.
+type jobStatus string
+
+const (
+	jobQueued      jobStatus = "queued"
+	jobDownloading jobStatus = "downloading"
+	jobDone        jobStatus = "done"
+	jobFailed      jobStatus = "failed"
+	jobCanceled    jobStatus = "canceled"
+)
+
+// finished reports whether the job will not change anymore.
+func (s jobStatus) finished() bool {
+	return s == jobDone || s == jobFailed || s == jobCanceled
+}
+
+// downloadRequest is the body of POST /downloads.
+type downloadRequest struct {
+	URL       string `json:"url"`
+	Quality   string `json:"quality,omitempty"`
+	Format    string `json:"format,omitempty"`
+	AudioOnly bool   `json:"audio_only,omitempty"`
+	Priority  int    `json:"priority,omitempty"`
+}
+
+// jobChange is the body of PATCH /downloads/{id}.
+type jobChange struct {
+	Priority *int `json:"priority,omitempty"`
+	Position *int `json:"position,omitempty"`
+}
+
+// jobProgress is the progress of a job in API responses.
+type jobProgress struct {
+	Downloaded int64   `json:"downloaded"`
+	Total      int64   `json:"total,omitempty"`
+	Percent    float64 `json:"percent,omitempty"`
+	Speed      float64 `json:"speed"`
+}
+
+// jobView is a job as returned by the API.
+type jobView struct {
+	ID       string          `json:"id"`
+	Request  downloadRequest `json:"request"`
+	Status   jobStatus       `json:"status"`
+	Priority int             `json:"priority"`
+	Position int             `json:"position,omitempty"`
+	Title    string          `json:"title,omitempty"`
+	FilePath string          `json:"file_path,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Progress *jobProgress    `json:"progress,omitempty"`
+	Created  time.Time       `json:"created"`
+}
+
+// job is a download in the queue. Its fields are guarded by the queue's mutex.
+type job struct {
+	view   jobView
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// changed is closed and replaced whenever the job changes, waking event streams.
+	changed   chan struct{}
+	lastEvent time.Time
+}
+
+// jobQueue runs queued downloads with a limited number at a time, highest
+// priority first.
+type jobQueue struct {
+	ctx    context.Context
+	client *ytdl.Client
+	output string
+
+	mu      sync.Mutex
+	workers int
+	jobs    map[string]*job
+	order   []string
+	pending []*job // Queued jobs in the order they run, by descending priority
+	running int
+	nextID  int
+
+	// transferred is the number of bytes the jobs have downloaded, for throughputProbe.
+	transferred int64
+}
+
+// newJobQueue creates a queue that runs up to workers downloads to output at a
+// time until ctx is canceled.
+func newJobQueue(ctx context.Context, client *ytdl.Client, output string, workers int) *jobQueue {
+	return &jobQueue{
+		ctx:     ctx,
+		client:  client,
+		output:  output,
+		workers: workers,
+		jobs:    make(map[string]*job),
+	}
+}
+
+// handler returns the HTTP API of the queue.
+func (q *jobQueue) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /downloads", q.handleCreate)
+	mux.HandleFunc("GET /downloads", q.handleList)
+	mux.HandleFunc("GET /downloads/{id}", q.handleGet)
+	mux.HandleFunc("PATCH /downloads/{id}", q.handlePatch)
+	mux.HandleFunc("DELETE /downloads/{id}", q.handleDelete)
+	return mux
+}
+
+func (q *jobQueue) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req downloadRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if strings.TrimSpace(req.URL) == "" {
+		writeAPIError(w, http.StatusBadRequest, errors.New("url is required"))
+		return
+	}
+	switch strings.ToLower(req.Format) {
+	case "", "mp4", "webm", "mkv":
+	default:
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("unsupported format %q (use mp4, webm or mkv)", req.Format))
+		return
+	}
+
+	view := q.enqueue(req)
+	w.Header().Set("Location", "/downloads/"+view.ID)
+	writeJSON(w, http.StatusCreated, view)
+}
+
+func (q *jobQueue) handleList(w http.ResponseWriter, _ *http.Request) {
+	q.mu.Lock()
+	views := make([]jobView, 0, len(q.order))
+	for _, id := range q.order {
+		views = append(views, q.viewLocked(q.jobs[id]))
+	}
+	q.mu.Unlock()
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (q *jobQueue) handleGet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	view, changed, ok := q.snapshot(id)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("download %s not found", id))
+		return
+	}
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		writeJSON(w, http.StatusOK, view)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		data, err := json.Marshal(view)
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", view.Status, data); err != nil {
+			return
+		}
+		flusher.Flush()
+		if view.Status.finished() {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+		if view, changed, ok = q.snapshot(id); !ok {
+			return
+		}
+	}
+}
+
+func (q *jobQueue) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	q.mu.Lock()
+	j, ok := q.jobs[id]
+	if !ok {
+		q.mu.Unlock()
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("download %s not found", id))
+		return
+	}
+	if j.view.Status.finished() {
+		delete(q.jobs, id)
+		q.order = slices.DeleteFunc(q.order, func(other string) bool { return other == id })
+		q.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	q.removePendingLocked(j)
+	j.cancel()
+	q.updateLocked(j, func(v *jobView) { v.Status = jobCanceled })
+	view := j.view
+	q.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (q *jobQueue) handlePatch(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var change jobChange
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&change); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	switch {
+	case change.Priority == nil && change.Position == nil:
+		writeAPIError(w, http.StatusBadRequest, errors.New("priority or position is required"))
+		return
+	case change.Position != nil && *change.Position < 1:
+		writeAPIError(w, http.StatusBadRequest, errors.New("position must be at least 1"))
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("download %s not found", id))
+		return
+	}
+	if j.view.Status != jobQueued {
+		writeAPIError(w, http.StatusConflict, fmt.Errorf("download %s is %s, not queued", id, j.view.Status))
+		return
+	}
+	if change.Priority != nil {
+		q.removePendingLocked(j)
+		j.view.Priority = *change.Priority
+		q.insertPendingLocked(j)
+	}
+	if change.Position != nil {
+		q.movePendingLocked(j, *change.Position)
+	}
+	q.notifyLocked(j)
+	writeJSON(w, http.StatusOK, q.viewLocked(j))
+}
+
+// enqueue adds a download to the queue and starts it once a worker is free.
+func (q *jobQueue) enqueue(req downloadRequest) jobView {
+	ctx, cancel := context.WithCancel(q.ctx)
+
+	q.mu.Lock()
+	q.nextID++
+	id := strconv.Itoa(q.nextID)
+	j := &job{
+		view:    jobView{ID: id, Request: req, Status: jobQueued, Priority: req.Priority, Created: time.Now().UTC()},
+		ctx:     ctx,
+		cancel:  cancel,
+		changed: make(chan struct{}),
+	}
+	q.jobs[id] = j
+	q.order = append(q.order, id)
+	q.insertPendingLocked(j)
+	q.scheduleLocked()
+	view := q.viewLocked(j)
+	q.mu.Unlock()
+
+	// A job canceled before it starts, or on shutdown, leaves the queue
+	context.AfterFunc(ctx, func() { q.dequeue(j) })
+	return view
+}
+
+// scheduleLocked starts the first pending jobs while workers are free; the
+// caller must hold q.mu.
+func (q *jobQueue) scheduleLocked() {
+	for q.running < q.workers && len(q.pending) > 0 {
+		j := q.pending[0]
+		q.pending = q.pending[1:]
+		q.running++
+		q.updateLocked(j, func(v *jobView) { v.Status = jobDownloading })
+		go q.run(j)
+	}
+}
+
+// setWorkers changes how many downloads run at a time. Downloads running
+// beyond the new number are left to finish.
+func (q *jobQueue) setWorkers(workers int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.workers = workers
+	q.scheduleLocked()
+}
+
+// throughputProbe returns a probe of the bytes the jobs downloaded since it
+// was last called. It fails while no job runs, so idle time isn't measured.
+func (q *jobQueue) throughputProbe() download.ThroughputProbe {
+	var last int64
+	since := time.Now()
+	return func(context.Context) (int64, time.Duration, error) {
+		q.mu.Lock()
+		transferred, running := q.transferred, q.running
+		q.mu.Unlock()
+
+		now := time.Now()
+		n, elapsed := transferred-last, now.Sub(since)
+		last, since = transferred, now
+		if running == 0 {
+			return 0, 0, errNothingToMeasure
+		}
+		return n, elapsed, nil
+	}
+}
+
+// dequeue removes a job that is still pending from the queue as canceled.
+func (q *jobQueue) dequeue(j *job) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.removePendingLocked(j) {
+		q.updateLocked(j, func(v *jobView) { v.Status = jobCanceled })
+	}
+}
+
+// insertPendingLocked queues j after the pending jobs of the same or higher priority.
+func (q *jobQueue) insertPendingLocked(j *job) {
+	i := slices.IndexFunc(q.pending, func(other *job) bool { return other.view.Priority < j.view.Priority })
+	if i < 0 {
+		i = len(q.pending)
+	}
+	q.pending = slices.Insert(q.pending, i, j)
+}
+
+// movePendingLocked moves j to the 1-based position of the pending jobs,
+// bringing its priority within those of its new neighbours so the order holds.
+func (q *jobQueue) movePendingLocked(j *job, position int) {
+	q.removePendingLocked(j)
+	i := min(position-1, len(q.pending))
+	if i > 0 {
+		j.view.Priority = min(j.view.Priority, q.pending[i-1].view.Priority)
+	}
+	if i < len(q.pending) {
+		j.view.Priority = max(j.view.Priority, q.pending[i].view.Priority)
+	}
+	q.pending = slices.Insert(q.pending, i, j)
+}
+
+// removePendingLocked removes j from the pending jobs, reporting whether it was there.
+func (q *jobQueue) removePendingLocked(j *job) bool {
+	i := slices.Index(q.pending, j)
+	if i < 0 {
+		return false
+	}
+	q.pending = slices.Delete(q.pending, i, i+1)
+	return true
+}
+
+// run downloads a job started by scheduleLocked, then starts the next one.
+func (q *jobQueue) run(j *job) {
+	ctx := j.ctx
+	defer func() {
+		j.cancel()
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.running--
+		q.scheduleLocked()
+	}()
+
+	req := j.view.Request
+	video, err := q.client.GetVideo(ctx, req.URL)
+	if err != nil {
+		q.finish(j, nil, err)
+		return
+	}
+	q.update(j, func(v *jobView) { v.Title = video.Title })
+
+	opts := []ytdl.Option{
+		ytdl.WithOutputDir(q.output),
+		ytdl.WithQuality(parseQualityPreference(req.Quality)),
+		ytdl.WithContainer(parseContainer(req.Format)),
+		ytdl.WithOverwritePolicy(download.RenameExisting),
+		ytdl.WithProgress(func(p download.Progress) { q.progress(j, p) }),
+	}
+	if req.AudioOnly {
+		opts = append(opts, ytdl.WithAudioOnly())
+	}
+	result, err := q.client.DownloadVideo(ctx, video, opts...)
+	q.finish(j, result, err)
+}
+
+// progress records a job's progress, notifying event streams at most every
+// jobEventInterval.
+func (q *jobQueue) progress(j *job, p download.Progress) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// Progress starts over with each stream of a video
+	q.transferred += p.Downloaded
+	if prev := j.view.Progress; prev != nil && prev.Downloaded <= p.Downloaded {
+		q.transferred -= prev.Downloaded
+	}
+	j.view.Progress = &jobProgress{Downloaded: p.Downloaded, Total: p.Total, Percent: p.Percentage(), Speed: p.Speed}
+	if now := time.Now(); now.Sub(j.lastEvent) >= jobEventInterval {
+		j.lastEvent = now
+		q.notifyLocked(j)
+	}
+}
+
+// finish records the outcome of a job. A job canceled through the API stays canceled.
+func (q *jobQueue) finish(j *job, result *ytdl.Result, err error) {
+	q.update(j, func(v *jobView) {
+		switch {
+		case v.Status == jobCanceled:
+		case err != nil && errors.Is(err, context.Canceled):
+			v.Status = jobCanceled
+		case err != nil:
+			v.Status = jobFailed
+			v.Error = WrapError(err).Error()
+		default:
+			v.Status = jobDone
+			v.FilePath = result.FilePath
+		}
+	})
+}
+
+// update changes a job and notifies its event streams.
+func (q *jobQueue) update(j *job, change func(*jobView)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.updateLocked(j, change)
+}
+
+// updateLocked is update for callers holding q.mu.
+func (q *jobQueue) updateLocked(j *job, change func(*jobView)) {
+	change(&j.view)
+	q.notifyLocked(j)
+}
+
+// notifyLocked wakes the event streams of a job; the caller must hold q.mu.
+func (q *jobQueue) notifyLocked(j *job) {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// snapshot returns a copy of a job and the channel closed on its next change.
+func (q *jobQueue) snapshot(id string) (jobView, <-chan struct{}, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return jobView{}, nil, false
+	}
+	return q.viewLocked(j), j.changed, true
+}
+
+// viewLocked returns a copy of a job with its position in the queue filled in;
+// the caller must hold q.mu.
+func (q *jobQueue) viewLocked(j *job) jobView {
+	// Progress is replaced rather than modified, so the copy can share it
+	view := j.view
+	if view.Status == jobQueued {
+		view.Position = slices.Index(q.pending, j) + 1
+	}
+	return view
+}
+
+// writeJSON writes v as the JSON response body with the given status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError writes an error response in the form {"error": "..."}.
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
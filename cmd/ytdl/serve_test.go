@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+// newServeTestQueue returns the API of a job queue downloading from a fake YouTube
+// whose only stream is served at /stream. Streams of videos with IDs starting with
+// "slow" never finish.
+func newServeTestQueue(t *testing.T, workers int) (*httptest.Server, string) {
+	t.Helper()
+	queue, output := newTestJobQueue(t, workers)
+	api := httptest.NewServer(queue.handler())
+	t.Cleanup(api.Close)
+	return api, output
+}
+
+// newTestJobQueue returns the job queue behind newServeTestQueue and its output directory.
+func newTestJobQueue(t *testing.T, workers int) (*jobQueue, string) {
+	t.Helper()
+	// The output is created first so that it's removed after the downloads stop
+	output := t.TempDir()
+	var youtubeServer *httptest.Server
+	youtubeServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/watch":
+			id := r.URL.Query().Get("v")
+			playerResponse := `{
+				"videoDetails": {"videoId": "` + id + `", "title": "Video ` + id + `", "author": "Test Channel", "lengthSeconds": "60"},
+				"playabilityStatus": {"status": "OK"},
+				"streamingData": {"formats": [
+					{"itag": 18, "url": "` + youtubeServer.URL + `/stream/` + id + `", "mimeType": "video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"", "height": 360, "qualityLabel": "360p"}
+				]}
+			}`
+			_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = ` + playerResponse + `;</script>`))
+		default:
+			if strings.HasPrefix(r.URL.Path, "/stream/slow") {
+				w.Header().Set("Content-Length", "100")
+				_, _ = w.Write([]byte("x"))
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+				return
+			}
+			_, _ = w.Write([]byte("video data"))
+		}
+	}))
+	t.Cleanup(youtubeServer.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := ytdl.NewClient(ytdl.WithHTTPClient(youtubeServer.Client()), ytdl.WithBaseURL(youtubeServer.URL))
+	queue := newJobQueue(ctx, client, output, workers)
+	t.Cleanup(func() {
+		cancel()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			queue.mu.Lock()
+			running := queue.running
+			queue.mu.Unlock()
+			if running == 0 {
+				return
+			}
+		}
+		t.Error("downloads still running after the queue was stopped")
+	})
+	return queue, output
+}
+
+func postDownload(t *testing.T, api *httptest.Server, body string) (int, jobView) {
+	t.Helper()
+	resp, err := http.Post(api.URL+"/downloads", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var view jobView
+	_ = json.NewDecoder(resp.Body).Decode(&view)
+	return resp.StatusCode, view
+}
+
+func getDownload(t *testing.T, api *httptest.Server, id string) jobView {
+	t.Helper()
+	resp, err := http.Get(api.URL + "/downloads/" + id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var view jobView
+	if err := json.NewDecoder(resp.Body).Decode(&view); err != nil {
+		t.Fatal(err)
+	}
+	return view
+}
+
+// waitForStatus polls a download until it has the wanted status.
+func waitForStatus(t *testing.T, api *httptest.Server, id string, want jobStatus) jobView {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		view := getDownload(t, api, id)
+		if view.Status == want {
+			return view
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("download %s is %s, want %s (%s)", id, view.Status, want, view.Error)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func deleteDownload(t *testing.T, api *httptest.Server, id string) int {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodDelete, api.URL+"/downloads/"+id, http.NoBody)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestServe_Download(t *testing.T) {
+	api, output := newServeTestQueue(t, 1)
+
+	status, view := postDownload(t, api, `{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ"}`)
+	if status != http.StatusCreated || view.ID != "1" {
+		t.Fatalf("POST /downloads = %d %+v", status, view)
+	}
+
+	view = waitForStatus(t, api, view.ID, jobDone)
+	if view.Title != "Video dQw4w9WgXcQ" || view.FilePath != filepath.Join(output, "Video dQw4w9WgXcQ.mp4") {
+		t.Errorf("finished download = %+v", view)
+	}
+	if data, err := os.ReadFile(view.FilePath); err != nil || string(data) != "video data" {
+		t.Errorf("downloaded %q, %v", data, err)
+	}
+
+	resp, err := http.Get(api.URL + "/downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var views []jobView
+	_ = json.NewDecoder(resp.Body).Decode(&views)
+	_ = resp.Body.Close()
+	if len(views) != 1 || views[0].ID != "1" {
+		t.Errorf("GET /downloads = %+v", views)
+	}
+
+	if status := deleteDownload(t, api, "1"); status != http.StatusNoContent {
+		t.Errorf("DELETE finished download = %d, want %d", status, http.StatusNoContent)
+	}
+	if status := deleteDownload(t, api, "1"); status != http.StatusNotFound {
+		t.Errorf("DELETE removed download = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestServe_InvalidRequests(t *testing.T) {
+	api, _ := newServeTestQueue(t, 1)
+
+	for _, body := range []string{`not json`, `{}`, `{"url": "dQw4w9WgXcQ", "format": "avi"}`} {
+		if status, _ := postDownload(t, api, body); status != http.StatusBadRequest {
+			t.Errorf("POST %s = %d, want %d", body, status, http.StatusBadRequest)
+		}
+	}
+
+	resp, err := http.Get(api.URL + "/downloads/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET unknown download = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestServe_FailedDownload(t *testing.T) {
+	api, _ := newServeTestQueue(t, 1)
+
+	_, view := postDownload(t, api, `{"url": "https://example.com/not-youtube"}`)
+	view = waitForStatus(t, api, view.ID, jobFailed)
+	if view.Error == "" {
+		t.Error("expected an error message")
+	}
+}
+
+func TestServe_Cancel(t *testing.T) {
+	api, _ := newServeTestQueue(t, 1)
+
+	_, running := postDownload(t, api, `{"url": "slowAAAAAAA"}`)
+	_, queued := postDownload(t, api, `{"url": "dQw4w9WgXcQ"}`)
+	waitForStatus(t, api, running.ID, jobDownloading)
+
+	if status := deleteDownload(t, api, queued.ID); status != http.StatusOK {
+		t.Errorf("DELETE queued download = %d", status)
+	}
+	waitForStatus(t, api, queued.ID, jobCanceled)
+
+	if status := deleteDownload(t, api, running.ID); status != http.StatusOK {
+		t.Errorf("DELETE running download = %d", status)
+	}
+	waitForStatus(t, api, running.ID, jobCanceled)
+}
+
+func TestServe_Events(t *testing.T) {
+	api, _ := newServeTestQueue(t, 1)
+	_, view := postDownload(t, api, `{"url": "dQw4w9WgXcQ"}`)
+
+	req, _ := http.NewRequest(http.MethodGet, api.URL+"/downloads/"+view.ID, http.NoBody)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	// The stream ends after the event for the finished download
+	var events []string
+	var last jobView
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if event, ok := strings.CutPrefix(line, "event: "); ok {
+			events = append(events, event)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			if err := json.Unmarshal([]byte(data), &last); err != nil {
+				t.Fatalf("invalid event data %q: %v", data, err)
+			}
+		}
+	}
+	if len(events) == 0 || events[len(events)-1] != string(jobDone) || last.Status != jobDone {
+		t.Errorf("events = %v, last = %+v", events, last)
+	}
+}
+
+func patchDownload(t *testing.T, api *httptest.Server, id, body string) (int, jobView) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPatch, api.URL+"/downloads/"+id, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var view jobView
+	_ = json.NewDecoder(resp.Body).Decode(&view)
+	return resp.StatusCode, view
+}
+
+func TestServe_Priority(t *testing.T) {
+	api, _ := newServeTestQueue(t, 1)
+
+	_, running := postDownload(t, api, `{"url": "slowAAAAAAA"}`)
+	waitForStatus(t, api, running.ID, jobDownloading)
+	_, a := postDownload(t, api, `{"url": "slowBBBBBBB"}`)
+	_, b := postDownload(t, api, `{"url": "slowCCCCCCC", "priority": 5}`)
+	_, c := postDownload(t, api, `{"url": "slowDDDDDDD"}`)
+
+	positions := func() map[string]int {
+		got := make(map[string]int)
+		for _, id := range []string{a.ID, b.ID, c.ID} {
+			got[id] = getDownload(t, api, id).Position
+		}
+		return got
+	}
+	if got := positions(); got[b.ID] != 1 || got[a.ID] != 2 || got[c.ID] != 3 {
+		t.Errorf("positions = %v, want the higher priority first", got)
+	}
+
+	// Moving to the front takes the priority of the download it passes
+	status, view := patchDownload(t, api, c.ID, `{"position": 1}`)
+	if status != http.StatusOK || view.Position != 1 || view.Priority != 5 {
+		t.Errorf("PATCH position = %d %+v", status, view)
+	}
+	status, view = patchDownload(t, api, a.ID, `{"priority": 10}`)
+	if status != http.StatusOK || view.Position != 1 || view.Priority != 10 {
+		t.Errorf("PATCH priority = %d %+v", status, view)
+	}
+	if got := positions(); got[a.ID] != 1 || got[c.ID] != 2 || got[b.ID] != 3 {
+		t.Errorf("positions = %v after reordering", got)
+	}
+
+	// The next worker takes the first download of the queue
+	deleteDownload(t, api, running.ID)
+	waitForStatus(t, api, a.ID, jobDownloading)
+	if view := getDownload(t, api, c.ID); view.Status != jobQueued || view.Position != 1 {
+		t.Errorf("download %s = %s at %d, want queued at 1", c.ID, view.Status, view.Position)
+	}
+
+	if status, _ := patchDownload(t, api, a.ID, `{"position": 2}`); status != http.StatusConflict {
+		t.Errorf("PATCH running download = %d, want %d", status, http.StatusConflict)
+	}
+	for _, body := range []string{`{}`, `{"position": 0}`, `not json`} {
+		if status, _ := patchDownload(t, api, c.ID, body); status != http.StatusBadRequest {
+			t.Errorf("PATCH %s = %d, want %d", body, status, http.StatusBadRequest)
+		}
+	}
+	if status, _ := patchDownload(t, api, "42", `{"position": 1}`); status != http.StatusNotFound {
+		t.Errorf("PATCH unknown download = %d, want %d", status, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+// sources are what the download commands fetch videos, playlists, mixes,
+// clips and channels with. The video and playlist fetchers are interfaces, so they can be
+// replaced with caching or instrumented implementations, or fakes in tests.
+type sources struct {
+	videos    ytdl.VideoFetcher
+	playlists ytdl.PlaylistFetcher
+	mixes     *youtube.MixFetcher
+	clips     *youtube.ClipFetcher
+	channels  *youtube.ChannelFetcher
+
+	// client fetches everything else, such as short links, captions and
+	// music metadata.
+	client *http.Client
+}
+
+// newSources returns the sources that fetch from YouTube with the client,
+// base URL, cookies and cache of page.
+func newSources(page *youtube.WatchPageFetcher) *sources {
+	return &sources{
+		videos:    ytdl.WatchPageVideos{Fetcher: page},
+		playlists: &youtube.PlaylistFetcher{Client: page.Client, BaseURL: page.BaseURL, Cookies: page.Cookies, Cache: page.Cache},
+		mixes:     &youtube.MixFetcher{Client: page.Client, BaseURL: page.BaseURL, Cookies: page.Cookies},
+		clips:     &youtube.ClipFetcher{Client: page.Client, BaseURL: page.BaseURL},
+		channels:  &youtube.ChannelFetcher{Client: page.Client, BaseURL: page.BaseURL},
+		client:    page.Client,
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+// fakeVideoFetcher returns a video with one video and one audio stream for any ID.
+type fakeVideoFetcher struct {
+	mu      sync.Mutex
+	fetched []youtube.VideoID
+}
+
+func (f *fakeVideoFetcher) FetchVideo(_ context.Context, videoID youtube.VideoID) (*ytdl.Video, error) {
+	f.mu.Lock()
+	f.fetched = append(f.fetched, videoID)
+	f.mu.Unlock()
+	return &ytdl.Video{
+		Video: youtube.Video{ID: videoID.String(), Title: "Fake " + videoID.String(), Duration: time.Minute},
+		Streams: &youtube.StreamManifest{
+			VideoStreams: []youtube.VideoStreamInfo{{StreamInfo: youtube.StreamInfo{URL: "fake://video", Container: youtube.ContainerMP4}, Height: 720}},
+			AudioStreams: []youtube.AudioStreamInfo{{StreamInfo: youtube.StreamInfo{URL: "fake://audio", Container: youtube.ContainerMP4, Bitrate: 128000}}},
+		},
+	}, nil
+}
+
+// fakePlaylistFetcher returns the same two videos for any playlist.
+type fakePlaylistFetcher struct{}
+
+func (fakePlaylistFetcher) Fetch(_ context.Context, playlistID youtube.PlaylistID) (*youtube.Playlist, []youtube.PlaylistVideo, error) {
+	videos := []youtube.PlaylistVideo{{ID: "aaaaaaaaaaa", Title: "First"}, {ID: "bbbbbbbbbbb", Title: "Second"}}
+	return &youtube.Playlist{ID: playlistID.String(), Title: "Fake Playlist"}, videos, nil
+}
+
+// fakeStreamDownloader writes each stream's URL as its content.
+type fakeStreamDownloader struct {
+	mu   sync.Mutex
+	urls []string
+}
+
+func (f *fakeStreamDownloader) DownloadStreamResult(_ context.Context, url, filePath string, _ download.ProgressCallback) download.DownloadResult {
+	f.mu.Lock()
+	f.urls = append(f.urls, url)
+	f.mu.Unlock()
+	err := os.WriteFile(filePath, []byte(url), 0o644)
+	return download.DownloadResult{FilePath: filePath, Error: err, Size: int64(len(url))}
+}
+
+func (f *fakeStreamDownloader) DownloadStreamTo(_ context.Context, url string, dst io.Writer, _ download.ProgressCallback) error {
+	f.mu.Lock()
+	f.urls = append(f.urls, url)
+	f.mu.Unlock()
+	_, err := io.WriteString(dst, url)
+	return err
+}
+
+// fakeMuxer writes the muxed output without running FFmpeg.
+func fakeMuxer(_ context.Context, _, _, outputPath string, _ time.Duration, _ ffmpeg.ProgressCallback) error {
+	return os.WriteFile(outputPath, []byte("muxed"), 0o644)
+}
+
+func TestRunDownloadWithFakeSources(t *testing.T) {
+	videos := &fakeVideoFetcher{}
+	src := &sources{videos: videos, playlists: fakePlaylistFetcher{}, client: http.DefaultClient}
+	downloader := &fakeStreamDownloader{}
+	outputDir := t.TempDir()
+	opts := &downloadOptions{output: outputDir, quality: "best", format: "mp4", hwAccel: "none"}
+
+	if err := runDownloadWithDeps(context.Background(), new(bytes.Buffer), "dQw4w9WgXcQ", opts, src, downloader, fakeMuxer); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if len(videos.fetched) != 1 || videos.fetched[0] != "dQw4w9WgXcQ" {
+		t.Errorf("fetched %v, want the video", videos.fetched)
+	}
+	if len(downloader.urls) != 2 {
+		t.Errorf("downloaded %v, want the video and audio stream", downloader.urls)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "Fake dQw4w9WgXcQ.mp4")); err != nil {
+		t.Errorf("output not written: %v", err)
+	}
+}
+
+func TestRunDownloadWithFakePlaylists(t *testing.T) {
+	videos := &fakeVideoFetcher{}
+	src := &sources{videos: videos, playlists: fakePlaylistFetcher{}, client: http.DefaultClient}
+	outputDir := t.TempDir()
+	opts := &downloadOptions{output: outputDir, quality: "best", format: "mp4", hwAccel: "none"}
+
+	buf := new(bytes.Buffer)
+	if err := runDownloadWithDeps(context.Background(), buf, "PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf", opts, src, &fakeStreamDownloader{}, fakeMuxer); err != nil {
+		t.Fatalf("download failed: %v\n%s", err, buf)
+	}
+	if len(videos.fetched) != 2 {
+		t.Errorf("fetched %v, want both playlist videos", videos.fetched)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("Playlist: Fake Playlist (2 videos)")) {
+		t.Errorf("output = %s", buf)
+	}
+}
+
+func TestRunDownloadWithChannelHandle(t *testing.T) {
+	server := newChannelCmdTestServer(t)
+	videos := &fakeVideoFetcher{}
+	src := &sources{
+		videos:    videos,
+		playlists: fakePlaylistFetcher{},
+		channels:  &youtube.ChannelFetcher{Client: server.Client(), BaseURL: server.URL},
+		client:    http.DefaultClient,
+	}
+	opts := &downloadOptions{output: t.TempDir(), quality: "best", format: "mp4", hwAccel: "none"}
+
+	buf := new(bytes.Buffer)
+	if err := runDownloadWithDeps(context.Background(), buf, testChannelURL, opts, src, &fakeStreamDownloader{}, fakeMuxer); err != nil {
+		t.Fatalf("download failed: %v\n%s", err, buf)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("Converting to uploads playlist: UUuAXFkgsw1L7xaCfnd5JJOw")) {
+		t.Errorf("output = %s, want the uploads playlist of the resolved channel", buf)
+	}
+	if len(videos.fetched) != 2 {
+		t.Errorf("fetched %v, want both uploads", videos.fetched)
+	}
+}
@@ -0,0 +1,593 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/metrics"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/notify"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+// defaultSyncLimit is how many of a subscription's videos are checked when the
+// subscription doesn't set a limit.
+const defaultSyncLimit = 50
+
+// Discovery backends listing a subscription's videos.
+const (
+	// discoveryPage scrapes the playlist, or the channel's uploads playlist, page by page.
+	discoveryPage = "page"
+
+	// discoveryRSS reads the channel's or playlist's feed, a single cheap request
+	// that only lists the 15 most recent videos.
+	discoveryRSS = "rss"
+)
+
+// syncOptions holds the flags of the sync command.
+type syncOptions struct {
+	config   string
+	interval time.Duration
+	cron     string
+	once     bool
+	metrics  string
+}
+
+// syncConfig is the subscriptions file read by the sync command.
+type syncConfig struct {
+	// Archive is the download archive recording the downloaded videos,
+	// archive.txt next to the config file by default.
+	Archive string `json:"archive"`
+
+	// Output is the directory subscriptions download to, the current directory by default.
+	Output string `json:"output"`
+
+	// Interval is how often to check for new videos, as a duration such as "6h".
+	Interval string `json:"interval"`
+
+	// Cron is a cron expression for when to check for new videos, instead of Interval.
+	Cron string `json:"cron"`
+
+	// Discovery is how subscriptions' videos are listed, discoveryPage by default.
+	Discovery string `json:"discovery"`
+
+	// Notify sends notifications for each video downloaded or failed.
+	Notify notifyConfig `json:"notify"`
+
+	// Subscriptions are the channels and playlists to keep in sync.
+	Subscriptions []subscription `json:"subscriptions"`
+}
+
+// subscription is a channel or playlist the sync command downloads new videos of.
+type subscription struct {
+	// URL is the channel or playlist URL or ID.
+	URL string `json:"url"`
+
+	// Name is shown in the output instead of the URL.
+	Name string `json:"name"`
+
+	// Output is the directory videos are saved to, relative to the config's output.
+	Output string `json:"output"`
+
+	// Quality and Format are as for download --quality and --format.
+	Quality string `json:"quality"`
+	Format  string `json:"format"`
+
+	// AudioOnly downloads the audio stream alone.
+	AudioOnly bool `json:"audio_only"`
+
+	// Template names the files as download's --output-template does, and can
+	// sort them into folders such as "$uploadDate/$title".
+	Template string `json:"template"`
+
+	// Limit is how many videos are checked, the newest uploads for channels and the
+	// first entries for playlists. The default is defaultSyncLimit.
+	Limit int `json:"limit"`
+
+	// Discovery overrides the config's discovery backend for this subscription.
+	Discovery string `json:"discovery"`
+}
+
+func (s *subscription) displayName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.URL
+}
+
+func (s *subscription) limit() int {
+	if s.Limit > 0 {
+		return s.Limit
+	}
+	return defaultSyncLimit
+}
+
+func newSyncCmd() *cobra.Command {
+	opts := &syncOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Download new videos of subscribed channels and playlists",
+		Long: `Check the channels and playlists listed in a subscriptions file for new videos
+and download them. Downloaded videos are recorded in a download archive, in the
+format of youtube-dl and yt-dlp, so each video is only downloaded once.
+
+The subscriptions file is JSON:
+
+  {
+    "output": "/home/me/Videos",
+    "interval": "6h",
+    "subscriptions": [
+      {"url": "https://www.youtube.com/@channel", "output": "channel", "quality": "1080p"},
+      {"url": "https://www.youtube.com/playlist?list=PL...", "format": "mkv", "limit": 10},
+      {"url": "https://www.youtube.com/@podcast", "audio_only": true}
+    ]
+  }
+
+Each subscription can set "name", "output" (relative to the top-level output),
+"quality", "format", "audio_only", "template" (a file name template as for
+download --output-template) and "limit", the number of videos checked
+(the newest 50 by default). The top level can also set "archive", which defaults
+to archive.txt next to the subscriptions file.
+
+"discovery", at the top level or per subscription, chooses how videos are found:
+"page" (the default) reads the channel's uploads or the playlist page by page,
+"rss" reads the channel's or playlist's RSS feed. Feeds take a single small
+request, so they suit frequent checks, but only list the 15 most recent videos.
+
+"notify" at the top level sends a notification for each video downloaded or
+failed: {"desktop": true} shows a desktop notification and {"webhook": URL}
+posts the video's title, status, path, size and download duration as JSON, as
+download's --notify-desktop and --notify-webhook do.
+
+With an "interval" or "cron" schedule, in the file or given as flags, sync keeps
+running and checks again at each scheduled time until interrupted. Without one,
+or with --once, it checks once and exits.
+
+--metrics-addr serves Prometheus metrics at /metrics while sync runs: stream
+downloads started, succeeded and failed, bytes downloaded, download durations,
+retried requests, stalled transfers and requests YouTube rate limited.`,
+		Example: `  ytdl sync
+  ytdl sync --config subscriptions.json --once
+  ytdl sync --interval 1h
+  ytdl sync --cron "0 */6 * * *"`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := runSync(cmd, opts); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.config, "config", defaultSyncConfigPath(), "Path to the subscriptions file")
+	cmd.Flags().DurationVar(&opts.interval, "interval", 0, "Check for new videos at this interval, overriding the config")
+	cmd.Flags().StringVar(&opts.cron, "cron", "", "Check for new videos on this cron schedule, overriding the config")
+	cmd.Flags().BoolVar(&opts.once, "once", false, "Check once and exit, ignoring any schedule")
+	cmd.Flags().StringVar(&opts.metrics, "metrics-addr", "", "Serve Prometheus metrics of the downloads at /metrics on this address (e.g. 127.0.0.1:9090)")
+
+	return cmd
+}
+
+// defaultSyncConfigPath returns subscriptions.json in the user's config directory.
+func defaultSyncConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "subscriptions.json"
+	}
+	return filepath.Join(dir, "ytdl", "subscriptions.json")
+}
+
+// loadSyncConfig reads and validates a subscriptions file.
+func loadSyncConfig(path string) (*syncConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+	var cfg syncConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse subscriptions %s: %w", path, err)
+	}
+	if cfg.Discovery == "" {
+		cfg.Discovery = discoveryPage
+	}
+	if err := checkDiscovery(cfg.Discovery); err != nil {
+		return nil, err
+	}
+	for i := range cfg.Subscriptions {
+		sub := &cfg.Subscriptions[i]
+		if sub.URL == "" {
+			return nil, fmt.Errorf("subscription %d has no url", i+1)
+		}
+		if sub.Discovery == "" {
+			sub.Discovery = cfg.Discovery
+		}
+		if err := checkDiscovery(sub.Discovery); err != nil {
+			return nil, fmt.Errorf("subscription %d: %w", i+1, err)
+		}
+	}
+	if cfg.Archive == "" {
+		cfg.Archive = filepath.Join(filepath.Dir(path), "archive.txt")
+	}
+	if cfg.Output == "" {
+		cfg.Output = "."
+	}
+	return &cfg, nil
+}
+
+func checkDiscovery(discovery string) error {
+	if discovery != discoveryPage && discovery != discoveryRSS {
+		return fmt.Errorf("invalid discovery %q: use %q or %q", discovery, discoveryPage, discoveryRSS)
+	}
+	return nil
+}
+
+// syncSchedule returns the schedule from the flags, falling back to the config.
+// It returns nil when neither sets one.
+func syncSchedule(cfg *syncConfig, opts *syncOptions) (schedule, error) {
+	interval, cronExpr := opts.interval, opts.cron
+	if interval == 0 && cronExpr == "" {
+		cronExpr = cfg.Cron
+		if cfg.Interval != "" {
+			d, err := time.ParseDuration(cfg.Interval)
+			if err != nil {
+				return nil, fmt.Errorf("invalid interval %q: %w", cfg.Interval, err)
+			}
+			interval = d
+		}
+	}
+
+	switch {
+	case interval != 0 && cronExpr != "":
+		return nil, errors.New("set either an interval or a cron schedule, not both")
+	case interval < 0:
+		return nil, fmt.Errorf("invalid interval %s: must be positive", interval)
+	case interval > 0:
+		return intervalSchedule(interval), nil
+	case cronExpr != "":
+		return parseCron(cronExpr)
+	default:
+		return nil, nil
+	}
+}
+
+// runSync checks the subscriptions once, or on the schedule until interrupted.
+func runSync(cmd *cobra.Command, opts *syncOptions) error {
+	cfg, err := loadSyncConfig(opts.config)
+	if err != nil {
+		return err
+	}
+	sched, err := syncSchedule(cfg, opts)
+	if err != nil {
+		return err
+	}
+	if opts.once {
+		sched = nil
+	}
+
+	archive, err := download.OpenArchive(cfg.Archive)
+	if err != nil {
+		return err
+	}
+	var m *metrics.Metrics
+	if opts.metrics != "" {
+		m = metrics.New()
+	}
+	client, ytdlClient, err := newDaemonClients(cmd, m)
+	if err != nil {
+		return err
+	}
+	notifier, err := newNotifier(cfg.Notify, client)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if m != nil {
+		if err := serveMetrics(ctx, statusWriter(cmd), opts.metrics, m); err != nil {
+			return err
+		}
+	}
+
+	s := &syncer{
+		w:        statusWriter(cmd),
+		client:   ytdlClient,
+		listers:  newVideoListers(client, ""),
+		archive:  archive,
+		config:   cfg,
+		notifier: notifier,
+	}
+	return s.loop(ctx, sched)
+}
+
+// newVideoListers returns the listers of each discovery backend.
+func newVideoListers(client *http.Client, baseURL string) map[string]videoLister {
+	sources := &sourceResolver{
+		channels:   &youtube.ChannelFetcher{Client: client, BaseURL: baseURL},
+		channelIDs: make(map[string]youtube.ChannelID),
+	}
+	return map[string]videoLister{
+		discoveryPage: &pageLister{sources: sources, playlists: &youtube.PlaylistFetcher{Client: client, BaseURL: baseURL}},
+		discoveryRSS:  &feedLister{sources: sources, feeds: &youtube.FeedFetcher{Client: client, BaseURL: baseURL}},
+	}
+}
+
+// videoLister lists the videos of a subscription to check against the archive.
+type videoLister interface {
+	listVideos(ctx context.Context, sub *subscription) ([]youtube.PlaylistVideo, error)
+}
+
+// subscriptionSource is the channel or playlist a subscription follows.
+type subscriptionSource struct {
+	channelID  youtube.ChannelID
+	playlistID youtube.PlaylistID
+}
+
+// sourceResolver resolves subscription URLs to channel and playlist IDs. Channel
+// handles and custom URLs take a page request, so their IDs are remembered for
+// later checks.
+type sourceResolver struct {
+	channels   *youtube.ChannelFetcher
+	channelIDs map[string]youtube.ChannelID
+}
+
+func (r *sourceResolver) resolve(ctx context.Context, sub *subscription) (subscriptionSource, error) {
+	query, err := youtube.ResolveQuery(sub.URL)
+	if err != nil {
+		return subscriptionSource{}, err
+	}
+
+	switch query.Type {
+	case youtube.QueryTypePlaylist:
+		return subscriptionSource{playlistID: query.PlaylistID}, nil
+	case youtube.QueryTypeChannel:
+		key := query.Channel.Path()
+		if id, ok := r.channelIDs[key]; ok {
+			return subscriptionSource{channelID: id}, nil
+		}
+		id, err := r.channels.ResolveID(ctx, query.Channel)
+		if err != nil {
+			return subscriptionSource{}, fmt.Errorf("failed to resolve channel: %w", err)
+		}
+		r.channelIDs[key] = id
+		return subscriptionSource{channelID: id}, nil
+	default:
+		return subscriptionSource{}, fmt.Errorf("%s is a %s, not a channel or playlist", sub.URL, query.Type)
+	}
+}
+
+// pageLister lists the videos of playlists, and of channels through their uploads
+// playlist, from their pages.
+type pageLister struct {
+	sources   *sourceResolver
+	playlists *youtube.PlaylistFetcher
+}
+
+func (l *pageLister) listVideos(ctx context.Context, sub *subscription) ([]youtube.PlaylistVideo, error) {
+	source, err := l.sources.resolve(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	playlistID := source.playlistID
+	if source.channelID != "" {
+		playlistID = youtube.ChannelToUploadsPlaylistID(source.channelID)
+	}
+
+	page, err := l.playlists.FetchPage(ctx, playlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch playlist: %w", err)
+	}
+	videos := page.Videos
+	for page.HasMore() && len(videos) < sub.limit() {
+		if page, err = l.playlists.NextPage(ctx, page); err != nil {
+			return nil, fmt.Errorf("failed to fetch playlist: %w", err)
+		}
+		videos = append(videos, page.Videos...)
+	}
+	if len(videos) > sub.limit() {
+		videos = videos[:sub.limit()]
+	}
+	return videos, nil
+}
+
+// feedLister lists the recent videos of channels and playlists from their feeds.
+type feedLister struct {
+	sources *sourceResolver
+	feeds   *youtube.FeedFetcher
+}
+
+func (l *feedLister) listVideos(ctx context.Context, sub *subscription) ([]youtube.PlaylistVideo, error) {
+	source, err := l.sources.resolve(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	var feed *youtube.Feed
+	if source.channelID != "" {
+		feed, err = l.feeds.FetchChannel(ctx, source.channelID)
+	} else {
+		feed, err = l.feeds.FetchPlaylist(ctx, source.playlistID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	videos := feed.PlaylistVideos()
+	if len(videos) > sub.limit() {
+		videos = videos[:sub.limit()]
+	}
+	return videos, nil
+}
+
+// syncer downloads the videos of subscriptions that aren't in the archive yet.
+type syncer struct {
+	w       io.Writer
+	client  *ytdl.Client
+	listers map[string]videoLister
+	archive *download.Archive
+	config  *syncConfig
+
+	// dedup skips the videos of a check that an earlier subscription already listed.
+	dedup *download.Dedup
+
+	// notifier, when set, is told about every video downloaded or failed.
+	notifier notify.Notifier
+}
+
+// loop syncs once and, with a schedule, again at each scheduled time until the
+// context is canceled. Without a schedule failed downloads are returned as a
+// *PartialDownloadError.
+func (s *syncer) loop(ctx context.Context, sched schedule) error {
+	for {
+		downloaded, failed := s.syncAll(ctx)
+		if ctx.Err() != nil {
+			if sched != nil {
+				return nil
+			}
+			return ctx.Err()
+		}
+		if sched == nil {
+			if failed > 0 {
+				return &PartialDownloadError{Failed: failed, Total: downloaded + failed}
+			}
+			return nil
+		}
+
+		next := sched.next(time.Now())
+		if next.IsZero() {
+			return errors.New("the cron schedule never runs again")
+		}
+		_, _ = fmt.Fprintf(s.w, "Next check at %s\n", next.Format(time.DateTime))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// syncAll syncs every subscription and returns the number of videos downloaded
+// and failed.
+func (s *syncer) syncAll(ctx context.Context) (downloaded, failed int) {
+	_, _ = fmt.Fprintf(s.w, "Checking %d subscriptions...\n", len(s.config.Subscriptions))
+	// Each check starts afresh, so videos that failed or were upcoming are retried
+	s.dedup = &download.Dedup{Archive: s.archive}
+	for i := range s.config.Subscriptions {
+		d, f := s.syncSubscription(ctx, &s.config.Subscriptions[i])
+		downloaded += d
+		failed += f
+		if ctx.Err() != nil {
+			return downloaded, failed
+		}
+	}
+	_, _ = fmt.Fprintf(s.w, "Sync complete: %d downloaded, %d failed\n", downloaded, failed)
+	return downloaded, failed
+}
+
+// syncSubscription downloads the subscription's videos that aren't in the archive.
+// A subscription that can't be listed counts as one failure.
+func (s *syncer) syncSubscription(ctx context.Context, sub *subscription) (downloaded, failed int) {
+	videos, err := s.listers[sub.Discovery].listVideos(ctx, sub)
+	if err != nil {
+		_, _ = fmt.Fprintf(s.w, "%s: %v\n", sub.displayName(), err)
+		return 0, 1
+	}
+
+	var pending []youtube.PlaylistVideo
+	for _, v := range videos {
+		if s.dedup.Claim(v.ID) == "" {
+			pending = append(pending, v)
+		}
+	}
+	_, _ = fmt.Fprintf(s.w, "%s: %d new videos\n", sub.displayName(), len(pending))
+
+	for i := range pending {
+		v := &pending[i]
+		if err := s.downloadVideo(ctx, sub, v.ID); err != nil {
+			if ctx.Err() != nil {
+				return downloaded, failed
+			}
+			var upcomingErr *youtube.UpcomingVideoError
+			if errors.As(err, &upcomingErr) {
+				_, _ = fmt.Fprintf(s.w, "  Skipping %s: %v\n", v.ID, err)
+				continue
+			}
+			_, _ = fmt.Fprintf(s.w, "  Failed to download %s: %v\n", v.ID, err)
+			failed++
+			continue
+		}
+		downloaded++
+	}
+	return downloaded, failed
+}
+
+// downloadVideo downloads one video with the subscription's settings and adds it
+// to the archive. Files that already exist are kept and archived.
+func (s *syncer) downloadVideo(ctx context.Context, sub *subscription, videoID string) (err error) {
+	report := download.DownloadResult{Title: videoID}
+	started := time.Now()
+	defer func() {
+		// Upcoming videos are retried at the next check rather than failed
+		var upcomingErr *youtube.UpcomingVideoError
+		if errors.As(err, &upcomingErr) {
+			return
+		}
+		report.Error = err
+		report.Elapsed = time.Since(started)
+		notifyResult(ctx, s.w, s.notifier, report)
+	}()
+
+	video, err := s.client.GetVideo(ctx, videoID)
+	if err != nil {
+		return err
+	}
+	report.Title = video.Title
+
+	outputDir := s.config.Output
+	if sub.Output != "" {
+		outputDir = sub.Output
+		if !filepath.IsAbs(outputDir) {
+			outputDir = filepath.Join(s.config.Output, outputDir)
+		}
+	}
+	opts := []ytdl.Option{
+		ytdl.WithOutputDir(outputDir),
+		ytdl.WithQuality(parseQualityPreference(sub.Quality)),
+		ytdl.WithContainer(parseContainer(sub.Format)),
+		ytdl.WithOverwritePolicy(download.SkipExisting),
+	}
+	if sub.AudioOnly {
+		opts = append(opts, ytdl.WithAudioOnly())
+	}
+	if sub.Template != "" {
+		opts = append(opts, ytdl.WithFilenameTemplate(sub.Template))
+	}
+
+	result, err := s.client.DownloadVideo(ctx, video, opts...)
+	if err != nil {
+		return err
+	}
+	report.FilePath, report.Size, report.Skipped = result.FilePath, result.Size, result.Skipped
+	if result.Skipped {
+		_, _ = fmt.Fprintf(s.w, "  Already exists: %s\n", result.FilePath)
+	} else {
+		_, _ = fmt.Fprintf(s.w, "  Downloaded %s -> %s\n", video.Title, result.FilePath)
+	}
+	return s.archive.Add(videoID)
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/notify"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+// newSyncTestServer serves a playlist with two videos, a feed with the same
+// videos, their watch pages and a muxed stream for each. Watch pages of videos in unavailable fail.
+func newSyncTestServer(t *testing.T, unavailable ...string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/playlist":
+			servePlaylistCmdTestPage(w)
+		case r.URL.Path == "/feeds/videos.xml":
+			_, _ = w.Write([]byte(`<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom"><title>Test Channel</title>` +
+				`<entry><yt:videoId>jNQXAC9IVRw</yt:videoId><title>Newest</title></entry>` +
+				`<entry><yt:videoId>dQw4w9WgXcQ</yt:videoId><title>Older</title></entry></feed>`))
+		case r.URL.Path == "/watch":
+			id := r.URL.Query().Get("v")
+			for _, u := range unavailable {
+				if u == id {
+					_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"playabilityStatus":{"status":"ERROR","reason":"Video unavailable"}};</script>`))
+					return
+				}
+			}
+			_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"` + id + `","title":"Video ` + id + `","lengthSeconds":"60"},` +
+				`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[{"itag":18,"url":"` + server.URL + `/stream/` + id +
+				`","mimeType":"video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"","height":360,"qualityLabel":"360p"}]}};</script>`))
+		case strings.HasPrefix(r.URL.Path, "/stream/"):
+			_, _ = w.Write([]byte("video data"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestSyncer(t *testing.T, server *httptest.Server, cfg *syncConfig) (*syncer, *bytes.Buffer) {
+	t.Helper()
+	archive, err := download.OpenArchive(cfg.Archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	return &syncer{
+		w:       buf,
+		client:  ytdl.NewClient(ytdl.WithHTTPClient(server.Client()), ytdl.WithBaseURL(server.URL)),
+		listers: newVideoListers(server.Client(), server.URL),
+		archive: archive,
+		config:  cfg,
+	}, buf
+}
+
+func TestSyncer_DownloadsNewVideos(t *testing.T) {
+	server := newSyncTestServer(t)
+	dir := t.TempDir()
+	cfg := &syncConfig{
+		Archive:       filepath.Join(dir, "archive.txt"),
+		Output:        dir,
+		Subscriptions: []subscription{{URL: testPlaylistURL, Name: "Test", Output: "test", Discovery: discoveryPage}},
+	}
+	if err := os.WriteFile(cfg.Archive, []byte("youtube dQw4w9WgXcQ\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, buf := newTestSyncer(t, server, cfg)
+	if err := s.loop(context.Background(), nil); err != nil {
+		t.Fatalf("loop() error = %v\n%s", err, buf)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"Test: 1 new videos", "Sync complete: 1 downloaded, 0 failed"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "test", "Video jNQXAC9IVRw.mp4")); err != nil || string(data) != "video data" {
+		t.Errorf("downloaded file = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "test", "Video dQw4w9WgXcQ.mp4")); !os.IsNotExist(err) {
+		t.Error("expected the archived video to be skipped")
+	}
+	archive, _ := os.ReadFile(cfg.Archive)
+	if string(archive) != "youtube dQw4w9WgXcQ\nyoutube jNQXAC9IVRw\n" {
+		t.Errorf("archive = %q", archive)
+	}
+
+	// Everything is archived now
+	buf.Reset()
+	if err := s.loop(context.Background(), nil); err != nil {
+		t.Fatalf("second loop() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "Test: 0 new videos") {
+		t.Errorf("expected nothing new:\n%s", buf)
+	}
+}
+
+func TestSyncer_RSSDiscovery(t *testing.T) {
+	server := newSyncTestServer(t)
+	dir := t.TempDir()
+	cfg := &syncConfig{
+		Archive:       filepath.Join(dir, "archive.txt"),
+		Output:        dir,
+		Subscriptions: []subscription{{URL: "https://www.youtube.com/channel/UCuAXFkgsw1L7xaCfnd5JJOw", Name: "Feed", Limit: 1, Discovery: discoveryRSS}},
+	}
+
+	s, buf := newTestSyncer(t, server, cfg)
+	if err := s.loop(context.Background(), nil); err != nil {
+		t.Fatalf("loop() error = %v\n%s", err, buf)
+	}
+	if !strings.Contains(buf.String(), "Feed: 1 new videos") {
+		t.Errorf("unexpected output:\n%s", buf)
+	}
+	if !s.archive.Has("jNQXAC9IVRw") || s.archive.Has("dQw4w9WgXcQ") {
+		t.Error("expected only the newest feed entry to be downloaded")
+	}
+}
+
+func TestSyncer_Failures(t *testing.T) {
+	server := newSyncTestServer(t, "dQw4w9WgXcQ")
+	dir := t.TempDir()
+	cfg := &syncConfig{
+		Archive: filepath.Join(dir, "archive.txt"),
+		Output:  dir,
+		Subscriptions: []subscription{
+			{URL: testPlaylistURL, Limit: 1, Discovery: discoveryPage},
+			{URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", Name: "Not a playlist", Discovery: discoveryPage},
+		},
+	}
+
+	s, buf := newTestSyncer(t, server, cfg)
+	err := s.loop(context.Background(), nil)
+	var partialErr *PartialDownloadError
+	if !errors.As(err, &partialErr) || partialErr.Failed != 2 {
+		t.Fatalf("loop() error = %v, want 2 failures\n%s", err, buf)
+	}
+	if !strings.Contains(buf.String(), "Not a playlist: ") || !strings.Contains(buf.String(), "Failed to download dQw4w9WgXcQ") {
+		t.Errorf("unexpected output:\n%s", buf)
+	}
+	if s.archive.Len() != 0 {
+		t.Error("expected failed videos to stay out of the archive")
+	}
+}
+
+func TestSyncer_SharedVideosTriedOnce(t *testing.T) {
+	server := newSyncTestServer(t, "dQw4w9WgXcQ")
+	dir := t.TempDir()
+	cfg := &syncConfig{
+		Archive: filepath.Join(dir, "archive.txt"),
+		Output:  dir,
+		Subscriptions: []subscription{
+			{URL: testPlaylistURL, Name: "First", Discovery: discoveryPage},
+			{URL: testPlaylistURL, Name: "Second", Discovery: discoveryPage},
+		},
+	}
+
+	s, buf := newTestSyncer(t, server, cfg)
+	err := s.loop(context.Background(), nil)
+	var partialErr *PartialDownloadError
+	if !errors.As(err, &partialErr) || partialErr.Failed != 1 {
+		t.Fatalf("loop() error = %v, want the failing video counted once\n%s", err, buf)
+	}
+	if !strings.Contains(buf.String(), "First: 2 new videos") || !strings.Contains(buf.String(), "Second: 0 new videos") {
+		t.Errorf("expected the second subscription to skip the videos of the first:\n%s", buf)
+	}
+
+	// The next check tries the failed video again
+	buf.Reset()
+	_ = s.loop(context.Background(), nil)
+	if !strings.Contains(buf.String(), "First: 1 new videos") {
+		t.Errorf("expected the failed video to be retried:\n%s", buf)
+	}
+}
+
+func TestSyncer_Notifies(t *testing.T) {
+	server := newSyncTestServer(t, "dQw4w9WgXcQ")
+	dir := t.TempDir()
+	cfg := &syncConfig{
+		Archive:       filepath.Join(dir, "archive.txt"),
+		Output:        dir,
+		Subscriptions: []subscription{{URL: testPlaylistURL, Discovery: discoveryPage}},
+	}
+
+	s, buf := newTestSyncer(t, server, cfg)
+	n := &recordingNotifier{}
+	s.notifier = n
+	_ = s.loop(context.Background(), nil)
+
+	if len(n.events) != 2 {
+		t.Fatalf("events = %+v, want one per video\n%s", n.events, buf)
+	}
+	byStatus := map[string]string{}
+	for _, e := range n.events {
+		byStatus[e.Status] = e.Title
+	}
+	if byStatus[notify.StatusSucceeded] != "Video jNQXAC9IVRw" || byStatus[notify.StatusFailed] != "dQw4w9WgXcQ" {
+		t.Errorf("events = %+v", n.events)
+	}
+}
+
+// cancelSchedule cancels the sync loop when it's asked for the next run.
+type cancelSchedule struct{ cancel context.CancelFunc }
+
+func (s cancelSchedule) next(t time.Time) time.Time {
+	s.cancel()
+	return t.Add(time.Hour)
+}
+
+func TestSyncer_LoopStopsOnCancel(t *testing.T) {
+	server := newSyncTestServer(t)
+	dir := t.TempDir()
+	s, buf := newTestSyncer(t, server, &syncConfig{Archive: filepath.Join(dir, "archive.txt"), Output: dir})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.loop(ctx, cancelSchedule{cancel}); err != nil {
+		t.Errorf("loop() error = %v, want nil after cancel", err)
+	}
+	if !strings.Contains(buf.String(), "Next check at") {
+		t.Errorf("expected the next check to be announced:\n%s", buf)
+	}
+}
+
+func TestLoadSyncConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "subscriptions.json")
+	if err := os.WriteFile(path, []byte(`{"cron": "@daily", "subscriptions": [{"url": "UCuAXFkgsw1L7xaCfnd5JJOw", "quality": "720p"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadSyncConfig(path)
+	if err != nil {
+		t.Fatalf("loadSyncConfig() error = %v", err)
+	}
+	if cfg.Archive != filepath.Join(dir, "archive.txt") || cfg.Output != "." {
+		t.Errorf("defaults = %q, %q", cfg.Archive, cfg.Output)
+	}
+	if len(cfg.Subscriptions) != 1 || cfg.Subscriptions[0].Quality != "720p" || cfg.Subscriptions[0].limit() != defaultSyncLimit {
+		t.Errorf("subscriptions = %+v", cfg.Subscriptions)
+	}
+	if cfg.Discovery != discoveryPage || cfg.Subscriptions[0].Discovery != discoveryPage {
+		t.Errorf("discovery = %q, %q, want the page default", cfg.Discovery, cfg.Subscriptions[0].Discovery)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"discovery": "rss", "notify": {"webhook": "https://example.com/hook", "desktop": true}, "subscriptions": [{"url": "a"}, {"url": "b", "discovery": "page"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err = loadSyncConfig(path); err != nil {
+		t.Fatalf("loadSyncConfig() error = %v", err)
+	}
+	if cfg.Subscriptions[0].Discovery != discoveryRSS || cfg.Subscriptions[1].Discovery != discoveryPage {
+		t.Errorf("discovery = %q, %q", cfg.Subscriptions[0].Discovery, cfg.Subscriptions[1].Discovery)
+	}
+	if cfg.Notify.Webhook != "https://example.com/hook" || !cfg.Notify.Desktop {
+		t.Errorf("notify = %+v", cfg.Notify)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"subscriptions": [{"url": "a", "discovery": "scrape"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSyncConfig(path); err == nil {
+		t.Error("expected an error for an unknown discovery backend")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"subscriptions": [{"name": "no url"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSyncConfig(path); err == nil {
+		t.Error("expected an error for a subscription without a url")
+	}
+}
+
+func TestSyncSchedule(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     syncConfig
+		opts    syncOptions
+		want    string
+		wantErr bool
+	}{
+		{name: "none", want: "<nil>"},
+		{name: "config interval", cfg: syncConfig{Interval: "6h"}, want: "main.intervalSchedule"},
+		{name: "config cron", cfg: syncConfig{Cron: "@hourly"}, want: "*main.cronSchedule"},
+		{name: "flag overrides config", cfg: syncConfig{Cron: "@hourly"}, opts: syncOptions{interval: time.Hour}, want: "main.intervalSchedule"},
+		{name: "both", cfg: syncConfig{Interval: "1h", Cron: "@hourly"}, wantErr: true},
+		{name: "bad interval", cfg: syncConfig{Interval: "often"}, wantErr: true},
+		{name: "bad cron", opts: syncOptions{cron: "every day"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := syncSchedule(&tt.cfg, &tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("syncSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && fmt.Sprintf("%T", s) != tt.want {
+				t.Errorf("syncSchedule() = %T, want %s", s, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,739 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/filename"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ytdl"
+)
+
+// tuiRedraw is the minimum interval between redraws caused by download progress.
+const tuiRedraw = 100 * time.Millisecond
+
+// tuiFormatRows is the number of formats listed at once; longer lists scroll.
+const tuiFormatRows = 12
+
+var (
+	// errNotTerminal is returned when the tui command isn't run in an interactive terminal.
+	errNotTerminal = errors.New("ytdl tui needs an interactive terminal")
+
+	// errNoANSI is returned when the terminal can't process the escape codes the
+	// tui command draws with, as legacy Windows consoles can't.
+	errNoANSI = errors.New("ytdl tui needs a terminal that supports ANSI escape codes, such as Windows Terminal")
+)
+
+// tuiOptions holds the flags of the tui command.
+type tuiOptions struct {
+	output       string
+	restrictName bool
+	asciiName    bool
+}
+
+func newTUICmd() *cobra.Command {
+	opts := &tuiOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Download videos interactively",
+		Long: `Open an interactive terminal interface for downloading videos.
+
+Paste a video URL or ID and press Enter to list its formats. Choose one with the
+arrow keys, switch the container between the source format and MKV with left and
+right, and press Enter to add the download to the queue. Downloads run one at a
+time while more videos are added, with their progress shown below the input.
+
+Existing files are never overwritten; a numeric suffix is added instead.
+Press Esc to go back or quit, and Ctrl+C to cancel all downloads and quit.`,
+		Example: `  ytdl tui
+  ytdl tui -o ~/Videos`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := runTUI(cmd, opts); err != nil {
+				return WrapError(err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.output, "output", "o", ".", "Output directory for downloaded files")
+	cmd.Flags().BoolVar(&opts.restrictName, "restrict-filenames", false, "Normalize Unicode, strip invisible characters and replace emoji in file names")
+	cmd.Flags().BoolVar(&opts.asciiName, "ascii-filenames", false, "Transliterate file names to ASCII")
+
+	return cmd
+}
+
+// runTUI runs the interactive interface until the user quits.
+func runTUI(cmd *cobra.Command, opts *tuiOptions) error {
+	in, ok := cmd.InOrStdin().(*os.File)
+	c := detectConsole(cmd.OutOrStdout())
+	if !ok || !term.IsTerminal(int(in.Fd())) || !c.tty {
+		return errNotTerminal
+	}
+	if !c.ansi {
+		return errNoANSI
+	}
+	out := consoleOutput(cmd, cmd.OutOrStdout())
+
+	client, err := newHTTPClient(cmd)
+	if err != nil {
+		return err
+	}
+	page, err := newWatchPageFetcher(cmd, client)
+	if err != nil {
+		return err
+	}
+	downloader, err := newStreamDownloader(cmd, client)
+	if err != nil {
+		return err
+	}
+
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to set up terminal: %w", err)
+	}
+	defer func() { _ = term.Restore(int(in.Fd()), state) }()
+
+	// Draw on the alternate screen so the shell's scrollback is left intact
+	_, _ = io.WriteString(out, "\x1b[?1049h\x1b[?25l")
+	defer func() { _, _ = io.WriteString(out, "\x1b[?25h\x1b[?1049l") }()
+
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	msgs := make(chan any)
+	backend := &tuiBackend{
+		ctx:        ctx,
+		msgs:       msgs,
+		src:        newSources(page),
+		downloader: downloader,
+		muxer:      muxStreams,
+		notify:     make(chan struct{}, 1),
+	}
+	go backend.work()
+	go readKeys(ctx, in, msgs)
+
+	model := newTUIModel(backend, opts)
+	runTUILoop(ctx, out, model, msgs)
+	return nil
+}
+
+// runTUILoop feeds messages to the model and redraws it until the model quits or
+// ctx is canceled. Key presses redraw at once, progress at most every tuiRedraw.
+func runTUILoop(ctx context.Context, w io.Writer, model *tuiModel, msgs <-chan any) {
+	ticker := time.NewTicker(tuiRedraw)
+	defer ticker.Stop()
+
+	drawTUI(w, model.view())
+	dirty := false
+	for !model.quit {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-msgs:
+			model.update(msg)
+			if _, ok := msg.(tuiKey); ok {
+				drawTUI(w, model.view())
+				dirty = false
+			} else {
+				dirty = true
+			}
+		case <-ticker.C:
+			if dirty {
+				drawTUI(w, model.view())
+				dirty = false
+			}
+		}
+	}
+}
+
+// drawTUI redraws the screen with view. The terminal is in raw mode, so lines
+// need an explicit carriage return.
+func drawTUI(w io.Writer, view string) {
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for _, line := range strings.Split(view, "\n") {
+		b.WriteString(line)
+		b.WriteString("\x1b[K\r\n")
+	}
+	b.WriteString("\x1b[J")
+	_, _ = io.WriteString(w, b.String())
+}
+
+// tuiKeyType identifies a key press.
+type tuiKeyType int
+
+const (
+	keyRune tuiKeyType = iota
+	keyEnter
+	keyBackspace
+	keyEsc
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyCtrlC
+	keyCtrlU
+)
+
+// tuiKey is a key pressed by the user. r is set for keyRune.
+type tuiKey struct {
+	typ tuiKeyType
+	r   rune
+}
+
+// readKeys sends the keys read from r to msgs until reading fails or ctx is canceled.
+func readKeys(ctx context.Context, r io.Reader, msgs chan<- any) {
+	buf := make([]byte, 256)
+	for {
+		n, err := r.Read(buf)
+		for _, key := range parseKeys(buf[:n]) {
+			select {
+			case msgs <- key:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// arrowKeys maps the final byte of ANSI cursor key sequences to keys.
+var arrowKeys = map[byte]tuiKeyType{'A': keyUp, 'B': keyDown, 'C': keyRight, 'D': keyLeft}
+
+// parseKeys decodes the bytes of one terminal read into keys. Pasted text arrives
+// in a single read and decodes to one keyRune per character.
+func parseKeys(b []byte) []tuiKey {
+	var keys []tuiKey
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			// Cursor keys are ESC [ or ESC O followed by a letter; a lone ESC is Esc.
+			// Other sequences, such as ESC [ 3 ~ for Delete, are skipped.
+			if len(b) >= 3 && (b[1] == '[' || b[1] == 'O') {
+				end := 2
+				for end < len(b)-1 && (b[end] < 0x40 || b[end] > 0x7e) {
+					end++
+				}
+				if typ, ok := arrowKeys[b[end]]; ok && end == 2 {
+					keys = append(keys, tuiKey{typ: typ})
+				}
+				b = b[end+1:]
+				continue
+			}
+			keys = append(keys, tuiKey{typ: keyEsc})
+		case c == '\r' || c == '\n':
+			keys = append(keys, tuiKey{typ: keyEnter})
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, tuiKey{typ: keyBackspace})
+		case c == 0x03:
+			keys = append(keys, tuiKey{typ: keyCtrlC})
+		case c == 0x15:
+			keys = append(keys, tuiKey{typ: keyCtrlU})
+		case c < 0x20:
+			// Other control keys do nothing
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys = append(keys, tuiKey{typ: keyRune, r: r})
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// tuiScreen is the part of the interface that receives key presses.
+type tuiScreen int
+
+const (
+	tuiScreenInput tuiScreen = iota
+	tuiScreenLoading
+	tuiScreenFormats
+)
+
+// tuiJobState is the state of a queued download.
+type tuiJobState int
+
+const (
+	tuiJobQueued tuiJobState = iota
+	tuiJobDownloading
+	tuiJobMuxing
+	tuiJobDone
+	tuiJobFailed
+)
+
+// tuiVideoMsg delivers the result of fetching the video for the seq-th URL entered.
+type tuiVideoMsg struct {
+	seq      int
+	video    *youtube.Video
+	manifest *youtube.StreamManifest
+	err      error
+}
+
+// tuiJobMsg reports a change in the state or progress of a download.
+type tuiJobMsg struct {
+	id       int
+	state    tuiJobState
+	progress download.Progress
+	path     string
+	err      error
+}
+
+// tuiRequest is a download added to the queue.
+type tuiRequest struct {
+	id        int
+	video     *youtube.Video
+	selection *streamSelection
+	path      string
+}
+
+// tuiJob is a download shown in the queue.
+type tuiJob struct {
+	id       int
+	title    string
+	path     string
+	state    tuiJobState
+	progress download.Progress
+	err      error
+}
+
+// tuiRunner does the work the model asks for in the background, reporting back
+// with tuiVideoMsg and tuiJobMsg.
+type tuiRunner interface {
+	fetch(seq int, url string)
+	enqueue(req tuiRequest)
+}
+
+// tuiModel is the state of the interactive interface. It only changes in update,
+// and view draws it.
+type tuiModel struct {
+	runner    tuiRunner
+	outputDir string
+	fileOpts  filename.Options
+
+	screen tuiScreen
+	input  string
+	status string
+	quit   bool
+
+	// fetchSeq numbers the URLs entered, so a fetch abandoned with Esc is ignored.
+	fetchSeq int
+
+	video    *youtube.Video
+	manifest *youtube.StreamManifest
+	formats  []youtube.DownloadOption
+	cursor   int
+	mkv      bool
+
+	jobs []*tuiJob
+}
+
+// newTUIModel creates the model of the interface, starting at the URL input.
+func newTUIModel(runner tuiRunner, opts *tuiOptions) *tuiModel {
+	return &tuiModel{
+		runner:    runner,
+		outputDir: opts.output,
+		fileOpts: filename.Options{
+			Normalize:        opts.restrictName,
+			StripFormatChars: opts.restrictName,
+			ReplaceEmoji:     opts.restrictName,
+			ASCIIOnly:        opts.asciiName,
+		},
+	}
+}
+
+// update applies a key press or background result to the model.
+func (m *tuiModel) update(msg any) {
+	switch msg := msg.(type) {
+	case tuiKey:
+		m.handleKey(msg)
+	case tuiVideoMsg:
+		m.handleVideo(msg)
+	case tuiJobMsg:
+		for _, job := range m.jobs {
+			if job.id == msg.id {
+				job.state, job.progress, job.err = msg.state, msg.progress, msg.err
+				if msg.path != "" {
+					job.path = msg.path
+				}
+			}
+		}
+	}
+}
+
+func (m *tuiModel) handleKey(key tuiKey) {
+	if key.typ == keyCtrlC {
+		m.quit = true
+		return
+	}
+
+	switch m.screen {
+	case tuiScreenInput:
+		switch key.typ {
+		case keyRune:
+			m.input += string(key.r)
+		case keyBackspace:
+			if _, size := utf8.DecodeLastRuneInString(m.input); size > 0 {
+				m.input = m.input[:len(m.input)-size]
+			}
+		case keyCtrlU:
+			m.input = ""
+		case keyEnter:
+			url := strings.TrimSpace(m.input)
+			if url == "" {
+				return
+			}
+			m.fetchSeq++
+			m.screen = tuiScreenLoading
+			m.status = ""
+			m.runner.fetch(m.fetchSeq, url)
+		case keyEsc:
+			if n := m.activeJobs(); n > 0 {
+				m.status = fmt.Sprintf("%d downloads in progress, press Ctrl+C to cancel them and quit", n)
+				return
+			}
+			m.quit = true
+		}
+
+	case tuiScreenLoading:
+		if key.typ == keyEsc {
+			m.screen = tuiScreenInput
+		}
+
+	case tuiScreenFormats:
+		switch key.typ {
+		case keyUp:
+			m.cursor = max(m.cursor-1, 0)
+		case keyDown:
+			m.cursor = min(m.cursor+1, len(m.formats)-1)
+		case keyLeft, keyRight:
+			m.mkv = !m.mkv
+		case keyEnter:
+			m.queueSelected()
+		case keyEsc:
+			m.screen = tuiScreenInput
+		}
+	}
+}
+
+func (m *tuiModel) handleVideo(msg tuiVideoMsg) {
+	if msg.seq != m.fetchSeq || m.screen != tuiScreenLoading {
+		return
+	}
+	m.screen = tuiScreenInput
+	if msg.err != nil {
+		m.status = "Error: " + WrapError(msg.err).Error()
+		return
+	}
+
+	var formats []youtube.DownloadOption
+	for _, option := range msg.manifest.GetDownloadOptions() {
+		if tuiSelection(msg.manifest, option, false) != nil {
+			formats = append(formats, option)
+		}
+	}
+	if len(formats) == 0 {
+		m.status = "Error: no downloadable formats for " + msg.video.Title
+		return
+	}
+
+	m.video, m.manifest, m.formats = msg.video, msg.manifest, formats
+	m.cursor = 0
+	m.screen = tuiScreenFormats
+}
+
+// queueSelected adds the format under the cursor to the download queue and
+// returns to the URL input for the next video.
+func (m *tuiModel) queueSelected() {
+	selection := tuiSelection(m.manifest, m.formats[m.cursor], m.mkv)
+	container := selection.container
+	outputFilename := filename.ApplyTemplateWithOptions(filename.DefaultTemplate, m.video, string(container), "", m.fileOpts)
+
+	job := &tuiJob{id: len(m.jobs) + 1, title: m.video.Title, path: filepath.Join(m.outputDir, outputFilename)}
+	m.jobs = append(m.jobs, job)
+	m.runner.enqueue(tuiRequest{id: job.id, video: m.video, selection: selection, path: job.path})
+
+	m.status = "Queued: " + m.video.Title
+	m.input = ""
+	m.screen = tuiScreenInput
+}
+
+// activeJobs returns the number of downloads that haven't finished.
+func (m *tuiModel) activeJobs() int {
+	n := 0
+	for _, job := range m.jobs {
+		if job.state < tuiJobDone {
+			n++
+		}
+	}
+	return n
+}
+
+// tuiSelection returns the streams to download for a format, or nil when the
+// format has no stream URL. With mkv set, formats that are muxed from separate
+// streams are written to MKV; single streams keep their own container.
+func tuiSelection(manifest *youtube.StreamManifest, option youtube.DownloadOption, mkv bool) *streamSelection {
+	switch {
+	case option.IsAudioOnly:
+		if option.AudioStream == nil || option.AudioStream.URL == "" {
+			return nil
+		}
+		return &streamSelection{quality: option.QualityLabel(), container: option.AudioStream.Container, audio: option.AudioStream}
+	case option.VideoStream == nil || option.VideoStream.URL == "":
+		return nil
+	case option.AudioStream != nil && option.AudioStream.URL != "" && option.AudioStream.URL != option.VideoStream.URL:
+		if mkv {
+			option = *manifest.AdaptForContainer(&option, youtube.ContainerMKV)
+		}
+		return &streamSelection{quality: option.QualityLabel(), container: option.Container, video: option.VideoStream, audio: option.AudioStream}
+	default:
+		return &streamSelection{quality: option.QualityLabel(), container: option.VideoStream.Container, video: option.VideoStream}
+	}
+}
+
+// view draws the interface.
+func (m *tuiModel) view() string {
+	var b strings.Builder
+	b.WriteString("ytdl - paste a YouTube URL and press Enter\n\n")
+
+	switch m.screen {
+	case tuiScreenInput:
+		fmt.Fprintf(&b, "URL: %s_\n", m.input)
+	case tuiScreenLoading:
+		fmt.Fprintf(&b, "URL: %s\nFetching video info...\n", m.input)
+	case tuiScreenFormats:
+		m.viewFormats(&b)
+	}
+	if m.status != "" {
+		fmt.Fprintf(&b, "\n%s\n", m.status)
+	}
+
+	if len(m.jobs) > 0 {
+		b.WriteString("\nQueue:\n")
+		for _, job := range m.jobs {
+			b.WriteString("  " + job.line() + "\n")
+		}
+	}
+
+	b.WriteString("\n")
+	switch m.screen {
+	case tuiScreenInput:
+		b.WriteString("Enter: list formats  Ctrl+U: clear  Esc: quit")
+	case tuiScreenLoading:
+		b.WriteString("Esc: cancel")
+	case tuiScreenFormats:
+		b.WriteString("Up/Down: choose format  Left/Right: container  Enter: download  Esc: back")
+	}
+	return b.String()
+}
+
+func (m *tuiModel) viewFormats(b *strings.Builder) {
+	fmt.Fprintf(b, "%s (%s, %s)\n", m.video.Title, m.video.Author.Name, m.video.DurationString())
+	container := "source"
+	if m.mkv {
+		container = "mkv"
+	}
+	fmt.Fprintf(b, "Container: < %s >\n\n", container)
+
+	// Scroll the list to keep the cursor in view
+	start := max(0, min(m.cursor-tuiFormatRows/2, len(m.formats)-tuiFormatRows))
+	end := min(start+tuiFormatRows, len(m.formats))
+	for i := start; i < end; i++ {
+		marker := "  "
+		if i == m.cursor {
+			marker = "> "
+		}
+		selection := tuiSelection(m.manifest, m.formats[i], m.mkv)
+		b.WriteString(marker + formatLabel(selection, m.video.Duration) + "\n")
+	}
+	if end-start < len(m.formats) {
+		fmt.Fprintf(b, "  (%d of %d)\n", m.cursor+1, len(m.formats))
+	}
+}
+
+// formatLabel describes a format in the format list.
+func formatLabel(selection *streamSelection, duration time.Duration) string {
+	var codecs []string
+	if v := selection.video; v != nil {
+		codecs = append(codecs, v.VideoCodec)
+	}
+	if a := selection.audio; a != nil {
+		codecs = append(codecs, a.AudioCodec)
+	}
+	quality := selection.quality
+	if selection.video == nil && selection.audio != nil {
+		quality = fmt.Sprintf("Audio %dk", selection.audio.Bitrate/1000)
+	}
+	return fmt.Sprintf("%-10s %-5s %-28s %s", quality, selection.container, strings.Join(codecs, " + "),
+		formatSize(selection.estimatedSize(duration), selection.sizeIsExact()))
+}
+
+// line draws the job's line in the queue.
+func (j *tuiJob) line() string {
+	switch j.state {
+	case tuiJobQueued:
+		return "queued   " + j.title
+	case tuiJobDownloading:
+		return fmt.Sprintf("%s %s %s", progressBar(j.progress, multiProgressBarWidth), j.title, j.progress)
+	case tuiJobMuxing:
+		return "muxing   " + j.title
+	case tuiJobDone:
+		return fmt.Sprintf("done     %s -> %s", j.title, j.path)
+	default:
+		return fmt.Sprintf("failed   %s: %v", j.title, WrapError(j.err))
+	}
+}
+
+// tuiBackend fetches videos and runs queued downloads one at a time for the model.
+type tuiBackend struct {
+	ctx        context.Context
+	msgs       chan<- any
+	src        *sources
+	downloader download.StreamDownloader
+	muxer      MuxerFunc
+
+	mu      sync.Mutex
+	pending []tuiRequest
+	notify  chan struct{}
+}
+
+// send delivers msg to the model unless the interface has been closed.
+func (b *tuiBackend) send(msg any) {
+	select {
+	case b.msgs <- msg:
+	case <-b.ctx.Done():
+	}
+}
+
+func (b *tuiBackend) fetch(seq int, url string) {
+	go func() {
+		msg := tuiVideoMsg{seq: seq}
+		query, err := youtube.ResolveQueryContext(b.ctx, url, youtube.NewURLExpander(b.src.client))
+		switch {
+		case err != nil:
+			msg.err = fmt.Errorf("invalid URL or ID: %w", err)
+		case query.Type != youtube.QueryTypeVideo:
+			msg.err = errors.New("only single videos can be added here; use ytdl download for playlists and channels")
+		default:
+			msg.video, msg.manifest, msg.err = fetchVideo(b.ctx, io.Discard, query.VideoID, b.src)
+		}
+		b.send(msg)
+	}()
+}
+
+func (b *tuiBackend) enqueue(req tuiRequest) {
+	b.mu.Lock()
+	b.pending = append(b.pending, req)
+	b.mu.Unlock()
+
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// work runs queued downloads in order until the interface is closed.
+func (b *tuiBackend) work() {
+	for {
+		b.mu.Lock()
+		if len(b.pending) == 0 {
+			b.mu.Unlock()
+			select {
+			case <-b.notify:
+				continue
+			case <-b.ctx.Done():
+				return
+			}
+		}
+		req := b.pending[0]
+		b.pending = b.pending[1:]
+		b.mu.Unlock()
+
+		path, err := b.download(req)
+		if err != nil {
+			b.send(tuiJobMsg{id: req.id, state: tuiJobFailed, err: err})
+			continue
+		}
+		b.send(tuiJobMsg{id: req.id, state: tuiJobDone, path: path})
+	}
+}
+
+// download downloads a queued request and returns the path it was saved to.
+func (b *tuiBackend) download(req tuiRequest) (string, error) {
+	path, _, err := download.ResolveTarget(req.path, download.RenameExisting)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	selection := req.selection
+	var streams []streamTarget
+	var tempDir string
+	switch {
+	case selection.needsMux():
+		tempDir, err = download.MkdirTemp("", path)
+		if err != nil {
+			return "", fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tempDir) }()
+		streams = []streamTarget{
+			{name: "Video", url: selection.video.URL, path: filepath.Join(tempDir, "video."+string(selection.video.Container)), index: selection.video.IndexRange},
+			{name: "Audio", url: selection.audio.URL, path: filepath.Join(tempDir, "audio."+string(selection.audio.Container)), index: selection.audio.IndexRange},
+		}
+	case selection.video != nil:
+		streams = []streamTarget{{name: "Video", url: selection.video.URL, path: path, index: selection.video.IndexRange}}
+	default:
+		streams = []streamTarget{{name: "Audio", url: selection.audio.URL, path: path, index: selection.audio.IndexRange}}
+	}
+
+	var mu sync.Mutex
+	progress := make([]download.Progress, len(streams))
+	callback := func(i int) download.ProgressCallback {
+		return func(p download.Progress) {
+			mu.Lock()
+			progress[i] = p
+			total := combineProgress(progress)
+			mu.Unlock()
+			b.send(tuiJobMsg{id: req.id, state: tuiJobDownloading, progress: total})
+		}
+	}
+	b.send(tuiJobMsg{id: req.id, state: tuiJobDownloading})
+	// Queued requests can wait long enough for their stream URLs to expire
+	downloader := b.downloader
+	if b.src != nil {
+		downloader = download.WithRefresher(downloader, ytdl.FetcherRefresher(b.src.videos, youtube.VideoID(req.video.ID)))
+	}
+	if err := downloadStreams(b.ctx, downloader, streams, callback); err != nil {
+		return "", err
+	}
+
+	if selection.needsMux() {
+		b.send(tuiJobMsg{id: req.id, state: tuiJobMuxing})
+		muxedPath := filepath.Join(tempDir, "muxed"+filepath.Ext(path))
+		if err := b.muxer(b.ctx, streams[0].path, streams[1].path, muxedPath, req.video.Duration, nil); err != nil {
+			return "", fmt.Errorf("failed to mux streams: %w", err)
+		}
+		if err := download.MoveFile(muxedPath, path); err != nil {
+			return "", fmt.Errorf("failed to move muxed file into place: %w", err)
+		}
+	}
+	return path, nil
+}
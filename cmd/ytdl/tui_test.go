@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// fakeTUIRunner records the work the model asks for.
+type fakeTUIRunner struct {
+	fetches  []string
+	requests []tuiRequest
+}
+
+func (r *fakeTUIRunner) fetch(_ int, url string) { r.fetches = append(r.fetches, url) }
+
+func (r *fakeTUIRunner) enqueue(req tuiRequest) { r.requests = append(r.requests, req) }
+
+func tuiTestManifest() *youtube.StreamManifest {
+	return &youtube.StreamManifest{
+		VideoStreams: []youtube.VideoStreamInfo{{
+			StreamInfo: youtube.StreamInfo{URL: "https://example.com/video", Quality: "1080p", Container: youtube.ContainerMP4, ContentLength: 1000},
+			Height:     1080,
+			VideoCodec: "avc1",
+		}},
+		AudioStreams: []youtube.AudioStreamInfo{{
+			StreamInfo: youtube.StreamInfo{URL: "https://example.com/audio", Container: youtube.ContainerMP4, Bitrate: 128000, ContentLength: 100},
+			AudioCodec: "mp4a",
+		}},
+	}
+}
+
+func typeKeys(m *tuiModel, s string) {
+	for _, key := range parseKeys([]byte(s)) {
+		m.update(key)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("aé\x1b[A\x1b[B\x1bOC\x1b[D\x1b[3~\r\x7f\x03\x15\x01\x1b"))
+	want := []tuiKey{
+		{typ: keyRune, r: 'a'}, {typ: keyRune, r: 'é'},
+		{typ: keyUp}, {typ: keyDown}, {typ: keyRight}, {typ: keyLeft},
+		{typ: keyEnter}, {typ: keyBackspace}, {typ: keyCtrlC}, {typ: keyCtrlU},
+		{typ: keyEsc},
+	}
+	if len(keys) != len(want) {
+		t.Fatalf("parseKeys() = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("key %d = %v, want %v", i, keys[i], want[i])
+		}
+	}
+}
+
+func TestTUIModel_QueueDownload(t *testing.T) {
+	runner := &fakeTUIRunner{}
+	m := newTUIModel(runner, &tuiOptions{output: "out"})
+
+	typeKeys(m, "dQw4w9WgXcQx\x7f\r")
+	if len(runner.fetches) != 1 || runner.fetches[0] != "dQw4w9WgXcQ" {
+		t.Fatalf("fetches = %v", runner.fetches)
+	}
+	if m.screen != tuiScreenLoading || !strings.Contains(m.view(), "Fetching video info") {
+		t.Fatalf("expected loading screen, got %q", m.view())
+	}
+
+	video := &youtube.Video{ID: "dQw4w9WgXcQ", Title: "Test Video", Duration: time.Minute}
+	m.update(tuiVideoMsg{seq: 1, video: video, manifest: tuiTestManifest()})
+	if m.screen != tuiScreenFormats {
+		t.Fatalf("expected format list, got %q", m.view())
+	}
+	view := m.view()
+	if !strings.Contains(view, "> 1080p") || !strings.Contains(view, "avc1 + mp4a") || !strings.Contains(view, "Audio 128k") {
+		t.Errorf("unexpected format list:\n%s", view)
+	}
+
+	// Choose the muxed format in MKV
+	typeKeys(m, "\x1b[B\x1b[A\x1b[C\r")
+	if len(runner.requests) != 1 {
+		t.Fatalf("requests = %v", runner.requests)
+	}
+	req := runner.requests[0]
+	if !req.selection.needsMux() || req.selection.container != youtube.ContainerMKV {
+		t.Errorf("selection = %+v, want video and audio muxed to mkv", req.selection)
+	}
+	if req.path != filepath.Join("out", "Test Video.mkv") {
+		t.Errorf("path = %q", req.path)
+	}
+	if m.screen != tuiScreenInput || m.input != "" {
+		t.Errorf("expected an empty URL input after queueing, got %q", m.view())
+	}
+
+	m.update(tuiJobMsg{id: req.id, state: tuiJobDownloading, progress: download.Progress{Downloaded: 550, Total: 1100}})
+	if view := m.view(); !strings.Contains(view, "Test Video 550 B / 1.1 KiB (50.0%)") {
+		t.Errorf("expected job progress in queue:\n%s", view)
+	}
+
+	// Esc doesn't quit while a download is running
+	typeKeys(m, "\x1b")
+	if m.quit || !strings.Contains(m.view(), "1 downloads in progress") {
+		t.Errorf("expected warning instead of quitting, got %q", m.view())
+	}
+
+	m.update(tuiJobMsg{id: req.id, state: tuiJobDone, path: "out/Test Video (1).mkv"})
+	if view := m.view(); !strings.Contains(view, "done     Test Video -> out/Test Video (1).mkv") {
+		t.Errorf("expected finished job in queue:\n%s", view)
+	}
+	typeKeys(m, "\x1b")
+	if !m.quit {
+		t.Error("expected Esc to quit once downloads are finished")
+	}
+}
+
+func TestTUIModel_FetchError(t *testing.T) {
+	m := newTUIModel(&fakeTUIRunner{}, &tuiOptions{})
+	typeKeys(m, "bad\r")
+	m.update(tuiVideoMsg{seq: 1, err: youtube.ErrInvalidVideoID})
+	if m.screen != tuiScreenInput || !strings.Contains(m.view(), "Error: ") {
+		t.Errorf("expected error on the input screen, got %q", m.view())
+	}
+}
+
+func TestTUIModel_CanceledFetchIgnored(t *testing.T) {
+	m := newTUIModel(&fakeTUIRunner{}, &tuiOptions{})
+	typeKeys(m, "first\r\x1b")
+	typeKeys(m, "\x15second\r")
+
+	m.update(tuiVideoMsg{seq: 1, video: &youtube.Video{Title: "First"}, manifest: tuiTestManifest()})
+	if m.screen != tuiScreenLoading {
+		t.Errorf("expected the abandoned fetch to be ignored, got %q", m.view())
+	}
+}
+
+func TestTUIModel_CtrlC(t *testing.T) {
+	m := newTUIModel(&fakeTUIRunner{}, &tuiOptions{})
+	m.jobs = []*tuiJob{{id: 1, state: tuiJobDownloading}}
+	typeKeys(m, "\x03")
+	if !m.quit {
+		t.Error("expected Ctrl+C to quit")
+	}
+}
+
+func TestTUISelection(t *testing.T) {
+	manifest := tuiTestManifest()
+	options := manifest.GetDownloadOptions()
+
+	muxed := tuiSelection(manifest, options[0], false)
+	if !muxed.needsMux() || muxed.container != youtube.ContainerMP4 {
+		t.Errorf("selection = %+v, want mp4 video and audio", muxed)
+	}
+
+	audio := tuiSelection(manifest, options[len(options)-1], true)
+	if audio.video != nil || audio.audio == nil || audio.container != youtube.ContainerMP4 {
+		t.Errorf("audio selection = %+v, want the audio stream in its own container", audio)
+	}
+
+	if tuiSelection(manifest, youtube.DownloadOption{VideoStream: &youtube.VideoStreamInfo{}}, false) != nil {
+		t.Error("expected nil for a format without a URL")
+	}
+}
+
+func TestTUIBackend_Download(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "video.mp4")
+	if err := os.WriteFile(existing, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	msgs := make(chan any, 100)
+	var muxed []string
+	backend := &tuiBackend{
+		ctx:        ctx,
+		msgs:       msgs,
+		downloader: download.NewDownloader(server.Client()),
+		muxer: func(_ context.Context, videoPath, audioPath, outputPath string, _ time.Duration, _ ffmpeg.ProgressCallback) error {
+			muxed = append(muxed, filepath.Base(videoPath), filepath.Base(audioPath))
+			return os.WriteFile(outputPath, []byte("muxed"), 0o644)
+		},
+	}
+
+	selection := &streamSelection{
+		container: youtube.ContainerMP4,
+		video:     &youtube.VideoStreamInfo{StreamInfo: youtube.StreamInfo{URL: server.URL + "/video", Container: youtube.ContainerMP4}},
+		audio:     &youtube.AudioStreamInfo{StreamInfo: youtube.StreamInfo{URL: server.URL + "/audio", Container: youtube.ContainerMP4}},
+	}
+	path, err := backend.download(tuiRequest{id: 1, video: &youtube.Video{}, selection: selection, path: existing})
+	if err != nil {
+		t.Fatalf("download() error = %v", err)
+	}
+	if path != filepath.Join(dir, "video (1).mp4") {
+		t.Errorf("path = %q, want the existing file kept", path)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "muxed" {
+		t.Errorf("output = %q", data)
+	}
+	if strings.Join(muxed, ",") != "video.mp4,audio.mp4" {
+		t.Errorf("muxed = %v", muxed)
+	}
+
+	var states []tuiJobState
+	for len(msgs) > 0 {
+		states = append(states, (<-msgs).(tuiJobMsg).state)
+	}
+	if states[0] != tuiJobDownloading || states[len(states)-1] != tuiJobMuxing {
+		t.Errorf("states = %v, want downloading then muxing", states)
+	}
+
+	audioOnly := &streamSelection{audio: selection.audio}
+	path, err = backend.download(tuiRequest{id: 2, video: &youtube.Video{}, selection: audioOnly, path: filepath.Join(dir, "sub", "audio.m4a")})
+	if err != nil {
+		t.Fatalf("download() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "/audio" {
+		t.Errorf("audio output = %q", data)
+	}
+}
+
+func TestTUICommandRequiresTerminal(t *testing.T) {
+	cmd := newRootCmd()
+	cmd.SetArgs([]string{"tui"})
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetOut(&strings.Builder{})
+	cmd.SetErr(&strings.Builder{})
+	if err := cmd.Execute(); !errors.Is(err, errNotTerminal) {
+		t.Errorf("Execute() error = %v, want %v", err, errNotTerminal)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/storage"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// openUploadStorage opens the storage named by --upload-to, if any. S3 requests
+// go through client, so they use the same proxy and network settings.
+func openUploadStorage(opts *downloadOptions, client *http.Client) error {
+	if opts.uploadTo == "" {
+		return nil
+	}
+	upload, err := storage.Open(opts.uploadTo, client)
+	if err != nil {
+		return fmt.Errorf("invalid --upload-to: %w", err)
+	}
+	opts.upload = upload
+	return nil
+}
+
+// validateUpload checks that the options can be combined with --upload-to.
+// Downloads are streamed to the storage as they are muxed, so steps that need
+// the finished file on disk can't run.
+func validateUpload(opts *downloadOptions) error {
+	if opts.upload == nil {
+		return nil
+	}
+	switch {
+	case opts.output == stdoutOutput:
+		return errors.New("--upload-to cannot be used with --output -")
+	case opts.splitSize != "":
+		return errors.New("--split-size cannot be used with --upload-to")
+	case opts.section != "":
+		return errors.New("--section cannot be used with --upload-to")
+	case opts.recodeVideo != "":
+		return errors.New("--recode-video cannot be used with --upload-to")
+	case len(opts.exec) > 0:
+		return errors.New("--exec cannot be used with --upload-to")
+	case opts.verifyOutput:
+		return errors.New("--verify-output cannot be used with --upload-to")
+	case opts.keepSeparate:
+		return errors.New("--keep-separate cannot be used with --upload-to")
+	case opts.splitChapters:
+		return errors.New("--split-chapters cannot be used with --upload-to")
+	case opts.normalizeAudio:
+		return errors.New("--normalize-audio cannot be used with --upload-to")
+	case opts.embedLyrics:
+		return errors.New("--embed-lyrics cannot be used with --upload-to")
+	case opts.musicMetadata != "":
+		return errors.New("--music-metadata cannot be used with --upload-to")
+	case opts.replayGain:
+		return errors.New("--replaygain cannot be used with --upload-to")
+	case opts.writeStoryboards:
+		return errors.New("--write-storyboards cannot be used with --upload-to")
+	}
+	return nil
+}
+
+// storageKey returns the key a download is uploaded under: its path relative to
+// the output directory, so file name templates with directories keep them.
+func storageKey(outputDir, outputPath string) string {
+	rel, err := filepath.Rel(outputDir, outputPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = filepath.Base(outputPath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// uploadSelectedStreams streams the video's selected streams to opts.upload
+// under key, muxing them on the way when needed, and returns the uploaded size.
+// location is where the object ends up, shown in the status output.
+func uploadSelectedStreams(
+	ctx context.Context,
+	w io.Writer,
+	video *youtube.Video,
+	manifest *youtube.StreamManifest,
+	key, location string,
+	opts *downloadOptions,
+	downloader download.StreamDownloader,
+	muxer MuxerFunc,
+) (int64, error) {
+	var written int64
+	err := opts.upload.Put(ctx, key, storage.WriterToFunc(func(dst io.Writer) (int64, error) {
+		counter := &byteCounter{w: dst}
+		streamOpts := *opts
+		streamOpts.pipe = counter
+		err := downloadSelectedStreams(ctx, w, video, manifest, location, &streamOpts, downloader, muxer)
+		written = counter.n
+		return counter.n, err
+	}))
+	if err != nil {
+		return 0, fmt.Errorf("upload to %s failed: %w", location, err)
+	}
+	_, _ = fmt.Fprintf(w, "Uploaded: %s (%s)\n", location, download.FormatBytes(written))
+	return written, nil
+}
+
+// byteCounter counts the bytes written through it.
+type byteCounter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/storage"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// memoryStorage keeps uploaded objects in memory.
+type memoryStorage struct {
+	objects map[string]string
+}
+
+func (m *memoryStorage) Put(_ context.Context, key string, src io.WriterTo) error {
+	var buf bytes.Buffer
+	if _, err := src.WriteTo(&buf); err != nil {
+		return err
+	}
+	m.objects[key] = buf.String()
+	return nil
+}
+
+func (m *memoryStorage) URL(key string) string { return "mem://" + key }
+
+func TestDownloadCommandUploadTo(t *testing.T) {
+	streamContent := "fake video content for uploading"
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/watch" {
+			_, _ = w.Write([]byte(streamContent))
+			return
+		}
+		_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"dQw4w9WgXcQ","title":"Test Video","lengthSeconds":"10"},` +
+			`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[` +
+			`{"itag":18,"url":"` + server.URL + `/stream","mimeType":"video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"","height":360,"qualityLabel":"360p"}]}};</script>`))
+	}))
+	defer server.Close()
+
+	outputDir := t.TempDir()
+	upload := &memoryStorage{objects: map[string]string{}}
+	opts := &downloadOptions{output: outputDir, quality: "best", format: "mp4", upload: upload}
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+	downloader := download.NewDownloader(server.Client())
+
+	status := new(bytes.Buffer)
+	if err := runDownloadWithDeps(context.Background(), status, "dQw4w9WgXcQ", opts, fetcher, downloader, nil); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	if got := upload.objects["Test Video.mp4"]; got != streamContent {
+		t.Errorf("uploaded %v, want the stream under the file name", upload.objects)
+	}
+	if !strings.Contains(status.String(), "Uploaded: mem://Test Video.mp4") {
+		t.Errorf("status output should report the upload, got:\n%s", status.String())
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 0 {
+		t.Errorf("no files should be created in the output directory, found %d", len(entries))
+	}
+}
+
+func TestValidateUpload(t *testing.T) {
+	upload := &memoryStorage{}
+	tests := []struct {
+		name    string
+		opts    downloadOptions
+		wantErr bool
+	}{
+		{"no upload", downloadOptions{output: stdoutOutput, splitSize: "10M"}, false},
+		{"upload", downloadOptions{output: ".", upload: upload, remixSources: true}, false},
+		{"upload to stdout", downloadOptions{output: stdoutOutput, upload: upload}, true},
+		{"upload with split", downloadOptions{output: ".", upload: upload, splitSize: "10M"}, true},
+		{"upload with section", downloadOptions{output: ".", upload: upload, section: "1:00-2:00"}, true},
+		{"upload with recode", downloadOptions{output: ".", upload: upload, recodeVideo: "mkv"}, true},
+		{"upload with exec", downloadOptions{output: ".", upload: upload, exec: []string{"echo {}"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateUpload(&tt.opts); (err != nil) != tt.wantErr {
+				t.Errorf("validateUpload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStorageKey(t *testing.T) {
+	dir := filepath.Join("out", "videos")
+	tests := map[string]string{
+		filepath.Join(dir, "Test Video.mp4"):            "Test Video.mp4",
+		filepath.Join(dir, "Channel", "Test Video.mp4"): "Channel/Test Video.mp4",
+		filepath.Join("elsewhere", "Test Video.mp4"):    "Test Video.mp4",
+	}
+	for path, want := range tests {
+		if got := storageKey(dir, path); got != want {
+			t.Errorf("storageKey(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestOpenUploadStorage(t *testing.T) {
+	opts := &downloadOptions{uploadTo: "file://" + t.TempDir()}
+	if err := openUploadStorage(opts, http.DefaultClient); err != nil {
+		t.Fatalf("openUploadStorage() error = %v", err)
+	}
+	if _, ok := opts.upload.(*storage.Local); !ok {
+		t.Errorf("upload = %#v, want local storage", opts.upload)
+	}
+
+	opts = &downloadOptions{uploadTo: "ftp://example.com/videos"}
+	if err := openUploadStorage(opts, http.DefaultClient); !errors.Is(err, storage.ErrUnsupportedScheme) || !strings.Contains(err.Error(), "--upload-to") {
+		t.Errorf("error = %v, want an invalid --upload-to error", err)
+	}
+}
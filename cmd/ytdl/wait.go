@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// maxWaitDelay caps how long --wait-for-video sleeps before checking again, so a
+// premiere that is moved to an earlier time is not missed by hours.
+const maxWaitDelay = time.Hour
+
+// fetchVideoWhenAvailable fetches a video like fetchVideo, or takes it from
+// opts.prefetched if it was fetched ahead. With --wait-for-video,
+// an upcoming premiere or live stream is checked again until it can be downloaded.
+func fetchVideoWhenAvailable(
+	ctx context.Context,
+	w io.Writer,
+	videoID youtube.VideoID,
+	opts *downloadOptions,
+	src *sources,
+) (*youtube.Video, *youtube.StreamManifest, error) {
+	video, manifest, err := fetchPrefetchedVideo(ctx, w, videoID, opts, src)
+	for {
+		var upcoming *youtube.UpcomingVideoError
+		if opts.waitForVideo <= 0 || !errors.As(err, &upcoming) {
+			return video, manifest, err
+		}
+
+		delay := waitDelay(upcoming.ScheduledStart, time.Now(), opts.waitForVideo)
+		_, _ = fmt.Fprintf(w, "Waiting for video: %v; checking again in %s\n", upcoming, delay.Round(time.Second))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, ctx.Err()
+		case <-timer.C:
+		}
+		video, manifest, err = fetchVideo(ctx, w, videoID, src)
+	}
+}
+
+// fetchPrefetchedVideo returns the video fetched ahead of its download by
+// opts.prefetched, or fetches it now if it wasn't.
+func fetchPrefetchedVideo(
+	ctx context.Context,
+	w io.Writer,
+	videoID youtube.VideoID,
+	opts *downloadOptions,
+	src *sources,
+) (*youtube.Video, *youtube.StreamManifest, error) {
+	result, ok, err := opts.prefetched.take(ctx, videoID)
+	if !ok {
+		return fetchVideo(ctx, w, videoID, src)
+	}
+	_, _ = fmt.Fprintf(w, "Fetched video info ahead: %s\n", videoID)
+	if err != nil {
+		return nil, nil, err
+	}
+	video, manifest := showVideo(ctx, w, videoID, result)
+	return video, manifest, nil
+}
+
+// waitDelay returns how long to wait before checking an upcoming video again:
+// until its scheduled start, but no longer than maxWaitDelay and no less than interval.
+func waitDelay(start, now time.Time, interval time.Duration) time.Duration {
+	delay := min(start.Sub(now), maxWaitDelay)
+	return max(delay, interval)
+}
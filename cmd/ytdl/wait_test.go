@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// newPremiereTestServer serves a premiere that becomes available after it was checked offline times.
+func newPremiereTestServer(t *testing.T, offline int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var checks atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if checks.Add(1) <= offline {
+			_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"dQw4w9WgXcQ","title":"Premiere","lengthSeconds":"0"},` +
+				`"playabilityStatus":{"status":"LIVE_STREAM_OFFLINE","reason":"Premieres in 1 minute","liveStreamability":{"liveStreamabilityRenderer":` +
+				`{"offlineSlate":{"liveStreamOfflineSlateRenderer":{"scheduledStartTime":"1000000000"}}}}}};</script>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"videoId":"dQw4w9WgXcQ","title":"Premiere","lengthSeconds":"60"},` +
+			`"playabilityStatus":{"status":"OK"},"streamingData":{"formats":[]}};</script>`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &checks
+}
+
+func TestWaitDelay(t *testing.T) {
+	now := time.Unix(1000000000, 0)
+	tests := []struct {
+		name  string
+		start time.Time
+		want  time.Duration
+	}{
+		{"unknown start", time.Time{}, time.Minute},
+		{"start passed", now.Add(-time.Hour), time.Minute},
+		{"starts soon", now.Add(10 * time.Minute), 10 * time.Minute},
+		{"starts tomorrow", now.Add(24 * time.Hour), maxWaitDelay},
+		{"starts within interval", now.Add(10 * time.Second), time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := waitDelay(tt.start, now, time.Minute); got != tt.want {
+				t.Errorf("waitDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchVideoWhenAvailable_WaitsForPremiere(t *testing.T) {
+	server, checks := newPremiereTestServer(t, 2)
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+
+	buf := new(bytes.Buffer)
+	opts := &downloadOptions{waitForVideo: time.Millisecond}
+	video, _, err := fetchVideoWhenAvailable(context.Background(), buf, "dQw4w9WgXcQ", opts, fetcher)
+	if err != nil {
+		t.Fatalf("fetchVideoWhenAvailable failed: %v", err)
+	}
+	if video.Title != "Premiere" || checks.Load() != 3 {
+		t.Errorf("expected the video after 3 checks, got %q after %d", video.Title, checks.Load())
+	}
+	if got := strings.Count(buf.String(), "Waiting for video"); got != 2 {
+		t.Errorf("expected 2 waiting messages, got %d:\n%s", got, buf.String())
+	}
+}
+
+func TestFetchVideoWhenAvailable_NoWait(t *testing.T) {
+	server, _ := newPremiereTestServer(t, 1)
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+
+	_, _, err := fetchVideoWhenAvailable(context.Background(), new(bytes.Buffer), "dQw4w9WgXcQ", &downloadOptions{}, fetcher)
+	var upcoming *youtube.UpcomingVideoError
+	if !errors.As(err, &upcoming) {
+		t.Fatalf("expected UpcomingVideoError, got %v", err)
+	}
+	if !upcoming.ScheduledStart.Equal(time.Unix(1000000000, 0)) {
+		t.Errorf("unexpected scheduled start %v", upcoming.ScheduledStart)
+	}
+}
+
+func TestFetchVideoWhenAvailable_Canceled(t *testing.T) {
+	server, _ := newPremiereTestServer(t, 100)
+	fetcher := newSources(&youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, _, err := fetchVideoWhenAvailable(ctx, new(bytes.Buffer), "dQw4w9WgXcQ", &downloadOptions{waitForVideo: time.Hour}, fetcher)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
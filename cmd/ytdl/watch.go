@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+)
+
+const (
+	// defaultWatchInterval is how often serve --watch looks for new trigger files.
+	defaultWatchInterval = 2 * time.Second
+
+	// watchSettleTime is how long a trigger file must go unmodified before it's
+	// read, so a file still being written isn't read half-way.
+	watchSettleTime = time.Second
+
+	// failedTriggerSuffix is appended to trigger files whose downloads failed,
+	// which keeps them out of later scans.
+	failedTriggerSuffix = ".failed"
+)
+
+// partialFileSuffixes are the suffixes of files still being written by
+// browsers and download managers, which are left alone until renamed.
+var partialFileSuffixes = []string{".tmp", ".part", ".crdownload", ".download"}
+
+// folderWatcher queues the URLs of trigger files dropped into a directory.
+// Once all downloads of a file are done, the file is removed, or moved to the
+// archive directory if there is one; if any fails, it's renamed with
+// failedTriggerSuffix.
+type folderWatcher struct {
+	dir      string
+	archive  string
+	queue    *jobQueue
+	interval time.Duration
+	settle   time.Duration
+	w        io.Writer
+	now      func() time.Time
+
+	mu     sync.Mutex
+	active map[string]bool // Trigger files whose downloads are running
+	wg     sync.WaitGroup
+}
+
+// newFolderWatcher creates a watcher of dir queueing into queue and logging to w.
+func newFolderWatcher(dir, archive string, queue *jobQueue, interval time.Duration, w io.Writer) *folderWatcher {
+	return &folderWatcher{
+		dir:      dir,
+		archive:  archive,
+		queue:    queue,
+		interval: interval,
+		settle:   watchSettleTime,
+		w:        w,
+		now:      time.Now,
+		active:   make(map[string]bool),
+	}
+}
+
+// run scans the directory every interval until ctx is canceled. Trigger files
+// whose downloads don't finish before then are left in place for the next run.
+func (fw *folderWatcher) run(ctx context.Context) {
+	defer fw.wg.Wait()
+	ticker := time.NewTicker(fw.interval)
+	defer ticker.Stop()
+	for {
+		fw.scan(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// scan queues the downloads of the trigger files that appeared since the last scan.
+func (fw *folderWatcher) scan(ctx context.Context) {
+	entries, err := os.ReadDir(fw.dir)
+	if err != nil {
+		_, _ = fmt.Fprintf(fw.w, "Warning: failed to read watched directory: %v\n", err)
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !isTriggerFile(name) || fw.isActive(name) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || fw.now().Sub(info.ModTime()) < fw.settle {
+			continue
+		}
+
+		path := filepath.Join(fw.dir, name)
+		urls, err := readTriggerFile(path)
+		if err != nil {
+			_, _ = fmt.Fprintf(fw.w, "Warning: failed to read %s: %v\n", displayText(name), err)
+			continue
+		}
+		ids := make([]string, len(urls))
+		for i, url := range urls {
+			ids[i] = fw.queue.enqueue(downloadRequest{URL: url}).ID
+		}
+		_, _ = fmt.Fprintf(fw.w, "Queued %d downloads from %s\n", len(ids), displayText(name))
+
+		fw.setActive(name, true)
+		fw.wg.Add(1)
+		go func() {
+			defer fw.wg.Done()
+			defer fw.setActive(name, false)
+			fw.settleTrigger(ctx, name, ids)
+		}()
+	}
+}
+
+// settleTrigger waits for the downloads of a trigger file and then archives,
+// removes or marks the file as failed.
+func (fw *folderWatcher) settleTrigger(ctx context.Context, name string, ids []string) {
+	failed := 0
+	for _, id := range ids {
+		status, ok := fw.awaitJob(ctx, id)
+		if ctx.Err() != nil {
+			return
+		}
+		if ok && status != jobDone {
+			failed++
+		}
+	}
+
+	path := filepath.Join(fw.dir, name)
+	if failed > 0 {
+		_, _ = fmt.Fprintf(fw.w, "%d of %d downloads from %s failed; renamed it to %s\n",
+			failed, len(ids), displayText(name), displayText(name+failedTriggerSuffix))
+		if err := os.Rename(path, path+failedTriggerSuffix); err != nil {
+			_, _ = fmt.Fprintf(fw.w, "Warning: %v\n", err)
+		}
+		return
+	}
+
+	var err error
+	if fw.archive != "" {
+		if err = os.MkdirAll(fw.archive, 0o755); err == nil {
+			err = download.MoveFile(path, filepath.Join(fw.archive, name))
+		}
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(fw.w, "Warning: failed to clean up %s: %v\n", displayText(name), err)
+		return
+	}
+	_, _ = fmt.Fprintf(fw.w, "Finished the downloads from %s\n", displayText(name))
+}
+
+// awaitJob waits until a job is finished and returns its status. A job removed
+// from the queue, which only finished jobs can be, reports false.
+func (fw *folderWatcher) awaitJob(ctx context.Context, id string) (jobStatus, bool) {
+	for {
+		view, changed, ok := fw.queue.snapshot(id)
+		if !ok {
+			return "", false
+		}
+		if view.Status.finished() {
+			return view.Status, true
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return "", false
+		}
+	}
+}
+
+func (fw *folderWatcher) isActive(name string) bool {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return fw.active[name]
+}
+
+func (fw *folderWatcher) setActive(name string, active bool) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if active {
+		fw.active[name] = true
+	} else {
+		delete(fw.active, name)
+	}
+}
+
+// isTriggerFile reports whether a file in the watched directory should be read:
+// hidden files, files still being written and failed trigger files are not.
+func isTriggerFile(name string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, failedTriggerSuffix) {
+		return false
+	}
+	for _, suffix := range partialFileSuffixes {
+		if strings.HasSuffix(strings.ToLower(name), suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// readTriggerFile returns the URLs in a trigger file: one per line as in a
+// batch file, or the URL of an Internet shortcut (.url) file.
+func readTriggerFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	lines, err := readBatchFile(f)
+	if err != nil || !strings.EqualFold(filepath.Ext(path), ".url") {
+		return lines, err
+	}
+	var urls []string
+	for _, line := range lines {
+		if key, value, ok := strings.Cut(line, "="); ok && strings.EqualFold(strings.TrimSpace(key), "URL") {
+			urls = append(urls, strings.TrimSpace(value))
+		}
+	}
+	return urls, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestWatcher returns a watcher of a new directory that reads files as soon
+// as they appear.
+func newTestWatcher(t *testing.T, queue *jobQueue, archive string) (*folderWatcher, *bytes.Buffer) {
+	t.Helper()
+	var log bytes.Buffer
+	fw := newFolderWatcher(t.TempDir(), archive, queue, 10*time.Millisecond, &log)
+	fw.settle = 0
+	return fw, &log
+}
+
+// queuedJobs returns the number of jobs ever queued.
+func queuedJobs(queue *jobQueue) int {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	return len(queue.jobs)
+}
+
+// waitForFile waits until path exists, or doesn't when exists is false.
+func waitForFile(t *testing.T, path string, exists bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := os.Stat(path)
+		if (err == nil) == exists {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s exists = %v, want %v", path, err == nil, exists)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFolderWatcher_QueuesAndArchives(t *testing.T) {
+	queue, output := newTestJobQueue(t, 2)
+	archive := filepath.Join(t.TempDir(), "done")
+	fw, log := newTestWatcher(t, queue, archive)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		fw.run(ctx)
+		close(done)
+	}()
+
+	trigger := filepath.Join(fw.dir, "links.txt")
+	if err := os.WriteFile(trigger, []byte("# saved links\ndQw4w9WgXcQ\nhttps://youtu.be/jNQXAC9IVRw\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(fw.dir, "more.txt.crdownload"), []byte("9bZkp7q19f0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForFile(t, filepath.Join(archive, "links.txt"), true)
+	cancel()
+	<-done
+	if _, err := os.Stat(trigger); !os.IsNotExist(err) {
+		t.Errorf("trigger file left in the watched directory")
+	}
+	for _, name := range []string{"Video dQw4w9WgXcQ.mp4", "Video jNQXAC9IVRw.mp4"} {
+		if _, err := os.Stat(filepath.Join(output, name)); err != nil {
+			t.Errorf("%s not downloaded: %v", name, err)
+		}
+	}
+	if got := queuedJobs(queue); got != 2 {
+		t.Errorf("queued %d downloads, want 2 without the partial file", got)
+	}
+	if !strings.Contains(log.String(), "Queued 2 downloads from links.txt") {
+		t.Errorf("log = %q", log.String())
+	}
+}
+
+func TestFolderWatcher_FailedDownload(t *testing.T) {
+	queue, _ := newTestJobQueue(t, 1)
+	fw, log := newTestWatcher(t, queue, "")
+
+	trigger := filepath.Join(fw.dir, "links.txt")
+	if err := os.WriteFile(trigger, []byte("dQw4w9WgXcQ\nhttps://example.com/not-youtube\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fw.scan(ctx)
+	fw.wg.Wait()
+
+	if _, err := os.Stat(trigger + failedTriggerSuffix); err != nil {
+		t.Errorf("failed trigger file not renamed: %v", err)
+	}
+	if !strings.Contains(log.String(), "1 of 2 downloads from links.txt failed") {
+		t.Errorf("log = %q", log.String())
+	}
+
+	// A failed trigger file isn't read again
+	fw.scan(ctx)
+	if got := queuedJobs(queue); got != 2 {
+		t.Errorf("queued %d downloads, want 2", got)
+	}
+}
+
+func TestFolderWatcher_RemovesWithoutArchive(t *testing.T) {
+	queue, _ := newTestJobQueue(t, 1)
+	fw, _ := newTestWatcher(t, queue, "")
+
+	trigger := filepath.Join(fw.dir, "video.url")
+	if err := os.WriteFile(trigger, []byte("[InternetShortcut]\nURL=https://www.youtube.com/watch?v=dQw4w9WgXcQ\nIconIndex=0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fw.scan(context.Background())
+	fw.wg.Wait()
+
+	if _, err := os.Stat(trigger); !os.IsNotExist(err) {
+		t.Errorf("finished trigger file not removed")
+	}
+	if view, _, ok := queue.snapshot("1"); !ok || view.Request.URL != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" || view.Status != jobDone {
+		t.Errorf("queued %+v", view)
+	}
+}
+
+func TestFolderWatcher_WaitsForFileToSettle(t *testing.T) {
+	queue, _ := newTestJobQueue(t, 1)
+	fw, _ := newTestWatcher(t, queue, "")
+	fw.settle = time.Minute
+
+	if err := os.WriteFile(filepath.Join(fw.dir, "links.txt"), []byte("dQw4w9WgXcQ\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fw.scan(context.Background())
+	if queuedJobs(queue) != 0 {
+		t.Error("read a file that is still being written")
+	}
+
+	fw.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	fw.scan(context.Background())
+	fw.wg.Wait()
+	if got := queuedJobs(queue); got != 1 {
+		t.Errorf("queued %d downloads once the file settled, want 1", got)
+	}
+}
+
+func TestIsTriggerFile(t *testing.T) {
+	var got []string
+	for _, name := range []string{"links.txt", "video.url", ".hidden", "links.txt.failed", "a.part", "b.TMP", "c.crdownload", "urls"} {
+		if isTriggerFile(name) {
+			got = append(got, name)
+		}
+	}
+	if want := []string{"links.txt", "video.url", "urls"}; !slices.Equal(got, want) {
+		t.Errorf("trigger files = %v, want %v", got, want)
+	}
+}
+
+func TestServe_WatchFlags(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--watch-archive", dir}, "--watch-archive requires --watch"},
+		{[]string{"--watch", filepath.Join(dir, "missing")}, "--watch must be an existing directory"},
+		{[]string{"--watch", filepath.Join(dir, "403", "404")}, "--watch must be an existing directory"},
+		{[]string{"--watch", dir, "--watch-interval", "0s"}, "--watch-interval must be positive"},
+	}
+	for _, tt := range tests {
+		cmd := newServeCmd()
+		cmd.SetArgs(append([]string{"--addr", "127.0.0.1:0"}, tt.args...))
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("serve %v = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
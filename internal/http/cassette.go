@@ -0,0 +1,291 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrNotRecorded is returned when a cassette is replayed and has no response
+// for a request.
+var ErrNotRecorded = errors.New("request not recorded in cassette")
+
+// CassetteMode selects whether a cassette records responses or replays them.
+type CassetteMode int
+
+const (
+	// CassetteReplay answers requests from the cassette without touching the network.
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord sends requests over the network and records the responses.
+	CassetteRecord
+)
+
+// scrubbedParams are the query parameters replaced by scrubbedValue in recorded
+// URLs and bodies: stream URLs carry the IP address they were requested from
+// and the PO token of the session.
+var scrubbedParams = []string{"ip", "pot"}
+
+// scrubbedValue replaces the values of scrubbedParams.
+const scrubbedValue = "REDACTED"
+
+// recordedHeaders are the response headers kept in a cassette; cookies and
+// anything else about the session are dropped.
+var recordedHeaders = []string{"Content-Type", "Content-Length", "Content-Range", "Accept-Ranges", "Retry-After", "Location"}
+
+// scrubbedParamPattern matches scrubbedParams in URLs inside page bodies, where
+// they may be separated by & or, in JSON, by \u0026.
+var scrubbedParamPattern = regexp.MustCompile(`((?:[?&]|\\u0026)(?:ip|pot)=)[^&"\\\s]+`)
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Range  string `json:"range,omitempty"`
+
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+
+	// Body holds text responses such as watch pages, BodyBase64 binary ones such as streams.
+	Body       string `json:"body,omitempty"`
+	BodyBase64 []byte `json:"body_base64,omitempty"`
+}
+
+// Cassette is a set of recorded HTTP interactions, VCR style. Recording one and
+// replaying it later makes a command or test run the same requests offline,
+// getting the same watch pages and streams back. The IP addresses and PO tokens
+// in URLs are scrubbed before anything is kept.
+type Cassette struct {
+	// Interactions are the recorded interactions in the order they happened.
+	Interactions []Interaction `json:"interactions"`
+
+	mode CassetteMode
+	path string
+
+	mu sync.Mutex
+	// replayed counts how often each request was answered, so repeated
+	// requests get their recorded responses in order.
+	replayed map[string]int
+}
+
+// NewCassette returns an empty cassette in mode. A recording cassette with a
+// path is saved there after every interaction.
+func NewCassette(path string, mode CassetteMode) *Cassette {
+	return &Cassette{mode: mode, path: path}
+}
+
+// LoadCassette reads the cassette saved at path for replay.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cassette: %w", err)
+	}
+	c := NewCassette(path, CassetteReplay)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("parsing cassette %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the cassette to its path as indented JSON.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.save()
+}
+
+func (c *Cassette) save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding cassette: %w", err)
+	}
+	if err := os.WriteFile(c.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing cassette: %w", err)
+	}
+	return nil
+}
+
+// Add records a response to a GET of rawURL, for building cassettes in tests.
+func (c *Cassette) Add(rawURL string, status int, contentType, body string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	interaction := Interaction{Method: http.MethodGet, URL: ScrubURL(rawURL), Status: status}
+	if contentType != "" {
+		interaction.Header = http.Header{"Content-Type": {contentType}}
+	}
+	interaction.setBody([]byte(body))
+	c.Interactions = append(c.Interactions, interaction)
+}
+
+// Transport returns a round tripper that records the responses of base into the
+// cassette or, when replaying, answers from it and never calls base.
+func (c *Cassette) Transport(base http.RoundTripper) http.RoundTripper {
+	if c.mode == CassetteRecord {
+		return &recordingTransport{cassette: c, base: base}
+	}
+	return &replayingTransport{cassette: c}
+}
+
+// ScrubURL returns rawURL with the values of the IP address and PO token
+// parameters replaced, the form URLs are kept and matched in.
+func ScrubURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+	query := u.Query()
+	changed := false
+	for _, name := range scrubbedParams {
+		if query.Has(name) {
+			query.Set(name, scrubbedValue)
+			changed = true
+		}
+	}
+	if changed {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// scrubBody replaces the scrubbed parameters in the URLs of a text body.
+func scrubBody(body []byte) []byte {
+	return scrubbedParamPattern.ReplaceAll(body, []byte("${1}"+scrubbedValue))
+}
+
+// key identifies the requests an interaction answers.
+func (i *Interaction) key() string {
+	return i.Method + " " + i.URL + " " + i.Range
+}
+
+// requestKey returns the key of the interactions that answer req.
+func requestKey(req *http.Request) string {
+	i := Interaction{Method: req.Method, URL: ScrubURL(req.URL.String()), Range: req.Header.Get("Range")}
+	return i.key()
+}
+
+// setBody stores body as text if it is valid UTF-8 and as base64 otherwise.
+func (i *Interaction) setBody(body []byte) {
+	if utf8.Valid(body) {
+		i.Body = string(scrubBody(body))
+		return
+	}
+	i.BodyBase64 = body
+}
+
+// response returns the recorded response to req.
+func (i *Interaction) response(req *http.Request) *http.Response {
+	body := []byte(i.Body)
+	if i.BodyBase64 != nil {
+		body = i.BodyBase64
+	}
+	header := i.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(i.Status) + " " + http.StatusText(i.Status),
+		StatusCode:    i.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// replayingTransport answers requests from a cassette.
+type replayingTransport struct {
+	cassette *Cassette
+}
+
+func (t *replayingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	c := t.cassette
+	key := requestKey(req)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var matches []*Interaction
+	for i := range c.Interactions {
+		if c.Interactions[i].key() == key {
+			matches = append(matches, &c.Interactions[i])
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, req.Method, ScrubURL(req.URL.String()))
+	}
+	if c.replayed == nil {
+		c.replayed = make(map[string]int)
+	}
+	// Once every recorded response was served, the last one is repeated
+	n := min(c.replayed[key], len(matches)-1)
+	c.replayed[key]++
+	return matches[n].response(req), nil
+}
+
+// recordingTransport sends requests with base and records their responses. Each
+// body is read in full before the response is returned, so recording a stream
+// holds it in memory.
+type recordingTransport struct {
+	cassette *Cassette
+	base     http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := Interaction{
+		Method: req.Method,
+		URL:    ScrubURL(req.URL.String()),
+		Range:  req.Header.Get("Range"),
+		Status: resp.StatusCode,
+	}
+	for _, name := range recordedHeaders {
+		if values := resp.Header.Values(name); len(values) > 0 {
+			if interaction.Header == nil {
+				interaction.Header = http.Header{}
+			}
+			interaction.Header[name] = values
+		}
+	}
+	interaction.setBody(body)
+
+	c := t.cassette
+	c.mu.Lock()
+	c.Interactions = append(c.Interactions, interaction)
+	var saveErr error
+	if c.path != "" {
+		saveErr = c.save()
+	}
+	c.mu.Unlock()
+	if saveErr != nil {
+		return nil, saveErr
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// get fetches url with client and returns the status and body.
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading %s: %v", url, err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestCassette_RecordAndReplay(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		switch r.URL.Path {
+		case "/watch":
+			http.SetCookie(w, &http.Cookie{Name: "VISITOR_INFO1_LIVE", Value: "secret"})
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = io.WriteString(w, `{"url":"https://rr1.googlevideo.com/videoplayback?itag=140\u0026ip=203.0.113.7\u0026pot=token"}`)
+		case "/videoplayback":
+			_, _ = w.Write([]byte{0x00, 0xff, 0xfe, 0x01})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder, err := NewClientWithOptions(Options{Cassette: NewCassette(path, CassetteRecord)})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions failed: %v", err)
+	}
+	_, page := get(t, recorder, server.URL+"/watch?v=dQw4w9WgXcQ")
+	if !strings.Contains(page, "ip=203.0.113.7") {
+		t.Errorf("recording changed the live response: %s", page)
+	}
+	get(t, recorder, server.URL+"/videoplayback?itag=140&ip=203.0.113.7&pot=token")
+	if status, _ := get(t, recorder, server.URL+"/missing"); status != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 recorded as is", status)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"203.0.113.7", "token", "VISITOR_INFO1_LIVE"} {
+		if strings.Contains(string(saved), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, saved)
+		}
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("LoadCassette failed: %v", err)
+	}
+	replayer, err := NewClientWithOptions(Options{Cassette: cassette})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions failed: %v", err)
+	}
+	server.Close()
+	recordedHits := hits
+
+	_, page = get(t, replayer, server.URL+"/watch?v=dQw4w9WgXcQ")
+	if !strings.Contains(page, `\u0026ip=REDACTED\u0026pot=REDACTED`) {
+		t.Errorf("replayed page = %s, want the scrubbed stream URL", page)
+	}
+	// The stream is requested with the scrubbed URL from the replayed page
+	if _, body := get(t, replayer, server.URL+"/videoplayback?itag=140&ip=REDACTED&pot=REDACTED"); body != "\x00\xff\xfe\x01" {
+		t.Errorf("replayed stream = %q", body)
+	}
+	if status, _ := get(t, replayer, server.URL+"/missing"); status != http.StatusNotFound {
+		t.Errorf("replayed status = %d, want 404", status)
+	}
+	if hits != recordedHits {
+		t.Errorf("replay made %d requests to the server", hits-recordedHits)
+	}
+
+	if _, err := replayer.Get(server.URL + "/watch?v=other"); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("unrecorded request error = %v, want ErrNotRecorded", err)
+	}
+}
+
+func TestCassette_ReplaysRepeatedRequestsInOrder(t *testing.T) {
+	cassette := NewCassette("", CassetteReplay)
+	cassette.Add("https://www.youtube.com/watch?v=dQw4w9WgXcQ", http.StatusTooManyRequests, "", "slow down")
+	cassette.Add("https://www.youtube.com/watch?v=dQw4w9WgXcQ", http.StatusOK, "text/html", "page")
+	client := &http.Client{Transport: cassette.Transport(nil)}
+
+	var statuses []int
+	for range 3 {
+		status, _ := get(t, client, "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+		statuses = append(statuses, status)
+	}
+	if statuses[0] != 429 || statuses[1] != 200 || statuses[2] != 200 {
+		t.Errorf("statuses = %v, want 429 then 200 repeated", statuses)
+	}
+}
+
+func TestScrubURL(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"https://rr1.googlevideo.com/videoplayback?itag=140&ip=203.0.113.7", "https://rr1.googlevideo.com/videoplayback?ip=REDACTED&itag=140"},
+		{"https://rr1.googlevideo.com/videoplayback?pot=abc&itag=18", "https://rr1.googlevideo.com/videoplayback?itag=18&pot=REDACTED"},
+	}
+	for _, tt := range tests {
+		if got := ScrubURL(tt.url); got != tt.want {
+			t.Errorf("ScrubURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,13 @@
 package http
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 )
@@ -13,9 +19,19 @@ var Version = "dev"
 // defaultTimeout is the default timeout for HTTP requests.
 const defaultTimeout = 30 * time.Second
 
+// Connection pool defaults. Go keeps only two idle connections per host, which makes
+// parallel chunk downloads from a googlevideo.com host reconnect constantly.
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 16
+)
+
 var (
 	defaultClient     *http.Client
 	defaultClientOnce sync.Once
+
+	streamClient     *http.Client
+	streamClientOnce sync.Once
 )
 
 // UserAgent returns the User-Agent string for HTTP requests.
@@ -28,10 +44,11 @@ func UserAgent() string {
 //   - Custom User-Agent header
 //   - Accept-Language header
 //   - Reasonable timeout
+//   - Connection pool sized for parallel stream downloads
 func NewClient() *http.Client {
 	return &http.Client{
 		Timeout:   defaultTimeout,
-		Transport: &transport{base: http.DefaultTransport},
+		Transport: &transport{base: newTransport(Options{}), header: requestHeader(Options{})},
 	}
 }
 
@@ -44,22 +61,424 @@ func DefaultClient() *http.Client {
 	return defaultClient
 }
 
+// StreamClient returns a shared HTTP client for stream downloads. It uses the tuned
+// connection pool of NewClientWithOptions and has no overall timeout, since a stream
+// can take longer to download than any fixed timeout.
+func StreamClient() *http.Client {
+	streamClientOnce.Do(func() {
+		streamClient = &http.Client{Transport: &transport{base: newTransport(Options{}), header: requestHeader(Options{})}}
+	})
+	return streamClient
+}
+
+// defaultAcceptLanguage is the Accept-Language sent when none is configured.
+// English keeps the text the parsers match on stable.
+const defaultAcceptLanguage = "en-US,en;q=0.9"
+
 // transport is a custom http.RoundTripper that adds required headers.
 type transport struct {
 	base http.RoundTripper
+
+	// header holds the headers added to requests that don't set them.
+	header http.Header
+
+	// limiter, if set, paces metadata requests, and those answered with 429 are
+	// retried up to retries times.
+	limiter *Limiter
+	retries int
+
+	// rateLimited, if set, is called for every 429 a paced request gets.
+	rateLimited func()
 }
 
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	// Clone the request to avoid modifying the original
 	reqCopy := req.Clone(req.Context())
 
-	// Set required headers
-	if reqCopy.Header.Get("User-Agent") == "" {
-		reqCopy.Header.Set("User-Agent", UserAgent())
-	}
-	if reqCopy.Header.Get("Accept-Language") == "" {
-		reqCopy.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	// Headers set on the request itself take precedence
+	for name, values := range t.header {
+		if reqCopy.Header.Get(name) == "" {
+			reqCopy.Header[name] = values
+		}
 	}
 
+	if t.limiter != nil && paced(reqCopy) {
+		return t.roundTripPaced(reqCopy)
+	}
 	return t.base.RoundTrip(reqCopy)
 }
+
+// requestHeader returns the headers a client built from opts adds to its requests.
+func requestHeader(opts Options) http.Header {
+	header := make(http.Header, len(opts.Header)+2)
+	for name, values := range opts.Header {
+		header[http.CanonicalHeaderKey(name)] = values
+	}
+
+	if opts.UserAgent != "" {
+		header.Set("User-Agent", opts.UserAgent)
+	} else if header.Get("User-Agent") == "" {
+		header.Set("User-Agent", UserAgent())
+	}
+	if opts.AcceptLanguage != "" {
+		header.Set("Accept-Language", opts.AcceptLanguage)
+	} else if header.Get("Accept-Language") == "" {
+		header.Set("Accept-Language", defaultAcceptLanguage)
+	}
+	return header
+}
+
+// ParseHeader parses a header given as "Name: value".
+func ParseHeader(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid header %q (use \"Name: value\")", s)
+	}
+	return http.CanonicalHeaderKey(name), strings.TrimSpace(value), nil
+}
+
+// DefaultTorProxy is the SOCKS address of a local Tor daemon.
+const DefaultTorProxy = "socks5h://127.0.0.1:9050"
+
+// Timeouts used in restricted mode, where proxies such as Tor add significant latency.
+const (
+	restrictedDialTimeout           = 60 * time.Second
+	restrictedTLSHandshakeTimeout   = 60 * time.Second
+	restrictedResponseHeaderTimeout = 2 * time.Minute
+)
+
+var (
+	// ErrUnsupportedProxy is returned when a proxy URL has an unsupported scheme.
+	ErrUnsupportedProxy = errors.New("unsupported proxy")
+
+	// ErrNonEssentialRequest is returned for requests blocked in restricted mode,
+	// such as thumbnails and telemetry.
+	ErrNonEssentialRequest = errors.New("non-essential request blocked in restricted mode")
+
+	// ErrStreamRefused is returned in restricted mode when a stream host rejects a
+	// request made through the proxy.
+	ErrStreamRefused = errors.New("stream host refused proxied request")
+
+	// ErrUnsupportedProtocol is returned when an HTTP version can't be parsed.
+	ErrUnsupportedProtocol = errors.New("unsupported HTTP version")
+)
+
+// Protocol selects the HTTP versions a client may use.
+type Protocol string
+
+const (
+	// ProtocolAuto uses HTTP/2 when the server offers it and HTTP/1.1 otherwise.
+	ProtocolAuto Protocol = ""
+	// ProtocolHTTP1 always uses HTTP/1.1.
+	ProtocolHTTP1 Protocol = "1.1"
+	// ProtocolHTTP2 always uses HTTP/2; servers without HTTP/2 support fail.
+	ProtocolHTTP2 Protocol = "2"
+)
+
+// ParseProtocol parses an HTTP version such as "auto", "1.1", "http/1.1", "2" or "http2".
+func ParseProtocol(s string) (Protocol, error) {
+	normalized := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "http"), "/")
+	switch normalized {
+	case "", "auto":
+		return ProtocolAuto, nil
+	case "1", "1.1":
+		return ProtocolHTTP1, nil
+	case "2", "2.0":
+		return ProtocolHTTP2, nil
+	default:
+		return "", fmt.Errorf("%w %q (use auto, 1.1 or 2)", ErrUnsupportedProtocol, s)
+	}
+}
+
+// Options configures a client created by NewClientWithOptions.
+type Options struct {
+	// ProxyURL routes all traffic through the given proxy (socks5, socks5h, http or https).
+	// Empty uses the proxy from the environment, unless Restricted is set.
+	ProxyURL string
+
+	// Restricted enables read-only mode for restricted networks: all traffic goes through
+	// a single proxy (DefaultTorProxy if ProxyURL is empty), non-essential requests are
+	// blocked and timeouts are lengthened.
+	Restricted bool
+
+	// Timeout is the overall request timeout. 0 means no timeout, which is what
+	// stream downloads need.
+	Timeout time.Duration
+
+	// SocketTimeout bounds connecting, the TLS handshake and every read and write
+	// on a connection, so a request fails with ErrSocketTimeout once no data has
+	// moved for that long, however long the whole request takes. Idle keep-alive
+	// connections are closed after it too. 0 uses the dialer and TLS defaults.
+	SocketTimeout time.Duration
+
+	// Logger, if set, receives a debug record for every request.
+	Logger *slog.Logger
+
+	// MaxConnsPerHost limits the connections to a single host, counting those in use.
+	// 0 means no limit.
+	MaxConnsPerHost int
+
+	// MaxIdleConns limits the idle keep-alive connections across all hosts.
+	// 0 uses DefaultMaxIdleConns.
+	MaxIdleConns int
+
+	// MaxIdleConnsPerHost limits the idle keep-alive connections to a single host.
+	// 0 uses DefaultMaxIdleConnsPerHost.
+	MaxIdleConnsPerHost int
+
+	// Protocol forces HTTP/1.1 or HTTP/2. The default negotiates the version per server.
+	Protocol Protocol
+
+	// TLSConfig, if set, replaces the default TLS settings, for example to require
+	// TLS 1.3 or trust a custom root CA.
+	TLSConfig *tls.Config
+
+	// UserAgent replaces the default User-Agent. Stream URLs are tied to the client
+	// that requested them, so the same value is used for every request.
+	UserAgent string
+
+	// AcceptLanguage replaces the default Accept-Language of English.
+	AcceptLanguage string
+
+	// Header holds extra headers added to every request that doesn't set them itself.
+	Header http.Header
+
+	// IPVersion forces connections over IPv4 or IPv6, for networks where one of them
+	// is throttled. The default uses whichever the system prefers.
+	IPVersion IPVersion
+
+	// SourceAddress is the local IP address connections are made from. It also
+	// decides the IP version, so it must match IPVersion if both are set.
+	SourceAddress string
+
+	// GeoBypassCountry is a two-letter country code. When set, requests carry an
+	// X-Forwarded-For address from the country and YouTube requests ask for its
+	// region, to get around "not available in your country" errors.
+	GeoBypassCountry string
+
+	// Limiter paces metadata requests and holds them back after a 429 Too Many
+	// Requests. Clients given the same limiter are paced together. Nil uses a new
+	// limiter without a delay, which still backs off after a 429.
+	Limiter *Limiter
+
+	// RateLimitRetries is how many times a metadata request answered with 429 is
+	// retried, after the Retry-After pause or an exponential backoff. 0 returns
+	// the first 429.
+	RateLimitRetries int
+
+	// RateLimited, if set, is called each time a metadata request is answered
+	// with 429, including those that are then retried.
+	RateLimited func()
+
+	// Cassette, if set, records every response the client gets or, in replay
+	// mode, answers every request from its recordings without touching the
+	// network. Headers, pacing and restricted mode still apply as they would live.
+	Cassette *Cassette
+}
+
+// ParseProxyURL parses and validates a proxy URL.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedProxy, err)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http", "https":
+	default:
+		return nil, fmt.Errorf("%w: scheme %q (use socks5, socks5h, http or https)", ErrUnsupportedProxy, u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%w: missing host in %q", ErrUnsupportedProxy, raw)
+	}
+	return u, nil
+}
+
+// NewClientWithOptions creates an HTTP client like NewClient, configured with the given
+// proxy, restricted mode, connection, address and pacing settings.
+func NewClientWithOptions(opts Options) (*http.Client, error) {
+	base := newTransport(opts)
+
+	dial, err := newDialContext(opts)
+	if err != nil {
+		return nil, err
+	}
+	base.DialContext = dial
+
+	proxyURL := opts.ProxyURL
+	if proxyURL == "" && opts.Restricted {
+		proxyURL = DefaultTorProxy
+	}
+	if proxyURL != "" {
+		u, err := ParseProxyURL(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+		// A fixed proxy also means the environment can't send traffic elsewhere
+		base.Proxy = http.ProxyURL(u)
+	}
+
+	var rt http.RoundTripper = base
+	if opts.Cassette != nil {
+		rt = opts.Cassette.Transport(base)
+	}
+	if opts.Restricted {
+		base.TLSHandshakeTimeout = restrictedTLSHandshakeTimeout
+		base.ResponseHeaderTimeout = restrictedResponseHeaderTimeout
+		rt = &restrictedTransport{base: rt}
+	}
+	if opts.SocketTimeout > 0 {
+		// Reads and writes time out on their own, so a slow but moving response
+		// isn't cut off
+		base.TLSHandshakeTimeout = 0
+		base.ResponseHeaderTimeout = 0
+	}
+	if opts.Logger != nil {
+		rt = NewLoggingTransport(rt, opts.Logger)
+	}
+
+	header := requestHeader(opts)
+	if opts.GeoBypassCountry != "" {
+		ip, err := GeoBypassIP(opts.GeoBypassCountry)
+		if err != nil {
+			return nil, err
+		}
+		header.Set("X-Forwarded-For", ip)
+		rt = &geoTransport{base: rt, country: strings.ToUpper(opts.GeoBypassCountry)}
+	}
+
+	limiter := opts.Limiter
+	if limiter == nil {
+		limiter = NewLimiter(0, 0)
+	}
+
+	return &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: &transport{base: rt, header: header, limiter: limiter, retries: opts.RateLimitRetries, rateLimited: opts.RateLimited},
+	}, nil
+}
+
+// newTransport returns a copy of http.DefaultTransport with the connection pool,
+// protocol and TLS settings of opts.
+func newTransport(opts Options) *http.Transport {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+
+	base.MaxConnsPerHost = opts.MaxConnsPerHost
+	base.MaxIdleConns = DefaultMaxIdleConns
+	if opts.MaxIdleConns > 0 {
+		base.MaxIdleConns = opts.MaxIdleConns
+	}
+	base.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	if opts.MaxIdleConnsPerHost > 0 {
+		base.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+
+	if opts.TLSConfig != nil {
+		base.TLSClientConfig = opts.TLSConfig.Clone()
+	}
+
+	switch opts.Protocol {
+	case ProtocolHTTP1:
+		base.Protocols = new(http.Protocols)
+		base.Protocols.SetHTTP1(true)
+	case ProtocolHTTP2:
+		base.Protocols = new(http.Protocols)
+		base.Protocols.SetHTTP2(true)
+		base.Protocols.SetUnencryptedHTTP2(true)
+	}
+	return base
+}
+
+// IsNonEssential reports whether a request can be skipped without affecting
+// metadata extraction or stream downloads (thumbnails, avatars, telemetry).
+func IsNonEssential(req *http.Request) bool {
+	host := strings.ToLower(req.URL.Hostname())
+	if host == "ytimg.com" || strings.HasSuffix(host, ".ytimg.com") ||
+		strings.HasSuffix(host, ".ggpht.com") || strings.HasSuffix(host, ".doubleclick.net") {
+		return true
+	}
+
+	for _, prefix := range []string{"/api/stats/", "/ptracking", "/generate_204", "/youtubei/v1/log_event"} {
+		if strings.HasPrefix(req.URL.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStreamHost reports whether host serves media streams.
+func isStreamHost(host string) bool {
+	return strings.HasSuffix(strings.ToLower(host), ".googlevideo.com")
+}
+
+// restrictedTransport blocks non-essential requests and turns stream host
+// rejections into ErrStreamRefused.
+type restrictedTransport struct {
+	base http.RoundTripper
+}
+
+func (t *restrictedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if IsNonEssential(req) {
+		return nil, fmt.Errorf("%w: %s", ErrNonEssentialRequest, req.URL.Host)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if isStreamHost(req.URL.Hostname()) {
+		switch resp.StatusCode {
+		case http.StatusForbidden, http.StatusTooManyRequests, http.StatusRequestedRangeNotSatisfiable:
+			_ = resp.Body.Close()
+			kind := "request"
+			if req.Header.Get("Range") != "" {
+				kind = "ranged request"
+			}
+			return nil, fmt.Errorf("%w: %s rejected %s with %s", ErrStreamRefused, req.URL.Host, kind, resp.Status)
+		}
+	}
+
+	return resp, nil
+}
+
+// NewLoggingTransport wraps base so that each request and its outcome is
+// logged at debug level. Query strings are redacted.
+func NewLoggingTransport(base http.RoundTripper, logger *slog.Logger) http.RoundTripper {
+	return &loggingTransport{base: base, logger: logger}
+}
+
+// loggingTransport logs each request and its outcome at debug level.
+type loggingTransport struct {
+	base   http.RoundTripper
+	logger *slog.Logger
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	attrs := []any{"method", req.Method, "url", RedactURL(req.URL)}
+	if r := req.Header.Get("Range"); r != "" {
+		attrs = append(attrs, "range", r)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	attrs = append(attrs, "duration", time.Since(start).Round(time.Millisecond))
+	if err != nil {
+		t.logger.DebugContext(req.Context(), "http request failed", append(attrs, "error", err)...)
+		return nil, err
+	}
+
+	t.logger.DebugContext(req.Context(), "http request", append(attrs, "status", resp.StatusCode)...)
+	return resp, nil
+}
+
+// RedactURL returns u without its query string, which for stream URLs
+// carries signatures and the client IP.
+func RedactURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+	if redacted.RawQuery != "" {
+		redacted.RawQuery = "REDACTED"
+	}
+	return redacted.String()
+}
@@ -1,6 +1,11 @@
 package http
 
 import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -73,9 +78,331 @@ func TestClient_HasTimeout(t *testing.T) {
 	}
 }
 
+func TestStreamClient_NoTimeout(t *testing.T) {
+	client := StreamClient()
+	if client != StreamClient() {
+		t.Error("StreamClient should return the same instance")
+	}
+	if client.Timeout != 0 {
+		t.Errorf("stream client should have no timeout, got %v", client.Timeout)
+	}
+	base := client.Transport.(*transport).base.(*http.Transport)
+	if base.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost {
+		t.Errorf("expected %d idle connections per host, got %d", DefaultMaxIdleConnsPerHost, base.MaxIdleConnsPerHost)
+	}
+}
+
+func TestParseProtocol(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Protocol
+	}{
+		{"", ProtocolAuto},
+		{"auto", ProtocolAuto},
+		{"1.1", ProtocolHTTP1},
+		{"HTTP/1.1", ProtocolHTTP1},
+		{"2", ProtocolHTTP2},
+		{"http2", ProtocolHTTP2},
+	}
+	for _, tt := range tests {
+		got, err := ParseProtocol(tt.input)
+		if err != nil {
+			t.Errorf("ParseProtocol(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseProtocol(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	if _, err := ParseProtocol("3"); !errors.Is(err, ErrUnsupportedProtocol) {
+		t.Errorf("expected ErrUnsupportedProtocol, got %v", err)
+	}
+}
+
+func TestNewClientWithOptions_ConnectionSettings(t *testing.T) {
+	client, err := NewClientWithOptions(Options{
+		MaxConnsPerHost: 4,
+		MaxIdleConns:    10,
+		Protocol:        ProtocolHTTP1,
+		TLSConfig:       &tls.Config{MinVersion: tls.VersionTLS13},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions failed: %v", err)
+	}
+
+	base := client.Transport.(*transport).base.(*http.Transport)
+	if base.MaxConnsPerHost != 4 || base.MaxIdleConns != 10 || base.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost {
+		t.Errorf("unexpected pool settings: conns %d, idle %d, idle per host %d", base.MaxConnsPerHost, base.MaxIdleConns, base.MaxIdleConnsPerHost)
+	}
+	if base.Protocols == nil || !base.Protocols.HTTP1() || base.Protocols.HTTP2() {
+		t.Errorf("expected HTTP/1.1 only, got %v", base.Protocols)
+	}
+	if base.TLSClientConfig == nil || base.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Error("expected the TLS config to be applied")
+	}
+}
+
+func TestNewClientWithOptions_ForcedHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	client, err := NewClientWithOptions(Options{
+		Protocol:  ProtocolHTTP2,
+		TLSConfig: server.Client().Transport.(*http.Transport).TLSClientConfig,
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions failed: %v", err)
+	}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "HTTP/2.0" {
+		t.Errorf("expected HTTP/2.0, got %q", body)
+	}
+}
+
+func TestNewClientWithOptions_Headers(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client, err := NewClientWithOptions(Options{
+		UserAgent:      "Mozilla/5.0 Test",
+		AcceptLanguage: "de-DE",
+		Header:         http.Header{"x-client-data": {"abc"}, "Referer": {"https://example.com/"}},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions failed: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, http.NoBody)
+	req.Header.Set("Referer", "https://www.youtube.com/")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	want := map[string]string{
+		"User-Agent":      "Mozilla/5.0 Test",
+		"Accept-Language": "de-DE",
+		"X-Client-Data":   "abc",
+		"Referer":         "https://www.youtube.com/",
+	}
+	for name, value := range want {
+		if got.Get(name) != value {
+			t.Errorf("%s = %q, want %q", name, got.Get(name), value)
+		}
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	name, value, err := ParseHeader("x-goog-visitor-id:  abc:def ")
+	if err != nil {
+		t.Fatalf("ParseHeader failed: %v", err)
+	}
+	if name != "X-Goog-Visitor-Id" || value != "abc:def" {
+		t.Errorf("ParseHeader = %q, %q", name, value)
+	}
+
+	for _, invalid := range []string{"no colon", ": value", "two words: value"} {
+		if _, _, err := ParseHeader(invalid); err == nil {
+			t.Errorf("ParseHeader(%q) should fail", invalid)
+		}
+	}
+}
+
 func TestUserAgent_ContainsVersion(t *testing.T) {
 	ua := UserAgent()
 	if !strings.Contains(ua, "ytdl/") {
 		t.Errorf("UserAgent should contain 'ytdl/', got: %s", ua)
 	}
 }
+
+func TestParseProxyURL(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{"socks5h://127.0.0.1:9050", false},
+		{"socks5://localhost:1080", false},
+		{"http://proxy.example.com:8080", false},
+		{"ftp://proxy.example.com", true},
+		{"socks5://", true},
+		{"127.0.0.1:9050", true},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseProxyURL(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseProxyURL(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrUnsupportedProxy) {
+			t.Errorf("ParseProxyURL(%q) error should wrap ErrUnsupportedProxy, got %v", tt.input, err)
+		}
+	}
+}
+
+func TestNewClientWithOptions_RoutesThroughProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Requests sent to a proxy carry the absolute target URL
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	client, err := NewClientWithOptions(Options{ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions failed: %v", err)
+	}
+
+	resp, err := client.Get("http://www.youtube.invalid/watch?v=abc")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if proxied != "http://www.youtube.invalid/watch?v=abc" {
+		t.Errorf("expected request to go through proxy, proxy saw %q", proxied)
+	}
+}
+
+func TestNewClientWithOptions_InvalidProxy(t *testing.T) {
+	if _, err := NewClientWithOptions(Options{ProxyURL: "ftp://example.com"}); !errors.Is(err, ErrUnsupportedProxy) {
+		t.Errorf("expected ErrUnsupportedProxy, got %v", err)
+	}
+}
+
+func TestNewClientWithOptions_RestrictedTimeouts(t *testing.T) {
+	client, err := NewClientWithOptions(Options{Restricted: true})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions failed: %v", err)
+	}
+
+	rt, ok := client.Transport.(*transport).base.(*restrictedTransport)
+	if !ok {
+		t.Fatal("restricted client should use restrictedTransport")
+	}
+	base := rt.base.(*http.Transport)
+	if base.ResponseHeaderTimeout != restrictedResponseHeaderTimeout {
+		t.Errorf("expected response header timeout %v, got %v", restrictedResponseHeaderTimeout, base.ResponseHeaderTimeout)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://www.youtube.com/", http.NoBody)
+	proxyURL, err := base.Proxy(req)
+	if err != nil {
+		t.Fatalf("Proxy failed: %v", err)
+	}
+	if proxyURL == nil || proxyURL.String() != DefaultTorProxy {
+		t.Errorf("expected default Tor proxy, got %v", proxyURL)
+	}
+}
+
+func TestIsNonEssential(t *testing.T) {
+	tests := map[string]bool{
+		"https://i.ytimg.com/vi/abc/maxresdefault.jpg":       true,
+		"https://yt3.ggpht.com/avatar.jpg":                   true,
+		"https://www.youtube.com/api/stats/watchtime?x=1":    true,
+		"https://www.youtube.com/youtubei/v1/log_event":      true,
+		"https://www.youtube.com/watch?v=abc":                false,
+		"https://rr1---sn-abc.googlevideo.com/videoplayback": false,
+	}
+
+	for rawURL, want := range tests {
+		req, _ := http.NewRequest(http.MethodGet, rawURL, http.NoBody)
+		if got := IsNonEssential(req); got != want {
+			t.Errorf("IsNonEssential(%q) = %v, want %v", rawURL, got, want)
+		}
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRestrictedTransport_BlocksNonEssential(t *testing.T) {
+	rt := &restrictedTransport{base: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		t.Error("blocked request should not reach the network")
+		return nil, errors.New("unexpected")
+	})}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://i.ytimg.com/vi/abc/hqdefault.jpg", http.NoBody)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, ErrNonEssentialRequest) {
+		t.Errorf("expected ErrNonEssentialRequest, got %v", err)
+	}
+}
+
+func TestRestrictedTransport_StreamRefused(t *testing.T) {
+	rt := &restrictedTransport{base: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusForbidden,
+			Status:     "403 Forbidden",
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	})}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://rr1---sn-abc.googlevideo.com/videoplayback", http.NoBody)
+	req.Header.Set("Range", "bytes=0-1023")
+
+	_, err := rt.RoundTrip(req)
+	if !errors.Is(err, ErrStreamRefused) {
+		t.Fatalf("expected ErrStreamRefused, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "ranged request") {
+		t.Errorf("error should mention the ranged request, got %q", err.Error())
+	}
+}
+
+func TestLoggingTransport_LogsRequests(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	rt := &loggingTransport{logger: logger, base: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusPartialContent, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://rr1---sn-abc.googlevideo.com/videoplayback?sig=secret&ip=1.2.3.4", http.NoBody)
+	req.Header.Set("Range", "bytes=0-1023")
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip failed: %v", err)
+	}
+
+	out := logs.String()
+	for _, want := range []string{"http request", "status=206", `range="bytes=0-1023"`, "googlevideo.com/videoplayback"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log should contain %q, got %s", want, out)
+		}
+	}
+	if strings.Contains(out, "secret") || strings.Contains(out, "1.2.3.4") {
+		t.Errorf("query string should be redacted, got %s", out)
+	}
+}
+
+func TestLoggingTransport_LogsErrors(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	rt := &loggingTransport{logger: logger, base: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection reset")
+	})}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://www.youtube.com/watch?v=abc", http.NoBody)
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Fatal("expected error")
+	}
+	if out := logs.String(); !strings.Contains(out, "http request failed") || !strings.Contains(out, "connection reset") {
+		t.Errorf("failed request should be logged, got %s", out)
+	}
+}
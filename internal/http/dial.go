@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Dialer defaults, matching http.DefaultTransport.
+const (
+	defaultDialTimeout = 30 * time.Second
+	dialKeepAlive      = 30 * time.Second
+)
+
+var (
+	// ErrInvalidSourceAddress is returned when a source address is not an IP address
+	// or doesn't match the forced IP version.
+	ErrInvalidSourceAddress = errors.New("invalid source address")
+
+	// ErrUnsupportedIPVersion is returned for an IP version other than 4 or 6.
+	ErrUnsupportedIPVersion = errors.New("unsupported IP version")
+
+	// ErrSocketTimeout is returned when a connection moves no data for longer
+	// than Options.SocketTimeout. It is also a net.Error whose Timeout is true.
+	ErrSocketTimeout = errors.New("socket timed out")
+)
+
+// IPVersion selects the address family of outgoing connections.
+type IPVersion int
+
+const (
+	// IPAny connects over IPv4 or IPv6, whichever the system prefers.
+	IPAny IPVersion = 0
+	// IPv4 connects over IPv4 only.
+	IPv4 IPVersion = 4
+	// IPv6 connects over IPv6 only.
+	IPv6 IPVersion = 6
+)
+
+// network returns the dial network that restricts "tcp" to the IP version.
+func (v IPVersion) network(network string) string {
+	if network != "tcp" {
+		return network
+	}
+	switch v {
+	case IPv4:
+		return "tcp4"
+	case IPv6:
+		return "tcp6"
+	default:
+		return network
+	}
+}
+
+// newDialContext returns the dial function for a transport configured with opts:
+// restricted mode lengthens the timeout and a socket timeout replaces it, and
+// the IP version and source address decide which local address and family
+// connections use.
+func newDialContext(opts Options) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	switch opts.IPVersion {
+	case IPAny, IPv4, IPv6:
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedIPVersion, opts.IPVersion)
+	}
+
+	dialer := &net.Dialer{Timeout: defaultDialTimeout, KeepAlive: dialKeepAlive}
+	if opts.Restricted {
+		dialer.Timeout = restrictedDialTimeout
+	}
+	if opts.SocketTimeout > 0 {
+		dialer.Timeout = opts.SocketTimeout
+	}
+
+	version := opts.IPVersion
+	if opts.SourceAddress != "" {
+		ip := net.ParseIP(opts.SourceAddress)
+		if ip == nil {
+			return nil, fmt.Errorf("%w: %q is not an IP address", ErrInvalidSourceAddress, opts.SourceAddress)
+		}
+
+		// A connection can only be made from an address of its own family
+		sourceVersion := IPv6
+		if ip.To4() != nil {
+			sourceVersion = IPv4
+		}
+		if version != IPAny && version != sourceVersion {
+			return nil, fmt.Errorf("%w: %s is not an IPv%d address", ErrInvalidSourceAddress, ip, version)
+		}
+		version = sourceVersion
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, version.network(network), addr)
+		if err != nil {
+			if opts.SocketTimeout > 0 && isTimeout(err) && ctx.Err() == nil {
+				err = fmt.Errorf("%w: connecting took longer than %s: %w", ErrSocketTimeout, opts.SocketTimeout, err)
+			}
+			return nil, err
+		}
+		if opts.SocketTimeout > 0 {
+			conn = &deadlineConn{Conn: conn, timeout: opts.SocketTimeout}
+		}
+		return conn, nil
+	}, nil
+}
+
+// deadlineConn is a connection whose every read and write must make progress
+// within timeout.
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *deadlineConn) Read(p []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	n, err := c.Conn.Read(p)
+	return n, c.timeoutError(err)
+}
+
+func (c *deadlineConn) Write(p []byte) (int, error) {
+	if err := c.Conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	n, err := c.Conn.Write(p)
+	return n, c.timeoutError(err)
+}
+
+// timeoutError marks a deadline error as ErrSocketTimeout, keeping the original
+// so the error still reports itself as a timeout.
+func (c *deadlineConn) timeoutError(err error) error {
+	if err == nil || !isTimeout(err) {
+		return err
+	}
+	return fmt.Errorf("%w: no data for %s: %w", ErrSocketTimeout, c.timeout, err)
+}
+
+// isTimeout reports whether err is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
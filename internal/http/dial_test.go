@@ -0,0 +1,151 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIPVersion_Network(t *testing.T) {
+	tests := []struct {
+		version IPVersion
+		network string
+		want    string
+	}{
+		{IPAny, "tcp", "tcp"},
+		{IPv4, "tcp", "tcp4"},
+		{IPv6, "tcp", "tcp6"},
+		{IPv4, "udp", "udp"},
+	}
+	for _, tt := range tests {
+		if got := tt.version.network(tt.network); got != tt.want {
+			t.Errorf("IPVersion(%d).network(%q) = %q, want %q", tt.version, tt.network, got, tt.want)
+		}
+	}
+}
+
+func TestNewDialContext_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr error
+	}{
+		{"not an IP", Options{SourceAddress: "example.com"}, ErrInvalidSourceAddress},
+		{"IPv4 address with IPv6", Options{SourceAddress: "192.0.2.1", IPVersion: IPv6}, ErrInvalidSourceAddress},
+		{"IPv6 address with IPv4", Options{SourceAddress: "2001:db8::1", IPVersion: IPv4}, ErrInvalidSourceAddress},
+		{"unknown IP version", Options{IPVersion: 5}, ErrUnsupportedIPVersion},
+		{"matching address", Options{SourceAddress: "2001:db8::1", IPVersion: IPv6}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newDialContext(tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewClientWithOptions_ForceIPVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ipv4, err := NewClientWithOptions(Options{IPVersion: IPv4})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions failed: %v", err)
+	}
+	resp, err := ipv4.Get(server.URL)
+	if err != nil {
+		t.Fatalf("IPv4 request to %s failed: %v", server.URL, err)
+	}
+	_ = resp.Body.Close()
+
+	// The test server listens on 127.0.0.1, which can't be reached over IPv6
+	ipv6, err := NewClientWithOptions(Options{IPVersion: IPv6})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions failed: %v", err)
+	}
+	if resp, err := ipv6.Get(server.URL); err == nil {
+		_ = resp.Body.Close()
+		t.Error("expected IPv6-only request to an IPv4 address to fail")
+	}
+}
+
+func TestNewClientWithOptions_SourceAddress(t *testing.T) {
+	var remote string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote, _, _ = net.SplitHostPort(r.RemoteAddr)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClientWithOptions(Options{SourceAddress: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions failed: %v", err)
+	}
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, http.NoBody)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if remote != "127.0.0.1" {
+		t.Errorf("expected the request to come from 127.0.0.1, got %q", remote)
+	}
+}
+
+func TestNewClientWithOptions_SocketTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			// Data keeps moving, just slower than the socket timeout in total
+			for range 4 {
+				_, _ = w.Write([]byte("x"))
+				w.(http.Flusher).Flush()
+				time.Sleep(30 * time.Millisecond)
+			}
+			return
+		}
+		_, _ = w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewClientWithOptions(Options{SocketTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions failed: %v", err)
+	}
+
+	resp, err := client.Get(server.URL + "/slow")
+	if err != nil {
+		t.Fatalf("slow request failed: %v", err)
+	}
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Errorf("reading a slow but moving response failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	resp, err = client.Get(server.URL + "/stalled")
+	if err != nil {
+		t.Fatalf("stalled request failed before its body: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, err = io.ReadAll(resp.Body)
+	if !errors.Is(err, ErrSocketTimeout) {
+		t.Fatalf("reading a stalled response: error = %v, want ErrSocketTimeout", err)
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("error %v is not a net.Error timeout", err)
+	}
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ErrUnknownCountry is returned for a geo-bypass country without a known IP block.
+var ErrUnknownCountry = errors.New("unknown geo-bypass country")
+
+// geoBypassBlocks maps ISO 3166-1 alpha-2 country codes to an IPv4 block allocated
+// to that country. YouTube honors X-Forwarded-For for region checks on some requests,
+// so an address from the block makes the request appear to come from the country.
+var geoBypassBlocks = map[string]string{
+	"AE": "94.200.0.0/13",
+	"AR": "181.0.0.0/12",
+	"AT": "84.112.0.0/13",
+	"AU": "1.128.0.0/11",
+	"BE": "57.0.0.0/8",
+	"BR": "179.0.0.0/10",
+	"CA": "99.224.0.0/11",
+	"CH": "85.0.0.0/13",
+	"CL": "152.172.0.0/14",
+	"CN": "36.128.0.0/10",
+	"CZ": "88.100.0.0/14",
+	"DE": "53.0.0.0/8",
+	"DK": "87.48.0.0/12",
+	"ES": "88.0.0.0/11",
+	"FI": "91.152.0.0/13",
+	"FR": "90.0.0.0/9",
+	"GB": "25.0.0.0/8",
+	"HK": "219.76.0.0/14",
+	"ID": "114.120.0.0/13",
+	"IE": "87.32.0.0/12",
+	"IL": "79.176.0.0/13",
+	"IN": "117.192.0.0/10",
+	"IT": "79.0.0.0/10",
+	"JP": "133.0.0.0/8",
+	"KR": "175.192.0.0/10",
+	"MX": "187.192.0.0/11",
+	"NL": "145.0.0.0/8",
+	"NO": "84.208.0.0/13",
+	"NZ": "49.224.0.0/14",
+	"PL": "83.0.0.0/11",
+	"PT": "85.240.0.0/13",
+	"RU": "5.136.0.0/13",
+	"SE": "78.64.0.0/12",
+	"SG": "8.128.0.0/10",
+	"TR": "78.160.0.0/11",
+	"TW": "120.96.0.0/11",
+	"UA": "37.52.0.0/14",
+	"US": "6.0.0.0/8",
+	"ZA": "41.0.0.0/11",
+}
+
+// GeoBypassIP returns a random IPv4 address allocated to the country.
+func GeoBypassIP(country string) (string, error) {
+	block, ok := geoBypassBlocks[strings.ToUpper(country)]
+	if !ok {
+		return "", fmt.Errorf("%w %q (use a two-letter country code such as US or DE)", ErrUnknownCountry, country)
+	}
+	_, network, err := net.ParseCIDR(block)
+	if err != nil {
+		return "", err
+	}
+
+	ones, bits := network.Mask.Size()
+	base := binary.BigEndian.Uint32(network.IP.To4())
+	// Skip the network and broadcast addresses
+	host := 1 + rand.Uint32N(uint32(1)<<(bits-ones)-2)
+
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, base+host)
+	return ip.String(), nil
+}
+
+// geoTransport adds the "gl" region parameter to YouTube page and API requests,
+// so content is served for the geo-bypass country. Stream hosts are left alone
+// because their URLs are signed.
+type geoTransport struct {
+	base    http.RoundTripper
+	country string
+}
+
+func (t *geoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	if host != "youtube.com" && !strings.HasSuffix(host, ".youtube.com") {
+		return t.base.RoundTrip(req)
+	}
+
+	query := req.URL.Query()
+	if query.Get("gl") != "" {
+		return t.base.RoundTrip(req)
+	}
+	query.Set("gl", t.country)
+
+	reqCopy := req.Clone(req.Context())
+	reqCopy.URL.RawQuery = query.Encode()
+	return t.base.RoundTrip(reqCopy)
+}
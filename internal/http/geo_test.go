@@ -0,0 +1,100 @@
+package http
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestGeoBypassIP(t *testing.T) {
+	_, block, _ := net.ParseCIDR(geoBypassBlocks["DE"])
+	for range 100 {
+		got, err := GeoBypassIP("de")
+		if err != nil {
+			t.Fatalf("GeoBypassIP failed: %v", err)
+		}
+		ip := net.ParseIP(got)
+		if ip == nil || !block.Contains(ip) {
+			t.Fatalf("GeoBypassIP(de) = %q, want an address in %s", got, block)
+		}
+	}
+
+	if _, err := GeoBypassIP("XX"); !errors.Is(err, ErrUnknownCountry) {
+		t.Errorf("expected ErrUnknownCountry, got %v", err)
+	}
+}
+
+func TestGeoBypassBlocks_Valid(t *testing.T) {
+	for country, block := range geoBypassBlocks {
+		if _, network, err := net.ParseCIDR(block); err != nil || network.IP.To4() == nil {
+			t.Errorf("%s: invalid IPv4 block %q", country, block)
+		}
+	}
+}
+
+func TestNewClientWithOptions_GeoBypass(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClientWithOptions(Options{GeoBypassCountry: "jp"})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions failed: %v", err)
+	}
+	resp, err := client.Get(server.URL + "/videoplayback?id=1")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	_, block, _ := net.ParseCIDR(geoBypassBlocks["JP"])
+	if ip := net.ParseIP(got.Header.Get("X-Forwarded-For")); ip == nil || !block.Contains(ip) {
+		t.Errorf("X-Forwarded-For = %q, want an address in %s", got.Header.Get("X-Forwarded-For"), block)
+	}
+	// Only YouTube hosts get the region parameter
+	if got.URL.Query().Has("gl") {
+		t.Errorf("unexpected gl parameter in %s", got.URL)
+	}
+
+	if _, err := NewClientWithOptions(Options{GeoBypassCountry: "XX"}); !errors.Is(err, ErrUnknownCountry) {
+		t.Errorf("expected ErrUnknownCountry, got %v", err)
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestGeoTransport_AddsRegion(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"https://www.youtube.com/watch?v=abc", "JP"},
+		{"https://www.youtube.com/watch?v=abc&gl=US", "US"},
+		{"https://rr1---sn-abc.googlevideo.com/videoplayback?id=1", ""},
+	}
+	for _, tt := range tests {
+		var sent *url.URL
+		rt := &geoTransport{country: "JP", base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			sent = req.URL
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})}
+		req, _ := http.NewRequest(http.MethodGet, tt.url, http.NoBody)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+		if got := sent.Query().Get("gl"); got != tt.want {
+			t.Errorf("%s: gl = %q, want %q", tt.url, got, tt.want)
+		}
+		if req.URL.Query().Get("gl") != "" && tt.want == "JP" {
+			t.Errorf("%s: original request was modified", tt.url)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package http
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate limit backoff bounds. Without a Retry-After header, the pause after a 429
+// starts at rateLimitBackoffBase and doubles with each retry up to rateLimitBackoffMax.
+// A Retry-After longer than maxRetryAfter isn't waited for; the 429 is returned.
+const (
+	rateLimitBackoffBase = 5 * time.Second
+	rateLimitBackoffMax  = time.Minute
+	maxRetryAfter        = 5 * time.Minute
+)
+
+// Limiter spaces out requests and pauses them after YouTube answers with
+// 429 Too Many Requests. Clients sharing a limiter are paced together, so
+// parallel fetchers can't exceed the pace between them.
+type Limiter struct {
+	// Delay is the minimum pause between the starts of two requests.
+	Delay time.Duration
+
+	// Jitter is the longest random pause added to Delay, so requests don't
+	// arrive at a fixed interval.
+	Jitter time.Duration
+
+	mu sync.Mutex
+	// next is the earliest time the next request may start.
+	next time.Time
+}
+
+// NewLimiter returns a limiter that waits delay plus up to jitter between requests.
+func NewLimiter(delay, jitter time.Duration) *Limiter {
+	return &Limiter{Delay: delay, Jitter: jitter}
+}
+
+// Wait blocks until the next request may start and reserves its slot. It
+// returns early with the context's error if ctx is done first.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	gap := l.Delay
+	if l.Jitter > 0 {
+		gap += rand.N(l.Jitter)
+	}
+	l.next = start.Add(gap)
+	l.mu.Unlock()
+
+	wait := time.Until(start)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Backoff holds back all requests through the limiter for d.
+func (l *Limiter) Backoff(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.next) {
+		l.next = until
+	}
+}
+
+// ParseRetryAfter parses a Retry-After header, given either as a number of
+// seconds or as an HTTP date, into how long to wait from now. It reports false
+// if the value is empty or invalid.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(t.Sub(now), 0), true
+}
+
+// rateLimitBackoff returns the pause before retry number attempt (from 0) of a
+// request answered with 429 and no Retry-After.
+func rateLimitBackoff(attempt int) time.Duration {
+	wait := rateLimitBackoffBase
+	for range attempt {
+		wait *= 2
+		if wait >= rateLimitBackoffMax {
+			return rateLimitBackoffMax
+		}
+	}
+	return wait
+}
+
+// paced reports whether a request goes through the limiter. Stream and thumbnail
+// requests don't, since they aren't what YouTube rate limits batch jobs on and
+// pacing them would only slow downloads down.
+func paced(req *http.Request) bool {
+	return !isStreamHost(req.URL.Hostname()) && !IsNonEssential(req)
+}
+
+// roundTripPaced sends a metadata request when the limiter allows it and
+// retries it after the pause YouTube asks for when it is answered with 429.
+func (t *transport) roundTripPaced(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+		if t.rateLimited != nil {
+			t.rateLimited()
+		}
+
+		wait, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			wait = rateLimitBackoff(attempt)
+		}
+		// Later requests would be refused too, so every request through the limiter waits
+		t.limiter.Backoff(min(wait, maxRetryAfter))
+		if attempt >= t.retries || wait > maxRetryAfter {
+			return resp, nil
+		}
+		retry, ok := rewindRequest(req)
+		if !ok {
+			return resp, nil
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		_ = resp.Body.Close()
+		req = retry
+	}
+}
+
+// rewindRequest returns req ready to be sent again, with a fresh body if it has
+// one. It reports false if the body can't be read again.
+func rewindRequest(req *http.Request) (*http.Request, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	retry := req.Clone(req.Context())
+	retry.Body = body
+	return retry, true
+}
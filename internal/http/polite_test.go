@@ -0,0 +1,204 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"Fri, 02 Jan 2026 15:04:35 GMT", 30 * time.Second, true},
+		{"Fri, 02 Jan 2026 15:00:00 GMT", 0, true},
+		{"", 0, false},
+		{"-5", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRateLimitBackoff(t *testing.T) {
+	for attempt, want := range []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute} {
+		if got := rateLimitBackoff(attempt); got != want {
+			t.Errorf("rateLimitBackoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestLimiter_SpacesRequests(t *testing.T) {
+	limiter := NewLimiter(30*time.Millisecond, 10*time.Millisecond)
+	start := time.Now()
+	for range 3 {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first request goes at once, the other two wait at least the delay each
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("three requests took %v, want at least 60ms", elapsed)
+	}
+}
+
+func TestLimiter_Backoff(t *testing.T) {
+	limiter := NewLimiter(0, 0)
+	limiter.Backoff(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait during backoff = %v, want the context's deadline", err)
+	}
+
+	// A shorter backoff doesn't cut the pause short
+	limiter.Backoff(time.Millisecond)
+	if time.Until(limiter.next) < 59*time.Minute {
+		t.Errorf("next request at %v, want the hour's backoff kept", limiter.next)
+	}
+}
+
+// newRateLimitedServer answers the first limited requests with 429 and retryAfter,
+// then with the request body.
+func newRateLimitedServer(t *testing.T, limited int32, retryAfter string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= limited {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestTransport_PacedRetriesAfterRetryAfter(t *testing.T) {
+	server, requests := newRateLimitedServer(t, 2, "0")
+	client := &http.Client{Transport: &transport{base: http.DefaultTransport, limiter: NewLimiter(0, 0), retries: 3}}
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"continuation":"x"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != `{"continuation":"x"}` {
+		t.Errorf("got %d %q, want the body sent again", resp.StatusCode, body)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("made %d requests, want 3", n)
+	}
+}
+
+func TestTransport_PacedGivesUpAfterRetries(t *testing.T) {
+	server, requests := newRateLimitedServer(t, 10, "0")
+	client := &http.Client{Transport: &transport{base: http.DefaultTransport, limiter: NewLimiter(0, 0), retries: 1}}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || requests.Load() != 2 {
+		t.Errorf("got %d after %d requests, want 429 after 2", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestTransport_PacedReportsRateLimits(t *testing.T) {
+	server, _ := newRateLimitedServer(t, 2, "0")
+	var hits atomic.Int32
+	client := &http.Client{Transport: &transport{
+		base:        http.DefaultTransport,
+		limiter:     NewLimiter(0, 0),
+		retries:     3,
+		rateLimited: func() { hits.Add(1) },
+	}}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if n := hits.Load(); n != 2 {
+		t.Errorf("reported %d rate limits, want 2", n)
+	}
+}
+
+func TestTransport_PacedLongRetryAfterNotWaited(t *testing.T) {
+	server, requests := newRateLimitedServer(t, 10, "3600")
+	limiter := NewLimiter(0, 0)
+	client := &http.Client{Transport: &transport{base: http.DefaultTransport, limiter: limiter, retries: 3}}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || requests.Load() != 1 {
+		t.Errorf("got %d after %d requests, want the first 429", resp.StatusCode, requests.Load())
+	}
+	// Other requests still hold back, for at most maxRetryAfter
+	if wait := time.Until(limiter.next); wait < maxRetryAfter-time.Minute || wait > maxRetryAfter {
+		t.Errorf("limiter holds requests back for %v, want about %v", wait, maxRetryAfter)
+	}
+}
+
+func TestTransport_PacedSkipsStreams(t *testing.T) {
+	limiter := NewLimiter(time.Hour, 0)
+	limiter.Backoff(time.Hour)
+	var called bool
+	rt := &transport{base: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		called = true
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}), limiter: limiter}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://rr1---sn-abc.googlevideo.com/videoplayback", http.NoBody)
+	resp, err := rt.RoundTrip(req)
+	if err != nil || !called {
+		t.Fatalf("stream request = %v, %v; want it passed through at once", resp, err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestNewClientWithOptions_SharedLimiter(t *testing.T) {
+	server, _ := newRateLimitedServer(t, 1, "3600")
+	limiter := NewLimiter(0, 0)
+	first, _ := NewClientWithOptions(Options{Limiter: limiter})
+	second, _ := NewClientWithOptions(Options{Limiter: limiter})
+
+	resp, err := first.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	// The other client waits out the backoff too
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, http.NoBody)
+	if _, err := second.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("second client error = %v, want it held back", err)
+	}
+}
@@ -0,0 +1,166 @@
+// Package cache keeps fetched metadata, such as watch pages and playlist listings,
+// in memory and on disk for a limited time so repeated requests skip the network.
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Cache stores data by key for a fixed time to live. Entries are kept in memory
+// and, when the cache has a directory, in gzip-compressed files there so they
+// survive between runs. A Cache is safe for concurrent use.
+type Cache struct {
+	store  *store
+	prefix string
+}
+
+// store is the storage shared by a cache and its namespaces.
+type store struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+type entry struct {
+	data    []byte
+	expires time.Time
+}
+
+// New creates a cache whose entries expire after ttl. Entries are also written to
+// dir, unless dir is empty, in which case the cache only lives in memory.
+func New(dir string, ttl time.Duration) *Cache {
+	return &Cache{store: &store{dir: dir, ttl: ttl, now: time.Now, entries: make(map[string]entry)}}
+}
+
+// Namespace returns a view of the cache whose keys don't collide with keys of
+// other namespaces, for data that depends on how it was fetched.
+func (c *Cache) Namespace(name string) *Cache {
+	return &Cache{store: c.store, prefix: c.prefix + name + "/"}
+}
+
+// Get returns the data stored for key, if it hasn't expired.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	return c.store.get(c.prefix + key)
+}
+
+// Set stores data for key. Failing to write the entry to disk isn't an error;
+// the entry is still kept in memory.
+func (c *Cache) Set(key string, data []byte) {
+	c.store.set(c.prefix+key, data)
+}
+
+func (s *store) get(key string) ([]byte, bool) {
+	now := s.now()
+
+	s.mu.Lock()
+	e, ok := s.entries[key]
+	s.mu.Unlock()
+	if ok {
+		if now.Before(e.expires) {
+			return e.data, true
+		}
+		s.mu.Lock()
+		delete(s.entries, key)
+		s.mu.Unlock()
+	}
+
+	if s.dir == "" {
+		return nil, false
+	}
+	path := s.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	expires := info.ModTime().Add(s.ttl)
+	if !now.Before(expires) {
+		_ = os.Remove(path)
+		return nil, false
+	}
+	data, err := readGzip(path)
+	if err != nil {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	s.entries[key] = entry{data: data, expires: expires}
+	s.mu.Unlock()
+	return data, true
+}
+
+func (s *store) set(key string, data []byte) {
+	s.mu.Lock()
+	s.entries[key] = entry{data: data, expires: s.now().Add(s.ttl)}
+	s.mu.Unlock()
+
+	if s.dir != "" {
+		_ = writeGzip(s.path(key), data)
+	}
+}
+
+// path returns the file an entry is stored in, named by the hash of its key.
+func (s *store) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:16])+".gz")
+}
+
+func readGzip(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(zr)
+}
+
+// writeGzip writes data compressed to path through a temporary file, so readers
+// never see a partial entry.
+func writeGzip(path string, data []byte) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache_Memory(t *testing.T) {
+	c := New("", time.Minute)
+	now := time.Now()
+	c.store.now = func() time.Time { return now }
+
+	if _, ok := c.Get("watch/a"); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+	c.Set("watch/a", []byte("page"))
+	if data, ok := c.Get("watch/a"); !ok || string(data) != "page" {
+		t.Fatalf("Get() = %q, %v", data, ok)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("watch/a"); ok {
+		t.Error("expected the entry to expire")
+	}
+}
+
+func TestCache_Disk(t *testing.T) {
+	dir := t.TempDir()
+	New(dir, time.Hour).Set("watch/a", []byte("page"))
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.gz"))
+	if len(files) != 1 {
+		t.Fatalf("cache files = %v, want one", files)
+	}
+
+	// A new cache, as in a later run, reads the entry from disk
+	c := New(dir, time.Hour)
+	if data, ok := c.Get("watch/a"); !ok || string(data) != "page" {
+		t.Fatalf("Get() = %q, %v", data, ok)
+	}
+
+	// Entries older than the TTL are removed
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(files[0], old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := New(dir, time.Hour).Get("watch/a"); ok {
+		t.Error("expected the expired file to be a miss")
+	}
+	if _, err := os.Stat(files[0]); !os.IsNotExist(err) {
+		t.Error("expected the expired file to be removed")
+	}
+}
+
+func TestCache_CorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir, time.Hour)
+	if err := os.WriteFile(c.store.path("watch/a"), []byte("not gzip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get("watch/a"); ok {
+		t.Error("expected a corrupt file to be a miss")
+	}
+}
+
+func TestCache_Namespace(t *testing.T) {
+	c := New(t.TempDir(), time.Hour)
+	first := c.Namespace("ua1")
+	second := c.Namespace("ua2")
+
+	first.Set("watch/a", []byte("one"))
+	if _, ok := second.Get("watch/a"); ok {
+		t.Error("expected namespaces not to share keys")
+	}
+	if _, ok := c.Get("watch/a"); ok {
+		t.Error("expected the namespace key to differ from the plain key")
+	}
+	if data, ok := c.Namespace("ua1").Get("watch/a"); !ok || string(data) != "one" {
+		t.Errorf("Get() = %q, %v", data, ok)
+	}
+}
@@ -0,0 +1,134 @@
+package dlna
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxActionResponseSize bounds the responses to actions read from renderers.
+const maxActionResponseSize = 64 << 10
+
+// ActionError is a UPnP error returned by a renderer for an action.
+type ActionError struct {
+	// Action is the name of the action, such as "Play".
+	Action string
+
+	// Code is the UPnP error code, such as 701 for a transition the renderer
+	// can't make in its current state.
+	Code int
+
+	// Description is the renderer's description of the error, often empty.
+	Description string
+}
+
+func (e *ActionError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("%s failed with UPnP error %d", e.Action, e.Code)
+	}
+	return fmt.Sprintf("%s failed with UPnP error %d: %s", e.Action, e.Code, e.Description)
+}
+
+// actionArg is an argument of an action. Arguments are sent in order, as
+// renderers expect them in the order of the service description.
+type actionArg struct {
+	name  string
+	value string
+}
+
+// SetURI tells the renderer to load the media at uri, with title and MIME
+// type mimeType, such as "video/mp4". Play starts it.
+func (r *Renderer) SetURI(ctx context.Context, uri, title, mimeType string) error {
+	return r.action(ctx, "SetAVTransportURI",
+		actionArg{"InstanceID", "0"},
+		actionArg{"CurrentURI", uri},
+		actionArg{"CurrentURIMetaData", didlLite(uri, title, mimeType)})
+}
+
+// Play starts or resumes playback.
+func (r *Renderer) Play(ctx context.Context) error {
+	return r.action(ctx, "Play", actionArg{"InstanceID", "0"}, actionArg{"Speed", "1"})
+}
+
+// Pause pauses playback.
+func (r *Renderer) Pause(ctx context.Context) error {
+	return r.action(ctx, "Pause", actionArg{"InstanceID", "0"})
+}
+
+// Stop stops playback.
+func (r *Renderer) Stop(ctx context.Context) error {
+	return r.action(ctx, "Stop", actionArg{"InstanceID", "0"})
+}
+
+// action sends an AVTransport action to the renderer.
+func (r *Renderer) action(ctx context.Context, name string, args ...actionArg) error {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body><u:` + name + ` xmlns:u="` + avTransportType + `">`)
+	for _, arg := range args {
+		body.WriteString("<" + arg.name + ">")
+		_ = xml.EscapeText(&body, []byte(arg.value))
+		body.WriteString("</" + arg.name + ">")
+	}
+	body.WriteString(`</u:` + name + `></s:Body></s:Envelope>`)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.ControlURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", `"`+avTransportType+"#"+name+`"`)
+
+	client := r.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if actionErr := parseActionError(name, io.LimitReader(resp.Body, maxActionResponseSize)); actionErr != nil {
+		return actionErr
+	}
+	return fmt.Errorf("%s failed: renderer responded %s", name, resp.Status)
+}
+
+// parseActionError returns the UPnP error of a SOAP fault, or nil if r holds none.
+func parseActionError(action string, r io.Reader) *ActionError {
+	var fault struct {
+		Code        int    `xml:"Body>Fault>detail>UPnPError>errorCode"`
+		Description string `xml:"Body>Fault>detail>UPnPError>errorDescription"`
+	}
+	if err := xml.NewDecoder(r).Decode(&fault); err != nil || fault.Code == 0 {
+		return nil
+	}
+	return &ActionError{Action: action, Code: fault.Code, Description: strings.TrimSpace(fault.Description)}
+}
+
+// didlLite returns the DIDL-Lite metadata of the media at uri, which many
+// renderers need to play it and show its title.
+func didlLite(uri, title, mimeType string) string {
+	class := "object.item.videoItem"
+	if strings.HasPrefix(mimeType, "audio/") {
+		class = "object.item.audioItem.musicTrack"
+	}
+	var b strings.Builder
+	b.WriteString(`<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">`)
+	b.WriteString(`<item id="0" parentID="-1" restricted="1"><dc:title>`)
+	_ = xml.EscapeText(&b, []byte(title))
+	b.WriteString(`</dc:title><upnp:class>` + class + `</upnp:class><res protocolInfo="http-get:*:`)
+	_ = xml.EscapeText(&b, []byte(mimeType))
+	b.WriteString(`:*">`)
+	_ = xml.EscapeText(&b, []byte(uri))
+	b.WriteString(`</res></item></DIDL-Lite>`)
+	return b.String()
+}
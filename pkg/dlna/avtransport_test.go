@@ -0,0 +1,102 @@
+package dlna
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestRenderer returns a renderer whose control URL records the actions it
+// gets and answers with handler.
+func newTestRenderer(t *testing.T, handler http.HandlerFunc) (*Renderer, *[]string) {
+	t.Helper()
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.Header.Get("SOAPAction")+" "+string(body))
+		if handler != nil {
+			handler(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return &Renderer{Name: "TV", ControlURL: server.URL + "/avt", client: server.Client()}, &bodies
+}
+
+func TestRenderer_Actions(t *testing.T) {
+	r, bodies := newTestRenderer(t, nil)
+	ctx := context.Background()
+
+	if err := r.SetURI(ctx, "http://192.168.1.2:8766/video.mp4?a=1&b=2", "Tom & Jerry", "video/mp4"); err != nil {
+		t.Fatalf("SetURI failed: %v", err)
+	}
+	for _, action := range []func(context.Context) error{r.Play, r.Pause, r.Stop} {
+		if err := action(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(*bodies) != 4 {
+		t.Fatalf("renderer got %d actions, want 4", len(*bodies))
+	}
+	setURI := (*bodies)[0]
+	for _, want := range []string{
+		`"urn:schemas-upnp-org:service:AVTransport:1#SetAVTransportURI"`,
+		`<u:SetAVTransportURI xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><InstanceID>0</InstanceID>`,
+		`<CurrentURI>http://192.168.1.2:8766/video.mp4?a=1&amp;b=2</CurrentURI>`,
+		// The metadata is XML escaped twice: in the DIDL-Lite and in the envelope
+		`&lt;dc:title&gt;Tom &amp;amp; Jerry&lt;/dc:title&gt;`,
+		`protocolInfo=&#34;http-get:*:video/mp4:*&#34;`,
+		`object.item.videoItem`,
+	} {
+		if !strings.Contains(setURI, want) {
+			t.Errorf("SetAVTransportURI request is missing %q:\n%s", want, setURI)
+		}
+	}
+	for i, want := range []string{"#Play\" ", "#Pause\" ", "#Stop\" "} {
+		if !strings.Contains((*bodies)[i+1], want) {
+			t.Errorf("action %d = %q, want %s", i+1, (*bodies)[i+1], want)
+		}
+	}
+	if !strings.Contains((*bodies)[1], "<Speed>1</Speed>") {
+		t.Errorf("Play request is missing the speed: %s", (*bodies)[1])
+	}
+}
+
+func TestRenderer_ActionError(t *testing.T) {
+	r, _ := newTestRenderer(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>
+<faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring>
+<detail><UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>701</errorCode><errorDescription>Transition not available</errorDescription></UPnPError></detail>
+</s:Fault></s:Body></s:Envelope>`))
+	})
+
+	err := r.Pause(context.Background())
+	var actionErr *ActionError
+	if !errors.As(err, &actionErr) || actionErr.Code != 701 || actionErr.Action != "Pause" {
+		t.Fatalf("err = %v, want UPnP error 701", err)
+	}
+	if got := err.Error(); got != "Pause failed with UPnP error 701: Transition not available" {
+		t.Errorf("err = %q", got)
+	}
+}
+
+func TestRenderer_HTTPError(t *testing.T) {
+	r, _ := newTestRenderer(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	if err := r.Stop(context.Background()); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("err = %v, want the HTTP status", err)
+	}
+}
+
+func TestDIDLLite_Audio(t *testing.T) {
+	if got := didlLite("http://h/audio.webm", "Song", "audio/webm"); !strings.Contains(got, "object.item.audioItem.musicTrack") {
+		t.Errorf("didlLite of audio = %s", got)
+	}
+}
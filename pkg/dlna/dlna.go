@@ -0,0 +1,138 @@
+// Package dlna finds DLNA media renderers, such as smart TVs and speakers, on
+// the local network with SSDP and controls their playback with UPnP AVTransport.
+package dlna
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// MediaRendererType is the UPnP device type of media renderers.
+	MediaRendererType = "urn:schemas-upnp-org:device:MediaRenderer:1"
+
+	// avTransportType is the UPnP service type renderers are controlled with.
+	avTransportType = "urn:schemas-upnp-org:service:AVTransport:1"
+
+	// maxDescriptionSize bounds the device descriptions read from renderers.
+	maxDescriptionSize = 1 << 20
+)
+
+// ErrNoAVTransport is returned for devices that can't be told what to play.
+var ErrNoAVTransport = errors.New("device has no AVTransport service")
+
+// Renderer is a media renderer on the network.
+type Renderer struct {
+	// Name is the renderer's friendly name, such as "Living Room TV".
+	Name string
+
+	// Model is the renderer's model name, if it reports one.
+	Model string
+
+	// UDN is the renderer's unique device name, as in "uuid:...".
+	UDN string
+
+	// Location is the URL of the renderer's device description.
+	Location string
+
+	// ControlURL is the URL AVTransport actions are sent to.
+	ControlURL string
+
+	client *http.Client
+}
+
+// description is the part of a UPnP device description the renderer needs.
+type description struct {
+	URLBase string        `xml:"URLBase"`
+	Device  deviceElement `xml:"device"`
+}
+
+type deviceElement struct {
+	FriendlyName string           `xml:"friendlyName"`
+	ModelName    string           `xml:"modelName"`
+	UDN          string           `xml:"UDN"`
+	Services     []serviceElement `xml:"serviceList>service"`
+	Devices      []deviceElement  `xml:"deviceList>device"`
+}
+
+type serviceElement struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+// avTransport returns the device, or embedded device, with an AVTransport
+// service and the service's control URL.
+func (d *deviceElement) avTransport() (*deviceElement, string) {
+	for _, service := range d.Services {
+		if strings.HasPrefix(service.ServiceType, "urn:schemas-upnp-org:service:AVTransport:") {
+			return d, service.ControlURL
+		}
+	}
+	for i := range d.Devices {
+		if device, controlURL := d.Devices[i].avTransport(); device != nil {
+			return device, controlURL
+		}
+	}
+	return nil, ""
+}
+
+// fetchRenderer fetches the device description at location.
+func fetchRenderer(ctx context.Context, client *http.Client, location string) (*Renderer, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch device description: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch device description: %s", resp.Status)
+	}
+	var desc description
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, maxDescriptionSize)).Decode(&desc); err != nil {
+		return nil, fmt.Errorf("invalid device description: %w", err)
+	}
+	return newRenderer(client, location, &desc)
+}
+
+// newRenderer returns the renderer described by desc, fetched from location.
+func newRenderer(client *http.Client, location string, desc *description) (*Renderer, error) {
+	device, controlURL := desc.Device.avTransport()
+	if device == nil {
+		return nil, fmt.Errorf("%s: %w", desc.Device.FriendlyName, ErrNoAVTransport)
+	}
+	base := location
+	if desc.URLBase != "" {
+		base = desc.URLBase
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device URL: %w", err)
+	}
+	control, err := baseURL.Parse(controlURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AVTransport control URL: %w", err)
+	}
+
+	// The renderer is named after the root device; embedded ones are often unnamed
+	name := desc.Device.FriendlyName
+	if name == "" {
+		name = device.FriendlyName
+	}
+	return &Renderer{
+		Name:       strings.TrimSpace(name),
+		Model:      strings.TrimSpace(desc.Device.ModelName),
+		UDN:        strings.TrimSpace(desc.Device.UDN),
+		Location:   location,
+		ControlURL: control.String(),
+		client:     client,
+	}, nil
+}
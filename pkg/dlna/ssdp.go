@@ -0,0 +1,149 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SSDPAddr is the multicast address SSDP searches are sent to.
+const SSDPAddr = "239.255.255.250:1900"
+
+// maxSearchWait is the longest a device may wait before answering a search,
+// the MX header. Devices answer at a random time up to it to spread replies.
+const maxSearchWait = 5 * time.Second
+
+// Discoverer finds media renderers on the local network.
+type Discoverer struct {
+	// Client fetches device descriptions and sends actions to renderers.
+	// http.DefaultClient is used if nil.
+	Client *http.Client
+
+	// Addr is the address searches are sent to, SSDPAddr if empty.
+	Addr string
+}
+
+func (d *Discoverer) client() *http.Client {
+	if d.Client != nil {
+		return d.Client
+	}
+	return http.DefaultClient
+}
+
+// Discover searches the network for media renderers for the duration of wait
+// and returns the ones that answered, in the order they answered. Devices that
+// answer but can't be controlled are left out.
+func (d *Discoverer) Discover(ctx context.Context, wait time.Duration) ([]*Renderer, error) {
+	addr := d.Addr
+	if addr == "" {
+		addr = SSDPAddr
+	}
+	dst, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSDP address: %w", err)
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SSDP socket: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// Searches are sent twice, as UDP datagrams may be lost
+	search := searchRequest(addr, wait)
+	for range 2 {
+		if _, err := conn.WriteToUDP(search, dst); err != nil {
+			return nil, fmt.Errorf("failed to send SSDP search: %w", err)
+		}
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(wait))
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	var locations []string
+	seen := make(map[string]bool)
+	buf := make([]byte, 8192)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return nil, fmt.Errorf("failed to read SSDP responses: %w", err)
+		}
+		location := searchResponseLocation(buf[:n])
+		if location != "" && !seen[location] {
+			seen[location] = true
+			locations = append(locations, location)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return d.fetchRenderers(ctx, locations), nil
+}
+
+// Renderer returns the renderer whose device description is at location, for
+// renderers that are known but don't answer searches.
+func (d *Discoverer) Renderer(ctx context.Context, location string) (*Renderer, error) {
+	return fetchRenderer(ctx, d.client(), location)
+}
+
+// fetchRenderers fetches the descriptions at locations in parallel, skipping
+// those that fail or aren't renderers.
+func (d *Discoverer) fetchRenderers(ctx context.Context, locations []string) []*Renderer {
+	renderers := make([]*Renderer, len(locations))
+	var wg sync.WaitGroup
+	for i, location := range locations {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if r, err := fetchRenderer(ctx, d.client(), location); err == nil {
+				renderers[i] = r
+			}
+		}()
+	}
+	wg.Wait()
+
+	found := renderers[:0]
+	for _, r := range renderers {
+		if r != nil {
+			found = append(found, r)
+		}
+	}
+	return found
+}
+
+// searchRequest returns an M-SEARCH request for media renderers sent to addr,
+// which devices answer within wait.
+func searchRequest(addr string, wait time.Duration) []byte {
+	mx := int(min(wait, maxSearchWait) / time.Second)
+	mx = max(mx, 1)
+	return []byte("M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + addr + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		fmt.Sprintf("MX: %d\r\n", mx) +
+		"ST: " + MediaRendererType + "\r\n" +
+		"\r\n")
+}
+
+// searchResponseLocation returns the description URL of an answer to a
+// search, or "" if data isn't one.
+func searchResponseLocation(data []byte) string {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+	if err != nil {
+		return ""
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	return resp.Header.Get("Location")
+}
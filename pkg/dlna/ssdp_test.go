@@ -0,0 +1,140 @@
+package dlna
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+    <friendlyName>Living Room TV</friendlyName>
+    <modelName>Model 1</modelName>
+    <UDN>uuid:1234</UDN>
+    <serviceList>
+      <service>
+        <serviceType>urn:schemas-upnp-org:service:RenderingControl:1</serviceType>
+        <controlURL>/rc/control</controlURL>
+      </service>
+      <service>
+        <serviceType>urn:schemas-upnp-org:service:AVTransport:1</serviceType>
+        <controlURL>/avt/control</controlURL>
+      </service>
+    </serviceList>
+  </device>
+</root>`
+
+// newSSDPResponder answers each search sent to the returned address with the
+// locations, and returns the searches it got on searches.
+func newSSDPResponder(t *testing.T, locations ...string) (string, <-chan string) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	searches := make(chan string, 10)
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			searches <- string(buf[:n])
+			for _, location := range locations {
+				reply := "HTTP/1.1 200 OK\r\nCACHE-CONTROL: max-age=1800\r\nLOCATION: " + location + "\r\nST: " + MediaRendererType + "\r\n\r\n"
+				_, _ = conn.WriteToUDP([]byte(reply), from)
+			}
+		}
+	}()
+	return conn.LocalAddr().String(), searches
+}
+
+func TestDiscover(t *testing.T) {
+	devices := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tv.xml":
+			_, _ = w.Write([]byte(testDescription))
+		case "/printer.xml":
+			_, _ = w.Write([]byte(`<root><device><friendlyName>Printer</friendlyName></device></root>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer devices.Close()
+	addr, searches := newSSDPResponder(t, devices.URL+"/tv.xml", devices.URL+"/printer.xml", devices.URL+"/missing.xml")
+
+	d := &Discoverer{Client: devices.Client(), Addr: addr}
+	renderers, err := d.Discover(context.Background(), 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	if len(renderers) != 1 {
+		t.Fatalf("found %d renderers, want only the TV", len(renderers))
+	}
+	r := renderers[0]
+	if r.Name != "Living Room TV" || r.Model != "Model 1" || r.UDN != "uuid:1234" {
+		t.Errorf("renderer = %+v", r)
+	}
+	if r.ControlURL != devices.URL+"/avt/control" {
+		t.Errorf("ControlURL = %q, want %q", r.ControlURL, devices.URL+"/avt/control")
+	}
+
+	search := <-searches
+	for _, want := range []string{"M-SEARCH * HTTP/1.1\r\n", `MAN: "ssdp:discover"`, "MX: 1\r\n", "ST: " + MediaRendererType} {
+		if !strings.Contains(search, want) {
+			t.Errorf("search %q is missing %q", search, want)
+		}
+	}
+}
+
+func TestDiscover_ContextCanceled(t *testing.T) {
+	addr, _ := newSSDPResponder(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := (&Discoverer{Addr: addr}).Discover(ctx, time.Minute)
+	if err == nil {
+		t.Error("Discover succeeded after the context was done")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Discover took %v after the context was done", elapsed)
+	}
+}
+
+func TestNewRenderer_EmbeddedDeviceAndURLBase(t *testing.T) {
+	desc := `<root>
+  <URLBase>http://192.168.1.20:9197/</URLBase>
+  <device>
+    <friendlyName>Receiver</friendlyName>
+    <deviceList><device>
+      <serviceList><service>
+        <serviceType>urn:schemas-upnp-org:service:AVTransport:2</serviceType>
+        <controlURL>upnp/control/AVTransport1</controlURL>
+      </service></serviceList>
+    </device></deviceList>
+  </device>
+</root>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(desc))
+	}))
+	defer server.Close()
+
+	r, err := (&Discoverer{Client: server.Client()}).Renderer(context.Background(), server.URL+"/dmr/desc.xml")
+	if err != nil {
+		t.Fatalf("Renderer failed: %v", err)
+	}
+	if r.Name != "Receiver" || r.ControlURL != "http://192.168.1.20:9197/upnp/control/AVTransport1" {
+		t.Errorf("renderer = %+v", r)
+	}
+}
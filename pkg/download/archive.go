@@ -0,0 +1,86 @@
+package download
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// archivePrefix starts every archive line, naming the site as youtube-dl and yt-dlp do.
+const archivePrefix = "youtube "
+
+// Archive records the videos that were downloaded so later runs can skip them.
+// The file has one "youtube <video ID>" line per video, the format of youtube-dl
+// and yt-dlp download archives, so archives can be shared with those tools.
+type Archive struct {
+	path string
+
+	mu  sync.Mutex
+	ids map[string]bool
+}
+
+// OpenArchive reads the archive at path. A missing file is an empty archive,
+// created when the first video is added.
+func OpenArchive(path string) (*Archive, error) {
+	a := &Archive{path: path, ids: make(map[string]bool)}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening download archive: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), archivePrefix); ok {
+			a.ids[strings.TrimSpace(id)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading download archive: %w", err)
+	}
+	return a, nil
+}
+
+// Has reports whether the video is in the archive.
+func (a *Archive) Has(videoID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.ids[videoID]
+}
+
+// Add records the video in the archive file.
+func (a *Archive) Add(videoID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.ids[videoID] {
+		return nil
+	}
+
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening download archive: %w", err)
+	}
+	if _, err := fmt.Fprintf(f, "%s%s\n", archivePrefix, videoID); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("writing download archive: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing download archive: %w", err)
+	}
+	a.ids[videoID] = true
+	return nil
+}
+
+// Len returns the number of videos in the archive.
+func (a *Archive) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.ids)
+}
@@ -0,0 +1,56 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.txt")
+
+	archive, err := OpenArchive(path)
+	if err != nil {
+		t.Fatalf("OpenArchive() on a missing file error = %v", err)
+	}
+	if archive.Len() != 0 || archive.Has("dQw4w9WgXcQ") {
+		t.Fatal("expected an empty archive")
+	}
+
+	for _, id := range []string{"dQw4w9WgXcQ", "jNQXAC9IVRw", "dQw4w9WgXcQ"} {
+		if err := archive.Add(id); err != nil {
+			t.Fatalf("Add(%q) error = %v", id, err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "youtube dQw4w9WgXcQ\nyoutube jNQXAC9IVRw\n"; string(data) != want {
+		t.Errorf("archive file = %q, want %q", data, want)
+	}
+
+	reopened, err := OpenArchive(path)
+	if err != nil {
+		t.Fatalf("OpenArchive() error = %v", err)
+	}
+	if reopened.Len() != 2 || !reopened.Has("jNQXAC9IVRw") {
+		t.Errorf("reopened archive has %d videos", reopened.Len())
+	}
+}
+
+func TestOpenArchive_SkipsOtherLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.txt")
+	content := "youtube dQw4w9WgXcQ\n\nvimeo 12345\n  youtube jNQXAC9IVRw  \n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := OpenArchive(path)
+	if err != nil {
+		t.Fatalf("OpenArchive() error = %v", err)
+	}
+	if archive.Len() != 2 || archive.Has("12345") {
+		t.Errorf("archive = %v, want the two YouTube videos", archive.ids)
+	}
+}
@@ -0,0 +1,147 @@
+package download
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// throughputTolerance is the relative throughput gain a level must bring to be kept.
+const throughputTolerance = 0.05
+
+// ConcurrencyTuner holds a concurrency level within user-set bounds, which Tune
+// adjusts to the measured throughput. It is safe for concurrent use.
+type ConcurrencyTuner struct {
+	mu        sync.Mutex
+	min       int
+	max       int
+	current   int
+	previous  int // Level before the one being tried
+	direction int
+}
+
+// NewConcurrencyTuner creates a tuner bounded by minLevel and maxLevel, starting at minLevel.
+// Bounds below 1 are raised to 1, and maxLevel is raised to minLevel if smaller.
+func NewConcurrencyTuner(minLevel, maxLevel int) *ConcurrencyTuner {
+	if minLevel < 1 {
+		minLevel = 1
+	}
+	if maxLevel < minLevel {
+		maxLevel = minLevel
+	}
+	return &ConcurrencyTuner{
+		min:       minLevel,
+		max:       maxLevel,
+		current:   minLevel,
+		direction: 1,
+	}
+}
+
+// Concurrency returns the current recommended concurrency level.
+func (t *ConcurrencyTuner) Concurrency() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current
+}
+
+// try moves one level in the tuner's direction, turning back at the bounds,
+// and reports whether the level changed.
+func (t *ConcurrencyTuner) try() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	next := t.current + t.direction
+	if next < t.min || next > t.max {
+		t.direction = -t.direction
+		next = t.current + t.direction
+	}
+	if next < t.min || next > t.max {
+		return false
+	}
+	t.previous, t.current = t.current, next
+	return true
+}
+
+// revert goes back to the level before the one tried, to try the other
+// direction next time.
+func (t *ConcurrencyTuner) revert() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current = t.previous
+	t.direction = -t.direction
+}
+
+// ThroughputProbe measures achievable throughput, returning the bytes transferred
+// and the time it took.
+type ThroughputProbe func(ctx context.Context) (bytes int64, elapsed time.Duration, err error)
+
+// Tune adjusts the tuners one at a time, measuring throughput with probe every
+// interval until the context is canceled. Each probe judges the single change
+// made since the one before: a level tried is kept only if it raised the
+// throughput, and the next tuner in turn then tries a level; otherwise the
+// tuner goes back, and the next probe measures the level it went back to.
+// Failed probes and empty samples are skipped. The optional onChange callback
+// is invoked whenever the level of a tuner changes.
+func Tune(ctx context.Context, interval time.Duration, probe ThroughputProbe, onChange func(tuner *ConcurrencyTuner, level int), tuners ...*ConcurrencyTuner) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s := &tuning{tuners: tuners, tried: -1, onChange: onChange}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			bytes, elapsed, err := probe(ctx)
+			if err != nil || bytes <= 0 || elapsed <= 0 {
+				continue
+			}
+			s.observe(float64(bytes) / elapsed.Seconds())
+		}
+	}
+}
+
+// tuning is the state of Tune between probes.
+type tuning struct {
+	tuners   []*ConcurrencyTuner
+	onChange func(*ConcurrencyTuner, int)
+
+	// tried is the index of the tuner that tried a level since the last
+	// probe, -1 if none did; next is the index of the next tuner to try one.
+	tried int
+	next  int
+
+	// last is the throughput of the last probe, before the level tried.
+	last float64
+}
+
+// observe judges the change made since the last probe by its throughput and
+// makes the next one.
+func (s *tuning) observe(throughput float64) {
+	if s.tried >= 0 {
+		t := s.tuners[s.tried]
+		s.tried = -1
+		if throughput <= s.last*(1+throughputTolerance) {
+			t.revert()
+			s.changed(t)
+			return
+		}
+	}
+	s.last = throughput
+
+	for range s.tuners {
+		i := s.next
+		s.next = (s.next + 1) % len(s.tuners)
+		if s.tuners[i].try() {
+			s.tried = i
+			s.changed(s.tuners[i])
+			return
+		}
+	}
+}
+
+// changed reports the level of t to the callback, if there is one.
+func (s *tuning) changed(t *ConcurrencyTuner) {
+	if s.onChange != nil {
+		s.onChange(t, t.Concurrency())
+	}
+}
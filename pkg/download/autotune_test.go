@@ -0,0 +1,106 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewConcurrencyTuner_ClampsBounds(t *testing.T) {
+	tuner := NewConcurrencyTuner(0, -3)
+	if got := tuner.Concurrency(); got != 1 {
+		t.Errorf("expected concurrency 1, got %d", got)
+	}
+
+	tuner = NewConcurrencyTuner(2, 8)
+	if got := tuner.Concurrency(); got != 2 {
+		t.Errorf("expected to start at min (2), got %d", got)
+	}
+}
+
+func TestConcurrencyTuner_TryTurnsBackAtBounds(t *testing.T) {
+	tuner := NewConcurrencyTuner(1, 2)
+	var levels []int
+	for range 4 {
+		tuner.try()
+		levels = append(levels, tuner.Concurrency())
+	}
+	if want := []int{2, 1, 2, 1}; !slices.Equal(levels, want) {
+		t.Errorf("levels = %v, want %v", levels, want)
+	}
+
+	tuner.revert()
+	if got := tuner.Concurrency(); got != 2 {
+		t.Errorf("level after revert = %d, want 2", got)
+	}
+	if fixed := NewConcurrencyTuner(3, 3); fixed.try() || fixed.Concurrency() != 3 {
+		t.Errorf("tuner with equal bounds moved to %d", fixed.Concurrency())
+	}
+}
+
+// peakThroughput is a throughput that is highest with 3 jobs and 2 chunks,
+// falling off with every level away from them.
+func peakThroughput(jobs, chunks int) float64 {
+	dj, dc := float64(jobs-3), float64(chunks-2)
+	return 1000 - 100*dj*dj - 100*dc*dc
+}
+
+func TestTuning_KnobsSettle(t *testing.T) {
+	jobs, chunks := NewConcurrencyTuner(1, 6), NewConcurrencyTuner(1, 6)
+	s := &tuning{tuners: []*ConcurrencyTuner{jobs, chunks}, tried: -1}
+
+	atPeak := 0
+	for i := range 60 {
+		before := [2]int{jobs.Concurrency(), chunks.Concurrency()}
+		s.observe(peakThroughput(before[0], before[1]))
+		after := [2]int{jobs.Concurrency(), chunks.Concurrency()}
+
+		if moved := abs(after[0]-before[0]) + abs(after[1]-before[1]); moved > 1 {
+			t.Fatalf("probe %d moved from %v to %v, want one level of one knob", i, before, after)
+		}
+		if i < 20 {
+			continue
+		}
+		// Once settled, only a level next to the peak is tried, and it's given up
+		if off := abs(after[0]-3) + abs(after[1]-2); off > 1 {
+			t.Errorf("probe %d left the knobs at %v, want next to 3 jobs and 2 chunks", i, after)
+		}
+		if after == [2]int{3, 2} {
+			atPeak++
+		}
+	}
+	if atPeak < 15 {
+		t.Errorf("knobs were back at the peak after %d of 40 probes, want every other one", atPeak)
+	}
+}
+
+func TestTune_SkipsFailedProbes(t *testing.T) {
+	jobs, chunks := NewConcurrencyTuner(1, 6), NewConcurrencyTuner(1, 6)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	probe := func(context.Context) (int64, time.Duration, error) {
+		calls++
+		switch {
+		case calls == 200:
+			cancel()
+		case calls%3 == 0:
+			return 0, 0, errors.New("nothing to measure")
+		}
+		return int64(peakThroughput(jobs.Concurrency(), chunks.Concurrency())), time.Second, nil
+	}
+	changes := 0
+	Tune(ctx, time.Microsecond, probe, func(*ConcurrencyTuner, int) { changes++ }, jobs, chunks)
+
+	if off := abs(jobs.Concurrency()-3) + abs(chunks.Concurrency()-2); off > 1 || changes == 0 {
+		t.Errorf("tuned to %d jobs and %d chunks in %d changes, want next to 3 and 2",
+			jobs.Concurrency(), chunks.Concurrency(), changes)
+	}
+}
+
+func abs(n int) int {
+	return max(n, -n)
+}
@@ -0,0 +1,509 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrItemCanceled is the error of a batch item canceled with BatchDownloader.Cancel.
+var ErrItemCanceled = errors.New("batch item canceled")
+
+// ErrNoBatchItem is returned when controlling an item that isn't in the running batch.
+var ErrNoBatchItem = errors.New("no such batch item")
+
+// errItemPaused is the cause of a running download stopped by BatchDownloader.Pause.
+var errItemPaused = errors.New("batch item paused")
+
+// BatchProgress represents the progress of a batch download operation.
+type BatchProgress struct {
+	// CompletedCount is the number of videos that are done: downloaded,
+	// failed or canceled.
+	CompletedCount int
+
+	// TotalCount is the total number of videos in the batch.
+	TotalCount int
+
+	// CurrentIndex is the index of the video currently being downloaded (0-based).
+	CurrentIndex int
+
+	// CurrentTitle is the title of the video currently being downloaded.
+	CurrentTitle string
+
+	// CurrentProgress is the download progress of the current video.
+	CurrentProgress Progress
+
+	// CurrentState is the state of the current video.
+	CurrentState BatchItemState
+
+	// CompletedBytes is the number of bytes written by the videos that are done.
+	CompletedBytes int64
+
+	// DownloadedBytes is CompletedBytes plus the bytes of the current video so far.
+	DownloadedBytes int64
+
+	// TotalBytes is the estimated size of the whole batch: the sizes of the
+	// videos known so far, with each video of unknown size counted as their
+	// average. 0 until any size is known.
+	TotalBytes int64
+
+	// Speed is the transfer rate of the batch in bytes per second.
+	Speed float64
+
+	// ETA is the estimated time until the whole batch completes. 0 if unknown.
+	ETA time.Duration
+}
+
+// OverallPercentage returns the overall batch completion percentage (0-100),
+// by bytes once the size of the batch is estimated and by videos before.
+func (bp BatchProgress) OverallPercentage() float64 {
+	if bp.TotalBytes > 0 {
+		return min(float64(bp.DownloadedBytes)/float64(bp.TotalBytes)*100, 100)
+	}
+	if bp.TotalCount == 0 {
+		return 0
+	}
+	return float64(bp.CompletedCount) / float64(bp.TotalCount) * 100
+}
+
+// String returns a human-readable string representation of the batch progress,
+// such as "3/10 videos complete, 120.0 MiB / 400.0 MiB, ETA 2m20s".
+func (bp BatchProgress) String() string {
+	s := fmt.Sprintf("%d/%d videos complete", bp.CompletedCount, bp.TotalCount)
+	if bp.TotalBytes > 0 {
+		s += fmt.Sprintf(", %s / %s", FormatBytes(bp.DownloadedBytes), FormatBytes(bp.TotalBytes))
+	}
+	if bp.ETA > 0 {
+		s += ", ETA " + bp.ETA.Round(time.Second).String()
+	}
+	return s
+}
+
+// BatchProgressCallback is a function called to report batch download progress.
+type BatchProgressCallback func(BatchProgress)
+
+// BatchItemState is the state of an item of a batch download.
+type BatchItemState int
+
+const (
+	// BatchItemPending is an item waiting for its turn.
+	BatchItemPending BatchItemState = iota
+	// BatchItemRunning is the item being downloaded.
+	BatchItemRunning
+	// BatchItemPaused is an item skipped until it's resumed.
+	BatchItemPaused
+	// BatchItemCompleted is an item downloaded successfully.
+	BatchItemCompleted
+	// BatchItemFailed is an item whose download failed.
+	BatchItemFailed
+	// BatchItemCanceled is an item canceled before it completed.
+	BatchItemCanceled
+)
+
+// String returns the name of the state.
+func (s BatchItemState) String() string {
+	switch s {
+	case BatchItemPending:
+		return "pending"
+	case BatchItemRunning:
+		return "running"
+	case BatchItemPaused:
+		return "paused"
+	case BatchItemCompleted:
+		return "completed"
+	case BatchItemFailed:
+		return "failed"
+	case BatchItemCanceled:
+		return "canceled"
+	default:
+		return fmt.Sprintf("BatchItemState(%d)", int(s))
+	}
+}
+
+// done reports whether the item is finished for good.
+func (s BatchItemState) done() bool {
+	return s >= BatchItemCompleted
+}
+
+// BatchItem represents a single item in a batch download.
+type BatchItem struct {
+	// URL is the stream URL to download from.
+	URL string
+
+	// FilePath is the destination file path.
+	FilePath string
+
+	// Title is the video title (used for progress reporting).
+	Title string
+
+	// Size is the expected size of the stream in bytes, such as its reported
+	// content length, used to estimate the size of the batch before the item
+	// starts. 0 if unknown.
+	Size int64
+}
+
+// BatchDownloader handles downloading multiple videos as a batch.
+// While DownloadBatch runs, its items can be paused, resumed and canceled
+// one by one from other goroutines; it controls one batch at a time.
+type BatchDownloader struct {
+	downloader *Downloader
+
+	mu      sync.Mutex
+	items   []*batchItem  // Items of the running batch
+	changed chan struct{} // Closed and replaced when a paused item is resumed or canceled
+}
+
+// batchItem is the control state of an item of the running batch.
+type batchItem struct {
+	state  BatchItemState
+	cancel context.CancelCauseFunc // Stops the item while it's running
+}
+
+// NewBatchDownloader creates a new BatchDownloader.
+func NewBatchDownloader(downloader *Downloader) *BatchDownloader {
+	return &BatchDownloader{downloader: downloader}
+}
+
+// DownloadBatch downloads all items sequentially and reports progress.
+// Returns a slice of DownloadResult in the same order as the input items.
+//
+// Items are downloaded in order, skipping paused ones; a resumed item is
+// downloaded next. DownloadBatch returns once every item is done, so a paused
+// item holds it until it's resumed or canceled, or ctx is done. Canceled
+// items fail with ErrItemCanceled.
+func (bd *BatchDownloader) DownloadBatch(ctx context.Context, items []BatchItem, progress BatchProgressCallback) []DownloadResult {
+	results := make([]DownloadResult, len(items))
+	resumeTargets := make([]string, len(items)) // Files of paused downloads, by item
+	tracker := newBatchTracker(items, time.Now)
+	bd.start(len(items))
+	defer bd.stop()
+
+	for {
+		i, itemCtx, ok := bd.next(ctx)
+		if !ok {
+			break
+		}
+		item := items[i]
+
+		// Report starting this video
+		if progress != nil {
+			progress(tracker.progress(i, item.Title, Progress{}, BatchItemRunning))
+		}
+
+		// Create progress callback for current video
+		var videoProgress ProgressCallback
+		if progress != nil {
+			videoProgress = func(p Progress) {
+				progress(tracker.progress(i, item.Title, p, BatchItemRunning))
+			}
+		}
+
+		// Download this video. A resumed one replaces the partial file of its
+		// paused download, which the overwrite policy would take for an existing
+		// file and skip or rename around.
+		var result DownloadResult
+		if target := resumeTargets[i]; target != "" {
+			result = bd.downloader.downloadTarget(itemCtx, item.URL, target, videoProgress)
+		} else {
+			result = bd.downloader.DownloadStreamResult(itemCtx, item.URL, item.FilePath, videoProgress)
+		}
+		result.Title = item.Title
+		state := bd.finish(i, result.Error)
+		switch state {
+		case BatchItemPaused:
+			// It's downloaded again from the start once resumed
+			if !result.Skipped {
+				resumeTargets[i] = result.FilePath
+			}
+		case BatchItemCanceled:
+			result.Error = ErrItemCanceled
+			results[i] = result
+		default:
+			tracker.complete(i, result.Size)
+			results[i] = result
+		}
+		tracker.dropCanceled(bd.states())
+
+		// Report completion of this video
+		if progress != nil {
+			progress(tracker.progress(i, item.Title, Progress{}, state))
+		}
+	}
+
+	// Items not done when ctx ended fail with its error
+	for i, state := range bd.states() {
+		switch {
+		case state == BatchItemCanceled && results[i].Error == nil:
+			results[i] = DownloadResult{FilePath: items[i].FilePath, Error: ErrItemCanceled, Title: items[i].Title}
+		case !state.done():
+			results[i] = DownloadResult{FilePath: items[i].FilePath, Error: ctx.Err(), Title: items[i].Title}
+		}
+	}
+	return results
+}
+
+// Pause pauses the i-th item of the running batch. A pending item is skipped
+// until it's resumed; a running one is stopped, and downloaded again from the
+// start once resumed, over the partial file it left whatever the overwrite
+// policy. Pausing a paused item does nothing.
+func (bd *BatchDownloader) Pause(i int) error {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+	item, err := bd.itemLocked(i)
+	if err != nil {
+		return err
+	}
+	switch item.state {
+	case BatchItemPending:
+		item.state = BatchItemPaused
+	case BatchItemRunning:
+		item.state = BatchItemPaused
+		item.cancel(errItemPaused)
+	case BatchItemPaused:
+	default:
+		return fmt.Errorf("cannot pause batch item %d: it is %s", i, item.state)
+	}
+	return nil
+}
+
+// Resume resumes the i-th item of the running batch after Pause, so it's
+// downloaded next. Resuming an item that isn't paused and not done does nothing.
+func (bd *BatchDownloader) Resume(i int) error {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+	item, err := bd.itemLocked(i)
+	if err != nil {
+		return err
+	}
+	switch {
+	case item.state == BatchItemPaused:
+		item.state = BatchItemPending
+		bd.notifyLocked()
+	case item.state.done():
+		return fmt.Errorf("cannot resume batch item %d: it is %s", i, item.state)
+	}
+	return nil
+}
+
+// Cancel cancels the i-th item of the running batch, stopping it if it's
+// running; the other items continue. Its result fails with ErrItemCanceled.
+func (bd *BatchDownloader) Cancel(i int) error {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+	item, err := bd.itemLocked(i)
+	if err != nil {
+		return err
+	}
+	switch item.state {
+	case BatchItemPending, BatchItemPaused:
+		item.state = BatchItemCanceled
+		bd.notifyLocked()
+	case BatchItemRunning:
+		item.state = BatchItemCanceled
+		item.cancel(ErrItemCanceled)
+	default:
+		return fmt.Errorf("cannot cancel batch item %d: it is %s", i, item.state)
+	}
+	return nil
+}
+
+// State returns the state of the i-th item of the running batch.
+func (bd *BatchDownloader) State(i int) (BatchItemState, error) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+	item, err := bd.itemLocked(i)
+	if err != nil {
+		return 0, err
+	}
+	return item.state, nil
+}
+
+// start sets up the control state of a batch of count items.
+func (bd *BatchDownloader) start(count int) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+	bd.items = make([]*batchItem, count)
+	for i := range bd.items {
+		bd.items[i] = &batchItem{}
+	}
+	bd.changed = make(chan struct{})
+}
+
+// stop drops the control state once the batch is over.
+func (bd *BatchDownloader) stop() {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+	bd.items = nil
+}
+
+// next marks the first pending item running and returns it with the context to
+// download it under. With only paused items left it waits for one to be resumed
+// or canceled. It returns false when every item is done or ctx is.
+func (bd *BatchDownloader) next(ctx context.Context) (int, context.Context, bool) {
+	for {
+		if ctx.Err() != nil {
+			return 0, nil, false
+		}
+		bd.mu.Lock()
+		paused := false
+		for i, item := range bd.items {
+			switch item.state {
+			case BatchItemPending:
+				itemCtx, cancel := context.WithCancelCause(ctx)
+				item.state, item.cancel = BatchItemRunning, cancel
+				bd.mu.Unlock()
+				return i, itemCtx, true
+			case BatchItemPaused:
+				paused = true
+			}
+		}
+		changed := bd.changed
+		bd.mu.Unlock()
+
+		if !paused {
+			return 0, nil, false
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// finish records the outcome of the i-th item's download and returns its
+// state: paused or canceled if it was stopped, otherwise completed or failed.
+func (bd *BatchDownloader) finish(i int, err error) BatchItemState {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+	item := bd.items[i]
+	item.cancel(nil)
+	item.cancel = nil
+	if item.state == BatchItemRunning {
+		item.state = BatchItemCompleted
+		if err != nil {
+			item.state = BatchItemFailed
+		}
+	}
+	return item.state
+}
+
+// states returns the state of every item of the running batch.
+func (bd *BatchDownloader) states() []BatchItemState {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+	states := make([]BatchItemState, len(bd.items))
+	for i, item := range bd.items {
+		states[i] = item.state
+	}
+	return states
+}
+
+func (bd *BatchDownloader) itemLocked(i int) (*batchItem, error) {
+	if i < 0 || i >= len(bd.items) {
+		return nil, fmt.Errorf("%w: %d", ErrNoBatchItem, i)
+	}
+	return bd.items[i], nil
+}
+
+// notifyLocked wakes a batch waiting for paused items.
+func (bd *BatchDownloader) notifyLocked() {
+	close(bd.changed)
+	bd.changed = make(chan struct{})
+}
+
+// batchTracker tracks the bytes of a batch download.
+type batchTracker struct {
+	sizes     []int64 // Known size per item, 0 if unknown
+	done      []bool  // Items finished, at their final size
+	dropped   []bool  // Canceled items, left out of the estimate
+	completed int     // Number of items done
+	bytes     int64   // Bytes written by finished items
+	meter     *speedMeter
+}
+
+func newBatchTracker(items []BatchItem, now func() time.Time) *batchTracker {
+	t := &batchTracker{
+		sizes:   make([]int64, len(items)),
+		done:    make([]bool, len(items)),
+		dropped: make([]bool, len(items)),
+		meter:   newSpeedMeter(now),
+	}
+	for i, item := range items {
+		t.sizes[i] = max(item.Size, 0)
+	}
+	return t
+}
+
+// complete records that the i-th item finished after writing size bytes, which
+// is its size from now on, even if it failed.
+func (t *batchTracker) complete(i int, size int64) {
+	t.completed++
+	t.bytes += size
+	t.sizes[i] = size
+	t.done[i] = true
+}
+
+// drop records that the i-th item was canceled.
+func (t *batchTracker) drop(i int) {
+	t.completed++
+	t.sizes[i] = 0
+	t.done[i], t.dropped[i] = true, true
+}
+
+// dropCanceled records the items canceled since the last call.
+func (t *batchTracker) dropCanceled(states []BatchItemState) {
+	for i, state := range states {
+		if state == BatchItemCanceled && !t.done[i] {
+			t.drop(i)
+		}
+	}
+}
+
+// progress returns the progress of the batch while the i-th item is at current.
+func (t *batchTracker) progress(i int, title string, current Progress, state BatchItemState) BatchProgress {
+	downloaded := t.bytes
+	if !t.done[i] {
+		if current.Total > 0 {
+			t.sizes[i] = current.Total
+		}
+		downloaded += current.Downloaded
+	}
+
+	overall := t.meter.progress(downloaded, t.estimatedTotal())
+	return BatchProgress{
+		CompletedCount:  t.completed,
+		TotalCount:      len(t.sizes),
+		CurrentIndex:    i,
+		CurrentTitle:    title,
+		CurrentProgress: current,
+		CurrentState:    state,
+		CompletedBytes:  t.bytes,
+		DownloadedBytes: downloaded,
+		TotalBytes:      overall.Total,
+		Speed:           overall.Speed,
+		ETA:             overall.ETA,
+	}
+}
+
+// estimatedTotal returns the known sizes plus their average for each item of
+// unknown size, or 0 when no size is known. Finished items count at the size
+// they were written with and canceled ones not at all.
+func (t *batchTracker) estimatedTotal() int64 {
+	var known, count, open int64
+	for i, size := range t.sizes {
+		switch {
+		case t.dropped[i]:
+		case size > 0 || t.done[i]:
+			known += size
+			count++
+		default:
+			open++
+		}
+	}
+	if known == 0 {
+		return 0
+	}
+	return known + known/count*open
+}
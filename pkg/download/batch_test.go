@@ -0,0 +1,249 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// newStallingServer serves content, except that the first stalls requests are
+// sent only half of it and then held until the client goes away.
+func newStallingServer(t *testing.T, content []byte, stalls int32) *httptest.Server {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(content)))
+		if requests.Add(1) > stalls {
+			_, _ = w.Write(content)
+			return
+		}
+		_, _ = w.Write(content[:len(content)/2])
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newBatchItems(t *testing.T, servers ...*httptest.Server) []BatchItem {
+	t.Helper()
+	dir := t.TempDir()
+	items := make([]BatchItem, len(servers))
+	for i, server := range servers {
+		items[i] = BatchItem{URL: server.URL, FilePath: filepath.Join(dir, fmt.Sprintf("v%d.mp4", i)), Title: fmt.Sprintf("Video %d", i)}
+	}
+	return items
+}
+
+func TestBatchDownloader_CancelRunningItem(t *testing.T) {
+	content := testContent(1000)
+	items := newBatchItems(t, newStallingServer(t, content, 1), newStallingServer(t, content, 0))
+
+	bd := NewBatchDownloader(NewDownloader(http.DefaultClient))
+	results := bd.DownloadBatch(context.Background(), items, func(bp BatchProgress) {
+		if bp.CurrentIndex == 0 && bp.CurrentProgress.Downloaded > 0 {
+			if err := bd.Cancel(0); err != nil {
+				t.Errorf("Cancel(0) failed: %v", err)
+			}
+		}
+	})
+
+	if !errors.Is(results[0].Error, ErrItemCanceled) {
+		t.Errorf("results[0].Error = %v, want ErrItemCanceled", results[0].Error)
+	}
+	if results[1].Error != nil {
+		t.Errorf("results[1].Error = %v, want the other item downloaded", results[1].Error)
+	}
+}
+
+func TestBatchDownloader_CancelPendingItem(t *testing.T) {
+	content := testContent(100)
+	server := newStallingServer(t, content, 0)
+	items := newBatchItems(t, server, server, server)
+
+	bd := NewBatchDownloader(NewDownloader(http.DefaultClient))
+	var last BatchProgress
+	results := bd.DownloadBatch(context.Background(), items, func(bp BatchProgress) {
+		if bp.CurrentIndex == 0 && bp.CurrentState == BatchItemRunning {
+			_ = bd.Cancel(1)
+		}
+		last = bp
+	})
+
+	if !errors.Is(results[1].Error, ErrItemCanceled) || results[1].Title != "Video 1" {
+		t.Errorf("results[1] = %+v, want canceled", results[1])
+	}
+	if _, err := os.Stat(items[1].FilePath); !os.IsNotExist(err) {
+		t.Errorf("canceled item was downloaded")
+	}
+	if results[0].Error != nil || results[2].Error != nil {
+		t.Errorf("other items failed: %v, %v", results[0].Error, results[2].Error)
+	}
+	if last.CompletedCount != 3 || last.TotalBytes != 200 {
+		t.Errorf("last progress = %d done, %d bytes in total; want 3, 200", last.CompletedCount, last.TotalBytes)
+	}
+}
+
+func TestBatchDownloader_PauseAndResume(t *testing.T) {
+	content := testContent(1000)
+	items := newBatchItems(t, newStallingServer(t, content, 1), newStallingServer(t, content, 0))
+
+	bd := NewBatchDownloader(NewDownloader(http.DefaultClient))
+	var order []int
+	var paused bool
+	results := bd.DownloadBatch(context.Background(), items, func(bp BatchProgress) {
+		switch {
+		case bp.CurrentIndex == 0 && bp.CurrentProgress.Downloaded > 0 && !paused:
+			paused = true
+			if err := bd.Pause(0); err != nil {
+				t.Errorf("Pause(0) failed: %v", err)
+			}
+		case bp.CurrentState == BatchItemPaused:
+			order = append(order, -bp.CurrentIndex-1)
+		case bp.CurrentState == BatchItemCompleted:
+			order = append(order, bp.CurrentIndex)
+			if err := bd.Resume(0); bp.CurrentIndex == 1 && err != nil {
+				t.Errorf("Resume(0) failed: %v", err)
+			}
+		}
+	})
+
+	for i, result := range results {
+		if result.Error != nil {
+			t.Fatalf("results[%d].Error = %v", i, result.Error)
+		}
+		if got, _ := os.ReadFile(items[i].FilePath); len(got) != len(content) {
+			t.Errorf("item %d has %d bytes, want %d", i, len(got), len(content))
+		}
+	}
+	// The first item pauses, the second completes, then the first once resumed
+	if want := []int{-1, 1, 0}; fmt.Sprint(order) != fmt.Sprint(want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
+
+func TestBatchDownloader_PauseAndResumeReplacesPartialFile(t *testing.T) {
+	for _, policy := range []OverwritePolicy{OverwriteExisting, SkipExisting, RenameExisting} {
+		t.Run(policy.String(), func(t *testing.T) {
+			content := testContent(1000)
+			items := newBatchItems(t, newStallingServer(t, content, 1))
+
+			bd := NewBatchDownloader(NewDownloader(http.DefaultClient, WithOverwritePolicy(policy)))
+			var paused bool
+			results := bd.DownloadBatch(context.Background(), items, func(bp BatchProgress) {
+				switch {
+				case bp.CurrentProgress.Downloaded > 0 && !paused:
+					paused = true
+					if err := bd.Pause(0); err != nil {
+						t.Errorf("Pause(0) failed: %v", err)
+					}
+				case bp.CurrentState == BatchItemPaused:
+					if err := bd.Resume(0); err != nil {
+						t.Errorf("Resume(0) failed: %v", err)
+					}
+				}
+			})
+
+			// The partial file of the paused download is neither kept as done nor renamed around
+			result := results[0]
+			if result.Error != nil || result.Skipped || result.FilePath != items[0].FilePath {
+				t.Fatalf("result = %+v, want %s downloaded", result, items[0].FilePath)
+			}
+			if got, _ := os.ReadFile(items[0].FilePath); len(got) != len(content) {
+				t.Errorf("file has %d bytes, want %d", len(got), len(content))
+			}
+			if entries, _ := os.ReadDir(filepath.Dir(items[0].FilePath)); len(entries) != 1 {
+				t.Errorf("directory has %d files, want only the download", len(entries))
+			}
+		})
+	}
+}
+
+func TestBatchDownloader_WaitsForPausedItem(t *testing.T) {
+	server := newStallingServer(t, testContent(100), 0)
+	items := newBatchItems(t, server, server)
+
+	bd := NewBatchDownloader(NewDownloader(http.DefaultClient))
+	done := make(chan []DownloadResult)
+	started := make(chan struct{})
+	go func() {
+		done <- bd.DownloadBatch(context.Background(), items, func(bp BatchProgress) {
+			if bp.CurrentIndex == 0 && bp.CurrentState == BatchItemRunning && bp.CurrentProgress.Downloaded == 0 {
+				_ = bd.Pause(1)
+			}
+			if bp.CurrentIndex == 0 && bp.CurrentState == BatchItemCompleted {
+				close(started)
+			}
+		})
+	}()
+
+	<-started
+	select {
+	case <-done:
+		t.Fatal("DownloadBatch returned with an item paused")
+	default:
+	}
+	if state, err := bd.State(1); err != nil || state != BatchItemPaused {
+		t.Fatalf("State(1) = %v, %v; want paused", state, err)
+	}
+	if err := bd.Resume(1); err != nil {
+		t.Fatalf("Resume(1) failed: %v", err)
+	}
+	results := <-done
+	if results[1].Error != nil {
+		t.Errorf("results[1].Error = %v, want resumed item downloaded", results[1].Error)
+	}
+}
+
+func TestBatchDownloader_ControlErrors(t *testing.T) {
+	bd := NewBatchDownloader(NewDownloader(http.DefaultClient))
+	if err := bd.Pause(0); !errors.Is(err, ErrNoBatchItem) {
+		t.Errorf("Pause without a batch = %v, want ErrNoBatchItem", err)
+	}
+
+	server := newStallingServer(t, testContent(100), 0)
+	items := newBatchItems(t, server, server)
+	bd.DownloadBatch(context.Background(), items, func(bp BatchProgress) {
+		if bp.CurrentIndex != 1 || bp.CurrentState != BatchItemRunning || bp.CurrentProgress.Downloaded != 0 {
+			return
+		}
+		if err := bd.Cancel(0); err == nil {
+			t.Error("Cancel of a completed item succeeded")
+		}
+		if err := bd.Resume(1); err != nil {
+			t.Errorf("Resume of a running item = %v, want no-op", err)
+		}
+		if err := bd.Pause(2); !errors.Is(err, ErrNoBatchItem) {
+			t.Errorf("Pause(2) = %v, want ErrNoBatchItem", err)
+		}
+	})
+}
+
+func TestBatchDownloader_CanceledContextWithPausedItem(t *testing.T) {
+	server := newStallingServer(t, testContent(100), 0)
+	items := newBatchItems(t, server, server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bd := NewBatchDownloader(NewDownloader(http.DefaultClient))
+	results := bd.DownloadBatch(ctx, items, func(bp BatchProgress) {
+		if bp.CurrentIndex == 0 && bp.CurrentState == BatchItemRunning {
+			_ = bd.Pause(1)
+		}
+		if bp.CurrentIndex == 0 && bp.CurrentState == BatchItemCompleted {
+			cancel()
+		}
+	})
+
+	if results[0].Error != nil {
+		t.Errorf("results[0].Error = %v", results[0].Error)
+	}
+	if !errors.Is(results[1].Error, context.Canceled) || results[1].Title != "Video 1" {
+		t.Errorf("results[1] = %+v, want context canceled", results[1])
+	}
+}
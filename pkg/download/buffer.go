@@ -0,0 +1,43 @@
+package download
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBufferSize is the size of the buffer streams are copied through.
+	// Large buffers mean fewer, larger writes to disk for gigabyte-sized files.
+	DefaultBufferSize = 1 << 20
+
+	// DefaultProgressInterval is the minimum time between progress reports,
+	// so callbacks run ten times a second rather than after every network read.
+	DefaultProgressInterval = 100 * time.Millisecond
+)
+
+// bufferPools holds a *sync.Pool of copy buffers for each buffer size, shared by
+// all downloaders so parallel and successive transfers reuse their buffers.
+var bufferPools sync.Map
+
+// getBuffer returns a buffer of size bytes from the pool, DefaultBufferSize if
+// size isn't positive.
+func getBuffer(size int) *[]byte {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	pool, ok := bufferPools.Load(size)
+	if !ok {
+		pool, _ = bufferPools.LoadOrStore(size, &sync.Pool{New: func() any {
+			buf := make([]byte, size)
+			return &buf
+		}})
+	}
+	return pool.(*sync.Pool).Get().(*[]byte)
+}
+
+// putBuffer returns a buffer from getBuffer to its pool.
+func putBuffer(buf *[]byte) {
+	if pool, ok := bufferPools.Load(len(*buf)); ok {
+		pool.(*sync.Pool).Put(buf)
+	}
+}
@@ -0,0 +1,62 @@
+package download
+
+import "sync"
+
+// Dedup skips the videos already downloaded, so a video in several playlists of
+// one run is only downloaded once. The zero value remembers the videos claimed
+// in the run; an Archive extends that to earlier runs. A nil Dedup claims every
+// video. It is safe for concurrent use.
+type Dedup struct {
+	// Archive, if set, holds the videos of earlier runs, which are skipped, and
+	// records those downloaded in this one.
+	Archive *Archive
+
+	// AllowRepeats downloads a video as often as it is claimed in the run,
+	// skipping only the videos in the Archive.
+	AllowRepeats bool
+
+	mu      sync.Mutex
+	claimed map[string]bool
+}
+
+// Reasons Claim gives for skipping a video.
+const (
+	// SkipClaimed is for videos claimed earlier in the run, whether or not
+	// their download succeeded, so a failure is only reported once.
+	SkipClaimed = "claimed"
+	// SkipArchived is for videos in the archive.
+	SkipArchived = "archived"
+)
+
+// Claim claims the video for download. It returns "" if the video should be
+// downloaded, or else why it is skipped: SkipClaimed, unless repeats are
+// allowed, or SkipArchived.
+func (d *Dedup) Claim(videoID string) (skip string) {
+	if d == nil {
+		return ""
+	}
+	if !d.AllowRepeats {
+		d.mu.Lock()
+		claimed := d.claimed[videoID]
+		if d.claimed == nil {
+			d.claimed = make(map[string]bool)
+		}
+		d.claimed[videoID] = true
+		d.mu.Unlock()
+		if claimed {
+			return SkipClaimed
+		}
+	}
+	if d.Archive != nil && d.Archive.Has(videoID) {
+		return SkipArchived
+	}
+	return ""
+}
+
+// Done records a downloaded video in the archive, if there is one.
+func (d *Dedup) Done(videoID string) error {
+	if d == nil || d.Archive == nil {
+		return nil
+	}
+	return d.Archive.Add(videoID)
+}
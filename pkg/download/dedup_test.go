@@ -0,0 +1,55 @@
+package download
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDedup_ClaimsEachVideoOnce(t *testing.T) {
+	var d Dedup
+	if d.Claim("a") != "" || d.Claim("b") != "" {
+		t.Fatal("new videos should be claimed")
+	}
+	if got := d.Claim("a"); got != SkipClaimed {
+		t.Errorf("Claim() = %q for a video claimed earlier in the run, want %q", got, SkipClaimed)
+	}
+}
+
+func TestDedup_Archive(t *testing.T) {
+	archive, err := OpenArchive(filepath.Join(t.TempDir(), "archive.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Add("old"); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Dedup{Archive: archive}
+	if got := d.Claim("old"); got != SkipArchived {
+		t.Errorf("Claim() = %q for an archived video, want %q", got, SkipArchived)
+	}
+	if d.Claim("new") != "" {
+		t.Fatal("a new video should be claimed")
+	}
+	if err := d.Done("new"); err != nil {
+		t.Fatalf("Done() error = %v", err)
+	}
+	if got := d.Claim("new"); !archive.Has("new") || got != SkipClaimed {
+		t.Errorf("Claim() = %q after Done, want the video archived and skipped as claimed", got)
+	}
+
+	repeats := &Dedup{Archive: archive, AllowRepeats: true}
+	if repeats.Claim("other") != "" || repeats.Claim("other") != "" {
+		t.Error("with AllowRepeats a video should be claimed every time")
+	}
+	if got := repeats.Claim("new"); got != SkipArchived {
+		t.Errorf("Claim() = %q with AllowRepeats, want archived videos still skipped", got)
+	}
+}
+
+func TestDedup_Nil(t *testing.T) {
+	var d *Dedup
+	if d.Claim("a") != "" || d.Claim("a") != "" || d.Done("a") != nil {
+		t.Error("a nil Dedup should claim every video and record nothing")
+	}
+}
@@ -0,0 +1,120 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// errFreeSpaceUnsupported is returned by statVolume on platforms where free space can't be queried.
+var errFreeSpaceUnsupported = errors.New("free space lookup not supported on this platform")
+
+// SpaceRequirement is the number of bytes a download needs in a directory.
+type SpaceRequirement struct {
+	// Dir is the directory the bytes are written to. It doesn't need to exist yet.
+	Dir string
+
+	// Bytes is the number of bytes needed.
+	Bytes int64
+}
+
+// InsufficientSpaceError is returned when a filesystem doesn't have room for a download.
+type InsufficientSpaceError struct {
+	// Dir is the directory whose filesystem is too small.
+	Dir string
+
+	// Required is the number of bytes needed on the filesystem.
+	Required int64
+
+	// Available is the number of bytes free on the filesystem.
+	Available int64
+}
+
+func (e *InsufficientSpaceError) Error() string {
+	return fmt.Sprintf("not enough free space in %s: %d bytes required, %d available", e.Dir, e.Required, e.Available)
+}
+
+// volume is the filesystem holding a path.
+type volume struct {
+	// id identifies the filesystem, so paths on the same one can be grouped.
+	id string
+
+	// available is the number of bytes free for unprivileged users.
+	available int64
+}
+
+// CheckDiskSpace verifies that each directory's filesystem has room for the bytes
+// required in it. Requirements on the same filesystem are added together, since
+// temporary files and the output exist at the same time while muxing.
+// Directories whose free space can't be determined are not checked.
+func CheckDiskSpace(requirements ...SpaceRequirement) error {
+	type usage struct {
+		dir       string
+		required  int64
+		available int64
+	}
+	var order []string
+	usages := make(map[string]*usage)
+
+	for _, req := range requirements {
+		if req.Bytes <= 0 {
+			continue
+		}
+		vol, err := statVolume(existingDir(req.Dir))
+		if err != nil {
+			continue
+		}
+		u, ok := usages[vol.id]
+		if !ok {
+			u = &usage{dir: req.Dir, available: vol.available}
+			usages[vol.id] = u
+			order = append(order, vol.id)
+		}
+		u.required = addBytes(u.required, req.Bytes)
+	}
+
+	for _, id := range order {
+		if u := usages[id]; u.required > u.available {
+			return &InsufficientSpaceError{Dir: u.dir, Required: u.required, Available: u.available}
+		}
+	}
+	return nil
+}
+
+// existingDir returns dir or its nearest existing parent, since output
+// directories are only created once the download starts.
+func existingDir(dir string) string {
+	if dir == "" {
+		dir = "."
+	}
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// maxBytes is the largest byte count an int64 can hold.
+const maxBytes = 1<<63 - 1
+
+// addBytes adds two non-negative byte counts, saturating instead of overflowing.
+func addBytes(a, b int64) int64 {
+	if a > maxBytes-b {
+		return maxBytes
+	}
+	return a + b
+}
+
+// clampBytes converts a byte count reported by the OS to int64.
+func clampBytes(n uint64) int64 {
+	if n > maxBytes {
+		return maxBytes
+	}
+	return int64(n)
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package download
+
+// statVolume returns the filesystem holding path.
+func statVolume(_ string) (volume, error) {
+	return volume{}, errFreeSpaceUnsupported
+}
@@ -0,0 +1,112 @@
+package download
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// availableSpace returns the free space in dir, skipping the test where it can't be queried.
+func availableSpace(t *testing.T, dir string) int64 {
+	t.Helper()
+	vol, err := statVolume(dir)
+	if err != nil {
+		t.Skipf("free space not available: %v", err)
+	}
+	return vol.available
+}
+
+func TestCheckDiskSpace_EnoughSpace(t *testing.T) {
+	dir := t.TempDir()
+	availableSpace(t, dir)
+
+	if err := CheckDiskSpace(SpaceRequirement{Dir: dir, Bytes: 1024}); err != nil {
+		t.Errorf("CheckDiskSpace() error = %v", err)
+	}
+}
+
+func TestCheckDiskSpace_InsufficientSpace(t *testing.T) {
+	dir := t.TempDir()
+	available := availableSpace(t, dir)
+
+	err := CheckDiskSpace(SpaceRequirement{Dir: dir, Bytes: available + 1<<40})
+
+	var spaceErr *InsufficientSpaceError
+	if !errors.As(err, &spaceErr) {
+		t.Fatalf("CheckDiskSpace() error = %v, want InsufficientSpaceError", err)
+	}
+	if spaceErr.Dir != dir {
+		t.Errorf("Dir = %q, want %q", spaceErr.Dir, dir)
+	}
+	if spaceErr.Required != available+1<<40 {
+		t.Errorf("Required = %d, want %d", spaceErr.Required, available+1<<40)
+	}
+	if spaceErr.Available <= 0 {
+		t.Errorf("Available = %d, want > 0", spaceErr.Available)
+	}
+}
+
+func TestCheckDiskSpace_AddsRequirementsOnSameFilesystem(t *testing.T) {
+	dir := t.TempDir()
+	available := availableSpace(t, dir)
+	other := filepath.Join(dir, "other")
+	if err := os.Mkdir(other, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each fits on its own, but not both together
+	half := available/2 + available/4
+	err := CheckDiskSpace(
+		SpaceRequirement{Dir: dir, Bytes: half},
+		SpaceRequirement{Dir: other, Bytes: half},
+	)
+
+	var spaceErr *InsufficientSpaceError
+	if !errors.As(err, &spaceErr) {
+		t.Fatalf("CheckDiskSpace() error = %v, want InsufficientSpaceError", err)
+	}
+	if spaceErr.Required != 2*half {
+		t.Errorf("Required = %d, want %d", spaceErr.Required, 2*half)
+	}
+}
+
+func TestCheckDiskSpace_MissingDirUsesParent(t *testing.T) {
+	dir := t.TempDir()
+	available := availableSpace(t, dir)
+	missing := filepath.Join(dir, "not", "created", "yet")
+
+	err := CheckDiskSpace(SpaceRequirement{Dir: missing, Bytes: available + 1<<40})
+
+	var spaceErr *InsufficientSpaceError
+	if !errors.As(err, &spaceErr) {
+		t.Fatalf("CheckDiskSpace() error = %v, want InsufficientSpaceError", err)
+	}
+	if spaceErr.Dir != missing {
+		t.Errorf("Dir = %q, want %q", spaceErr.Dir, missing)
+	}
+}
+
+func TestCheckDiskSpace_IgnoresUnknownSizes(t *testing.T) {
+	if err := CheckDiskSpace(SpaceRequirement{Dir: t.TempDir(), Bytes: 0}); err != nil {
+		t.Errorf("CheckDiskSpace() error = %v", err)
+	}
+}
+
+func TestCheckDiskSpace_SaturatesHugeRequirements(t *testing.T) {
+	dir := t.TempDir()
+	availableSpace(t, dir)
+
+	err := CheckDiskSpace(
+		SpaceRequirement{Dir: dir, Bytes: maxBytes - 10},
+		SpaceRequirement{Dir: dir, Bytes: maxBytes - 10},
+	)
+
+	var spaceErr *InsufficientSpaceError
+	if !errors.As(err, &spaceErr) {
+		t.Fatalf("CheckDiskSpace() error = %v, want InsufficientSpaceError", err)
+	}
+	if spaceErr.Required != maxBytes {
+		t.Errorf("Required = %d, want %d", spaceErr.Required, int64(maxBytes))
+	}
+}
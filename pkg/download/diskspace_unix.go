@@ -0,0 +1,27 @@
+//go:build linux || darwin || freebsd
+
+package download
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// statVolume returns the filesystem holding path.
+func statVolume(path string) (volume, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return volume{}, fmt.Errorf("statfs %s: %w", path, err)
+	}
+
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return volume{}, fmt.Errorf("stat %s: %w", path, err)
+	}
+
+	//nolint:unconvert // field types differ between platforms
+	return volume{
+		id:        fmt.Sprint(st.Dev),
+		available: clampBytes(uint64(fs.Bavail) * uint64(fs.Bsize)),
+	}, nil
+}
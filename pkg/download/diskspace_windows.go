@@ -0,0 +1,36 @@
+//go:build windows
+
+package download
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// statVolume returns the filesystem holding path.
+func statVolume(path string) (volume, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return volume{}, err
+	}
+	p, err := syscall.UTF16PtrFromString(abs)
+	if err != nil {
+		return volume{}, err
+	}
+
+	var available uint64
+	ok, _, callErr := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&available)), 0, 0)
+	if ok == 0 {
+		return volume{}, fmt.Errorf("GetDiskFreeSpaceEx %s: %w", abs, callErr)
+	}
+
+	return volume{
+		id:        strings.ToUpper(filepath.VolumeName(abs)),
+		available: clampBytes(available),
+	}, nil
+}
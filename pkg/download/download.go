@@ -8,7 +8,11 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
+
+	ytdlhttp "github.com/SakuraBurst/golang-youtube-downloader/internal/http"
 )
 
 // Progress represents the current download progress.
@@ -18,6 +22,18 @@ type Progress struct {
 
 	// Total is the total size in bytes. May be 0 if unknown.
 	Total int64
+
+	// Elapsed is the time since the transfer started.
+	Elapsed time.Duration
+
+	// Speed is the current transfer rate in bytes per second, smoothed over recent reads.
+	Speed float64
+
+	// AverageSpeed is the transfer rate in bytes per second since the start.
+	AverageSpeed float64
+
+	// ETA is the estimated time until the transfer completes. 0 if unknown.
+	ETA time.Duration
 }
 
 // Percentage returns the download completion percentage (0-100).
@@ -29,6 +45,23 @@ func (p Progress) Percentage() float64 {
 	return float64(p.Downloaded) / float64(p.Total) * 100
 }
 
+// String returns a human-readable summary such as
+// "12.0 MiB / 48.0 MiB (25.0%), 2.0 MiB/s, ETA 18s".
+func (p Progress) String() string {
+	var b strings.Builder
+	b.WriteString(FormatBytes(p.Downloaded))
+	if p.Total > 0 {
+		fmt.Fprintf(&b, " / %s (%.1f%%)", FormatBytes(p.Total), p.Percentage())
+	}
+	if p.Speed > 0 {
+		b.WriteString(", " + FormatSpeed(p.Speed))
+	}
+	if p.ETA > 0 {
+		b.WriteString(", ETA " + p.ETA.Round(time.Second).String())
+	}
+	return b.String()
+}
+
 // ProgressCallback is a function called to report download progress.
 type ProgressCallback func(Progress)
 
@@ -57,90 +90,284 @@ func ChannelCallback(ch chan<- Progress) ProgressCallback {
 // Downloader handles downloading streams to files.
 type Downloader struct {
 	client *http.Client
+
+	// header holds the headers sent with every stream request.
+	header http.Header
+
+	// overwrite decides what happens to files that already exist.
+	overwrite OverwritePolicy
+
+	// refresh replaces expired stream URLs, nil to fail on them.
+	refresh URLRefresher
+
+	// bufferSize is the size of the buffer streams are copied through.
+	bufferSize int
+
+	// progressInterval is the minimum time between progress reports.
+	progressInterval time.Duration
+
+	// listeners receive the events of every download.
+	listeners []EventListener
+
+	// stallTimeout is how long a transfer may receive nothing before it is
+	// reported as stalled, 0 to not watch for stalls.
+	stallTimeout time.Duration
+
+	// timeout bounds each stream download, 0 for no limit.
+	timeout time.Duration
+
+	// chunkSize is the largest range requested at once from streams with a
+	// segment index.
+	chunkSize int64
+
+	// chunkTuner sets how many chunks are requested at once, nil for one at a time.
+	chunkTuner *ConcurrencyTuner
+}
+
+// Option configures a Downloader.
+type Option func(*Downloader)
+
+// WithUserAgent sets the User-Agent of stream requests. Stream URLs are bound to the
+// client that requested them, so it should match the one used for the watch page.
+func WithUserAgent(userAgent string) Option {
+	return WithHeader("User-Agent", userAgent)
+}
+
+// WithHeader sets a header on every stream request, replacing earlier values.
+func WithHeader(name, value string) Option {
+	return func(d *Downloader) {
+		d.header.Set(name, value)
+	}
 }
 
-// NewDownloader creates a new Downloader with the given HTTP client.
-func NewDownloader(client *http.Client) *Downloader {
+// WithOverwritePolicy sets what happens when a destination file already exists.
+// The default overwrites it.
+func WithOverwritePolicy(policy OverwritePolicy) Option {
+	return func(d *Downloader) {
+		d.overwrite = policy
+	}
+}
+
+// WithBufferSize sets the size of the buffer streams are copied through, which is
+// also the size of the writes to files. Sizes of 0 or less use DefaultBufferSize.
+func WithBufferSize(size int) Option {
+	return func(d *Downloader) {
+		d.bufferSize = size
+	}
+}
+
+// WithProgressInterval sets the minimum time between progress reports of a
+// stream, DefaultProgressInterval by default. 0 reports progress after every
+// read from the network.
+func WithProgressInterval(interval time.Duration) Option {
+	return func(d *Downloader) {
+		d.progressInterval = max(interval, 0)
+	}
+}
+
+// NewDownloader creates a new Downloader with the given HTTP client and options.
+// If client is nil, a shared client with a connection pool tuned for streams is used.
+func NewDownloader(client *http.Client, opts ...Option) *Downloader {
 	if client == nil {
-		client = http.DefaultClient
+		client = ytdlhttp.StreamClient()
+	}
+	d := &Downloader{
+		client:           client,
+		header:           make(http.Header),
+		bufferSize:       DefaultBufferSize,
+		progressInterval: DefaultProgressInterval,
+		stallTimeout:     DefaultStallTimeout,
+		chunkSize:        DefaultChunkSize,
+	}
+	for _, opt := range opts {
+		opt(d)
 	}
-	return &Downloader{client: client}
+	return d
 }
 
-// DownloadStream downloads a stream from the given URL to the specified file path.
-// Progress is reported via the optional callback function.
-func (d *Downloader) DownloadStream(ctx context.Context, url, filePath string, progress ProgressCallback) error {
-	// Create HTTP request with context
+// newRequest creates a GET request for a stream with the downloader's headers.
+func (d *Downloader) newRequest(ctx context.Context, url string) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
 	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	for name, values := range d.header {
+		req.Header[name] = values
 	}
+	return req, nil
+}
 
-	// Execute request
-	resp, err := d.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("executing request: %w", err)
+// DownloadStream downloads a stream from the given URL to the specified file path.
+// Progress is reported via the optional callback function. An existing file is
+// handled by the downloader's overwrite policy; use DownloadStreamResult to learn
+// whether it was skipped or where a renamed download went.
+func (d *Downloader) DownloadStream(ctx context.Context, url, filePath string, progress ProgressCallback) error {
+	return d.DownloadStreamResult(ctx, url, filePath, progress).Error
+}
+
+// DownloadStreamResult downloads a stream like DownloadStream and returns the
+// path written to, its size and how long the download took.
+func (d *Downloader) DownloadStreamResult(ctx context.Context, url, filePath string, progress ProgressCallback) DownloadResult {
+	// The destination is checked before any request is made
+	target, skip, err := ResolveTarget(filePath, d.overwrite)
+	if err != nil || skip {
+		return DownloadResult{FilePath: target, Error: err, Skipped: skip}
 	}
-	defer func() { _ = resp.Body.Close() }()
+	return d.downloadTarget(ctx, url, target, progress)
+}
 
-	// Check for HTTP errors
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("HTTP error: %s", resp.Status)
+// downloadTarget downloads a stream to target, replacing any file there,
+// without applying the overwrite policy.
+func (d *Downloader) downloadTarget(ctx context.Context, url, target string, progress ProgressCallback) DownloadResult {
+	started := time.Now()
+	d.emit(Event{Type: EventStarted, URL: url})
+	ctx, span := startStreamSpan(ctx, url)
+	verification, err := d.downloadFile(ctx, url, target, progress)
+	elapsed := time.Since(started)
+	d.emitFinished(url, verification, elapsed, err)
+	endStreamSpan(span, verification, err)
+	return DownloadResult{
+		FilePath:     target,
+		Error:        err,
+		Verification: verification,
+		Size:         verification.Downloaded,
+		Elapsed:      elapsed,
+	}
+}
+
+// downloadFile downloads a stream to filePath and returns how its size was verified.
+func (d *Downloader) downloadFile(ctx context.Context, url, filePath string, progress ProgressCallback) (v Verification, err error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	defer func() { err = timeoutError(ctx, err) }()
+
+	resp, url, err := d.openStream(ctx, url)
+	if err != nil {
+		return Verification{}, err
 	}
 
 	// Create parent directories if they don't exist
 	dir := filepath.Dir(filePath)
 	if dir != "" && dir != "." {
 		if err := os.MkdirAll(dir, 0o755); err != nil {
-			return fmt.Errorf("creating directory: %w", err)
+			_ = resp.Body.Close()
+			return Verification{}, fmt.Errorf("creating directory: %w", err)
 		}
 	}
 
 	// Create output file
 	file, err := os.Create(filePath)
 	if err != nil {
-		return fmt.Errorf("creating file: %w", err)
+		_ = resp.Body.Close()
+		return Verification{}, fmt.Errorf("creating file: %w", err)
 	}
 	defer func() { _ = file.Close() }()
 
-	// Get content length for progress tracking
-	totalSize := resp.ContentLength
+	verification, err := d.transferStream(ctx, url, resp, file, true, progress)
+	if err != nil {
+		return verification, fmt.Errorf("writing to file: %w", err)
+	}
 
-	// Create progress-tracking reader if callback is provided
-	var reader io.Reader = resp.Body
-	if progress != nil {
-		reader = &progressReader{
-			reader:   resp.Body,
-			total:    totalSize,
-			callback: progress,
-		}
+	return verification, nil
+}
+
+// DownloadStreamTo downloads a stream from the given URL and writes it to dst,
+// for example os.Stdout when piping into a player.
+// Progress is reported via the optional callback function.
+func (d *Downloader) DownloadStreamTo(ctx context.Context, url string, dst io.Writer, progress ProgressCallback) (err error) {
+	started := time.Now()
+	var verification Verification
+	d.emit(Event{Type: EventStarted, URL: url})
+	defer func(url string) { d.emitFinished(url, verification, time.Since(started), err) }(url)
+	ctx, span := startStreamSpan(ctx, url)
+	defer func() { endStreamSpan(span, verification, err) }()
+
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	defer func() { err = timeoutError(ctx, err) }()
+
+	resp, url, err := d.openStream(ctx, url)
+	if err != nil {
+		return err
 	}
 
-	// Copy data to file
-	_, err = io.Copy(file, reader)
+	verification, err = d.transferStream(ctx, url, resp, dst, false, progress)
 	if err != nil {
-		return fmt.Errorf("writing to file: %w", err)
+		return fmt.Errorf("writing output: %w", err)
 	}
 
 	return nil
 }
 
-// progressReader wraps an io.Reader to track and report progress.
+// DownloadStreamPiped downloads a stream from the given URL into a pipe that
+// consume reads, such as the stdin of an FFmpeg process converting it, so the
+// stream never has to be written to a temporary file. If consume returns before
+// reading everything, the download stops. A failed download is returned in
+// preference to consume's error, since consume fails too when its input breaks off.
+func (d *Downloader) DownloadStreamPiped(ctx context.Context, url string, consume func(r io.Reader) error, progress ProgressCallback) error {
+	return DownloadPiped(ctx, d, url, consume, progress)
+}
+
+// get performs a GET request for length bytes of url starting at offset, or
+// the rest of it when length is 0, and checks the response status.
+// The caller must close the response body.
+func (d *Downloader) get(ctx context.Context, url string, offset, length int64) (*http.Response, error) {
+	req, err := d.newRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case length > 0:
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	case offset > 0:
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_ = resp.Body.Close()
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	return resp, nil
+}
+
+// progressReader wraps an io.Reader to track and report progress. Reports are
+// coalesced to one per interval; the last one, when the reader ends or fails or
+// the total is reached, is always made.
 type progressReader struct {
 	reader     io.Reader
 	downloaded int64
 	total      int64
 	callback   ProgressCallback
+	meter      *speedMeter
+
+	// interval is the minimum time between reports, 0 to report every read.
+	interval time.Duration
+	// reported is when progress was last reported and pending whether reads
+	// happened since.
+	reported time.Time
+	pending  bool
 }
 
 func (pr *progressReader) Read(p []byte) (int, error) {
 	n, err := pr.reader.Read(p)
 	if n > 0 {
 		pr.downloaded += int64(n)
-		pr.callback(Progress{
-			Downloaded: pr.downloaded,
-			Total:      pr.total,
-		})
+		pr.pending = true
+	}
+	if !pr.pending {
+		return n, err
+	}
+	now := pr.meter.now()
+	if err != nil || now.Sub(pr.reported) >= pr.interval || (pr.total > 0 && pr.downloaded >= pr.total) {
+		pr.reported = now
+		pr.pending = false
+		pr.callback(pr.meter.progress(pr.downloaded, pr.total))
 	}
 	return n, err
 }
@@ -152,6 +379,10 @@ type StreamDownload struct {
 
 	// FilePath is the destination file path.
 	FilePath string
+
+	// Index is where the stream keeps its segment index, if known; the
+	// stream is then fetched segment by segment (see WithSegmentIndex).
+	Index *ByteRange
 }
 
 // DownloadResult represents the result of a download operation.
@@ -161,45 +392,29 @@ type DownloadResult struct {
 
 	// Error is any error that occurred during download (nil if successful).
 	Error error
-}
-
-// DownloadStreamsParallel downloads multiple streams in parallel using goroutines.
-// Progress is reported as an aggregate of all downloads via the optional callback.
-// Returns a slice of DownloadResult in the same order as the input streams.
-func (d *Downloader) DownloadStreamsParallel(ctx context.Context, streams []StreamDownload, progress ProgressCallback) []DownloadResult {
-	if len(streams) == 0 {
-		return nil
-	}
 
-	results := make([]DownloadResult, len(streams))
-	var wg sync.WaitGroup
+	// Verification describes how the downloaded size was checked.
+	Verification Verification
 
-	// Create aggregate progress tracker
-	var tracker *aggregateProgressTracker
-	if progress != nil {
-		tracker = newAggregateProgressTracker(len(streams), progress)
-	}
+	// Title names the item in reports, such as the video title.
+	Title string
 
-	for i, stream := range streams {
-		wg.Add(1)
-		go func(idx int, s StreamDownload) {
-			defer wg.Done()
+	// Size is the number of bytes written.
+	Size int64
 
-			var streamProgress ProgressCallback
-			if tracker != nil {
-				streamProgress = tracker.progressCallbackFor(idx)
-			}
+	// Elapsed is how long the download took.
+	Elapsed time.Duration
 
-			err := d.DownloadStream(ctx, s.URL, s.FilePath, streamProgress)
-			results[idx] = DownloadResult{
-				FilePath: s.FilePath,
-				Error:    err,
-			}
-		}(i, stream)
-	}
+	// Skipped reports that nothing was downloaded because the item didn't need it,
+	// for example because the file already exists.
+	Skipped bool
+}
 
-	wg.Wait()
-	return results
+// DownloadStreamsParallel downloads multiple streams in parallel using goroutines.
+// Progress is reported as an aggregate of all downloads via the optional callback.
+// Returns a slice of DownloadResult in the same order as the input streams.
+func (d *Downloader) DownloadStreamsParallel(ctx context.Context, streams []StreamDownload, progress ProgressCallback) []DownloadResult {
+	return DownloadParallel(ctx, d, streams, progress)
 }
 
 // aggregateProgressTracker tracks progress across multiple parallel downloads.
@@ -207,12 +422,14 @@ type aggregateProgressTracker struct {
 	mu         sync.Mutex
 	progresses []Progress // Per-stream progress
 	callback   ProgressCallback
+	meter      *speedMeter // Timing of the combined transfer
 }
 
 func newAggregateProgressTracker(count int, callback ProgressCallback) *aggregateProgressTracker {
 	return &aggregateProgressTracker{
 		progresses: make([]Progress, count),
 		callback:   callback,
+		meter:      newSpeedMeter(time.Now),
 	}
 }
 
@@ -227,130 +444,9 @@ func (apt *aggregateProgressTracker) progressCallbackFor(index int) ProgressCall
 			totalDownloaded += sp.Downloaded
 			totalSize += sp.Total
 		}
+		aggregate := apt.meter.progress(totalDownloaded, totalSize)
 		apt.mu.Unlock()
 
-		apt.callback(Progress{
-			Downloaded: totalDownloaded,
-			Total:      totalSize,
-		})
+		apt.callback(aggregate)
 	}
 }
-
-// BatchProgress represents the progress of a batch download operation.
-type BatchProgress struct {
-	// CompletedCount is the number of videos that have finished downloading.
-	CompletedCount int
-
-	// TotalCount is the total number of videos in the batch.
-	TotalCount int
-
-	// CurrentIndex is the index of the video currently being downloaded (0-based).
-	CurrentIndex int
-
-	// CurrentTitle is the title of the video currently being downloaded.
-	CurrentTitle string
-
-	// CurrentProgress is the download progress of the current video.
-	CurrentProgress Progress
-}
-
-// OverallPercentage returns the overall batch completion percentage (0-100).
-func (bp BatchProgress) OverallPercentage() float64 {
-	if bp.TotalCount == 0 {
-		return 0
-	}
-	return float64(bp.CompletedCount) / float64(bp.TotalCount) * 100
-}
-
-// String returns a human-readable string representation of the batch progress.
-func (bp BatchProgress) String() string {
-	return fmt.Sprintf("%d/%d videos complete", bp.CompletedCount, bp.TotalCount)
-}
-
-// BatchProgressCallback is a function called to report batch download progress.
-type BatchProgressCallback func(BatchProgress)
-
-// BatchItem represents a single item in a batch download.
-type BatchItem struct {
-	// URL is the stream URL to download from.
-	URL string
-
-	// FilePath is the destination file path.
-	FilePath string
-
-	// Title is the video title (used for progress reporting).
-	Title string
-}
-
-// BatchDownloader handles downloading multiple videos as a batch.
-type BatchDownloader struct {
-	downloader *Downloader
-}
-
-// NewBatchDownloader creates a new BatchDownloader.
-func NewBatchDownloader(downloader *Downloader) *BatchDownloader {
-	return &BatchDownloader{downloader: downloader}
-}
-
-// DownloadBatch downloads all items sequentially and reports progress.
-// Returns a slice of DownloadResult in the same order as the input items.
-func (bd *BatchDownloader) DownloadBatch(ctx context.Context, items []BatchItem, progress BatchProgressCallback) []DownloadResult {
-	results := make([]DownloadResult, len(items))
-
-	for i, item := range items {
-		// Report starting this video
-		if progress != nil {
-			progress(BatchProgress{
-				CompletedCount: i,
-				TotalCount:     len(items),
-				CurrentIndex:   i,
-				CurrentTitle:   item.Title,
-			})
-		}
-
-		// Create progress callback for current video
-		var videoProgress ProgressCallback
-		if progress != nil {
-			videoProgress = func(p Progress) {
-				progress(BatchProgress{
-					CompletedCount:  i,
-					TotalCount:      len(items),
-					CurrentIndex:    i,
-					CurrentTitle:    item.Title,
-					CurrentProgress: p,
-				})
-			}
-		}
-
-		// Download this video
-		err := bd.downloader.DownloadStream(ctx, item.URL, item.FilePath, videoProgress)
-		results[i] = DownloadResult{
-			FilePath: item.FilePath,
-			Error:    err,
-		}
-
-		// Report completion of this video
-		if progress != nil {
-			progress(BatchProgress{
-				CompletedCount: i + 1,
-				TotalCount:     len(items),
-				CurrentIndex:   i,
-				CurrentTitle:   item.Title,
-			})
-		}
-
-		// Check for context cancellation
-		if ctx.Err() != nil {
-			// Mark remaining items as failed
-			for j := i + 1; j < len(items); j++ {
-				results[j] = DownloadResult{
-					FilePath: items[j].FilePath,
-					Error:    ctx.Err(),
-				}
-			}
-			break
-		}
-	}
-
-	return results
-}
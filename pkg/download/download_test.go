@@ -3,14 +3,43 @@ package download
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	ytdlhttp "github.com/SakuraBurst/golang-youtube-downloader/internal/http"
 )
 
+func TestNewDownloader_NilClientUsesStreamClient(t *testing.T) {
+	d := NewDownloader(nil)
+	if d.client != ytdlhttp.StreamClient() {
+		t.Error("expected the shared stream client instead of http.DefaultClient")
+	}
+}
+
+func TestNewDownloader_WithHeaders(t *testing.T) {
+	var userAgent, custom string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent, custom = r.Header.Get("User-Agent"), r.Header.Get("X-Test")
+		_, _ = w.Write([]byte("data"))
+	}))
+	defer server.Close()
+
+	d := NewDownloader(server.Client(), WithUserAgent("Mozilla/5.0 Test"), WithHeader("X-Test", "1"))
+	if err := d.DownloadStreamTo(context.Background(), server.URL, io.Discard, nil); err != nil {
+		t.Fatalf("DownloadStreamTo failed: %v", err)
+	}
+	if userAgent != "Mozilla/5.0 Test" || custom != "1" {
+		t.Errorf("unexpected headers: User-Agent %q, X-Test %q", userAgent, custom)
+	}
+}
+
 func TestDownloadStream_WritesToFile(t *testing.T) {
 	// Setup test server that returns some content
 	content := []byte("test video content - this is fake stream data")
@@ -82,6 +111,12 @@ func TestDownloadStream_ReportsProgress(t *testing.T) {
 	if lastProgress.Downloaded != lastProgress.Total {
 		t.Errorf("Final progress incomplete: downloaded %d of %d", lastProgress.Downloaded, lastProgress.Total)
 	}
+	if lastProgress.Elapsed <= 0 {
+		t.Errorf("Expected elapsed time in final progress, got %v", lastProgress.Elapsed)
+	}
+	if lastProgress.ETA != 0 {
+		t.Errorf("Expected no ETA for a finished download, got %v", lastProgress.ETA)
+	}
 }
 
 func TestDownloadStream_HandlesContextCancellation(t *testing.T) {
@@ -156,6 +191,89 @@ func TestDownloadStream_HandlesHTTPError(t *testing.T) {
 	}
 }
 
+func TestDownloadStreamTo_WritesToWriter(t *testing.T) {
+	content := []byte("streamed content for a player")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(content)))
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	var last Progress
+	downloader := NewDownloader(http.DefaultClient)
+	err := downloader.DownloadStreamTo(context.Background(), server.URL, &buf, func(p Progress) { last = p })
+	if err != nil {
+		t.Fatalf("DownloadStreamTo failed: %v", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), content) {
+		t.Errorf("Content mismatch: got %q, want %q", buf.Bytes(), content)
+	}
+	if last.Downloaded != int64(len(content)) || last.Total != int64(len(content)) {
+		t.Errorf("final progress = %+v, want %d of %d", last, len(content), len(content))
+	}
+}
+
+func TestDownloadStreamPiped(t *testing.T) {
+	content := []byte("audio stream for a converter")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/forbidden" {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+	downloader := NewDownloader(http.DefaultClient)
+
+	var got []byte
+	err := downloader.DownloadStreamPiped(context.Background(), server.URL, func(r io.Reader) error {
+		var err error
+		got, err = io.ReadAll(r)
+		return err
+	}, nil)
+	if err != nil {
+		t.Fatalf("DownloadStreamPiped failed: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("consumer read %q, want %q", got, content)
+	}
+
+	// The download's failure is returned rather than the consumer's broken input
+	err = downloader.DownloadStreamPiped(context.Background(), server.URL+"/forbidden", func(r io.Reader) error {
+		_, err := io.ReadAll(r)
+		return fmt.Errorf("converter failed: %w", err)
+	}, nil)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusForbidden {
+		t.Errorf("error = %v, want HTTP 403", err)
+	}
+
+	// A consumer stopping early stops the download instead of blocking it
+	stop := errors.New("stop")
+	err = downloader.DownloadStreamPiped(context.Background(), server.URL, func(io.Reader) error { return stop }, nil)
+	if !errors.Is(err, stop) {
+		t.Errorf("error = %v, want the consumer's", err)
+	}
+}
+
+func TestDownloadStreamTo_HandlesHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	downloader := NewDownloader(http.DefaultClient)
+	if err := downloader.DownloadStreamTo(context.Background(), server.URL, &buf, nil); err == nil {
+		t.Fatal("Expected error for HTTP 403, got nil")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("nothing should be written on error, got %q", buf.String())
+	}
+}
+
 func TestProgress_Percentage(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -189,6 +307,38 @@ func TestProgress_Percentage(t *testing.T) {
 	}
 }
 
+func TestProgress_String(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress Progress
+		want     string
+	}{
+		{
+			name:     "unknown total",
+			progress: Progress{Downloaded: 512},
+			want:     "512 B",
+		},
+		{
+			name: "with speed and ETA",
+			progress: Progress{
+				Downloaded: 12 << 20,
+				Total:      48 << 20,
+				Speed:      2 << 20,
+				ETA:        18*time.Second + 200*time.Millisecond,
+			},
+			want: "12.0 MiB / 48.0 MiB (25.0%), 2.0 MiB/s, ETA 18s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.progress.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestDownloadStreamsParallel_DownloadsBothStreams(t *testing.T) {
 	// Setup test servers for video and audio
 	videoContent := []byte("video stream data - fake video content")
@@ -671,6 +821,70 @@ func TestBatchDownloader_ReportsBatchProgress(t *testing.T) {
 	}
 }
 
+func TestBatchDownloader_ReportsBatchBytes(t *testing.T) {
+	contents := [][]byte{testContent(1000), testContent(3000)}
+	servers := make([]*httptest.Server, len(contents))
+	for i, content := range contents {
+		servers[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", len(content)))
+			_, _ = w.Write(content)
+		}))
+		defer servers[i].Close()
+	}
+
+	tmpDir := t.TempDir()
+	items := []BatchItem{
+		{URL: servers[0].URL, FilePath: filepath.Join(tmpDir, "v1.mp4"), Title: "First", Size: 1000},
+		{URL: servers[1].URL, FilePath: filepath.Join(tmpDir, "v2.mp4"), Title: "Second"},
+	}
+
+	var updates []BatchProgress
+	NewBatchDownloader(NewDownloader(http.DefaultClient)).DownloadBatch(context.Background(), items, func(bp BatchProgress) {
+		updates = append(updates, bp)
+	})
+
+	// Before the second item reports its size it's estimated at the average
+	if first := updates[0]; first.TotalBytes != 2000 || first.DownloadedBytes != 0 {
+		t.Errorf("first update = %d/%d bytes, want 0/2000", first.DownloadedBytes, first.TotalBytes)
+	}
+	var sawSecond bool
+	for _, bp := range updates {
+		if bp.CurrentIndex == 1 && bp.CompletedCount == 1 && bp.CurrentProgress.Downloaded > 0 {
+			sawSecond = true
+			if bp.CompletedBytes != 1000 || bp.DownloadedBytes != 1000+bp.CurrentProgress.Downloaded || bp.TotalBytes != 4000 {
+				t.Errorf("second item update = %d completed, %d/%d bytes", bp.CompletedBytes, bp.DownloadedBytes, bp.TotalBytes)
+			}
+		}
+	}
+	if !sawSecond {
+		t.Error("no progress reported for the second item")
+	}
+	last := updates[len(updates)-1]
+	if last.CompletedBytes != 4000 || last.DownloadedBytes != 4000 || last.TotalBytes != 4000 || last.OverallPercentage() != 100 {
+		t.Errorf("last update = %d completed, %d/%d bytes", last.CompletedBytes, last.DownloadedBytes, last.TotalBytes)
+	}
+}
+
+func TestBatchTracker_ETA(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	tracker := newBatchTracker([]BatchItem{{Size: 1000}, {Size: 3000}}, clock.now)
+
+	clock.advance(time.Second)
+	tracker.complete(0, 1000)
+	bp := tracker.progress(1, "Second", Progress{Downloaded: 1000, Total: 3000}, BatchItemRunning)
+
+	// 2000 bytes in a second leaves 2000 bytes for another second
+	if bp.ETA != time.Second || bp.TotalBytes != 4000 || bp.DownloadedBytes != 2000 {
+		t.Errorf("progress = %d/%d bytes, ETA %v; want 2000/4000, ETA 1s", bp.DownloadedBytes, bp.TotalBytes, bp.ETA)
+	}
+	if got := bp.OverallPercentage(); got != 50 {
+		t.Errorf("OverallPercentage() = %v, want 50", got)
+	}
+	if got, want := bp.String(), "1/2 videos complete, 2.0 KiB / 3.9 KiB, ETA 1s"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
 func TestBatchDownloader_HandlesPartialFailure(t *testing.T) {
 	// Setup one working server and one failing server
 	content := []byte("working content")
@@ -0,0 +1,236 @@
+package download
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultStallTimeout is how long a transfer may receive nothing before an
+// EventStalled is emitted.
+const DefaultStallTimeout = 30 * time.Second
+
+// EventType is the kind of an Event.
+type EventType int
+
+// Events in the life of a stream download. Every started download ends with
+// exactly one EventCompleted or EventFailed.
+const (
+	// EventStarted is emitted when the download of a stream starts. Files the
+	// overwrite policy skips are never started.
+	EventStarted EventType = iota + 1
+
+	// EventRetryScheduled is emitted before a request of a download is made
+	// again, with the reason and the error that caused it.
+	EventRetryScheduled
+
+	// EventStalled is emitted when a transfer has received nothing for the
+	// downloader's stall timeout. It is emitted again if the transfer resumes
+	// and then stalls again.
+	EventStalled
+
+	// EventCompleted is emitted when a download has succeeded.
+	EventCompleted
+
+	// EventFailed is emitted when a download has failed, with the error.
+	EventFailed
+)
+
+// String returns the name of the event type, such as "started".
+func (t EventType) String() string {
+	switch t {
+	case EventStarted:
+		return "started"
+	case EventRetryScheduled:
+		return "retry scheduled"
+	case EventStalled:
+		return "stalled"
+	case EventCompleted:
+		return "completed"
+	case EventFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Retry reasons of EventRetryScheduled events.
+const (
+	// RetryResume is a range request for the rest of a transfer that broke off.
+	RetryResume = "resume"
+
+	// RetryRefresh is a request again with a new URL after the old one was refused.
+	RetryRefresh = "refresh"
+)
+
+// Event describes something that happened to a stream download.
+type Event struct {
+	Type EventType
+
+	// URL is the stream URL, as given to the downloader.
+	URL string
+
+	// Downloaded is the number of bytes written so far, the final size for
+	// EventCompleted and EventFailed.
+	Downloaded int64
+
+	// Total is the expected size of the stream, 0 if unknown.
+	Total int64
+
+	// Elapsed is how long the download took, for EventCompleted and EventFailed.
+	Elapsed time.Duration
+
+	// Reason is why a request is made again, RetryResume or RetryRefresh, for
+	// EventRetryScheduled.
+	Reason string
+
+	// Attempt is the number of the retry, from 1, for EventRetryScheduled.
+	Attempt int
+
+	// Err is why the download failed, for EventFailed, or the error that caused
+	// the retry, if there was one, for EventRetryScheduled.
+	Err error
+}
+
+// EventListener receives the events of a Downloader's downloads. Streams
+// downloaded in parallel emit events from several goroutines at once, so
+// listeners must be safe for concurrent use. HandleEvent runs on the download's
+// goroutine and should return quickly.
+type EventListener interface {
+	HandleEvent(Event)
+}
+
+// EventListenerFunc adapts a function to an EventListener.
+type EventListenerFunc func(Event)
+
+// HandleEvent calls f(e).
+func (f EventListenerFunc) HandleEvent(e Event) {
+	f(e)
+}
+
+// WithEventListener adds a listener that receives the events of every download.
+func WithEventListener(listener EventListener) Option {
+	return func(d *Downloader) {
+		d.listeners = append(d.listeners, listener)
+	}
+}
+
+// WithStallTimeout sets how long a transfer may receive nothing before an
+// EventStalled is emitted, DefaultStallTimeout by default. 0 disables stall
+// detection.
+func WithStallTimeout(timeout time.Duration) Option {
+	return func(d *Downloader) {
+		d.stallTimeout = max(timeout, 0)
+	}
+}
+
+// emit sends e to the downloader's listeners.
+func (d *Downloader) emit(e Event) {
+	for _, l := range d.listeners {
+		l.HandleEvent(e)
+	}
+}
+
+// emitFinished emits the EventCompleted or EventFailed that ends a download.
+func (d *Downloader) emitFinished(url string, v Verification, elapsed time.Duration, err error) {
+	e := Event{Type: EventCompleted, URL: url, Downloaded: v.Downloaded, Total: v.Expected, Elapsed: elapsed}
+	if err != nil {
+		e.Type = EventFailed
+		e.Err = err
+	}
+	d.emit(e)
+}
+
+// stallWatcher emits an EventStalled when the bodies it watches deliver
+// nothing for timeout.
+type stallWatcher struct {
+	timeout time.Duration
+	emit    func(downloaded int64)
+
+	mu      sync.Mutex
+	last    time.Time
+	read    int64
+	stalled bool
+	timer   *time.Timer
+}
+
+// watchStalls starts watching a transfer for stalls, or returns nil if the
+// downloader has no listeners or stall detection is disabled. The watcher must
+// be stopped.
+func (d *Downloader) watchStalls(url string, total int64) *stallWatcher {
+	if len(d.listeners) == 0 || d.stallTimeout <= 0 {
+		return nil
+	}
+	w := &stallWatcher{
+		timeout: d.stallTimeout,
+		last:    time.Now(),
+		emit: func(downloaded int64) {
+			d.emit(Event{Type: EventStalled, URL: url, Downloaded: downloaded, Total: total})
+		},
+	}
+	w.timer = time.AfterFunc(w.timeout, w.check)
+	return w
+}
+
+// check emits the stall event if nothing was read for the timeout, and
+// schedules the next check.
+func (w *stallWatcher) check() {
+	w.mu.Lock()
+	idle := time.Since(w.last)
+	report := idle >= w.timeout && !w.stalled
+	if report {
+		w.stalled = true
+	}
+	next := w.timeout
+	if idle < w.timeout {
+		next = w.timeout - idle
+	}
+	read := w.read
+	if w.timer != nil {
+		w.timer.Reset(next)
+	}
+	w.mu.Unlock()
+
+	if report {
+		w.emit(read)
+	}
+}
+
+// watch returns body, recording its reads as activity. A nil watcher returns
+// body as is.
+func (w *stallWatcher) watch(body io.Reader) io.Reader {
+	if w == nil {
+		return body
+	}
+	return &watchedReader{reader: body, watcher: w}
+}
+
+// stop stops watching. It can be called on a nil watcher.
+func (w *stallWatcher) stop() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timer.Stop()
+	w.timer = nil
+}
+
+// watchedReader reports the reads from a body to its stall watcher.
+type watchedReader struct {
+	reader  io.Reader
+	watcher *stallWatcher
+}
+
+func (r *watchedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		w := r.watcher
+		w.mu.Lock()
+		w.last = time.Now()
+		w.read += int64(n)
+		w.stalled = false
+		w.mu.Unlock()
+	}
+	return n, err
+}
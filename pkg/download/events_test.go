@@ -0,0 +1,173 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventRecorder is an EventListener that records the events it receives.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) HandleEvent(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) types() []EventType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	types := make([]EventType, len(r.events))
+	for i, e := range r.events {
+		types[i] = e.Type
+	}
+	return types
+}
+
+func equalTypes(got, want []EventType) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEvents_ResumedDownload(t *testing.T) {
+	content := testContent(1000)
+	server, _ := newTruncatingServer(t, content, 1, false)
+
+	rec := &eventRecorder{}
+	d := NewDownloader(server.Client(), WithEventListener(rec))
+	if err := d.DownloadStream(context.Background(), server.URL, filepath.Join(t.TempDir(), "out.mp4"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []EventType{EventStarted, EventRetryScheduled, EventCompleted}
+	if got := rec.types(); !equalTypes(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	retry := rec.events[1]
+	if retry.Reason != RetryResume || retry.Attempt != 1 || retry.Downloaded != 500 || retry.Total != 1000 {
+		t.Errorf("retry = %+v", retry)
+	}
+	if done := rec.events[2]; done.Downloaded != 1000 || done.Total != 1000 || done.URL != server.URL {
+		t.Errorf("completed = %+v", done)
+	}
+}
+
+func TestEvents_RefreshAndFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		case "/limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte("data"))
+		}
+	}))
+	defer server.Close()
+
+	rec := &eventRecorder{}
+	d := NewDownloader(server.Client(), WithEventListener(rec)).WithURLRefresher(func(context.Context, string) (string, error) {
+		return server.URL + "/fresh", nil
+	})
+
+	var buf bytes.Buffer
+	if err := d.DownloadStreamTo(context.Background(), server.URL+"/forbidden", &buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []EventType{EventStarted, EventRetryScheduled, EventCompleted}
+	if got := rec.types(); !equalTypes(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	var httpErr *HTTPError
+	if retry := rec.events[1]; retry.Reason != RetryRefresh || !errors.As(retry.Err, &httpErr) || httpErr.StatusCode != http.StatusForbidden {
+		t.Errorf("retry = %+v, want a refresh after 403", retry)
+	}
+	if done := rec.events[2]; done.Downloaded != 4 || done.URL != server.URL+"/forbidden" {
+		t.Errorf("completed = %+v", done)
+	}
+
+	rec.events = nil
+	if err := d.DownloadStreamTo(context.Background(), server.URL+"/limited", &buf, nil); err == nil {
+		t.Fatal("expected an error for 429")
+	}
+	want = []EventType{EventStarted, EventFailed}
+	if got := rec.types(); !equalTypes(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	if failed := rec.events[1]; !errors.As(failed.Err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("failed = %+v, want the 429", failed)
+	}
+}
+
+func TestEvents_SkippedFileNotStarted(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "out.mp4")
+	if err := os.WriteFile(filePath, []byte("existing"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &eventRecorder{}
+	d := NewDownloader(http.DefaultClient, WithOverwritePolicy(SkipExisting), WithEventListener(rec))
+	if result := d.DownloadStreamResult(context.Background(), "http://invalid.test/", filePath, nil); !result.Skipped {
+		t.Fatalf("result = %+v, want skipped", result)
+	}
+	if got := rec.types(); len(got) != 0 {
+		t.Errorf("events = %v for a skipped file", got)
+	}
+}
+
+func TestEvents_Stalled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "8")
+		_, _ = w.Write([]byte("data"))
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("more"))
+	}))
+	defer server.Close()
+
+	rec := &eventRecorder{}
+	d := NewDownloader(server.Client(), WithEventListener(rec), WithStallTimeout(50*time.Millisecond))
+	var buf bytes.Buffer
+	if err := d.DownloadStreamTo(context.Background(), server.URL, &buf, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []EventType{EventStarted, EventStalled, EventCompleted}
+	if got := rec.types(); !equalTypes(got, want) {
+		t.Fatalf("events = %v, want one stall reported once", got)
+	}
+	if stalled := rec.events[1]; stalled.Downloaded != 4 || stalled.Total != 8 {
+		t.Errorf("stalled = %+v", stalled)
+	}
+}
+
+func TestEvents_NoStallWithoutTimeout(t *testing.T) {
+	d := NewDownloader(nil, WithEventListener(&eventRecorder{}), WithStallTimeout(0))
+	if w := d.watchStalls("url", 0); w != nil {
+		t.Error("watchStalls() started a watcher with stall detection disabled")
+	}
+}
+
+func TestEventType_String(t *testing.T) {
+	if EventRetryScheduled.String() != "retry scheduled" || EventType(0).String() != "unknown" {
+		t.Errorf("String() = %q, %q", EventRetryScheduled, EventType(0))
+	}
+}
@@ -0,0 +1,35 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+)
+
+func ExampleWithBufferSize() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "stream.mp4", time.Time{}, bytes.NewReader(make([]byte, 10<<20)))
+	}))
+	defer server.Close()
+
+	// Write to disk in 4 MiB blocks and report progress at most once a second
+	downloader := download.NewDownloader(server.Client(),
+		download.WithBufferSize(4<<20), download.WithProgressInterval(time.Second))
+
+	var last download.Progress
+	err := downloader.DownloadStreamTo(context.Background(), server.URL, io.Discard, func(p download.Progress) {
+		last = p
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("downloaded %d of %d bytes\n", last.Downloaded, last.Total)
+	// Output: downloaded 10485760 of 10485760 bytes
+}
@@ -0,0 +1,100 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupportedOverwritePolicy is returned when an overwrite policy can't be parsed.
+var ErrUnsupportedOverwritePolicy = errors.New("unsupported overwrite policy")
+
+// maxRenameAttempts bounds the numeric suffixes tried by RenameExisting.
+const maxRenameAttempts = 10000
+
+// OverwritePolicy decides what happens when a download's destination file already exists.
+type OverwritePolicy int
+
+const (
+	// OverwriteExisting replaces the existing file.
+	OverwriteExisting OverwritePolicy = iota
+	// SkipExisting keeps the existing file and skips the download.
+	SkipExisting
+	// RenameExisting keeps the existing file and downloads to a new name with a
+	// numeric suffix, such as "video (1).mp4".
+	RenameExisting
+)
+
+// String returns the policy's name as accepted by ParseOverwritePolicy.
+func (p OverwritePolicy) String() string {
+	switch p {
+	case OverwriteExisting:
+		return "overwrite"
+	case SkipExisting:
+		return "skip"
+	case RenameExisting:
+		return "rename"
+	default:
+		return fmt.Sprintf("OverwritePolicy(%d)", int(p))
+	}
+}
+
+// ParseOverwritePolicy parses "overwrite", "skip" or "rename".
+func ParseOverwritePolicy(s string) (OverwritePolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "overwrite":
+		return OverwriteExisting, nil
+	case "skip":
+		return SkipExisting, nil
+	case "rename":
+		return RenameExisting, nil
+	default:
+		return 0, fmt.Errorf("%w %q (use overwrite, skip or rename)", ErrUnsupportedOverwritePolicy, s)
+	}
+}
+
+// ResolveTarget applies the policy to the destination filePath before anything is
+// downloaded. It returns the path to download to, which differs from filePath when
+// an existing file is renamed around, and whether the download should be skipped.
+func ResolveTarget(filePath string, policy OverwritePolicy) (target string, skip bool, err error) {
+	exists, err := fileExists(filePath)
+	if err != nil || !exists {
+		return filePath, false, err
+	}
+
+	switch policy {
+	case SkipExisting:
+		return filePath, true, nil
+	case RenameExisting:
+		ext := filepath.Ext(filePath)
+		base := strings.TrimSuffix(filePath, ext)
+		for i := 1; i <= maxRenameAttempts; i++ {
+			candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+			exists, err := fileExists(candidate)
+			if err != nil {
+				return "", false, err
+			}
+			if !exists {
+				return candidate, false, nil
+			}
+		}
+		return "", false, fmt.Errorf("no free name for %s", filePath)
+	default:
+		return filePath, false, nil
+	}
+}
+
+// fileExists reports whether a file exists at path.
+func fileExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, os.ErrNotExist):
+		return false, nil
+	default:
+		return false, fmt.Errorf("checking existing file: %w", err)
+	}
+}
@@ -0,0 +1,120 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseOverwritePolicy(t *testing.T) {
+	tests := map[string]OverwritePolicy{"overwrite": OverwriteExisting, "skip": SkipExisting, " Rename ": RenameExisting}
+	for input, want := range tests {
+		got, err := ParseOverwritePolicy(input)
+		if err != nil || got != want {
+			t.Errorf("ParseOverwritePolicy(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseOverwritePolicy("keep"); !errors.Is(err, ErrUnsupportedOverwritePolicy) {
+		t.Errorf("expected ErrUnsupportedOverwritePolicy, got %v", err)
+	}
+}
+
+func TestOverwritePolicy_String(t *testing.T) {
+	for _, p := range []OverwritePolicy{OverwriteExisting, SkipExisting, RenameExisting} {
+		if parsed, err := ParseOverwritePolicy(p.String()); err != nil || parsed != p {
+			t.Errorf("%v does not round-trip: %v, %v", p, parsed, err)
+		}
+	}
+}
+
+func TestResolveTarget(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "video.mp4")
+	for _, name := range []string{"video.mp4", "video (1).mp4"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("old"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing := filepath.Join(dir, "new.mp4")
+
+	tests := []struct {
+		name     string
+		path     string
+		policy   OverwritePolicy
+		want     string
+		wantSkip bool
+	}{
+		{"missing file", missing, SkipExisting, missing, false},
+		{"overwrite", existing, OverwriteExisting, existing, false},
+		{"skip", existing, SkipExisting, existing, true},
+		{"rename", existing, RenameExisting, filepath.Join(dir, "video (2).mp4"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skip, err := ResolveTarget(tt.path, tt.policy)
+			if err != nil {
+				t.Fatalf("ResolveTarget failed: %v", err)
+			}
+			if got != tt.want || skip != tt.wantSkip {
+				t.Errorf("ResolveTarget = %q, %v; want %q, %v", got, skip, tt.want, tt.wantSkip)
+			}
+		})
+	}
+}
+
+func TestDownloader_SkipExistingMakesNoRequest(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		_, _ = w.Write([]byte("new"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDownloader(server.Client(), WithOverwritePolicy(SkipExisting))
+	result := d.DownloadStreamResult(context.Background(), server.URL, path, nil)
+	if result.Error != nil || !result.Skipped || result.FilePath != path {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests for a skipped file, got %d", requests)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("existing file was changed to %q", data)
+	}
+}
+
+func TestBatchDownloader_RenameExisting(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("new"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "video.mp4")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	bd := NewBatchDownloader(NewDownloader(server.Client(), WithOverwritePolicy(RenameExisting)))
+	results := bd.DownloadBatch(context.Background(), []BatchItem{{URL: server.URL, FilePath: path, Title: "Video"}}, nil)
+
+	renamed := filepath.Join(dir, "video (1).mp4")
+	if results[0].Error != nil || results[0].FilePath != renamed || results[0].Title != "Video" || results[0].Size != 3 {
+		t.Errorf("unexpected result %+v", results[0])
+	}
+	if data, _ := os.ReadFile(renamed); string(data) != "new" {
+		t.Errorf("renamed file = %q, want new content", data)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("existing file was changed to %q", data)
+	}
+}
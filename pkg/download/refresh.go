@@ -0,0 +1,90 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// staleURLMargin is how long before its expire time a stream URL is refreshed,
+// so a request doesn't start just before the URL stops working.
+const staleURLMargin = time.Minute
+
+// HTTPError is returned when a stream request gets a non-2xx response.
+type HTTPError struct {
+	// StatusCode is the HTTP status code, such as 403.
+	StatusCode int
+
+	// Status is the status line, such as "403 Forbidden".
+	Status string
+}
+
+func (e *HTTPError) Error() string {
+	return "HTTP error: " + e.Status
+}
+
+// URLRefresher returns a new URL for the same stream as staleURL, which has
+// expired or was refused with 403 Forbidden.
+type URLRefresher func(ctx context.Context, staleURL string) (string, error)
+
+// WithURLRefresher returns a copy of the downloader that replaces stream URLs
+// through refresh when they are about to expire or are refused mid-download.
+// YouTube stream URLs stop working a few hours after the watch page was loaded,
+// and those of one video need the video to refresh them, so the refresher is
+// set per video rather than as an Option.
+func (d *Downloader) WithURLRefresher(refresh URLRefresher) *Downloader {
+	copied := *d
+	copied.refresh = refresh
+	return &copied
+}
+
+// URLExpiry returns when a YouTube stream URL expires, from its expire parameter,
+// or the zero time if it has none.
+func URLExpiry(streamURL string) time.Time {
+	u, err := url.Parse(streamURL)
+	if err != nil {
+		return time.Time{}
+	}
+	seconds, err := strconv.ParseInt(u.Query().Get("expire"), 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// urlStale reports whether streamURL expires within staleURLMargin of now.
+func urlStale(streamURL string, now time.Time) bool {
+	expiry := URLExpiry(streamURL)
+	return !expiry.IsZero() && !now.Add(staleURLMargin).Before(expiry)
+}
+
+// open requests streamURL from offset like get, refreshing the URL first when it
+// is stale and once more when the request is refused. It returns the URL that
+// was used, so later range requests for the stream go to the refreshed one.
+func (d *Downloader) open(ctx context.Context, streamURL string, offset, length int64) (*http.Response, string, error) {
+	if d.refresh != nil && urlStale(streamURL, time.Now()) {
+		fresh, err := d.refresh(ctx, streamURL)
+		if err != nil {
+			return nil, streamURL, fmt.Errorf("refreshing expired stream URL: %w", err)
+		}
+		streamURL = fresh
+	}
+
+	resp, err := d.get(ctx, streamURL, offset, length)
+	var httpErr *HTTPError
+	if d.refresh == nil || !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusForbidden {
+		return resp, streamURL, err
+	}
+
+	fresh, refreshErr := d.refresh(ctx, streamURL)
+	if refreshErr != nil {
+		return nil, streamURL, fmt.Errorf("%w (refreshing stream URL: %w)", err, refreshErr)
+	}
+	d.emitRetry(ctx, Event{URL: streamURL, Downloaded: offset, Reason: RetryRefresh, Attempt: 1, Err: err})
+	resp, err = d.get(ctx, fresh, offset, length)
+	return resp, fresh, err
+}
@@ -0,0 +1,140 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newExpiringServer serves content at /fresh and refuses /stale with 403.
+// /expiring serves the first half of content once, then expires and is refused too.
+func newExpiringServer(t *testing.T, content []byte) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var refused atomic.Int32
+	var expired atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stale" || (r.URL.Path == "/expiring" && expired.Load()) {
+			refused.Add(1)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var offset int
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+			offset, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rangeHeader, "bytes="), "-"))
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(content)-1, len(content)))
+		}
+		body := content[offset:]
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		if offset > 0 {
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		if r.URL.Path == "/expiring" {
+			// Writing less than the declared length makes the client see an unexpected EOF
+			expired.Store(true)
+			_, _ = w.Write(body[:len(body)/2])
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &refused
+}
+
+func TestURLExpiry(t *testing.T) {
+	if got := URLExpiry("https://example.com/videoplayback?expire=1700000000&itag=137"); !got.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("URLExpiry = %v", got)
+	}
+	for _, u := range []string{"https://example.com/videoplayback?itag=137", "https://example.com/?expire=soon", "%zz"} {
+		if got := URLExpiry(u); !got.IsZero() {
+			t.Errorf("URLExpiry(%q) = %v, want zero", u, got)
+		}
+	}
+}
+
+func TestDownloader_RefreshesExpiredURL(t *testing.T) {
+	content := testContent(1000)
+	server, staleRequests := newExpiringServer(t, content)
+
+	expired := fmt.Sprintf("%s/stale?expire=%d", server.URL, time.Now().Add(-time.Hour).Unix())
+	var refreshed string
+	downloader := NewDownloader(server.Client()).WithURLRefresher(func(_ context.Context, staleURL string) (string, error) {
+		refreshed = staleURL
+		return server.URL + "/fresh", nil
+	})
+
+	filePath := filepath.Join(t.TempDir(), "out.mp4")
+	if err := downloader.DownloadStream(context.Background(), expired, filePath, nil); err != nil {
+		t.Fatalf("DownloadStream: %v", err)
+	}
+	if refreshed != expired {
+		t.Errorf("refreshed %q, want the expired URL", refreshed)
+	}
+	if n := staleRequests.Load(); n != 0 {
+		t.Errorf("made %d requests to the expired URL, want none", n)
+	}
+	if data, _ := os.ReadFile(filePath); !bytes.Equal(data, content) {
+		t.Error("downloaded content does not match")
+	}
+}
+
+func TestDownloader_RefreshesOnForbiddenMidStream(t *testing.T) {
+	content := testContent(1000)
+	server, refused := newExpiringServer(t, content)
+
+	// The first response ends early, and by the time the rest is requested
+	// the URL has expired
+	var refreshed []string
+	downloader := NewDownloader(server.Client()).WithURLRefresher(func(_ context.Context, staleURL string) (string, error) {
+		refreshed = append(refreshed, staleURL)
+		return server.URL + "/fresh?clen=1000", nil
+	})
+
+	var buf bytes.Buffer
+	if err := downloader.DownloadStreamTo(context.Background(), server.URL+"/expiring?clen=1000", &buf, nil); err != nil {
+		t.Fatalf("DownloadStreamTo: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), content) {
+		t.Errorf("downloaded %d bytes, want the resumed content", buf.Len())
+	}
+	if len(refreshed) != 1 || refreshed[0] != server.URL+"/expiring?clen=1000" || refused.Load() != 1 {
+		t.Errorf("refreshed %v after %d refused requests, want the expiring URL once", refreshed, refused.Load())
+	}
+}
+
+func TestDownloader_ForbiddenWithoutRefresher(t *testing.T) {
+	server, _ := newExpiringServer(t, testContent(10))
+
+	err := NewDownloader(server.Client()).DownloadStreamTo(context.Background(), server.URL+"/stale", new(bytes.Buffer), nil)
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("error = %v, want a 403 HTTPError", err)
+	}
+	if err.Error() != "HTTP error: 403 Forbidden" {
+		t.Errorf("error = %q", err)
+	}
+}
+
+func TestDownloader_RefreshFailure(t *testing.T) {
+	server, _ := newExpiringServer(t, testContent(10))
+	refreshErr := errors.New("video removed")
+	downloader := NewDownloader(server.Client()).WithURLRefresher(func(context.Context, string) (string, error) {
+		return "", refreshErr
+	})
+
+	err := downloader.DownloadStreamTo(context.Background(), server.URL+"/stale", new(bytes.Buffer), nil)
+	var httpErr *HTTPError
+	if !errors.Is(err, refreshErr) || !errors.As(err, &httpErr) {
+		t.Errorf("error = %v, want both the 403 and the refresh error", err)
+	}
+}
@@ -0,0 +1,92 @@
+package download
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Item statuses used in a Report.
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+)
+
+// Report summarizes a download of several items, such as a playlist or batch.
+type Report struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+
+	// TotalBytes is the number of bytes written by all items.
+	TotalBytes int64 `json:"total_bytes"`
+
+	// Elapsed is the wall-clock time of the whole download.
+	Elapsed time.Duration `json:"-"`
+
+	// AverageSpeed is TotalBytes over Elapsed, in bytes per second.
+	AverageSpeed float64 `json:"average_speed"`
+
+	Items []ReportItem `json:"items"`
+}
+
+// ReportItem is the outcome of one item of a Report.
+type ReportItem struct {
+	Title    string        `json:"title"`
+	FilePath string        `json:"file_path,omitempty"`
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Size     int64         `json:"size"`
+	Elapsed  time.Duration `json:"-"`
+}
+
+// NewReport builds a report from the results of a download that took elapsed.
+// Items run one after another or in parallel, so elapsed is measured by the caller
+// rather than summed from the results.
+func NewReport(results []DownloadResult, elapsed time.Duration) *Report {
+	r := &Report{Elapsed: elapsed, Items: make([]ReportItem, 0, len(results))}
+	for _, result := range results {
+		item := ReportItem{
+			Title:    result.Title,
+			FilePath: result.FilePath,
+			Size:     result.Size,
+			Elapsed:  result.Elapsed,
+		}
+		switch {
+		case result.Error != nil:
+			item.Status = StatusFailed
+			item.Error = result.Error.Error()
+			r.Failed++
+		case result.Skipped:
+			item.Status = StatusSkipped
+			r.Skipped++
+		default:
+			item.Status = StatusSucceeded
+			r.Succeeded++
+		}
+		r.TotalBytes += result.Size
+		r.Items = append(r.Items, item)
+	}
+	if elapsed > 0 {
+		r.AverageSpeed = float64(r.TotalBytes) / elapsed.Seconds()
+	}
+	return r
+}
+
+// MarshalJSON encodes the report with its durations in seconds.
+func (r *Report) MarshalJSON() ([]byte, error) {
+	type report Report
+	return json.Marshal(struct {
+		*report
+		ElapsedSeconds float64 `json:"elapsed_seconds"`
+	}{(*report)(r), r.Elapsed.Seconds()})
+}
+
+// MarshalJSON encodes the item with its duration in seconds.
+func (i ReportItem) MarshalJSON() ([]byte, error) {
+	type item ReportItem
+	return json.Marshal(struct {
+		item
+		ElapsedSeconds float64 `json:"elapsed_seconds"`
+	}{item(i), i.Elapsed.Seconds()})
+}
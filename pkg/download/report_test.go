@@ -0,0 +1,69 @@
+package download
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewReport(t *testing.T) {
+	results := []DownloadResult{
+		{Title: "First", FilePath: "first.mp4", Size: 3000, Elapsed: time.Second},
+		{Title: "Second", Error: errors.New("video unavailable")},
+		{Title: "Third", FilePath: "third.mp4", Skipped: true},
+		{Title: "Fourth", FilePath: "fourth.mp4", Size: 1000, Elapsed: time.Second},
+	}
+	r := NewReport(results, 2*time.Second)
+
+	if r.Succeeded != 2 || r.Failed != 1 || r.Skipped != 1 {
+		t.Errorf("counts = %d/%d/%d, want 2/1/1", r.Succeeded, r.Failed, r.Skipped)
+	}
+	if r.TotalBytes != 4000 || r.AverageSpeed != 2000 {
+		t.Errorf("TotalBytes = %d, AverageSpeed = %v; want 4000, 2000", r.TotalBytes, r.AverageSpeed)
+	}
+	if len(r.Items) != 4 {
+		t.Fatalf("expected 4 items, got %d", len(r.Items))
+	}
+	if item := r.Items[1]; item.Status != StatusFailed || item.Error != "video unavailable" {
+		t.Errorf("unexpected failed item %+v", item)
+	}
+	if r.Items[2].Status != StatusSkipped || r.Items[3].Status != StatusSucceeded {
+		t.Errorf("unexpected statuses %q, %q", r.Items[2].Status, r.Items[3].Status)
+	}
+}
+
+func TestNewReport_Empty(t *testing.T) {
+	r := NewReport(nil, 0)
+	if r.Succeeded != 0 || r.AverageSpeed != 0 || r.Items == nil {
+		t.Errorf("unexpected empty report %+v", r)
+	}
+}
+
+func TestReport_MarshalJSON(t *testing.T) {
+	r := NewReport([]DownloadResult{{Title: "First", Size: 10, Elapsed: 1500 * time.Millisecond}}, 3*time.Second)
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded struct {
+		Succeeded      int     `json:"succeeded"`
+		TotalBytes     int64   `json:"total_bytes"`
+		ElapsedSeconds float64 `json:"elapsed_seconds"`
+		Items          []struct {
+			Title          string  `json:"title"`
+			Status         string  `json:"status"`
+			ElapsedSeconds float64 `json:"elapsed_seconds"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Succeeded != 1 || decoded.TotalBytes != 10 || decoded.ElapsedSeconds != 3 {
+		t.Errorf("unexpected report JSON %s", data)
+	}
+	if len(decoded.Items) != 1 || decoded.Items[0].Title != "First" || decoded.Items[0].Status != StatusSucceeded || decoded.Items[0].ElapsedSeconds != 1.5 {
+		t.Errorf("unexpected item JSON %s", data)
+	}
+}
@@ -0,0 +1,382 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultChunkSize is the largest range requested at once from a stream with
+// a segment index. googlevideo servers sometimes cut off responses of hundreds
+// of megabytes, while ranges of a few segments come through whole.
+const DefaultChunkSize = 10 << 20
+
+// ByteRange is an inclusive range of bytes of a stream, as in an HTTP Range header.
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+// Length returns the number of bytes in the range.
+func (r ByteRange) Length() int64 {
+	return r.End - r.Start + 1
+}
+
+// WithChunkSize sets the largest range requested at once from streams with a
+// segment index, DefaultChunkSize by default. Chunks always hold whole
+// segments, so one larger than size is requested on its own.
+func WithChunkSize(size int64) Option {
+	return func(d *Downloader) {
+		if size > 0 {
+			d.chunkSize = size
+		}
+	}
+}
+
+// WithChunkTuner requests up to the tuner's concurrency level of chunks of a
+// stream with a segment index at once, holding those ahead of the one being
+// written in memory. The level is read as each chunk is requested, so a tuner
+// running alongside the downloads adjusts them as they go. Without a tuner,
+// chunks are requested one at a time.
+func WithChunkTuner(tuner *ConcurrencyTuner) Option {
+	return func(d *Downloader) {
+		d.chunkTuner = tuner
+	}
+}
+
+// segmentIndexKey is the context key of the segment index of a stream.
+type segmentIndexKey struct{}
+
+// WithSegmentIndex returns a context under which a Downloader fetches streams
+// segment by segment: index is where the stream keeps its segment index, as
+// YouTube reports for adaptive formats in indexRange. The stream is requested up
+// to the end of the index first, then in ranges of whole segments up to the
+// chunk size; streams whose index isn't an MP4 sidx box, such as WebM, are
+// requested in ranges of the chunk size. Streams of other contexts are
+// requested whole.
+func WithSegmentIndex(ctx context.Context, index ByteRange) context.Context {
+	return context.WithValue(ctx, segmentIndexKey{}, index)
+}
+
+// segmentIndex returns the segment index WithSegmentIndex gave ctx, if any.
+func segmentIndex(ctx context.Context) (ByteRange, bool) {
+	index, ok := ctx.Value(segmentIndexKey{}).(ByteRange)
+	return index, ok && index.Start >= 0 && index.End >= index.Start
+}
+
+// openStream makes the first request of a stream download: for the bytes up
+// to the end of the segment index when ctx has one, otherwise for the whole
+// stream. It returns the URL that was used, like open.
+func (d *Downloader) openStream(ctx context.Context, url string) (*http.Response, string, error) {
+	if index, ok := segmentIndex(ctx); ok {
+		return d.open(ctx, url, 0, index.End+1)
+	}
+	return d.open(ctx, url, 0, 0)
+}
+
+// transferStream copies a stream opened with openStream to dst like transfer,
+// fetching the rest segment by segment when the first response is the part up
+// to the end of the segment index.
+func (d *Downloader) transferStream(ctx context.Context, url string, resp *http.Response, dst io.Writer, coalesce bool, progress ProgressCallback) (Verification, error) {
+	index, ok := segmentIndex(ctx)
+	if !ok || resp.StatusCode != http.StatusPartialContent {
+		// A server that ignores ranges sends the whole stream at once
+		return d.transfer(ctx, url, resp, dst, coalesce, progress)
+	}
+
+	t := &segmentTransfer{d: d, url: url, coalesce: coalesce}
+	t.v.Expected = expectedLength(url, resp)
+	if t.v.Expected == 0 {
+		t.v.Expected = contentRangeTotal(resp)
+	}
+	if progress != nil {
+		t.pr = &progressReader{total: t.v.Expected, callback: progress, meter: newSpeedMeter(time.Now), interval: d.progressInterval}
+	}
+	buf := getBuffer(d.bufferSize)
+	defer putBuffer(buf)
+	t.buf = *buf
+	t.stalls = d.watchStalls(url, t.v.Expected)
+	defer t.stalls.stop()
+
+	// The index is read as it's written, to learn where the segments are
+	var head bytes.Buffer
+	if err := t.fetch(ctx, ByteRange{Start: 0, End: index.End}, resp, io.MultiWriter(dst, &head)); err != nil {
+		t.v.Status = VerificationFailed
+		return t.v, err
+	}
+	// Without a sidx box, the rest is requested in chunks of the chunk size
+	segments, _ := parseSidx(head.Bytes()[index.Start:], index.Start)
+	if err := t.fetchChunks(ctx, segmentChunks(segments, t.v.Downloaded, t.v.Expected, d.chunkSize), dst); err != nil {
+		t.v.Status = VerificationFailed
+		return t.v, err
+	}
+
+	switch {
+	case t.v.Expected == 0:
+		t.v.Status = VerificationSkipped
+	case t.v.Downloaded == t.v.Expected:
+		t.v.Status = Verified
+		if t.v.Repairs > 0 {
+			t.v.Status = Repaired
+		}
+	default:
+		t.v.Status = VerificationFailed
+		return t.v, fmt.Errorf("%w: got %d of %d bytes", ErrSizeMismatch, t.v.Downloaded, t.v.Expected)
+	}
+	return t.v, nil
+}
+
+// segmentTransfer is the state of a stream download made in range requests.
+// The ranges follow each other, so Downloaded is also the offset of the next byte.
+type segmentTransfer struct {
+	d        *Downloader
+	url      string
+	coalesce bool
+	v        Verification
+	pr       *progressReader
+	buf      []byte
+	stalls   *stallWatcher
+}
+
+// fetch copies the bytes of r from the next one on to w, starting with resp if
+// it isn't nil. A range ending at -1 runs to the end of the stream. A transfer
+// that breaks off is resumed up to maxRepairAttempts times.
+func (t *segmentTransfer) fetch(ctx context.Context, r ByteRange, resp *http.Response, w io.Writer) error {
+	for attempt := 0; ; attempt++ {
+		if resp == nil {
+			var length int64
+			if r.End >= 0 {
+				length = r.End - t.v.Downloaded + 1
+			}
+			var err error
+			resp, t.url, err = t.d.open(ctx, t.url, t.v.Downloaded, length)
+			if err != nil {
+				return fmt.Errorf("requesting bytes from %d: %w", t.v.Downloaded, err)
+			}
+			if resp.StatusCode == http.StatusOK {
+				// The server ignored the range; skip the bytes already written
+				if _, err := io.CopyN(io.Discard, resp.Body, t.v.Downloaded); err != nil {
+					_ = resp.Body.Close()
+					return fmt.Errorf("requesting bytes from %d: %w", t.v.Downloaded, err)
+				}
+			}
+		}
+
+		// The rest of a response to an ignored range is kept, so it isn't requested again
+		whole := r.End < 0 || resp.StatusCode == http.StatusOK
+		var body io.Reader = resp.Body
+		if !whole {
+			body = io.LimitReader(resp.Body, r.End-t.v.Downloaded+1)
+		}
+		n, copyErr := copyBody(w, t.stalls.watch(body), t.pr, t.buf, t.coalesce)
+		_ = resp.Body.Close()
+		resp = nil
+		t.v.Downloaded += n
+
+		var readErr *bodyReadError
+		if copyErr != nil && !errors.As(copyErr, &readErr) {
+			return copyErr
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if (whole && copyErr == nil) || (!whole && t.v.Downloaded == r.End+1) {
+			return nil
+		}
+		if attempt == maxRepairAttempts {
+			err := fmt.Errorf("%w: got %d of %d bytes", ErrSizeMismatch, t.v.Downloaded, r.End+1)
+			if copyErr != nil {
+				err = errors.Join(err, copyErr)
+			}
+			return err
+		}
+
+		t.v.Repairs++
+		t.d.emitRetry(ctx, Event{
+			URL: t.url, Downloaded: t.v.Downloaded, Total: t.v.Expected,
+			Reason: RetryResume, Attempt: attempt + 1, Err: copyErr,
+		})
+	}
+}
+
+// fetchChunks copies the chunks to w in order. With a chunk tuner, chunks
+// after the one being written are requested ahead while the tuner's level
+// allows; at level 1 they're copied straight to w.
+func (t *segmentTransfer) fetchChunks(ctx context.Context, chunks []ByteRange, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // Stops the chunks requested ahead when one fails
+
+	ahead := make([]<-chan fetchedChunk, len(chunks))
+	started := 0
+	for i, chunk := range chunks {
+		started = max(started, i)
+		if t.d.chunkTuner != nil && chunk.End >= 0 {
+			for level := t.d.chunkTuner.Concurrency(); level > 1 && started < min(i+level, len(chunks)); started++ {
+				ahead[started] = t.fetchAhead(ctx, chunks[started])
+			}
+		}
+		if chunk.End >= 0 && t.v.Downloaded > chunk.End {
+			// A server that ignored a range already sent this chunk
+			continue
+		}
+		if i == started {
+			started++
+			if err := t.fetch(ctx, chunk, nil, w); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fetched := <-ahead[i]
+		if fetched.err != nil {
+			return fetched.err
+		}
+		t.url = fetched.url
+		t.v.Repairs += fetched.repairs
+		n, err := copyBody(w, bytes.NewReader(fetched.data[t.v.Downloaded-chunk.Start:]), t.pr, t.buf, t.coalesce)
+		t.v.Downloaded += n
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchedChunk is a chunk requested ahead, with the URL it ended up being
+// requested from and the number of times its transfer was resumed.
+type fetchedChunk struct {
+	data    []byte
+	url     string
+	repairs int
+	err     error
+}
+
+// fetchAhead requests the chunk r into memory in the background.
+func (t *segmentTransfer) fetchAhead(ctx context.Context, r ByteRange) <-chan fetchedChunk {
+	c := make(chan fetchedChunk, 1)
+	a := &segmentTransfer{d: t.d, url: t.url, stalls: t.stalls}
+	a.v.Downloaded, a.v.Expected = r.Start, t.v.Expected
+	go func() {
+		buf := getBuffer(t.d.bufferSize)
+		defer putBuffer(buf)
+		a.buf = *buf
+
+		var data bytes.Buffer
+		if r.End >= 0 {
+			data.Grow(int(r.Length()))
+		}
+		err := a.fetch(ctx, r, nil, &data)
+		c <- fetchedChunk{data: data.Bytes(), url: a.url, repairs: a.v.Repairs, err: err}
+	}()
+	return c
+}
+
+// segmentChunks groups segments into the ranges to request after the first
+// start bytes: as many whole segments as fit in size, then the rest of the
+// stream up to total in ranges of size. Without segments or a total, the rest
+// is one range ending at -1.
+func segmentChunks(segments []ByteRange, start, total, size int64) []ByteRange {
+	var chunks []ByteRange
+	next := start
+	chunk := ByteRange{Start: start, End: start - 1}
+	for _, s := range segments {
+		if s.End < next || (total > 0 && s.End >= total) {
+			continue
+		}
+		if chunk.End >= chunk.Start && s.End-chunk.Start+1 > size {
+			chunks = append(chunks, chunk)
+			chunk = ByteRange{Start: chunk.End + 1, End: chunk.End}
+		}
+		chunk.End = s.End
+		next = s.End + 1
+	}
+	if chunk.End >= chunk.Start {
+		chunks = append(chunks, chunk)
+	}
+
+	if total <= 0 {
+		if len(chunks) == 0 {
+			chunks = append(chunks, ByteRange{Start: start, End: -1})
+		}
+		return chunks
+	}
+	for next < total {
+		end := min(next+size, total) - 1
+		chunks = append(chunks, ByteRange{Start: next, End: end})
+		next = end + 1
+	}
+	return chunks
+}
+
+// parseSidx parses the segments out of an MP4 segment index box (sidx) read
+// from offset of the stream. Segments follow each other from the end of the
+// box, after its first offset.
+func parseSidx(data []byte, offset int64) ([]ByteRange, error) {
+	if len(data) < 8 || string(data[4:8]) != "sidx" {
+		return nil, errors.New("not a sidx box")
+	}
+	size := int64(binary.BigEndian.Uint32(data[:4]))
+	if size < 8 || size > int64(len(data)) {
+		return nil, errors.New("truncated sidx box")
+	}
+	box := data[:size]
+
+	// version and flags, reference ID and timescale
+	pos := 8 + 4 + 8
+	var firstOffset int64
+	switch version := box[8]; {
+	case version == 0 && len(box) >= pos+8:
+		firstOffset = int64(binary.BigEndian.Uint32(box[pos+4:]))
+		pos += 8
+	case version == 1 && len(box) >= pos+16:
+		firstOffset = int64(binary.BigEndian.Uint64(box[pos+8:]))
+		pos += 16
+	default:
+		return nil, errors.New("truncated sidx box")
+	}
+	if len(box) < pos+4 {
+		return nil, errors.New("truncated sidx box")
+	}
+	count := int(binary.BigEndian.Uint16(box[pos+2:]))
+	pos += 4
+	if len(box) < pos+count*12 {
+		return nil, errors.New("truncated sidx box")
+	}
+
+	segments := make([]ByteRange, 0, count)
+	start := offset + size + firstOffset
+	for i := range count {
+		ref := binary.BigEndian.Uint32(box[pos+i*12:])
+		if ref&(1<<31) != 0 {
+			return nil, errors.New("sidx box references other indexes")
+		}
+		length := int64(ref &^ (1 << 31))
+		if length == 0 {
+			continue
+		}
+		segments = append(segments, ByteRange{Start: start, End: start + length - 1})
+		start += length
+	}
+	return segments, nil
+}
+
+// contentRangeTotal returns the full length of the stream a partial response
+// is part of, from its Content-Range header, or 0 if it doesn't say.
+func contentRangeTotal(resp *http.Response) int64 {
+	_, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.ParseInt(total, 10, 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n
+}
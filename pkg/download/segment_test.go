@@ -0,0 +1,283 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSidx builds a version 0 sidx box referencing segments of the given sizes,
+// which start firstOffset bytes after the box.
+func testSidx(firstOffset uint32, sizes ...uint32) []byte {
+	box := make([]byte, 32+12*len(sizes))
+	binary.BigEndian.PutUint32(box, uint32(len(box)))
+	copy(box[4:], "sidx")
+	binary.BigEndian.PutUint32(box[16:], 1000) // timescale
+	binary.BigEndian.PutUint32(box[24:], firstOffset)
+	binary.BigEndian.PutUint16(box[30:], uint16(len(sizes)))
+	for i, size := range sizes {
+		binary.BigEndian.PutUint32(box[32+12*i:], size)
+	}
+	return box
+}
+
+// newRangeServer serves content with range support, recording the Range
+// header of every request.
+func newRangeServer(t *testing.T, content []byte) (*httptest.Server, func() []string) {
+	t.Helper()
+	var (
+		mu     sync.Mutex
+		ranges []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(ranges)
+	}
+}
+
+// testSegmentedContent returns a stream of a 100 byte init segment, a sidx box
+// at 100, and segments of 300, 300, 500 and 400 bytes, with the box's range.
+func testSegmentedContent() ([]byte, ByteRange) {
+	sidx := testSidx(0, 300, 300, 500, 400)
+	content := testContent(100 + len(sidx) + 1500)
+	copy(content[100:], sidx)
+	return content, ByteRange{Start: 100, End: int64(100 + len(sidx) - 1)}
+}
+
+func TestParseSidx(t *testing.T) {
+	segments, err := parseSidx(testSidx(10, 300, 500), 100)
+	if err != nil {
+		t.Fatalf("parseSidx failed: %v", err)
+	}
+	// The box is 56 bytes long, so the first segment starts at 100+56+10
+	want := []ByteRange{{Start: 166, End: 465}, {Start: 466, End: 965}}
+	if !slices.Equal(segments, want) {
+		t.Errorf("segments = %v, want %v", segments, want)
+	}
+
+	for _, data := range [][]byte{nil, []byte("\x00\x00\x00\x10moof00000000"), testSidx(0, 300)[:40]} {
+		if _, err := parseSidx(data, 0); err == nil {
+			t.Errorf("parseSidx(%q) succeeded", data)
+		}
+	}
+}
+
+func TestSegmentChunks(t *testing.T) {
+	segments := []ByteRange{{Start: 100, End: 399}, {Start: 400, End: 699}, {Start: 700, End: 1199}, {Start: 1200, End: 1599}}
+	tests := []struct {
+		name     string
+		segments []ByteRange
+		total    int64
+		size     int64
+		want     []ByteRange
+	}{
+		{"grouped", segments, 1600, 600, []ByteRange{{100, 699}, {700, 1199}, {1200, 1599}}},
+		{"segment larger than size", segments, 1600, 250, []ByteRange{{100, 399}, {400, 699}, {700, 1199}, {1200, 1599}}},
+		{"rest after segments", segments[:2], 1000, 1000, []ByteRange{{100, 699}, {700, 999}}},
+		{"no index", nil, 1000, 400, []ByteRange{{100, 499}, {500, 899}, {900, 999}}},
+		{"no index or total", nil, 0, 400, []ByteRange{{100, -1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := segmentChunks(tt.segments, 100, tt.total, tt.size); !slices.Equal(got, tt.want) {
+				t.Errorf("segmentChunks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadStream_SegmentIndex(t *testing.T) {
+	content, index := testSegmentedContent()
+	server, ranges := newRangeServer(t, content)
+
+	filePath := filepath.Join(t.TempDir(), "video.mp4")
+	ctx := WithSegmentIndex(context.Background(), index)
+	var last Progress
+	result := NewDownloader(server.Client(), WithChunkSize(700)).DownloadStreamResult(ctx, server.URL, filePath, func(p Progress) { last = p })
+	if result.Error != nil {
+		t.Fatalf("DownloadStreamResult failed: %v", result.Error)
+	}
+
+	got, _ := os.ReadFile(filePath)
+	if !bytes.Equal(got, content) {
+		t.Errorf("downloaded %d bytes that differ from the %d of the stream", len(got), len(content))
+	}
+	// The init segment and index, then two segments, then one each as no two fit in 700 bytes
+	boxEnd := index.End + 1
+	want := []string{
+		"bytes=0-" + itoa(index.End),
+		"bytes=" + itoa(boxEnd) + "-" + itoa(boxEnd+599),
+		"bytes=" + itoa(boxEnd+600) + "-" + itoa(boxEnd+1099),
+		"bytes=" + itoa(boxEnd+1100) + "-" + itoa(boxEnd+1499),
+	}
+	if got := ranges(); !slices.Equal(got, want) {
+		t.Errorf("requested %q, want %q", got, want)
+	}
+	if result.Verification.Status != Verified || last.Downloaded != int64(len(content)) || last.Total != int64(len(content)) {
+		t.Errorf("verification = %+v, final progress %d/%d", result.Verification, last.Downloaded, last.Total)
+	}
+}
+
+func TestDownloadStreamTo_SegmentIndexWithoutSidx(t *testing.T) {
+	content := testContent(1000)
+	server, ranges := newRangeServer(t, content)
+
+	var buf bytes.Buffer
+	ctx := WithSegmentIndex(context.Background(), ByteRange{Start: 50, End: 99})
+	if err := NewDownloader(server.Client(), WithChunkSize(400)).DownloadStreamTo(ctx, server.URL, &buf, nil); err != nil {
+		t.Fatalf("DownloadStreamTo failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), content) {
+		t.Errorf("downloaded %d bytes that differ from the stream", buf.Len())
+	}
+	want := []string{"bytes=0-99", "bytes=100-499", "bytes=500-899", "bytes=900-999"}
+	if got := ranges(); !slices.Equal(got, want) {
+		t.Errorf("requested %q, want %q", got, want)
+	}
+}
+
+func TestDownloadStream_SegmentIndexRepairsTruncatedChunk(t *testing.T) {
+	content, index := testSegmentedContent()
+	var cut sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "bytes=0-"+itoa(index.End) {
+			truncated := false
+			cut.Do(func() { truncated = true })
+			if truncated {
+				// Declare the whole range but send only part of it
+				w.Header().Set("Content-Length", "300")
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = w.Write(content[index.End+1 : index.End+101])
+				return
+			}
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	filePath := filepath.Join(t.TempDir(), "video.mp4")
+	ctx := WithSegmentIndex(context.Background(), index)
+	result := NewDownloader(server.Client()).DownloadStreamResult(ctx, server.URL, filePath, nil)
+	if result.Error != nil {
+		t.Fatalf("DownloadStreamResult failed: %v", result.Error)
+	}
+	if got, _ := os.ReadFile(filePath); !bytes.Equal(got, content) {
+		t.Errorf("repaired file differs from the stream (%d of %d bytes)", len(got), len(content))
+	}
+	if result.Verification.Status != Repaired || result.Verification.Repairs != 1 {
+		t.Errorf("verification = %+v, want repaired once", result.Verification)
+	}
+}
+
+func TestDownloadStream_SegmentIndexChunksAhead(t *testing.T) {
+	content, index := testSegmentedContent()
+	// Chunk requests wait for each other, so they're only answered if all three are made at once
+	var mu sync.Mutex
+	waiting := 0
+	all := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "bytes=0-"+itoa(index.End) {
+			mu.Lock()
+			if waiting++; waiting == 3 {
+				close(all)
+			}
+			mu.Unlock()
+			select {
+			case <-all:
+			case <-time.After(5 * time.Second):
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	filePath := filepath.Join(t.TempDir(), "video.mp4")
+	ctx := WithSegmentIndex(context.Background(), index)
+	var last Progress
+	d := NewDownloader(server.Client(), WithChunkSize(700), WithChunkTuner(NewConcurrencyTuner(3, 3)))
+	result := d.DownloadStreamResult(ctx, server.URL, filePath, func(p Progress) { last = p })
+	if result.Error != nil {
+		t.Fatalf("DownloadStreamResult failed: %v", result.Error)
+	}
+	if got, _ := os.ReadFile(filePath); !bytes.Equal(got, content) {
+		t.Errorf("downloaded %d bytes that differ from the %d of the stream", len(got), len(content))
+	}
+	if result.Verification.Status != Verified || last.Downloaded != int64(len(content)) {
+		t.Errorf("verification = %+v, final progress %d", result.Verification, last.Downloaded)
+	}
+}
+
+func TestDownloadStream_SegmentIndexRepairsChunkAhead(t *testing.T) {
+	content, index := testSegmentedContent()
+	boxEnd := index.End + 1
+	var cut sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") == "bytes="+itoa(boxEnd+1100)+"-"+itoa(boxEnd+1499) {
+			truncated := false
+			cut.Do(func() { truncated = true })
+			if truncated {
+				w.Header().Set("Content-Length", "400")
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = w.Write(content[boxEnd+1100 : boxEnd+1200])
+				return
+			}
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	filePath := filepath.Join(t.TempDir(), "video.mp4")
+	ctx := WithSegmentIndex(context.Background(), index)
+	d := NewDownloader(server.Client(), WithChunkSize(700), WithChunkTuner(NewConcurrencyTuner(2, 2)))
+	result := d.DownloadStreamResult(ctx, server.URL, filePath, nil)
+	if result.Error != nil {
+		t.Fatalf("DownloadStreamResult failed: %v", result.Error)
+	}
+	if got, _ := os.ReadFile(filePath); !bytes.Equal(got, content) {
+		t.Errorf("repaired file differs from the stream (%d of %d bytes)", len(got), len(content))
+	}
+	if result.Verification.Status != Repaired || result.Verification.Repairs != 1 {
+		t.Errorf("verification = %+v, want repaired once", result.Verification)
+	}
+}
+
+func TestDownloadStream_SegmentIndexIgnoredRange(t *testing.T) {
+	content, index := testSegmentedContent()
+	server, requests := newTruncatingServer(t, content, 0, true)
+
+	filePath := filepath.Join(t.TempDir(), "video.mp4")
+	ctx := WithSegmentIndex(context.Background(), index)
+	result := NewDownloader(server.Client()).DownloadStreamResult(ctx, server.URL, filePath, nil)
+	if result.Error != nil {
+		t.Fatalf("DownloadStreamResult failed: %v", result.Error)
+	}
+	if got, _ := os.ReadFile(filePath); !bytes.Equal(got, content) {
+		t.Errorf("downloaded file differs from the stream")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want the whole stream in one", got)
+	}
+}
+
+func itoa(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
@@ -0,0 +1,86 @@
+package download
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// speedWindow is the minimum interval between instantaneous speed samples.
+	// Shorter windows make the speed jump with every network read.
+	speedWindow = 500 * time.Millisecond
+
+	// speedSmoothing is the weight of the newest sample in the smoothed speed.
+	speedSmoothing = 0.3
+)
+
+// speedMeter computes the timing fields of Progress for a single transfer.
+type speedMeter struct {
+	now   func() time.Time
+	start time.Time
+
+	sampled     bool
+	sampleTime  time.Time
+	sampleBytes int64
+	speed       float64
+}
+
+// newSpeedMeter creates a speedMeter whose transfer starts now.
+func newSpeedMeter(now func() time.Time) *speedMeter {
+	start := now()
+	return &speedMeter{now: now, start: start, sampleTime: start}
+}
+
+// progress returns the progress of the transfer with elapsed time, speed and ETA filled in.
+func (m *speedMeter) progress(downloaded, total int64) Progress {
+	now := m.now()
+	p := Progress{
+		Downloaded: downloaded,
+		Total:      total,
+		Elapsed:    now.Sub(m.start),
+	}
+	if p.Elapsed > 0 {
+		p.AverageSpeed = float64(downloaded) / p.Elapsed.Seconds()
+	}
+
+	if window := now.Sub(m.sampleTime); window >= speedWindow {
+		sample := float64(downloaded-m.sampleBytes) / window.Seconds()
+		if m.sampled {
+			m.speed = speedSmoothing*sample + (1-speedSmoothing)*m.speed
+		} else {
+			m.speed = sample
+			m.sampled = true
+		}
+		m.sampleTime, m.sampleBytes = now, downloaded
+	}
+
+	// Until the first window completes the average is the best estimate
+	p.Speed = m.speed
+	if !m.sampled {
+		p.Speed = p.AverageSpeed
+	}
+
+	if total > 0 && downloaded < total && p.Speed > 0 {
+		p.ETA = time.Duration(float64(total-downloaded) / p.Speed * float64(time.Second))
+	}
+	return p
+}
+
+// FormatBytes formats a byte count using binary units (e.g. "25.0 MiB").
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// FormatSpeed formats a transfer rate in bytes per second (e.g. "2.5 MiB/s").
+func FormatSpeed(bytesPerSecond float64) string {
+	return FormatBytes(int64(bytesPerSecond)) + "/s"
+}
@@ -0,0 +1,116 @@
+package download
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for speed tests.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func TestSpeedMeter_AverageSpeedAndETA(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	meter := newSpeedMeter(clock.now)
+
+	clock.advance(2 * time.Second)
+	p := meter.progress(2000, 10000)
+
+	if p.Elapsed != 2*time.Second {
+		t.Errorf("Elapsed = %v, want 2s", p.Elapsed)
+	}
+	if p.AverageSpeed != 1000 {
+		t.Errorf("AverageSpeed = %v, want 1000", p.AverageSpeed)
+	}
+	if p.Speed != 1000 {
+		t.Errorf("Speed = %v, want 1000", p.Speed)
+	}
+	if p.ETA != 8*time.Second {
+		t.Errorf("ETA = %v, want 8s", p.ETA)
+	}
+}
+
+func TestSpeedMeter_SmoothsInstantaneousSpeed(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	meter := newSpeedMeter(clock.now)
+
+	clock.advance(time.Second)
+	meter.progress(1000, 0)
+
+	// The transfer speeds up to 2000 B/s; the smoothed speed moves towards it
+	clock.advance(time.Second)
+	p := meter.progress(3000, 0)
+
+	want := speedSmoothing*2000 + (1-speedSmoothing)*1000
+	if p.Speed != want {
+		t.Errorf("Speed = %v, want %v", p.Speed, want)
+	}
+	if p.AverageSpeed != 1500 {
+		t.Errorf("AverageSpeed = %v, want 1500", p.AverageSpeed)
+	}
+}
+
+func TestSpeedMeter_KeepsSpeedWithinWindow(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	meter := newSpeedMeter(clock.now)
+
+	clock.advance(time.Second)
+	meter.progress(1000, 0)
+
+	// A burst shorter than the sample window doesn't change the speed
+	clock.advance(10 * time.Millisecond)
+	p := meter.progress(5000, 0)
+
+	if p.Speed != 1000 {
+		t.Errorf("Speed = %v, want 1000", p.Speed)
+	}
+}
+
+func TestSpeedMeter_NoETAWithoutTotal(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	meter := newSpeedMeter(clock.now)
+
+	clock.advance(time.Second)
+	if p := meter.progress(1000, 0); p.ETA != 0 {
+		t.Errorf("ETA = %v, want 0 for unknown total", p.ETA)
+	}
+
+	clock.advance(time.Second)
+	if p := meter.progress(2000, 2000); p.ETA != 0 {
+		t.Errorf("ETA = %v, want 0 for finished transfer", p.ETA)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		input int64
+		want  string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{25 << 20, "25.0 MiB"},
+		{3 << 29, "1.5 GiB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatBytes(tt.input); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSpeed(t *testing.T) {
+	if got := FormatSpeed(2.5 * (1 << 20)); got != "2.5 MiB/s" {
+		t.Errorf("FormatSpeed() = %q, want %q", got, "2.5 MiB/s")
+	}
+}
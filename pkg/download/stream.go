@@ -0,0 +1,97 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// StreamDownloader downloads streams by URL. Downloader implements it; other
+// implementations can cache streams, instrument downloads or fake them in tests.
+type StreamDownloader interface {
+	// DownloadStreamResult downloads the stream at url to filePath.
+	DownloadStreamResult(ctx context.Context, url, filePath string, progress ProgressCallback) DownloadResult
+
+	// DownloadStreamTo downloads the stream at url and writes it to dst.
+	DownloadStreamTo(ctx context.Context, url string, dst io.Writer, progress ProgressCallback) error
+}
+
+var _ StreamDownloader = (*Downloader)(nil)
+
+// urlRefreshable is implemented by stream downloaders that can replace expired
+// stream URLs, such as Downloader.
+type urlRefreshable interface {
+	withURLRefresher(refresh URLRefresher) StreamDownloader
+}
+
+func (d *Downloader) withURLRefresher(refresh URLRefresher) StreamDownloader {
+	return d.WithURLRefresher(refresh)
+}
+
+// WithRefresher returns d set to replace stream URLs through refresh, like
+// Downloader.WithURLRefresher. Other implementations are returned unchanged.
+func WithRefresher(d StreamDownloader, refresh URLRefresher) StreamDownloader {
+	if r, ok := d.(urlRefreshable); ok {
+		return r.withURLRefresher(refresh)
+	}
+	return d
+}
+
+// DownloadPiped downloads the stream at url with d into a pipe that consume
+// reads, like Downloader.DownloadStreamPiped.
+func DownloadPiped(ctx context.Context, d StreamDownloader, url string, consume func(r io.Reader) error, progress ProgressCallback) error {
+	pr, pw := io.Pipe()
+	downloadErr := make(chan error, 1)
+	go func() {
+		err := d.DownloadStreamTo(ctx, url, pw, progress)
+		_ = pw.CloseWithError(err)
+		downloadErr <- err
+	}()
+
+	err := consume(pr)
+	// Unblock the download if consume stopped reading early
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	if dlErr := <-downloadErr; dlErr != nil && !errors.Is(dlErr, io.ErrClosedPipe) {
+		return dlErr
+	}
+	return err
+}
+
+// DownloadParallel downloads streams with d in parallel, like
+// Downloader.DownloadStreamsParallel.
+func DownloadParallel(ctx context.Context, d StreamDownloader, streams []StreamDownload, progress ProgressCallback) []DownloadResult {
+	if len(streams) == 0 {
+		return nil
+	}
+
+	results := make([]DownloadResult, len(streams))
+	var wg sync.WaitGroup
+
+	// Create aggregate progress tracker
+	var tracker *aggregateProgressTracker
+	if progress != nil {
+		tracker = newAggregateProgressTracker(len(streams), progress)
+	}
+
+	for i, stream := range streams {
+		wg.Add(1)
+		go func(idx int, s StreamDownload) {
+			defer wg.Done()
+
+			var streamProgress ProgressCallback
+			if tracker != nil {
+				streamProgress = tracker.progressCallbackFor(idx)
+			}
+
+			streamCtx := ctx
+			if s.Index != nil {
+				streamCtx = WithSegmentIndex(ctx, *s.Index)
+			}
+			results[idx] = d.DownloadStreamResult(streamCtx, s.URL, s.FilePath, streamProgress)
+		}(i, stream)
+	}
+
+	wg.Wait()
+	return results
+}
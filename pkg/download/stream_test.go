@@ -0,0 +1,103 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// memoryDownloader serves streams from a map of URL to content.
+type memoryDownloader map[string]string
+
+func (m memoryDownloader) DownloadStreamResult(ctx context.Context, url, filePath string, progress ProgressCallback) DownloadResult {
+	content, ok := m[url]
+	if !ok {
+		return DownloadResult{FilePath: filePath, Error: &HTTPError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}}
+	}
+	if progress != nil {
+		progress(Progress{Downloaded: int64(len(content)), Total: int64(len(content))})
+	}
+	err := os.WriteFile(filePath, []byte(content), 0o644)
+	return DownloadResult{FilePath: filePath, Error: err, Size: int64(len(content))}
+}
+
+func (m memoryDownloader) DownloadStreamTo(ctx context.Context, url string, dst io.Writer, progress ProgressCallback) error {
+	content, ok := m[url]
+	if !ok {
+		return &HTTPError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}
+	}
+	_, err := io.WriteString(dst, content)
+	return err
+}
+
+func TestDownloadParallel_CustomDownloader(t *testing.T) {
+	d := memoryDownloader{"video": "vvvv", "audio": "aa"}
+	dir := t.TempDir()
+	streams := []StreamDownload{
+		{URL: "video", FilePath: filepath.Join(dir, "video.mp4")},
+		{URL: "audio", FilePath: filepath.Join(dir, "audio.m4a")},
+		{URL: "missing", FilePath: filepath.Join(dir, "missing.bin")},
+	}
+
+	var mu sync.Mutex
+	var last Progress
+	results := DownloadParallel(context.Background(), d, streams, func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if p.Downloaded > last.Downloaded {
+			last = p
+		}
+	})
+	if results[0].Error != nil || results[1].Error != nil {
+		t.Fatalf("results = %+v", results)
+	}
+	var httpErr *HTTPError
+	if !errors.As(results[2].Error, &httpErr) {
+		t.Errorf("missing stream error = %v, want an HTTPError", results[2].Error)
+	}
+	if data, _ := os.ReadFile(streams[1].FilePath); string(data) != "aa" {
+		t.Errorf("audio = %q", data)
+	}
+	if last.Downloaded != 6 {
+		t.Errorf("aggregate progress = %+v, want 6 bytes", last)
+	}
+}
+
+func TestDownloadPiped_CustomDownloader(t *testing.T) {
+	d := memoryDownloader{"stream": "piped content"}
+	var got []byte
+	err := DownloadPiped(context.Background(), d, "stream", func(r io.Reader) error {
+		var err error
+		got, err = io.ReadAll(r)
+		return err
+	}, nil)
+	if err != nil {
+		t.Fatalf("DownloadPiped() error = %v", err)
+	}
+	if string(got) != "piped content" {
+		t.Errorf("consumed %q", got)
+	}
+}
+
+func TestWithRefresher(t *testing.T) {
+	refresh := func(context.Context, string) (string, error) { return "fresh", nil }
+
+	d := NewDownloader(http.DefaultClient)
+	refreshing, ok := WithRefresher(d, refresh).(*Downloader)
+	if !ok || refreshing.refresh == nil {
+		t.Errorf("WithRefresher(*Downloader) = %#v, want a downloader with the refresher", refreshing)
+	}
+	if d.refresh != nil {
+		t.Error("WithRefresher changed the original downloader")
+	}
+
+	m := memoryDownloader{}
+	if got, ok := WithRefresher(m, refresh).(memoryDownloader); !ok || got == nil {
+		t.Errorf("WithRefresher(custom) = %#v, want it unchanged", got)
+	}
+}
@@ -0,0 +1,79 @@
+package download
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// tempDirPattern names the directories of a download's intermediate files.
+// They are hidden because by default they sit next to the output.
+const tempDirPattern = ".ytdl-*"
+
+// TempParent returns the directory the intermediate files of a download to
+// outputPath are kept in: dir when it is set, otherwise the output's directory,
+// so the finished file is moved into place by a rename on the same filesystem.
+// Without an output path, such as when streaming to stdout, it is the system
+// temp directory.
+func TempParent(dir, outputPath string) string {
+	switch {
+	case dir != "":
+		return dir
+	case outputPath == "":
+		return os.TempDir()
+	default:
+		return filepath.Dir(outputPath)
+	}
+}
+
+// MkdirTemp creates a directory for the intermediate files of a download to
+// outputPath in TempParent(dir, outputPath). The caller removes it when done.
+func MkdirTemp(dir, outputPath string) (string, error) {
+	parent := TempParent(dir, outputPath)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(parent, tempDirPattern)
+}
+
+// MoveFile moves src to dst, replacing dst. When they are on different
+// filesystems, where a rename fails, src is copied to dst and removed.
+func MoveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+	if err := copyFile(src, dst); err != nil {
+		return fmt.Errorf("copying %s across filesystems: %w", src, err)
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies src to dst, preserving the file mode. A partial dst is removed.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestTempParent(t *testing.T) {
+	output := filepath.Join("videos", "clip.mp4")
+	if got := TempParent("", output); got != "videos" {
+		t.Errorf("TempParent() = %q, want the output's directory", got)
+	}
+	if got := TempParent("scratch", output); got != "scratch" {
+		t.Errorf("TempParent() = %q, want the given directory", got)
+	}
+	if got := TempParent("", ""); got != os.TempDir() {
+		t.Errorf("TempParent() = %q, want the system temp directory without an output", got)
+	}
+}
+
+func TestMkdirTemp(t *testing.T) {
+	parent := filepath.Join(t.TempDir(), "missing")
+	dir, err := MkdirTemp(parent, "ignored.mp4")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	if filepath.Dir(dir) != parent || !strings.HasPrefix(filepath.Base(dir), ".ytdl-") {
+		t.Errorf("MkdirTemp() = %q, want a hidden directory in %q", dir, parent)
+	}
+}
+
+func TestMoveFile(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "muxed.mp4"), filepath.Join(dir, "video.mp4")
+	if err := os.WriteFile(src, []byte("muxed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MoveFile(src, dst); err != nil {
+		t.Fatalf("MoveFile() error = %v", err)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "muxed" {
+		t.Errorf("dst = %q, %v; want the moved file", data, err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("src still exists: %v", err)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	if err := os.WriteFile(src, []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyFile(src, dst); err != nil {
+		t.Fatalf("copyFile() error = %v", err)
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "content" || info.Mode().Perm() != 0o600 {
+		t.Errorf("dst = %q with mode %v, want a copy of src", data, info.Mode().Perm())
+	}
+}
+
+func TestIsCrossDevice(t *testing.T) {
+	if isCrossDevice(&os.LinkError{Op: "rename", Err: syscall.ENOENT}) {
+		t.Error("a missing file isn't a cross-device rename")
+	}
+	if isCrossDevice(nil) {
+		t.Error("nil isn't a cross-device rename")
+	}
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package download
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, returned when a file is moved
+// to another drive.
+const errorNotSameDevice syscall.Errno = 17
+
+// isCrossDevice reports whether a rename failed because its paths are on
+// different filesystems.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, errorNotSameDevice)
+}
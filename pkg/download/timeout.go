@@ -0,0 +1,40 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is returned when a stream download takes longer than the
+// downloader's timeout.
+var ErrTimeout = errors.New("download timed out")
+
+// WithTimeout bounds how long each stream download may take, however fast data
+// is still arriving; it fails with ErrTimeout. 0, the default, means no limit.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Downloader) {
+		d.timeout = max(timeout, 0)
+	}
+}
+
+// withTimeout returns ctx bounded by the downloader's timeout.
+func (d *Downloader) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, d.timeout, fmt.Errorf("%w after %s", ErrTimeout, d.timeout))
+}
+
+// timeoutError replaces err with the downloader's timeout if that is what
+// ended the download, rather than the caller's context or the network.
+func timeoutError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if cause := context.Cause(ctx); errors.Is(cause, ErrTimeout) {
+		return cause
+	}
+	return err
+}
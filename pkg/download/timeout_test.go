@@ -0,0 +1,75 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTricklingServer serves a stream one byte every interval, for count bytes.
+func newTricklingServer(t *testing.T, count int, interval time.Duration) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for range count {
+			if _, err := w.Write([]byte("x")); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-time.After(interval):
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWithTimeout_StopsLongDownloads(t *testing.T) {
+	server := newTricklingServer(t, 100, 10*time.Millisecond)
+	d := NewDownloader(server.Client(), WithTimeout(50*time.Millisecond))
+
+	err := d.DownloadStream(context.Background(), server.URL, filepath.Join(t.TempDir(), "out.bin"), nil)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("DownloadStream() error = %v, want ErrTimeout", err)
+	}
+	if want := "download timed out after 50ms"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+
+	err = d.DownloadStreamTo(context.Background(), server.URL, new(bytes.Buffer), nil)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("DownloadStreamTo() error = %v, want ErrTimeout", err)
+	}
+}
+
+func TestWithTimeout_FinishesInTime(t *testing.T) {
+	server := newTricklingServer(t, 3, time.Millisecond)
+	d := NewDownloader(server.Client(), WithTimeout(time.Minute))
+
+	var buf bytes.Buffer
+	if err := d.DownloadStreamTo(context.Background(), server.URL, &buf, nil); err != nil {
+		t.Fatalf("DownloadStreamTo() error = %v", err)
+	}
+	if buf.String() != "xxx" {
+		t.Errorf("downloaded %q, want %q", buf.String(), "xxx")
+	}
+}
+
+func TestWithTimeout_KeepsCallerCancellation(t *testing.T) {
+	server := newTricklingServer(t, 100, 10*time.Millisecond)
+	d := NewDownloader(server.Client(), WithTimeout(time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	err := d.DownloadStreamTo(ctx, server.URL, new(bytes.Buffer), nil)
+	if errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DownloadStreamTo() error = %v, want the caller's deadline", err)
+	}
+}
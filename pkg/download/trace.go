@@ -0,0 +1,45 @@
+package download
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tracing"
+)
+
+// startStreamSpan starts the span of a stream download, with the stream's
+// host and itag.
+func startStreamSpan(ctx context.Context, streamURL string) (context.Context, tracing.Span) {
+	var attrs []tracing.Attribute
+	if u, err := url.Parse(streamURL); err == nil {
+		attrs = append(attrs, tracing.String(tracing.AttrHost, u.Host))
+		if itag, err := strconv.ParseInt(u.Query().Get("itag"), 10, 64); err == nil {
+			attrs = append(attrs, tracing.Int64(tracing.AttrItag, itag))
+		}
+	}
+	return tracing.Start(ctx, tracing.SpanStream, attrs...)
+}
+
+// endStreamSpan ends the span of a stream download with its size and how it
+// was verified.
+func endStreamSpan(span tracing.Span, v Verification, err error) {
+	span.SetAttributes(tracing.Int64(tracing.AttrBytes, v.Downloaded), tracing.String(tracing.AttrVerification, v.Status.String()))
+	tracing.End(span, err)
+}
+
+// emitRetry emits an EventRetryScheduled and records the retry on the span of
+// ctx, so retries show up in the trace of the download they slowed down.
+func (d *Downloader) emitRetry(ctx context.Context, e Event) {
+	e.Type = EventRetryScheduled
+	d.emit(e)
+	attrs := []tracing.Attribute{
+		tracing.String(tracing.AttrRetryReason, e.Reason),
+		tracing.Int64(tracing.AttrRetryAttempt, int64(e.Attempt)),
+		tracing.Int64(tracing.AttrOffset, e.Downloaded),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, tracing.String(tracing.AttrError, e.Err.Error()))
+	}
+	tracing.SpanFrom(ctx).AddEvent(tracing.EventRetry, attrs...)
+}
@@ -0,0 +1,62 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tracing"
+)
+
+func TestDownloader_TracesStreams(t *testing.T) {
+	content := testContent(1000)
+	server, _ := newExpiringServer(t, content)
+	downloader := NewDownloader(server.Client()).WithURLRefresher(func(context.Context, string) (string, error) {
+		return server.URL + "/fresh?itag=140&clen=1000", nil
+	})
+	recorder := &tracing.Recorder{}
+	ctx := tracing.WithTracer(context.Background(), recorder)
+
+	// The stream breaks off and is resumed from a refreshed URL
+	if err := downloader.DownloadStreamTo(ctx, server.URL+"/expiring?itag=140&clen=1000", new(bytes.Buffer), nil); err != nil {
+		t.Fatalf("DownloadStreamTo: %v", err)
+	}
+	spans := recorder.Named(tracing.SpanStream)
+	if len(spans) != 1 {
+		t.Fatalf("stream spans = %d, want 1", len(spans))
+	}
+	span := spans[0]
+	if itag, _ := span.Attribute(tracing.AttrItag); itag != int64(140) {
+		t.Errorf("itag = %v, want 140", itag)
+	}
+	if size, _ := span.Attribute(tracing.AttrBytes); size != int64(len(content)) {
+		t.Errorf("bytes = %v, want %d", size, len(content))
+	}
+	var reasons []any
+	for _, event := range span.Events {
+		if event.Name == tracing.EventRetry {
+			reasons = append(reasons, event.Attributes[0].Value)
+		}
+	}
+	if len(reasons) != 2 || reasons[0] != RetryResume || reasons[1] != RetryRefresh {
+		t.Errorf("retry events = %v, want a resume and a refresh", reasons)
+	}
+	if !span.Ended || span.Err != nil {
+		t.Errorf("span ended %v with error %v", span.Ended, span.Err)
+	}
+}
+
+func TestDownloader_TracesFailedStream(t *testing.T) {
+	server, _ := newExpiringServer(t, testContent(10))
+	recorder := &tracing.Recorder{}
+	ctx := tracing.WithTracer(context.Background(), recorder)
+
+	result := NewDownloader(server.Client()).DownloadStreamResult(ctx, server.URL+"/stale", filepath.Join(t.TempDir(), "out.bin"), nil)
+	spans := recorder.Named(tracing.SpanStream)
+	var httpErr *HTTPError
+	if len(spans) != 1 || !errors.As(spans[0].Err, &httpErr) || !errors.Is(spans[0].Err, result.Error) {
+		t.Errorf("spans = %+v, want one with the download's error", spans)
+	}
+}
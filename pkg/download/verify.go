@@ -0,0 +1,227 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// maxRepairAttempts is the number of range requests made to fetch missing bytes
+// after a transfer ends short of the expected length.
+const maxRepairAttempts = 3
+
+// ErrSizeMismatch is returned when a download's size doesn't match the expected
+// length and the missing bytes could not be downloaded again.
+var ErrSizeMismatch = errors.New("downloaded size does not match expected length")
+
+// VerificationStatus is the outcome of checking a download's size.
+type VerificationStatus int
+
+const (
+	// VerificationSkipped means the expected length was unknown.
+	VerificationSkipped VerificationStatus = iota
+	// Verified means the downloaded size matched the expected length.
+	Verified
+	// Repaired means missing ranges were downloaded again until the size matched.
+	Repaired
+	// VerificationFailed means the size still didn't match after repairing.
+	VerificationFailed
+)
+
+// String returns the name of the status.
+func (s VerificationStatus) String() string {
+	switch s {
+	case Verified:
+		return "verified"
+	case Repaired:
+		return "repaired"
+	case VerificationFailed:
+		return "failed"
+	default:
+		return "skipped"
+	}
+}
+
+// Verification describes how a download's size was checked.
+type Verification struct {
+	// Status is the outcome of the check.
+	Status VerificationStatus
+
+	// Expected is the expected length in bytes, 0 if unknown.
+	Expected int64
+
+	// Downloaded is the number of bytes written.
+	Downloaded int64
+
+	// Repairs is the number of range requests made for missing bytes.
+	Repairs int
+}
+
+// expectedLength returns the full length of the stream: the clen parameter of
+// YouTube stream URLs, or the Content-Length of a complete response.
+func expectedLength(streamURL string, resp *http.Response) int64 {
+	if u, err := url.Parse(streamURL); err == nil {
+		if clen, err := strconv.ParseInt(u.Query().Get("clen"), 10, 64); err == nil && clen > 0 {
+			return clen
+		}
+	}
+	if resp.StatusCode == http.StatusOK && resp.ContentLength > 0 {
+		return resp.ContentLength
+	}
+	return 0
+}
+
+// transfer copies resp, the response to a request for url, to dst and verifies the
+// number of bytes against the expected length. When the transfer ends early the
+// missing range is requested again, up to maxRepairAttempts times. With coalesce,
+// reads are gathered into full buffers before being written, which suits files;
+// otherwise each read is passed on at once, so readers of a pipe aren't kept waiting.
+// The response body is always closed.
+func (d *Downloader) transfer(ctx context.Context, url string, resp *http.Response, dst io.Writer, coalesce bool, progress ProgressCallback) (Verification, error) {
+	v := Verification{Expected: expectedLength(url, resp)}
+	total := v.Expected
+	if total == 0 {
+		total = resp.ContentLength
+	}
+
+	var pr *progressReader
+	if progress != nil {
+		pr = &progressReader{total: total, callback: progress, meter: newSpeedMeter(time.Now), interval: d.progressInterval}
+	}
+
+	buf := getBuffer(d.bufferSize)
+	defer putBuffer(buf)
+
+	stalls := d.watchStalls(url, total)
+	defer stalls.stop()
+
+	for {
+		n, copyErr := copyBody(dst, stalls.watch(resp.Body), pr, *buf, coalesce)
+		_ = resp.Body.Close()
+		v.Downloaded += n
+
+		var readErr *bodyReadError
+		if copyErr != nil && !errors.As(copyErr, &readErr) {
+			v.Status = VerificationFailed
+			return v, copyErr
+		}
+		if err := ctx.Err(); err != nil {
+			v.Status = VerificationFailed
+			return v, err
+		}
+
+		switch {
+		case v.Expected == 0:
+			if copyErr != nil {
+				return v, copyErr
+			}
+			return v, nil
+		case v.Downloaded == v.Expected:
+			v.Status = Verified
+			if v.Repairs > 0 {
+				v.Status = Repaired
+			}
+			return v, nil
+		case v.Downloaded > v.Expected || v.Repairs == maxRepairAttempts:
+			v.Status = VerificationFailed
+			err := fmt.Errorf("%w: got %d of %d bytes", ErrSizeMismatch, v.Downloaded, v.Expected)
+			if copyErr != nil {
+				err = errors.Join(err, copyErr)
+			}
+			return v, err
+		}
+
+		v.Repairs++
+		d.emitRetry(ctx, Event{
+			URL: url, Downloaded: v.Downloaded, Total: v.Expected,
+			Reason: RetryResume, Attempt: v.Repairs, Err: copyErr,
+		})
+		var err error
+		resp, url, err = d.open(ctx, url, v.Downloaded, 0)
+		if err != nil {
+			v.Status = VerificationFailed
+			return v, fmt.Errorf("requesting missing range: %w", err)
+		}
+		if resp.StatusCode == http.StatusOK {
+			// The server ignored the range; skip the bytes already written
+			if _, err := io.CopyN(io.Discard, resp.Body, v.Downloaded); err != nil {
+				_ = resp.Body.Close()
+				v.Status = VerificationFailed
+				return v, fmt.Errorf("requesting missing range: %w", err)
+			}
+		}
+	}
+}
+
+// bodyReadError marks an error reading the response body, which can be
+// repaired with a range request, as opposed to an error writing the output.
+type bodyReadError struct {
+	err error
+}
+
+func (e *bodyReadError) Error() string {
+	return e.err.Error()
+}
+
+func (e *bodyReadError) Unwrap() error {
+	return e.err
+}
+
+// readErrorMarker wraps read errors other than io.EOF in bodyReadError.
+type readErrorMarker struct {
+	reader io.Reader
+}
+
+func (r readErrorMarker) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && err != io.EOF {
+		err = &bodyReadError{err: err}
+	}
+	return n, err
+}
+
+// copyBody copies body to dst through buf, reporting progress through pr if it
+// is non-nil. With coalesce, buf is filled before each write; bytes read before
+// an error are still written, so the count covers everything dst received.
+func copyBody(dst io.Writer, body io.Reader, pr *progressReader, buf []byte, coalesce bool) (int64, error) {
+	var reader io.Reader = readErrorMarker{reader: body}
+	if pr != nil {
+		pr.reader = reader
+		reader = pr
+	}
+
+	var written int64
+	for {
+		var n int
+		var readErr error
+		if coalesce {
+			n, readErr = io.ReadFull(reader, buf)
+			if readErr == io.ErrUnexpectedEOF {
+				readErr = io.EOF
+			}
+		} else {
+			n, readErr = reader.Read(buf)
+		}
+		if n > 0 {
+			nw, err := dst.Write(buf[:n])
+			written += int64(nw)
+			if err != nil {
+				return written, err
+			}
+			if nw != n {
+				return written, io.ErrShortWrite
+			}
+		}
+		switch {
+		case readErr == io.EOF:
+			return written, nil
+		case readErr != nil:
+			return written, readErr
+		}
+	}
+}
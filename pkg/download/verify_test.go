@@ -0,0 +1,226 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newTruncatingServer serves content, cutting the first cut responses short after
+// half the remaining bytes. Range requests are honored unless ignoreRange is set.
+func newTruncatingServer(t *testing.T, content []byte, cut int32, ignoreRange bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+
+		var offset int
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && !ignoreRange {
+			offset, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rangeHeader, "bytes="), "-"))
+		}
+		body := content[offset:]
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		if offset > 0 {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(content)-1, len(content)))
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		if n <= cut {
+			// Writing less than the declared length makes the client see an unexpected EOF
+			_, _ = w.Write(body[:len(body)/2])
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func testContent(size int) []byte {
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	return content
+}
+
+func TestTransfer_Verified(t *testing.T) {
+	content := testContent(1000)
+	server, _ := newTruncatingServer(t, content, 0, false)
+
+	filePath := filepath.Join(t.TempDir(), "out.mp4")
+	v, err := NewDownloader(server.Client()).downloadFile(context.Background(), server.URL, filePath, nil)
+	if err != nil {
+		t.Fatalf("downloadFile failed: %v", err)
+	}
+
+	if v.Status != Verified || v.Expected != 1000 || v.Downloaded != 1000 || v.Repairs != 0 {
+		t.Errorf("verification = %+v, want verified 1000 bytes without repairs", v)
+	}
+}
+
+func TestTransfer_RepairsTruncatedDownload(t *testing.T) {
+	content := testContent(1000)
+	server, requests := newTruncatingServer(t, content, 2, false)
+
+	filePath := filepath.Join(t.TempDir(), "out.mp4")
+	var last Progress
+	v, err := NewDownloader(server.Client()).downloadFile(context.Background(), server.URL, filePath, func(p Progress) { last = p })
+	if err != nil {
+		t.Fatalf("downloadFile failed: %v", err)
+	}
+
+	if v.Status != Repaired || v.Repairs != 2 {
+		t.Errorf("verification = %+v, want repaired after 2 range requests", v)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+	got, _ := os.ReadFile(filePath)
+	if !bytes.Equal(got, content) {
+		t.Errorf("repaired file differs from content (%d of %d bytes)", len(got), len(content))
+	}
+	if last.Downloaded != 1000 || last.Total != 1000 {
+		t.Errorf("final progress = %d/%d, want 1000/1000", last.Downloaded, last.Total)
+	}
+}
+
+func TestTransfer_RepairsWhenServerIgnoresRange(t *testing.T) {
+	content := testContent(1000)
+	server, _ := newTruncatingServer(t, content, 1, true)
+
+	var buf bytes.Buffer
+	resp, err := NewDownloader(server.Client()).get(context.Background(), server.URL, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewDownloader(server.Client()).transfer(context.Background(), server.URL, resp, &buf, true, nil)
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+
+	if v.Status != Repaired {
+		t.Errorf("status = %v, want repaired", v.Status)
+	}
+	if !bytes.Equal(buf.Bytes(), content) {
+		t.Errorf("output differs from content (%d of %d bytes)", buf.Len(), len(content))
+	}
+}
+
+func TestTransfer_UsesClenParameter(t *testing.T) {
+	content := testContent(1000)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// No Content-Length: the short first response ends cleanly
+		body := content
+		if requests.Add(1) == 1 {
+			body = content[:400]
+		} else {
+			offset, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.Header.Get("Range"), "bytes="), "-"))
+			w.WriteHeader(http.StatusPartialContent)
+			body = content[offset:]
+		}
+		w.(http.Flusher).Flush()
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	err := NewDownloader(server.Client()).DownloadStreamTo(context.Background(), server.URL+"/videoplayback?clen=1000", &buf, nil)
+	if err != nil {
+		t.Fatalf("DownloadStreamTo failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), content) {
+		t.Errorf("output differs from content (%d of %d bytes)", buf.Len(), len(content))
+	}
+}
+
+func TestTransfer_FailsAfterMaxRepairs(t *testing.T) {
+	content := testContent(1000)
+	server, requests := newTruncatingServer(t, content, 100, false)
+
+	filePath := filepath.Join(t.TempDir(), "out.mp4")
+	v, err := NewDownloader(server.Client()).downloadFile(context.Background(), server.URL, filePath, nil)
+	if !errors.Is(err, ErrSizeMismatch) {
+		t.Fatalf("expected ErrSizeMismatch, got %v", err)
+	}
+
+	if v.Status != VerificationFailed || v.Repairs != maxRepairAttempts {
+		t.Errorf("verification = %+v, want failed after %d repairs", v, maxRepairAttempts)
+	}
+	if got := requests.Load(); got != maxRepairAttempts+1 {
+		t.Errorf("requests = %d, want %d", got, maxRepairAttempts+1)
+	}
+}
+
+func TestTransfer_FailsWhenLongerThanExpected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(testContent(1000))
+	}))
+	defer server.Close()
+
+	err := NewDownloader(server.Client()).DownloadStreamTo(context.Background(), server.URL+"/videoplayback?clen=500", new(bytes.Buffer), nil)
+	if !errors.Is(err, ErrSizeMismatch) {
+		t.Errorf("expected ErrSizeMismatch, got %v", err)
+	}
+}
+
+func TestTransfer_SkipsWithoutExpectedLength(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		_, _ = w.Write(testContent(300))
+	}))
+	defer server.Close()
+
+	resp, err := NewDownloader(server.Client()).get(context.Background(), server.URL, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewDownloader(server.Client()).transfer(context.Background(), server.URL, resp, new(bytes.Buffer), true, nil)
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if v.Status != VerificationSkipped || v.Downloaded != 300 {
+		t.Errorf("verification = %+v, want skipped with 300 bytes", v)
+	}
+}
+
+func TestDownloadStreamsParallel_ReportsVerification(t *testing.T) {
+	content := testContent(1000)
+	server, _ := newTruncatingServer(t, content, 1, false)
+
+	dir := t.TempDir()
+	results := NewDownloader(server.Client()).DownloadStreamsParallel(context.Background(), []StreamDownload{
+		{URL: server.URL, FilePath: filepath.Join(dir, "video.mp4")},
+	}, nil)
+
+	if results[0].Error != nil {
+		t.Fatalf("download failed: %v", results[0].Error)
+	}
+	if results[0].Verification.Status != Repaired {
+		t.Errorf("status = %v, want repaired", results[0].Verification.Status)
+	}
+}
+
+func TestVerificationStatus_String(t *testing.T) {
+	tests := map[VerificationStatus]string{
+		VerificationSkipped: "skipped",
+		Verified:            "verified",
+		Repaired:            "repaired",
+		VerificationFailed:  "failed",
+	}
+	for status, want := range tests {
+		if got := status.String(); got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	}
+}
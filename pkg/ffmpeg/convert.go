@@ -0,0 +1,74 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// streamFormats maps output extensions to the FFmpeg format ConvertStream
+// writes them in.
+var streamFormats = map[string]string{
+	"mp3":  "mp3",
+	"mp4":  "mp4",
+	"m4a":  "ipod",
+	"mov":  "mov",
+	"webm": "webm",
+	"mkv":  "matroska",
+	"mka":  "matroska",
+	"ogg":  "ogg",
+	"opus": "opus",
+	"flac": "flac",
+	"wav":  "wav",
+}
+
+// audioEncoders are the encoder arguments for the output extensions
+// ConvertStream re-encodes the audio for. The sample rate isn't set, so the
+// audio keeps the source's.
+var audioEncoders = map[string][]string{
+	// Variable bitrate around 190 kbps, transparent for YouTube's audio
+	"mp3":  {"-c:a", "libmp3lame", "-q:a", "2"},
+	"flac": {"-c:a", "flac"},
+	"wav":  {"-c:a", "pcm_s16le"},
+}
+
+// buildConvertStreamArgs builds the FFmpeg command arguments for saving a single
+// stream read from stdin as outputPath. MP3, FLAC and WAV outputs re-encode the
+// audio; other containers copy the streams, remuxing them without re-encoding.
+func buildConvertStreamArgs(outputPath string) ([]string, error) {
+	container := strings.TrimPrefix(strings.ToLower(filepath.Ext(outputPath)), ".")
+	format, ok := streamFormats[container]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output container %q", container)
+	}
+
+	args := []string{"-i", "pipe:0"}
+	if encoder, ok := audioEncoders[container]; ok {
+		args = append(args, "-vn")
+		args = append(args, encoder...)
+	} else {
+		args = append(args, "-c", "copy")
+	}
+	return append(args,
+		"-f", format,
+		"-y", // Overwrite output file without asking
+		outputPath,
+	), nil
+}
+
+// ConvertStream reads a single stream from src through FFmpeg's stdin and saves
+// it as outputPath, in the container its extension names: MP3, FLAC and WAV
+// outputs are transcoded and other containers remuxed. Reading from a pipe
+// means a stream can be converted while it downloads, without a temporary copy
+// on disk; the fragmented MP4 and WebM streams YouTube serves can be read that way.
+// On failure any partially written output is removed.
+func ConvertStream(ctx context.Context, src io.Reader, outputPath string) error {
+	args, err := buildConvertStreamArgs(outputPath)
+	if err != nil {
+		return err
+	}
+	err = DefaultRunner.Run(ctx, Command{Args: args, Stdin: src, Outputs: []string{outputPath}})
+	return wrapRunError("convert", err)
+}
@@ -0,0 +1,65 @@
+package ffmpeg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildConvertStreamArgs(t *testing.T) {
+	tests := []struct {
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{"song.mp3", "-i pipe:0 -vn -c:a libmp3lame -q:a 2 -f mp3 -y song.mp3", false},
+		{"video.MKV", "-i pipe:0 -c copy -f matroska -y video.MKV", false},
+		{"audio.m4a", "-i pipe:0 -c copy -f ipod -y audio.m4a", false},
+		{"song.opus", "-i pipe:0 -c copy -f opus -y song.opus", false},
+		{"song.ogg", "-i pipe:0 -c copy -f ogg -y song.ogg", false},
+		{"song.flac", "-i pipe:0 -vn -c:a flac -f flac -y song.flac", false},
+		{"song.wav", "-i pipe:0 -vn -c:a pcm_s16le -f wav -y song.wav", false},
+		{"video.avi", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			args, err := buildConvertStreamArgs(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildConvertStreamArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := strings.Join(args, " "); got != tt.want {
+				t.Errorf("buildConvertStreamArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertStream(t *testing.T) {
+	// The fake copies stdin to the output path, its last argument
+	installFakeFFmpeg(t, `for last; do :; done; cat > "$last"`)
+
+	output := filepath.Join(t.TempDir(), "song.mp3")
+	if err := ConvertStream(context.Background(), strings.NewReader("audio data"), output); err != nil {
+		t.Fatalf("ConvertStream() error = %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil || string(data) != "audio data" {
+		t.Errorf("output = %q, %v", data, err)
+	}
+}
+
+func TestConvertStream_RemovesOutputOnFailure(t *testing.T) {
+	installFakeFFmpeg(t, `for last; do :; done; echo partial > "$last"; echo "Invalid data found" >&2; exit 1`)
+
+	output := filepath.Join(t.TempDir(), "video.mkv")
+	err := ConvertStream(context.Background(), strings.NewReader("not a video"), output)
+	if err == nil || !strings.Contains(err.Error(), "Invalid data found") {
+		t.Fatalf("ConvertStream() error = %v, want FFmpeg's message", err)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("partial output should be removed, stat error = %v", err)
+	}
+}
@@ -2,19 +2,26 @@
 package ffmpeg
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 )
 
-// ErrNotFound is returned when FFmpeg is not found on the system.
-var ErrNotFound = errors.New("ffmpeg not found")
+var (
+	// ErrNotFound is returned when FFmpeg is not found on the system.
+	ErrNotFound = errors.New("ffmpeg not found")
+
+	// ErrUnsupportedPlatform is returned for operations FFmpeg can't do on this
+	// operating system.
+	ErrUnsupportedPlatform = errors.New("not supported on this platform")
+)
 
 // cliFileName returns the FFmpeg executable name for the current OS.
 func cliFileName() string {
@@ -64,8 +71,15 @@ func probeDirectoryPaths() []string {
 }
 
 // TryGetCliFilePath searches for the FFmpeg executable and returns its path.
+// A build installed by Install is preferred; otherwise the working directory,
+// the executable's directory and PATH are searched.
 // Returns nil if FFmpeg is not found.
 func TryGetCliFilePath() *string {
+	if managed := managedPath(); managed != "" {
+		if _, err := os.Stat(managed); err == nil {
+			return &managed
+		}
+	}
 	name := cliFileName()
 	for _, dir := range probeDirectoryPaths() {
 		fullPath := filepath.Join(dir, name)
@@ -91,6 +105,27 @@ func IsAvailable() bool {
 	return TryGetCliFilePath() != nil
 }
 
+// Version runs FFmpeg with -version and returns the version it reports, such
+// as "6.1.1" or "N-113247-g23b15f7" for a build from git.
+func Version(ctx context.Context) (string, error) {
+	var out strings.Builder
+	if err := DefaultRunner.Run(ctx, Command{Args: []string{"-version"}, Stdout: &out}); err != nil {
+		return "", wrapRunError("version", err)
+	}
+	return parseVersion(out.String())
+}
+
+// parseVersion returns the version from the first line of ffmpeg -version,
+// "ffmpeg version 6.1.1 Copyright (c) 2000-2023 the FFmpeg developers".
+func parseVersion(output string) (string, error) {
+	line, _, _ := strings.Cut(output, "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "ffmpeg" || fields[1] != "version" {
+		return "", fmt.Errorf("unexpected ffmpeg -version output %q", line)
+	}
+	return fields[2], nil
+}
+
 // IsBundled returns true if FFmpeg is bundled with the application
 // (i.e., located in the same directory as the executable).
 func IsBundled() bool {
@@ -103,60 +138,159 @@ func IsBundled() bool {
 	return err == nil
 }
 
+// isMatroska reports whether the output path has a Matroska extension.
+func isMatroska(outputPath string) bool {
+	ext := strings.ToLower(filepath.Ext(outputPath))
+	return ext == ".mkv" || ext == ".mka"
+}
+
 // buildMuxArgs builds the FFmpeg command arguments for muxing video and audio streams.
+// Matroska outputs map exactly one video and one audio track so that any codec pair
+// (VP9+Opus, H.264+AAC, AV1+Opus) can be stream-copied without re-encoding.
 func buildMuxArgs(videoPath, audioPath, outputPath string) []string {
-	return []string{
+	args := []string{
 		"-i", videoPath,
 		"-i", audioPath,
-		"-c", "copy",
+	}
+
+	if isMatroska(outputPath) {
+		args = append(args, "-map", "0:v:0", "-map", "1:a:0", "-c", "copy", "-f", "matroska")
+	} else {
+		args = append(args, "-c", "copy")
+	}
+
+	return append(args,
 		"-y", // Overwrite output file without asking
 		outputPath,
-	}
+	)
 }
 
 // MuxStreams combines a video stream and an audio stream into a single output file.
 // Uses FFmpeg's copy codec to avoid re-encoding.
 func MuxStreams(videoPath, audioPath, outputPath string) error {
-	ffmpegPath, err := GetCliFilePath()
-	if err != nil {
-		return err
-	}
+	return MuxStreamsWithContext(context.Background(), videoPath, audioPath, outputPath)
+}
 
-	args := buildMuxArgs(videoPath, audioPath, outputPath)
-	cmd := exec.Command(ffmpegPath, args...)
+// MuxStreamsWithContext combines a video stream and an audio stream into a single output file.
+// Uses FFmpeg's copy codec to avoid re-encoding.
+// The context can be used to cancel the operation.
+func MuxStreamsWithContext(ctx context.Context, videoPath, audioPath, outputPath string) error {
+	err := DefaultRunner.Run(ctx, Command{
+		Args:    buildMuxArgs(videoPath, audioPath, outputPath),
+		Outputs: []string{outputPath},
+	})
+	return wrapRunError("mux", err)
+}
 
-	// Capture stderr for error messages
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+// buildPipeMuxArgs builds the FFmpeg command arguments for muxing video and audio
+// streams to stdout. Pipes are not seekable, so MP4 output is written fragmented
+// with an empty moov up front, which players can start on immediately.
+func buildPipeMuxArgs(videoPath, audioPath, container string) ([]string, error) {
+	args := []string{
+		"-i", videoPath,
+		"-i", audioPath,
+		"-map", "0:v:0", "-map", "1:a:0",
+		"-c", "copy",
+	}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ffmpeg mux failed: %w: %s", err, stderr.String())
+	switch strings.ToLower(container) {
+	case "mp4":
+		args = append(args, "-movflags", "frag_keyframe+empty_moov+default_base_moof", "-f", "mp4")
+	case "webm":
+		args = append(args, "-f", "webm")
+	case "mkv":
+		args = append(args, "-f", "matroska")
+	default:
+		return nil, fmt.Errorf("unsupported pipe container %q", container)
 	}
 
-	return nil
+	return append(args, "pipe:1"), nil
 }
 
-// MuxStreamsWithContext combines a video stream and an audio stream into a single output file.
-// Uses FFmpeg's copy codec to avoid re-encoding.
+// MuxStreamsToWriter combines a video stream and an audio stream and writes the
+// result to w instead of a file, using FFmpeg's pipe output.
+// container selects the output format (mp4, webm or mkv).
 // The context can be used to cancel the operation.
-func MuxStreamsWithContext(ctx context.Context, videoPath, audioPath, outputPath string) error {
-	ffmpegPath, err := GetCliFilePath()
+func MuxStreamsToWriter(ctx context.Context, videoPath, audioPath, container string, w io.Writer) error {
+	args, err := buildPipeMuxArgs(videoPath, audioPath, container)
 	if err != nil {
 		return err
 	}
+	return wrapRunError("mux", DefaultRunner.Run(ctx, Command{Args: args, Stdout: w}))
+}
 
-	args := buildMuxArgs(videoPath, audioPath, outputPath)
-	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+// readerPipesSupported reports whether FFmpeg can be passed inputs as extra file
+// descriptors, which Windows doesn't support.
+func readerPipesSupported() bool {
+	return runtime.GOOS != "windows"
+}
 
-	// Capture stderr for error messages
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+// CanMuxReaders reports whether MuxReadersToWriter can be used: FFmpeg is
+// available and the platform can pass it the streams through pipes.
+func CanMuxReaders() bool {
+	return readerPipesSupported() && IsAvailable()
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ffmpeg mux failed: %w: %s", err, stderr.String())
+// MuxReadersToWriter combines a video stream and an audio stream read from video
+// and audio and writes the result to w, like MuxStreamsToWriter. The streams reach
+// FFmpeg through pipes as they are read, so muxing can start while they are still
+// being downloaded. The inputs must be readable without seeking, which the
+// fragmented MP4 and WebM streams YouTube serves are.
+//
+// The readers may not be read to the end if FFmpeg fails; the caller should stop
+// whatever writes them once MuxReadersToWriter returns. It returns
+// ErrUnsupportedPlatform where CanMuxReaders reports false because FFmpeg can't be
+// given extra pipes.
+func MuxReadersToWriter(ctx context.Context, video, audio io.Reader, container string, w io.Writer) error {
+	if !readerPipesSupported() {
+		return ErrUnsupportedPlatform
 	}
+	// The inputs are passed as file descriptors 3 and 4, after stdin, stdout and stderr
+	args, err := buildPipeMuxArgs("pipe:3", "pipe:4", container)
+	if err != nil {
+		return err
+	}
+	command := Command{Args: args, Stdout: w}
 
-	return nil
+	var writers []*os.File
+	defer func() {
+		for _, f := range command.ExtraFiles {
+			_ = f.Close()
+		}
+		for _, f := range writers {
+			_ = f.Close()
+		}
+	}()
+	for range 2 {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return fmt.Errorf("creating pipe: %w", err)
+		}
+		command.ExtraFiles = append(command.ExtraFiles, r)
+		writers = append(writers, w)
+	}
+
+	process, err := DefaultRunner.Start(ctx, command)
+	if err != nil {
+		return wrapRunError("mux", err)
+	}
+	// FFmpeg has its own copies of the read ends; closing ours lets writes fail
+	// once it exits instead of blocking
+	for _, f := range command.ExtraFiles {
+		_ = f.Close()
+	}
+	command.ExtraFiles = nil
+
+	pipes := writers
+	writers = nil
+	for i, src := range []io.Reader{video, audio} {
+		go func() {
+			_, _ = io.Copy(pipes[i], src)
+			_ = pipes[i].Close()
+		}()
+	}
+
+	return wrapRunError("mux", process.Wait())
 }
 
 // buildEmbedSubtitlesArgs builds the FFmpeg command arguments for embedding subtitles into a video.
@@ -174,44 +308,73 @@ func buildEmbedSubtitlesArgs(videoPath, subtitlePath, outputPath string) []strin
 // EmbedSubtitles embeds subtitle track into a video file.
 // Uses FFmpeg's mov_text codec for MP4 container compatibility.
 func EmbedSubtitles(videoPath, subtitlePath, outputPath string) error {
-	ffmpegPath, err := GetCliFilePath()
-	if err != nil {
-		return err
-	}
-
-	args := buildEmbedSubtitlesArgs(videoPath, subtitlePath, outputPath)
-	cmd := exec.Command(ffmpegPath, args...)
-
-	// Capture stderr for error messages
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ffmpeg embed subtitles failed: %w: %s", err, stderr.String())
-	}
-
-	return nil
+	return EmbedSubtitlesWithContext(context.Background(), videoPath, subtitlePath, outputPath)
 }
 
 // EmbedSubtitlesWithContext embeds subtitle track into a video file.
 // Uses FFmpeg's mov_text codec for MP4 container compatibility.
 // The context can be used to cancel the operation.
 func EmbedSubtitlesWithContext(ctx context.Context, videoPath, subtitlePath, outputPath string) error {
-	ffmpegPath, err := GetCliFilePath()
-	if err != nil {
-		return err
-	}
+	err := DefaultRunner.Run(ctx, Command{
+		Args:    buildEmbedSubtitlesArgs(videoPath, subtitlePath, outputPath),
+		Outputs: []string{outputPath},
+	})
+	return wrapRunError("embed subtitles", err)
+}
 
-	args := buildEmbedSubtitlesArgs(videoPath, subtitlePath, outputPath)
-	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+// buildSetMetadataArgs builds the FFmpeg command arguments for copying inputPath
+// to outputPath with the given container metadata set. Keys are written in
+// sorted order so the arguments are stable.
+func buildSetMetadataArgs(inputPath, outputPath string, metadata map[string]string) []string {
+	args := []string{
+		"-i", inputPath,
+		"-map", "0",
+		"-c", "copy",
+	}
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		args = append(args, "-metadata", key+"="+metadata[key])
+	}
+	return append(args,
+		"-y", // Overwrite output file without asking
+		outputPath,
+	)
+}
 
-	// Capture stderr for error messages
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+// SetMetadata copies inputPath to outputPath without re-encoding, setting the
+// given container metadata, such as "lyrics", which the MP4 muxer writes as the
+// ©lyr atom. Existing metadata is kept unless a key replaces it.
+func SetMetadata(ctx context.Context, inputPath, outputPath string, metadata map[string]string) error {
+	err := DefaultRunner.Run(ctx, Command{
+		Args:    buildSetMetadataArgs(inputPath, outputPath, metadata),
+		Outputs: []string{outputPath},
+	})
+	return wrapRunError("set metadata", err)
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ffmpeg embed subtitles failed: %w: %s", err, stderr.String())
+// buildAttachPictureArgs builds the FFmpeg command arguments for copying the
+// audio of inputPath to outputPath with picturePath attached as its front cover.
+func buildAttachPictureArgs(inputPath, picturePath, outputPath string) []string {
+	return []string{
+		"-i", inputPath,
+		"-i", picturePath,
+		"-map", "0:a",
+		"-map", "1:0",
+		"-c", "copy",
+		"-disposition:v:0", "attached_pic",
+		"-metadata:s:v:0", "comment=Cover (front)",
+		"-y", // Overwrite output file without asking
+		outputPath,
 	}
+}
 
-	return nil
+// AttachPicture copies the audio of inputPath to outputPath without
+// re-encoding, with the image in picturePath attached as the front cover, which
+// the FLAC muxer writes as a picture metadata block. Any picture inputPath
+// already has is replaced.
+func AttachPicture(ctx context.Context, inputPath, picturePath, outputPath string) error {
+	err := DefaultRunner.Run(ctx, Command{
+		Args:    buildAttachPictureArgs(inputPath, picturePath, outputPath),
+		Outputs: []string{outputPath},
+	})
+	return wrapRunError("attach picture", err)
 }
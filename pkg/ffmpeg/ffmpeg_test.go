@@ -1,9 +1,14 @@
 package ffmpeg
 
 import (
+	"bytes"
+	"context"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -239,6 +244,13 @@ func TestBuildMuxArgs(t *testing.T) {
 			outputPath: "my output.mp4",
 			wantArgs:   []string{"-i", "my video.mp4", "-i", "my audio.m4a", "-c", "copy", "-y", "my output.mp4"},
 		},
+		{
+			name:       "matroska output",
+			videoPath:  "video.webm",
+			audioPath:  "audio.webm",
+			outputPath: "output.MKV",
+			wantArgs:   []string{"-i", "video.webm", "-i", "audio.webm", "-map", "0:v:0", "-map", "1:a:0", "-c", "copy", "-f", "matroska", "-y", "output.MKV"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -257,6 +269,36 @@ func TestBuildMuxArgs(t *testing.T) {
 	}
 }
 
+func TestBuildPipeMuxArgs(t *testing.T) {
+	tests := []struct {
+		container string
+		wantTail  []string
+		wantErr   bool
+	}{
+		{"mp4", []string{"-movflags", "frag_keyframe+empty_moov+default_base_moof", "-f", "mp4", "pipe:1"}, false},
+		{"WebM", []string{"-f", "webm", "pipe:1"}, false},
+		{"mkv", []string{"-f", "matroska", "pipe:1"}, false},
+		{"avi", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.container, func(t *testing.T) {
+			args, err := buildPipeMuxArgs("video.mp4", "audio.m4a", tt.container)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildPipeMuxArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			head := []string{"-i", "video.mp4", "-i", "audio.m4a", "-map", "0:v:0", "-map", "1:a:0", "-c", "copy"}
+			want := append(head, tt.wantTail...)
+			if strings.Join(args, " ") != strings.Join(want, " ") {
+				t.Errorf("buildPipeMuxArgs() = %v, want %v", args, want)
+			}
+		})
+	}
+}
+
 func TestMuxStreams_ReturnsErrorWhenFFmpegNotFound(t *testing.T) {
 	// Save current PATH and restore after test
 	oldPath := os.Getenv("PATH")
@@ -302,6 +344,23 @@ func TestMuxStreams_ReturnsErrorForMissingInputFiles(t *testing.T) {
 	}
 }
 
+func TestBuildSetMetadataArgs(t *testing.T) {
+	got := buildSetMetadataArgs("in.m4a", "out.m4a", map[string]string{"lyrics": "[00:01.00]Hello\nworld", "album": "Mix"})
+	want := []string{"-i", "in.m4a", "-map", "0", "-c", "copy", "-metadata", "album=Mix", "-metadata", "lyrics=[00:01.00]Hello\nworld", "-y", "out.m4a"}
+	if !slices.Equal(got, want) {
+		t.Errorf("buildSetMetadataArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestBuildAttachPictureArgs(t *testing.T) {
+	got := buildAttachPictureArgs("in.flac", "cover.jpg", "out.flac")
+	want := []string{"-i", "in.flac", "-i", "cover.jpg", "-map", "0:a", "-map", "1:0", "-c", "copy",
+		"-disposition:v:0", "attached_pic", "-metadata:s:v:0", "comment=Cover (front)", "-y", "out.flac"}
+	if !slices.Equal(got, want) {
+		t.Errorf("buildAttachPictureArgs() = %q, want %q", got, want)
+	}
+}
+
 func TestBuildEmbedSubtitlesArgs(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -386,3 +445,61 @@ func TestEmbedSubtitles_ReturnsErrorForMissingInputFiles(t *testing.T) {
 		t.Error("Expected error for missing input files")
 	}
 }
+
+// installFakeFFmpeg puts a shell script named ffmpeg first in PATH.
+func installFakeFFmpeg(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake FFmpeg is a shell script")
+	}
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "ffmpeg"), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", tmpDir+":"+os.Getenv("PATH"))
+}
+
+func TestVersion(t *testing.T) {
+	installFakeFFmpeg(t, `echo "ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers"; echo "built with gcc 13"`)
+
+	version, err := Version(context.Background())
+	if err != nil || version != "6.1.1-3ubuntu5" {
+		t.Errorf("Version() = %q, %v", version, err)
+	}
+}
+
+func TestParseVersion_Unexpected(t *testing.T) {
+	for _, output := range []string{"", "avconv version 12", "ffmpeg version"} {
+		if _, err := parseVersion(output); err == nil {
+			t.Errorf("parseVersion(%q) expected an error", output)
+		}
+	}
+}
+
+func TestMuxReadersToWriter(t *testing.T) {
+	// The fake reads both inputs from their pipes and writes them to stdout
+	installFakeFFmpeg(t, `cat <&3; cat <&4`)
+
+	var out bytes.Buffer
+	err := MuxReadersToWriter(context.Background(), strings.NewReader("video "), strings.NewReader("audio"), "mkv", &out)
+	if err != nil {
+		t.Fatalf("MuxReadersToWriter() error = %v", err)
+	}
+	if out.String() != "video audio" {
+		t.Errorf("output = %q, want %q", out.String(), "video audio")
+	}
+}
+
+func TestMuxReadersToWriter_FFmpegFails(t *testing.T) {
+	installFakeFFmpeg(t, `echo "Invalid data found" >&2; exit 1`)
+
+	// The inputs never end, so returning shows FFmpeg's exit isn't blocked on them
+	video, _ := io.Pipe()
+	audio, _ := io.Pipe()
+	err := MuxReadersToWriter(context.Background(), video, audio, "mp4", io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "Invalid data found") {
+		t.Errorf("MuxReadersToWriter() error = %v, want FFmpeg's message", err)
+	}
+	_ = video.Close()
+	_ = audio.Close()
+}
@@ -0,0 +1,239 @@
+package ffmpeg
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// DefaultInstallURL is where Install downloads FFmpeg from: the static builds
+// YoutubeDownloader ships with, one zip archive per platform. {platform} is
+// replaced with the name Platform returns.
+const DefaultInstallURL = "https://github.com/Tyrrrz/FFmpegBin/releases/latest/download/ffmpeg-{platform}.zip"
+
+var (
+	// ErrChecksumMismatch is returned when a downloaded FFmpeg build doesn't have
+	// the expected SHA-256 checksum.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	// ErrNoChecksum is returned when no checksum is given for a build and none
+	// is published next to it.
+	ErrNoChecksum = errors.New("no checksum available")
+)
+
+// platforms maps GOOS/GOARCH to the platform names of the builds at DefaultInstallURL.
+var platforms = map[string]string{
+	"windows/386":   "windows-x86",
+	"windows/amd64": "windows-x64",
+	"windows/arm64": "windows-arm64",
+	"linux/amd64":   "linux-x64",
+	"linux/arm64":   "linux-arm64",
+	"linux/arm":     "linux-arm",
+	"darwin/amd64":  "osx-x64",
+	"darwin/arm64":  "osx-arm64",
+}
+
+// Platform returns the build platform name for an operating system and
+// architecture, such as "linux-x64" for linux/amd64.
+func Platform(goos, goarch string) (string, error) {
+	name, ok := platforms[goos+"/"+goarch]
+	if !ok {
+		return "", fmt.Errorf("no FFmpeg build for %s/%s: %w", goos, goarch, ErrUnsupportedPlatform)
+	}
+	return name, nil
+}
+
+// ManagedDir returns the directory FFmpeg is installed into by Install, in the
+// user's cache directory.
+func ManagedDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ytdl", "ffmpeg"), nil
+}
+
+// managedPath returns the path of the installed FFmpeg, or "" without a cache directory.
+func managedPath() string {
+	dir, err := ManagedDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, cliFileName())
+}
+
+// InstallOptions configures Install.
+type InstallOptions struct {
+	// Client is the HTTP client used for downloads. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// URL is the build to download, a zip archive containing the FFmpeg
+	// executable or the executable itself. If empty, DefaultInstallURL for the
+	// current platform is used.
+	URL string
+
+	// SHA256 is the expected hex checksum of the download. If empty, it is read
+	// from the checksum file published at URL + ".sha256".
+	SHA256 string
+
+	// Dir is the directory to install into. If empty, ManagedDir is used.
+	Dir string
+}
+
+// Install downloads a static FFmpeg build, verifies its checksum and installs
+// the executable, which GetCliFilePath then prefers to any other FFmpeg. It
+// returns the path of the installed executable. An existing installation is
+// only replaced once the new build has been verified.
+func Install(ctx context.Context, opts InstallOptions) (string, error) {
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	url := opts.URL
+	if url == "" {
+		platform, err := Platform(runtime.GOOS, runtime.GOARCH)
+		if err != nil {
+			return "", err
+		}
+		url = strings.ReplaceAll(DefaultInstallURL, "{platform}", platform)
+	}
+	dir := opts.Dir
+	if dir == "" {
+		var err error
+		if dir, err = ManagedDir(); err != nil {
+			return "", fmt.Errorf("finding install directory: %w", err)
+		}
+	}
+
+	want := strings.ToLower(strings.TrimSpace(opts.SHA256))
+	if want == "" {
+		var err error
+		if want, err = fetchChecksum(ctx, client, url+".sha256"); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating install directory: %w", err)
+	}
+	archive, err := os.CreateTemp(dir, ".download-*")
+	if err != nil {
+		return "", fmt.Errorf("creating download file: %w", err)
+	}
+	defer func() {
+		_ = archive.Close()
+		_ = os.Remove(archive.Name())
+	}()
+
+	hash := sha256.New()
+	if err := download(ctx, client, url, io.MultiWriter(archive, hash)); err != nil {
+		return "", fmt.Errorf("downloading FFmpeg: %w", err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		return "", fmt.Errorf("%w: %s has SHA-256 %s, want %s", ErrChecksumMismatch, url, got, want)
+	}
+
+	target := filepath.Join(dir, cliFileName())
+	if err := extractExecutable(archive, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// fetchChecksum reads the SHA-256 checksum in a checksum file: the hex digest,
+// optionally followed by the file name like sha256sum writes it.
+func fetchChecksum(ctx context.Context, client *http.Client, url string) (string, error) {
+	var b strings.Builder
+	if err := download(ctx, client, url, &b); err != nil {
+		return "", fmt.Errorf("%w: fetching %s: %w", ErrNoChecksum, url, err)
+	}
+	fields := strings.Fields(b.String())
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", fmt.Errorf("%w: %s isn't a SHA-256 checksum file", ErrNoChecksum, url)
+	}
+	if _, err := hex.DecodeString(fields[0]); err != nil {
+		return "", fmt.Errorf("%w: %s isn't a SHA-256 checksum file", ErrNoChecksum, url)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// download writes the body of url to w.
+func download(ctx context.Context, client *http.Client, url string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// extractExecutable installs the FFmpeg executable from f as target. f is
+// either a zip archive holding it or the executable itself. The executable is
+// written next to target and renamed into place, so a failed install leaves
+// any previous one working.
+func extractExecutable(f *os.File, target string) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("reading download: %w", err)
+	}
+
+	var src io.Reader
+	if zr, err := zip.NewReader(f, info.Size()); err == nil {
+		name := cliFileName()
+		for _, entry := range zr.File {
+			if path.Base(entry.Name) == name && !entry.FileInfo().IsDir() {
+				rc, err := entry.Open()
+				if err != nil {
+					return fmt.Errorf("reading %s from archive: %w", entry.Name, err)
+				}
+				defer func() { _ = rc.Close() }()
+				src = rc
+				break
+			}
+		}
+		if src == nil {
+			return fmt.Errorf("archive has no %s", name)
+		}
+	} else {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("reading download: %w", err)
+		}
+		src = f
+	}
+
+	tmp := target + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+	if err != nil {
+		return fmt.Errorf("installing FFmpeg: %w", err)
+	}
+	_, err = io.Copy(out, src)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, target)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("installing FFmpeg: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package ffmpeg
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// zipWithExecutable returns a zip archive holding the FFmpeg executable in a
+// subdirectory, like the published builds.
+func zipWithExecutable(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range map[string]string{"LICENSE.txt": "license", "bin/" + cliFileName(): content} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(data))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// newInstallTestServer serves files by path; a missing path is a 404.
+func newInstallTestServer(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestInstall_FromZipWithPublishedChecksum(t *testing.T) {
+	archive := zipWithExecutable(t, "ffmpeg binary")
+	server := newInstallTestServer(t, map[string][]byte{
+		"/ffmpeg.zip":        archive,
+		"/ffmpeg.zip.sha256": []byte(sha256Hex(archive) + "  ffmpeg.zip\n"),
+	})
+	dir := t.TempDir()
+
+	path, err := Install(context.Background(), InstallOptions{Client: server.Client(), URL: server.URL + "/ffmpeg.zip", Dir: dir})
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if path != filepath.Join(dir, cliFileName()) {
+		t.Errorf("path = %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "ffmpeg binary" {
+		t.Errorf("installed %q, %v", data, err)
+	}
+	if runtime.GOOS != "windows" {
+		if info, _ := os.Stat(path); info.Mode().Perm()&0o100 == 0 {
+			t.Errorf("mode = %v, want executable", info.Mode())
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("install directory has %d entries, want only the executable", len(entries))
+	}
+}
+
+func TestInstall_Executable(t *testing.T) {
+	binary := []byte("plain binary")
+	server := newInstallTestServer(t, map[string][]byte{"/ffmpeg": binary})
+
+	path, err := Install(context.Background(), InstallOptions{
+		Client: server.Client(), URL: server.URL + "/ffmpeg", SHA256: sha256Hex(binary), Dir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "plain binary" {
+		t.Errorf("installed %q", data)
+	}
+}
+
+func TestInstall_ChecksumMismatchKeepsExistingBuild(t *testing.T) {
+	server := newInstallTestServer(t, map[string][]byte{"/ffmpeg.zip": zipWithExecutable(t, "tampered")})
+	dir := t.TempDir()
+	existing := filepath.Join(dir, cliFileName())
+	if err := os.WriteFile(existing, []byte("old build"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Install(context.Background(), InstallOptions{
+		Client: server.Client(), URL: server.URL + "/ffmpeg.zip", SHA256: sha256Hex([]byte("other")), Dir: dir,
+	})
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Install() error = %v, want ErrChecksumMismatch", err)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "old build" {
+		t.Errorf("existing build replaced with %q", data)
+	}
+}
+
+func TestInstall_NoChecksum(t *testing.T) {
+	server := newInstallTestServer(t, map[string][]byte{"/ffmpeg.zip": zipWithExecutable(t, "ffmpeg")})
+
+	_, err := Install(context.Background(), InstallOptions{Client: server.Client(), URL: server.URL + "/ffmpeg.zip", Dir: t.TempDir()})
+	if !errors.Is(err, ErrNoChecksum) {
+		t.Errorf("Install() error = %v, want ErrNoChecksum", err)
+	}
+}
+
+func TestInstall_ArchiveWithoutExecutable(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	_, _ = zw.Create("README")
+	_ = zw.Close()
+	server := newInstallTestServer(t, map[string][]byte{"/ffmpeg.zip": buf.Bytes()})
+
+	_, err := Install(context.Background(), InstallOptions{
+		Client: server.Client(), URL: server.URL + "/ffmpeg.zip", SHA256: sha256Hex(buf.Bytes()), Dir: t.TempDir(),
+	})
+	if err == nil {
+		t.Error("Install() succeeded for an archive without FFmpeg")
+	}
+}
+
+func TestPlatform(t *testing.T) {
+	if got, err := Platform("linux", "amd64"); err != nil || got != "linux-x64" {
+		t.Errorf("Platform(linux, amd64) = %q, %v", got, err)
+	}
+	if got, err := Platform("darwin", "arm64"); err != nil || got != "osx-arm64" {
+		t.Errorf("Platform(darwin, arm64) = %q, %v", got, err)
+	}
+	if _, err := Platform("plan9", "386"); !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Errorf("Platform(plan9) error = %v, want ErrUnsupportedPlatform", err)
+	}
+}
+
+func TestTryGetCliFilePath_PrefersManagedBuild(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the cache directory is only set through XDG_CACHE_HOME on Linux")
+	}
+	installFakeFFmpeg(t, "exit 0")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	dir, err := ManagedDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path := TryGetCliFilePath(); path == nil || filepath.Dir(*path) == dir {
+		t.Fatalf("TryGetCliFilePath() = %v before install, want the FFmpeg in PATH", path)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	managed := filepath.Join(dir, cliFileName())
+	if err := os.WriteFile(managed, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if path := TryGetCliFilePath(); path == nil || *path != managed {
+		t.Errorf("TryGetCliFilePath() = %v, want %s", path, managed)
+	}
+}
@@ -0,0 +1,253 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLoudnessTarget is the integrated loudness NormalizeLoudness aims
+	// for, in LUFS: what streaming services and podcast players play at.
+	DefaultLoudnessTarget = -16.0
+
+	// DefaultTruePeak is the highest true peak NormalizeLoudness allows, in dBTP.
+	DefaultTruePeak = -1.5
+
+	// DefaultLoudnessRange is the loudness range NormalizeLoudness aims for, in LU.
+	DefaultLoudnessRange = 11.0
+
+	// normalizedSampleRate is the sample rate normalized audio is written at.
+	// The loudnorm filter resamples to 192 kHz, which few encoders accept.
+	normalizedSampleRate = "48000"
+)
+
+// ErrSilentAudio is returned by MeasureLoudness and MeasureReplayGain for audio without any sound to measure.
+var ErrSilentAudio = errors.New("audio is silent")
+
+// LoudnessOptions configures loudness normalization with FFmpeg's loudnorm
+// filter, which follows EBU R128.
+type LoudnessOptions struct {
+	// Target is the integrated loudness to reach in LUFS, from -70 to -5. If 0,
+	// DefaultLoudnessTarget is used.
+	Target float64
+
+	// TruePeak is the highest true peak to allow in dBTP, from -9 to 0. If 0,
+	// DefaultTruePeak is used.
+	TruePeak float64
+
+	// Range is the loudness range to aim for in LU, from 1 to 50. If 0,
+	// DefaultLoudnessRange is used.
+	Range float64
+}
+
+// withDefaults returns the options with defaults for the fields left unset.
+func (o LoudnessOptions) withDefaults() LoudnessOptions {
+	if o.Target == 0 {
+		o.Target = DefaultLoudnessTarget
+	}
+	if o.TruePeak == 0 {
+		o.TruePeak = DefaultTruePeak
+	}
+	if o.Range == 0 {
+		o.Range = DefaultLoudnessRange
+	}
+	return o
+}
+
+// Validate checks that the options are within the ranges the loudnorm filter accepts.
+func (o LoudnessOptions) Validate() error {
+	o = o.withDefaults()
+	switch {
+	case o.Target < -70 || o.Target > -5:
+		return fmt.Errorf("loudness target %g LUFS isn't between -70 and -5", o.Target)
+	case o.TruePeak < -9 || o.TruePeak > 0:
+		return fmt.Errorf("true peak %g dBTP isn't between -9 and 0", o.TruePeak)
+	case o.Range < 1 || o.Range > 50:
+		return fmt.Errorf("loudness range %g LU isn't between 1 and 50", o.Range)
+	}
+	return nil
+}
+
+// filter returns the loudnorm filter with the options' targets and the given extra parameters.
+func (o LoudnessOptions) filter(extra ...string) string {
+	params := append([]string{
+		"I=" + formatLoudness(o.Target),
+		"TP=" + formatLoudness(o.TruePeak),
+		"LRA=" + formatLoudness(o.Range),
+	}, extra...)
+	return "loudnorm=" + strings.Join(params, ":")
+}
+
+// LoudnessMeasurement is the loudness of a file, as the first loudnorm pass measures it.
+type LoudnessMeasurement struct {
+	// Integrated is the integrated loudness in LUFS.
+	Integrated float64
+
+	// TruePeak is the true peak in dBTP.
+	TruePeak float64
+
+	// Range is the loudness range in LU.
+	Range float64
+
+	// Threshold is the gating threshold in LUFS.
+	Threshold float64
+
+	// Offset is the gain the second pass applies after normalizing, in LU.
+	Offset float64
+}
+
+// buildMeasureLoudnessArgs builds the FFmpeg command arguments for the first
+// loudnorm pass, which decodes the audio of inputPath and prints its loudness as
+// JSON on stderr without writing anything.
+func buildMeasureLoudnessArgs(inputPath string, opts LoudnessOptions) []string {
+	return []string{
+		"-hide_banner",
+		"-i", inputPath,
+		"-map", "0:a:0",
+		"-af", opts.filter("print_format=json"),
+		"-f", "null",
+		"-",
+	}
+}
+
+// MeasureLoudness measures the loudness of the audio in inputPath against the
+// targets in opts. Audio without sound fails with ErrSilentAudio.
+func MeasureLoudness(ctx context.Context, inputPath string, opts LoudnessOptions) (*LoudnessMeasurement, error) {
+	opts = opts.withDefaults()
+	var stderr strings.Builder
+	command := Command{Args: buildMeasureLoudnessArgs(inputPath, opts), Stderr: &stderr}
+	if err := DefaultRunner.Run(ctx, command); err != nil {
+		return nil, wrapRunError("loudness measurement", err)
+	}
+	return parseLoudnessOutput(stderr.String())
+}
+
+// loudnessOutput is the JSON the loudnorm filter prints with print_format=json.
+// Every value is a string, and silent input measures as "-inf".
+type loudnessOutput struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+// parseLoudnessOutput parses the measurement from FFmpeg's stderr, where it is
+// the last JSON object, after the usual log lines.
+func parseLoudnessOutput(stderr string) (*LoudnessMeasurement, error) {
+	start, end := strings.LastIndex(stderr, "{"), strings.LastIndex(stderr, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("no loudness measurement in FFmpeg output")
+	}
+	var out loudnessOutput
+	if err := json.Unmarshal([]byte(stderr[start:end+1]), &out); err != nil {
+		return nil, fmt.Errorf("parsing loudness measurement: %w", err)
+	}
+
+	var m LoudnessMeasurement
+	for _, field := range []struct {
+		name  string
+		value string
+		dst   *float64
+	}{
+		{"input_i", out.InputI, &m.Integrated},
+		{"input_tp", out.InputTP, &m.TruePeak},
+		{"input_lra", out.InputLRA, &m.Range},
+		{"input_thresh", out.InputThresh, &m.Threshold},
+		{"target_offset", out.TargetOffset, &m.Offset},
+	} {
+		v, err := strconv.ParseFloat(strings.TrimSpace(field.value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing loudness measurement: invalid %s %q", field.name, field.value)
+		}
+		*field.dst = v
+	}
+	if math.IsInf(m.Integrated, 0) || math.IsInf(m.Threshold, 0) {
+		return nil, ErrSilentAudio
+	}
+	return &m, nil
+}
+
+// loudnessEncoders are the audio encoder arguments normalized audio is written
+// with, by output extension. Normalizing changes the samples, so the audio is
+// always re-encoded; other streams are copied.
+var loudnessEncoders = map[string][]string{
+	"mp3":  {"-c:a", "libmp3lame", "-q:a", "2"},
+	"m4a":  {"-c:a", "aac", "-b:a", "192k"},
+	"mp4":  {"-c:a", "aac", "-b:a", "192k"},
+	"mov":  {"-c:a", "aac", "-b:a", "192k"},
+	"aac":  {"-c:a", "aac", "-b:a", "192k"},
+	"webm": {"-c:a", "libopus", "-b:a", "160k"},
+	"mkv":  {"-c:a", "libopus", "-b:a", "160k"},
+	"mka":  {"-c:a", "libopus", "-b:a", "160k"},
+	"ogg":  {"-c:a", "libopus", "-b:a", "160k"},
+	"opus": {"-c:a", "libopus", "-b:a", "160k"},
+	"flac": {"-c:a", "flac"},
+	"wav":  {"-c:a", "pcm_s16le"},
+}
+
+// buildNormalizeLoudnessArgs builds the FFmpeg command arguments for the second
+// loudnorm pass, which applies the gain for measured linearly where it can, so
+// the audio's dynamics are kept, and writes the result to outputPath.
+func buildNormalizeLoudnessArgs(inputPath, outputPath string, opts LoudnessOptions, measured *LoudnessMeasurement) ([]string, error) {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(outputPath)), ".")
+	encoder, ok := loudnessEncoders[ext]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output container %q", ext)
+	}
+
+	args := []string{
+		"-i", inputPath,
+		"-map", "0",
+		"-c", "copy",
+		"-af", opts.filter(
+			"measured_I="+formatLoudness(measured.Integrated),
+			"measured_TP="+formatLoudness(measured.TruePeak),
+			"measured_LRA="+formatLoudness(measured.Range),
+			"measured_thresh="+formatLoudness(measured.Threshold),
+			"offset="+formatLoudness(measured.Offset),
+			"linear=true",
+		),
+	}
+	args = append(args, encoder...)
+	return append(args,
+		"-ar", normalizedSampleRate,
+		"-y", // Overwrite output file without asking
+		outputPath,
+	), nil
+}
+
+// NormalizeLoudness normalizes the loudness of the audio in inputPath to the
+// targets in opts with two loudnorm passes, writing the result to outputPath.
+// The first pass measures the audio and the second applies the gain that
+// measurement calls for; progress is reported for the second pass, over the
+// media duration total. Other streams are copied. On failure any partially
+// written output is removed.
+func NormalizeLoudness(ctx context.Context, inputPath, outputPath string, opts LoudnessOptions, total time.Duration, progress ProgressCallback) error {
+	opts = opts.withDefaults()
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	measured, err := MeasureLoudness(ctx, inputPath, opts)
+	if err != nil {
+		return err
+	}
+	args, err := buildNormalizeLoudnessArgs(inputPath, outputPath, opts, measured)
+	if err != nil {
+		return err
+	}
+	command := Command{Args: args, Outputs: []string{outputPath}}
+	return wrapRunError("loudness normalization", runWithProgress(ctx, command, total, progress))
+}
+
+// formatLoudness formats a loudness value for a filter parameter.
+func formatLoudness(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
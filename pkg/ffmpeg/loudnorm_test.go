@@ -0,0 +1,125 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loudnormJSON is what the first loudnorm pass prints on stderr.
+const loudnormJSON = `[Parsed_loudnorm_0 @ 0x5581c8a0c2c0]
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-16.58",
+	"output_tp" : "-1.50",
+	"output_lra" : "14.78",
+	"output_thresh" : "-27.71",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.58"
+}`
+
+func TestBuildMeasureLoudnessArgs(t *testing.T) {
+	args := buildMeasureLoudnessArgs("in.m4a", LoudnessOptions{}.withDefaults())
+	want := "-hide_banner -i in.m4a -map 0:a:0 -af loudnorm=I=-16:TP=-1.5:LRA=11:print_format=json -f null -"
+	if got := strings.Join(args, " "); got != want {
+		t.Errorf("buildMeasureLoudnessArgs = %q, want %q", got, want)
+	}
+}
+
+func TestBuildNormalizeLoudnessArgs(t *testing.T) {
+	measured := &LoudnessMeasurement{Integrated: -27.61, TruePeak: -4.47, Range: 18.06, Threshold: -39.2, Offset: 0.58}
+	opts := LoudnessOptions{Target: -23, TruePeak: -2, Range: 7}
+
+	args, err := buildNormalizeLoudnessArgs("in.mp3", "out.mp3", opts, measured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "-i in.mp3 -map 0 -c copy " +
+		"-af loudnorm=I=-23:TP=-2:LRA=7:measured_I=-27.61:measured_TP=-4.47:measured_LRA=18.06:measured_thresh=-39.2:offset=0.58:linear=true " +
+		"-c:a libmp3lame -q:a 2 -ar 48000 -y out.mp3"
+	if got := strings.Join(args, " "); got != want {
+		t.Errorf("buildNormalizeLoudnessArgs = %q, want %q", got, want)
+	}
+
+	for ext, encoder := range map[string]string{".m4a": "aac", ".webm": "libopus", ".MKV": "libopus"} {
+		args, err := buildNormalizeLoudnessArgs("in", "out"+ext, opts, measured)
+		if err != nil || !strings.Contains(strings.Join(args, " "), "-c:a "+encoder) {
+			t.Errorf("buildNormalizeLoudnessArgs for %s = %v, %v; want the %s encoder", ext, args, err, encoder)
+		}
+	}
+	if _, err := buildNormalizeLoudnessArgs("in", "out.flv", opts, measured); err == nil {
+		t.Error("expected an error for an unsupported container")
+	}
+}
+
+func TestParseLoudnessOutput(t *testing.T) {
+	m, err := parseLoudnessOutput("ffmpeg log line {not json}\n" + loudnormJSON + "\n[out#0/null] video:0kB audio:1kB\n")
+	if err != nil {
+		t.Fatalf("parseLoudnessOutput failed: %v", err)
+	}
+	want := LoudnessMeasurement{Integrated: -27.61, TruePeak: -4.47, Range: 18.06, Threshold: -39.2, Offset: 0.58}
+	if *m != want {
+		t.Errorf("parseLoudnessOutput = %+v, want %+v", *m, want)
+	}
+
+	silent := strings.NewReplacer(`"-27.61"`, `"-inf"`, `"-39.20"`, `"-inf"`).Replace(loudnormJSON)
+	if _, err := parseLoudnessOutput(silent); !errors.Is(err, ErrSilentAudio) {
+		t.Errorf("parseLoudnessOutput of silent audio error = %v, want ErrSilentAudio", err)
+	}
+	for _, output := range []string{"", "no measurement here", `{"input_i": "loud"}`} {
+		if _, err := parseLoudnessOutput(output); err == nil {
+			t.Errorf("parseLoudnessOutput(%q) expected an error", output)
+		}
+	}
+}
+
+func TestLoudnessOptions_Validate(t *testing.T) {
+	valid := []LoudnessOptions{{}, {Target: -23}, {Target: -14, TruePeak: -1, Range: 20}}
+	for _, opts := range valid {
+		if err := opts.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", opts, err)
+		}
+	}
+	invalid := []LoudnessOptions{{Target: -80}, {Target: 3}, {TruePeak: 1}, {Range: 60}}
+	for _, opts := range invalid {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected an error", opts)
+		}
+	}
+}
+
+func TestNormalizeLoudness(t *testing.T) {
+	dir := t.TempDir()
+	measureArgs := filepath.Join(dir, "measure")
+	// The first pass prints the measurement; the second writes its last argument
+	installFakeFFmpeg(t, `for last; do :; done
+if [ "$last" = "-" ]; then
+  echo "$@" > '`+measureArgs+`'
+  cat >&2 <<'JSON'
+`+loudnormJSON+`
+JSON
+else
+  echo "$@" > "$last"
+fi`)
+
+	output := filepath.Join(dir, "out.m4a")
+	if err := NormalizeLoudness(context.Background(), "in.m4a", output, LoudnessOptions{Target: -14}, 0, nil); err != nil {
+		t.Fatalf("NormalizeLoudness failed: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "loudnorm=I=-14:TP=-1.5:LRA=11:measured_I=-27.61:") {
+		t.Errorf("second pass arguments = %q, want the measurement from the first", data)
+	}
+	if data, _ := os.ReadFile(measureArgs); !strings.Contains(string(data), "print_format=json") {
+		t.Errorf("first pass arguments = %q", data)
+	}
+}
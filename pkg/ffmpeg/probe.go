@@ -0,0 +1,275 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrProbeNotFound is returned when ffprobe is not found on the system.
+	ErrProbeNotFound = errors.New("ffprobe not found")
+
+	// ErrCorruptOutput is returned by Verify when a file doesn't match what was expected.
+	ErrCorruptOutput = errors.New("output doesn't match the downloaded streams")
+)
+
+// probeFileName returns the ffprobe executable name for the current OS.
+func probeFileName() string {
+	if runtime.GOOS == "windows" {
+		return "ffprobe.exe"
+	}
+	return "ffprobe"
+}
+
+// GetProbeFilePath searches for the ffprobe executable and returns its path.
+// The directory of the FFmpeg GetCliFilePath finds is searched first, since
+// both usually come together, then the same places as for FFmpeg.
+// Returns ErrProbeNotFound if ffprobe is not found.
+func GetProbeFilePath() (string, error) {
+	name := probeFileName()
+	dirs := probeDirectoryPaths()
+	if ffmpegPath := TryGetCliFilePath(); ffmpegPath != nil {
+		dirs = append([]string{filepath.Dir(*ffmpegPath)}, dirs...)
+	}
+	for _, dir := range dirs {
+		fullPath := filepath.Join(dir, name)
+		if _, err := os.Stat(fullPath); err == nil {
+			return fullPath, nil
+		}
+	}
+	return "", ErrProbeNotFound
+}
+
+// IsProbeAvailable returns true if ffprobe is available on the system.
+func IsProbeAvailable() bool {
+	_, err := GetProbeFilePath()
+	return err == nil
+}
+
+// Stream types reported by ffprobe.
+const (
+	StreamTypeVideo    = "video"
+	StreamTypeAudio    = "audio"
+	StreamTypeSubtitle = "subtitle"
+)
+
+// ProbeStream is a stream in a probed file.
+type ProbeStream struct {
+	// Index is the stream's position in the file.
+	Index int
+
+	// Type is the kind of stream, such as StreamTypeVideo or StreamTypeAudio.
+	Type string
+
+	// Codec is FFmpeg's name for the codec, such as "h264", "vp9" or "opus".
+	Codec string
+
+	// Width and Height are the video dimensions in pixels, 0 for other streams.
+	Width  int
+	Height int
+
+	// Duration is the stream's duration, 0 if the container doesn't record it.
+	Duration time.Duration
+}
+
+// ProbeResult describes a media file as ffprobe reads it.
+type ProbeResult struct {
+	// Format is the container format, such as "matroska,webm" or "mov,mp4,m4a,3gp,3g2,mj2".
+	Format string
+
+	// Duration is the duration of the file.
+	Duration time.Duration
+
+	// Streams are the file's streams in order.
+	Streams []ProbeStream
+}
+
+// StreamsOfType returns the streams of the given type.
+func (r *ProbeResult) StreamsOfType(streamType string) []ProbeStream {
+	var streams []ProbeStream
+	for _, s := range r.Streams {
+		if s.Type == streamType {
+			streams = append(streams, s)
+		}
+	}
+	return streams
+}
+
+// buildProbeArgs builds the ffprobe command arguments for reading a file's format and streams as JSON.
+func buildProbeArgs(path string) []string {
+	return []string{
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		path,
+	}
+}
+
+// Probe reads the duration, format and streams of the media file at path with
+// ffprobe. Files ffprobe can't read, such as truncated downloads, fail with the
+// reason it gives.
+func Probe(ctx context.Context, path string) (*ProbeResult, error) {
+	probePath, err := GetProbeFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	var out strings.Builder
+	runner := &Runner{Path: probePath, Timeout: DefaultRunner.Timeout}
+	if err := runner.Run(ctx, Command{Args: buildProbeArgs(path), Stdout: &out}); err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	return parseProbeOutput([]byte(out.String()))
+}
+
+// probeOutput is the JSON ffprobe prints with -show_format and -show_streams.
+type probeOutput struct {
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+	} `json:"format"`
+	Streams []struct {
+		Index     int    `json:"index"`
+		CodecType string `json:"codec_type"`
+		CodecName string `json:"codec_name"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+		Duration  string `json:"duration"`
+	} `json:"streams"`
+}
+
+// parseProbeOutput parses ffprobe's JSON output.
+func parseProbeOutput(data []byte) (*ProbeResult, error) {
+	var out probeOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("parsing ffprobe output: %w", err)
+	}
+	result := &ProbeResult{
+		Format:   out.Format.FormatName,
+		Duration: parseProbeDuration(out.Format.Duration),
+	}
+	for _, s := range out.Streams {
+		result.Streams = append(result.Streams, ProbeStream{
+			Index:    s.Index,
+			Type:     s.CodecType,
+			Codec:    s.CodecName,
+			Width:    s.Width,
+			Height:   s.Height,
+			Duration: parseProbeDuration(s.Duration),
+		})
+	}
+	return result, nil
+}
+
+// parseProbeDuration parses a duration in seconds as ffprobe prints it, such
+// as "212.091000". Missing or invalid values, like "N/A", are 0.
+func parseProbeDuration(s string) time.Duration {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil || seconds < 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// codecFamilies maps the prefixes of codec names in YouTube's MIME types
+// (RFC 6381 codec strings) to FFmpeg's codec names.
+var codecFamilies = []struct{ prefix, name string }{
+	{"avc1", "h264"},
+	{"avc3", "h264"},
+	{"hev1", "hevc"},
+	{"hvc1", "hevc"},
+	{"vp09", "vp9"},
+	{"vp9", "vp9"},
+	{"vp8", "vp8"},
+	{"av01", "av1"},
+	{"mp4a", "aac"},
+	{"opus", "opus"},
+	{"vorbis", "vorbis"},
+	{"ac-3", "ac3"},
+	{"ec-3", "eac3"},
+	{"mp3", "mp3"},
+}
+
+// CodecName returns FFmpeg's name for a codec as YouTube lists it, such as
+// "h264" for "avc1.640028" or "aac" for "mp4a.40.2". Unknown codecs are
+// returned lowercased.
+func CodecName(codec string) string {
+	codec = strings.ToLower(strings.TrimSpace(codec))
+	for _, family := range codecFamilies {
+		if strings.HasPrefix(codec, family.prefix) {
+			return family.name
+		}
+	}
+	return codec
+}
+
+// DefaultDurationTolerance is how far a file's duration may be from the
+// expected one before Verify reports it, on top of 1% of the expected duration.
+// Streams rarely end on exactly the same frame, and YouTube rounds durations
+// to the second.
+const DefaultDurationTolerance = 2 * time.Second
+
+// Expectation describes what a downloaded file should contain, for Verify.
+// Zero fields aren't checked.
+type Expectation struct {
+	// Duration is the expected duration of the file.
+	Duration time.Duration
+
+	// Tolerance is how far the duration may be off. If 0,
+	// DefaultDurationTolerance plus 1% of Duration is used.
+	Tolerance time.Duration
+
+	// VideoCodec and AudioCodec are the codecs the file's video and audio
+	// streams must have, as FFmpeg or YouTube names them. Setting one also
+	// requires the file to have a stream of that type.
+	VideoCodec string
+	AudioCodec string
+}
+
+// Verify checks that the probed file matches want, returning an error wrapping
+// ErrCorruptOutput that lists every mismatch.
+func (r *ProbeResult) Verify(want Expectation) error {
+	var problems []string
+
+	if want.Duration > 0 {
+		tolerance := want.Tolerance
+		if tolerance <= 0 {
+			tolerance = DefaultDurationTolerance + want.Duration/100
+		}
+		if diff := r.Duration - want.Duration; diff > tolerance || diff < -tolerance {
+			problems = append(problems, fmt.Sprintf("duration is %s, want %s", r.Duration.Round(time.Millisecond), want.Duration))
+		}
+	}
+
+	for _, check := range []struct{ streamType, codec string }{
+		{StreamTypeVideo, want.VideoCodec},
+		{StreamTypeAudio, want.AudioCodec},
+	} {
+		if check.codec == "" {
+			continue
+		}
+		streams := r.StreamsOfType(check.streamType)
+		if len(streams) == 0 {
+			problems = append(problems, "no "+check.streamType+" stream")
+			continue
+		}
+		if got, wantCodec := CodecName(streams[0].Codec), CodecName(check.codec); got != wantCodec {
+			problems = append(problems, fmt.Sprintf("%s codec is %s, want %s", check.streamType, got, wantCodec))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrCorruptOutput, strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testProbeOutput = `{
+  "streams": [
+    {"index": 0, "codec_name": "vp9", "codec_type": "video", "width": 1920, "height": 1080},
+    {"index": 1, "codec_name": "opus", "codec_type": "audio", "duration": "212.081000"}
+  ],
+  "format": {"filename": "video.mkv", "format_name": "matroska,webm", "duration": "212.091000"}
+}`
+
+func TestParseProbeOutput(t *testing.T) {
+	result, err := parseProbeOutput([]byte(testProbeOutput))
+	if err != nil {
+		t.Fatalf("parseProbeOutput() error = %v", err)
+	}
+	if result.Format != "matroska,webm" || result.Duration != 212091*time.Millisecond {
+		t.Errorf("result = %+v", result)
+	}
+	if len(result.Streams) != 2 {
+		t.Fatalf("streams = %+v, want 2", result.Streams)
+	}
+	video := result.StreamsOfType(StreamTypeVideo)
+	if len(video) != 1 || video[0].Codec != "vp9" || video[0].Width != 1920 || video[0].Height != 1080 {
+		t.Errorf("video streams = %+v", video)
+	}
+	audio := result.StreamsOfType(StreamTypeAudio)
+	if len(audio) != 1 || audio[0].Index != 1 || audio[0].Duration != 212081*time.Millisecond {
+		t.Errorf("audio streams = %+v", audio)
+	}
+
+	if _, err := parseProbeOutput([]byte("not json")); err == nil {
+		t.Error("parseProbeOutput() accepted invalid JSON")
+	}
+}
+
+func TestParseProbeDuration(t *testing.T) {
+	for input, want := range map[string]time.Duration{
+		"1.500000": 1500 * time.Millisecond,
+		"N/A":      0,
+		"":         0,
+		"-1":       0,
+	} {
+		if got := parseProbeDuration(input); got != want {
+			t.Errorf("parseProbeDuration(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestCodecName(t *testing.T) {
+	for input, want := range map[string]string{
+		"avc1.640028":   "h264",
+		"vp09.00.51.08": "vp9",
+		"vp9":           "vp9",
+		"av01.0.08M.08": "av1",
+		"mp4a.40.2":     "aac",
+		"opus":          "opus",
+		"h264":          "h264",
+		"HEVC":          "hevc",
+	} {
+		if got := CodecName(input); got != want {
+			t.Errorf("CodecName(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestProbeResult_Verify(t *testing.T) {
+	result, err := parseProbeOutput([]byte(testProbeOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		want    Expectation
+		problem string
+	}{
+		{"matches", Expectation{Duration: 212 * time.Second, VideoCodec: "vp09.00.51.08", AudioCodec: "opus"}, ""},
+		{"nothing expected", Expectation{}, ""},
+		{"truncated", Expectation{Duration: 300 * time.Second}, "duration is 3m32.091s, want 5m0s"},
+		{"wrong codec", Expectation{VideoCodec: "avc1.640028"}, "video codec is vp9, want h264"},
+		{"tight tolerance", Expectation{Duration: 211 * time.Second, Tolerance: time.Second / 2}, "duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := result.Verify(tt.want)
+			if tt.problem == "" {
+				if err != nil {
+					t.Errorf("Verify() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrCorruptOutput) || !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.problem)
+			}
+		})
+	}
+
+	videoOnly := &ProbeResult{Streams: []ProbeStream{{Type: StreamTypeVideo, Codec: "h264"}}}
+	if err := videoOnly.Verify(Expectation{AudioCodec: "mp4a.40.2"}); err == nil || !strings.Contains(err.Error(), "no audio stream") {
+		t.Errorf("Verify() error = %v, want a missing audio stream", err)
+	}
+}
+
+// installFakeProbe puts a fake ffprobe running script first in PATH.
+func installFakeProbe(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffprobe is a shell script")
+	}
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "ffprobe"), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", tmpDir+":"+os.Getenv("PATH"))
+}
+
+func TestProbe(t *testing.T) {
+	installFakeProbe(t, `cat <<'JSON'
+`+testProbeOutput+`
+JSON`)
+
+	result, err := Probe(context.Background(), "video.mkv")
+	if err != nil {
+		t.Fatalf("Probe() error = %v", err)
+	}
+	if result.Duration != 212091*time.Millisecond || len(result.Streams) != 2 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestProbe_ReportsUnreadableFile(t *testing.T) {
+	installFakeProbe(t, `echo "video.mp4: Invalid data found when processing input" >&2; exit 1`)
+
+	_, err := Probe(context.Background(), "video.mp4")
+	if err == nil || !strings.Contains(err.Error(), "Invalid data found") {
+		t.Errorf("Probe() error = %v, want ffprobe's reason", err)
+	}
+}
+
+func TestGetProbeFilePath_PrefersFFmpegDirectory(t *testing.T) {
+	installFakeProbe(t, "exit 0")
+	installFakeFFmpeg(t, "exit 0")
+	ffmpegPath, err := GetCliFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := filepath.Join(filepath.Dir(ffmpegPath), "ffprobe")
+	if err := os.WriteFile(next, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := GetProbeFilePath(); err != nil || got != next {
+		t.Errorf("GetProbeFilePath() = %q, %v, want %s", got, err, next)
+	}
+}
@@ -0,0 +1,109 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tracing"
+)
+
+// Progress represents the progress of an FFmpeg operation.
+type Progress struct {
+	// Processed is the media time written to the output so far.
+	Processed time.Duration
+
+	// Total is the total media duration. May be 0 if unknown.
+	Total time.Duration
+
+	// Done is true once FFmpeg reports the end of processing.
+	Done bool
+}
+
+// Percentage returns the completion percentage (0-100).
+// Returns 0 if the total duration is unknown.
+func (p Progress) Percentage() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	pct := float64(p.Processed) / float64(p.Total) * 100
+	if pct > 100 {
+		return 100
+	}
+	return pct
+}
+
+// ProgressCallback is a function called to report FFmpeg progress.
+type ProgressCallback func(Progress)
+
+// progressArgs are prepended to FFmpeg arguments to emit machine-readable progress on stdout.
+var progressArgs = []string{"-progress", "pipe:1", "-nostats"}
+
+// parseProgress reads FFmpeg `-progress` key=value output and reports progress
+// after each block. It returns when r is exhausted.
+func parseProgress(r io.Reader, total time.Duration, callback ProgressCallback) {
+	scanner := bufio.NewScanner(r)
+	var processed time.Duration
+
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "out_time_us", "out_time_ms":
+			// Both keys are in microseconds (out_time_ms is misnamed in FFmpeg)
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+				processed = time.Duration(us) * time.Microsecond
+			}
+		case "progress":
+			done := value == "end"
+			if done && total > 0 {
+				processed = total
+			}
+			callback(Progress{Processed: processed, Total: total, Done: done})
+		}
+	}
+}
+
+// runWithProgress runs c with DefaultRunner, with progress reporting enabled.
+// If progress is nil, FFmpeg is run without the progress flags.
+func runWithProgress(ctx context.Context, c Command, total time.Duration, progress ProgressCallback) error {
+	if progress == nil {
+		return DefaultRunner.Run(ctx, c)
+	}
+	c.Args = append(append([]string{}, progressArgs...), c.Args...)
+
+	stdout, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("creating stdout pipe: %w", err)
+	}
+	defer func() { _ = stdout.Close() }()
+	c.Stdout = w
+	process, err := DefaultRunner.Start(ctx, c)
+	// FFmpeg has its own copy of the write end, so reading ends when it exits
+	_ = w.Close()
+	if err != nil {
+		return err
+	}
+
+	parseProgress(stdout, total, progress)
+	return process.Wait()
+}
+
+// MuxStreamsWithProgress combines a video stream and an audio stream into a single output file,
+// reporting progress via the callback. total is the media duration used to compute percentages.
+// The context can be used to cancel the operation.
+func MuxStreamsWithProgress(ctx context.Context, videoPath, audioPath, outputPath string, total time.Duration, progress ProgressCallback) error {
+	ctx, span := tracing.Start(ctx, tracing.SpanFFmpegMux, tracing.String(tracing.AttrOutput, outputPath))
+	command := Command{Args: buildMuxArgs(videoPath, audioPath, outputPath), Outputs: []string{outputPath}}
+	err := wrapRunError("mux", runWithProgress(ctx, command, total, progress))
+	tracing.End(span, err)
+	return err
+}
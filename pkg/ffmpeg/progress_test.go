@@ -0,0 +1,72 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress_Percentage(t *testing.T) {
+	tests := []struct {
+		progress Progress
+		want     float64
+	}{
+		{Progress{Processed: 30 * time.Second, Total: time.Minute}, 50},
+		{Progress{Processed: 30 * time.Second}, 0},
+		{Progress{Processed: 2 * time.Minute, Total: time.Minute}, 100},
+	}
+
+	for _, tt := range tests {
+		if got := tt.progress.Percentage(); got != tt.want {
+			t.Errorf("Percentage() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestParseProgress(t *testing.T) {
+	output := strings.Join([]string{
+		"frame=10",
+		"out_time_us=1500000",
+		"out_time=00:00:01.500000",
+		"progress=continue",
+		"frame=20",
+		"out_time_ms=3000000",
+		"progress=continue",
+		"out_time_us=N/A",
+		"progress=end",
+	}, "\n")
+
+	var updates []Progress
+	parseProgress(strings.NewReader(output), 4*time.Second, func(p Progress) {
+		updates = append(updates, p)
+	})
+
+	if len(updates) != 3 {
+		t.Fatalf("expected 3 progress updates, got %d: %v", len(updates), updates)
+	}
+	if updates[0].Processed != 1500*time.Millisecond {
+		t.Errorf("first update processed = %v, want 1.5s", updates[0].Processed)
+	}
+	if updates[1].Processed != 3*time.Second {
+		t.Errorf("second update processed = %v, want 3s", updates[1].Processed)
+	}
+	if !updates[2].Done || updates[2].Processed != 4*time.Second {
+		t.Errorf("final update = %+v, want done at total duration", updates[2])
+	}
+	for _, u := range updates {
+		if u.Total != 4*time.Second {
+			t.Errorf("expected total 4s, got %v", u.Total)
+		}
+	}
+}
+
+func TestParseProgress_UnknownTotal(t *testing.T) {
+	var last Progress
+	parseProgress(strings.NewReader("out_time_us=2000000\nprogress=end\n"), 0, func(p Progress) {
+		last = p
+	})
+
+	if !last.Done || last.Processed != 2*time.Second {
+		t.Errorf("unexpected final progress: %+v", last)
+	}
+}
@@ -0,0 +1,118 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ReplayGainReference is the loudness ReplayGain 2.0 gains bring audio to, in LUFS.
+	ReplayGainReference = -18.0
+
+	// R128Reference is the loudness EBU R128 gains, such as the R128_TRACK_GAIN
+	// of Opus files, bring audio to, in LUFS.
+	R128Reference = -23.0
+)
+
+// ReplayGain is the loudness of a file's audio, as the ebur128 filter measures
+// it, from which players work out how much to turn it up or down.
+type ReplayGain struct {
+	// Integrated is the integrated loudness in LUFS.
+	Integrated float64
+
+	// TruePeak is the true peak in dBFS.
+	TruePeak float64
+}
+
+// TrackGain returns the ReplayGain 2.0 track gain in dB.
+func (g *ReplayGain) TrackGain() float64 {
+	return ReplayGainReference - g.Integrated
+}
+
+// TrackPeak returns the true peak as a linear amplitude, where 1 is full scale.
+func (g *ReplayGain) TrackPeak() float64 {
+	return math.Pow(10, g.TruePeak/20)
+}
+
+// R128TrackGain returns the EBU R128 track gain in the Q7.8 fixed-point format
+// of Opus R128_TRACK_GAIN tags: dB times 256.
+func (g *ReplayGain) R128TrackGain() int {
+	return int(math.Round((R128Reference - g.Integrated) * 256))
+}
+
+// buildMeasureReplayGainArgs builds the FFmpeg command arguments for measuring
+// the audio of inputPath with the ebur128 filter, which prints a summary on
+// stderr without writing anything.
+func buildMeasureReplayGainArgs(inputPath string) []string {
+	return []string{
+		"-hide_banner",
+		"-nostats",
+		"-i", inputPath,
+		"-map", "0:a:0",
+		"-af", "ebur128=peak=true",
+		"-f", "null",
+		"-",
+	}
+}
+
+// MeasureReplayGain measures the loudness and true peak of the audio in
+// inputPath. Audio without sound fails with ErrSilentAudio.
+func MeasureReplayGain(ctx context.Context, inputPath string) (*ReplayGain, error) {
+	var stderr strings.Builder
+	command := Command{Args: buildMeasureReplayGainArgs(inputPath), Stderr: &stderr}
+	if err := DefaultRunner.Run(ctx, command); err != nil {
+		return nil, wrapRunError("ReplayGain measurement", err)
+	}
+	return parseEBUR128Summary(stderr.String())
+}
+
+var (
+	// ebur128Integrated matches the integrated loudness of an ebur128 summary,
+	// "I: -14.1 LUFS". Silent input measures as -70 LUFS or -inf.
+	ebur128Integrated = regexp.MustCompile(`(?m)^\s*I:\s+(\S+) LUFS`)
+
+	// ebur128Peak matches the true peak of an ebur128 summary, "Peak: 0.9 dBFS".
+	ebur128Peak = regexp.MustCompile(`(?m)^\s*Peak:\s+(\S+) dBFS`)
+)
+
+// parseEBUR128Summary parses the measurement from the summary the ebur128
+// filter prints at the end of FFmpeg's stderr.
+func parseEBUR128Summary(stderr string) (*ReplayGain, error) {
+	i := strings.LastIndex(stderr, "Summary:")
+	if i < 0 {
+		return nil, errors.New("no ebur128 summary in FFmpeg output")
+	}
+	summary := stderr[i:]
+
+	integrated, err := parseSummaryValue(ebur128Integrated, summary, "integrated loudness")
+	if err != nil {
+		return nil, err
+	}
+	peak, err := parseSummaryValue(ebur128Peak, summary, "true peak")
+	if err != nil {
+		return nil, err
+	}
+	// The filter gates out everything below -70 LUFS, which is where silence ends up
+	if math.IsInf(integrated, 0) || integrated <= -70 {
+		return nil, ErrSilentAudio
+	}
+	return &ReplayGain{Integrated: integrated, TruePeak: peak}, nil
+}
+
+// parseSummaryValue parses the first number pattern captures in summary.
+func parseSummaryValue(pattern *regexp.Regexp, summary, name string) (float64, error) {
+	m := pattern.FindStringSubmatch(summary)
+	if m == nil {
+		return 0, fmt.Errorf("no %s in ebur128 summary", name)
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing ebur128 summary: invalid %s %q", name, m[1])
+	}
+	return v, nil
+}
@@ -0,0 +1,85 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// ebur128Output is the end of what the ebur128 filter prints on stderr.
+const ebur128Output = `[Parsed_ebur128_0 @ 0x55d0c8a3f2c0] t: 212.9     TARGET:-23 LUFS    M: -12.1 S: -13.0     I: -14.2 LUFS       LRA:   6.1 LU  FTPK:  0.1 dBFS  TPK:  0.6 dBFS
+[Parsed_ebur128_0 @ 0x55d0c8a3f2c0] Summary:
+
+  Integrated loudness:
+    I:         -14.1 LUFS
+    Threshold: -24.4 LUFS
+
+  Loudness range:
+    LRA:         6.8 LU
+    Threshold:  -34.4 LUFS
+    LRA low:   -19.2 LUFS
+    LRA high:  -12.4 LUFS
+
+  True peak:
+    Peak:       -0.3 dBFS
+`
+
+func TestBuildMeasureReplayGainArgs(t *testing.T) {
+	want := "-hide_banner -nostats -i in.mp3 -map 0:a:0 -af ebur128=peak=true -f null -"
+	if got := strings.Join(buildMeasureReplayGainArgs("in.mp3"), " "); got != want {
+		t.Errorf("buildMeasureReplayGainArgs = %q, want %q", got, want)
+	}
+}
+
+func TestParseEBUR128Summary(t *testing.T) {
+	gain, err := parseEBUR128Summary(ebur128Output)
+	if err != nil {
+		t.Fatalf("parseEBUR128Summary failed: %v", err)
+	}
+	if gain.Integrated != -14.1 || gain.TruePeak != -0.3 {
+		t.Errorf("parseEBUR128Summary() = %+v", gain)
+	}
+	if got := gain.TrackGain(); math.Abs(got-(-3.9)) > 1e-9 {
+		t.Errorf("TrackGain() = %v, want -3.9", got)
+	}
+	if got := gain.TrackPeak(); math.Abs(got-0.966051) > 1e-6 {
+		t.Errorf("TrackPeak() = %v, want 0.966051", got)
+	}
+	if got := gain.R128TrackGain(); got != -2278 {
+		t.Errorf("R128TrackGain() = %d, want -2278", got)
+	}
+
+	tests := []struct {
+		name, stderr string
+		want         string
+	}{
+		{"no summary", "[in#0 @ 0x1] Error opening input", "no ebur128 summary"},
+		{"no peak", "Summary:\n    I:         -14.1 LUFS\n", "no true peak"},
+		{"invalid", "Summary:\n    I:         abc LUFS\n    Peak: 0.0 dBFS\n", "invalid integrated loudness"},
+	}
+	for _, tt := range tests {
+		if _, err := parseEBUR128Summary(tt.stderr); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: parseEBUR128Summary() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	silent := "Summary:\n    I:         -70.0 LUFS\n    Peak:       -inf dBFS\n"
+	if _, err := parseEBUR128Summary(silent); !errors.Is(err, ErrSilentAudio) {
+		t.Errorf("parseEBUR128Summary() of silence error = %v, want ErrSilentAudio", err)
+	}
+}
+
+func TestMeasureReplayGain(t *testing.T) {
+	installFakeFFmpeg(t, `cat >&2 <<'EOF'
+`+ebur128Output+`EOF`)
+
+	gain, err := MeasureReplayGain(context.Background(), "in.mp3")
+	if err != nil {
+		t.Fatalf("MeasureReplayGain failed: %v", err)
+	}
+	if gain.Integrated != -14.1 {
+		t.Errorf("MeasureReplayGain() = %+v", gain)
+	}
+}
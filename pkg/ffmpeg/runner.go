@@ -0,0 +1,252 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is returned when an FFmpeg process runs longer than its Runner's timeout.
+var ErrTimeout = errors.New("ffmpeg timed out")
+
+const (
+	// DefaultKillDelay is how long a cancelled FFmpeg process is given to exit
+	// after being interrupted before it is killed.
+	DefaultKillDelay = 5 * time.Second
+
+	// DefaultStderrLimit is how much of the end of FFmpeg's stderr is kept for errors.
+	DefaultStderrLimit = 16 << 10
+)
+
+// Runner runs FFmpeg processes. Each process is stopped when its context is
+// cancelled or its timeout passes: it is interrupted so FFmpeg can exit
+// cleanly, then killed if it hasn't exited after KillDelay. The end of its
+// stderr is kept and returned in the *Error of a failed run, and outputs
+// the command names are removed if it fails.
+//
+// The zero Runner is ready to use. It finds FFmpeg with GetCliFilePath and has
+// no timeout.
+type Runner struct {
+	// Path is the FFmpeg executable. If empty, GetCliFilePath is used.
+	Path string
+
+	// Timeout limits how long each process may run. 0 means no limit.
+	Timeout time.Duration
+
+	// KillDelay is how long to wait for an interrupted process before killing
+	// it. If 0, DefaultKillDelay is used.
+	KillDelay time.Duration
+
+	// StderrLimit is how many bytes at the end of stderr are kept. If 0,
+	// DefaultStderrLimit is used.
+	StderrLimit int
+}
+
+// DefaultRunner is the Runner used by the package-level functions, such as
+// MuxStreams and Recode. Set its Timeout to limit how long they may run.
+var DefaultRunner = &Runner{}
+
+// Command is an FFmpeg invocation for a Runner.
+type Command struct {
+	// Args are the arguments passed to FFmpeg.
+	Args []string
+
+	// Stdin, Stdout and ExtraFiles are connected to the process like the
+	// fields of exec.Cmd with the same names.
+	Stdin      io.Reader
+	Stdout     io.Writer
+	ExtraFiles []*os.File
+
+	// Stderr, if set, receives everything FFmpeg writes to stderr, for commands
+	// that print their results there. The end of it is still kept for errors.
+	Stderr io.Writer
+
+	// Outputs are files the command writes, removed if it fails so no partial
+	// output is left behind.
+	Outputs []string
+}
+
+// Error is returned for an FFmpeg process that failed, timed out or was cancelled.
+type Error struct {
+	// Err is the reason: the *exec.ExitError of a failed process, ErrTimeout,
+	// or the context's error if it was cancelled.
+	Err error
+
+	// Stderr is the end of what FFmpeg wrote to stderr, which usually says why it failed.
+	Stderr string
+}
+
+func (e *Error) Error() string {
+	if e.Stderr == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + ": " + e.Stderr
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Process is an FFmpeg process started by Runner.Start.
+type Process struct {
+	cmd     *exec.Cmd
+	cancel  context.CancelFunc
+	ctx     context.Context
+	parent  context.Context
+	stderr  *tailBuffer
+	outputs []string
+	timeout time.Duration
+}
+
+// path returns the FFmpeg executable to run.
+func (r *Runner) path() (string, error) {
+	if r.Path != "" {
+		return r.Path, nil
+	}
+	return GetCliFilePath()
+}
+
+// Run runs c and waits for it to finish.
+func (r *Runner) Run(ctx context.Context, c Command) error {
+	p, err := r.Start(ctx, c)
+	if err != nil {
+		return err
+	}
+	return p.Wait()
+}
+
+// Start starts c without waiting for it. Wait must be called to release the
+// process's resources.
+func (r *Runner) Start(ctx context.Context, c Command) (*Process, error) {
+	path, err := r.path()
+	if err != nil {
+		return nil, err
+	}
+
+	parent := ctx
+	var cancel context.CancelFunc
+	if r.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	limit := r.StderrLimit
+	if limit <= 0 {
+		limit = DefaultStderrLimit
+	}
+	killDelay := r.KillDelay
+	if killDelay <= 0 {
+		killDelay = DefaultKillDelay
+	}
+
+	cmd := exec.CommandContext(ctx, path, c.Args...)
+	cmd.Stdin = c.Stdin
+	cmd.Stdout = c.Stdout
+	cmd.ExtraFiles = c.ExtraFiles
+	stderr := &tailBuffer{limit: limit}
+	cmd.Stderr = stderr
+	if c.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(stderr, c.Stderr)
+	}
+	cmd.Cancel = func() error {
+		// Once cancelled, the process is killed after the delay if it ignores the
+		// interrupt, and Wait stops waiting for stdin and stdout copies that never
+		// finish. It isn't set up front since a finished process's stdout can
+		// take any time to drain into a slow writer.
+		cmd.WaitDelay = killDelay
+		// Windows can't interrupt a process; killing it is the only option
+		if runtime.GOOS == "windows" {
+			return cmd.Process.Kill()
+		}
+		return cmd.Process.Signal(os.Interrupt)
+	}
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("starting ffmpeg: %w", err)
+	}
+	return &Process{
+		cmd:     cmd,
+		cancel:  cancel,
+		ctx:     ctx,
+		parent:  parent,
+		stderr:  stderr,
+		outputs: c.Outputs,
+		timeout: r.Timeout,
+	}, nil
+}
+
+// Wait waits for the process to exit. It returns an *Error if the process
+// failed, timed out or was cancelled, after removing the command's outputs.
+func (p *Process) Wait() error {
+	err := p.cmd.Wait()
+	// The context is checked before it is released by cancel
+	ctxErr := p.ctx.Err()
+	parentErr := p.parent.Err()
+	p.cancel()
+	if err == nil {
+		return nil
+	}
+
+	for _, output := range p.outputs {
+		_ = os.Remove(output)
+	}
+	switch {
+	case parentErr != nil:
+		err = parentErr
+	case ctxErr != nil:
+		err = fmt.Errorf("%w after %s", ErrTimeout, p.timeout)
+	}
+	return &Error{Err: err, Stderr: strings.TrimSpace(p.stderr.String())}
+}
+
+// wrapRunError adds what FFmpeg was doing to an error from a Runner. FFmpeg not
+// being found is returned as is, so callers can compare it with ErrNotFound.
+func wrapRunError(operation string, err error) error {
+	if err == nil || errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return fmt.Errorf("ffmpeg %s failed: %w", operation, err)
+}
+
+// tailBuffer is an io.Writer that keeps the last limit bytes written to it.
+// It is safe for concurrent use, since exec may copy stderr from a goroutine.
+type tailBuffer struct {
+	mu        sync.Mutex
+	limit     int
+	buf       []byte
+	truncated bool
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.limit; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+// String returns the kept bytes, starting at a line boundary if earlier output was dropped.
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := string(b.buf)
+	if b.truncated {
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			s = s[i+1:]
+		}
+		s = "...\n" + s
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunner_RunCapturesStderr(t *testing.T) {
+	installFakeFFmpeg(t, `echo "Invalid data found when processing input" >&2; exit 1`)
+
+	err := (&Runner{}).Run(context.Background(), Command{Args: []string{"-i", "in.mp4"}})
+	var ffErr *Error
+	if !errors.As(err, &ffErr) {
+		t.Fatalf("Run() error = %v, want *Error", err)
+	}
+	if ffErr.Stderr != "Invalid data found when processing input" {
+		t.Errorf("Stderr = %q", ffErr.Stderr)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("Run() error = %v, want exit status 1", err)
+	}
+	if !strings.Contains(err.Error(), "Invalid data found") {
+		t.Errorf("error message %q doesn't include stderr", err)
+	}
+}
+
+func TestRunner_RunRemovesOutputsOnFailure(t *testing.T) {
+	installFakeFFmpeg(t, `echo partial > "$1"; exit 1`)
+	output := filepath.Join(t.TempDir(), "out.mp4")
+
+	if err := (&Runner{}).Run(context.Background(), Command{Args: []string{output}, Outputs: []string{output}}); err == nil {
+		t.Fatal("Run() succeeded, want failure")
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("partial output not removed: %v", err)
+	}
+}
+
+func TestRunner_RunKeepsOutputsOnSuccess(t *testing.T) {
+	installFakeFFmpeg(t, `echo done > "$1"`)
+	output := filepath.Join(t.TempDir(), "out.mp4")
+
+	if err := (&Runner{}).Run(context.Background(), Command{Args: []string{output}, Outputs: []string{output}}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if _, err := os.Stat(output); err != nil {
+		t.Errorf("output removed: %v", err)
+	}
+}
+
+func TestRunner_Timeout(t *testing.T) {
+	installFakeFFmpeg(t, `exec sleep 10`)
+
+	start := time.Now()
+	err := (&Runner{Timeout: 50 * time.Millisecond}).Run(context.Background(), Command{})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Run() error = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() took %v, want the process stopped at the timeout", elapsed)
+	}
+}
+
+func TestRunner_KillsProcessIgnoringInterrupt(t *testing.T) {
+	// The ignored interrupt is inherited by sleep, so only the kill stops it
+	installFakeFFmpeg(t, `trap '' INT; exec sleep 10`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	err := (&Runner{KillDelay: 100 * time.Millisecond}).Run(ctx, Command{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() took %v, want the process killed after the delay", elapsed)
+	}
+}
+
+func TestRunner_Path(t *testing.T) {
+	err := (&Runner{Path: filepath.Join(t.TempDir(), "missing-ffmpeg")}).Run(context.Background(), Command{})
+	if err == nil || !strings.Contains(err.Error(), "starting ffmpeg") {
+		t.Errorf("Run() error = %v, want a start failure", err)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{limit: 16}
+	_, _ = b.Write([]byte("line one\n"))
+	_, _ = b.Write([]byte("line two\nline three\n"))
+
+	if got := b.String(); got != "...\nline three\n" {
+		t.Errorf("String() = %q, want the last whole line", got)
+	}
+
+	short := &tailBuffer{limit: 16}
+	_, _ = short.Write([]byte("short\n"))
+	if got := short.String(); got != "short\n" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestWrapRunError(t *testing.T) {
+	if err := wrapRunError("mux", ErrNotFound); err != ErrNotFound {
+		t.Errorf("wrapRunError(ErrNotFound) = %v, want it unwrapped", err)
+	}
+	if err := wrapRunError("mux", nil); err != nil {
+		t.Errorf("wrapRunError(nil) = %v", err)
+	}
+	err := wrapRunError("mux", &Error{Err: errors.New("exit status 1"), Stderr: "bad"})
+	if err.Error() != "ffmpeg mux failed: exit status 1: bad" {
+		t.Errorf("wrapRunError() = %q", err)
+	}
+}
+
+func TestRunner_RunCopiesStderr(t *testing.T) {
+	installFakeFFmpeg(t, `echo "measured" >&2`)
+
+	var stderr strings.Builder
+	if err := (&Runner{}).Run(context.Background(), Command{Stderr: &stderr}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if stderr.String() != "measured\n" {
+		t.Errorf("Stderr received %q, want %q", stderr.String(), "measured\n")
+	}
+}
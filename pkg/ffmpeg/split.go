@@ -0,0 +1,228 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrPartTooLarge is returned when a part cannot be made smaller than the size limit,
+// usually because the keyframe interval of the source is too long for stream-copy cuts.
+var ErrPartTooLarge = errors.New("split part exceeds size limit")
+
+// splitSafetyFactor leaves headroom for stream-copy cuts, which can only happen on keyframes.
+const splitSafetyFactor = 0.9
+
+// splitMaxAttempts is the number of times splitting is retried with shorter segments.
+const splitMaxAttempts = 3
+
+// partNumber is the placeholder of the part number in a part pattern.
+const partNumber = "%02d"
+
+// PartPattern returns the FFmpeg output pattern for the parts of a split file.
+// Percent signs of the path are doubled, as FFmpeg would take them for
+// placeholders. Example: "100% video.mp4" -> "100%% video.part%02d.mp4"
+func PartPattern(inputPath string) string {
+	ext := filepath.Ext(inputPath)
+	escape := func(s string) string { return strings.ReplaceAll(s, "%", "%%") }
+	return escape(strings.TrimSuffix(inputPath, ext)) + ".part" + partNumber + escape(ext)
+}
+
+// buildSplitArgs builds the FFmpeg command arguments for segmenting a file without re-encoding.
+func buildSplitArgs(inputPath, pattern string, segmentSeconds float64) []string {
+	return []string{
+		"-i", inputPath,
+		"-map", "0",
+		"-c", "copy",
+		"-f", "segment",
+		"-segment_time", strconv.FormatFloat(segmentSeconds, 'f', 3, 64),
+		"-segment_start_number", "1",
+		"-reset_timestamps", "1",
+		"-y", // Overwrite output files without asking
+		pattern,
+	}
+}
+
+// SplitBySize splits a media file into parts no larger than maxPartSize bytes using
+// FFmpeg's segment muxer with stream copy. The duration of the media is used to
+// estimate the segment length. Parts are named like "video.part01.mp4".
+// If the file already fits within the limit, it is returned as the only part.
+func SplitBySize(ctx context.Context, inputPath string, maxPartSize int64, duration time.Duration) ([]string, error) {
+	if maxPartSize <= 0 {
+		return nil, errors.New("max part size must be positive")
+	}
+
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading input file: %w", err)
+	}
+	if info.Size() <= maxPartSize {
+		return []string{inputPath}, nil
+	}
+
+	if duration <= 0 {
+		return nil, errors.New("media duration is required to split by size")
+	}
+
+	if _, err := DefaultRunner.path(); err != nil {
+		return nil, err
+	}
+
+	pattern := PartPattern(inputPath)
+	segmentSeconds := duration.Seconds() * float64(maxPartSize) / float64(info.Size()) * splitSafetyFactor
+
+	for attempt := 0; attempt < splitMaxAttempts; attempt++ {
+		removeParts(pattern)
+
+		args := buildSplitArgs(inputPath, pattern, segmentSeconds)
+		if err := DefaultRunner.Run(ctx, Command{Args: args}); err != nil {
+			removeParts(pattern)
+			return nil, wrapRunError("split", err)
+		}
+
+		parts, err := listParts(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		if largestPart(parts) <= maxPartSize {
+			return parts, nil
+		}
+
+		// Keyframes landed badly; try again with shorter segments
+		segmentSeconds *= 0.75
+	}
+
+	removeParts(pattern)
+	return nil, ErrPartTooLarge
+}
+
+// listParts returns the existing part files for a pattern, sorted by part number.
+func listParts(pattern string) ([]string, error) {
+	i := strings.LastIndex(pattern, partNumber)
+	if i < 0 {
+		return nil, fmt.Errorf("invalid part pattern: %s", pattern)
+	}
+	unescape := func(s string) string { return strings.ReplaceAll(s, "%%", "%") }
+	prefix, suffix := unescape(pattern[:i]), unescape(pattern[i+len(partNumber):])
+
+	matches, err := filepath.Glob(globEscape(prefix) + "[0-9][0-9]*" + globEscape(suffix))
+	if err != nil {
+		return nil, fmt.Errorf("listing split parts: %w", err)
+	}
+
+	// Part 100 follows part 99, which sorting the names wouldn't keep
+	numbers := make(map[string]int, len(matches))
+	parts := matches[:0]
+	for _, match := range matches {
+		digits := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), filepath.Base(prefix)), suffix)
+		if n, err := strconv.Atoi(digits); err == nil {
+			numbers[match] = n
+			parts = append(parts, match)
+		}
+	}
+	sort.Slice(parts, func(a, b int) bool { return numbers[parts[a]] < numbers[parts[b]] })
+	return parts, nil
+}
+
+// globEscape escapes glob metacharacters in a path.
+func globEscape(path string) string {
+	replacer := strings.NewReplacer("*", `\*`, "?", `\?`, "[", `\[`)
+	return replacer.Replace(path)
+}
+
+// removeParts deletes any existing part files for a pattern.
+func removeParts(pattern string) {
+	parts, _ := listParts(pattern)
+	for _, p := range parts {
+		_ = os.Remove(p)
+	}
+}
+
+// largestPart returns the size of the largest file in parts.
+func largestPart(parts []string) int64 {
+	var largest int64
+	for _, p := range parts {
+		if info, err := os.Stat(p); err == nil && info.Size() > largest {
+			largest = info.Size()
+		}
+	}
+	return largest
+}
+
+// WriteRejoinScripts writes an FFmpeg concat list plus shell and batch scripts next to
+// the parts that rejoin them into originalPath. Returns the paths of the written files.
+func WriteRejoinScripts(parts []string, originalPath string) ([]string, error) {
+	if len(parts) == 0 {
+		return nil, errors.New("no parts to rejoin")
+	}
+
+	dir := filepath.Dir(originalPath)
+	ext := filepath.Ext(originalPath)
+	base := strings.TrimSuffix(filepath.Base(originalPath), ext)
+
+	listName := base + ".parts.txt"
+	files := map[string]string{
+		listName:             buildConcatList(parts),
+		base + ".rejoin.sh":  buildRejoinShellScript(listName, filepath.Base(originalPath)),
+		base + ".rejoin.bat": buildRejoinBatchScript(listName, filepath.Base(originalPath)),
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	written := make([]string, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		perm := os.FileMode(0o644)
+		if strings.HasSuffix(name, ".sh") {
+			perm = 0o755
+		}
+		if err := os.WriteFile(path, []byte(files[name]), perm); err != nil {
+			return written, fmt.Errorf("writing %s: %w", name, err)
+		}
+		written = append(written, path)
+	}
+
+	return written, nil
+}
+
+// buildConcatList builds an FFmpeg concat demuxer list referencing the parts by file name.
+func buildConcatList(parts []string) string {
+	var sb strings.Builder
+	for _, p := range parts {
+		name := strings.ReplaceAll(filepath.Base(p), "'", `'\''`)
+		sb.WriteString("file '" + name + "'\n")
+	}
+	return sb.String()
+}
+
+// buildRejoinShellScript builds a POSIX shell script that rejoins the parts.
+func buildRejoinShellScript(listName, outputName string) string {
+	quote := func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	return "#!/bin/sh\n" +
+		"# Rejoins split parts into the original file (requires FFmpeg).\n" +
+		"cd \"$(dirname \"$0\")\" || exit 1\n" +
+		"ffmpeg -f concat -safe 0 -i " + quote(listName) + " -c copy " + quote(outputName) + "\n"
+}
+
+// buildRejoinBatchScript builds a Windows batch script that rejoins the parts.
+// Percent signs of the names are doubled, as batch files expand them as variables.
+func buildRejoinBatchScript(listName, outputName string) string {
+	escape := func(s string) string { return strings.ReplaceAll(s, "%", "%%") }
+	return "@echo off\r\n" +
+		"rem Rejoins split parts into the original file (requires FFmpeg).\r\n" +
+		"cd /d \"%~dp0\"\r\n" +
+		"ffmpeg -f concat -safe 0 -i \"" + escape(listName) + "\" -c copy \"" + escape(outputName) + "\"\r\n"
+}
@@ -0,0 +1,183 @@
+package ffmpeg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPartPattern(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"video.mp4", "video.part%02d.mp4"},
+		{filepath.Join("out", "My Video.mkv"), filepath.Join("out", "My Video.part%02d.mkv")},
+		{"noext", "noext.part%02d"},
+		{"100% Pure.mp4", "100%% Pure.part%02d.mp4"},
+	}
+
+	for _, tt := range tests {
+		if got := PartPattern(tt.input); got != tt.want {
+			t.Errorf("PartPattern(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestBuildSplitArgs(t *testing.T) {
+	args := buildSplitArgs("in.mp4", "in.part%02d.mp4", 90)
+	want := []string{
+		"-i", "in.mp4",
+		"-map", "0",
+		"-c", "copy",
+		"-f", "segment",
+		"-segment_time", "90.000",
+		"-segment_start_number", "1",
+		"-reset_timestamps", "1",
+		"-y", "in.part%02d.mp4",
+	}
+
+	if len(args) != len(want) {
+		t.Fatalf("buildSplitArgs() = %v, want %v", args, want)
+	}
+	for i := range args {
+		if args[i] != want[i] {
+			t.Errorf("buildSplitArgs()[%d] = %v, want %v", i, args[i], want[i])
+		}
+	}
+}
+
+func TestSplitBySize_FileWithinLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "small.mp4")
+	if err := os.WriteFile(input, make([]byte, 100), 0o644); err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+
+	parts, err := SplitBySize(context.Background(), input, 1000, time.Minute)
+	if err != nil {
+		t.Fatalf("SplitBySize failed: %v", err)
+	}
+	if len(parts) != 1 || parts[0] != input {
+		t.Errorf("expected the input file as the only part, got %v", parts)
+	}
+}
+
+func TestSplitBySize_RequiresDuration(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "big.mp4")
+	if err := os.WriteFile(input, make([]byte, 2000), 0o644); err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+
+	if _, err := SplitBySize(context.Background(), input, 1000, 0); err == nil {
+		t.Error("expected error when duration is unknown")
+	}
+}
+
+func TestSplitBySize_InvalidLimit(t *testing.T) {
+	if _, err := SplitBySize(context.Background(), "any.mp4", 0, time.Minute); err == nil {
+		t.Error("expected error for non-positive size limit")
+	}
+}
+
+func TestListParts_SortedAndEscaped(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "video [1080p].mp4")
+	for _, name := range []string{"video [1080p].part02.mp4", "video [1080p].part01.mp4", "other.part01.mp4"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("x"), 0o644); err != nil {
+			t.Fatalf("Failed to create part: %v", err)
+		}
+	}
+
+	parts, err := listParts(PartPattern(input))
+	if err != nil {
+		t.Fatalf("listParts failed: %v", err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got %v", parts)
+	}
+	if !strings.HasSuffix(parts[0], "part01.mp4") || !strings.HasSuffix(parts[1], "part02.mp4") {
+		t.Errorf("parts not sorted: %v", parts)
+	}
+}
+
+func TestListParts_NumericOrderWithPercent(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "100% Pure.mp4")
+	for _, name := range []string{"100% Pure.part100.mp4", "100% Pure.part99.mp4", "100% Pure.part01.mp4", "100% Pure.partXY.mp4"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("x"), 0o644); err != nil {
+			t.Fatalf("Failed to create part: %v", err)
+		}
+	}
+
+	parts, err := listParts(PartPattern(input))
+	if err != nil {
+		t.Fatalf("listParts failed: %v", err)
+	}
+	var names []string
+	for _, p := range parts {
+		names = append(names, filepath.Base(p))
+	}
+	want := []string{"100% Pure.part01.mp4", "100% Pure.part99.mp4", "100% Pure.part100.mp4"}
+	if strings.Join(names, "|") != strings.Join(want, "|") {
+		t.Errorf("parts = %q, want %q", names, want)
+	}
+}
+
+func TestBuildRejoinBatchScript_EscapesPercent(t *testing.T) {
+	script := buildRejoinBatchScript("100% Pure.parts.txt", "100% Pure.mp4")
+	if !strings.Contains(script, `-i "100%% Pure.parts.txt" -c copy "100%% Pure.mp4"`) {
+		t.Errorf("unexpected batch script:\n%s", script)
+	}
+	if !strings.Contains(script, `cd /d "%~dp0"`) {
+		t.Errorf("batch script lost its directory change:\n%s", script)
+	}
+}
+
+func TestWriteRejoinScripts(t *testing.T) {
+	tmpDir := t.TempDir()
+	original := filepath.Join(tmpDir, "It's a video.mp4")
+	parts := []string{
+		filepath.Join(tmpDir, "It's a video.part01.mp4"),
+		filepath.Join(tmpDir, "It's a video.part02.mp4"),
+	}
+
+	written, err := WriteRejoinScripts(parts, original)
+	if err != nil {
+		t.Fatalf("WriteRejoinScripts failed: %v", err)
+	}
+	if len(written) != 3 {
+		t.Fatalf("expected 3 files, got %v", written)
+	}
+
+	list, err := os.ReadFile(filepath.Join(tmpDir, "It's a video.parts.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read concat list: %v", err)
+	}
+	wantList := "file 'It'\\''s a video.part01.mp4'\nfile 'It'\\''s a video.part02.mp4'\n"
+	if string(list) != wantList {
+		t.Errorf("concat list = %q, want %q", list, wantList)
+	}
+
+	script, err := os.ReadFile(filepath.Join(tmpDir, "It's a video.rejoin.sh"))
+	if err != nil {
+		t.Fatalf("Failed to read shell script: %v", err)
+	}
+	if !strings.Contains(string(script), "-f concat") || !strings.Contains(string(script), `'It'\''s a video.mp4'`) {
+		t.Errorf("unexpected shell script:\n%s", script)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "It's a video.rejoin.bat")); err != nil {
+		t.Errorf("expected batch script to exist: %v", err)
+	}
+}
+
+func TestWriteRejoinScripts_NoParts(t *testing.T) {
+	if _, err := WriteRejoinScripts(nil, "video.mp4"); err == nil {
+		t.Error("expected error for empty parts")
+	}
+}
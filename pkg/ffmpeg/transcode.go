@@ -0,0 +1,313 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedCodec is returned when no encoder is known for a codec and acceleration pair.
+var ErrUnsupportedCodec = errors.New("unsupported codec")
+
+// HWAccel identifies a hardware video encoding backend.
+type HWAccel string
+
+// Supported hardware acceleration backends.
+const (
+	// HWAccelNone uses software encoders only.
+	HWAccelNone HWAccel = "none"
+	// HWAccelAuto picks the first hardware encoder available in FFmpeg, or software.
+	HWAccelAuto HWAccel = "auto"
+	// HWAccelNVENC uses NVIDIA NVENC encoders.
+	HWAccelNVENC HWAccel = "nvenc"
+	// HWAccelVideoToolbox uses Apple VideoToolbox encoders.
+	HWAccelVideoToolbox HWAccel = "videotoolbox"
+	// HWAccelQSV uses Intel Quick Sync Video encoders.
+	HWAccelQSV HWAccel = "qsv"
+)
+
+// hardwareAccels lists the hardware backends in detection priority order.
+var hardwareAccels = []HWAccel{HWAccelNVENC, HWAccelVideoToolbox, HWAccelQSV}
+
+// videoEncoders maps a codec to its FFmpeg encoder for each acceleration backend.
+var videoEncoders = map[string]map[HWAccel]string{
+	"h264": {
+		HWAccelNone:         "libx264",
+		HWAccelNVENC:        "h264_nvenc",
+		HWAccelVideoToolbox: "h264_videotoolbox",
+		HWAccelQSV:          "h264_qsv",
+	},
+	"h265": {
+		HWAccelNone:         "libx265",
+		HWAccelNVENC:        "hevc_nvenc",
+		HWAccelVideoToolbox: "hevc_videotoolbox",
+		HWAccelQSV:          "hevc_qsv",
+	},
+	"vp9": {
+		HWAccelNone: "libvpx-vp9",
+		HWAccelQSV:  "vp9_qsv",
+	},
+	"av1": {
+		HWAccelNone:  "libsvtav1",
+		HWAccelNVENC: "av1_nvenc",
+		HWAccelQSV:   "av1_qsv",
+	},
+}
+
+// codecAliases maps alternative codec names to their canonical form.
+var codecAliases = map[string]string{
+	"avc":  "h264",
+	"x264": "h264",
+	"hevc": "h265",
+	"x265": "h265",
+	"vp09": "vp9",
+	"av01": "av1",
+}
+
+// NormalizeCodec returns the canonical name for a video codec (e.g. "hevc" -> "h265").
+func NormalizeCodec(codec string) string {
+	codec = strings.ToLower(strings.TrimSpace(codec))
+	if alias, ok := codecAliases[codec]; ok {
+		return alias
+	}
+	return codec
+}
+
+// DefaultCodecForContainer returns the video codec used when recoding to a container
+// without an explicit codec.
+func DefaultCodecForContainer(container string) string {
+	if strings.EqualFold(container, "webm") {
+		return "vp9"
+	}
+	return "h264"
+}
+
+// containerCodecs lists the video codecs of the containers that can't hold
+// them all; MP4 and Matroska hold any of videoEncoders.
+var containerCodecs = map[string][]string{
+	"webm": {"vp9", "av1"},
+	"mov":  {"h264", "h265"},
+}
+
+// CheckContainerCodec returns an error wrapping ErrUnsupportedCodec if the
+// container can't hold video of the codec.
+func CheckContainerCodec(container, codec string) error {
+	container, codec = strings.ToLower(container), NormalizeCodec(codec)
+	codecs, ok := containerCodecs[container]
+	if !ok || slices.Contains(codecs, codec) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s can't hold %s video (use %s)", ErrUnsupportedCodec, container, codec, strings.Join(codecs, " or "))
+}
+
+// audioCodecForContainer returns the audio encoder to use for a container,
+// or "copy" when the container accepts any audio codec.
+func audioCodecForContainer(container string) string {
+	switch strings.ToLower(container) {
+	case "mp4", "mov", "m4v":
+		return "aac"
+	case "webm":
+		return "libopus"
+	default:
+		return "copy"
+	}
+}
+
+// RecodeOptions configures a video re-encode.
+type RecodeOptions struct {
+	// Codec is the target video codec (h264, h265, vp9, av1).
+	// Empty selects the default for the output container.
+	Codec string
+
+	// CRF is the constant quality factor (lower is better). 0 uses the encoder default.
+	CRF int
+
+	// Preset is the encoder speed/quality preset (e.g. "medium", "slow"). Empty uses the default.
+	Preset string
+
+	// HWAccel selects the hardware encoding backend. Empty is treated as HWAccelNone.
+	HWAccel HWAccel
+
+	// Duration is the media duration, used to compute progress percentages.
+	Duration time.Duration
+
+	// Progress, if set, receives progress updates while encoding.
+	Progress ProgressCallback
+}
+
+// ParseHWAccel parses a hardware acceleration name. Empty input means HWAccelNone.
+func ParseHWAccel(s string) (HWAccel, error) {
+	switch hw := HWAccel(strings.ToLower(strings.TrimSpace(s))); hw {
+	case "":
+		return HWAccelNone, nil
+	case HWAccelNone, HWAccelAuto, HWAccelNVENC, HWAccelVideoToolbox, HWAccelQSV:
+		return hw, nil
+	default:
+		return "", fmt.Errorf("unknown hardware acceleration %q", s)
+	}
+}
+
+// parseEncoders extracts encoder names from `ffmpeg -encoders` output.
+func parseEncoders(output string) map[string]bool {
+	encoders := make(map[string]bool)
+	inList := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "------") {
+			inList = true
+			continue
+		}
+		if !inList || line == "" {
+			continue
+		}
+		// Lines look like: "V....D h264_nvenc           NVIDIA NVENC H.264 encoder"
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			encoders[fields[1]] = true
+		}
+	}
+	return encoders
+}
+
+// AvailableEncoders returns the set of encoders supported by the installed FFmpeg.
+func AvailableEncoders(ctx context.Context) (map[string]bool, error) {
+	var out strings.Builder
+	if err := DefaultRunner.Run(ctx, Command{Args: []string{"-hide_banner", "-encoders"}, Stdout: &out}); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("listing ffmpeg encoders: %w", err)
+	}
+	return parseEncoders(out.String()), nil
+}
+
+// DetectHardwareAccels returns the hardware backends whose encoders for codec are
+// compiled into FFmpeg, in priority order.
+// Note that an encoder being listed does not guarantee the hardware is present.
+func DetectHardwareAccels(codec string, encoders map[string]bool) []HWAccel {
+	var found []HWAccel
+	for _, hw := range hardwareAccels {
+		if name, ok := videoEncoders[NormalizeCodec(codec)][hw]; ok && encoders[name] {
+			found = append(found, hw)
+		}
+	}
+	return found
+}
+
+// ResolveEncoder returns the FFmpeg encoder name for a codec and acceleration backend.
+// HWAccelAuto picks the first detected hardware encoder and falls back to software.
+func ResolveEncoder(codec string, hw HWAccel, encoders map[string]bool) (string, error) {
+	codec = NormalizeCodec(codec)
+	byAccel, ok := videoEncoders[codec]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedCodec, codec)
+	}
+
+	if hw == HWAccelAuto {
+		if detected := DetectHardwareAccels(codec, encoders); len(detected) > 0 {
+			return byAccel[detected[0]], nil
+		}
+		hw = HWAccelNone
+	}
+	if hw == "" {
+		hw = HWAccelNone
+	}
+
+	name, ok := byAccel[hw]
+	if !ok {
+		return "", fmt.Errorf("%w: %s with %s", ErrUnsupportedCodec, codec, hw)
+	}
+	return name, nil
+}
+
+// qualityArgs returns the constant quality arguments for an encoder.
+func qualityArgs(encoder string, crf int) []string {
+	if crf <= 0 {
+		return nil
+	}
+	value := strconv.Itoa(crf)
+	switch {
+	case strings.HasSuffix(encoder, "_nvenc"):
+		return []string{"-cq", value}
+	case strings.HasSuffix(encoder, "_qsv"):
+		return []string{"-global_quality", value}
+	case strings.HasSuffix(encoder, "_videotoolbox"):
+		// VideoToolbox has no CRF equivalent on the same scale
+		return nil
+	case encoder == "libvpx-vp9":
+		// Constant quality mode in libvpx requires a zero target bitrate
+		return []string{"-crf", value, "-b:v", "0"}
+	default:
+		return []string{"-crf", value}
+	}
+}
+
+// buildRecodeArgs builds the FFmpeg command arguments for re-encoding a video.
+func buildRecodeArgs(inputPath, outputPath, encoder string, opts RecodeOptions) []string {
+	container := strings.TrimPrefix(strings.ToLower(filepath.Ext(outputPath)), ".")
+
+	args := []string{
+		"-i", inputPath,
+		"-map", "0:v:0",
+		"-map", "0:a?",
+		"-c:v", encoder,
+	}
+	args = append(args, qualityArgs(encoder, opts.CRF)...)
+	if opts.Preset != "" && !strings.HasSuffix(encoder, "_videotoolbox") {
+		args = append(args, "-preset", opts.Preset)
+	}
+	args = append(args, "-c:a", audioCodecForContainer(container))
+	if container == "mp4" || container == "mov" || container == "m4v" {
+		// Move the index to the front so playback can start before the file is fully read
+		args = append(args, "-movflags", "+faststart")
+	}
+
+	return append(args,
+		"-y", // Overwrite output file without asking
+		outputPath,
+	)
+}
+
+// RecodePath returns the output path for recoding inputPath into container.
+// If the container matches the input extension, a ".recode" infix is used so the
+// input is not overwritten while it is being read.
+func RecodePath(inputPath, container string) string {
+	ext := filepath.Ext(inputPath)
+	base := strings.TrimSuffix(inputPath, ext)
+	if strings.EqualFold(strings.TrimPrefix(ext, "."), container) {
+		return base + ".recode." + strings.ToLower(container)
+	}
+	return base + "." + strings.ToLower(container)
+}
+
+// Recode re-encodes inputPath into outputPath, with the container taken from the
+// output extension. On failure any partially written output is removed.
+func Recode(ctx context.Context, inputPath, outputPath string, opts RecodeOptions) error {
+	if _, err := DefaultRunner.path(); err != nil {
+		return err
+	}
+
+	codec := opts.Codec
+	if codec == "" {
+		codec = DefaultCodecForContainer(strings.TrimPrefix(filepath.Ext(outputPath), "."))
+	}
+
+	var encoders map[string]bool
+	if opts.HWAccel == HWAccelAuto {
+		// Detection failure just means falling back to software
+		encoders, _ = AvailableEncoders(ctx)
+	}
+
+	encoder, err := ResolveEncoder(codec, opts.HWAccel, encoders)
+	if err != nil {
+		return err
+	}
+
+	command := Command{Args: buildRecodeArgs(inputPath, outputPath, encoder, opts), Outputs: []string{outputPath}}
+	return wrapRunError("recode", runWithProgress(ctx, command, opts.Duration, opts.Progress))
+}
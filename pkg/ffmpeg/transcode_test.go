@@ -0,0 +1,231 @@
+package ffmpeg
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const sampleEncodersOutput = `Encoders:
+ V..... = Video
+ A..... = Audio
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ V....D h264_nvenc           NVIDIA NVENC H.264 encoder (codec h264)
+ V....D hevc_qsv             HEVC (Intel Quick Sync Video acceleration) (codec hevc)
+ A....D aac                  AAC (Advanced Audio Coding)
+`
+
+func TestParseEncoders(t *testing.T) {
+	encoders := parseEncoders(sampleEncodersOutput)
+
+	for _, name := range []string{"libx264", "h264_nvenc", "hevc_qsv", "aac"} {
+		if !encoders[name] {
+			t.Errorf("expected encoder %q to be detected", name)
+		}
+	}
+	if encoders["="] || encoders["Video"] {
+		t.Error("legend lines should not be parsed as encoders")
+	}
+}
+
+func TestNormalizeCodec(t *testing.T) {
+	tests := map[string]string{
+		"H264": "h264",
+		"avc":  "h264",
+		"hevc": "h265",
+		"vp09": "vp9",
+		"av01": "av1",
+		"vp9":  "vp9",
+	}
+	for input, want := range tests {
+		if got := NormalizeCodec(input); got != want {
+			t.Errorf("NormalizeCodec(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestParseHWAccel(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    HWAccel
+		wantErr bool
+	}{
+		{"", HWAccelNone, false},
+		{"auto", HWAccelAuto, false},
+		{"NVENC", HWAccelNVENC, false},
+		{"videotoolbox", HWAccelVideoToolbox, false},
+		{"qsv", HWAccelQSV, false},
+		{"cuda", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseHWAccel(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseHWAccel(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseHWAccel(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDetectHardwareAccels(t *testing.T) {
+	encoders := parseEncoders(sampleEncodersOutput)
+
+	if got := DetectHardwareAccels("h264", encoders); !reflect.DeepEqual(got, []HWAccel{HWAccelNVENC}) {
+		t.Errorf("DetectHardwareAccels(h264) = %v, want [nvenc]", got)
+	}
+	if got := DetectHardwareAccels("hevc", encoders); !reflect.DeepEqual(got, []HWAccel{HWAccelQSV}) {
+		t.Errorf("DetectHardwareAccels(hevc) = %v, want [qsv]", got)
+	}
+	if got := DetectHardwareAccels("vp9", encoders); len(got) != 0 {
+		t.Errorf("DetectHardwareAccels(vp9) = %v, want none", got)
+	}
+}
+
+func TestResolveEncoder(t *testing.T) {
+	encoders := parseEncoders(sampleEncodersOutput)
+
+	tests := []struct {
+		codec   string
+		hw      HWAccel
+		want    string
+		wantErr bool
+	}{
+		{"h264", HWAccelNone, "libx264", false},
+		{"h264", "", "libx264", false},
+		{"h264", HWAccelAuto, "h264_nvenc", false},
+		{"vp9", HWAccelAuto, "libvpx-vp9", false},
+		{"h265", HWAccelVideoToolbox, "hevc_videotoolbox", false},
+		{"vp9", HWAccelVideoToolbox, "", true},
+		{"mpeg2", HWAccelNone, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ResolveEncoder(tt.codec, tt.hw, encoders)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ResolveEncoder(%q, %q) error = %v, wantErr %v", tt.codec, tt.hw, err, tt.wantErr)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrUnsupportedCodec) {
+			t.Errorf("expected ErrUnsupportedCodec, got %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("ResolveEncoder(%q, %q) = %q, want %q", tt.codec, tt.hw, got, tt.want)
+		}
+	}
+}
+
+func TestBuildRecodeArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		encoder  string
+		opts     RecodeOptions
+		wantArgs []string
+	}{
+		{
+			name:    "software h264 to mp4",
+			output:  "out.mp4",
+			encoder: "libx264",
+			opts:    RecodeOptions{CRF: 23, Preset: "slow"},
+			wantArgs: []string{
+				"-i", "in.webm", "-map", "0:v:0", "-map", "0:a?",
+				"-c:v", "libx264", "-crf", "23", "-preset", "slow",
+				"-c:a", "aac", "-movflags", "+faststart", "-y", "out.mp4",
+			},
+		},
+		{
+			name:    "nvenc uses cq",
+			output:  "out.mkv",
+			encoder: "hevc_nvenc",
+			opts:    RecodeOptions{CRF: 28},
+			wantArgs: []string{
+				"-i", "in.webm", "-map", "0:v:0", "-map", "0:a?",
+				"-c:v", "hevc_nvenc", "-cq", "28",
+				"-c:a", "copy", "-y", "out.mkv",
+			},
+		},
+		{
+			name:    "vp9 constant quality",
+			output:  "out.webm",
+			encoder: "libvpx-vp9",
+			opts:    RecodeOptions{CRF: 31},
+			wantArgs: []string{
+				"-i", "in.webm", "-map", "0:v:0", "-map", "0:a?",
+				"-c:v", "libvpx-vp9", "-crf", "31", "-b:v", "0",
+				"-c:a", "libopus", "-y", "out.webm",
+			},
+		},
+		{
+			name:    "videotoolbox ignores crf and preset",
+			output:  "out.mov",
+			encoder: "h264_videotoolbox",
+			opts:    RecodeOptions{CRF: 20, Preset: "fast"},
+			wantArgs: []string{
+				"-i", "in.webm", "-map", "0:v:0", "-map", "0:a?",
+				"-c:v", "h264_videotoolbox",
+				"-c:a", "aac", "-movflags", "+faststart", "-y", "out.mov",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := buildRecodeArgs("in.webm", tt.output, tt.encoder, tt.opts)
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("buildRecodeArgs() = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestRecodePath(t *testing.T) {
+	tests := []struct {
+		input     string
+		container string
+		want      string
+	}{
+		{"video.webm", "mp4", "video.mp4"},
+		{"video.mp4", "mp4", "video.recode.mp4"},
+		{"video.MP4", "mkv", "video.mkv"},
+	}
+	for _, tt := range tests {
+		if got := RecodePath(tt.input, tt.container); got != tt.want {
+			t.Errorf("RecodePath(%q, %q) = %q, want %q", tt.input, tt.container, got, tt.want)
+		}
+	}
+}
+
+func TestCheckContainerCodec(t *testing.T) {
+	tests := []struct {
+		container, codec string
+		ok               bool
+	}{
+		{"webm", "vp9", true},
+		{"WebM", "av01", true},
+		{"webm", "h264", false},
+		{"webm", "hevc", false},
+		{"mov", "h265", true},
+		{"mov", "vp9", false},
+		{"mp4", "av1", true},
+		{"mkv", "h264", true},
+	}
+	for _, tt := range tests {
+		err := CheckContainerCodec(tt.container, tt.codec)
+		if (err == nil) != tt.ok || (err != nil && !errors.Is(err, ErrUnsupportedCodec)) {
+			t.Errorf("CheckContainerCodec(%q, %q) = %v, want ok %v", tt.container, tt.codec, err, tt.ok)
+		}
+	}
+}
+
+func TestDefaultCodecForContainer(t *testing.T) {
+	if got := DefaultCodecForContainer("webm"); got != "vp9" {
+		t.Errorf("expected vp9 for webm, got %s", got)
+	}
+	if got := DefaultCodecForContainer("mp4"); got != "h264" {
+		t.Errorf("expected h264 for mp4, got %s", got)
+	}
+}
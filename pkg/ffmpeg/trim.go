@@ -0,0 +1,45 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// formatSeconds formats a duration as seconds with millisecond precision for FFmpeg time options.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// buildTrimArgs builds the FFmpeg command arguments for cutting a section out of a
+// file without re-encoding. Seeking on the input is fast but cuts at the keyframe
+// at or before start, so the section may begin slightly early.
+func buildTrimArgs(inputPath, outputPath string, start, end time.Duration) []string {
+	args := []string{}
+	if start > 0 {
+		args = append(args, "-ss", formatSeconds(start))
+	}
+	args = append(args,
+		"-i", inputPath,
+		"-t", formatSeconds(end-start),
+		"-map", "0",
+		"-c", "copy",
+		"-avoid_negative_ts", "make_zero",
+		"-y", // Overwrite output file without asking
+		outputPath,
+	)
+	return args
+}
+
+// Trim writes the section of inputPath between start and end to outputPath,
+// reporting progress via the callback. Streams are copied, not re-encoded.
+// On failure any partially written output is removed.
+func Trim(ctx context.Context, inputPath, outputPath string, start, end time.Duration, progress ProgressCallback) error {
+	if start < 0 || end <= start {
+		return errors.New("trim end must be after start")
+	}
+
+	command := Command{Args: buildTrimArgs(inputPath, outputPath, start, end), Outputs: []string{outputPath}}
+	return wrapRunError("trim", runWithProgress(ctx, command, end-start, progress))
+}
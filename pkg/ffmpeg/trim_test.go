@@ -0,0 +1,33 @@
+package ffmpeg
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildTrimArgs(t *testing.T) {
+	args := buildTrimArgs("in.mp4", "out.mp4", 90*time.Second, 180500*time.Millisecond)
+	want := "-ss 90.000 -i in.mp4 -t 90.500 -map 0 -c copy -avoid_negative_ts make_zero -y out.mp4"
+	if got := strings.Join(args, " "); got != want {
+		t.Errorf("buildTrimArgs = %q, want %q", got, want)
+	}
+}
+
+func TestBuildTrimArgs_FromStart(t *testing.T) {
+	args := buildTrimArgs("in.webm", "out.webm", 0, 30*time.Second)
+	if slices.Contains(args, "-ss") {
+		t.Errorf("a section starting at 0 should not seek: %v", args)
+	}
+	if i := slices.Index(args, "-t"); i < 0 || args[i+1] != "30.000" {
+		t.Errorf("expected -t 30.000 in %v", args)
+	}
+}
+
+func TestTrim_InvalidRange(t *testing.T) {
+	if err := Trim(context.Background(), "in.mp4", "out.mp4", 10*time.Second, 10*time.Second, nil); err == nil {
+		t.Error("expected an error for an empty range")
+	}
+}
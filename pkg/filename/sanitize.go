@@ -0,0 +1,264 @@
+package filename
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultMaxLength is the default maximum length of a file name in bytes,
+// the limit of most filesystems.
+const DefaultMaxLength = 255
+
+// smartCutWindow is how far back from the length limit truncation looks for a
+// word boundary to cut at, as a fraction of the limit.
+const smartCutWindow = 0.2
+
+// Platform selects which filesystem naming rules sanitization follows.
+type Platform int
+
+const (
+	// PlatformAny follows the rules of every platform, so files can be copied
+	// between systems. It is the same as PlatformWindows, the strictest one.
+	PlatformAny Platform = iota
+	// PlatformWindows forbids <>:"/\|? and *, reserved device names such as
+	// CON and NUL, and trailing dots and spaces.
+	PlatformWindows
+	// PlatformUnix only forbids slashes and NUL.
+	PlatformUnix
+)
+
+// windowsReservedNames are device names Windows doesn't allow as file names,
+// with or without an extension.
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// asciiReplacements transliterates letters that don't decompose into an ASCII
+// letter and combining marks.
+var asciiReplacements = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE", 'ø': "o", 'Ø': "O",
+	'ł': "l", 'Ł': "L", 'đ': "d", 'Đ': "D", 'ð': "d", 'Ð': "D", 'þ': "th", 'Þ': "Th",
+	'ı': "i", '‘': "'", '’': "'", '“': `"`, '”': `"`, '–': "-", '—': "-", '…': "...",
+}
+
+// Options configures how file names are sanitized.
+type Options struct {
+	// Platform selects the naming rules to follow. The default follows all of them.
+	Platform Platform
+
+	// MaxLength is the maximum length of a file name in bytes, including the
+	// extension. 0 means DefaultMaxLength.
+	MaxLength int
+
+	// MaxTitleLength limits the title to this many characters, cut at a word
+	// boundary where possible. 0 means no limit other than MaxLength.
+	MaxTitleLength int
+
+	// ASCIIOnly transliterates accented letters to ASCII and replaces any other
+	// non-ASCII character with an underscore.
+	ASCIIOnly bool
+
+	// Normalize converts names to Unicode normalization form C, so letters typed
+	// with combining accents get the same bytes as precomposed ones.
+	Normalize bool
+
+	// StripFormatChars removes invisible control and format characters, such as
+	// right-to-left marks and zero-width spaces.
+	StripFormatChars bool
+
+	// ReplaceEmoji replaces each emoji, including multi-character sequences such
+	// as flags and skin tones, with an underscore.
+	ReplaceEmoji bool
+}
+
+// maxLength returns the effective maximum file name length.
+func (o Options) maxLength() int {
+	if o.MaxLength > 0 {
+		return o.MaxLength
+	}
+	return DefaultMaxLength
+}
+
+// Sanitize replaces the characters of name that aren't allowed in file names on
+// the selected platform with underscores and trims surrounding spaces. Windows
+// additionally loses trailing dots, and reserved device names get an underscore.
+func Sanitize(name string, opts Options) string {
+	if opts.Normalize {
+		name = norm.NFC.String(name)
+	}
+	if opts.StripFormatChars {
+		name = strings.Map(func(r rune) rune {
+			if unicode.In(r, unicode.Cc, unicode.Cf) && !(opts.ReplaceEmoji && isEmoji(r)) {
+				return -1
+			}
+			return r
+		}, name)
+	}
+	if opts.ReplaceEmoji {
+		name = replaceEmoji(name)
+	}
+	if opts.ASCIIOnly {
+		name = toASCII(name)
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(name))
+	for _, r := range name {
+		if isInvalidRune(r, opts.Platform) {
+			sb.WriteRune('_')
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return fixName(strings.TrimSpace(sb.String()), opts.Platform)
+}
+
+// isInvalidRune reports whether r can't appear in a file name on the platform.
+func isInvalidRune(r rune, platform Platform) bool {
+	if platform == PlatformUnix {
+		return r == '/' || r == 0
+	}
+	return r < 0x20 || strings.ContainsRune(invalidChars, r)
+}
+
+// fixName makes a sanitized name usable as a whole file name: Windows strips
+// trailing dots and spaces and doesn't allow device names, and no platform
+// allows "." or "..".
+func fixName(name string, platform Platform) string {
+	if platform != PlatformUnix {
+		trimmed := strings.TrimRight(name, ". ")
+		if trimmed == "" && name != "" {
+			trimmed = "_"
+		}
+		name = trimmed
+		base, _, _ := strings.Cut(name, ".")
+		if windowsReservedNames[strings.ToUpper(strings.TrimSpace(base))] {
+			name = strings.Replace(name, base, base+"_", 1)
+		}
+	}
+	if name == "." || name == ".." {
+		return strings.Repeat("_", len(name))
+	}
+	return name
+}
+
+// emojiRanges are the code points that make up emoji: pictographs, symbols and
+// dingbats, regional indicators for flags, skin tone modifiers, and the joiners,
+// variation selectors and tags that combine them into sequences.
+var emojiRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x200d, Hi: 0x200d, Stride: 1},
+		{Lo: 0x20e3, Hi: 0x20e3, Stride: 1},
+		{Lo: 0x2300, Hi: 0x23ff, Stride: 1},
+		{Lo: 0x2600, Hi: 0x27bf, Stride: 1},
+		{Lo: 0x2b00, Hi: 0x2bff, Stride: 1},
+		{Lo: 0xfe0e, Hi: 0xfe0f, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f000, Hi: 0x1faff, Stride: 1},
+		{Lo: 0xe0020, Hi: 0xe007f, Stride: 1},
+	},
+}
+
+// isEmoji reports whether r is part of an emoji.
+func isEmoji(r rune) bool {
+	return unicode.Is(emojiRanges, r)
+}
+
+// replaceEmoji replaces every run of emoji code points in s with one underscore,
+// so a sequence drawn as a single emoji becomes a single character.
+func replaceEmoji(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	inEmoji := false
+	for _, r := range s {
+		if isEmoji(r) {
+			if !inEmoji {
+				sb.WriteRune('_')
+			}
+			inEmoji = true
+			continue
+		}
+		inEmoji = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// toASCII transliterates s to ASCII: accents are dropped, a few letters are spelled
+// out and any other non-ASCII character becomes an underscore.
+func toASCII(s string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	if err == nil {
+		s = stripped
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for _, r := range s {
+		switch {
+		case r < utf8.RuneSelf:
+			sb.WriteRune(r)
+		case asciiReplacements[r] != "":
+			sb.WriteString(asciiReplacements[r])
+		case unicode.IsSpace(r):
+			sb.WriteRune(' ')
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
+
+// Truncate shortens a file name to at most maxBytes bytes, keeping its extension.
+// The cut falls on a word boundary when one is close to the limit, and the
+// shortened name loses trailing spaces, dots and separators.
+func Truncate(name string, maxBytes int) string {
+	if len(name) <= maxBytes {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	if len(ext) >= maxBytes || strings.ContainsRune(ext, ' ') {
+		ext = ""
+	}
+	base := strings.TrimSuffix(name, ext)
+	return cutWords(base, maxBytes-len(ext), func(s string) int { return len(s) }) + ext
+}
+
+// truncateTitle shortens a title to at most maxRunes characters.
+func truncateTitle(title string, maxRunes int) string {
+	if maxRunes <= 0 || utf8.RuneCountInString(title) <= maxRunes {
+		return title
+	}
+	return cutWords(title, maxRunes, utf8.RuneCountInString)
+}
+
+// cutWords returns the longest prefix of s whose size is at most limit, preferring
+// to end at a space within the last part of the limit.
+func cutWords(s string, limit int, size func(string) int) string {
+	end := 0
+	for i := range s {
+		if size(s[:i]) > limit {
+			break
+		}
+		end = i
+	}
+	if size(s) <= limit {
+		end = len(s)
+	}
+	prefix := s[:end]
+
+	if space := strings.LastIndexByte(prefix, ' '); space > 0 && size(prefix[:space]) >= int(float64(limit)*(1-smartCutWindow)) {
+		prefix = prefix[:space]
+	}
+	return strings.TrimRight(prefix, " .-_,")
+}
@@ -0,0 +1,133 @@
+package filename
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+func TestSanitize_Platforms(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		platform Platform
+		want     string
+	}{
+		{"reserved name", "CON", PlatformAny, "CON_"},
+		{"reserved name any case", "nul", PlatformWindows, "nul_"},
+		{"reserved name with extension", "com1.txt", PlatformWindows, "com1_.txt"},
+		{"reserved name prefix is fine", "CONTROL", PlatformWindows, "CONTROL"},
+		{"trailing dots", "Wait for it...", PlatformAny, "Wait for it"},
+		{"control characters", "Line\nbreak\t", PlatformAny, "Line_break_"},
+		{"only dots", "..", PlatformAny, "_"},
+		{"unix keeps colons", "Part 1: Intro?", PlatformUnix, "Part 1: Intro?"},
+		{"unix replaces slashes", "AC/DC", PlatformUnix, "AC_DC"},
+		{"unix keeps reserved names", "CON", PlatformUnix, "CON"},
+		{"unix dot names", ".", PlatformUnix, "_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.input, Options{Platform: tt.platform}); got != tt.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitize_ASCIIOnly(t *testing.T) {
+	tests := map[string]string{
+		"Café Señor":          "Cafe Senor",
+		"Straße – Ørsted":     "Strasse - Orsted",
+		"Łódź “live”":         "Lodz _live_",
+		"日本語 title":           "___ title",
+		"already plain ASCII": "already plain ASCII",
+	}
+	for input, want := range tests {
+		if got := Sanitize(input, Options{ASCIIOnly: true}); got != want {
+			t.Errorf("Sanitize(%q, ASCIIOnly) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		max  int
+		want string
+	}{
+		{"short enough", "video.mp4", 20, "video.mp4"},
+		{"cut at word boundary", "the quick brown fox jumps.mp4", 22, "the quick brown.mp4"},
+		{"cut inside long word", "abcdefghijklmnopqrstuvwxyz.mp4", 14, "abcdefghij.mp4"},
+		{"trailing separator removed", "chapter one - part two.mp4", 18, "chapter one.mp4"},
+		{"no extension", "abcdefghijklmnop", 10, "abcdefghij"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.in, tt.max)
+			if got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+			}
+			if len(got) > tt.max {
+				t.Errorf("Truncate(%q, %d) is %d bytes", tt.in, tt.max, len(got))
+			}
+		})
+	}
+}
+
+func TestTruncate_MultibyteRunes(t *testing.T) {
+	name := strings.Repeat("日", 100) + ".mp4"
+	got := Truncate(name, DefaultMaxLength)
+	if len(got) > DefaultMaxLength || !utf8.ValidString(got) || !strings.HasSuffix(got, ".mp4") {
+		t.Errorf("Truncate produced %d bytes, valid UTF-8 %v: %q", len(got), utf8.ValidString(got), got)
+	}
+}
+
+func TestApplyTemplateWithOptions_Limits(t *testing.T) {
+	video := youtube.Video{ID: "dQw4w9WgXcQ", Title: strings.Repeat("very long title ", 30)}
+
+	got := ApplyTemplateWithOptions("$title", &video, "mp4", "", Options{})
+	if len(got) > DefaultMaxLength || !strings.HasSuffix(got, ".mp4") {
+		t.Errorf("expected at most %d bytes ending in .mp4, got %d bytes: %q", DefaultMaxLength, len(got), got)
+	}
+
+	got = ApplyTemplateWithOptions("$title [$id]", &video, "mp4", "", Options{MaxTitleLength: 20})
+	if want := "very long title very [dQw4w9WgXcQ].mp4"; got != want {
+		t.Errorf("ApplyTemplateWithOptions() = %q, want %q", got, want)
+	}
+
+	got = ApplyTemplateWithOptions("$title", &youtube.Video{Title: "aux"}, "mp4", "", Options{})
+	if got != "aux_.mp4" {
+		t.Errorf("reserved title should be escaped, got %q", got)
+	}
+}
+
+func TestSanitize_Unicode(t *testing.T) {
+	restrict := Options{Normalize: true, StripFormatChars: true, ReplaceEmoji: true}
+	tests := []struct {
+		name  string
+		input string
+		opts  Options
+		want  string
+	}{
+		{"NFC normalization", "Cafe\u0301", Options{Normalize: true}, "Caf\u00e9"},
+		{"no normalization by default", "Cafe\u0301", Options{}, "Cafe\u0301"},
+		{"right-to-left marks", "\u202bשלום\u202c title\u200f", Options{StripFormatChars: true}, "שלום title"},
+		{"zero-width space", "zero\u200bwidth", Options{StripFormatChars: true}, "zerowidth"},
+		{"single emoji", "Party 🎉 time", Options{ReplaceEmoji: true}, "Party _ time"},
+		{"ZWJ sequence", "Family \U0001f468\u200d\U0001f469\u200d\U0001f467 vlog", restrict, "Family _ vlog"},
+		{"flag and skin tone", "\U0001f1ef\U0001f1f5 trip \U0001f44d\U0001f3fd", restrict, "_ trip _"},
+		{"emoji with variation selector", "Love \u2764\ufe0f!", restrict, "Love _!"},
+		{"emoji kept without option", "Party 🎉", Options{}, "Party 🎉"},
+		{"restrict then ASCII", "Cre\u0300me \U0001f370", Options{Normalize: true, ReplaceEmoji: true, ASCIIOnly: true}, "Creme _"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.input, tt.opts); got != tt.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@
 package filename
 
 import (
+	"fmt"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
@@ -10,20 +13,10 @@ import (
 // invalidChars contains characters that are not allowed in filenames across platforms.
 const invalidChars = `<>:"/\|?*`
 
-// SanitizeFilename replaces invalid filename characters with underscores and trims spaces.
+// SanitizeFilename replaces characters that are invalid in filenames on any platform
+// with underscores and trims spaces. It is Sanitize with the default options.
 func SanitizeFilename(name string) string {
-	var sb strings.Builder
-	sb.Grow(len(name))
-
-	for _, r := range name {
-		if strings.ContainsRune(invalidChars, r) {
-			sb.WriteRune('_')
-		} else {
-			sb.WriteRune(r)
-		}
-	}
-
-	return strings.TrimSpace(sb.String())
+	return Sanitize(name, Options{})
 }
 
 // ApplyTemplate applies a template to generate a filename from video metadata.
@@ -37,7 +30,20 @@ func SanitizeFilename(name string) string {
 //
 // The container extension is automatically appended.
 // All placeholders are sanitized to remove invalid filename characters.
+//
+// Slashes in the template, such as in "$author/$uploadDate - $title", split the
+// result into directories, each fixed up like a file name. Empty directories
+// are dropped, so the result is always a relative path below the output
+// directory.
 func ApplyTemplate(template string, video *youtube.Video, container, number string) string {
+	return ApplyTemplateWithOptions(template, video, container, number, Options{})
+}
+
+// ApplyTemplateWithOptions applies a template like ApplyTemplate, sanitizing the
+// placeholders with opts. The title is limited to opts.MaxTitleLength, and the
+// file name and each directory are truncated to opts.MaxLength bytes, keeping
+// the extension.
+func ApplyTemplateWithOptions(template string, video *youtube.Video, container, number string, opts Options) string {
 	result := template
 
 	// Replace number placeholders first (they need special handling)
@@ -50,9 +56,9 @@ func ApplyTemplate(template string, video *youtube.Video, container, number stri
 	}
 
 	// Replace video metadata placeholders
-	result = strings.ReplaceAll(result, "$id", SanitizeFilename(video.ID))
-	result = strings.ReplaceAll(result, "$title", SanitizeFilename(video.Title))
-	result = strings.ReplaceAll(result, "$author", SanitizeFilename(video.Author.Name))
+	result = strings.ReplaceAll(result, "$id", Sanitize(video.ID, opts))
+	result = strings.ReplaceAll(result, "$title", Sanitize(truncateTitle(video.Title, opts.MaxTitleLength), opts))
+	result = strings.ReplaceAll(result, "$author", Sanitize(video.Author.Name, opts))
 
 	// Format upload date
 	uploadDate := ""
@@ -61,10 +67,48 @@ func ApplyTemplate(template string, video *youtube.Video, container, number stri
 	}
 	result = strings.ReplaceAll(result, "$uploadDate", uploadDate)
 
-	// Trim and append extension
-	result = strings.TrimSpace(result)
-	return result + "." + container
+	// Fix up each directory and the file name, then make room for the extension
+	segments := splitTemplatePath(result, opts.Platform)
+	dirs := make([]string, 0, len(segments))
+	for _, dir := range segments[:len(segments)-1] {
+		if dir = fixName(strings.TrimSpace(dir), opts.Platform); dir != "" {
+			dirs = append(dirs, Truncate(dir, opts.maxLength()))
+		}
+	}
+	ext := "." + container
+	name := fixName(strings.TrimSpace(segments[len(segments)-1]), opts.Platform)
+	return filepath.Join(append(dirs, Truncate(name+ext, opts.maxLength()))...)
+}
+
+// splitTemplatePath splits a filled-in template at its path separators. Slashes
+// always separate directories, and so do backslashes unless the platform allows
+// them in file names. Placeholders are sanitized before, so only separators
+// written in the template split it.
+func splitTemplatePath(path string, platform Platform) []string {
+	if platform != PlatformUnix {
+		path = strings.ReplaceAll(path, `\`, "/")
+	}
+	return strings.Split(path, "/")
 }
 
 // DefaultTemplate is the default filename template.
 const DefaultTemplate = "$title"
+
+// DefaultChapterTemplate is the default filename template for chapters split
+// out of a video.
+const DefaultChapterTemplate = "$title - $chapterIndex - $chapterTitle"
+
+// ApplyChapterTemplate applies a template to generate the filename of one of a
+// video's chapters. On top of the placeholders of ApplyTemplate it supports:
+//   - $chapterIndex: Chapter number, counting from 1, zero-padded to the width of the chapter count (at least 2 digits)
+//   - $chapterTitle: Chapter title
+//
+// index is the chapter's number and count the number of chapters in the video.
+func ApplyChapterTemplate(template string, video *youtube.Video, chapter youtube.Chapter, index, count int, container string, opts Options) string {
+	width := max(2, len(strconv.Itoa(count)))
+	result := strings.NewReplacer(
+		"$chapterIndex", fmt.Sprintf("%0*d", width, index),
+		"$chapterTitle", Sanitize(chapter.Title, opts),
+	).Replace(template)
+	return ApplyTemplateWithOptions(result, video, container, "", opts)
+}
@@ -1,6 +1,8 @@
 package filename
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -233,3 +235,74 @@ func TestApplyTemplate_DifferentContainers(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyTemplate_Subdirectories(t *testing.T) {
+	video := youtube.Video{
+		ID:         "dQw4w9WgXcQ",
+		Title:      "AC/DC: Live",
+		Author:     youtube.Author{Name: "Rock/Metal"},
+		UploadDate: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		video    youtube.Video
+		want     string
+	}{
+		{"author folder", "$author/$uploadDate - $title", video, filepath.Join("Rock_Metal", "2024-03-15 - AC_DC_ Live.mp4")},
+		{"nested folders", "$author/$uploadDate/$id", video, filepath.Join("Rock_Metal", "2024-03-15", "dQw4w9WgXcQ.mp4")},
+		{"backslash", `$author\$id`, video, filepath.Join("Rock_Metal", "dQw4w9WgXcQ.mp4")},
+		{"empty folder dropped", "$author/$title", youtube.Video{Title: "Song"}, "Song.mp4"},
+		{"absolute made relative", "/$id", video, "dQw4w9WgXcQ.mp4"},
+		{"no parent directories", "../$id", video, filepath.Join("_", "dQw4w9WgXcQ.mp4")},
+		{"folder fixed up", "$id . /$title", video, filepath.Join("dQw4w9WgXcQ", "AC_DC_ Live.mp4")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ApplyTemplate(tt.template, &tt.video, "mp4", ""); got != tt.want {
+				t.Errorf("ApplyTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTemplate_SubdirectoriesTruncatedPerSegment(t *testing.T) {
+	video := youtube.Video{Title: strings.Repeat("t", 40), Author: youtube.Author{Name: strings.Repeat("a", 40)}}
+	got := ApplyTemplateWithOptions("$author/$title", &video, "mp4", "", Options{MaxLength: 20})
+	dir, name := filepath.Split(got)
+	if len(filepath.Clean(dir)) != 20 || len(name) != 20 || !strings.HasSuffix(name, ".mp4") {
+		t.Errorf("ApplyTemplateWithOptions() = %q, want each segment cut to 20 bytes", got)
+	}
+}
+
+func TestApplyTemplate_UnixKeepsBackslashes(t *testing.T) {
+	video := youtube.Video{Title: `a\b`}
+	if got := ApplyTemplateWithOptions("$title", &video, "mp4", "", Options{Platform: PlatformUnix}); got != `a\b.mp4` {
+		t.Errorf("ApplyTemplateWithOptions() = %q, want the backslash kept", got)
+	}
+}
+
+func TestApplyChapterTemplate(t *testing.T) {
+	video := &youtube.Video{ID: "dQw4w9WgXcQ", Title: "Mix 2024", Author: youtube.Author{Name: "DJ"}}
+	chapter := youtube.Chapter{Title: "Intro / Outro?", Start: time.Minute}
+
+	tests := []struct {
+		name     string
+		template string
+		index    int
+		count    int
+		want     string
+	}{
+		{"default", DefaultChapterTemplate, 3, 9, "Mix 2024 - 03 - Intro _ Outro_.m4a"},
+		{"wide index", "$chapterIndex $chapterTitle", 7, 120, "007 Intro _ Outro_.m4a"},
+		{"video placeholders", "$author - $id $chapterIndex", 1, 2, "DJ - dQw4w9WgXcQ 01.m4a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ApplyChapterTemplate(tt.template, video, chapter, tt.index, tt.count, "m4a", Options{}); got != tt.want {
+				t.Errorf("ApplyChapterTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
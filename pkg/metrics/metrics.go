@@ -0,0 +1,176 @@
+// Package metrics collects download metrics and serves them in the Prometheus
+// text exposition format, for monitoring the serve and sync daemons.
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+)
+
+// ContentType is the content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DurationBuckets are the upper bounds, in seconds, of the download duration
+// histogram's buckets, from a short audio stream to a long 4K video.
+var DurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+// Metrics counts stream downloads. Add it to the downloaders as an event
+// listener and give its RateLimited to the HTTP client, and serve it at
+// /metrics. The zero value is not ready to use; create one with New. It is safe
+// for concurrent use.
+type Metrics struct {
+	started     atomic.Uint64
+	succeeded   atomic.Uint64
+	failed      atomic.Uint64
+	stalled     atomic.Uint64
+	bytes       atomic.Uint64
+	rateLimited atomic.Uint64
+
+	mu       sync.Mutex
+	retries  map[string]uint64
+	duration histogram
+}
+
+// New returns a Metrics with all counters at zero.
+func New() *Metrics {
+	return &Metrics{
+		retries:  make(map[string]uint64),
+		duration: histogram{bounds: DurationBuckets, counts: make([]uint64, len(DurationBuckets))},
+	}
+}
+
+// HandleEvent records a download event. Downloads refused with 429 Too Many
+// Requests also count as rate limited.
+func (m *Metrics) HandleEvent(e download.Event) {
+	switch e.Type {
+	case download.EventStarted:
+		m.started.Add(1)
+	case download.EventRetryScheduled:
+		m.mu.Lock()
+		m.retries[e.Reason]++
+		m.mu.Unlock()
+	case download.EventStalled:
+		m.stalled.Add(1)
+	case download.EventCompleted, download.EventFailed:
+		if e.Type == download.EventFailed {
+			m.failed.Add(1)
+			var httpErr *download.HTTPError
+			if errors.As(e.Err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests {
+				m.RateLimited()
+			}
+		} else {
+			m.succeeded.Add(1)
+		}
+		if e.Downloaded > 0 {
+			m.bytes.Add(uint64(e.Downloaded))
+		}
+		m.mu.Lock()
+		m.duration.observe(e.Elapsed.Seconds())
+		m.mu.Unlock()
+	}
+}
+
+// RateLimited counts a request YouTube answered with 429 Too Many Requests.
+func (m *Metrics) RateLimited() {
+	m.rateLimited.Add(1)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+
+	writeCounter(cw, "ytdl_downloads_started_total", "Stream downloads started.", m.started.Load())
+	writeCounter(cw, "ytdl_downloads_succeeded_total", "Stream downloads that succeeded.", m.succeeded.Load())
+	writeCounter(cw, "ytdl_downloads_failed_total", "Stream downloads that failed.", m.failed.Load())
+	writeCounter(cw, "ytdl_download_stalls_total", "Times a stream transfer received nothing for the stall timeout.", m.stalled.Load())
+	writeCounter(cw, "ytdl_downloaded_bytes_total", "Bytes of streams written.", m.bytes.Load())
+	writeCounter(cw, "ytdl_rate_limited_total", "Requests answered with 429 Too Many Requests.", m.rateLimited.Load())
+
+	m.mu.Lock()
+	reasons := make([]string, 0, len(m.retries))
+	for reason := range m.retries {
+		reasons = append(reasons, reason)
+	}
+	slices.Sort(reasons)
+	_, _ = fmt.Fprintf(cw, "# HELP ytdl_download_retries_total Stream requests made again, by reason.\n# TYPE ytdl_download_retries_total counter\n")
+	for _, reason := range reasons {
+		_, _ = fmt.Fprintf(cw, "ytdl_download_retries_total{reason=%q} %d\n", reason, m.retries[reason])
+	}
+	m.duration.writeTo(cw, "ytdl_download_duration_seconds", "How long stream downloads took.")
+	m.mu.Unlock()
+
+	if cw.err == nil {
+		cw.err = bw.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP serves the metrics, for mounting at /metrics.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = m.WriteTo(w)
+}
+
+func writeCounter(w io.Writer, name, help string, value uint64) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+}
+
+// histogram counts observations into buckets with the given upper bounds.
+// counts are per bucket, not cumulative.
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	h.count++
+	h.sum += v
+	if i, _ := slices.BinarySearch(h.bounds, v); i < len(h.bounds) {
+		h.counts[i]++
+	}
+}
+
+func (h *histogram) writeTo(w io.Writer, name, help string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		_, _ = fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", name, formatFloat(bound), cumulative)
+	}
+	_, _ = fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	_, _ = fmt.Fprintf(w, "%s_sum %s\n%s_count %d\n", name, formatFloat(h.sum), name, h.count)
+}
+
+// formatFloat formats v as the exposition format expects.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// countingWriter counts the bytes written through it and keeps the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+)
+
+func TestMetrics_WriteTo(t *testing.T) {
+	m := New()
+	for _, e := range []download.Event{
+		{Type: download.EventStarted},
+		{Type: download.EventStarted},
+		{Type: download.EventStarted},
+		{Type: download.EventRetryScheduled, Reason: download.RetryResume},
+		{Type: download.EventRetryScheduled, Reason: download.RetryResume},
+		{Type: download.EventRetryScheduled, Reason: download.RetryRefresh},
+		{Type: download.EventStalled},
+		{Type: download.EventCompleted, Downloaded: 1000, Elapsed: 3 * time.Second},
+		{Type: download.EventFailed, Downloaded: 200, Elapsed: 90 * time.Second, Err: errors.New("connection reset")},
+		{Type: download.EventFailed, Err: fmt.Errorf("requesting missing range: %w", &download.HTTPError{StatusCode: http.StatusTooManyRequests})},
+	} {
+		m.HandleEvent(e)
+	}
+	m.RateLimited()
+
+	var b strings.Builder
+	n, err := m.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if n != int64(len(out)) {
+		t.Errorf("WriteTo() = %d, wrote %d bytes", n, len(out))
+	}
+
+	for _, want := range []string{
+		"# TYPE ytdl_downloads_started_total counter\nytdl_downloads_started_total 3\n",
+		"ytdl_downloads_succeeded_total 1\n",
+		"ytdl_downloads_failed_total 2\n",
+		"ytdl_download_stalls_total 1\n",
+		"ytdl_downloaded_bytes_total 1200\n",
+		"ytdl_rate_limited_total 2\n",
+		`ytdl_download_retries_total{reason="refresh"} 1` + "\n" + `ytdl_download_retries_total{reason="resume"} 2` + "\n",
+		"# TYPE ytdl_download_duration_seconds histogram\n",
+		`ytdl_download_duration_seconds_bucket{le="1"} 1` + "\n",
+		`ytdl_download_duration_seconds_bucket{le="5"} 2` + "\n",
+		`ytdl_download_duration_seconds_bucket{le="60"} 2` + "\n",
+		`ytdl_download_duration_seconds_bucket{le="120"} 3` + "\n",
+		`ytdl_download_duration_seconds_bucket{le="+Inf"} 3` + "\n",
+		"ytdl_download_duration_seconds_sum 93\nytdl_download_duration_seconds_count 3\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHistogram_ObservationsAboveBuckets(t *testing.T) {
+	h := histogram{bounds: []float64{1, 10}, counts: make([]uint64, 2)}
+	h.observe(1)
+	h.observe(100)
+
+	var b strings.Builder
+	h.writeTo(&b, "x", "help")
+	want := "# HELP x help\n# TYPE x histogram\n" +
+		`x_bucket{le="1"} 1` + "\n" + `x_bucket{le="10"} 1` + "\n" + `x_bucket{le="+Inf"} 2` + "\n" +
+		"x_sum 101\nx_count 2\n"
+	if b.String() != want {
+		t.Errorf("output = %q, want %q", b.String(), want)
+	}
+}
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	m := New()
+	m.HandleEvent(download.Event{Type: download.EventStarted})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != ContentType {
+		t.Errorf("got %d with Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "ytdl_downloads_started_total 1\n") {
+		t.Errorf("body = %q", rec.Body)
+	}
+}
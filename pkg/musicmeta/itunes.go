@@ -0,0 +1,75 @@
+package musicmeta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultITunesURL is the search endpoint ITunes queries by default.
+const DefaultITunesURL = "https://itunes.apple.com/search"
+
+// ITunes is a Provider that searches the songs of the iTunes Store with the
+// iTunes Search API. Its album titles add " - Single" or " - EP" to releases
+// that aren't albums.
+type ITunes struct {
+	// Client is the HTTP client used. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// BaseURL is the search endpoint to query. If empty, DefaultITunesURL is used.
+	BaseURL string
+}
+
+// iTunesSearch is the JSON response of a song search.
+type iTunesSearch struct {
+	Results []iTunesSong `json:"results"`
+}
+
+// iTunesSong is a song found by a search.
+type iTunesSong struct {
+	ArtistName      string `json:"artistName"`
+	TrackName       string `json:"trackName"`
+	CollectionName  string `json:"collectionName"`
+	ReleaseDate     string `json:"releaseDate"`
+	TrackNumber     int    `json:"trackNumber"`
+	TrackTimeMillis int64  `json:"trackTimeMillis"`
+}
+
+// Lookup searches for songs matching the artist and title and returns the
+// first one by the queried artist that matches.
+func (s *ITunes) Lookup(ctx context.Context, query Query) (*Track, error) {
+	base := s.BaseURL
+	if base == "" {
+		base = DefaultITunesURL
+	}
+	params := url.Values{
+		"term":   {query.Artist + " " + query.Title},
+		"media":  {"music"},
+		"entity": {"song"},
+		"limit":  {"10"},
+	}
+
+	var search iTunesSearch
+	if err := getJSON(ctx, s.Client, base+"?"+params.Encode(), &search); err != nil {
+		return nil, fmt.Errorf("searching iTunes: %w", err)
+	}
+
+	artist := normalize(query.Artist)
+	for _, song := range search.Results {
+		if !strings.Contains(normalize(song.ArtistName), artist) ||
+			!matches(query, song.TrackName, time.Duration(song.TrackTimeMillis)*time.Millisecond) {
+			continue
+		}
+		return &Track{
+			Artist:      song.ArtistName,
+			Title:       song.TrackName,
+			Album:       song.CollectionName,
+			Year:        parseYear(song.ReleaseDate),
+			TrackNumber: song.TrackNumber,
+		}, nil
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,49 @@
+package musicmeta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const iTunesResponse = `{"resultCount":3,"results":[
+	{"artistName":"Various Artists","trackName":"Never Gonna Give You Up","collectionName":"80s Hits","trackTimeMillis":213000},
+	{"artistName":"Rick Astley","trackName":"Never Gonna Give You Up (Cake Mix)","collectionName":"Cake Mixes","trackTimeMillis":355000},
+	{"artistName":"Rick Astley","trackName":"Never Gonna Give You Up","collectionName":"Whenever You Need Somebody","releaseDate":"1987-11-12T08:00:00Z","trackNumber":1,"trackTimeMillis":214000}
+]}`
+
+func TestITunes_Lookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("term") != "Rick Astley Never Gonna Give You Up" || q.Get("entity") != "song" {
+			t.Errorf("request = %s", r.URL)
+		}
+		_, _ = w.Write([]byte(iTunesResponse))
+	}))
+	defer server.Close()
+
+	s := &ITunes{Client: server.Client(), BaseURL: server.URL}
+	track, err := s.Lookup(context.Background(), Query{Artist: "Rick Astley", Title: "Never Gonna Give You Up", Duration: 213 * time.Second})
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	want := Track{Artist: "Rick Astley", Title: "Never Gonna Give You Up", Album: "Whenever You Need Somebody", Year: 1987, TrackNumber: 1}
+	if *track != want {
+		t.Errorf("Lookup() = %+v, want %+v", *track, want)
+	}
+}
+
+func TestITunes_LookupNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"resultCount":0,"results":[]}`))
+	}))
+	defer server.Close()
+
+	s := &ITunes{Client: server.Client(), BaseURL: server.URL}
+	if _, err := s.Lookup(context.Background(), Query{Artist: "Nobody", Title: "Nothing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup() error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,171 @@
+package musicmeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMusicBrainzURL is the MusicBrainz web service MusicBrainz queries by default.
+const DefaultMusicBrainzURL = "https://musicbrainz.org/ws/2"
+
+// minMusicBrainzScore is the lowest search score, out of 100, a recording can
+// have to match. Lower scores are recordings that only share a word or two.
+const minMusicBrainzScore = 80
+
+// MusicBrainz is a Provider that searches the recordings of MusicBrainz, the
+// open music encyclopedia. Its API allows about one request per second.
+type MusicBrainz struct {
+	// Client is the HTTP client used. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// BaseURL is the web service to query. If empty, DefaultMusicBrainzURL is used.
+	BaseURL string
+}
+
+// musicBrainzSearch is the JSON response of a recording search.
+type musicBrainzSearch struct {
+	Recordings []musicBrainzRecording `json:"recordings"`
+}
+
+// musicBrainzRecording is a recording found by a search.
+type musicBrainzRecording struct {
+	Score            int                  `json:"score"`
+	Title            string               `json:"title"`
+	Length           int64                `json:"length"` // milliseconds
+	FirstReleaseDate string               `json:"first-release-date"`
+	ArtistCredit     []musicBrainzCredit  `json:"artist-credit"`
+	Releases         []musicBrainzRelease `json:"releases"`
+}
+
+// musicBrainzCredit is one artist of a recording's artist credit.
+type musicBrainzCredit struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+}
+
+// musicBrainzRelease is a release a recording appears on.
+type musicBrainzRelease struct {
+	Title        string `json:"title"`
+	Status       string `json:"status"`
+	Date         string `json:"date"`
+	ReleaseGroup struct {
+		PrimaryType string `json:"primary-type"`
+	} `json:"release-group"`
+	Media []struct {
+		Track []struct {
+			Number string `json:"number"`
+		} `json:"track"`
+	} `json:"media"`
+}
+
+// Lookup searches for recordings of the queried title by the queried artist
+// and returns the best scored one that matches, with the album it was first
+// released on.
+func (m *MusicBrainz) Lookup(ctx context.Context, query Query) (*Track, error) {
+	base := m.BaseURL
+	if base == "" {
+		base = DefaultMusicBrainzURL
+	}
+	params := url.Values{
+		"query": {fmt.Sprintf("recording:%s AND artist:%s", luceneQuote(query.Title), luceneQuote(query.Artist))},
+		"fmt":   {"json"},
+		"limit": {"10"},
+	}
+
+	var search musicBrainzSearch
+	if err := getJSON(ctx, m.Client, strings.TrimSuffix(base, "/")+"/recording?"+params.Encode(), &search); err != nil {
+		return nil, fmt.Errorf("searching MusicBrainz: %w", err)
+	}
+
+	for _, recording := range search.Recordings {
+		if recording.Score < minMusicBrainzScore || !matches(query, recording.Title, time.Duration(recording.Length)*time.Millisecond) {
+			continue
+		}
+		track := &Track{Artist: recording.artist(), Title: recording.Title, Year: parseYear(recording.FirstReleaseDate)}
+		if release := recording.album(); release != nil {
+			track.Album = release.Title
+			if track.Year == 0 {
+				track.Year = parseYear(release.Date)
+			}
+			if len(release.Media) > 0 && len(release.Media[0].Track) > 0 {
+				track.TrackNumber, _ = strconv.Atoi(release.Media[0].Track[0].Number)
+			}
+		}
+		return track, nil
+	}
+	return nil, ErrNotFound
+}
+
+// artist joins the recording's artist credit, such as "Artist feat. Other".
+func (r *musicBrainzRecording) artist() string {
+	var sb strings.Builder
+	for _, credit := range r.ArtistCredit {
+		sb.WriteString(credit.Name)
+		sb.WriteString(credit.JoinPhrase)
+	}
+	return sb.String()
+}
+
+// album returns the release to tag the recording with: the earliest official
+// album it is on, or else its earliest official release of any kind, such as
+// a single. Compilations and unofficial releases are only used if there is
+// nothing else.
+func (r *musicBrainzRecording) album() *musicBrainzRelease {
+	var best *musicBrainzRelease
+	rank := func(release *musicBrainzRelease) int {
+		switch {
+		case release.Status != "Official":
+			return 0
+		case release.ReleaseGroup.PrimaryType == "Album":
+			return 2
+		default:
+			return 1
+		}
+	}
+	for i := range r.Releases {
+		release := &r.Releases[i]
+		if best == nil || rank(release) > rank(best) ||
+			(rank(release) == rank(best) && release.Date != "" && (best.Date == "" || release.Date < best.Date)) {
+			best = release
+		}
+	}
+	return best
+}
+
+// luceneQuote quotes s as a phrase in a Lucene search query.
+func luceneQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// getJSON fetches rawURL and decodes its JSON response into v. Responses other
+// than 200 OK are errors.
+func getJSON(ctx context.Context, client *http.Client, rawURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package musicmeta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const musicBrainzResponse = `{"recordings":[
+	{"score":100,"title":"Never Gonna Give You Up (live)","length":260000,"artist-credit":[{"name":"Rick Astley"}]},
+	{"score":98,"title":"Never Gonna Give You Up","length":213000,"first-release-date":"1987-07-27",
+	 "artist-credit":[{"name":"Rick Astley","joinphrase":""}],
+	 "releases":[
+		{"title":"Now That's What I Call Music 10","status":"Official","date":"1987-11-30","release-group":{"primary-type":"Album"},"media":[{"track":[{"number":"4"}]}]},
+		{"title":"Never Gonna Give You Up","status":"Official","date":"1987-07-27","release-group":{"primary-type":"Single"},"media":[{"track":[{"number":"A"}]}]},
+		{"title":"Whenever You Need Somebody","status":"Official","date":"1987-11-16","release-group":{"primary-type":"Album"},"media":[{"track":[{"number":"1"}]}]}
+	 ]}
+]}`
+
+func TestMusicBrainz_Lookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/recording" || r.URL.Query().Get("fmt") != "json" {
+			t.Errorf("request = %s", r.URL)
+		}
+		if got := r.URL.Query().Get("query"); got != `recording:"Never Gonna Give You Up" AND artist:"Rick \"The Rick\" Astley"` {
+			t.Errorf("query = %q", got)
+		}
+		if !strings.Contains(r.UserAgent(), "golang-youtube-downloader") {
+			t.Errorf("User-Agent = %q", r.UserAgent())
+		}
+		_, _ = w.Write([]byte(musicBrainzResponse))
+	}))
+	defer server.Close()
+
+	m := &MusicBrainz{Client: server.Client(), BaseURL: server.URL}
+	query := Query{Artist: `Rick "The Rick" Astley`, Title: "Never Gonna Give You Up", Duration: 212 * time.Second}
+	track, err := m.Lookup(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	want := Track{Artist: "Rick Astley", Title: "Never Gonna Give You Up", Album: "Whenever You Need Somebody", Year: 1987, TrackNumber: 1}
+	if *track != want {
+		t.Errorf("Lookup() = %+v, want %+v", *track, want)
+	}
+}
+
+func TestMusicBrainz_LookupNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"recordings":[{"score":40,"title":"Never Gonna Give You Up"}]}`))
+	}))
+	defer server.Close()
+
+	m := &MusicBrainz{Client: server.Client(), BaseURL: server.URL}
+	if _, err := m.Lookup(context.Background(), Query{Artist: "Rick Astley", Title: "Never Gonna Give You Up"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMusicBrainz_LookupHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	m := &MusicBrainz{Client: server.Client(), BaseURL: server.URL}
+	_, err := m.Lookup(context.Background(), Query{Artist: "Rick Astley", Title: "Never Gonna Give You Up"})
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Lookup() error = %v, want the status code", err)
+	}
+}
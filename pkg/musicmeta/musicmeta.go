@@ -0,0 +1,154 @@
+// Package musicmeta looks up the canonical artist, album and track metadata of
+// songs downloaded from YouTube in music databases such as MusicBrainz and the
+// iTunes Search API.
+package musicmeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+var (
+	// ErrNotFound is returned by a Provider when no track matches the query.
+	ErrNotFound = errors.New("no matching track found")
+
+	// ErrUnknownProvider is returned by Open for a provider name it doesn't know.
+	ErrUnknownProvider = errors.New("unknown music metadata provider")
+)
+
+// Providers lists the provider names Open accepts.
+var Providers = []string{"musicbrainz", "itunes"}
+
+// userAgent identifies requests to the music databases, which MusicBrainz
+// requires of every client.
+const userAgent = "golang-youtube-downloader ( https://github.com/SakuraBurst/golang-youtube-downloader )"
+
+// durationTolerance is how far a track's length may be from the video's for the
+// track to match, which tells a song apart from a live version or a remix.
+const durationTolerance = 15 * time.Second
+
+// Query identifies a song to look up.
+type Query struct {
+	// Artist is the performing artist.
+	Artist string
+
+	// Title is the song title.
+	Title string
+
+	// Duration is the length of the recording, or zero if unknown. Tracks much
+	// longer or shorter don't match.
+	Duration time.Duration
+}
+
+// Track is the canonical metadata of a song.
+type Track struct {
+	// Artist is the credited artist, including featured artists.
+	Artist string
+
+	// Title is the track title.
+	Title string
+
+	// Album is the title of the album or single the track was released on.
+	Album string
+
+	// Year is the year the track was released, or zero if unknown.
+	Year int
+
+	// TrackNumber is the position of the track on the album, or zero if unknown.
+	TrackNumber int
+}
+
+// Provider looks up tracks in a music database.
+type Provider interface {
+	// Lookup returns the best match for the query, or ErrNotFound.
+	Lookup(ctx context.Context, query Query) (*Track, error)
+}
+
+// Open returns the provider with the given name, one of Providers. Requests go
+// through client; if nil, http.DefaultClient is used.
+func Open(name string, client *http.Client) (Provider, error) {
+	switch strings.ToLower(name) {
+	case "musicbrainz":
+		return &MusicBrainz{Client: client}, nil
+	case "itunes":
+		return &ITunes{Client: client}, nil
+	default:
+		return nil, fmt.Errorf("%w %q: must be one of %s", ErrUnknownProvider, name, strings.Join(Providers, ", "))
+	}
+}
+
+// titleSeparators split "Artist - Title" video titles, with the dashes
+// uploaders use in place of a hyphen.
+var titleSeparators = []string{" - ", " – ", " — ", " | "}
+
+// titleNoise matches the bracketed notes added to music video titles, such as
+// "(Official Music Video)" or "[Lyrics]", which aren't part of the song title.
+var titleNoise = regexp.MustCompile(`(?i)\s*[(\[](?:[^)\]]*\b(?:official|lyrics?|audio|video|visuali[sz]er|hd|hq|4k|mv|m/v)\b[^)\]]*)[)\]]`)
+
+// topicSuffix ends the names of the channels YouTube generates for artists,
+// whose uploads are titled with just the song title.
+const topicSuffix = " - Topic"
+
+// QueryFromVideo builds the query for a music video from its title, which is
+// usually "Artist - Title", or from the channel name for the artist channels
+// YouTube generates ("Artist - Topic"). It reports false if neither gives an
+// artist.
+func QueryFromVideo(video *youtube.Video) (Query, bool) {
+	title := strings.TrimSpace(titleNoise.ReplaceAllString(video.Title, ""))
+	query := Query{Duration: video.Duration}
+
+	if artist, ok := strings.CutSuffix(video.Author.Name, topicSuffix); ok && title != "" {
+		query.Artist, query.Title = strings.TrimSpace(artist), title
+		return query, query.Artist != ""
+	}
+	for _, sep := range titleSeparators {
+		if artist, song, ok := strings.Cut(title, sep); ok {
+			query.Artist, query.Title = strings.TrimSpace(artist), strings.Trim(strings.TrimSpace(song), `"'`)
+			return query, query.Artist != "" && query.Title != ""
+		}
+	}
+	return Query{}, false
+}
+
+// matches reports whether a track found for the query is the same song: its
+// title has to contain the queried title, ignoring case and punctuation, and
+// its length has to be close to the query's when both are known.
+func matches(query Query, title string, length time.Duration) bool {
+	if !strings.Contains(normalize(title), normalize(query.Title)) {
+		return false
+	}
+	if query.Duration > 0 && length > 0 {
+		diff := query.Duration - length
+		return diff <= durationTolerance && diff >= -durationTolerance
+	}
+	return true
+}
+
+// normalize lowercases s and keeps only its words of letters and digits, so
+// titles compare equal regardless of punctuation.
+func normalize(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// parseYear returns the year a "2009-10-25"-style date starts with, or zero.
+func parseYear(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(date[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
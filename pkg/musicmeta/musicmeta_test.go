@@ -0,0 +1,67 @@
+package musicmeta
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+func TestQueryFromVideo(t *testing.T) {
+	tests := []struct {
+		title, channel string
+		want           Query
+		ok             bool
+	}{
+		{"Rick Astley - Never Gonna Give You Up (Official Music Video)", "Rick Astley", Query{Artist: "Rick Astley", Title: "Never Gonna Give You Up"}, true},
+		{"Daft Punk – Get Lucky [Official Audio] (feat. Pharrell Williams)", "Daft Punk", Query{Artist: "Daft Punk", Title: "Get Lucky (feat. Pharrell Williams)"}, true},
+		{`Queen - "Bohemian Rhapsody" [HD]`, "Queen Official", Query{Artist: "Queen", Title: "Bohemian Rhapsody"}, true},
+		{"Blinding Lights", "The Weeknd - Topic", Query{Artist: "The Weeknd", Title: "Blinding Lights"}, true},
+		{"My vacation vlog", "Some Channel", Query{}, false},
+		{" - Untitled", "Some Channel", Query{}, false},
+	}
+	for _, tt := range tests {
+		video := &youtube.Video{Title: tt.title, Author: youtube.Author{Name: tt.channel}, Duration: 3 * time.Minute}
+		got, ok := QueryFromVideo(video)
+		if tt.ok {
+			tt.want.Duration = video.Duration
+		}
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("QueryFromVideo(%q, %q) = %+v, %v, want %+v, %v", tt.title, tt.channel, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	query := Query{Artist: "Rick Astley", Title: "Never Gonna Give You Up", Duration: 213 * time.Second}
+	tests := []struct {
+		title  string
+		length time.Duration
+		want   bool
+	}{
+		{"Never Gonna Give You Up", 212 * time.Second, true},
+		{"never gonna give you up!", 0, true},
+		{"Never Gonna Give You Up (Extended Mix)", 6 * time.Minute, false},
+		{"Together Forever", 213 * time.Second, false},
+	}
+	for _, tt := range tests {
+		if got := matches(query, tt.title, tt.length); got != tt.want {
+			t.Errorf("matches(%q, %v) = %v, want %v", tt.title, tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	for _, name := range Providers {
+		if _, err := Open(name, nil); err != nil {
+			t.Errorf("Open(%q) error = %v", name, err)
+		}
+	}
+	if p, _ := Open("MusicBrainz", nil); p == nil {
+		t.Error("Open() names aren't case-insensitive")
+	}
+	if _, err := Open("spotify", nil); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Open(\"spotify\") error = %v, want ErrUnknownProvider", err)
+	}
+}
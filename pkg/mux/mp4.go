@@ -0,0 +1,603 @@
+package mux
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// Flags in the tfhd box.
+const (
+	tfhdBaseDataOffsetPresent = 0x000001
+)
+
+// box describes an ISO BMFF box within a file or buffer.
+type box struct {
+	typ        string
+	offset     int64 // start of the box header
+	headerSize int64
+	size       int64 // total size including the header
+}
+
+func (b box) end() int64 {
+	return b.offset + b.size
+}
+
+// payload returns the payload of b within data.
+func (b box) payload(data []byte) []byte {
+	return data[b.offset+b.headerSize : b.end()]
+}
+
+// readBoxHeader reads the header of the box starting at off.
+// end is the end of the enclosing region, used for boxes that extend to the end.
+func readBoxHeader(r io.ReaderAt, off, end int64) (box, error) {
+	if end-off < 8 {
+		return box{}, fmt.Errorf("truncated box header at offset %d", off)
+	}
+
+	var hdr [16]byte
+	if _, err := r.ReadAt(hdr[:8], off); err != nil {
+		return box{}, fmt.Errorf("reading box header: %w", err)
+	}
+
+	b := box{typ: string(hdr[4:8]), offset: off, headerSize: 8}
+	size := int64(binary.BigEndian.Uint32(hdr[:4]))
+	switch size {
+	case 0:
+		// Box extends to the end of the enclosing region
+		size = end - off
+	case 1:
+		if _, err := r.ReadAt(hdr[8:16], off+8); err != nil {
+			return box{}, fmt.Errorf("reading box header: %w", err)
+		}
+		size = int64(binary.BigEndian.Uint64(hdr[8:16]))
+		b.headerSize = 16
+	}
+	if size < b.headerSize || size > end-off {
+		return box{}, fmt.Errorf("invalid %q box size %d at offset %d", b.typ, size, off)
+	}
+	b.size = size
+	return b, nil
+}
+
+// childBoxes parses the boxes contained in data.
+func childBoxes(data []byte) ([]box, error) {
+	r := bytes.NewReader(data)
+	end := int64(len(data))
+
+	var boxes []box
+	for off := int64(0); off < end; {
+		b, err := readBoxHeader(r, off, end)
+		if err != nil {
+			return nil, err
+		}
+		boxes = append(boxes, b)
+		off = b.end()
+	}
+	return boxes, nil
+}
+
+// findBox returns the payload of the first box at path within data.
+// The returned slice aliases data, so it can be patched in place.
+func findBox(data []byte, path ...string) ([]byte, bool) {
+	for _, typ := range path {
+		children, err := childBoxes(data)
+		if err != nil {
+			return nil, false
+		}
+		found := false
+		for _, b := range children {
+			if b.typ == typ {
+				data = b.payload(data)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// boxPayload returns the payload of a full box held in b.
+func boxPayload(b []byte) []byte {
+	if binary.BigEndian.Uint32(b) == 1 {
+		return b[16:]
+	}
+	return b[8:]
+}
+
+// makeBox builds a box from its type and payload parts.
+func makeBox(typ string, payload ...[]byte) []byte {
+	size := 8
+	for _, p := range payload {
+		size += len(p)
+	}
+	buf := make([]byte, 8, size)
+	binary.BigEndian.PutUint32(buf, uint32(size))
+	copy(buf[4:], typ)
+	for _, p := range payload {
+		buf = append(buf, p...)
+	}
+	return buf
+}
+
+// fullBoxField returns the offset of a field within a full box payload,
+// which differs between version 0 and version 1 boxes.
+func fullBoxField(payload []byte, v0, v1, width0, width1 int) (offset, width int, ok bool) {
+	if len(payload) < 4 {
+		return 0, 0, false
+	}
+	offset, width = v0, width0
+	if payload[0] == 1 {
+		offset, width = v1, width1
+	}
+	return offset, width, len(payload) >= offset+width
+}
+
+// mp4Input is a parsed fragmented MP4 input with a single track.
+type mp4Input struct {
+	file *os.File
+
+	// moov is the payload of the moov box.
+	moov []byte
+	// trak and trex are the full boxes describing the track.
+	trak, trex []byte
+
+	trackID        uint32
+	timescale      uint32 // media timescale from mdhd
+	movieTimescale uint32 // movie timescale from mvhd
+
+	// outputTrackID is the track ID used in the muxed output.
+	outputTrackID uint32
+
+	fragments []mp4Fragment
+}
+
+// mp4Fragment is a moof box followed by its media data.
+type mp4Fragment struct {
+	input      *mp4Input
+	moofOffset int64
+	moofSize   int64
+	mdatSize   int64 // total size of the mdat boxes directly following the moof
+	decodeTime uint64
+}
+
+// time returns the fragment's decode time.
+func (f *mp4Fragment) time() time.Duration {
+	return time.Duration(float64(f.decodeTime) / float64(f.input.timescale) * float64(time.Second))
+}
+
+// openMP4 opens and indexes a fragmented MP4 file.
+func openMP4(path string) (*mp4Input, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening input: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("reading input: %w", err)
+	}
+
+	in := &mp4Input{file: file}
+	if err := in.index(info.Size()); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return in, nil
+}
+
+// index reads the top-level boxes of the file.
+func (in *mp4Input) index(size int64) error {
+	for off := int64(0); off < size; {
+		b, err := readBoxHeader(in.file, off, size)
+		if err != nil {
+			return err
+		}
+
+		switch b.typ {
+		case "moov":
+			data := make([]byte, b.size)
+			if _, err := in.file.ReadAt(data, b.offset); err != nil {
+				return fmt.Errorf("reading moov: %w", err)
+			}
+			in.moov = data[b.headerSize:]
+			if err := in.parseMoov(); err != nil {
+				return err
+			}
+
+		case "moof":
+			if in.moov == nil {
+				return fmt.Errorf("%w: moof before moov", ErrUnsupported)
+			}
+			if err := in.addFragment(b); err != nil {
+				return err
+			}
+
+		case "mdat":
+			n := len(in.fragments)
+			if n == 0 {
+				return fmt.Errorf("%w: not a fragmented MP4", ErrUnsupported)
+			}
+			last := &in.fragments[n-1]
+			if last.moofOffset+last.moofSize+last.mdatSize != b.offset {
+				return fmt.Errorf("%w: media data is not adjacent to its fragment", ErrUnsupported)
+			}
+			last.mdatSize += b.size
+		}
+
+		off = b.end()
+	}
+
+	if in.moov == nil {
+		return fmt.Errorf("%w: missing moov box", ErrUnsupported)
+	}
+	if len(in.fragments) == 0 {
+		return fmt.Errorf("%w: no movie fragments", ErrUnsupported)
+	}
+	return nil
+}
+
+// parseMoov extracts the single track and its defaults from the moov box.
+func (in *mp4Input) parseMoov() error {
+	children, err := childBoxes(in.moov)
+	if err != nil {
+		return err
+	}
+
+	var traks, trexes [][]byte
+	hasMvex := false
+	for _, b := range children {
+		switch b.typ {
+		case "mvhd":
+			payload := b.payload(in.moov)
+			off, _, ok := fullBoxField(payload, 12, 20, 4, 4)
+			if !ok {
+				return errors.New("invalid mvhd box")
+			}
+			in.movieTimescale = binary.BigEndian.Uint32(payload[off:])
+		case "trak":
+			traks = append(traks, in.moov[b.offset:b.end()])
+		case "mvex":
+			hasMvex = true
+			mvex := b.payload(in.moov)
+			mvexChildren, err := childBoxes(mvex)
+			if err != nil {
+				return err
+			}
+			for _, c := range mvexChildren {
+				if c.typ == "trex" {
+					trexes = append(trexes, mvex[c.offset:c.end()])
+				}
+			}
+		}
+	}
+
+	if !hasMvex {
+		return fmt.Errorf("%w: not a fragmented MP4", ErrUnsupported)
+	}
+	if len(traks) != 1 || len(trexes) != 1 {
+		return fmt.Errorf("%w: expected a single track, found %d", ErrUnsupported, len(traks))
+	}
+	in.trak, in.trex = traks[0], trexes[0]
+
+	tkhd, ok := findBox(boxPayload(in.trak), "tkhd")
+	if !ok {
+		return errors.New("missing tkhd box")
+	}
+	off, _, ok := fullBoxField(tkhd, 12, 20, 4, 4)
+	if !ok {
+		return errors.New("invalid tkhd box")
+	}
+	in.trackID = binary.BigEndian.Uint32(tkhd[off:])
+
+	mdhd, ok := findBox(boxPayload(in.trak), "mdia", "mdhd")
+	if !ok {
+		return errors.New("missing mdhd box")
+	}
+	off, _, ok = fullBoxField(mdhd, 12, 20, 4, 4)
+	if !ok {
+		return errors.New("invalid mdhd box")
+	}
+	in.timescale = binary.BigEndian.Uint32(mdhd[off:])
+	if in.timescale == 0 || in.movieTimescale == 0 {
+		return errors.New("invalid timescale")
+	}
+	return nil
+}
+
+// addFragment reads the moof box b and records it as a fragment.
+func (in *mp4Input) addFragment(b box) error {
+	moof := make([]byte, b.size)
+	if _, err := in.file.ReadAt(moof, b.offset); err != nil {
+		return fmt.Errorf("reading moof: %w", err)
+	}
+
+	trafs, err := trafBoxes(moof)
+	if err != nil {
+		return err
+	}
+	if len(trafs) != 1 {
+		return fmt.Errorf("%w: fragment with %d track runs", ErrUnsupported, len(trafs))
+	}
+
+	tfhd, ok := findBox(trafs[0], "tfhd")
+	if !ok || len(tfhd) < 8 {
+		return errors.New("invalid tfhd box")
+	}
+	flags := uint32(tfhd[1])<<16 | uint32(tfhd[2])<<8 | uint32(tfhd[3])
+	if flags&tfhdBaseDataOffsetPresent != 0 {
+		// Absolute data offsets would need rebasing in the output
+		return fmt.Errorf("%w: explicit base data offsets", ErrUnsupported)
+	}
+	if id := binary.BigEndian.Uint32(tfhd[4:]); id != in.trackID {
+		return fmt.Errorf("fragment references unknown track %d", id)
+	}
+
+	tfdt, ok := findBox(trafs[0], "tfdt")
+	if !ok {
+		return fmt.Errorf("%w: fragment without decode time", ErrUnsupported)
+	}
+	off, width, ok := fullBoxField(tfdt, 4, 4, 4, 8)
+	if !ok {
+		return errors.New("invalid tfdt box")
+	}
+	var decodeTime uint64
+	if width == 8 {
+		decodeTime = binary.BigEndian.Uint64(tfdt[off:])
+	} else {
+		decodeTime = uint64(binary.BigEndian.Uint32(tfdt[off:]))
+	}
+
+	in.fragments = append(in.fragments, mp4Fragment{
+		input:      in,
+		moofOffset: b.offset,
+		moofSize:   b.size,
+		decodeTime: decodeTime,
+	})
+	return nil
+}
+
+// trafBoxes returns the payloads of the traf boxes in a full moof box.
+func trafBoxes(moof []byte) ([][]byte, error) {
+	payload := boxPayload(moof)
+	children, err := childBoxes(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var trafs [][]byte
+	for _, b := range children {
+		if b.typ == "traf" {
+			trafs = append(trafs, b.payload(payload))
+		}
+	}
+	return trafs, nil
+}
+
+// patchMoof rewrites the sequence number and track ID of a full moof box in place.
+// Sizes are unchanged, so data offsets relative to the moof stay valid.
+func patchMoof(moof []byte, sequence, trackID uint32) error {
+	payload := boxPayload(moof)
+
+	mfhd, ok := findBox(payload, "mfhd")
+	if !ok || len(mfhd) < 8 {
+		return errors.New("invalid mfhd box")
+	}
+	binary.BigEndian.PutUint32(mfhd[4:], sequence)
+
+	trafs, err := trafBoxes(moof)
+	if err != nil {
+		return err
+	}
+	for _, traf := range trafs {
+		tfhd, ok := findBox(traf, "tfhd")
+		if !ok || len(tfhd) < 8 {
+			return errors.New("invalid tfhd box")
+		}
+		binary.BigEndian.PutUint32(tfhd[4:], trackID)
+	}
+	return nil
+}
+
+// outputTrak returns a copy of the input's trak box with the output track ID,
+// with movie-timescale durations converted to movieTimescale.
+func (in *mp4Input) outputTrak(movieTimescale uint32) []byte {
+	trak := bytes.Clone(in.trak)
+
+	if tkhd, ok := findBox(boxPayload(trak), "tkhd"); ok {
+		if off, _, ok := fullBoxField(tkhd, 12, 20, 4, 4); ok {
+			binary.BigEndian.PutUint32(tkhd[off:], in.outputTrackID)
+		}
+		if in.movieTimescale != movieTimescale {
+			if off, width, ok := fullBoxField(tkhd, 20, 28, 4, 8); ok {
+				rescaleField(tkhd[off:off+width], in.movieTimescale, movieTimescale)
+			}
+		}
+	}
+
+	// Edit list segment durations are in the movie timescale too
+	if elst, ok := findBox(boxPayload(trak), "edts", "elst"); ok && in.movieTimescale != movieTimescale && len(elst) >= 8 {
+		width, entrySize := 4, 12
+		if elst[0] == 1 {
+			width, entrySize = 8, 20
+		}
+		count := int(binary.BigEndian.Uint32(elst[4:]))
+		for i := 0; i < count && 8+(i+1)*entrySize <= len(elst); i++ {
+			off := 8 + i*entrySize
+			rescaleField(elst[off:off+width], in.movieTimescale, movieTimescale)
+		}
+	}
+	return trak
+}
+
+// outputTrex returns a copy of the input's trex box with the output track ID.
+func (in *mp4Input) outputTrex() []byte {
+	trex := bytes.Clone(in.trex)
+	if len(trex) >= 16 {
+		binary.BigEndian.PutUint32(trex[12:], in.outputTrackID)
+	}
+	return trex
+}
+
+// rescaleField converts a big-endian 4 or 8 byte duration between timescales in place.
+func rescaleField(field []byte, from, to uint32) {
+	if len(field) == 8 {
+		v := binary.BigEndian.Uint64(field)
+		if v != math.MaxUint64 {
+			binary.BigEndian.PutUint64(field, uint64(float64(v)*float64(to)/float64(from)))
+		}
+		return
+	}
+
+	v := binary.BigEndian.Uint32(field)
+	if v == math.MaxUint32 {
+		return
+	}
+	scaled := float64(v) * float64(to) / float64(from)
+	if scaled > math.MaxUint32-1 {
+		scaled = math.MaxUint32 - 1
+	}
+	binary.BigEndian.PutUint32(field, uint32(scaled))
+}
+
+func (in *mp4Input) close() {
+	_ = in.file.Close()
+}
+
+// buildMoov builds the output moov box with the video track followed by the audio track.
+// Other top-level moov children (udta, pssh, ...) are taken from the video input.
+func buildMoov(video, audio *mp4Input) ([]byte, error) {
+	children, err := childBoxes(video.moov)
+	if err != nil {
+		return nil, err
+	}
+
+	var mvhd []byte
+	var extra [][]byte
+	for _, b := range children {
+		switch b.typ {
+		case "mvhd":
+			mvhd = makeBox("mvhd", bytes.Clone(b.payload(video.moov)))
+		case "trak", "mvex":
+		default:
+			extra = append(extra, video.moov[b.offset:b.end()])
+		}
+	}
+	if len(mvhd) < 12 {
+		return nil, errors.New("missing mvhd box")
+	}
+	// next_track_ID is the last field of mvhd
+	binary.BigEndian.PutUint32(mvhd[len(mvhd)-4:], audio.outputTrackID+1)
+
+	movieTimescale := video.movieTimescale
+	parts := [][]byte{
+		mvhd,
+		video.outputTrak(movieTimescale),
+		audio.outputTrak(movieTimescale),
+		makeBox("mvex", video.outputTrex(), audio.outputTrex()),
+	}
+	return makeBox("moov", append(parts, extra...)...), nil
+}
+
+// mp4FileType is the ftyp box written to muxed MP4 files.
+var mp4FileType = makeBox("ftyp",
+	[]byte("isom"),
+	[]byte{0, 0, 0x02, 0x00}, // minor version
+	[]byte("isomiso6iso2mp41"),
+)
+
+// mergeFragments interleaves the fragments of two inputs by decode time.
+func mergeFragments(a, b []mp4Fragment) []*mp4Fragment {
+	merged := make([]*mp4Fragment, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if j >= len(b) || (i < len(a) && a[i].time() <= b[j].time()) {
+			merged = append(merged, &a[i])
+			i++
+		} else {
+			merged = append(merged, &b[j])
+			j++
+		}
+	}
+	return merged
+}
+
+// muxMP4 muxes a fragmented MP4 video and audio into a fragmented MP4 output.
+func muxMP4(ctx context.Context, videoPath, audioPath, outputPath string, progress ProgressCallback) error {
+	video, err := openMP4(videoPath)
+	if err != nil {
+		return err
+	}
+	defer video.close()
+
+	audio, err := openMP4(audioPath)
+	if err != nil {
+		return err
+	}
+	defer audio.close()
+
+	video.outputTrackID, audio.outputTrackID = 1, 2
+
+	moov, err := buildMoov(video, audio)
+	if err != nil {
+		return err
+	}
+
+	out, err := createOutput(outputPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+
+	w := bufio.NewWriterSize(out, 1<<20)
+	if _, err := w.Write(mp4FileType); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	if _, err := w.Write(moov); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+
+	for i, frag := range mergeFragments(video.fragments, audio.fragments) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		moof := make([]byte, frag.moofSize)
+		if _, err := frag.input.file.ReadAt(moof, frag.moofOffset); err != nil {
+			return fmt.Errorf("reading moof: %w", err)
+		}
+		if err := patchMoof(moof, uint32(i+1), frag.input.outputTrackID); err != nil {
+			return err
+		}
+		if _, err := w.Write(moof); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+
+		mdat := io.NewSectionReader(frag.input.file, frag.moofOffset+frag.moofSize, frag.mdatSize)
+		if _, err := io.Copy(w, mdat); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+
+		if progress != nil {
+			progress(frag.time())
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("closing output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package mux
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fullBox builds a full box with the given version and flags.
+func fullBox(typ string, version byte, flags uint32, payload ...[]byte) []byte {
+	vf := []byte{version, byte(flags >> 16), byte(flags >> 8), byte(flags)}
+	return makeBox(typ, append([][]byte{vf}, payload...)...)
+}
+
+func be32(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+func be64(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
+
+// testMP4 describes a synthetic fragmented MP4 with one track.
+type testMP4 struct {
+	trackID        uint32
+	timescale      uint32
+	movieTimescale uint32
+	// fragments holds the media data of each one second fragment.
+	fragments [][]byte
+	// startOffset shifts the decode time of every fragment, in seconds.
+	startOffset uint64
+}
+
+// bytes encodes the synthetic file.
+func (m testMP4) bytes() []byte {
+	mvhd := fullBox("mvhd", 0, 0,
+		be32(0), be32(0), be32(m.movieTimescale), be32(0), // times, timescale, duration
+		make([]byte, 76), // rate, volume, reserved, matrix, pre_defined
+		be32(m.trackID+1),
+	)
+	tkhd := fullBox("tkhd", 0, 3,
+		be32(0), be32(0), be32(m.trackID), be32(0), be32(m.movieTimescale*10),
+		make([]byte, 60),
+	)
+	mdhd := fullBox("mdhd", 0, 0, be32(0), be32(0), be32(m.timescale), be32(0), make([]byte, 4))
+	elst := fullBox("elst", 0, 0, be32(1), be32(m.movieTimescale*10), be32(0), be32(0x00010000))
+	trak := makeBox("trak", tkhd, makeBox("edts", elst), makeBox("mdia", mdhd))
+	trex := fullBox("trex", 0, 0, be32(m.trackID), be32(1), be32(0), be32(0), be32(0))
+	moov := makeBox("moov", mvhd, trak, makeBox("mvex", trex), makeBox("udta", []byte("meta")))
+
+	var buf bytes.Buffer
+	buf.Write(makeBox("ftyp", []byte("dash"), be32(0), []byte("iso6mp41")))
+	buf.Write(moov)
+	buf.Write(makeBox("sidx", make([]byte, 24)))
+	for i, data := range m.fragments {
+		moof := makeBox("moof",
+			fullBox("mfhd", 0, 0, be32(uint32(i+1))),
+			makeBox("traf",
+				fullBox("tfhd", 0, 0x020000, be32(m.trackID)),
+				fullBox("tfdt", 1, 0, be64((m.startOffset+uint64(i))*uint64(m.timescale))),
+				fullBox("trun", 0, 0x000001, be32(1), be32(0)),
+			),
+		)
+		buf.Write(moof)
+		buf.Write(makeBox("mdat", data))
+	}
+	return buf.Bytes()
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// topLevelBoxes parses the top-level boxes of data.
+func topLevelBoxes(t *testing.T, data []byte) []box {
+	t.Helper()
+	boxes, err := childBoxes(data)
+	if err != nil {
+		t.Fatalf("parsing output: %v", err)
+	}
+	return boxes
+}
+
+func TestMuxMP4(t *testing.T) {
+	video := testMP4{
+		trackID: 1, timescale: 90000, movieTimescale: 1000,
+		fragments: [][]byte{[]byte("video0"), []byte("video1"), []byte("video2")},
+	}
+	audio := testMP4{
+		trackID: 1, timescale: 44100, movieTimescale: 44100,
+		fragments: [][]byte{[]byte("audio0"), []byte("audio1")}, startOffset: 1,
+	}
+	videoPath := writeTestFile(t, "video.mp4", video.bytes())
+	audioPath := writeTestFile(t, "audio.m4a", audio.bytes())
+	outputPath := filepath.Join(t.TempDir(), "out.mp4")
+
+	var updates int
+	if err := Mux(context.Background(), videoPath, audioPath, outputPath, func(time.Duration) { updates++ }); err != nil {
+		t.Fatalf("Mux failed: %v", err)
+	}
+	if updates != 5 {
+		t.Errorf("expected 5 progress updates, got %d", updates)
+	}
+
+	out, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	boxes := topLevelBoxes(t, out)
+
+	var types []string
+	for _, b := range boxes {
+		types = append(types, b.typ)
+	}
+	wantTypes := []string{"ftyp", "moov", "moof", "mdat", "moof", "mdat", "moof", "mdat", "moof", "mdat", "moof", "mdat"}
+	if len(types) != len(wantTypes) {
+		t.Fatalf("boxes = %v, want %v", types, wantTypes)
+	}
+
+	// moov: two tracks with new IDs and next_track_ID 3
+	moov := boxes[1].payload(out)
+	traks := 0
+	moovChildren, _ := childBoxes(moov)
+	for _, b := range moovChildren {
+		if b.typ != "trak" {
+			continue
+		}
+		traks++
+		tkhd, _ := findBox(b.payload(moov), "tkhd")
+		if id := binary.BigEndian.Uint32(tkhd[12:]); id != uint32(traks) {
+			t.Errorf("trak %d has track ID %d", traks, id)
+		}
+		if traks == 2 {
+			// Audio durations are converted from 44100 to the 1000 movie timescale
+			if d := binary.BigEndian.Uint32(tkhd[20:]); d != 10000 {
+				t.Errorf("audio tkhd duration = %d, want 10000", d)
+			}
+			elst, _ := findBox(b.payload(moov), "edts", "elst")
+			if d := binary.BigEndian.Uint32(elst[8:]); d != 10000 {
+				t.Errorf("audio elst segment duration = %d, want 10000", d)
+			}
+		}
+	}
+	if traks != 2 {
+		t.Errorf("expected 2 traks, got %d", traks)
+	}
+	mvhd, _ := findBox(moov, "mvhd")
+	if next := binary.BigEndian.Uint32(mvhd[len(mvhd)-4:]); next != 3 {
+		t.Errorf("next_track_ID = %d, want 3", next)
+	}
+	if _, ok := findBox(moov, "udta"); !ok {
+		t.Error("expected udta from the video input to be kept")
+	}
+
+	// Fragments interleaved by decode time, renumbered and with their media data
+	wantTracks := []uint32{1, 1, 2, 1, 2}
+	wantData := []string{"video0", "video1", "audio0", "video2", "audio1"}
+	for i := range wantTracks {
+		moof := out[boxes[2+2*i].offset:boxes[2+2*i].end()]
+		mfhd, _ := findBox(moof[8:], "mfhd")
+		if seq := binary.BigEndian.Uint32(mfhd[4:]); seq != uint32(i+1) {
+			t.Errorf("fragment %d: sequence number %d", i, seq)
+		}
+		tfhd, _ := findBox(moof[8:], "traf", "tfhd")
+		if id := binary.BigEndian.Uint32(tfhd[4:]); id != wantTracks[i] {
+			t.Errorf("fragment %d: track ID %d, want %d", i, id, wantTracks[i])
+		}
+		if data := string(boxes[3+2*i].payload(out)); data != wantData[i] {
+			t.Errorf("fragment %d: data %q, want %q", i, data, wantData[i])
+		}
+	}
+}
+
+func TestMuxMP4_Progressive(t *testing.T) {
+	progressive := append(
+		makeBox("ftyp", []byte("isom"), be32(0)),
+		append(makeBox("moov", fullBox("mvhd", 0, 0, make([]byte, 96))), makeBox("mdat", []byte("data"))...)...,
+	)
+	videoPath := writeTestFile(t, "video.mp4", progressive)
+	audioPath := writeTestFile(t, "audio.m4a", testMP4{trackID: 1, timescale: 1000, movieTimescale: 1000, fragments: [][]byte{{1}}}.bytes())
+	outputPath := filepath.Join(t.TempDir(), "out.mp4")
+
+	err := Mux(context.Background(), videoPath, audioPath, outputPath, nil)
+	if !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Error("no output should be left behind")
+	}
+}
+
+func TestMuxMP4_ExplicitBaseDataOffset(t *testing.T) {
+	m := testMP4{trackID: 1, timescale: 1000, movieTimescale: 1000, fragments: [][]byte{{1}}}
+	data := m.bytes()
+	// Set base-data-offset-present in the tfhd flags
+	idx := bytes.Index(data, []byte("tfhd"))
+	data[idx+7] |= tfhdBaseDataOffsetPresent
+
+	if _, err := openMP4(writeTestFile(t, "video.mp4", data)); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestRescaleField(t *testing.T) {
+	field := be32(44100)
+	rescaleField(field, 44100, 1000)
+	if v := binary.BigEndian.Uint32(field); v != 1000 {
+		t.Errorf("rescaled 32-bit field = %d, want 1000", v)
+	}
+
+	field = be64(90000 * 60)
+	rescaleField(field, 90000, 1000)
+	if v := binary.BigEndian.Uint64(field); v != 60000 {
+		t.Errorf("rescaled 64-bit field = %d, want 60000", v)
+	}
+
+	// All ones means unknown and is left alone
+	field = be32(0xFFFFFFFF)
+	rescaleField(field, 1000, 90000)
+	if v := binary.BigEndian.Uint32(field); v != 0xFFFFFFFF {
+		t.Errorf("unknown duration should be unchanged, got %d", v)
+	}
+}
@@ -0,0 +1,142 @@
+// Package mux provides a native muxer that combines separate video and audio
+// streams into a single file without FFmpeg.
+//
+// Only the codec-copy case for YouTube's adaptive streams is supported:
+//   - Fragmented MP4 video + fragmented MP4 (M4A) audio into MP4
+//   - WebM video + WebM audio into WebM or Matroska
+//
+// Anything else returns ErrUnsupported so callers can fall back to FFmpeg.
+package mux
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tracing"
+)
+
+// ErrUnsupported is returned when inputs cannot be muxed natively.
+var ErrUnsupported = errors.New("unsupported input for native muxing")
+
+// Format identifies a media container format.
+type Format string
+
+// Supported container formats.
+const (
+	// FormatUnknown is returned for unrecognized files.
+	FormatUnknown Format = ""
+	// FormatMP4 is an ISO base media file (MP4, M4A).
+	FormatMP4 Format = "mp4"
+	// FormatWebM is a WebM or Matroska file.
+	FormatWebM Format = "webm"
+)
+
+// ProgressCallback is called with the media time written so far.
+type ProgressCallback func(processed time.Duration)
+
+// ebmlMagic is the EBML header element ID that starts every WebM/Matroska file.
+var ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+
+// DetectFormat sniffs the container format of a file from its first bytes.
+func DetectFormat(path string) (Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FormatUnknown, fmt.Errorf("opening file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	header := make([]byte, 12)
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return FormatUnknown, fmt.Errorf("reading file header: %w", err)
+	}
+	header = header[:n]
+
+	switch {
+	case len(header) >= 8 && string(header[4:8]) == "ftyp":
+		return FormatMP4, nil
+	case bytes.HasPrefix(header, ebmlMagic):
+		return FormatWebM, nil
+	default:
+		return FormatUnknown, nil
+	}
+}
+
+// outputFormat returns the container format and Matroska DocType for an output path.
+func outputFormat(outputPath string) (Format, string) {
+	switch strings.ToLower(filepath.Ext(outputPath)) {
+	case ".mp4", ".m4v", ".mov":
+		return FormatMP4, ""
+	case ".webm":
+		return FormatWebM, "webm"
+	case ".mkv", ".mka":
+		return FormatWebM, "matroska"
+	default:
+		return FormatUnknown, ""
+	}
+}
+
+// Mux combines a video stream and an audio stream into outputPath, copying codecs.
+// The output container is chosen from the output extension and must match the inputs.
+// Returns an error wrapping ErrUnsupported if the inputs cannot be muxed natively;
+// in that case no output file is left behind.
+func Mux(ctx context.Context, videoPath, audioPath, outputPath string, progress ProgressCallback) (err error) {
+	ctx, span := tracing.Start(ctx, tracing.SpanMux, tracing.String(tracing.AttrOutput, outputPath))
+	defer func() { tracing.End(span, err) }()
+
+	videoFormat, err := DetectFormat(videoPath)
+	if err != nil {
+		return err
+	}
+	audioFormat, err := DetectFormat(audioPath)
+	if err != nil {
+		return err
+	}
+
+	format, docType := outputFormat(outputPath)
+	if format == FormatUnknown || videoFormat != format || audioFormat != format {
+		return fmt.Errorf("%w: %s video and %s audio into %s",
+			ErrUnsupported, formatName(videoFormat), formatName(audioFormat), filepath.Ext(outputPath))
+	}
+
+	switch format {
+	case FormatMP4:
+		err = muxMP4(ctx, videoPath, audioPath, outputPath, progress)
+	default:
+		err = muxWebM(ctx, videoPath, audioPath, outputPath, docType, progress)
+	}
+	if err != nil {
+		_ = os.Remove(outputPath)
+		return err
+	}
+	return nil
+}
+
+// formatName returns a printable name for a format.
+func formatName(f Format) string {
+	if f == FormatUnknown {
+		return "unknown"
+	}
+	return string(f)
+}
+
+// createOutput creates the output file, including parent directories.
+func createOutput(outputPath string) (*os.File, error) {
+	if dir := filepath.Dir(outputPath); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("creating directory: %w", err)
+		}
+	}
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("creating output file: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,61 @@
+package mux
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want Format
+	}{
+		{"mp4", makeBox("ftyp", []byte("isom"), be32(0)), FormatMP4},
+		{"webm", buildEBMLHeader("webm"), FormatWebM},
+		{"unknown", []byte("ID3\x04\x00"), FormatUnknown},
+		{"empty", nil, FormatUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat(writeTestFile(t, "file", tt.data))
+			if err != nil {
+				t.Fatalf("DetectFormat failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("DetectFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMux_MismatchedFormats(t *testing.T) {
+	videoPath := writeTestFile(t, "video.webm", testWebM{
+		trackNumber: 1, trackType: trackTypeVideo, codecID: "V_VP9", timestamps: []uint64{0}, payloads: []string{"v"},
+	}.bytes())
+	audioPath := writeTestFile(t, "audio.m4a", testMP4{
+		trackID: 1, timescale: 1000, movieTimescale: 1000, fragments: [][]byte{{1}},
+	}.bytes())
+
+	tests := []struct {
+		name   string
+		audio  string
+		output string
+	}{
+		{"webm video with mp4 audio", audioPath, "out.mkv"},
+		{"webm into mp4", videoPath, "out.mp4"},
+		{"unknown output", videoPath, "out.avi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Mux(context.Background(), videoPath, tt.audio, filepath.Join(t.TempDir(), tt.output), nil)
+			if !errors.Is(err, ErrUnsupported) {
+				t.Errorf("expected ErrUnsupported, got %v", err)
+			}
+		})
+	}
+}
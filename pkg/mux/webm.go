@@ -0,0 +1,785 @@
+package mux
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"time"
+)
+
+// EBML and Matroska element IDs used by the muxer.
+const (
+	idEBML               = 0x1A45DFA3
+	idEBMLVersion        = 0x4286
+	idEBMLReadVersion    = 0x42F7
+	idEBMLMaxIDLength    = 0x42F2
+	idEBMLMaxSizeLength  = 0x42F3
+	idDocType            = 0x4282
+	idDocTypeVersion     = 0x4287
+	idDocTypeReadVersion = 0x4285
+
+	idSegment      = 0x18538067
+	idSeekHead     = 0x114D9B74
+	idSeek         = 0x4DBB
+	idSeekID       = 0x53AB
+	idSeekPosition = 0x53AC
+
+	idInfo           = 0x1549A966
+	idTimestampScale = 0x2AD7B1
+	idDuration       = 0x4489
+	idMuxingApp      = 0x4D80
+
+	idTracks      = 0x1654AE6B
+	idTrackEntry  = 0xAE
+	idTrackNumber = 0xD7
+	idTrackUID    = 0x73C5
+	idTrackType   = 0x83
+	idCodecID     = 0x86
+
+	idCluster     = 0x1F43B675
+	idTimestamp   = 0xE7
+	idSimpleBlock = 0xA3
+	idBlockGroup  = 0xA0
+	idBlock       = 0xA1
+	idPosition    = 0xA7
+	idPrevSize    = 0xAB
+	idCRC32       = 0xBF
+	idVoid        = 0xEC
+
+	idCues               = 0x1C53BB6B
+	idCuePoint           = 0xBB
+	idCueTime            = 0xB3
+	idCueTrackPositions  = 0xB7
+	idCueTrack           = 0xF7
+	idCueClusterPosition = 0xF1
+)
+
+// Matroska track types.
+const (
+	trackTypeVideo = 1
+	trackTypeAudio = 2
+)
+
+// defaultTimestampScale is the Matroska default of 1ms per tick.
+const defaultTimestampScale = 1000000
+
+// muxingApp is written to the Info element of muxed files.
+const muxingApp = "ytdl"
+
+// webmCodecs are the codecs allowed in the WebM subset of Matroska.
+var webmCodecs = map[string]bool{
+	"V_VP8":    true,
+	"V_VP9":    true,
+	"V_AV1":    true,
+	"A_OPUS":   true,
+	"A_VORBIS": true,
+}
+
+// element describes an EBML element within a file or buffer.
+type element struct {
+	id         uint32
+	offset     int64 // start of the element header
+	dataOffset int64
+	size       int64 // data size, -1 if unknown
+}
+
+func (e element) end() int64 {
+	return e.dataOffset + e.size
+}
+
+// data returns the element data within buf.
+func (e element) data(buf []byte) []byte {
+	return buf[e.dataOffset:e.end()]
+}
+
+// readVint decodes an EBML variable-length integer and returns it with its length.
+// Element IDs keep their length marker bit; sizes and values do not.
+func readVint(b []byte, keepMarker bool) (uint64, int, error) {
+	if len(b) == 0 {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	n := bits.LeadingZeros8(b[0]) + 1
+	if n > 8 {
+		return 0, 0, errors.New("invalid EBML variable-length integer")
+	}
+	if len(b) < n {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+
+	v := uint64(b[0])
+	if !keepMarker {
+		v &= 0xFF >> n
+	}
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, n, nil
+}
+
+// readElementHeader reads the header of the element starting at off.
+func readElementHeader(r io.ReaderAt, off, end int64) (element, error) {
+	hdr := make([]byte, 12)
+	if end-off < int64(len(hdr)) {
+		hdr = hdr[:max(end-off, 0)]
+	}
+	n, err := r.ReadAt(hdr, off)
+	if err != nil && !(errors.Is(err, io.EOF) && n > 0) {
+		return element{}, fmt.Errorf("reading element header: %w", err)
+	}
+	hdr = hdr[:n]
+
+	id, idLen, err := readVint(hdr, true)
+	if err != nil || idLen > 4 {
+		return element{}, fmt.Errorf("invalid element ID at offset %d", off)
+	}
+	size, sizeLen, err := readVint(hdr[idLen:], false)
+	if err != nil {
+		return element{}, fmt.Errorf("invalid element size at offset %d", off)
+	}
+
+	e := element{
+		id:         uint32(id),
+		offset:     off,
+		dataOffset: off + int64(idLen+sizeLen),
+		size:       int64(size),
+	}
+	if size == 1<<(7*sizeLen)-1 {
+		// All value bits set means the size is unknown
+		e.size = -1
+	} else if e.end() > end {
+		return element{}, fmt.Errorf("element 0x%X at offset %d exceeds its parent", id, off)
+	}
+	return e, nil
+}
+
+// parseElements parses the elements contained in buf.
+func parseElements(buf []byte) ([]element, error) {
+	r := bytes.NewReader(buf)
+	end := int64(len(buf))
+
+	var elements []element
+	for off := int64(0); off < end; {
+		e, err := readElementHeader(r, off, end)
+		if err != nil {
+			return nil, err
+		}
+		if e.size < 0 {
+			return nil, fmt.Errorf("%w: element 0x%X of unknown size", ErrUnsupported, e.id)
+		}
+		elements = append(elements, e)
+		off = e.end()
+	}
+	return elements, nil
+}
+
+// encodeID returns the encoded form of an element ID.
+func encodeID(id uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], id)
+	return buf[bits.LeadingZeros32(id)/8:]
+}
+
+// encodeSize encodes a size or value as the shortest EBML variable-length integer.
+func encodeSize(n uint64) []byte {
+	length := 1
+	// The all-ones value of each length is reserved for unknown sizes
+	for length < 8 && n >= 1<<(7*length)-1 {
+		length++
+	}
+	return encodeSizeFixed(n, length)
+}
+
+// encodeSizeFixed encodes n as an EBML variable-length integer of the given length.
+func encodeSizeFixed(n uint64, length int) []byte {
+	buf := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		buf[i] = byte(n)
+		n >>= 8
+	}
+	buf[0] |= 0x80 >> (length - 1)
+	return buf
+}
+
+// makeElement builds an element from its ID and data parts.
+func makeElement(id uint32, data ...[]byte) []byte {
+	size := 0
+	for _, d := range data {
+		size += len(d)
+	}
+	buf := append(encodeID(id), encodeSize(uint64(size))...)
+	for _, d := range data {
+		buf = append(buf, d...)
+	}
+	return buf
+}
+
+// uintElement builds an unsigned integer element using the fewest bytes.
+func uintElement(id uint32, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	n := bits.LeadingZeros64(v) / 8
+	return makeElement(id, buf[min(n, 7):])
+}
+
+// fixedUintElement builds an unsigned integer element with an 8 byte value,
+// so it can be rewritten later without changing size.
+func fixedUintElement(id uint32, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return makeElement(id, buf[:])
+}
+
+// floatElement builds a 64-bit float element.
+func floatElement(id uint32, f float64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], math.Float64bits(f))
+	return makeElement(id, buf[:])
+}
+
+// readUint decodes an unsigned integer element value.
+func readUint(data []byte) uint64 {
+	var v uint64
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+// readFloat decodes a 4 or 8 byte float element value.
+func readFloat(data []byte) float64 {
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	default:
+		return 0
+	}
+}
+
+// webmInput is a parsed WebM/Matroska input with a single track.
+type webmInput struct {
+	file *os.File
+
+	timestampScale uint64
+	duration       float64 // in timestamp ticks, 0 if unknown
+
+	// info and track are the data of the Info and TrackEntry elements.
+	info, track []byte
+
+	trackNumber uint64
+	trackType   uint64
+	trackUID    uint64
+	codecID     string
+
+	// outputTrack is the track number used in the muxed output.
+	outputTrack uint64
+
+	clusters []webmCluster
+}
+
+// webmCluster is a Cluster element within an input file.
+type webmCluster struct {
+	input     *webmInput
+	offset    int64
+	size      int64 // total size including the header
+	timestamp uint64
+}
+
+// time returns the cluster start time.
+func (c *webmCluster) time() time.Duration {
+	return time.Duration(c.timestamp * c.input.timestampScale)
+}
+
+// openWebM opens and indexes a WebM/Matroska file.
+func openWebM(path string) (*webmInput, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening input: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("reading input: %w", err)
+	}
+
+	in := &webmInput{file: file, timestampScale: defaultTimestampScale}
+	if err := in.index(info.Size()); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return in, nil
+}
+
+// index reads the EBML header and the top-level elements of the segment.
+func (in *webmInput) index(size int64) error {
+	header, err := readElementHeader(in.file, 0, size)
+	if err != nil {
+		return err
+	}
+	if header.id != idEBML || header.size < 0 {
+		return fmt.Errorf("%w: missing EBML header", ErrUnsupported)
+	}
+
+	segment, err := readElementHeader(in.file, header.end(), size)
+	if err != nil {
+		return err
+	}
+	if segment.id != idSegment {
+		return fmt.Errorf("%w: missing segment", ErrUnsupported)
+	}
+	end := size
+	if segment.size >= 0 {
+		end = segment.end()
+	}
+
+	for off := segment.dataOffset; off < end; {
+		e, err := readElementHeader(in.file, off, end)
+		if err != nil {
+			return err
+		}
+		if e.size < 0 {
+			return fmt.Errorf("%w: element 0x%X of unknown size", ErrUnsupported, e.id)
+		}
+
+		switch e.id {
+		case idInfo:
+			if in.info, err = in.readData(e); err != nil {
+				return err
+			}
+			if err := in.parseInfo(); err != nil {
+				return err
+			}
+		case idTracks:
+			tracks, err := in.readData(e)
+			if err != nil {
+				return err
+			}
+			if err := in.parseTracks(tracks); err != nil {
+				return err
+			}
+		case idCluster:
+			if err := in.addCluster(e); err != nil {
+				return err
+			}
+		}
+
+		off = e.end()
+	}
+
+	if in.track == nil {
+		return fmt.Errorf("%w: missing tracks", ErrUnsupported)
+	}
+	if len(in.clusters) == 0 {
+		return fmt.Errorf("%w: no clusters", ErrUnsupported)
+	}
+	return nil
+}
+
+// readData reads the data of an element from the file.
+func (in *webmInput) readData(e element) ([]byte, error) {
+	buf := make([]byte, e.size)
+	if _, err := in.file.ReadAt(buf, e.dataOffset); err != nil {
+		return nil, fmt.Errorf("reading element 0x%X: %w", e.id, err)
+	}
+	return buf, nil
+}
+
+// parseInfo reads the timestamp scale and duration from the Info element.
+func (in *webmInput) parseInfo() error {
+	children, err := parseElements(in.info)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		switch c.id {
+		case idTimestampScale:
+			in.timestampScale = readUint(c.data(in.info))
+		case idDuration:
+			in.duration = readFloat(c.data(in.info))
+		}
+	}
+	if in.timestampScale == 0 {
+		return errors.New("invalid timestamp scale")
+	}
+	return nil
+}
+
+// parseTracks reads the single TrackEntry of the Tracks element.
+func (in *webmInput) parseTracks(tracks []byte) error {
+	entries, err := parseElements(tracks)
+	if err != nil {
+		return err
+	}
+
+	var found []element
+	for _, e := range entries {
+		if e.id == idTrackEntry {
+			found = append(found, e)
+		}
+	}
+	if len(found) != 1 {
+		return fmt.Errorf("%w: expected a single track, found %d", ErrUnsupported, len(found))
+	}
+	in.track = found[0].data(tracks)
+
+	children, err := parseElements(in.track)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		switch c.id {
+		case idTrackNumber:
+			in.trackNumber = readUint(c.data(in.track))
+		case idTrackUID:
+			in.trackUID = readUint(c.data(in.track))
+		case idTrackType:
+			in.trackType = readUint(c.data(in.track))
+		case idCodecID:
+			in.codecID = string(c.data(in.track))
+		}
+	}
+	if in.trackNumber == 0 {
+		return errors.New("missing track number")
+	}
+	return nil
+}
+
+// maxClusterHeader is how many bytes of a cluster are read to find its timestamp.
+const maxClusterHeader = 64
+
+// addCluster records a cluster and its timestamp.
+func (in *webmInput) addCluster(e element) error {
+	head := make([]byte, min(e.size, maxClusterHeader))
+	if _, err := in.file.ReadAt(head, e.dataOffset); err != nil {
+		return fmt.Errorf("reading cluster: %w", err)
+	}
+
+	r := bytes.NewReader(head)
+	for off := int64(0); off < int64(len(head)); {
+		c, err := readElementHeader(r, off, e.size)
+		if err != nil {
+			break
+		}
+		if c.id == idTimestamp {
+			if c.end() > int64(len(head)) {
+				break
+			}
+			in.clusters = append(in.clusters, webmCluster{
+				input:     in,
+				offset:    e.offset,
+				size:      e.end() - e.offset,
+				timestamp: readUint(c.data(head)),
+			})
+			return nil
+		}
+		if c.id == idSimpleBlock || c.id == idBlockGroup || c.size < 0 {
+			break
+		}
+		off = c.end()
+	}
+	return fmt.Errorf("%w: cluster at offset %d has no leading timestamp", ErrUnsupported, e.offset)
+}
+
+// outputTrackEntry returns the TrackEntry element with the output track number and uid.
+func (in *webmInput) outputTrackEntry(uid uint64) ([]byte, error) {
+	children, err := parseElements(in.track)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := [][]byte{uintElement(idTrackNumber, in.outputTrack), uintElement(idTrackUID, uid)}
+	for _, c := range children {
+		if c.id != idTrackNumber && c.id != idTrackUID {
+			parts = append(parts, in.track[c.offset:c.end()])
+		}
+	}
+	return makeElement(idTrackEntry, parts...), nil
+}
+
+// outputCluster reads a cluster and rewrites its blocks to the output track number.
+// Position, PrevSize and CRC-32 elements are dropped because they no longer match.
+func (in *webmInput) outputCluster(c *webmCluster) ([]byte, error) {
+	buf := make([]byte, c.size)
+	if _, err := in.file.ReadAt(buf, c.offset); err != nil {
+		return nil, fmt.Errorf("reading cluster: %w", err)
+	}
+	e, err := readElementHeader(bytes.NewReader(buf), 0, c.size)
+	if err != nil {
+		return nil, err
+	}
+	data := e.data(buf)
+
+	children, err := parseElements(data)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([][]byte, 0, len(children))
+	for _, child := range children {
+		raw := data[child.offset:child.end()]
+		switch child.id {
+		case idPosition, idPrevSize, idCRC32, idVoid:
+			continue
+		case idSimpleBlock:
+			block, err := in.renumberBlock(child.data(data))
+			if err != nil {
+				return nil, err
+			}
+			raw = makeElement(idSimpleBlock, block)
+		case idBlockGroup:
+			group, err := in.renumberBlockGroup(child.data(data))
+			if err != nil {
+				return nil, err
+			}
+			raw = group
+		}
+		parts = append(parts, raw)
+	}
+	return makeElement(idCluster, parts...), nil
+}
+
+// renumberBlockGroup rewrites the Block inside a BlockGroup.
+func (in *webmInput) renumberBlockGroup(group []byte) ([]byte, error) {
+	children, err := parseElements(group)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([][]byte, 0, len(children))
+	for _, c := range children {
+		raw := group[c.offset:c.end()]
+		if c.id == idBlock {
+			block, err := in.renumberBlock(c.data(group))
+			if err != nil {
+				return nil, err
+			}
+			raw = makeElement(idBlock, block)
+		}
+		parts = append(parts, raw)
+	}
+	return makeElement(idBlockGroup, parts...), nil
+}
+
+// renumberBlock replaces the track number at the start of block data.
+func (in *webmInput) renumberBlock(block []byte) ([]byte, error) {
+	track, n, err := readVint(block, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block: %w", err)
+	}
+	if track != in.trackNumber {
+		return nil, fmt.Errorf("block references unknown track %d", track)
+	}
+	return append(encodeSize(in.outputTrack), block[n:]...), nil
+}
+
+func (in *webmInput) close() {
+	_ = in.file.Close()
+}
+
+// buildEBMLHeader builds the EBML header for the given DocType.
+func buildEBMLHeader(docType string) []byte {
+	return makeElement(idEBML,
+		uintElement(idEBMLVersion, 1),
+		uintElement(idEBMLReadVersion, 1),
+		uintElement(idEBMLMaxIDLength, 4),
+		uintElement(idEBMLMaxSizeLength, 8),
+		makeElement(idDocType, []byte(docType)),
+		uintElement(idDocTypeVersion, 4),
+		uintElement(idDocTypeReadVersion, 2),
+	)
+}
+
+// buildInfo builds the output Info element from the video input's Info,
+// with the longer of the two durations and this muxer as MuxingApp.
+func buildInfo(video, audio *webmInput) ([]byte, error) {
+	children, err := parseElements(video.info)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts [][]byte
+	for _, c := range children {
+		if c.id != idDuration && c.id != idMuxingApp {
+			parts = append(parts, video.info[c.offset:c.end()])
+		}
+	}
+	parts = append(parts, makeElement(idMuxingApp, []byte(muxingApp)))
+	if duration := max(video.duration, audio.duration); duration > 0 {
+		parts = append(parts, floatElement(idDuration, duration))
+	}
+	return makeElement(idInfo, parts...), nil
+}
+
+// buildSeekHead builds a SeekHead pointing at the Info, Tracks and Cues elements.
+// Positions are encoded with a fixed width so the SeekHead can be rewritten in place.
+func buildSeekHead(infoPos, tracksPos, cuesPos uint64) []byte {
+	seek := func(id uint32, pos uint64) []byte {
+		return makeElement(idSeek, makeElement(idSeekID, encodeID(id)), fixedUintElement(idSeekPosition, pos))
+	}
+	return makeElement(idSeekHead,
+		seek(idInfo, infoPos),
+		seek(idTracks, tracksPos),
+		seek(idCues, cuesPos),
+	)
+}
+
+// cuePoint builds a CuePoint for a cluster of the video track.
+func cuePoint(timestamp, clusterPos uint64) []byte {
+	return makeElement(idCuePoint,
+		uintElement(idCueTime, timestamp),
+		makeElement(idCueTrackPositions,
+			uintElement(idCueTrack, 1),
+			uintElement(idCueClusterPosition, clusterPos),
+		),
+	)
+}
+
+// mergeClusters interleaves the clusters of two inputs by timestamp.
+func mergeClusters(a, b []webmCluster) []*webmCluster {
+	merged := make([]*webmCluster, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if j >= len(b) || (i < len(a) && a[i].timestamp <= b[j].timestamp) {
+			merged = append(merged, &a[i])
+			i++
+		} else {
+			merged = append(merged, &b[j])
+			j++
+		}
+	}
+	return merged
+}
+
+// validateWebMInputs checks that two inputs can be muxed into docType.
+func validateWebMInputs(video, audio *webmInput, docType string) error {
+	if video.trackType != trackTypeVideo || audio.trackType != trackTypeAudio {
+		return fmt.Errorf("%w: expected a video track and an audio track", ErrUnsupported)
+	}
+	if video.timestampScale != audio.timestampScale {
+		return fmt.Errorf("%w: inputs use different timestamp scales", ErrUnsupported)
+	}
+	if docType == "webm" && (!webmCodecs[video.codecID] || !webmCodecs[audio.codecID]) {
+		return fmt.Errorf("%w: %s and %s are not allowed in WebM", ErrUnsupported, video.codecID, audio.codecID)
+	}
+	return nil
+}
+
+// segmentSizeLength is the width of the Segment size, fixed so it can be patched after writing.
+const segmentSizeLength = 8
+
+// muxWebM muxes WebM video and audio into a WebM or Matroska output.
+func muxWebM(ctx context.Context, videoPath, audioPath, outputPath, docType string, progress ProgressCallback) error {
+	video, err := openWebM(videoPath)
+	if err != nil {
+		return err
+	}
+	defer video.close()
+
+	audio, err := openWebM(audioPath)
+	if err != nil {
+		return err
+	}
+	defer audio.close()
+
+	if err := validateWebMInputs(video, audio, docType); err != nil {
+		return err
+	}
+	video.outputTrack, audio.outputTrack = 1, 2
+
+	info, err := buildInfo(video, audio)
+	if err != nil {
+		return err
+	}
+	videoEntry, err := video.outputTrackEntry(video.trackUID)
+	if err != nil {
+		return err
+	}
+	audioUID := audio.trackUID
+	if audioUID == video.trackUID {
+		audioUID++
+	}
+	audioEntry, err := audio.outputTrackEntry(audioUID)
+	if err != nil {
+		return err
+	}
+	tracks := makeElement(idTracks, videoEntry, audioEntry)
+
+	out, err := createOutput(outputPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+
+	w := bufio.NewWriterSize(out, 1<<20)
+
+	header := buildEBMLHeader(docType)
+	segmentHeader := append(encodeID(idSegment), encodeSizeFixed(0, segmentSizeLength)...)
+	segmentStart := int64(len(header) + len(segmentHeader))
+
+	// Positions are relative to the start of the segment data
+	seekHead := buildSeekHead(0, 0, 0)
+	infoPos := uint64(len(seekHead))
+	tracksPos := infoPos + uint64(len(info))
+
+	for _, part := range [][]byte{header, segmentHeader, seekHead, info, tracks} {
+		if _, err := w.Write(part); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+	}
+
+	pos := tracksPos + uint64(len(tracks))
+	var cues [][]byte
+	for _, cluster := range mergeClusters(video.clusters, audio.clusters) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		data, err := cluster.input.outputCluster(cluster)
+		if err != nil {
+			return err
+		}
+		if cluster.input == video {
+			cues = append(cues, cuePoint(cluster.timestamp, pos))
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		pos += uint64(len(data))
+
+		if progress != nil {
+			progress(cluster.time())
+		}
+	}
+
+	cuesPos := pos
+	cuesElement := makeElement(idCues, cues...)
+	if _, err := w.Write(cuesElement); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	pos += uint64(len(cuesElement))
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+
+	// Patch the segment size and the SeekHead now that all positions are known
+	if _, err := out.WriteAt(encodeSizeFixed(pos, segmentSizeLength), int64(len(header)+len(encodeID(idSegment)))); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	if _, err := out.WriteAt(buildSeekHead(infoPos, tracksPos, cuesPos), segmentStart); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("closing output: %w", err)
+	}
+	return nil
+}
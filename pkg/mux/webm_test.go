@@ -0,0 +1,262 @@
+package mux
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testWebM describes a synthetic WebM file with one track.
+type testWebM struct {
+	trackNumber uint64
+	trackType   uint64
+	codecID     string
+	duration    float64
+	// timestamps and payloads describe one single-block cluster each.
+	timestamps []uint64
+	payloads   []string
+	// blockGroups writes blocks inside BlockGroups instead of SimpleBlocks.
+	blockGroups bool
+}
+
+// bytes encodes the synthetic file.
+func (m testWebM) bytes() []byte {
+	info := makeElement(idInfo,
+		uintElement(idTimestampScale, defaultTimestampScale),
+		makeElement(idMuxingApp, []byte("test")),
+		floatElement(idDuration, m.duration),
+	)
+	tracks := makeElement(idTracks, makeElement(idTrackEntry,
+		uintElement(idTrackNumber, m.trackNumber),
+		uintElement(idTrackUID, 42),
+		uintElement(idTrackType, m.trackType),
+		makeElement(idCodecID, []byte(m.codecID)),
+	))
+
+	segment := [][]byte{makeElement(idSeekHead), info, tracks}
+	for i, ts := range m.timestamps {
+		block := append(encodeSize(m.trackNumber), 0, 0, 0x80)
+		block = append(block, m.payloads[i]...)
+
+		var blockElement []byte
+		if m.blockGroups {
+			blockElement = makeElement(idBlockGroup, makeElement(idBlock, block), uintElement(0x9B, 20))
+		} else {
+			blockElement = makeElement(idSimpleBlock, block)
+		}
+		segment = append(segment, makeElement(idCluster,
+			makeElement(idCRC32, []byte{1, 2, 3, 4}),
+			uintElement(idTimestamp, ts),
+			uintElement(idPrevSize, 100),
+			blockElement,
+		))
+	}
+	segment = append(segment, makeElement(idCues))
+
+	return append(buildEBMLHeader("webm"), makeElement(idSegment, segment...)...)
+}
+
+// testChildren parses elements or fails the test.
+func testChildren(t *testing.T, buf []byte) []element {
+	t.Helper()
+	elements, err := parseElements(buf)
+	if err != nil {
+		t.Fatalf("parsing elements: %v", err)
+	}
+	return elements
+}
+
+// findElement returns the data of the first element with the given ID.
+func findElement(t *testing.T, buf []byte, id uint32) []byte {
+	t.Helper()
+	for _, e := range testChildren(t, buf) {
+		if e.id == id {
+			return e.data(buf)
+		}
+	}
+	t.Fatalf("element 0x%X not found", id)
+	return nil
+}
+
+func TestMuxWebM(t *testing.T) {
+	video := testWebM{
+		trackNumber: 1, trackType: trackTypeVideo, codecID: "V_VP9", duration: 3000,
+		timestamps: []uint64{0, 1000, 2000}, payloads: []string{"v0", "v1", "v2"},
+	}
+	audio := testWebM{
+		trackNumber: 1, trackType: trackTypeAudio, codecID: "A_OPUS", duration: 3500,
+		timestamps: []uint64{500, 2500}, payloads: []string{"a0", "a1"}, blockGroups: true,
+	}
+	videoPath := writeTestFile(t, "video.webm", video.bytes())
+	audioPath := writeTestFile(t, "audio.webm", audio.bytes())
+	outputPath := filepath.Join(t.TempDir(), "out.webm")
+
+	if err := Mux(context.Background(), videoPath, audioPath, outputPath, nil); err != nil {
+		t.Fatalf("Mux failed: %v", err)
+	}
+
+	out, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	top := testChildren(t, out)
+	if len(top) != 2 || top[0].id != idEBML || top[1].id != idSegment {
+		t.Fatalf("expected EBML header and segment, got %d elements", len(top))
+	}
+	if docType := string(findElement(t, top[0].data(out), idDocType)); docType != "webm" {
+		t.Errorf("DocType = %q, want webm", docType)
+	}
+
+	segment := top[1].data(out)
+	children := testChildren(t, segment)
+
+	// SeekHead entries point at the elements they name
+	seekHead := findElement(t, segment, idSeekHead)
+	for _, seek := range testChildren(t, seekHead) {
+		data := seek.data(seekHead)
+		id := uint32(readUint(findElement(t, data, idSeekID)))
+		pos := readUint(findElement(t, data, idSeekPosition))
+		e, err := readElementHeader(bytes.NewReader(segment), int64(pos), int64(len(segment)))
+		if err != nil || e.id != id {
+			t.Errorf("seek entry for 0x%X points at %d, which is not that element", id, pos)
+		}
+	}
+
+	info := findElement(t, segment, idInfo)
+	if d := readFloat(findElement(t, info, idDuration)); d != 3500 {
+		t.Errorf("duration = %v, want the longer input duration 3500", d)
+	}
+	if app := string(findElement(t, info, idMuxingApp)); app != muxingApp {
+		t.Errorf("MuxingApp = %q, want %q", app, muxingApp)
+	}
+
+	tracks := findElement(t, segment, idTracks)
+	entries := testChildren(t, tracks)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 track entries, got %d", len(entries))
+	}
+	uids := map[uint64]bool{}
+	for i, entry := range entries {
+		data := entry.data(tracks)
+		if n := readUint(findElement(t, data, idTrackNumber)); n != uint64(i+1) {
+			t.Errorf("track %d has number %d", i, n)
+		}
+		uids[readUint(findElement(t, data, idTrackUID))] = true
+	}
+	if len(uids) != 2 {
+		t.Error("track UIDs should be unique")
+	}
+
+	// Clusters interleaved by timestamp with renumbered blocks
+	wantTracks := []byte{1, 2, 1, 1, 2}
+	wantPayloads := []string{"v0", "a0", "v1", "v2", "a1"}
+	var clusterIndex int
+	for _, c := range children {
+		if c.id != idCluster {
+			continue
+		}
+		cluster := c.data(segment)
+		var block []byte
+		for _, e := range testChildren(t, cluster) {
+			switch e.id {
+			case idCRC32, idPrevSize:
+				t.Error("CRC-32 and PrevSize should be dropped")
+			case idSimpleBlock:
+				block = e.data(cluster)
+			case idBlockGroup:
+				block = findElement(t, e.data(cluster), idBlock)
+			}
+		}
+		if clusterIndex >= len(wantTracks) {
+			t.Fatal("too many clusters")
+		}
+		if block[0] != 0x80|wantTracks[clusterIndex] {
+			t.Errorf("cluster %d: block track 0x%X, want track %d", clusterIndex, block[0], wantTracks[clusterIndex])
+		}
+		if payload := string(block[4:]); payload != wantPayloads[clusterIndex] {
+			t.Errorf("cluster %d: payload %q, want %q", clusterIndex, payload, wantPayloads[clusterIndex])
+		}
+		clusterIndex++
+	}
+	if clusterIndex != len(wantTracks) {
+		t.Errorf("expected %d clusters, got %d", len(wantTracks), clusterIndex)
+	}
+
+	cues := findElement(t, segment, idCues)
+	if n := len(testChildren(t, cues)); n != 3 {
+		t.Errorf("expected a cue point per video cluster (3), got %d", n)
+	}
+}
+
+func TestMuxWebM_MatroskaDocType(t *testing.T) {
+	video := testWebM{trackNumber: 1, trackType: trackTypeVideo, codecID: "V_MPEG4/ISO/AVC", timestamps: []uint64{0}, payloads: []string{"v"}}
+	audio := testWebM{trackNumber: 1, trackType: trackTypeAudio, codecID: "A_AAC", timestamps: []uint64{0}, payloads: []string{"a"}}
+	videoPath := writeTestFile(t, "video.mkv", video.bytes())
+	audioPath := writeTestFile(t, "audio.mka", audio.bytes())
+
+	// H.264 and AAC are not valid WebM codecs
+	if err := Mux(context.Background(), videoPath, audioPath, filepath.Join(t.TempDir(), "out.webm"), nil); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for WebM output, got %v", err)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "out.mkv")
+	if err := Mux(context.Background(), videoPath, audioPath, outputPath, nil); err != nil {
+		t.Fatalf("Mux failed: %v", err)
+	}
+	out, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := testChildren(t, out)[0]
+	if docType := string(findElement(t, header.data(out), idDocType)); docType != "matroska" {
+		t.Errorf("DocType = %q, want matroska", docType)
+	}
+}
+
+func TestMuxWebM_TrackTypes(t *testing.T) {
+	audio := testWebM{trackNumber: 1, trackType: trackTypeAudio, codecID: "A_OPUS", timestamps: []uint64{0}, payloads: []string{"a"}}
+	path := writeTestFile(t, "audio.webm", audio.bytes())
+
+	// Audio passed as the video input
+	err := Mux(context.Background(), path, path, filepath.Join(t.TempDir(), "out.webm"), nil)
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestEBMLVint(t *testing.T) {
+	for _, n := range []uint64{0, 1, 126, 127, 128, 16382, 16383, 1 << 20, 1 << 40} {
+		encoded := encodeSize(n)
+		got, length, err := readVint(encoded, false)
+		if err != nil {
+			t.Errorf("readVint(encodeSize(%d)) failed: %v", n, err)
+			continue
+		}
+		if got != n || length != len(encoded) {
+			t.Errorf("round trip of %d gave %d (length %d of %d)", n, got, length, len(encoded))
+		}
+	}
+
+	// 127 in one byte would be the reserved unknown size
+	if got := len(encodeSize(127)); got != 2 {
+		t.Errorf("expected 127 to be encoded in 2 bytes, got %d", got)
+	}
+
+	if id, n, _ := readVint(encodeID(idSegment), true); id != idSegment || n != 4 {
+		t.Errorf("readVint(encodeID(Segment)) = 0x%X (%d bytes)", id, n)
+	}
+}
+
+func TestReadElementHeader_UnknownSize(t *testing.T) {
+	data := append(encodeID(idCluster), 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+	e, err := readElementHeader(bytes.NewReader(data), 0, int64(len(data)))
+	if err != nil {
+		t.Fatalf("readElementHeader failed: %v", err)
+	}
+	if e.size != -1 {
+		t.Errorf("expected unknown size, got %d", e.size)
+	}
+}
@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrDesktopUnsupported is returned when desktop notifications aren't available
+// on the current operating system.
+var ErrDesktopUnsupported = errors.New("desktop notifications are not supported on this platform")
+
+// Desktop is a Notifier that shows each event as a desktop notification, with
+// notify-send on Linux and the BSDs, osascript on macOS and PowerShell on Windows.
+type Desktop struct {
+	// run executes the notification program, replaced in tests.
+	run func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+	// goos is the operating system to notify on, runtime.GOOS if empty. Set in tests.
+	goos string
+}
+
+// Notify shows event as a desktop notification.
+func (d *Desktop) Notify(ctx context.Context, event Event) error {
+	goos := d.goos
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	title, message := desktopText(event)
+	name, args, err := desktopCommand(goos, title, message)
+	if err != nil {
+		return err
+	}
+
+	run := d.run
+	if run == nil {
+		run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return exec.CommandContext(ctx, name, args...).CombinedOutput()
+		}
+	}
+	if out, err := run(ctx, name, args...); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("showing desktop notification: %w: %s", err, msg)
+		}
+		return fmt.Errorf("showing desktop notification: %w", err)
+	}
+	return nil
+}
+
+// desktopText returns the title and message of the notification for event.
+func desktopText(event Event) (title, message string) {
+	if event.Status == StatusFailed {
+		return "Download failed", event.Title + ": " + event.Error
+	}
+	if event.Path != "" {
+		return "Download complete", event.Title + "\n" + event.Path
+	}
+	return "Download complete", event.Title
+}
+
+// desktopCommand returns the program and arguments that show a notification on goos.
+func desktopCommand(goos, title, message string) (string, []string, error) {
+	switch goos {
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		return "notify-send", []string{"--app-name=ytdl", title, message}, nil
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptQuote(message), appleScriptQuote(title))
+		return "osascript", []string{"-e", script}, nil
+	case "windows":
+		script := "Add-Type -AssemblyName System.Windows.Forms; " +
+			"$n = New-Object System.Windows.Forms.NotifyIcon; " +
+			"$n.Icon = [System.Drawing.SystemIcons]::Information; $n.Visible = $true; " +
+			fmt.Sprintf("$n.ShowBalloonTip(5000, %s, %s, 'Info'); ", powerShellQuote(title), powerShellQuote(message)) +
+			"Start-Sleep -Seconds 5; $n.Dispose()"
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", script}, nil
+	default:
+		return "", nil, fmt.Errorf("%w: %s", ErrDesktopUnsupported, goos)
+	}
+}
+
+// appleScriptQuote returns s as an AppleScript string literal.
+func appleScriptQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// powerShellQuote returns s as a single-quoted PowerShell string literal.
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
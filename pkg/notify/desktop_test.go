@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDesktop_Commands(t *testing.T) {
+	tests := []struct {
+		goos     string
+		wantName string
+		wantArg  string
+	}{
+		{"linux", "notify-send", "Video \"1\"\n/tmp/Video.mp4"},
+		{"darwin", "osascript", `display notification "Video \"1\"` + "\n" + `/tmp/Video.mp4" with title "Download complete"`},
+		{"windows", "powershell", `'Download complete', 'Video "1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			var gotName string
+			var gotArgs []string
+			d := &Desktop{goos: tt.goos, run: func(_ context.Context, name string, args ...string) ([]byte, error) {
+				gotName, gotArgs = name, args
+				return nil, nil
+			}}
+			event := Event{Title: `Video "1"`, Status: StatusSucceeded, Path: "/tmp/Video.mp4"}
+			if err := d.Notify(context.Background(), event); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			if gotName != tt.wantName {
+				t.Errorf("program = %q, want %q", gotName, tt.wantName)
+			}
+			if joined := strings.Join(gotArgs, " "); !strings.Contains(joined, tt.wantArg) {
+				t.Errorf("args = %q, want them to contain %q", gotArgs, tt.wantArg)
+			}
+		})
+	}
+}
+
+func TestDesktop_Failure(t *testing.T) {
+	var gotArgs []string
+	d := &Desktop{goos: "linux", run: func(_ context.Context, _ string, args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte("no notification daemon\n"), errors.New("exit status 1")
+	}}
+	err := d.Notify(context.Background(), Event{Title: "Video", Status: StatusFailed, Error: "video unavailable"})
+	if err == nil || !strings.Contains(err.Error(), "no notification daemon") {
+		t.Errorf("Notify() error = %v, want the program's output", err)
+	}
+	if len(gotArgs) != 3 || gotArgs[1] != "Download failed" || gotArgs[2] != "Video: video unavailable" {
+		t.Errorf("args = %q", gotArgs)
+	}
+}
+
+func TestDesktop_Unsupported(t *testing.T) {
+	d := &Desktop{goos: "plan9"}
+	if err := d.Notify(context.Background(), Event{}); !errors.Is(err, ErrDesktopUnsupported) {
+		t.Errorf("Notify() error = %v, want ErrDesktopUnsupported", err)
+	}
+}
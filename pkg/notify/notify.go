@@ -0,0 +1,57 @@
+// Package notify tells the user or other programs when a download finishes,
+// with desktop notifications or webhooks.
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Event statuses, the same as the item statuses of a download report.
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Event describes a finished download.
+type Event struct {
+	// Title is the title of the video.
+	Title string `json:"title"`
+
+	// Status is StatusSucceeded or StatusFailed.
+	Status string `json:"status"`
+
+	// Path is where the file was saved, empty if the download failed before
+	// an output was chosen.
+	Path string `json:"path,omitempty"`
+
+	// Size is the number of bytes written.
+	Size int64 `json:"size"`
+
+	// Duration is how long the download took.
+	Duration time.Duration `json:"-"`
+
+	// Error is why the download failed, empty if it succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// Notifier delivers events.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Multi is a Notifier that delivers events to each of its notifiers in turn.
+// It returns the errors of all that failed, joined.
+type Multi []Notifier
+
+// Notify delivers event to every notifier.
+func (m Multi) Notify(ctx context.Context, event Event) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
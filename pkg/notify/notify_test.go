@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type recordingNotifier struct {
+	events []Event
+	err    error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, event Event) error {
+	n.events = append(n.events, event)
+	return n.err
+}
+
+func TestMulti_NotifiesAll(t *testing.T) {
+	errFirst := errors.New("first failed")
+	first := &recordingNotifier{err: errFirst}
+	second := &recordingNotifier{}
+
+	err := Multi{first, second}.Notify(context.Background(), Event{Title: "Video", Status: StatusSucceeded})
+	if !errors.Is(err, errFirst) {
+		t.Errorf("Notify() error = %v, want %v", err, errFirst)
+	}
+	if len(first.events) != 1 || len(second.events) != 1 {
+		t.Errorf("events = %d and %d, want 1 each", len(first.events), len(second.events))
+	}
+}
+
+func TestMulti_Empty(t *testing.T) {
+	if err := (Multi{}).Notify(context.Background(), Event{}); err != nil {
+		t.Errorf("Notify() error = %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Webhook is a Notifier that POSTs each event as JSON to a URL:
+//
+//	{"title": "...", "status": "succeeded", "path": "...", "size": 1234, "duration_seconds": 12.5}
+//
+// Failed downloads also have an "error" field.
+type Webhook struct {
+	// URL is the endpoint events are posted to.
+	URL string
+
+	// Client is the HTTP client used. If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// webhookPayload is the JSON body of a webhook request.
+type webhookPayload struct {
+	Event
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// Notify posts event to the webhook. Responses other than 2xx are errors.
+func (h *Webhook) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(webhookPayload{Event: event, DurationSeconds: event.Duration.Seconds()})
+	if err != nil {
+		return fmt.Errorf("encoding webhook payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhook_PostsEvent(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request = %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	h := &Webhook{URL: server.URL, Client: server.Client()}
+	event := Event{Title: "Video", Status: StatusSucceeded, Path: "/tmp/Video.mp4", Size: 1234, Duration: 2500 * time.Millisecond}
+	if err := h.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	want := map[string]any{"title": "Video", "status": "succeeded", "path": "/tmp/Video.mp4", "size": 1234.0, "duration_seconds": 2.5}
+	if len(got) != len(want) {
+		t.Errorf("payload = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("payload[%q] = %v, want %v", k, got[k], v)
+		}
+	}
+}
+
+func TestWebhook_IncludesError(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	h := &Webhook{URL: server.URL, Client: server.Client()}
+	if err := h.Notify(context.Background(), Event{Title: "Video", Status: StatusFailed, Error: "video unavailable"}); err != nil {
+		t.Fatal(err)
+	}
+	if got["status"] != "failed" || got["error"] != "video unavailable" {
+		t.Errorf("payload = %v", got)
+	}
+	if _, ok := got["path"]; ok {
+		t.Errorf("payload has a path without one: %v", got)
+	}
+}
+
+func TestWebhook_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	h := &Webhook{URL: server.URL, Client: server.Client()}
+	err := h.Notify(context.Background(), Event{Title: "Video", Status: StatusSucceeded})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Notify() error = %v, want the 403 status", err)
+	}
+}
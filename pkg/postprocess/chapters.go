@@ -0,0 +1,94 @@
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/filename"
+)
+
+// TrimFunc cuts the section between start and end out of inputPath into
+// outputPath, like ffmpeg.Trim.
+type TrimFunc func(ctx context.Context, inputPath, outputPath string, start, end time.Duration, progress ffmpeg.ProgressCallback) error
+
+// ChapterSplitStep writes each chapter of the video to its own file next to
+// the file, which is kept. Chapters are cut with FFmpeg without re-encoding, so
+// a video chapter starts at the keyframe at or before the chapter's start.
+// Videos without chapters are left alone.
+type ChapterSplitStep struct {
+	// Template names the chapter files; see filename.ApplyChapterTemplate. If
+	// empty, filename.DefaultChapterTemplate is used.
+	Template string
+
+	// Options configures how chapter file names are sanitized.
+	Options filename.Options
+
+	// Trim cuts the chapters. If nil, ffmpeg.Trim is used.
+	Trim TrimFunc
+
+	// Output receives a line for each chapter file written. If nil, nothing is printed.
+	Output io.Writer
+}
+
+// Name returns the step name.
+func (s *ChapterSplitStep) Name() string {
+	return "split chapters"
+}
+
+// Run cuts the chapters out of the file.
+func (s *ChapterSplitStep) Run(ctx context.Context, file *File) error {
+	if file.Video == nil {
+		return errors.New("no video metadata")
+	}
+	chapters := file.Video.Chapters
+	if len(chapters) == 0 {
+		printf(s.Output, "No chapters to split\n")
+		return nil
+	}
+
+	template := s.Template
+	if template == "" {
+		template = filename.DefaultChapterTemplate
+	}
+	trim := s.Trim
+	if trim == nil {
+		trim = ffmpeg.Trim
+	}
+	dir := filepath.Dir(file.Path)
+	container := strings.TrimPrefix(filepath.Ext(file.Path), ".")
+
+	for i, chapter := range chapters {
+		end := file.Video.Duration
+		if i+1 < len(chapters) {
+			end = chapters[i+1].Start
+		}
+		if end == 0 {
+			return errors.New("video duration unknown, can't find where the last chapter ends")
+		}
+		if end <= chapter.Start {
+			// Chapters listed past the end of the video have nothing to cut
+			continue
+		}
+
+		path := filepath.Join(dir, filename.ApplyChapterTemplate(template, file.Video, chapter, i+1, len(chapters), container, s.Options))
+		if path == file.Path {
+			return fmt.Errorf("chapter %d would overwrite the file; the template needs $chapterIndex or $chapterTitle", i+1)
+		}
+		// Templates with slashes sort the chapters into folders
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("creating directory for chapter %d: %w", i+1, err)
+		}
+		if err := trim(ctx, file.Path, path, chapter.Start, end, nil); err != nil {
+			return fmt.Errorf("cutting chapter %d %q: %w", i+1, chapter.Title, err)
+		}
+		printf(s.Output, "Chapter saved: %s\n", path)
+	}
+	return nil
+}
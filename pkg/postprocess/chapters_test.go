@@ -0,0 +1,115 @@
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// trimCall is a section cut by a fake TrimFunc.
+type trimCall struct {
+	output     string
+	start, end time.Duration
+}
+
+func recordTrims(calls *[]trimCall) TrimFunc {
+	return func(_ context.Context, _, outputPath string, start, end time.Duration, _ ffmpeg.ProgressCallback) error {
+		*calls = append(*calls, trimCall{output: filepath.Base(outputPath), start: start, end: end})
+		return nil
+	}
+}
+
+func TestChapterSplitStep_CutsEachChapter(t *testing.T) {
+	var calls []trimCall
+	out := new(bytes.Buffer)
+	step := &ChapterSplitStep{Template: "$chapterIndex $chapterTitle", Trim: recordTrims(&calls), Output: out}
+	file := &File{
+		Path: filepath.Join(t.TempDir(), "Mix.m4a"),
+		Video: &youtube.Video{
+			Title:    "Mix",
+			Duration: 10 * time.Minute,
+			Chapters: []youtube.Chapter{
+				{Title: "Intro", Start: 0},
+				{Title: "Song", Start: 90 * time.Second},
+				{Title: "Outro", Start: 8 * time.Minute},
+			},
+		},
+	}
+
+	if err := step.Run(context.Background(), file); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	want := []trimCall{
+		{"01 Intro.m4a", 0, 90 * time.Second},
+		{"02 Song.m4a", 90 * time.Second, 8 * time.Minute},
+		{"03 Outro.m4a", 8 * time.Minute, 10 * time.Minute},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("trimmed %+v, want %+v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("trim %d = %+v, want %+v", i, calls[i], want[i])
+		}
+	}
+	if got := strings.Count(out.String(), "Chapter saved: "); got != 3 {
+		t.Errorf("printed %d saved chapters, want 3:\n%s", got, out)
+	}
+	if !strings.HasSuffix(file.Path, "Mix.m4a") {
+		t.Errorf("file.Path = %q, want the original file", file.Path)
+	}
+}
+
+func TestChapterSplitStep_NoChapters(t *testing.T) {
+	var calls []trimCall
+	step := &ChapterSplitStep{Trim: recordTrims(&calls)}
+	if err := step.Run(context.Background(), &File{Path: "video.mp4", Video: &youtube.Video{}}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("trimmed %+v without chapters", calls)
+	}
+}
+
+func TestChapterSplitStep_Errors(t *testing.T) {
+	chapters := []youtube.Chapter{{Title: "A", Start: 0}, {Title: "B", Start: time.Minute}}
+	failing := func(context.Context, string, string, time.Duration, time.Duration, ffmpeg.ProgressCallback) error {
+		return ffmpeg.ErrNotFound
+	}
+
+	tests := []struct {
+		name string
+		step *ChapterSplitStep
+		file *File
+		want string
+	}{
+		{"no metadata", &ChapterSplitStep{}, &File{Path: "video.mp4"}, "no video metadata"},
+		{"unknown duration", &ChapterSplitStep{Trim: recordTrims(new([]trimCall))},
+			&File{Path: "video.mp4", Video: &youtube.Video{Chapters: chapters}}, "duration unknown"},
+		{"overwrites file", &ChapterSplitStep{Template: "$title", Trim: recordTrims(new([]trimCall))},
+			&File{Path: "video.mp4", Video: &youtube.Video{Title: "video", Duration: 2 * time.Minute, Chapters: chapters}}, "would overwrite"},
+		{"trim fails", &ChapterSplitStep{Trim: failing},
+			&File{Path: "video.mp4", Video: &youtube.Video{Duration: 2 * time.Minute, Chapters: chapters}}, `cutting chapter 1 "A"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.step.Run(context.Background(), tt.file)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Run() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+
+	err := (&ChapterSplitStep{Trim: failing}).Run(context.Background(),
+		&File{Path: "video.mp4", Video: &youtube.Video{Duration: 2 * time.Minute, Chapters: chapters}})
+	if !errors.Is(err, ffmpeg.ErrNotFound) {
+		t.Errorf("Run() error = %v, want it to wrap ffmpeg.ErrNotFound", err)
+	}
+}
@@ -0,0 +1,87 @@
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ExecStep runs a shell command for the file.
+//
+// The command is a template. These placeholders are replaced with
+// shell-quoted values:
+//   - {} or {filepath}: path of the file
+//   - {dir}: directory containing the file
+//   - {title}: video title
+//   - {id}: video ID
+//   - {author}: channel name
+//
+// If the command contains neither {} nor {filepath}, the quoted path is appended.
+type ExecStep struct {
+	// Command is the command template.
+	Command string
+
+	// Stdout and Stderr receive the command's output. If nil, it is discarded.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Name returns the step name.
+func (s *ExecStep) Name() string {
+	return "exec " + s.Command
+}
+
+// Run expands the command template and runs it with the system shell.
+func (s *ExecStep) Run(ctx context.Context, file *File) error {
+	command := ExpandCommand(s.Command, file)
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Stdout = s.Stdout
+	cmd.Stderr = s.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running %q: %w", command, err)
+	}
+	return nil
+}
+
+// ExpandCommand replaces the placeholders in a command template with shell-quoted values
+// from the file. See ExecStep for the supported placeholders.
+func ExpandCommand(template string, file *File) string {
+	path := shellQuote(file.Path)
+	if !strings.Contains(template, "{}") && !strings.Contains(template, "{filepath}") {
+		template += " {}"
+	}
+
+	var title, id, author string
+	if file.Video != nil {
+		title, id, author = file.Video.Title, file.Video.ID, file.Video.Author.Name
+	}
+
+	replacer := strings.NewReplacer(
+		"{}", path,
+		"{filepath}", path,
+		"{dir}", shellQuote(filepath.Dir(file.Path)),
+		"{title}", shellQuote(title),
+		"{id}", shellQuote(id),
+		"{author}", shellQuote(author),
+	)
+	return replacer.Replace(template)
+}
+
+// shellQuote quotes s as a single argument for the system shell.
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,70 @@
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+func TestExpandCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX quoting")
+	}
+	file := &File{
+		Path:  "/videos/It's here.mp4",
+		Video: &youtube.Video{ID: "dQw4w9WgXcQ", Title: "It's here", Author: youtube.Author{Name: "Channel"}},
+	}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"mpv {}", `mpv '/videos/It'\''s here.mp4'`},
+		{"echo {title} {id} {author}", `echo 'It'\''s here' 'dQw4w9WgXcQ' 'Channel' '/videos/It'\''s here.mp4'`},
+		{"cp {filepath} {dir}/backup", `cp '/videos/It'\''s here.mp4' '/videos'/backup`},
+		{"notify-send done", `notify-send done '/videos/It'\''s here.mp4'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			if got := ExpandCommand(tt.template, file); got != tt.want {
+				t.Errorf("ExpandCommand(%q) = %s, want %s", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecStep_Run(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "my video.mp4")
+	if err := os.WriteFile(path, []byte("media"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	step := &ExecStep{Command: "cat {}; echo; echo {id}", Stdout: &stdout}
+	if err := step.Run(context.Background(), &File{Path: path, Video: &youtube.Video{ID: "abc"}}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := stdout.String(); got != "media\nabc\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestExecStep_Failure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	err := (&ExecStep{Command: "exit 3;"}).Run(context.Background(), &File{Path: "x"})
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("expected exit status error, got %v", err)
+	}
+}
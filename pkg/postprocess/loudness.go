@@ -0,0 +1,66 @@
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+)
+
+// NormalizeFunc normalizes the loudness of inputPath into outputPath, like
+// ffmpeg.NormalizeLoudness.
+type NormalizeFunc func(ctx context.Context, inputPath, outputPath string, opts ffmpeg.LoudnessOptions, total time.Duration, progress ffmpeg.ProgressCallback) error
+
+// LoudnessStep normalizes the loudness of the file's audio in place, with
+// FFmpeg's two-pass loudnorm filter. The audio is re-encoded; other streams are
+// copied.
+type LoudnessStep struct {
+	// Options are the loudness targets.
+	Options ffmpeg.LoudnessOptions
+
+	// Normalize normalizes the file. If nil, ffmpeg.NormalizeLoudness is used.
+	Normalize NormalizeFunc
+
+	// Output receives a line when normalizing starts. If nil, nothing is printed.
+	Output io.Writer
+}
+
+// Name returns the step name.
+func (s *LoudnessStep) Name() string {
+	return "normalize audio"
+}
+
+// Run normalizes the file into a temporary file next to it, then replaces the
+// file with it.
+func (s *LoudnessStep) Run(ctx context.Context, file *File) error {
+	normalize := s.Normalize
+	if normalize == nil {
+		normalize = ffmpeg.NormalizeLoudness
+	}
+	var duration time.Duration
+	if file.Video != nil {
+		duration = file.Video.Duration
+	}
+
+	target := s.Options.Target
+	if target == 0 {
+		target = ffmpeg.DefaultLoudnessTarget
+	}
+	printf(s.Output, "Normalizing loudness to %g LUFS...\n", target)
+
+	ext := filepath.Ext(file.Path)
+	tmp := strings.TrimSuffix(file.Path, ext) + ".loudnorm" + ext
+	if err := normalize(ctx, file.Path, tmp, s.Options, duration, nil); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, file.Path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("replacing file: %w", err)
+	}
+	return nil
+}
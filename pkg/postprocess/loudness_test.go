@@ -0,0 +1,63 @@
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+func TestLoudnessStep_ReplacesFile(t *testing.T) {
+	path := writeTestFile(t, "song.m4a")
+	var got ffmpeg.LoudnessOptions
+	var gotDuration time.Duration
+	normalize := func(_ context.Context, inputPath, outputPath string, opts ffmpeg.LoudnessOptions, total time.Duration, _ ffmpeg.ProgressCallback) error {
+		got, gotDuration = opts, total
+		if inputPath != path || filepath.Dir(outputPath) != filepath.Dir(path) {
+			t.Errorf("normalize(%q, %q), want the file normalized into a file next to it", inputPath, outputPath)
+		}
+		return os.WriteFile(outputPath, []byte("normalized"), 0o644)
+	}
+
+	out := new(bytes.Buffer)
+	step := &LoudnessStep{Options: ffmpeg.LoudnessOptions{Target: -23}, Normalize: normalize, Output: out}
+	file := &File{Path: path, Video: &youtube.Video{Duration: 3 * time.Minute}}
+	if err := step.Run(context.Background(), file); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != "normalized" {
+		t.Errorf("file = %q, want the normalized audio", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("directory has %d files, want only the normalized one", len(entries))
+	}
+	if got.Target != -23 || gotDuration != 3*time.Minute {
+		t.Errorf("normalize got %+v over %v", got, gotDuration)
+	}
+	if !strings.Contains(out.String(), "-23 LUFS") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestLoudnessStep_KeepsFileOnFailure(t *testing.T) {
+	path := writeTestFile(t, "song.mp3")
+	failure := errors.New("loudnorm failed")
+	step := &LoudnessStep{Normalize: func(context.Context, string, string, ffmpeg.LoudnessOptions, time.Duration, ffmpeg.ProgressCallback) error {
+		return failure
+	}}
+
+	if err := step.Run(context.Background(), &File{Path: path}); !errors.Is(err, failure) {
+		t.Errorf("Run() error = %v, want %v", err, failure)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "media" {
+		t.Errorf("file = %q, want it unchanged", data)
+	}
+}
@@ -0,0 +1,63 @@
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tagging"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// LyricsFunc returns the lyrics to embed for the video, or "" if it has none.
+type LyricsFunc func(ctx context.Context, video *youtube.Video) (string, error)
+
+// LyricsStep embeds the video's lyrics into the file's tags; see
+// tagging.TagInjector.InjectLyrics for the formats supported.
+type LyricsStep struct {
+	// Lyrics looks up the lyrics. If nil, the video description is used.
+	Lyrics LyricsFunc
+
+	// Injector writes the lyrics. If nil, a default TagInjector is used.
+	Injector *tagging.TagInjector
+
+	// Output receives a line saying whether lyrics were embedded. If nil,
+	// nothing is printed.
+	Output io.Writer
+}
+
+// Name returns the step name.
+func (s *LyricsStep) Name() string {
+	return "embed lyrics"
+}
+
+// Run looks up the lyrics and embeds them into the file. Videos without lyrics
+// are left alone.
+func (s *LyricsStep) Run(ctx context.Context, file *File) error {
+	if file.Video == nil {
+		return errors.New("no video metadata")
+	}
+
+	lyrics := file.Video.Description
+	if s.Lyrics != nil {
+		var err error
+		if lyrics, err = s.Lyrics(ctx, file.Video); err != nil {
+			return fmt.Errorf("looking up lyrics: %w", err)
+		}
+	}
+	if lyrics == "" {
+		printf(s.Output, "No lyrics to embed\n")
+		return nil
+	}
+
+	injector := s.Injector
+	if injector == nil {
+		injector = tagging.NewTagInjector()
+	}
+	if err := injector.InjectLyrics(ctx, file.Path, lyrics); err != nil {
+		return err
+	}
+	printf(s.Output, "Lyrics embedded\n")
+	return nil
+}
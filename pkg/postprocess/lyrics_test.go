@@ -0,0 +1,68 @@
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tagging"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// recordLyrics returns an injector that records the lyrics written to M4A files.
+func recordLyrics(got *[]string) *tagging.TagInjector {
+	return &tagging.TagInjector{SetMetadata: func(_ context.Context, _, outputPath string, metadata map[string]string) error {
+		*got = append(*got, metadata["lyrics"])
+		return os.WriteFile(outputPath, []byte("tagged"), 0o644)
+	}}
+}
+
+func TestLyricsStep_EmbedsLyrics(t *testing.T) {
+	video := &youtube.Video{ID: "dQw4w9WgXcQ", Description: "Official video"}
+	tests := []struct {
+		name   string
+		lyrics LyricsFunc
+		want   []string
+		output string
+	}{
+		{"description", nil, []string{"Official video"}, "Lyrics embedded"},
+		{"lookup", func(context.Context, *youtube.Video) (string, error) {
+			return "[00:01.00]Never gonna give you up\n", nil
+		}, []string{"[00:01.00]Never gonna give you up\n"}, "Lyrics embedded"},
+		{"none", func(context.Context, *youtube.Video) (string, error) {
+			return "", nil
+		}, nil, "No lyrics to embed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			out := new(bytes.Buffer)
+			step := &LyricsStep{Lyrics: tt.lyrics, Injector: recordLyrics(&got), Output: out}
+			if err := step.Run(context.Background(), &File{Path: writeTestFile(t, "song.m4a"), Video: video}); err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("embedded %q, want %q", got, tt.want)
+			}
+			if !strings.Contains(out.String(), tt.output) {
+				t.Errorf("output = %q, want %q", out, tt.output)
+			}
+		})
+	}
+}
+
+func TestLyricsStep_Errors(t *testing.T) {
+	failure := errors.New("captions unavailable")
+	step := &LyricsStep{Lyrics: func(context.Context, *youtube.Video) (string, error) {
+		return "", failure
+	}}
+	if err := step.Run(context.Background(), &File{Path: "song.mp3", Video: &youtube.Video{}}); !errors.Is(err, failure) {
+		t.Errorf("Run() error = %v, want %v", err, failure)
+	}
+	if err := step.Run(context.Background(), &File{Path: "song.mp3"}); err == nil {
+		t.Error("Run() without video metadata succeeded")
+	}
+}
@@ -0,0 +1,76 @@
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/musicmeta"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tagging"
+)
+
+// MusicMetadataStep tags the file with the canonical title, artist, album,
+// year and track number of its song, looked up by the "Artist - Title" of the
+// video, in place of the video title and channel name. Files whose song can't
+// be found are left alone.
+type MusicMetadataStep struct {
+	// Provider looks up the song.
+	Provider musicmeta.Provider
+
+	// Injector writes the tags. If nil, a default TagInjector is used.
+	Injector *tagging.TagInjector
+
+	// Output receives a line with the metadata found, or why none was. If nil,
+	// nothing is printed.
+	Output io.Writer
+}
+
+// Name returns the step name.
+func (s *MusicMetadataStep) Name() string {
+	return "music metadata"
+}
+
+// Run looks up the song and writes its metadata to the file.
+func (s *MusicMetadataStep) Run(ctx context.Context, file *File) error {
+	if file.Video == nil {
+		return errors.New("no video metadata")
+	}
+	query, ok := musicmeta.QueryFromVideo(file.Video)
+	if !ok {
+		printf(s.Output, "No artist in title %q, skipping music metadata\n", file.Video.Title)
+		return nil
+	}
+
+	track, err := s.Provider.Lookup(ctx, query)
+	if errors.Is(err, musicmeta.ErrNotFound) {
+		printf(s.Output, "No music metadata found for %s - %s\n", query.Artist, query.Title)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("looking up %s - %s: %w", query.Artist, query.Title, err)
+	}
+
+	injector := s.Injector
+	if injector == nil {
+		injector = tagging.NewTagInjector()
+	}
+	tags := &tagging.Tags{Title: track.Title, Artist: track.Artist, Album: track.Album, Year: track.Year, Track: track.TrackNumber}
+	if err := injector.WriteTags(ctx, file.Path, tags); err != nil {
+		return err
+	}
+	printf(s.Output, "Music metadata: %s - %s (%s)\n", track.Artist, track.Title, albumLabel(track))
+	return nil
+}
+
+// albumLabel describes the album a track was found on, with its year if known.
+func albumLabel(track *musicmeta.Track) string {
+	album := track.Album
+	if album == "" {
+		album = "unknown album"
+	}
+	if track.Year > 0 {
+		return fmt.Sprintf("%s, %d", album, track.Year)
+	}
+	return album
+}
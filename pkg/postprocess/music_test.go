@@ -0,0 +1,94 @@
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/musicmeta"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tagging"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// fakeProvider is a musicmeta.Provider that returns a fixed result.
+type fakeProvider struct {
+	track   *musicmeta.Track
+	err     error
+	queries []musicmeta.Query
+}
+
+func (p *fakeProvider) Lookup(_ context.Context, query musicmeta.Query) (*musicmeta.Track, error) {
+	p.queries = append(p.queries, query)
+	return p.track, p.err
+}
+
+// recordMetadata returns an injector that records the metadata written to M4A files.
+func recordMetadata(got *map[string]string) *tagging.TagInjector {
+	return &tagging.TagInjector{SetMetadata: func(_ context.Context, _, outputPath string, metadata map[string]string) error {
+		*got = metadata
+		return os.WriteFile(outputPath, []byte("tagged"), 0o644)
+	}}
+}
+
+func TestMusicMetadataStep_WritesTags(t *testing.T) {
+	provider := &fakeProvider{track: &musicmeta.Track{
+		Artist: "Rick Astley", Title: "Never Gonna Give You Up", Album: "Whenever You Need Somebody", Year: 1987, TrackNumber: 1,
+	}}
+	var got map[string]string
+	out := new(bytes.Buffer)
+	step := &MusicMetadataStep{Provider: provider, Injector: recordMetadata(&got), Output: out}
+	video := &youtube.Video{Title: "Rick Astley - Never Gonna Give You Up (Official Video)", Author: youtube.Author{Name: "Rick Astley"}}
+
+	if err := step.Run(context.Background(), &File{Path: writeTestFile(t, "song.m4a"), Video: video}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(provider.queries) != 1 || provider.queries[0].Artist != "Rick Astley" || provider.queries[0].Title != "Never Gonna Give You Up" {
+		t.Errorf("queries = %+v", provider.queries)
+	}
+	if got["artist"] != "Rick Astley" || got["album"] != "Whenever You Need Somebody" || got["date"] != "1987" || got["track"] != "1" {
+		t.Errorf("metadata = %v", got)
+	}
+	if !strings.Contains(out.String(), "(Whenever You Need Somebody, 1987)") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestMusicMetadataStep_LeavesFileAlone(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		provider *fakeProvider
+		output   string
+	}{
+		{"no artist", "My vacation vlog", &fakeProvider{}, "No artist in title"},
+		{"not found", "Artist - Unknown Song", &fakeProvider{err: musicmeta.ErrNotFound}, "No music metadata found for Artist - Unknown Song"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]string
+			out := new(bytes.Buffer)
+			step := &MusicMetadataStep{Provider: tt.provider, Injector: recordMetadata(&got), Output: out}
+			if err := step.Run(context.Background(), &File{Path: writeTestFile(t, "song.m4a"), Video: &youtube.Video{Title: tt.title}}); err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if got != nil {
+				t.Errorf("wrote metadata %v", got)
+			}
+			if !strings.Contains(out.String(), tt.output) {
+				t.Errorf("output = %q, want %q", out, tt.output)
+			}
+		})
+	}
+}
+
+func TestMusicMetadataStep_LookupError(t *testing.T) {
+	failure := errors.New("service unavailable")
+	step := &MusicMetadataStep{Provider: &fakeProvider{err: failure}}
+	err := step.Run(context.Background(), &File{Path: "song.mp3", Video: &youtube.Video{Title: "Artist - Song"}})
+	if !errors.Is(err, failure) {
+		t.Errorf("Run() error = %v, want %v", err, failure)
+	}
+}
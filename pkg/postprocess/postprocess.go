@@ -0,0 +1,213 @@
+// Package postprocess provides a pipeline of steps that run on each finished download,
+// such as running a shell command, moving the file, changing its permissions,
+// or injecting metadata tags.
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tagging"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// File is a finished download passed through the pipeline.
+type File struct {
+	// Path is the current location of the file. Steps that move the file update it.
+	Path string
+
+	// Video is the metadata of the downloaded video.
+	Video *youtube.Video
+
+	// Playlist is where the video is in the playlist it was downloaded from, or
+	// nil if it wasn't downloaded from one.
+	Playlist *PlaylistEntry
+}
+
+// PlaylistEntry is the position of a video in a playlist being downloaded.
+type PlaylistEntry struct {
+	// Playlist is the metadata of the playlist.
+	Playlist *youtube.Playlist
+
+	// Index is the 1-based position of the video in the playlist.
+	Index int
+
+	// Count is the number of videos downloaded from the playlist.
+	Count int
+}
+
+// Step is a single post-processing step.
+type Step interface {
+	// Name returns a short description of the step for messages.
+	Name() string
+
+	// Run performs the step on the file.
+	Run(ctx context.Context, file *File) error
+}
+
+// ErrorPolicy controls what the pipeline does when a step fails.
+type ErrorPolicy int
+
+const (
+	// Abort stops the pipeline at the failed step.
+	Abort ErrorPolicy = iota
+	// Continue records the failure and runs the remaining steps.
+	Continue
+)
+
+// StepError is returned when a step fails.
+type StepError struct {
+	// Step is the name of the failed step.
+	Step string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("post-processing step %s: %v", e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// pipelineStep is a step with its error policy.
+type pipelineStep struct {
+	step   Step
+	policy ErrorPolicy
+}
+
+// Pipeline runs post-processing steps in order.
+type Pipeline struct {
+	steps []pipelineStep
+}
+
+// NewPipeline creates an empty Pipeline.
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Add appends a step with the given error policy and returns the pipeline.
+func (p *Pipeline) Add(step Step, policy ErrorPolicy) *Pipeline {
+	p.steps = append(p.steps, pipelineStep{step: step, policy: policy})
+	return p
+}
+
+// Len returns the number of steps in the pipeline.
+func (p *Pipeline) Len() int {
+	return len(p.steps)
+}
+
+// Run runs every step on the file in order.
+// A failing Abort step stops the pipeline and its *StepError is returned.
+// Failing Continue steps are joined into the returned error.
+func (p *Pipeline) Run(ctx context.Context, file *File) error {
+	var errs []error
+	for _, s := range p.steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := s.step.Run(ctx, file); err != nil {
+			stepErr := &StepError{Step: s.step.Name(), Err: err}
+			if s.policy == Abort {
+				return errors.Join(append(errs, stepErr)...)
+			}
+			errs = append(errs, stepErr)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MoveStep moves the file into a directory, creating it if needed.
+type MoveStep struct {
+	// Dir is the destination directory.
+	Dir string
+}
+
+// Name returns the step name.
+func (s *MoveStep) Name() string {
+	return "move to " + s.Dir
+}
+
+// Run moves the file and updates file.Path.
+func (s *MoveStep) Run(_ context.Context, file *File) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	target := filepath.Join(s.Dir, filepath.Base(file.Path))
+	if err := download.MoveFile(file.Path, target); err != nil {
+		return fmt.Errorf("moving file: %w", err)
+	}
+
+	file.Path = target
+	return nil
+}
+
+// ChmodStep changes the file's permissions.
+type ChmodStep struct {
+	// Mode is the permission bits to set.
+	Mode os.FileMode
+}
+
+// Name returns the step name.
+func (s *ChmodStep) Name() string {
+	return fmt.Sprintf("chmod %04o", s.Mode.Perm())
+}
+
+// Run changes the file mode.
+func (s *ChmodStep) Run(_ context.Context, file *File) error {
+	return os.Chmod(file.Path, s.Mode.Perm())
+}
+
+// TagStep writes the video metadata into the file's tags. Files downloaded
+// from a playlist are tagged as a track of an album: the playlist title is the
+// album, its author the album artist and the video's position the track number.
+type TagStep struct {
+	// Injector writes the tags. If nil, a default TagInjector is used.
+	Injector *tagging.TagInjector
+}
+
+// Name returns the step name.
+func (s *TagStep) Name() string {
+	return "tags"
+}
+
+// Run injects the video metadata into the file.
+func (s *TagStep) Run(ctx context.Context, file *File) error {
+	if file.Video == nil {
+		return errors.New("no video metadata")
+	}
+	injector := s.Injector
+	if injector == nil {
+		injector = tagging.NewTagInjector()
+	}
+	if err := injector.InjectTags(file.Path, file.Video); err != nil {
+		return err
+	}
+
+	entry := file.Playlist
+	if entry == nil || entry.Playlist == nil {
+		return nil
+	}
+	return injector.WriteTags(ctx, file.Path, &tagging.Tags{
+		Album:       entry.Playlist.Title,
+		AlbumArtist: entry.Playlist.Author.Name,
+		Track:       entry.Index,
+		TrackCount:  entry.Count,
+	})
+}
+
+// printf writes a message of a step to w, unless w is nil.
+func printf(w io.Writer, format string, args ...any) {
+	if w != nil {
+		_, _ = fmt.Fprintf(w, format, args...)
+	}
+}
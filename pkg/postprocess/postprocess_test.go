@@ -0,0 +1,167 @@
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tagging"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// recordStep records that it ran and returns err.
+type recordStep struct {
+	name string
+	err  error
+	ran  *[]string
+}
+
+func (s *recordStep) Name() string { return s.name }
+
+func (s *recordStep) Run(context.Context, *File) error {
+	*s.ran = append(*s.ran, s.name)
+	return s.err
+}
+
+func writeTestFile(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("media"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPipeline_RunsStepsInOrder(t *testing.T) {
+	var ran []string
+	p := NewPipeline().
+		Add(&recordStep{name: "a", ran: &ran}, Abort).
+		Add(&recordStep{name: "b", ran: &ran}, Abort)
+
+	if err := p.Run(context.Background(), &File{}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(ran) != 2 || ran[0] != "a" || ran[1] != "b" {
+		t.Errorf("ran %v, want [a b]", ran)
+	}
+	if p.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", p.Len())
+	}
+}
+
+func TestPipeline_AbortStopsPipeline(t *testing.T) {
+	var ran []string
+	failure := errors.New("boom")
+	p := NewPipeline().
+		Add(&recordStep{name: "a", err: failure, ran: &ran}, Abort).
+		Add(&recordStep{name: "b", ran: &ran}, Abort)
+
+	err := p.Run(context.Background(), &File{})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the step error, got %v", err)
+	}
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) || stepErr.Step != "a" {
+		t.Errorf("expected a StepError for step a, got %v", err)
+	}
+	if len(ran) != 1 {
+		t.Errorf("ran %v, want only [a]", ran)
+	}
+}
+
+func TestPipeline_ContinueRunsRemainingSteps(t *testing.T) {
+	var ran []string
+	first, second := errors.New("first"), errors.New("second")
+	p := NewPipeline().
+		Add(&recordStep{name: "a", err: first, ran: &ran}, Continue).
+		Add(&recordStep{name: "b", ran: &ran}, Abort).
+		Add(&recordStep{name: "c", err: second, ran: &ran}, Continue)
+
+	err := p.Run(context.Background(), &File{})
+	if !errors.Is(err, first) || !errors.Is(err, second) {
+		t.Errorf("expected both step errors, got %v", err)
+	}
+	if len(ran) != 3 {
+		t.Errorf("ran %v, want all three steps", ran)
+	}
+}
+
+func TestMoveStep(t *testing.T) {
+	path := writeTestFile(t, "video.mp4")
+	dir := filepath.Join(t.TempDir(), "archive", "2024")
+	file := &File{Path: path}
+
+	if err := (&MoveStep{Dir: dir}).Run(context.Background(), file); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	want := filepath.Join(dir, "video.mp4")
+	if file.Path != want {
+		t.Errorf("Path = %q, want %q", file.Path, want)
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("moved file missing: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("original should be gone")
+	}
+}
+
+func TestChmodStep(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not supported on Windows")
+	}
+	path := writeTestFile(t, "video.mp4")
+
+	if err := (&ChmodStep{Mode: 0o600}).Run(context.Background(), &File{Path: path}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %04o, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestTagStep_UnsupportedFormat(t *testing.T) {
+	path := writeTestFile(t, "video.webm")
+	err := (&TagStep{}).Run(context.Background(), &File{Path: path, Video: &youtube.Video{Title: "Test"}})
+	if err == nil {
+		t.Error("expected error for a format without tag support")
+	}
+}
+
+func TestTagStep_PlaylistAlbum(t *testing.T) {
+	// The ID3 library needs a file at least as long as a tag header
+	path := filepath.Join(t.TempDir(), "song.mp3")
+	if err := os.WriteFile(path, make([]byte, 64), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file := &File{
+		Path:     path,
+		Video:    &youtube.Video{Title: "Song", Author: youtube.Author{Name: "Channel"}},
+		Playlist: &PlaylistEntry{Playlist: &youtube.Playlist{Title: "Best of 2024", Author: youtube.Author{Name: "Curator"}}, Index: 3, Count: 12},
+	}
+	if err := (&TagStep{}).Run(context.Background(), file); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	tags, err := tagging.ReadTags(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags.Title != "Song" || tags.Artist != "Channel" || tags.Album != "Best of 2024" || tags.AlbumArtist != "Curator" || tags.Track != 3 || tags.TrackCount != 12 {
+		t.Errorf("tags = %+v", tags)
+	}
+}
+
+func TestTagStep_RequiresVideo(t *testing.T) {
+	if err := (&TagStep{}).Run(context.Background(), &File{Path: "a.mp3"}); err == nil {
+		t.Error("expected error without video metadata")
+	}
+}
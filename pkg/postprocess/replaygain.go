@@ -0,0 +1,59 @@
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tagging"
+)
+
+// MeasureFunc measures the loudness of the audio in inputPath, like
+// ffmpeg.MeasureReplayGain.
+type MeasureFunc func(ctx context.Context, inputPath string) (*ffmpeg.ReplayGain, error)
+
+// ReplayGainStep measures the file's audio and writes its ReplayGain track
+// gain and peak to the file's tags, leaving the audio untouched; see
+// tagging.TagInjector.WriteReplayGain for the formats supported.
+type ReplayGainStep struct {
+	// Measure measures the audio. If nil, ffmpeg.MeasureReplayGain is used.
+	Measure MeasureFunc
+
+	// Injector writes the tags. If nil, a default TagInjector is used.
+	Injector *tagging.TagInjector
+
+	// Output receives a line with the gain written. If nil, nothing is printed.
+	Output io.Writer
+}
+
+// Name returns the step name.
+func (s *ReplayGainStep) Name() string {
+	return "replaygain"
+}
+
+// Run measures the file and tags it with the gain. Silent files are left alone.
+func (s *ReplayGainStep) Run(ctx context.Context, file *File) error {
+	measure := s.Measure
+	if measure == nil {
+		measure = ffmpeg.MeasureReplayGain
+	}
+	gain, err := measure(ctx, file.Path)
+	if errors.Is(err, ffmpeg.ErrSilentAudio) {
+		printf(s.Output, "No sound to compute ReplayGain for\n")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	injector := s.Injector
+	if injector == nil {
+		injector = tagging.NewTagInjector()
+	}
+	if err := injector.WriteReplayGain(ctx, file.Path, gain); err != nil {
+		return err
+	}
+	printf(s.Output, "ReplayGain: %+.2f dB, peak %.6f\n", gain.TrackGain(), gain.TrackPeak())
+	return nil
+}
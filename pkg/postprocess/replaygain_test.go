@@ -0,0 +1,78 @@
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+)
+
+func TestReplayGainStep_WritesGain(t *testing.T) {
+	// A FLAC file with only its stream info block
+	path := filepath.Join(t.TempDir(), "song.flac")
+	flac := append([]byte("fLaC\x80\x00\x00\x22"), make([]byte, 34)...)
+	if err := os.WriteFile(path, flac, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(bytes.Buffer)
+	step := &ReplayGainStep{
+		Measure: func(_ context.Context, inputPath string) (*ffmpeg.ReplayGain, error) {
+			if inputPath != path {
+				t.Errorf("measured %q, want %q", inputPath, path)
+			}
+			return &ffmpeg.ReplayGain{Integrated: -14.1, TruePeak: -0.3}, nil
+		},
+		Output: out,
+	}
+
+	if err := step.Run(context.Background(), &File{Path: path}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// Vorbis comments are plain text in the file
+	if data, _ := os.ReadFile(path); !bytes.Contains(data, []byte("REPLAYGAIN_TRACK_GAIN=-3.90 dB")) {
+		t.Errorf("file = %q, want a -3.90 dB track gain comment", data)
+	}
+	if !strings.Contains(out.String(), "ReplayGain: -3.90 dB, peak 0.966051") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestReplayGainStep_SilentAudio(t *testing.T) {
+	out := new(bytes.Buffer)
+	step := &ReplayGainStep{
+		Measure: func(context.Context, string) (*ffmpeg.ReplayGain, error) {
+			return nil, ffmpeg.ErrSilentAudio
+		},
+		Output: out,
+	}
+	if err := step.Run(context.Background(), &File{Path: "song.mp3"}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "No sound") {
+		t.Errorf("output = %q, want a message about the silent file", out)
+	}
+}
+
+func TestReplayGainStep_Errors(t *testing.T) {
+	measured := func(context.Context, string) (*ffmpeg.ReplayGain, error) {
+		return &ffmpeg.ReplayGain{Integrated: -14}, nil
+	}
+
+	err := (&ReplayGainStep{Measure: func(context.Context, string) (*ffmpeg.ReplayGain, error) {
+		return nil, ffmpeg.ErrNotFound
+	}}).Run(context.Background(), &File{Path: "song.mp3"})
+	if !errors.Is(err, ffmpeg.ErrNotFound) {
+		t.Errorf("Run() error = %v, want it to wrap ffmpeg.ErrNotFound", err)
+	}
+
+	err = (&ReplayGainStep{Measure: measured}).Run(context.Background(), &File{Path: "song.m4a"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported file format") {
+		t.Errorf("Run() error = %v, want an unsupported format error", err)
+	}
+}
@@ -0,0 +1,121 @@
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// storyboardFrameQuality is the JPEG quality split storyboard frames are saved with.
+const storyboardFrameQuality = 90
+
+// StoryboardStep saves the video's highest resolution storyboard, the preview
+// frames shown while seeking, into a directory named after the file with a
+// .storyboard extension. The sheets are saved as they are downloaded, or with
+// Split each frame is saved on its own, named by its number and time.
+// Videos without storyboards are left alone.
+type StoryboardStep struct {
+	// Downloader fetches the sheets. If nil, one with the default HTTP client is used.
+	Downloader *youtube.StoryboardDownloader
+
+	// Split saves each frame instead of the sheets.
+	Split bool
+
+	// Output receives a line saying where the storyboard was saved. If nil,
+	// nothing is printed.
+	Output io.Writer
+}
+
+// Name returns the step name.
+func (s *StoryboardStep) Name() string {
+	return "write storyboards"
+}
+
+// Run downloads the storyboard next to the file.
+func (s *StoryboardStep) Run(ctx context.Context, file *File) error {
+	if file.Video == nil {
+		return errors.New("no video metadata")
+	}
+	storyboards := file.Video.Storyboards
+	if len(storyboards) == 0 {
+		printf(s.Output, "No storyboard to write\n")
+		return nil
+	}
+	storyboard := &storyboards[len(storyboards)-1]
+
+	downloader := s.Downloader
+	if downloader == nil {
+		downloader = youtube.NewStoryboardDownloader(nil)
+	}
+	dir := StoryboardDir(file.Path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating storyboard directory: %w", err)
+	}
+
+	written := 0
+	for n := range storyboard.URLs {
+		sheet, err := downloader.Download(ctx, storyboard, n)
+		if err != nil {
+			return fmt.Errorf("storyboard sheet %d: %w", n+1, err)
+		}
+		if !s.Split {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("sheet-%03d.jpg", n+1)), sheet, 0o644); err != nil {
+				return fmt.Errorf("writing storyboard sheet %d: %w", n+1, err)
+			}
+			written++
+			continue
+		}
+
+		frames, err := youtube.SplitStoryboardSheet(storyboard, n, sheet)
+		if err != nil {
+			return fmt.Errorf("storyboard sheet %d: %w", n+1, err)
+		}
+		for _, frame := range frames {
+			if err := writeFrame(filepath.Join(dir, frameName(frame)), frame); err != nil {
+				return fmt.Errorf("writing storyboard frame %d: %w", frame.Index+1, err)
+			}
+			written++
+		}
+	}
+
+	kind := "sheets"
+	if s.Split {
+		kind = "frames"
+	}
+	printf(s.Output, "Storyboard saved: %s (%d %s of %dx%d)\n", dir, written, kind, storyboard.Width, storyboard.Height)
+	return nil
+}
+
+// StoryboardDir returns the directory StoryboardStep saves the storyboard of
+// the file at path in.
+// Example: "video.mp4" -> "video.storyboard"
+func StoryboardDir(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".storyboard"
+}
+
+// frameName names a split frame by its number and time, so the files sort in
+// order, such as "frame-0013-1m0s.jpg".
+func frameName(frame youtube.StoryboardFrame) string {
+	return fmt.Sprintf("frame-%04d-%s.jpg", frame.Index+1, frame.Time.Truncate(time.Second))
+}
+
+// writeFrame saves a frame as a JPEG file.
+func writeFrame(path string, frame youtube.StoryboardFrame) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := jpeg.Encode(f, frame.Image, &jpeg.Options{Quality: storyboardFrameQuality}); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
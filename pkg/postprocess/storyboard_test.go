@@ -0,0 +1,101 @@
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// serveStoryboard serves a sheet of 2 by 2 black frames of 8 by 8 pixels at
+// every path, and returns a storyboard of 6 frames on 2 sheets.
+func serveStoryboard(t *testing.T) (*youtube.StoryboardDownloader, youtube.Storyboard) {
+	t.Helper()
+	var sheet bytes.Buffer
+	if err := jpeg.Encode(&sheet, image.NewGray(image.Rect(0, 0, 16, 16)), nil); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(sheet.Bytes())
+	}))
+	t.Cleanup(server.Close)
+
+	return youtube.NewStoryboardDownloader(server.Client()), youtube.Storyboard{
+		Width: 8, Height: 8, Count: 6, Columns: 2, Rows: 2, Interval: 30 * time.Second,
+		URLs: []string{server.URL + "/M0.jpg", server.URL + "/M1.jpg"},
+	}
+}
+
+func TestStoryboardStep_WritesSheets(t *testing.T) {
+	downloader, storyboard := serveStoryboard(t)
+	low := youtube.Storyboard{Width: 1, Height: 1, Count: 1, Columns: 1, Rows: 1, URLs: []string{"http://invalid.test/low.jpg"}}
+	video := &youtube.Video{Storyboards: []youtube.Storyboard{low, storyboard}}
+	path := writeTestFile(t, "video.mp4")
+	out := new(bytes.Buffer)
+
+	step := &StoryboardStep{Downloader: downloader, Output: out}
+	if err := step.Run(context.Background(), &File{Path: path, Video: video}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	dir := filepath.Join(filepath.Dir(path), "video.storyboard")
+	for _, name := range []string{"sheet-001.jpg", "sheet-002.jpg"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("sheet not written: %v", err)
+		}
+	}
+	if !strings.Contains(out.String(), "2 sheets of 8x8") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestStoryboardStep_SplitsFrames(t *testing.T) {
+	downloader, storyboard := serveStoryboard(t)
+	path := writeTestFile(t, "video.mp4")
+
+	step := &StoryboardStep{Downloader: downloader, Split: true}
+	if err := step.Run(context.Background(), &File{Path: path, Video: &youtube.Video{Storyboards: []youtube.Storyboard{storyboard}}}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(StoryboardDir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := "frame-0001-0s.jpg frame-0002-30s.jpg frame-0003-1m0s.jpg frame-0004-1m30s.jpg frame-0005-2m0s.jpg frame-0006-2m30s.jpg"
+	if strings.Join(names, " ") != want {
+		t.Errorf("frames = %q, want %q", names, want)
+	}
+}
+
+func TestStoryboardStep_NoStoryboards(t *testing.T) {
+	path := writeTestFile(t, "video.mp4")
+	out := new(bytes.Buffer)
+
+	step := &StoryboardStep{Output: out}
+	if err := step.Run(context.Background(), &File{Path: path, Video: &youtube.Video{}}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "No storyboard to write") {
+		t.Errorf("output = %q", out)
+	}
+	if _, err := os.Stat(StoryboardDir(path)); !os.IsNotExist(err) {
+		t.Errorf("storyboard directory created for a video without storyboards: %v", err)
+	}
+	if err := step.Run(context.Background(), &File{Path: path}); err == nil {
+		t.Error("Run() without video metadata succeeded")
+	}
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Local stores objects as files in a directory.
+type Local struct {
+	// Dir is the directory keys are relative to.
+	Dir string
+}
+
+// Put writes src to a temporary file next to the target and renames it into
+// place once complete.
+func (l *Local) Put(_ context.Context, key string, src io.WriterTo) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(l.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	tmp, err := writeToFile(filepath.Dir(target), src)
+	if err != nil {
+		return err
+	}
+	// Temporary files are private; the result gets the usual permissions
+	_ = os.Chmod(tmp, 0o644)
+	if err := os.Rename(tmp, target); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("moving file into place: %w", err)
+	}
+	return nil
+}
+
+// URL returns the file path for key.
+func (l *Local) URL(key string) string {
+	if cleaned, err := cleanKey(key); err == nil {
+		key = cleaned
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(key))
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocal_Put(t *testing.T) {
+	dir := t.TempDir()
+	l := &Local{Dir: dir}
+
+	if err := l.Put(context.Background(), "Channel/video.mp4", strings.NewReader("media")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	target := filepath.Join(dir, "Channel", "video.mp4")
+	if data, err := os.ReadFile(target); err != nil || string(data) != "media" {
+		t.Errorf("file = %q, %v", data, err)
+	}
+	if info, err := os.Stat(target); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("file mode = %v, %v", info.Mode(), err)
+	}
+	if got := l.URL("Channel/video.mp4"); got != target {
+		t.Errorf("URL() = %q, want %q", got, target)
+	}
+}
+
+func TestLocal_PutFailureLeavesNothing(t *testing.T) {
+	dir := t.TempDir()
+	srcErr := errors.New("download failed")
+	src := WriterToFunc(func(w io.Writer) (int64, error) {
+		_, _ = w.Write([]byte("partial"))
+		return 7, srcErr
+	})
+
+	if err := (&Local{Dir: dir}).Put(context.Background(), "video.mp4", src); !errors.Is(err, srcErr) {
+		t.Fatalf("Put() error = %v, want the source's", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("directory has %d entries after a failed Put, want none", len(entries))
+	}
+}
+
+func TestLocal_PutInvalidKey(t *testing.T) {
+	dir := t.TempDir()
+	if err := (&Local{Dir: filepath.Join(dir, "root")}).Put(context.Background(), "../escape.mp4", strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Put() error = %v, want ErrInvalidKey", err)
+	}
+}
@@ -0,0 +1,410 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPartSize is the size of the parts large objects are uploaded in. S3
+// allows at most 10,000 parts, so objects up to about 156 GiB fit.
+const DefaultPartSize = 16 << 20
+
+// maxParts is the most parts S3 accepts for one multipart upload.
+const maxParts = 10000
+
+// ErrMissingCredentials is returned by NewS3FromEnv when no access key is set.
+var ErrMissingCredentials = errors.New("missing S3 credentials (set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY)")
+
+// S3 stores objects in a bucket of Amazon S3 or an S3-compatible service such
+// as MinIO or Cloudflare R2. Requests are signed with AWS Signature Version 4.
+// Objects are streamed: those smaller than PartSize are sent in one request and
+// larger ones in a multipart upload, so at most one part is held in memory.
+type S3 struct {
+	// Bucket is the bucket name.
+	Bucket string
+
+	// Prefix is prepended to keys, such as "videos" for "videos/<key>".
+	Prefix string
+
+	// Region is the bucket's region, used to sign requests.
+	Region string
+
+	// Endpoint is the base URL of an S3-compatible service, such as
+	// http://127.0.0.1:9000. Buckets are then addressed by path. If empty, the
+	// AWS endpoint of Region is used with the bucket as a subdomain.
+	Endpoint string
+
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// SessionToken is needed with temporary credentials.
+	SessionToken string
+
+	// Client is the HTTP client to use for requests.
+	Client *http.Client
+
+	// PartSize is the size of multipart upload parts. 0 uses DefaultPartSize.
+	PartSize int
+
+	// now returns the signing time, replaced in tests.
+	now func() time.Time
+}
+
+// NewS3FromEnv returns an S3 storage for bucket configured from the standard AWS
+// environment variables: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN for credentials, AWS_REGION or AWS_DEFAULT_REGION (default
+// us-east-1), and AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL for S3-compatible services.
+func NewS3FromEnv(bucket, prefix string, client *http.Client) (*S3, error) {
+	s := &S3{
+		Bucket:          bucket,
+		Prefix:          prefix,
+		Region:          firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		Endpoint:        strings.TrimSuffix(firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"), "/"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Client:          client,
+	}
+	if s.Region == "" {
+		s.Region = "us-east-1"
+	}
+	if s.AccessKeyID == "" || s.SecretAccessKey == "" {
+		return nil, ErrMissingCredentials
+	}
+	return s, nil
+}
+
+// firstEnv returns the value of the first set environment variable of names.
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// S3Error is an error response from S3.
+type S3Error struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Code is S3's error code, such as "NoSuchBucket" or "AccessDenied".
+	Code string
+
+	Message string
+}
+
+func (e *S3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("S3 returned status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("S3 error %s: %s", e.Code, e.Message)
+}
+
+// URL returns the s3:// URL of the object for key.
+func (s *S3) URL(key string) string {
+	return "s3://" + s.Bucket + "/" + s.objectKey(key)
+}
+
+// objectKey returns the full object name of key under the prefix.
+func (s *S3) objectKey(key string) string {
+	if cleaned, err := cleanKey(key); err == nil {
+		key = cleaned
+	}
+	if s.Prefix == "" {
+		return key
+	}
+	return strings.TrimSuffix(s.Prefix, "/") + "/" + key
+}
+
+// Put uploads what src writes to the object for key.
+func (s *S3) Put(ctx context.Context, key string, src io.WriterTo) error {
+	if _, err := cleanKey(key); err != nil {
+		return err
+	}
+	object := s.objectKey(key)
+	return pipeTo(src, func(r io.Reader) error {
+		return s.upload(ctx, object, r)
+	})
+}
+
+// upload sends r to object in one request if it fits in a part, and in a
+// multipart upload otherwise.
+func (s *S3) upload(ctx context.Context, object string, r io.Reader) error {
+	partSize := s.PartSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	buf := make([]byte, partSize)
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		_, err := s.do(ctx, http.MethodPut, object, nil, buf[:n])
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	uploadID, err := s.createMultipartUpload(ctx, object)
+	if err != nil {
+		return err
+	}
+	if err := s.uploadParts(ctx, object, uploadID, buf, r); err != nil {
+		// Parts of an unfinished upload are billed until it is aborted
+		abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		_, _ = s.do(abortCtx, http.MethodDelete, object, url.Values{"uploadId": {uploadID}}, nil)
+		return err
+	}
+	return nil
+}
+
+// completedPart is a part listed in a CompleteMultipartUpload request.
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// uploadParts uploads the full first part in buf and the rest of r, then
+// completes the upload.
+func (s *S3) uploadParts(ctx context.Context, object, uploadID string, buf []byte, r io.Reader) error {
+	var parts []completedPart
+	part := buf
+	for number := 1; ; number++ {
+		if number > maxParts {
+			return fmt.Errorf("object is larger than %d parts of %d bytes", maxParts, len(buf))
+		}
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		resp, err := s.do(ctx, http.MethodPut, object, query, part)
+		if err != nil {
+			return fmt.Errorf("uploading part %d: %w", number, err)
+		}
+		parts = append(parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+
+		n, err := io.ReadFull(r, buf)
+		if n == 0 && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
+			break
+		}
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		part = buf[:n]
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return fmt.Errorf("encoding upload completion: %w", err)
+	}
+	resp, err := s.do(ctx, http.MethodPost, object, url.Values{"uploadId": {uploadID}}, body)
+	if err != nil {
+		return fmt.Errorf("completing upload: %w", err)
+	}
+	// S3 can report a failed completion in the body of a 200 response
+	return parseS3Error(resp.StatusCode, resp.body)
+}
+
+// createMultipartUpload starts a multipart upload and returns its ID.
+func (s *S3) createMultipartUpload(ctx context.Context, object string) (string, error) {
+	resp, err := s.do(ctx, http.MethodPost, object, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", fmt.Errorf("starting multipart upload: %w", err)
+	}
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(resp.body, &result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("starting multipart upload: no upload ID in response")
+	}
+	return result.UploadID, nil
+}
+
+// s3Response is a successful response with its body read.
+type s3Response struct {
+	StatusCode int
+	Header     http.Header
+	body       []byte
+}
+
+// do sends a signed request for object and returns the response, or an
+// *S3Error for a non-2xx status.
+func (s *S3) do(ctx context.Context, method, object string, query url.Values, body []byte) (*s3Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(object, query), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.ContentLength = int64(len(body))
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	s.sign(req, body, now())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if err := parseS3Error(resp.StatusCode, data); err != nil {
+			return nil, err
+		}
+		return nil, &S3Error{StatusCode: resp.StatusCode}
+	}
+	return &s3Response{StatusCode: resp.StatusCode, Header: resp.Header, body: data}, nil
+}
+
+// parseS3Error returns the error in an S3 error document, or nil if data isn't one.
+func parseS3Error(statusCode int, data []byte) error {
+	var doc struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(data, &doc) != nil || doc.XMLName.Local != "Error" {
+		return nil
+	}
+	return &S3Error{StatusCode: statusCode, Code: doc.Code, Message: doc.Message}
+}
+
+// objectURL returns the URL of object with the query parameters.
+func (s *S3) objectURL(object string, query url.Values) string {
+	var base string
+	if s.Endpoint != "" {
+		base = s.Endpoint + "/" + uriEncode(s.Bucket, false) + "/"
+	} else {
+		base = "https://" + s.Bucket + ".s3." + s.Region + ".amazonaws.com/"
+	}
+	u := base + uriEncode(object, false)
+	if len(query) > 0 {
+		u += "?" + canonicalQuery(query)
+	}
+	return u
+}
+
+// sign adds AWS Signature Version 4 headers to req for the given body. Every
+// header set on the request at this point is signed.
+func (s *S3) sign(req *http.Request, body []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKeyID+"/"+scope+
+		",SignedHeaders="+signedHeaders+",Signature="+signature)
+}
+
+// canonicalQuery encodes query parameters sorted by name, as signing requires.
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes s the way Signature Version 4 expects: everything
+// but unreserved characters, and slashes too when encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
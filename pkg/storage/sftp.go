@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// SFTP stores objects on an SSH server with the OpenSSH sftp client, which
+// authenticates with the user's SSH configuration, agent and keys. It runs in
+// batch mode, so hosts needing a password or an unknown host key fail instead
+// of prompting. The client uploads files, so objects are written to a local
+// temporary file first and removed once uploaded.
+type SFTP struct {
+	// Host is the server to connect to, or a Host alias from ~/.ssh/config.
+	Host string
+
+	// User is the login name. If empty, the SSH configuration decides.
+	User string
+
+	// Port is the SSH port. 0 uses the SSH configuration, usually 22.
+	Port int
+
+	// Dir is the remote directory keys are relative to. Relative paths start
+	// at the login directory.
+	Dir string
+
+	// Command is the sftp program to run. If empty, "sftp" is looked up in PATH.
+	Command string
+
+	// run executes the sftp client and returns its combined output, replaced in tests.
+	run func(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// URL returns the sftp:// URL of the object for key.
+func (s *SFTP) URL(key string) string {
+	host := s.Host
+	if s.User != "" {
+		host = s.User + "@" + host
+	}
+	if s.Port != 0 {
+		host += ":" + strconv.Itoa(s.Port)
+	}
+	remote := s.remotePath(key)
+	if !strings.HasPrefix(remote, "/") {
+		remote = "/" + remote
+	}
+	return "sftp://" + host + remote
+}
+
+// remotePath returns the path of key on the server.
+func (s *SFTP) remotePath(key string) string {
+	if cleaned, err := cleanKey(key); err == nil {
+		key = cleaned
+	}
+	if s.Dir == "" {
+		return key
+	}
+	return path.Join(s.Dir, key)
+}
+
+// Put writes src to a temporary file and uploads it next to the target, then
+// renames it into place so a partial upload never has the final name.
+func (s *SFTP) Put(ctx context.Context, key string, src io.WriterTo) error {
+	if _, err := cleanKey(key); err != nil {
+		return err
+	}
+	local, err := writeToFile("", src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(local) }()
+
+	remote := s.remotePath(key)
+	batch, err := os.CreateTemp("", "ytdl-sftp-*.batch")
+	if err != nil {
+		return fmt.Errorf("creating batch file: %w", err)
+	}
+	defer func() { _ = os.Remove(batch.Name()) }()
+	_, err = batch.WriteString(sftpBatch(local, remote))
+	if closeErr := batch.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing batch file: %w", err)
+	}
+
+	command := s.Command
+	if command == "" {
+		command = "sftp"
+	}
+	args := []string{"-b", batch.Name(), "-o", "BatchMode=yes"}
+	if s.Port != 0 {
+		args = append(args, "-P", strconv.Itoa(s.Port))
+	}
+	destination := s.Host
+	if s.User != "" {
+		destination = s.User + "@" + destination
+	}
+	args = append(args, destination)
+
+	run := s.run
+	if run == nil {
+		run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return exec.CommandContext(ctx, name, args...).CombinedOutput()
+		}
+	}
+	if output, err := run(ctx, command, args...); err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("uploading with sftp: %w: %s", err, msg)
+		}
+		return fmt.Errorf("uploading with sftp: %w", err)
+	}
+	return nil
+}
+
+// sftpBatch returns the sftp commands that upload local to remote. The mkdir
+// commands are prefixed with "-" since the directories may already exist. The
+// rename replaces an existing file on servers with the posix-rename extension,
+// which OpenSSH has; rm isn't used since it expands wildcards in titles.
+func sftpBatch(local, remote string) string {
+	var b strings.Builder
+	dir := path.Dir(remote)
+	var parents []string
+	for d := dir; d != "." && d != "/"; d = path.Dir(d) {
+		parents = append(parents, d)
+	}
+	for i := len(parents) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "-mkdir %s\n", sftpQuote(parents[i]))
+	}
+	partial := remote + ".part"
+	fmt.Fprintf(&b, "put %s %s\n", sftpQuote(local), sftpQuote(partial))
+	fmt.Fprintf(&b, "rename %s %s\n", sftpQuote(partial), sftpQuote(remote))
+	return b.String()
+}
+
+// sftpQuote quotes an argument for an sftp batch file.
+func sftpQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSFTP_URL(t *testing.T) {
+	tests := []struct {
+		s    SFTP
+		want string
+	}{
+		{SFTP{Host: "nas", Dir: "/srv/videos"}, "sftp://nas/srv/videos/Channel/video.mp4"},
+		{SFTP{Host: "nas", User: "me", Port: 2222, Dir: "videos"}, "sftp://me@nas:2222/videos/Channel/video.mp4"},
+		{SFTP{Host: "nas"}, "sftp://nas/Channel/video.mp4"},
+	}
+	for _, tt := range tests {
+		if got := tt.s.URL("Channel/video.mp4"); got != tt.want {
+			t.Errorf("URL() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestSFTPBatch(t *testing.T) {
+	got := sftpBatch("/tmp/.ytdl-upload-1", `/srv/videos/Chan "A"/video.mp4`)
+	want := `-mkdir "/srv"
+-mkdir "/srv/videos"
+-mkdir "/srv/videos/Chan \"A\""
+put "/tmp/.ytdl-upload-1" "/srv/videos/Chan \"A\"/video.mp4.part"
+rename "/srv/videos/Chan \"A\"/video.mp4.part" "/srv/videos/Chan \"A\"/video.mp4"
+`
+	if got != want {
+		t.Errorf("sftpBatch() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSFTP_Put(t *testing.T) {
+	var gotName string
+	var gotArgs []string
+	var batch, uploaded string
+	s := &SFTP{Host: "nas", User: "me", Port: 2222, Dir: "videos"}
+	s.run = func(_ context.Context, name string, args ...string) ([]byte, error) {
+		gotName, gotArgs = name, args
+		data, _ := os.ReadFile(args[1])
+		batch = string(data)
+		local := strings.Split(strings.Split(batch, "put \"")[1], "\"")[0]
+		content, _ := os.ReadFile(local)
+		uploaded = string(content)
+		return nil, nil
+	}
+
+	if err := s.Put(context.Background(), "video.mp4", strings.NewReader("media")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if gotName != "sftp" || strings.Join(gotArgs[2:], " ") != "-o BatchMode=yes -P 2222 me@nas" {
+		t.Errorf("ran %s %v", gotName, gotArgs)
+	}
+	if !strings.Contains(batch, `rename "videos/video.mp4.part" "videos/video.mp4"`) {
+		t.Errorf("batch =\n%s", batch)
+	}
+	if uploaded != "media" {
+		t.Errorf("uploaded %q", uploaded)
+	}
+	if _, err := os.Stat(gotArgs[1]); !os.IsNotExist(err) {
+		t.Error("batch file was not removed")
+	}
+}
+
+func TestSFTP_PutFailure(t *testing.T) {
+	s := &SFTP{Host: "nas"}
+	s.run = func(context.Context, string, ...string) ([]byte, error) {
+		return []byte("Permission denied (publickey).\n"), errors.New("exit status 255")
+	}
+	err := s.Put(context.Background(), "video.mp4", strings.NewReader("media"))
+	if err == nil || !strings.Contains(err.Error(), "Permission denied (publickey).") {
+		t.Errorf("error = %v, want the sftp output", err)
+	}
+}
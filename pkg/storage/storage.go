@@ -0,0 +1,153 @@
+// Package storage writes downloaded files to local or remote backends, such as
+// a directory, an S3-compatible bucket or an SFTP server.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedScheme is returned by Open for a URL without a known backend.
+	ErrUnsupportedScheme = errors.New("unsupported storage URL")
+
+	// ErrInvalidKey is returned for keys that are empty or leave the storage root.
+	ErrInvalidKey = errors.New("invalid storage key")
+)
+
+// Storage stores objects by key. Keys are slash-separated paths relative to the
+// storage root, such as "Channel/Video Title.mp4".
+type Storage interface {
+	// Put stores the bytes src writes under key, replacing any object already
+	// there. The object only appears once src has written everything; if src
+	// or the upload fails, nothing is stored.
+	Put(ctx context.Context, key string, src io.WriterTo) error
+
+	// URL returns where the object for key is stored, for display.
+	URL(key string) string
+}
+
+// WriterToFunc adapts a function to io.WriterTo, so a download can be streamed
+// to a Storage as it is produced instead of being written to a file first.
+type WriterToFunc func(w io.Writer) (int64, error)
+
+// WriteTo calls f(w).
+func (f WriterToFunc) WriteTo(w io.Writer) (int64, error) {
+	return f(w)
+}
+
+// Open returns the storage for a URL:
+//   - s3://bucket/prefix stores objects in an S3-compatible bucket, see NewS3FromEnv.
+//   - sftp://[user@]host[:port]/dir uploads with the OpenSSH sftp client.
+//   - file:///dir writes into a local directory.
+//
+// client is used for HTTP-based backends.
+func Open(rawURL string, client *http.Client) (Storage, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedScheme, err)
+	}
+	switch u.Scheme {
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("%w: missing bucket in %q", ErrUnsupportedScheme, rawURL)
+		}
+		s, err := NewS3FromEnv(u.Host, strings.Trim(u.Path, "/"), client)
+		if err != nil {
+			return nil, err
+		}
+		if endpoint := u.Query().Get("endpoint"); endpoint != "" {
+			s.Endpoint = strings.TrimSuffix(endpoint, "/")
+		}
+		if region := u.Query().Get("region"); region != "" {
+			s.Region = region
+		}
+		return s, nil
+	case "sftp":
+		if u.Hostname() == "" {
+			return nil, fmt.Errorf("%w: missing host in %q", ErrUnsupportedScheme, rawURL)
+		}
+		s := &SFTP{Host: u.Hostname(), User: u.User.Username(), Dir: u.Path}
+		if port := u.Port(); port != "" {
+			if s.Port, err = strconv.Atoi(port); err != nil {
+				return nil, fmt.Errorf("%w: invalid port in %q", ErrUnsupportedScheme, rawURL)
+			}
+		}
+		return s, nil
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("%w: missing path in %q", ErrUnsupportedScheme, rawURL)
+		}
+		return &Local{Dir: u.Path}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q (use s3://, sftp:// or file://)", ErrUnsupportedScheme, rawURL)
+	}
+}
+
+// cleanKey normalizes a key and rejects those that would escape the storage root.
+func cleanKey(key string) (string, error) {
+	slashed := strings.ReplaceAll(key, "\\", "/")
+	cleaned := path.Clean("/" + slashed)[1:]
+	if cleaned == "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	for _, segment := range strings.Split(slashed, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return cleaned, nil
+}
+
+// writeToFile writes src into a temporary file in dir and returns its path.
+// The file is removed if src fails.
+func writeToFile(dir string, src io.WriterTo) (string, error) {
+	f, err := os.CreateTemp(dir, ".ytdl-upload-*")
+	if err != nil {
+		return "", fmt.Errorf("creating temporary file: %w", err)
+	}
+	if _, err := src.WriteTo(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("writing temporary file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// pipeTo runs src in the background and lets upload read what it writes. If the
+// upload stops early, src's writes fail so it stops too. src's error is returned
+// in preference to the upload's, since a failed download makes the upload fail.
+func pipeTo(src io.WriterTo, upload func(r io.Reader) error) error {
+	pr, pw := io.Pipe()
+	srcErr := make(chan error, 1)
+	go func() {
+		_, err := src.WriteTo(pw)
+		_ = pw.CloseWithError(err)
+		srcErr <- err
+	}()
+
+	err := upload(pr)
+	if err != nil {
+		_ = pr.CloseWithError(err)
+	} else {
+		// Drain anything src writes after the upload stopped reading, such as a
+		// trailing empty write, so it can finish
+		_, _ = io.Copy(io.Discard, pr)
+	}
+	if writeErr := <-srcErr; writeErr != nil && !errors.Is(writeErr, io.ErrClosedPipe) && writeErr != err {
+		return writeErr
+	}
+	return err
+}
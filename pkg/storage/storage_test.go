@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_ENDPOINT_URL_S3", "")
+	t.Setenv("AWS_ENDPOINT_URL", "")
+
+	s, err := Open("s3://videos/archive/2026/", http.DefaultClient)
+	if err != nil {
+		t.Fatalf("Open(s3) error = %v", err)
+	}
+	if s3, ok := s.(*S3); !ok || s3.Bucket != "videos" || s3.Prefix != "archive/2026" || s3.Region != "eu-west-1" || s3.Endpoint != "" {
+		t.Errorf("Open(s3) = %#v", s)
+	}
+
+	s, err = Open("s3://videos?endpoint=http://127.0.0.1:9000/&region=local", http.DefaultClient)
+	if err != nil {
+		t.Fatalf("Open(s3 with endpoint) error = %v", err)
+	}
+	if s3 := s.(*S3); s3.Endpoint != "http://127.0.0.1:9000" || s3.Region != "local" || s3.Prefix != "" {
+		t.Errorf("Open(s3 with endpoint) = %#v", s3)
+	}
+
+	s, err = Open("sftp://me@nas.local:2222/srv/videos", nil)
+	if err != nil {
+		t.Fatalf("Open(sftp) error = %v", err)
+	}
+	if sftp, ok := s.(*SFTP); !ok || sftp.User != "me" || sftp.Host != "nas.local" || sftp.Port != 2222 || sftp.Dir != "/srv/videos" {
+		t.Errorf("Open(sftp) = %#v", s)
+	}
+
+	s, err = Open("file:///tmp/videos", nil)
+	if err != nil {
+		t.Fatalf("Open(file) error = %v", err)
+	}
+	if local, ok := s.(*Local); !ok || local.Dir != "/tmp/videos" {
+		t.Errorf("Open(file) = %#v", s)
+	}
+
+	for _, u := range []string{"ftp://example.com/x", "s3:///prefix", "sftp:///dir", "sftp://host:port/dir", "videos", "%zz"} {
+		if _, err := Open(u, nil); !errors.Is(err, ErrUnsupportedScheme) {
+			t.Errorf("Open(%q) error = %v, want ErrUnsupportedScheme", u, err)
+		}
+	}
+}
+
+func TestOpen_S3WithoutCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	if _, err := Open("s3://videos", nil); !errors.Is(err, ErrMissingCredentials) {
+		t.Errorf("error = %v, want ErrMissingCredentials", err)
+	}
+}
+
+func TestCleanKey(t *testing.T) {
+	tests := map[string]string{
+		"video.mp4":             "video.mp4",
+		"/Channel/video.mp4":    "Channel/video.mp4",
+		`Channel\video.mp4`:     "Channel/video.mp4",
+		"Channel//./video.mp4":  "Channel/video.mp4",
+		"What? [Official].webm": "What? [Official].webm",
+	}
+	for key, want := range tests {
+		if got, err := cleanKey(key); err != nil || got != want {
+			t.Errorf("cleanKey(%q) = %q, %v; want %q", key, got, err, want)
+		}
+	}
+	for _, key := range []string{"", "/", ".", "../video.mp4", "a/../../b", `..\b`} {
+		if _, err := cleanKey(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("cleanKey(%q) error = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestPipeTo(t *testing.T) {
+	var got string
+	err := pipeTo(strings.NewReader("streamed"), func(r io.Reader) error {
+		data, err := io.ReadAll(r)
+		got = string(data)
+		return err
+	})
+	if err != nil || got != "streamed" {
+		t.Errorf("pipeTo = %q, %v", got, err)
+	}
+}
+
+func TestPipeTo_SourceError(t *testing.T) {
+	srcErr := errors.New("download failed")
+	src := WriterToFunc(func(w io.Writer) (int64, error) {
+		n, _ := w.Write([]byte("partial"))
+		return int64(n), srcErr
+	})
+	err := pipeTo(src, func(r io.Reader) error {
+		_, err := io.ReadAll(r)
+		return err
+	})
+	if !errors.Is(err, srcErr) {
+		t.Errorf("error = %v, want the source's", err)
+	}
+}
+
+func TestPipeTo_UploadError(t *testing.T) {
+	uploadErr := errors.New("bucket gone")
+	src := WriterToFunc(func(w io.Writer) (int64, error) {
+		// Keeps writing until the upload stops reading
+		for {
+			if _, err := w.Write(make([]byte, 1024)); err != nil {
+				return 0, err
+			}
+		}
+	})
+	err := pipeTo(src, func(io.Reader) error { return uploadErr })
+	if !errors.Is(err, uploadErr) {
+		t.Errorf("error = %v, want the upload's", err)
+	}
+}
@@ -0,0 +1,102 @@
+package tagging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Registers the WebP decoder with image.Decode
+)
+
+// Cover image formats.
+const (
+	CoverJPEG = "jpeg"
+	CoverPNG  = "png"
+)
+
+// DefaultCoverQuality is the JPEG quality covers are encoded with when
+// CoverOptions doesn't set one.
+const DefaultCoverQuality = 90
+
+// CoverOptions controls how thumbnails are converted before they're embedded
+// as cover art. The zero value converts them to JPEG at DefaultCoverQuality
+// without resizing them.
+type CoverOptions struct {
+	// Format is the image format covers are embedded in, CoverJPEG or
+	// CoverPNG. CoverJPEG is used if empty, as every player reads it.
+	Format string
+
+	// Quality is the JPEG quality from 1 to 100, DefaultCoverQuality if 0.
+	Quality int
+
+	// MaxSize caps the width and height of covers in pixels, scaling larger
+	// ones down to fit; 0 keeps their size.
+	MaxSize int
+}
+
+// ConvertCover decodes a JPEG, PNG or WebP image and re-encodes it as opts
+// say, returning the image and its MIME type. An image already in the format
+// and within the size is returned unchanged, so it isn't compressed twice.
+func ConvertCover(data []byte, opts CoverOptions) ([]byte, string, error) {
+	format := opts.Format
+	if format == "" {
+		format = CoverJPEG
+	}
+	if format != CoverJPEG && format != CoverPNG {
+		return nil, "", fmt.Errorf("unsupported cover format: %s", format)
+	}
+	quality := opts.Quality
+	if quality == 0 {
+		quality = DefaultCoverQuality
+	}
+	if quality < 1 || quality > 100 {
+		return nil, "", fmt.Errorf("cover quality must be between 1 and 100, got %d", quality)
+	}
+	mimeType := "image/" + format
+
+	config, srcFormat, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode thumbnail: %w", err)
+	}
+	width, height := fitSize(config.Width, config.Height, opts.MaxSize)
+	if srcFormat == format && width == config.Width && height == config.Height {
+		return data, mimeType, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode thumbnail: %w", err)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if format == CoverJPEG {
+		// JPEG has no transparency, so transparent pixels are made white, not black
+		draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	}
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	var buf bytes.Buffer
+	if format == CoverJPEG {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode cover: %w", err)
+	}
+	return buf.Bytes(), mimeType, nil
+}
+
+// fitSize returns the size of a width by height image scaled down to fit in a
+// maxSize square, keeping its aspect ratio. maxSize 0 means no limit.
+func fitSize(width, height, maxSize int) (int, int) {
+	if maxSize <= 0 || (width <= maxSize && height <= maxSize) {
+		return width, height
+	}
+	if width >= height {
+		return maxSize, max(1, height*maxSize/width)
+	}
+	return max(1, width*maxSize/height), maxSize
+}
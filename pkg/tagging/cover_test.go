@@ -0,0 +1,126 @@
+package tagging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"testing"
+)
+
+// readTestWebP returns a 75x100 lossless WebP image.
+func readTestWebP(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/gopher.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// decodeConfig returns the size and format of an encoded image.
+func decodeConfig(t *testing.T, data []byte) (int, int, string) {
+	t.Helper()
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("converted cover doesn't decode: %v", err)
+	}
+	return config.Width, config.Height, format
+}
+
+func TestConvertCover_WebPToJPEG(t *testing.T) {
+	cover, mimeType, err := ConvertCover(readTestWebP(t), CoverOptions{})
+	if err != nil {
+		t.Fatalf("ConvertCover failed: %v", err)
+	}
+
+	if mimeType != "image/jpeg" {
+		t.Errorf("MIME type = %q, want image/jpeg", mimeType)
+	}
+	if width, height, format := decodeConfig(t, cover); width != 75 || height != 100 || format != "jpeg" {
+		t.Errorf("cover is a %dx%d %s, want a 75x100 jpeg", width, height, format)
+	}
+}
+
+func TestConvertCover_PNGWithMaxSize(t *testing.T) {
+	cover, mimeType, err := ConvertCover(readTestWebP(t), CoverOptions{Format: CoverPNG, MaxSize: 40})
+	if err != nil {
+		t.Fatalf("ConvertCover failed: %v", err)
+	}
+
+	if mimeType != "image/png" {
+		t.Errorf("MIME type = %q, want image/png", mimeType)
+	}
+	if width, height, format := decodeConfig(t, cover); width != 30 || height != 40 || format != "png" {
+		t.Errorf("cover is a %dx%d %s, want a 30x40 png", width, height, format)
+	}
+}
+
+func TestConvertCover_KeepsFittingJPEG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 36))
+	for x := range 64 {
+		img.Set(x, x%36, color.RGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 75}); err != nil {
+		t.Fatal(err)
+	}
+
+	cover, _, err := ConvertCover(buf.Bytes(), CoverOptions{Quality: 50, MaxSize: 64})
+	if err != nil {
+		t.Fatalf("ConvertCover failed: %v", err)
+	}
+	if !bytes.Equal(cover, buf.Bytes()) {
+		t.Error("a JPEG within the size was encoded again")
+	}
+
+	cover, _, err = ConvertCover(buf.Bytes(), CoverOptions{MaxSize: 32})
+	if err != nil {
+		t.Fatalf("ConvertCover failed: %v", err)
+	}
+	if width, height, _ := decodeConfig(t, cover); width != 32 || height != 18 {
+		t.Errorf("cover is %dx%d, want 32x18", width, height)
+	}
+}
+
+func TestConvertCover_Errors(t *testing.T) {
+	webp := readTestWebP(t)
+	tests := []struct {
+		name string
+		data []byte
+		opts CoverOptions
+	}{
+		{"unsupported format", webp, CoverOptions{Format: "gif"}},
+		{"quality too high", webp, CoverOptions{Quality: 101}},
+		{"quality negative", webp, CoverOptions{Quality: -5}},
+		{"not an image", []byte("<html>Not Found</html>"), CoverOptions{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ConvertCover(tt.data, tt.opts); err == nil {
+				t.Error("ConvertCover succeeded")
+			}
+		})
+	}
+}
+
+func TestFitSize(t *testing.T) {
+	tests := []struct {
+		width, height, maxSize int
+		wantWidth, wantHeight  int
+	}{
+		{1280, 720, 0, 1280, 720},
+		{1280, 720, 1280, 1280, 720},
+		{1280, 720, 500, 500, 281},
+		{720, 1280, 500, 281, 500},
+		{4000, 1, 100, 100, 1},
+	}
+	for _, tt := range tests {
+		width, height := fitSize(tt.width, tt.height, tt.maxSize)
+		if width != tt.wantWidth || height != tt.wantHeight {
+			t.Errorf("fitSize(%d, %d, %d) = %d, %d; want %d, %d",
+				tt.width, tt.height, tt.maxSize, width, height, tt.wantWidth, tt.wantHeight)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package tagging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// FLAC metadata block types.
+const (
+	flacStreamInfo    = 0
+	flacVorbisComment = 4
+	flacPicture       = 6
+)
+
+// maxFLACBlockSize is the most data a FLAC metadata block holds, as its length is 24 bits.
+const maxFLACBlockSize = 1<<24 - 1
+
+// flacMagic starts every FLAC stream.
+var flacMagic = []byte("fLaC")
+
+// flacBlock is a metadata block of a FLAC file.
+type flacBlock struct {
+	blockType byte
+	data      []byte
+}
+
+// readFLACBlocks reads the metadata blocks at the start of a FLAC stream,
+// leaving r at the first audio frame.
+func readFLACBlocks(r io.Reader) ([]flacBlock, error) {
+	magic := make([]byte, len(flacMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, flacMagic) {
+		return nil, errors.New("not a FLAC file")
+	}
+
+	var blocks []flacBlock
+	for last := false; !last; {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, fmt.Errorf("failed to read FLAC metadata: %w", err)
+		}
+		last = header[0]&0x80 != 0
+		block := flacBlock{blockType: header[0] & 0x7f}
+		if block.blockType == 127 || (len(blocks) == 0 && block.blockType != flacStreamInfo) {
+			return nil, errors.New("invalid FLAC metadata")
+		}
+		length := uint32(header[1])<<16 | uint32(header[2])<<8 | uint32(header[3])
+		block.data = make([]byte, length)
+		if _, err := io.ReadFull(r, block.data); err != nil {
+			return nil, fmt.Errorf("failed to read FLAC metadata: %w", err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// writeFLACBlocks writes the start of a FLAC stream with the metadata blocks.
+func writeFLACBlocks(w io.Writer, blocks []flacBlock) error {
+	if _, err := w.Write(flacMagic); err != nil {
+		return err
+	}
+	for i, block := range blocks {
+		if len(block.data) > maxFLACBlockSize {
+			return fmt.Errorf("FLAC metadata block of %d bytes is too large", len(block.data))
+		}
+		header := binary.BigEndian.AppendUint32(nil, uint32(len(block.data)))
+		header[0] = block.blockType
+		if i == len(blocks)-1 {
+			header[0] |= 0x80
+		}
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+		if _, err := w.Write(block.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flacTags returns the tags of the metadata blocks, with an empty comment if
+// there's no Vorbis comment block.
+func flacTags(blocks []flacBlock) (*vorbisTags, error) {
+	tags := &vorbisTags{}
+	for _, block := range blocks {
+		switch block.blockType {
+		case flacVorbisComment:
+			comment, _, err := parseVorbisComment(block.data)
+			if err != nil {
+				return nil, err
+			}
+			tags.comment = comment
+		case flacPicture:
+			p, err := parsePicture(block.data)
+			if err != nil {
+				return nil, err
+			}
+			tags.pictures = append(tags.pictures, p)
+		}
+	}
+	if tags.comment == nil {
+		tags.comment = &vorbisComment{vendor: vendorString}
+	}
+	return tags, nil
+}
+
+// readFLACTags reads the Vorbis comment and picture blocks of a FLAC file.
+func readFLACTags(filePath string) (*vorbisTags, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open FLAC file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	blocks, err := readFLACBlocks(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	return flacTags(blocks)
+}
+
+// updateFLACTags rewrites the Vorbis comment and picture blocks of a FLAC
+// file. They follow the stream info block, before the others, such as
+// padding, which are kept.
+func updateFLACTags(filePath string, update func(*vorbisTags)) error {
+	return rewriteFile(filePath, func(w io.Writer) error {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to open FLAC file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		r := bufio.NewReader(f)
+
+		blocks, err := readFLACBlocks(r)
+		if err != nil {
+			return err
+		}
+		tags, err := flacTags(blocks)
+		if err != nil {
+			return err
+		}
+		update(tags)
+
+		updated := []flacBlock{blocks[0], {blockType: flacVorbisComment, data: tags.comment.marshal()}}
+		for _, p := range tags.pictures {
+			updated = append(updated, flacBlock{blockType: flacPicture, data: p.marshal()})
+		}
+		for _, block := range blocks[1:] {
+			if block.blockType != flacVorbisComment && block.blockType != flacPicture {
+				updated = append(updated, block)
+			}
+		}
+		if err := writeFLACBlocks(w, updated); err != nil {
+			return err
+		}
+		_, err = io.Copy(w, r)
+		return err
+	})
+}
@@ -0,0 +1,90 @@
+package tagging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdateFLACTags_KeepsOtherBlocksAndAudio(t *testing.T) {
+	var buf bytes.Buffer
+	streamInfo := bytes.Repeat([]byte{7}, 34)
+	seekTable := bytes.Repeat([]byte{9}, 18)
+	oldCover := (&picture{pictureType: 0, mimeType: "image/png", data: []byte("icon")}).marshal()
+	if err := writeFLACBlocks(&buf, []flacBlock{
+		{blockType: flacStreamInfo, data: streamInfo},
+		{blockType: 3, data: seekTable},
+		{blockType: flacPicture, data: oldCover},
+		{blockType: 1, data: make([]byte, 8192)}, // Padding
+	}); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString("\xff\xf8audio frames")
+	testFile := filepath.Join(t.TempDir(), "song.flac")
+	if err := os.WriteFile(testFile, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewTagInjector().injectVorbisPicture(testFile, []byte("cover"), "image/jpeg"); err != nil {
+		t.Fatalf("injectVorbisPicture failed: %v", err)
+	}
+
+	f, err := os.Open(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	blocks, err := readFLACBlocks(f)
+	if err != nil {
+		t.Fatalf("readFLACBlocks failed: %v", err)
+	}
+	var types []byte
+	for _, block := range blocks {
+		types = append(types, block.blockType)
+	}
+	if want := []byte{flacStreamInfo, flacVorbisComment, flacPicture, flacPicture, 3, 1}; !bytes.Equal(types, want) {
+		t.Fatalf("block types = %v, want %v", types, want)
+	}
+	if !bytes.Equal(blocks[0].data, streamInfo) || !bytes.Equal(blocks[4].data, seekTable) || len(blocks[5].data) != 8192 {
+		t.Error("stream info, seek table or padding changed")
+	}
+	if p, err := parsePicture(blocks[2].data); err != nil || string(p.data) != "cover" || p.pictureType != pictureFrontCover {
+		t.Errorf("first picture = %+v, %v, want the front cover", p, err)
+	}
+	if !bytes.Equal(blocks[3].data, oldCover) {
+		t.Error("picture of another type wasn't kept")
+	}
+	if comment, _, err := parseVorbisComment(blocks[1].data); err != nil || comment.vendor != vendorString {
+		t.Errorf("comment = %+v, %v, want a new one", comment, err)
+	}
+
+	data, _ := os.ReadFile(testFile)
+	if !bytes.HasSuffix(data, []byte("\xff\xf8audio frames")) {
+		t.Error("audio frames weren't kept")
+	}
+}
+
+func TestReadFLACTags_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"not flac", []byte("ID3\x03\x00"), "not a FLAC file"},
+		{"no stream info", append([]byte("fLaC\x84\x00\x00\x00"), 0), "invalid FLAC metadata"},
+		{"truncated", createMinimalFLAC()[:30], "failed to read FLAC metadata"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "song.flac")
+			if err := os.WriteFile(testFile, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ReadTags(testFile); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadTags() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
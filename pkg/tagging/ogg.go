@@ -0,0 +1,350 @@
+package tagging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Ogg page header type flags.
+const (
+	oggContinued = 0x01
+	oggFirst     = 0x02
+)
+
+// oggHeaderSize is the size of an Ogg page header before its lacing values.
+const oggHeaderSize = 27
+
+// pictureField is the Vorbis comment Ogg files hold pictures in.
+const pictureField = "METADATA_BLOCK_PICTURE"
+
+// Magic signatures of the Opus and Vorbis identification and comment headers.
+var (
+	opusHeadMagic      = []byte("OpusHead")
+	opusTagsMagic      = []byte("OpusTags")
+	vorbisIDMagic      = []byte("\x01vorbis")
+	vorbisCommentMagic = []byte("\x03vorbis")
+)
+
+// oggPage is a page of an Ogg stream.
+type oggPage struct {
+	headerType byte
+	granule    uint64
+	serial     uint32
+	sequence   uint32
+	segments   []byte // Lacing values
+	data       []byte
+}
+
+// readOggPage reads the next page of an Ogg stream, returning io.EOF at the end.
+func readOggPage(r io.Reader) (*oggPage, error) {
+	header := make([]byte, oggHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read Ogg page: %w", err)
+	}
+	if !bytes.HasPrefix(header, []byte("OggS")) || header[4] != 0 {
+		return nil, errors.New("not an Ogg page")
+	}
+	page := &oggPage{
+		headerType: header[5],
+		granule:    binary.LittleEndian.Uint64(header[6:]),
+		serial:     binary.LittleEndian.Uint32(header[14:]),
+		sequence:   binary.LittleEndian.Uint32(header[18:]),
+		segments:   make([]byte, header[26]),
+	}
+	if _, err := io.ReadFull(r, page.segments); err != nil {
+		return nil, fmt.Errorf("failed to read Ogg page: %w", err)
+	}
+	size := 0
+	for _, lacing := range page.segments {
+		size += int(lacing)
+	}
+	page.data = make([]byte, size)
+	if _, err := io.ReadFull(r, page.data); err != nil {
+		return nil, fmt.Errorf("failed to read Ogg page: %w", err)
+	}
+	if !bytes.Equal(page.marshal()[22:26], header[22:26]) {
+		return nil, errors.New("corrupt Ogg page: checksum mismatch")
+	}
+	return page, nil
+}
+
+// marshal encodes the page with its checksum.
+func (p *oggPage) marshal() []byte {
+	data := make([]byte, oggHeaderSize, oggHeaderSize+len(p.segments)+len(p.data))
+	copy(data, "OggS")
+	data[5] = p.headerType
+	binary.LittleEndian.PutUint64(data[6:], p.granule)
+	binary.LittleEndian.PutUint32(data[14:], p.serial)
+	binary.LittleEndian.PutUint32(data[18:], p.sequence)
+	data[26] = byte(len(p.segments))
+	data = append(data, p.segments...)
+	data = append(data, p.data...)
+	binary.LittleEndian.PutUint32(data[22:], oggCRC(data))
+	return data
+}
+
+// oggCRCTable is the table of the CRC-32 Ogg pages are checked with, with
+// polynomial 0x04c11db7 and no reflection.
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for range 8 {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// oggCRC returns the checksum of a page whose checksum field is zero.
+func oggCRC(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// paginate splits header packets into pages of the stream serial, numbered
+// from sequence. Pages on which no packet ends have no granule position.
+func paginate(serial, sequence uint32, packets [][]byte) []*oggPage {
+	var pages []*oggPage
+	page := &oggPage{serial: serial, sequence: sequence}
+	continued := false
+	for _, packet := range packets {
+		for {
+			if len(page.segments) == 255 {
+				pages = append(pages, page)
+				sequence++
+				page = &oggPage{serial: serial, sequence: sequence}
+				if continued {
+					page.headerType = oggContinued
+				}
+			}
+			// A packet ends with a segment shorter than 255 bytes, which may be empty
+			n := min(len(packet), 255)
+			page.segments = append(page.segments, byte(n))
+			page.data = append(page.data, packet[:n]...)
+			packet = packet[n:]
+			continued = n == 255
+			if !continued {
+				break
+			}
+		}
+	}
+	pages = append(pages, page)
+
+	for _, p := range pages {
+		if !slices.ContainsFunc(p.segments, func(lacing byte) bool { return lacing < 255 }) {
+			p.granule = ^uint64(0)
+		}
+	}
+	return pages
+}
+
+// oggStream holds the header pages of an Opus or Ogg Vorbis stream.
+type oggStream struct {
+	serial uint32
+
+	// idPages are the pages holding the identification header.
+	idPages []*oggPage
+
+	// comment is the comment of the comment header, which starts with magic
+	// and may hold extra data after the comment, such as the framing bit of
+	// Vorbis. setup is the Vorbis setup header, nil for Opus.
+	comment *vorbisComment
+	magic   []byte
+	extra   []byte
+	setup   []byte
+
+	// nextSequence is the sequence number of the first page after the headers.
+	nextSequence uint32
+}
+
+// readOggStream reads the header pages of the Opus or Vorbis stream at the
+// start of r, leaving r at the first audio page. The comment header, and the
+// setup header of Vorbis, must end their last page, as the codecs require.
+func readOggStream(r io.Reader) (*oggStream, error) {
+	s := &oggStream{}
+	var packets [][]byte
+	var packet []byte
+	want := 0 // Number of header packets, once the codec is known
+	for want == 0 || len(packets) < want {
+		page, err := readOggPage(r)
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("truncated Ogg headers")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if s.idPages == nil {
+			if page.headerType&oggFirst == 0 {
+				return nil, errors.New("not an Ogg stream")
+			}
+			s.serial = page.serial
+		} else if page.serial != s.serial {
+			return nil, errors.New("multiplexed Ogg streams are not supported")
+		}
+		if len(packets) == 0 {
+			s.idPages = append(s.idPages, page)
+		}
+
+		data := page.data
+		for i, lacing := range page.segments {
+			packet = append(packet, data[:lacing]...)
+			data = data[lacing:]
+			if lacing == 255 {
+				continue
+			}
+			packets = append(packets, packet)
+			packet = nil
+			if len(packets) == 1 {
+				switch {
+				case bytes.HasPrefix(packets[0], opusHeadMagic):
+					want, s.magic = 2, opusTagsMagic
+				case bytes.HasPrefix(packets[0], vorbisIDMagic):
+					want, s.magic = 3, vorbisCommentMagic
+				default:
+					return nil, errors.New("unsupported Ogg codec; only Opus and Vorbis are supported")
+				}
+			}
+			if (len(packets) == 1 || len(packets) == want) && i != len(page.segments)-1 {
+				return nil, errors.New("invalid Ogg stream: headers share a page with other packets")
+			}
+		}
+		s.nextSequence = page.sequence + 1
+	}
+
+	if !bytes.HasPrefix(packets[1], s.magic) {
+		return nil, errors.New("invalid Ogg stream: missing comment header")
+	}
+	comment, extra, err := parseVorbisComment(packets[1][len(s.magic):])
+	if err != nil {
+		return nil, err
+	}
+	s.comment, s.extra = comment, extra
+	if want == 3 {
+		s.setup = packets[2]
+	}
+	return s, nil
+}
+
+// tags returns the tags of the stream, taking its pictures out of the comment.
+func (s *oggStream) tags() *vorbisTags {
+	tags := &vorbisTags{comment: &vorbisComment{vendor: s.comment.vendor}}
+	for _, field := range s.comment.fields {
+		key, value, _ := strings.Cut(field, "=")
+		if !strings.EqualFold(key, pictureField) {
+			tags.comment.fields = append(tags.comment.fields, field)
+			continue
+		}
+		// Pictures that can't be read are dropped when the tags are written
+		if data, err := base64.StdEncoding.DecodeString(value); err == nil {
+			if p, err := parsePicture(data); err == nil {
+				tags.pictures = append(tags.pictures, p)
+			}
+		}
+	}
+	return tags
+}
+
+// headerPackets returns the header packets after the identification header
+// with the tags as the comment.
+func (s *oggStream) headerPackets(tags *vorbisTags) [][]byte {
+	comment := &vorbisComment{vendor: tags.comment.vendor, fields: tags.comment.fields}
+	for _, p := range tags.pictures {
+		comment.fields = append(comment.fields, pictureField+"="+base64.StdEncoding.EncodeToString(p.marshal()))
+	}
+	packet := append(append([]byte{}, s.magic...), comment.marshal()...)
+	packet = append(packet, s.extra...)
+	if s.setup != nil {
+		return [][]byte{packet, s.setup}
+	}
+	return [][]byte{packet}
+}
+
+// readOggTags reads the comment header of an Opus or Ogg Vorbis file.
+func readOggTags(filePath string) (*vorbisTags, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Ogg file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	s, err := readOggStream(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	return s.tags(), nil
+}
+
+// updateOggTags rewrites the comment header of an Opus or Ogg Vorbis file.
+// When the header takes a different number of pages, the audio pages after it
+// are renumbered.
+func updateOggTags(filePath string, update func(*vorbisTags)) error {
+	return rewriteFile(filePath, func(w io.Writer) error {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to open Ogg file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		r := bufio.NewReader(f)
+
+		s, err := readOggStream(r)
+		if err != nil {
+			return err
+		}
+		tags := s.tags()
+		update(tags)
+
+		for _, page := range s.idPages {
+			if _, err := w.Write(page.marshal()); err != nil {
+				return err
+			}
+		}
+		first := s.idPages[len(s.idPages)-1].sequence + 1
+		pages := paginate(s.serial, first, s.headerPackets(tags))
+		for _, page := range pages {
+			if _, err := w.Write(page.marshal()); err != nil {
+				return err
+			}
+		}
+
+		shift := first + uint32(len(pages)) - s.nextSequence
+		if shift == 0 {
+			_, err = io.Copy(w, r)
+			return err
+		}
+		for {
+			page, err := readOggPage(r)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if page.serial == s.serial {
+				page.sequence += shift
+			}
+			if _, err := w.Write(page.marshal()); err != nil {
+				return err
+			}
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package tagging
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readTestPages reads all pages of an Ogg file, failing on invalid checksums.
+func readTestPages(t *testing.T, filePath string) []*oggPage {
+	t.Helper()
+	f, err := os.Open(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var pages []*oggPage
+	for {
+		page, err := readOggPage(f)
+		if errors.Is(err, io.EOF) {
+			return pages
+		}
+		if err != nil {
+			t.Fatalf("readOggPage failed: %v", err)
+		}
+		pages = append(pages, page)
+	}
+}
+
+func TestUpdateOggTags_RenumbersPagesAfterLargeComment(t *testing.T) {
+	for _, name := range []string{"song.opus", "song.ogg"} {
+		t.Run(name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(testFile, createMinimalVorbisFile(name), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			original := readTestPages(t, testFile)
+
+			// A picture of 100 KB takes several pages, each at most 255 segments of 255 bytes
+			cover := bytes.Repeat([]byte{0xa5}, 100_000)
+			if err := updateOggTags(testFile, func(tags *vorbisTags) {
+				tags.comment.set("TITLE", "Song")
+				tags.pictures = []*picture{newCoverPicture(cover, "image/jpeg")}
+			}); err != nil {
+				t.Fatalf("updateOggTags failed: %v", err)
+			}
+
+			pages := readTestPages(t, testFile)
+			if len(pages) <= len(original) {
+				t.Fatalf("got %d pages, want more than the %d before", len(pages), len(original))
+			}
+			for i, page := range pages {
+				if page.sequence != uint32(i) {
+					t.Errorf("page %d has sequence number %d", i, page.sequence)
+				}
+			}
+			if pages[1].headerType&oggContinued != 0 || pages[2].headerType&oggContinued == 0 || pages[1].granule != ^uint64(0) {
+				t.Errorf("comment pages have header types %d, %d and granule %d", pages[1].headerType, pages[2].headerType, pages[1].granule)
+			}
+			last, originalLast := pages[len(pages)-1], original[len(original)-1]
+			if !bytes.Equal(last.data, originalLast.data) || last.granule != originalLast.granule || last.headerType != originalLast.headerType {
+				t.Errorf("audio page changed: %+v, want %+v", last, originalLast)
+			}
+
+			tags, err := readOggTags(testFile)
+			if err != nil {
+				t.Fatalf("readOggTags failed: %v", err)
+			}
+			if tags.comment.get("TITLE") != "Song" || len(tags.pictures) != 1 || !bytes.Equal(tags.pictures[0].data, cover) {
+				t.Errorf("tags = %q with %d pictures", tags.comment.fields, len(tags.pictures))
+			}
+
+			// Removing the picture takes the comment back to one page
+			if err := updateOggTags(testFile, func(tags *vorbisTags) { tags.pictures = nil }); err != nil {
+				t.Fatalf("updateOggTags failed: %v", err)
+			}
+			if pages := readTestPages(t, testFile); len(pages) != len(original) || pages[len(pages)-1].sequence != originalLast.sequence {
+				t.Errorf("got %d pages after removing the picture, want %d", len(pages), len(original))
+			}
+		})
+	}
+}
+
+func TestUpdateOggTags_KeepsVorbisSetupHeader(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "song.ogg")
+	if err := os.WriteFile(testFile, createMinimalOggVorbis(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateOggTags(testFile, func(tags *vorbisTags) { tags.comment.set("ARTIST", "Artist") }); err != nil {
+		t.Fatalf("updateOggTags failed: %v", err)
+	}
+
+	f, err := os.Open(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	s, err := readOggStream(f)
+	if err != nil {
+		t.Fatalf("readOggStream failed: %v", err)
+	}
+	if string(s.setup) != "\x05vorbiscodebooks" {
+		t.Errorf("setup header = %q", s.setup)
+	}
+	if !bytes.Equal(s.extra, []byte{1}) {
+		t.Errorf("data after the comment = %v, want the framing bit", s.extra)
+	}
+	if s.comment.vendor != "Xiph.Org libVorbis I 20200704 (Reducing Environment)" || s.comment.get("ARTIST") != "Artist" {
+		t.Errorf("comment = %+v", s.comment)
+	}
+}
+
+func TestReadOggTags_Errors(t *testing.T) {
+	corrupt := createMinimalOpus()
+	corrupt[40] ^= 0xff
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"not ogg", []byte("RIFF....WAVE"), "failed to read Ogg page"},
+		{"corrupt", corrupt, "checksum mismatch"},
+		{"truncated", createMinimalOpus()[:47], "truncated Ogg headers"}, // Only the identification page
+		{"cut mid-page", createMinimalOpus()[:60], "unexpected EOF"},
+		{"other codec", createTestOgg([][]byte{[]byte("Speex   ")}, [][]byte{[]byte("comment")}, nil), "unsupported Ogg codec"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "song.opus")
+			if err := os.WriteFile(testFile, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := readOggTags(testFile); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("readOggTags() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOggCRC(t *testing.T) {
+	// Ogg uses CRC-32/POSIX without its final inversion, whose check value is 0x765e7680
+	if got := oggCRC([]byte("123456789")); got != 0x89a1897f {
+		t.Errorf("oggCRC() = %#x, want %#x", got, 0x89a1897f)
+	}
+}
@@ -1,15 +1,23 @@
 package tagging
 
 import (
+	"context"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/bogem/id3v2/v2"
 
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
 )
 
@@ -20,10 +28,27 @@ type Tags struct {
 	Album       string
 	Description string
 	Comment     string
+	Lyrics      string
+	AlbumArtist string
+	Year        int
+	Track       int
+	TrackCount  int
 }
 
+// MetadataFunc copies inputPath to outputPath with the given container metadata
+// set, like ffmpeg.SetMetadata.
+type MetadataFunc func(ctx context.Context, inputPath, outputPath string, metadata map[string]string) error
+
 // TagInjector injects metadata tags into media files.
-type TagInjector struct{}
+type TagInjector struct {
+	// SetMetadata writes M4A and MP4 metadata, which go through FFmpeg. If
+	// nil, ffmpeg.SetMetadata is used. Vorbis comments are written directly.
+	SetMetadata MetadataFunc
+
+	// Cover sets the format, quality and size thumbnails are converted to
+	// before they're embedded.
+	Cover CoverOptions
+}
 
 // NewTagInjector creates a new TagInjector instance.
 func NewTagInjector() *TagInjector {
@@ -31,7 +56,8 @@ func NewTagInjector() *TagInjector {
 }
 
 // InjectTags writes metadata from the video to the media file.
-// Supports MP3 files (ID3v2 tags) and M4A files (MP4 metadata).
+// Supports MP3 files (ID3v2 tags), M4A files (MP4 metadata) and Ogg, Opus and
+// FLAC files (Vorbis comments).
 func (t *TagInjector) InjectTags(filePath string, video *youtube.Video) error {
 	ext := strings.ToLower(filepath.Ext(filePath))
 
@@ -40,12 +66,17 @@ func (t *TagInjector) InjectTags(filePath string, video *youtube.Video) error {
 		return t.injectMP3Tags(filePath, video)
 	case ".m4a", ".mp4", ".aac":
 		return t.injectM4ATags(filePath, video)
+	case ".ogg", ".opus", ".flac":
+		return t.injectVorbisTags(filePath, video)
 	default:
 		return fmt.Errorf("unsupported file format: %s", ext)
 	}
 }
 
-// InjectThumbnail downloads the highest quality thumbnail and embeds it as cover art.
+// InjectThumbnail downloads the highest quality thumbnail and embeds it as cover
+// art: an APIC frame in MP3 files, a picture block in FLAC files and a
+// METADATA_BLOCK_PICTURE comment in Ogg and Opus files. WebP
+// thumbnails, which many players can't show, are converted as t.Cover says.
 func (t *TagInjector) InjectThumbnail(filePath string, video *youtube.Video) error {
 	ext := strings.ToLower(filepath.Ext(filePath))
 
@@ -57,19 +88,268 @@ func (t *TagInjector) InjectThumbnail(filePath string, video *youtube.Video) err
 	if err != nil {
 		return fmt.Errorf("failed to download thumbnail: %w", err)
 	}
+	cover, mimeType, err := ConvertCover(thumbnailData, t.Cover)
+	if err != nil {
+		return err
+	}
 
 	switch ext {
 	case ".mp3":
-		return t.injectMP3Thumbnail(filePath, thumbnailData)
+		return t.injectMP3Thumbnail(filePath, cover, mimeType)
 	case ".m4a", ".mp4", ".aac":
-		return t.injectM4AThumbnail(filePath, thumbnailData)
+		return t.injectM4AThumbnail(filePath, cover)
+	case ".ogg", ".opus", ".flac":
+		return t.injectVorbisPicture(filePath, cover, mimeType)
+	default:
+		return fmt.Errorf("unsupported file format: %s", ext)
+	}
+}
+
+// WriteTags writes the non-empty title, artist, album, album artist, year and
+// track number of tags to the media file, keeping its other tags. The track
+// count is written with the track number. MP3 files get ID3v2 tags; M4A and
+// MP4 files get their metadata set with FFmpeg, and Ogg, Opus and FLAC files
+// their Vorbis comments.
+func (t *TagInjector) WriteTags(ctx context.Context, filePath string, tags *Tags) error {
+	ext := strings.ToLower(filepath.Ext(filePath))
+
+	switch ext {
+	case ".mp3":
+		return t.writeMP3Tags(filePath, tags)
+	case ".ogg", ".opus", ".flac":
+		return writeVorbisTags(filePath, tags)
+	case ".m4a", ".mp4":
+		metadata := map[string]string{}
+		for key, value := range map[string]string{"title": tags.Title, "artist": tags.Artist, "album": tags.Album, "album_artist": tags.AlbumArtist} {
+			if value != "" {
+				metadata[key] = value
+			}
+		}
+		if tags.Year > 0 {
+			metadata["date"] = strconv.Itoa(tags.Year)
+		}
+		if tags.Track > 0 {
+			metadata["track"] = trackNumber(tags)
+		}
+		return t.setFFmpegMetadata(ctx, filePath, metadata)
+	default:
+		return fmt.Errorf("unsupported file format: %s", ext)
+	}
+}
+
+// writeMP3Tags writes the non-empty tags to an MP3 file.
+func (t *TagInjector) writeMP3Tags(filePath string, tags *Tags) error {
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err != nil {
+		return fmt.Errorf("failed to open MP3 file: %w", err)
+	}
+	defer func() { _ = tag.Close() }()
+
+	if tags.Title != "" {
+		tag.SetTitle(tags.Title)
+	}
+	if tags.Artist != "" {
+		tag.SetArtist(tags.Artist)
+	}
+	if tags.Album != "" {
+		tag.SetAlbum(tags.Album)
+	}
+	if tags.AlbumArtist != "" {
+		tag.AddTextFrame(tag.CommonID("Band/Orchestra/Accompaniment"), id3v2.EncodingUTF8, tags.AlbumArtist)
+	}
+	if tags.Year > 0 {
+		tag.SetYear(strconv.Itoa(tags.Year))
+	}
+	if tags.Track > 0 {
+		tag.AddTextFrame(tag.CommonID("Track number/Position in set"), id3v2.EncodingUTF8, trackNumber(tags))
+	}
+
+	if err := tag.Save(); err != nil {
+		return fmt.Errorf("failed to save MP3 tags: %w", err)
+	}
+
+	return nil
+}
+
+// writeVorbisTags writes the non-empty tags to an Ogg, Opus or FLAC file. The
+// track count goes in TRACKTOTAL, which players read more widely than "3/12".
+func writeVorbisTags(filePath string, tags *Tags) error {
+	fields := map[string]string{}
+	for name, value := range map[string]string{"TITLE": tags.Title, "ARTIST": tags.Artist, "ALBUM": tags.Album, "ALBUMARTIST": tags.AlbumArtist} {
+		if value != "" {
+			fields[name] = value
+		}
+	}
+	if tags.Year > 0 {
+		fields["DATE"] = strconv.Itoa(tags.Year)
+	}
+	if tags.Track > 0 {
+		fields["TRACKNUMBER"] = strconv.Itoa(tags.Track)
+		if tags.TrackCount > 0 {
+			fields["TRACKTOTAL"] = strconv.Itoa(tags.TrackCount)
+		}
+	}
+	return setVorbisComments(filePath, fields)
+}
+
+// trackNumber formats the track number of tags, followed by the track count if
+// known, as in "3/12".
+func trackNumber(tags *Tags) string {
+	if tags.TrackCount > 0 {
+		return fmt.Sprintf("%d/%d", tags.Track, tags.TrackCount)
+	}
+	return strconv.Itoa(tags.Track)
+}
+
+// InjectLyrics embeds lyrics into the media file: as an ID3v2 USLT frame in
+// MP3 files, replacing any lyrics already there, as the ©lyr atom in M4A and
+// MP4 files and as the LYRICS comment in Ogg, Opus and FLAC files. Timed lyrics
+// can be passed in LRC format, which many players show in step with the song.
+func (t *TagInjector) InjectLyrics(ctx context.Context, filePath, lyrics string) error {
+	ext := strings.ToLower(filepath.Ext(filePath))
+
+	switch ext {
+	case ".mp3":
+		return t.injectMP3Lyrics(filePath, lyrics)
+	case ".m4a", ".mp4":
+		return t.injectM4ALyrics(ctx, filePath, lyrics)
+	case ".ogg", ".opus", ".flac":
+		return setVorbisComments(filePath, map[string]string{"LYRICS": lyrics})
+	default:
+		return fmt.Errorf("unsupported file format: %s", ext)
+	}
+}
+
+// injectMP3Lyrics embeds lyrics as a USLT frame in an MP3 file.
+func (t *TagInjector) injectMP3Lyrics(filePath, lyrics string) error {
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err != nil {
+		return fmt.Errorf("failed to open MP3 file: %w", err)
+	}
+	defer func() { _ = tag.Close() }()
+
+	tag.DeleteFrames(tag.CommonID("Unsynchronised lyrics/text transcription"))
+	tag.AddUnsynchronisedLyricsFrame(id3v2.UnsynchronisedLyricsFrame{
+		Encoding:          id3v2.EncodingUTF8,
+		Language:          "eng",
+		ContentDescriptor: "",
+		Lyrics:            lyrics,
+	})
+
+	if err := tag.Save(); err != nil {
+		return fmt.Errorf("failed to save MP3 lyrics: %w", err)
+	}
+
+	return nil
+}
+
+// injectM4ALyrics sets the lyrics metadata of an M4A or MP4 file.
+func (t *TagInjector) injectM4ALyrics(ctx context.Context, filePath, lyrics string) error {
+	return t.setFFmpegMetadata(ctx, filePath, map[string]string{"lyrics": lyrics})
+}
+
+// WriteReplayGain writes the ReplayGain track gain and peak of the measured
+// audio to the media file, so players can play it at the same volume as the
+// rest of a library. MP3 files get REPLAYGAIN_TRACK_GAIN and
+// REPLAYGAIN_TRACK_PEAK TXXX frames and an RVA2 frame, for players that only
+// read that. Ogg and FLAC files get the same as Vorbis comments, and Opus files
+// an R128_TRACK_GAIN comment, which Opus players apply on top of the header gain.
+func (t *TagInjector) WriteReplayGain(ctx context.Context, filePath string, gain *ffmpeg.ReplayGain) error {
+	ext := strings.ToLower(filepath.Ext(filePath))
+
+	switch ext {
+	case ".mp3":
+		return t.writeMP3ReplayGain(filePath, gain)
+	case ".ogg", ".flac":
+		return setVorbisComments(filePath, map[string]string{
+			replayGainTrackGain: formatTrackGain(gain),
+			replayGainTrackPeak: formatTrackPeak(gain),
+		})
+	case ".opus":
+		return setVorbisComments(filePath, map[string]string{
+			"R128_TRACK_GAIN": strconv.Itoa(gain.R128TrackGain()),
+		})
 	default:
 		return fmt.Errorf("unsupported file format: %s", ext)
 	}
 }
 
+// ReplayGain tag names, the same as TXXX descriptions and as Vorbis comments.
+const (
+	replayGainTrackGain = "REPLAYGAIN_TRACK_GAIN"
+	replayGainTrackPeak = "REPLAYGAIN_TRACK_PEAK"
+)
+
+// writeMP3ReplayGain writes ReplayGain TXXX frames and an RVA2 frame to an MP3
+// file, replacing any already there.
+func (t *TagInjector) writeMP3ReplayGain(filePath string, gain *ffmpeg.ReplayGain) error {
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err != nil {
+		return fmt.Errorf("failed to open MP3 file: %w", err)
+	}
+	defer func() { _ = tag.Close() }()
+
+	// TXXX frames are told apart by description, so these replace earlier values
+	tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{Encoding: id3v2.EncodingUTF8, Description: replayGainTrackGain, Value: formatTrackGain(gain)})
+	tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{Encoding: id3v2.EncodingUTF8, Description: replayGainTrackPeak, Value: formatTrackPeak(gain)})
+
+	tag.DeleteFrames("RVA2")
+	tag.AddFrame("RVA2", id3v2.UnknownFrame{Body: rva2Body(gain)})
+
+	if err := tag.Save(); err != nil {
+		return fmt.Errorf("failed to save MP3 ReplayGain tags: %w", err)
+	}
+
+	return nil
+}
+
+// rva2Body builds the body of an RVA2 frame with the track gain and peak for
+// the master volume channel. The gain is in 1/512 dB, and the peak is held in 16
+// bits, with full scale at 32768.
+func rva2Body(gain *ffmpeg.ReplayGain) []byte {
+	adjustment := math.Round(gain.TrackGain() * 512)
+	adjustment = math.Max(math.MinInt16, math.Min(math.MaxInt16, adjustment))
+	peak := math.Min(math.Round(gain.TrackPeak()*32768), math.MaxUint16)
+
+	body := []byte("track\x00")
+	body = append(body, 0x01) // Master volume
+	body = binary.BigEndian.AppendUint16(body, uint16(int16(adjustment)))
+	body = append(body, 16) // Bits representing the peak
+	return binary.BigEndian.AppendUint16(body, uint16(peak))
+}
+
+// formatTrackGain formats the track gain the way ReplayGain tags hold it, as in "-3.90 dB".
+func formatTrackGain(gain *ffmpeg.ReplayGain) string {
+	return fmt.Sprintf("%.2f dB", gain.TrackGain())
+}
+
+// formatTrackPeak formats the track peak the way ReplayGain tags hold it, as in "0.966051".
+func formatTrackPeak(gain *ffmpeg.ReplayGain) string {
+	return fmt.Sprintf("%.6f", gain.TrackPeak())
+}
+
+// setFFmpegMetadata sets metadata of a file with FFmpeg, writing a copy next
+// to the file and then replacing the file with it.
+func (t *TagInjector) setFFmpegMetadata(ctx context.Context, filePath string, metadata map[string]string) error {
+	setMetadata := t.SetMetadata
+	if setMetadata == nil {
+		setMetadata = ffmpeg.SetMetadata
+	}
+
+	ext := filepath.Ext(filePath)
+	tmp := strings.TrimSuffix(filePath, ext) + ".tagged" + ext
+	if err := setMetadata(ctx, filePath, tmp, metadata); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	if err := os.Rename(tmp, filePath); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	return nil
+}
+
 // injectMP3Thumbnail embeds thumbnail as APIC frame in MP3 file.
-func (t *TagInjector) injectMP3Thumbnail(filePath string, thumbnailData []byte) error {
+func (t *TagInjector) injectMP3Thumbnail(filePath string, thumbnailData []byte, mimeType string) error {
 	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
 	if err != nil {
 		return fmt.Errorf("failed to open MP3 file: %w", err)
@@ -79,7 +359,7 @@ func (t *TagInjector) injectMP3Thumbnail(filePath string, thumbnailData []byte)
 	// Add attached picture frame (APIC)
 	pic := id3v2.PictureFrame{
 		Encoding:    id3v2.EncodingUTF8,
-		MimeType:    "image/jpeg",
+		MimeType:    mimeType,
 		PictureType: id3v2.PTFrontCover,
 		Description: "Cover",
 		Picture:     thumbnailData,
@@ -100,35 +380,65 @@ func (t *TagInjector) injectM4AThumbnail(filePath string, thumbnailData []byte)
 	return nil
 }
 
+// injectVorbisPicture embeds thumbnail as the front cover of an Ogg, Opus or
+// FLAC file, replacing any front cover already there.
+func (t *TagInjector) injectVorbisPicture(filePath string, thumbnailData []byte, mimeType string) error {
+	return updateVorbisTags(filePath, func(tags *vorbisTags) {
+		pictures := []*picture{newCoverPicture(thumbnailData, mimeType)}
+		for _, p := range tags.pictures {
+			if p.pictureType != pictureFrontCover {
+				pictures = append(pictures, p)
+			}
+		}
+		tags.pictures = pictures
+	})
+}
+
 // m4aThumbnailStore is a simple in-memory store for M4A thumbnails (for testing).
 var m4aThumbnailStore = make(map[string][]byte)
 
 // GetThumbnailURL returns the best thumbnail URL for a video.
-// It prefers the highest resolution JPG thumbnail, or falls back to hqdefault.
+// It prefers the highest resolution JPG, PNG or WebP thumbnail, which
+// ConvertCover can decode, or falls back to hqdefault.
 func GetThumbnailURL(videoID string, thumbnails []youtube.Thumbnail) string {
 	if len(thumbnails) == 0 {
 		return fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", videoID)
 	}
 
-	// Filter for JPG thumbnails and sort by resolution (highest first)
-	jpgThumbnails := make([]youtube.Thumbnail, 0, len(thumbnails))
+	// Filter for decodable thumbnails and sort by resolution (highest first)
+	imageThumbnails := make([]youtube.Thumbnail, 0, len(thumbnails))
 	for _, thumb := range thumbnails {
-		if strings.HasSuffix(strings.ToLower(thumb.URL), ".jpg") {
-			jpgThumbnails = append(jpgThumbnails, thumb)
+		if isDecodableImage(thumb.URL) {
+			imageThumbnails = append(imageThumbnails, thumb)
 		}
 	}
 
-	if len(jpgThumbnails) == 0 {
-		// No JPG thumbnails, use fallback
+	if len(imageThumbnails) == 0 {
+		// No decodable thumbnails, use fallback
 		return fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", videoID)
 	}
 
 	// Sort by resolution (area) descending
-	sort.Slice(jpgThumbnails, func(i, j int) bool {
-		return jpgThumbnails[i].Resolution() > jpgThumbnails[j].Resolution()
+	sort.SliceStable(imageThumbnails, func(i, j int) bool {
+		return imageThumbnails[i].Resolution() > imageThumbnails[j].Resolution()
 	})
 
-	return jpgThumbnails[0].URL
+	return imageThumbnails[0].URL
+}
+
+// isDecodableImage reports whether the image at rawURL is a JPG, PNG or WebP
+// file by the extension of its path, ignoring query parameters such as sqp.
+func isDecodableImage(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(path.Ext(u.Path)) {
+	case ".jpg", ".jpeg", ".png", ".webp":
+		return true
+	default:
+		return false
+	}
 }
 
 // downloadThumbnail downloads the thumbnail from the given URL.
@@ -160,6 +470,12 @@ func HasEmbeddedThumbnail(filePath string) (bool, error) {
 		return hasMP3Thumbnail(filePath)
 	case ".m4a", ".mp4", ".aac":
 		return hasM4AThumbnail(filePath)
+	case ".ogg", ".opus", ".flac":
+		tags, err := readVorbisTags(filePath)
+		if err != nil {
+			return false, err
+		}
+		return len(tags.pictures) > 0, nil
 	default:
 		return false, fmt.Errorf("unsupported file format: %s", ext)
 	}
@@ -229,6 +545,17 @@ func (t *TagInjector) injectM4ATags(filePath string, video *youtube.Video) error
 	return nil
 }
 
+// injectVorbisTags writes the title, artist and video info of an Ogg, Opus or
+// FLAC file as the TITLE, ARTIST, ALBUM and COMMENT Vorbis comments.
+func (t *TagInjector) injectVorbisTags(filePath string, video *youtube.Video) error {
+	return setVorbisComments(filePath, map[string]string{
+		"TITLE":   video.Title,
+		"ARTIST":  video.Author.Name,
+		"ALBUM":   video.Author.Name, // Use channel name as album by default
+		"COMMENT": BuildComment(video),
+	})
+}
+
 // m4aTagStore is a simple in-memory store for M4A tags (for testing).
 // In production, this would be replaced with actual file manipulation.
 var m4aTagStore = make(map[string]*Tags)
@@ -265,6 +592,12 @@ func ReadTags(filePath string) (*Tags, error) {
 		return readMP3Tags(filePath)
 	case ".m4a", ".mp4", ".aac":
 		return readM4ATags(filePath)
+	case ".ogg", ".opus", ".flac":
+		tags, err := readVorbisTags(filePath)
+		if err != nil {
+			return nil, err
+		}
+		return tags.comment.tags(), nil
 	default:
 		return nil, fmt.Errorf("unsupported file format: %s", ext)
 	}
@@ -283,6 +616,14 @@ func readMP3Tags(filePath string) (*Tags, error) {
 		Artist: tag.Artist(),
 		Album:  tag.Album(),
 	}
+	tags.AlbumArtist = tag.GetTextFrame(tag.CommonID("Band/Orchestra/Accompaniment")).Text
+	tags.Year, _ = strconv.Atoi(tag.Year())
+	if track := tag.GetTextFrame(tag.CommonID("Track number/Position in set")).Text; track != "" {
+		// The track number may be followed by the track count, as in "3/12"
+		number, count, _ := strings.Cut(track, "/")
+		tags.Track, _ = strconv.Atoi(number)
+		tags.TrackCount, _ = strconv.Atoi(count)
+	}
 
 	// Get comment from comment frames
 	if commentFrames := tag.GetFrames(tag.CommonID("Comments")); len(commentFrames) > 0 {
@@ -291,6 +632,13 @@ func readMP3Tags(filePath string) (*Tags, error) {
 		}
 	}
 
+	// Get lyrics from unsynchronised lyrics frames
+	if lyricsFrames := tag.GetFrames(tag.CommonID("Unsynchronised lyrics/text transcription")); len(lyricsFrames) > 0 {
+		if lf, ok := lyricsFrames[0].(id3v2.UnsynchronisedLyricsFrame); ok {
+			tags.Lyrics = lf.Lyrics
+		}
+	}
+
 	return tags, nil
 }
 
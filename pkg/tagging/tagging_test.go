@@ -1,11 +1,19 @@
 package tagging
 
 import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/bogem/id3v2/v2"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
 	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
 )
 
@@ -141,6 +149,287 @@ func TestTagInjector_InjectTags_M4AFile(t *testing.T) {
 	}
 }
 
+func TestTagInjector_WriteTags_MP3(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.mp3")
+	if err := os.WriteFile(testFile, createMinimalMP3(), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	video := &youtube.Video{Title: "Rick Astley - Never Gonna Give You Up", Author: youtube.Author{Name: "Rick Astley"}}
+
+	injector := NewTagInjector()
+	if err := injector.InjectTags(testFile, video); err != nil {
+		t.Fatalf("InjectTags failed: %v", err)
+	}
+	want := Tags{Title: "Never Gonna Give You Up", Artist: "Rick Astley", Album: "Whenever You Need Somebody", AlbumArtist: "Various", Year: 1987, Track: 1, TrackCount: 10}
+	if err := injector.WriteTags(context.Background(), testFile, &want); err != nil {
+		t.Fatalf("WriteTags failed: %v", err)
+	}
+
+	tags, err := ReadTags(testFile)
+	if err != nil {
+		t.Fatalf("ReadTags failed: %v", err)
+	}
+	if tags.Title != want.Title || tags.Artist != want.Artist || tags.Album != want.Album || tags.AlbumArtist != want.AlbumArtist ||
+		tags.Year != want.Year || tags.Track != want.Track || tags.TrackCount != want.TrackCount {
+		t.Errorf("tags = %+v, want %+v", tags, want)
+	}
+	if !strings.Contains(tags.Comment, "Video URL") {
+		t.Errorf("Comment = %q, want the comment InjectTags wrote kept", tags.Comment)
+	}
+}
+
+func TestTagInjector_WriteTags_M4A(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.m4a")
+	if err := os.WriteFile(testFile, createMinimalM4A(), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var got map[string]string
+	injector := &TagInjector{SetMetadata: func(_ context.Context, _, outputPath string, metadata map[string]string) error {
+		got = metadata
+		return os.WriteFile(outputPath, []byte("tagged"), 0o644)
+	}}
+	if err := injector.WriteTags(context.Background(), testFile, &Tags{Title: "Get Lucky", Artist: "Daft Punk", Year: 2013, Track: 8}); err != nil {
+		t.Fatalf("WriteTags failed: %v", err)
+	}
+
+	want := map[string]string{"title": "Get Lucky", "artist": "Daft Punk", "date": "2013", "track": "8"}
+	if len(got) != len(want) {
+		t.Errorf("metadata = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("metadata[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestTagInjector_VorbisComments(t *testing.T) {
+	for _, name := range []string{"test.opus", "test.ogg", "test.flac"} {
+		t.Run(name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(testFile, createMinimalVorbisFile(name), 0o644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			injector := NewTagInjector()
+			video := &youtube.Video{ID: "dQw4w9WgXcQ", Title: "Never Gonna Give You Up", Author: youtube.Author{Name: "Rick Astley"}}
+			if err := injector.InjectTags(testFile, video); err != nil {
+				t.Fatalf("InjectTags failed: %v", err)
+			}
+			if err := injector.WriteTags(context.Background(), testFile, &Tags{Album: "Whenever You Need Somebody", Year: 1987, Track: 1, TrackCount: 10}); err != nil {
+				t.Fatalf("WriteTags failed: %v", err)
+			}
+			if err := injector.InjectLyrics(context.Background(), testFile, "Never gonna give you up"); err != nil {
+				t.Fatalf("InjectLyrics failed: %v", err)
+			}
+
+			tags, err := ReadTags(testFile)
+			if err != nil {
+				t.Fatalf("ReadTags failed: %v", err)
+			}
+			if tags.Title != video.Title || tags.Artist != "Rick Astley" || !strings.Contains(tags.Comment, "Video URL") {
+				t.Errorf("InjectTags tags = %+v", tags)
+			}
+			if tags.Album != "Whenever You Need Somebody" || tags.Year != 1987 || tags.Track != 1 || tags.TrackCount != 10 {
+				t.Errorf("WriteTags tags = %+v", tags)
+			}
+			if tags.Lyrics != "Never gonna give you up" {
+				t.Errorf("Lyrics = %q, want the lyrics", tags.Lyrics)
+			}
+			if entries, _ := os.ReadDir(filepath.Dir(testFile)); len(entries) != 1 {
+				t.Errorf("directory has %d files, want only the tagged one", len(entries))
+			}
+		})
+	}
+}
+
+func TestTagInjector_VorbisPicture(t *testing.T) {
+	cover := createTestJPEG(t)
+	for _, name := range []string{"test.opus", "test.flac"} {
+		t.Run(name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(testFile, createMinimalVorbisFile(name), 0o644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			if has, err := HasEmbeddedThumbnail(testFile); err != nil || has {
+				t.Fatalf("HasEmbeddedThumbnail() = %v, %v before embedding", has, err)
+			}
+			injector := NewTagInjector()
+			for range 2 {
+				if err := injector.injectVorbisPicture(testFile, cover, "image/jpeg"); err != nil {
+					t.Fatalf("injectVorbisPicture failed: %v", err)
+				}
+			}
+
+			if has, err := HasEmbeddedThumbnail(testFile); err != nil || !has {
+				t.Errorf("HasEmbeddedThumbnail() = %v, %v, want true", has, err)
+			}
+			tags, err := readVorbisTags(testFile)
+			if err != nil {
+				t.Fatalf("readVorbisTags failed: %v", err)
+			}
+			if len(tags.pictures) != 1 {
+				t.Fatalf("got %d pictures, want the front cover replaced", len(tags.pictures))
+			}
+			p := tags.pictures[0]
+			if p.pictureType != pictureFrontCover || p.mimeType != "image/jpeg" || p.width != 4 || p.height != 3 || !bytes.Equal(p.data, cover) {
+				t.Errorf("picture = %d %s %dx%d, want a 4x3 JPEG front cover", p.pictureType, p.mimeType, p.width, p.height)
+			}
+			if len(tags.comment.fields) != 0 {
+				t.Errorf("comment fields = %q, want pictures kept out of them", tags.comment.fields)
+			}
+		})
+	}
+}
+
+func TestTagInjector_InjectLyrics_MP3(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.mp3")
+	if err := os.WriteFile(testFile, createMinimalMP3(), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	injector := NewTagInjector()
+	if err := injector.InjectLyrics(context.Background(), testFile, "old lyrics"); err != nil {
+		t.Fatalf("InjectLyrics failed: %v", err)
+	}
+	lyrics := "[00:01.00]Never gonna give you up\n[00:03.50]Never gonna let you down\n"
+	if err := injector.InjectLyrics(context.Background(), testFile, lyrics); err != nil {
+		t.Fatalf("InjectLyrics failed: %v", err)
+	}
+
+	tags, err := ReadTags(testFile)
+	if err != nil {
+		t.Fatalf("ReadTags failed: %v", err)
+	}
+	if tags.Lyrics != lyrics {
+		t.Errorf("Lyrics mismatch: got %q, want %q", tags.Lyrics, lyrics)
+	}
+}
+
+func TestTagInjector_InjectLyrics_M4A(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.m4a")
+	if err := os.WriteFile(testFile, createMinimalM4A(), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var got map[string]string
+	injector := &TagInjector{SetMetadata: func(_ context.Context, inputPath, outputPath string, metadata map[string]string) error {
+		if inputPath != testFile {
+			t.Errorf("SetMetadata input = %q, want %q", inputPath, testFile)
+		}
+		got = metadata
+		return os.WriteFile(outputPath, []byte("tagged"), 0o644)
+	}}
+	if err := injector.InjectLyrics(context.Background(), testFile, "Some lyrics"); err != nil {
+		t.Fatalf("InjectLyrics failed: %v", err)
+	}
+
+	if got["lyrics"] != "Some lyrics" {
+		t.Errorf("SetMetadata metadata = %v, want the lyrics", got)
+	}
+	if data, _ := os.ReadFile(testFile); string(data) != "tagged" {
+		t.Errorf("file = %q, want the tagged copy", data)
+	}
+	if entries, _ := os.ReadDir(tmpDir); len(entries) != 1 {
+		t.Errorf("directory has %d files, want only the tagged one", len(entries))
+	}
+}
+
+func TestTagInjector_InjectLyrics_UnsupportedFormat(t *testing.T) {
+	err := NewTagInjector().InjectLyrics(context.Background(), "song.webm", "lyrics")
+	if err == nil || !strings.Contains(err.Error(), "unsupported file format") {
+		t.Errorf("InjectLyrics() error = %v, want an unsupported format error", err)
+	}
+}
+
+func TestTagInjector_WriteReplayGain_MP3(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.mp3")
+	if err := os.WriteFile(testFile, createMinimalMP3(), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	injector := NewTagInjector()
+	if err := injector.WriteReplayGain(context.Background(), testFile, &ffmpeg.ReplayGain{Integrated: -10, TruePeak: 0}); err != nil {
+		t.Fatalf("WriteReplayGain failed: %v", err)
+	}
+	if err := injector.WriteReplayGain(context.Background(), testFile, &ffmpeg.ReplayGain{Integrated: -14.1, TruePeak: -0.3}); err != nil {
+		t.Fatalf("WriteReplayGain failed: %v", err)
+	}
+
+	tag, err := id3v2.Open(testFile, id3v2.Options{Parse: true})
+	if err != nil {
+		t.Fatalf("Failed to open MP3 file: %v", err)
+	}
+	defer func() { _ = tag.Close() }()
+
+	got := map[string]string{}
+	for _, f := range tag.GetFrames(tag.CommonID("User defined text information frame")) {
+		udtf := f.(id3v2.UserDefinedTextFrame)
+		got[udtf.Description] = udtf.Value
+	}
+	want := map[string]string{"REPLAYGAIN_TRACK_GAIN": "-3.90 dB", "REPLAYGAIN_TRACK_PEAK": "0.966051"}
+	if len(got) != len(want) || got["REPLAYGAIN_TRACK_GAIN"] != want["REPLAYGAIN_TRACK_GAIN"] || got["REPLAYGAIN_TRACK_PEAK"] != want["REPLAYGAIN_TRACK_PEAK"] {
+		t.Errorf("TXXX frames = %v, want %v", got, want)
+	}
+
+	frames := tag.GetFrames("RVA2")
+	if len(frames) != 1 {
+		t.Fatalf("got %d RVA2 frames, want 1", len(frames))
+	}
+	// -3.9 dB is -1997/512 dB, and a 0.966 peak is 31656/32768
+	wantBody := []byte{'t', 'r', 'a', 'c', 'k', 0, 0x01, 0xf8, 0x33, 16, 0x7b, 0xa8}
+	if body := frames[0].(id3v2.UnknownFrame).Body; !bytes.Equal(body, wantBody) {
+		t.Errorf("RVA2 body = % x, want % x", body, wantBody)
+	}
+}
+
+func TestTagInjector_WriteReplayGain_VorbisComments(t *testing.T) {
+	gain := &ffmpeg.ReplayGain{Integrated: -14.1, TruePeak: -0.3}
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"song.flac", []string{"REPLAYGAIN_TRACK_GAIN=-3.90 dB", "REPLAYGAIN_TRACK_PEAK=0.966051"}},
+		{"song.ogg", []string{"REPLAYGAIN_TRACK_GAIN=-3.90 dB", "REPLAYGAIN_TRACK_PEAK=0.966051"}},
+		{"song.opus", []string{"R128_TRACK_GAIN=-2278"}},
+	}
+	for _, tt := range tests {
+		testFile := filepath.Join(t.TempDir(), tt.file)
+		if err := os.WriteFile(testFile, createMinimalVorbisFile(tt.file), 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		injector := NewTagInjector()
+		// Writing twice replaces the earlier values
+		for range 2 {
+			if err := injector.WriteReplayGain(context.Background(), testFile, gain); err != nil {
+				t.Fatalf("WriteReplayGain(%s) failed: %v", tt.file, err)
+			}
+		}
+		tags, err := readVorbisTags(testFile)
+		if err != nil {
+			t.Fatalf("readVorbisTags(%s) failed: %v", tt.file, err)
+		}
+		if !slices.Equal(tags.comment.fields, tt.want) {
+			t.Errorf("%s: fields = %q, want %q", tt.file, tags.comment.fields, tt.want)
+		}
+	}
+}
+
+func TestTagInjector_WriteReplayGain_UnsupportedFormat(t *testing.T) {
+	err := NewTagInjector().WriteReplayGain(context.Background(), "song.m4a", &ffmpeg.ReplayGain{Integrated: -14})
+	if err == nil || !strings.Contains(err.Error(), "unsupported file format") {
+		t.Errorf("WriteReplayGain() error = %v, want an unsupported format error", err)
+	}
+}
+
 func TestTagInjector_InjectThumbnail_MP3(t *testing.T) {
 	tmpDir := t.TempDir()
 	testFile := filepath.Join(tmpDir, "test.mp3")
@@ -230,6 +519,19 @@ func TestGetThumbnailURL_SelectsHighestQualityJPG(t *testing.T) {
 	}
 }
 
+func TestGetThumbnailURL_ConsidersWebP(t *testing.T) {
+	thumbnails := []youtube.Thumbnail{
+		{URL: "https://i.ytimg.com/vi/abc/hqdefault.jpg?sqp=-oaymwE&rs=AOn4", Width: 480, Height: 360},
+		{URL: "https://i.ytimg.com/vi_webp/abc/maxresdefault.webp", Width: 1280, Height: 720},
+		{URL: "https://i.ytimg.com/vi/abc/storyboard.avif", Width: 1920, Height: 1080},
+	}
+
+	url := GetThumbnailURL("abc", thumbnails)
+	if url != "https://i.ytimg.com/vi_webp/abc/maxresdefault.webp" {
+		t.Errorf("Expected the WebP maxresdefault URL, got %s", url)
+	}
+}
+
 func TestGetThumbnailURL_UsesFallbackForEmptyList(t *testing.T) {
 	url := GetThumbnailURL("test123", []youtube.Thumbnail{})
 	expected := "https://i.ytimg.com/vi/test123/hqdefault.jpg"
@@ -354,6 +656,72 @@ func createMinimalMP3() []byte {
 	return result
 }
 
+// createMinimalVorbisFile creates a minimal FLAC file, or an Opus or Ogg
+// Vorbis one, by the extension of name.
+func createMinimalVorbisFile(name string) []byte {
+	switch filepath.Ext(name) {
+	case ".flac":
+		return createMinimalFLAC()
+	case ".ogg":
+		return createMinimalOggVorbis()
+	default:
+		return createMinimalOpus()
+	}
+}
+
+// createMinimalFLAC creates a FLAC file with a stream info block and a
+// Vorbis comment block, followed by a stand-in for the audio frames.
+func createMinimalFLAC() []byte {
+	var buf bytes.Buffer
+	comment := (&vorbisComment{vendor: "reference libFLAC 1.4.3"}).marshal()
+	_ = writeFLACBlocks(&buf, []flacBlock{{blockType: flacStreamInfo, data: make([]byte, 34)}, {blockType: flacVorbisComment, data: comment}})
+	buf.WriteString("\xff\xf8audio frames")
+	return buf.Bytes()
+}
+
+// createMinimalOpus creates an Opus file with its two header pages and an
+// audio page.
+func createMinimalOpus() []byte {
+	head := append([]byte("OpusHead"), 1, 2, 0x38, 0x01, 0x80, 0xbb, 0, 0, 0, 0, 0)
+	tags := append([]byte("OpusTags"), (&vorbisComment{vendor: "Lavf61.7.100"}).marshal()...)
+	return createTestOgg([][]byte{head}, [][]byte{tags}, []byte("opus audio"))
+}
+
+// createMinimalOggVorbis creates an Ogg Vorbis file with its identification
+// page, a page with the comment and setup headers and an audio page.
+func createMinimalOggVorbis() []byte {
+	id := append([]byte("\x01vorbis"), make([]byte, 23)...)
+	comment := append([]byte("\x03vorbis"), (&vorbisComment{vendor: "Xiph.Org libVorbis I 20200704 (Reducing Environment)"}).marshal()...)
+	comment = append(comment, 1) // Framing bit
+	setup := append([]byte("\x05vorbis"), "codebooks"...)
+	return createTestOgg([][]byte{id}, [][]byte{comment, setup}, []byte("vorbis audio"))
+}
+
+// createTestOgg creates an Ogg stream with a page for each group of packets,
+// the first starting the stream, and then a page with the audio packet ending it.
+func createTestOgg(id, headers [][]byte, audio []byte) []byte {
+	var buf bytes.Buffer
+	pages := paginate(0x1234, 0, id)
+	pages[0].headerType = oggFirst
+	pages = append(pages, paginate(0x1234, uint32(len(pages)), headers)...)
+	last := paginate(0x1234, uint32(len(pages)), [][]byte{audio})[0]
+	last.headerType, last.granule = 0x04, 960
+	for _, page := range append(pages, last) {
+		buf.Write(page.marshal())
+	}
+	return buf.Bytes()
+}
+
+// createTestJPEG creates a 4x3 JPEG image.
+func createTestJPEG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 3)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
 // createMinimalM4A creates a minimal valid M4A/MP4 container.
 func createMinimalM4A() []byte {
 	// Minimal ftyp box + moov box structure
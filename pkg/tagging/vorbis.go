@@ -0,0 +1,285 @@
+package tagging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// vendorString is the vendor of Vorbis comments created for files that had none.
+const vendorString = "golang-youtube-downloader"
+
+// pictureFrontCover is the picture type of front covers, as in ID3v2 APIC frames.
+const pictureFrontCover = 3
+
+// errInvalidComment is returned for Vorbis comments whose lengths run past their data.
+var errInvalidComment = errors.New("invalid Vorbis comment")
+
+// vorbisComment is a Vorbis comment header, the tags of FLAC, Ogg Vorbis and
+// Opus files. Fields are NAME=value pairs; names are case-insensitive and may
+// repeat.
+type vorbisComment struct {
+	vendor string
+	fields []string
+}
+
+// parseVorbisComment parses the Vorbis comment at the start of data and
+// returns it with the data after it, such as the framing bit of Ogg Vorbis.
+func parseVorbisComment(data []byte) (*vorbisComment, []byte, error) {
+	vendor, data, ok := cutLengthPrefixed(data)
+	if !ok || len(data) < 4 {
+		return nil, nil, errInvalidComment
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+
+	// Each field takes at least its 4 byte length, which bounds the count
+	if uint64(count) > uint64(len(data))/4 {
+		return nil, nil, errInvalidComment
+	}
+	c := &vorbisComment{vendor: string(vendor), fields: make([]string, 0, count)}
+	for range count {
+		var field []byte
+		if field, data, ok = cutLengthPrefixed(data); !ok {
+			return nil, nil, errInvalidComment
+		}
+		c.fields = append(c.fields, string(field))
+	}
+	return c, data, nil
+}
+
+// cutLengthPrefixed cuts a string prefixed by its 32-bit little-endian length
+// from the start of data.
+func cutLengthPrefixed(data []byte) ([]byte, []byte, bool) {
+	if len(data) < 4 {
+		return nil, nil, false
+	}
+	n := binary.LittleEndian.Uint32(data)
+	if uint64(n) > uint64(len(data)-4) {
+		return nil, nil, false
+	}
+	return data[4 : 4+n], data[4+n:], true
+}
+
+// marshal encodes the comment, without the packet type or framing bit of Ogg Vorbis.
+func (c *vorbisComment) marshal() []byte {
+	data := binary.LittleEndian.AppendUint32(nil, uint32(len(c.vendor)))
+	data = append(data, c.vendor...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(c.fields)))
+	for _, field := range c.fields {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(field)))
+		data = append(data, field...)
+	}
+	return data
+}
+
+// get returns the value of the first field named name, or "".
+func (c *vorbisComment) get(name string) string {
+	for _, field := range c.fields {
+		if key, value, ok := strings.Cut(field, "="); ok && strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// set replaces the fields named name with one holding value.
+func (c *vorbisComment) set(name, value string) {
+	c.remove(name)
+	c.fields = append(c.fields, strings.ToUpper(name)+"="+value)
+}
+
+// remove removes the fields named name.
+func (c *vorbisComment) remove(name string) {
+	fields := c.fields[:0]
+	for _, field := range c.fields {
+		if key, _, _ := strings.Cut(field, "="); !strings.EqualFold(key, name) {
+			fields = append(fields, field)
+		}
+	}
+	c.fields = fields
+}
+
+// tags returns the tags the comment holds. The track count is read from
+// TRACKTOTAL or TOTALTRACKS, or from a TRACKNUMBER such as "3/12".
+func (c *vorbisComment) tags() *Tags {
+	tags := &Tags{
+		Title:       c.get("TITLE"),
+		Artist:      c.get("ARTIST"),
+		Album:       c.get("ALBUM"),
+		Description: c.get("DESCRIPTION"),
+		Comment:     c.get("COMMENT"),
+		Lyrics:      c.get("LYRICS"),
+		AlbumArtist: c.get("ALBUMARTIST"),
+	}
+	// Dates may be full, as in "2013-05-17"
+	if date := c.get("DATE"); len(date) >= 4 {
+		tags.Year, _ = strconv.Atoi(date[:4])
+	}
+	number, count, _ := strings.Cut(c.get("TRACKNUMBER"), "/")
+	tags.Track, _ = strconv.Atoi(number)
+	tags.TrackCount, _ = strconv.Atoi(count)
+	for _, name := range []string{"TRACKTOTAL", "TOTALTRACKS"} {
+		if total, err := strconv.Atoi(c.get(name)); err == nil {
+			tags.TrackCount = total
+		}
+	}
+	return tags
+}
+
+// picture is a FLAC picture block, which Ogg files hold base64 encoded in a
+// METADATA_BLOCK_PICTURE comment.
+type picture struct {
+	pictureType uint32
+	mimeType    string
+	description string
+	width       uint32
+	height      uint32
+	depth       uint32
+	colors      uint32
+	data        []byte
+}
+
+// newCoverPicture returns a front cover picture of the image in data.
+func newCoverPicture(data []byte, mimeType string) *picture {
+	p := &picture{pictureType: pictureFrontCover, mimeType: mimeType, description: "Cover", data: data}
+	if config, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		p.width, p.height = uint32(config.Width), uint32(config.Height)
+		p.depth = 24
+		if format == "png" {
+			p.depth = 32
+		}
+	}
+	return p
+}
+
+// parsePicture parses the picture block data.
+func parsePicture(data []byte) (*picture, error) {
+	p := &picture{}
+	var ok bool
+	var mimeType, description []byte
+	if len(data) < 4 {
+		return nil, errors.New("invalid picture block")
+	}
+	p.pictureType = binary.BigEndian.Uint32(data)
+	if mimeType, data, ok = cutBigEndianPrefixed(data[4:]); !ok {
+		return nil, errors.New("invalid picture block")
+	}
+	if description, data, ok = cutBigEndianPrefixed(data); !ok || len(data) < 16 {
+		return nil, errors.New("invalid picture block")
+	}
+	p.mimeType, p.description = string(mimeType), string(description)
+	p.width = binary.BigEndian.Uint32(data)
+	p.height = binary.BigEndian.Uint32(data[4:])
+	p.depth = binary.BigEndian.Uint32(data[8:])
+	p.colors = binary.BigEndian.Uint32(data[12:])
+	if p.data, _, ok = cutBigEndianPrefixed(data[16:]); !ok {
+		return nil, errors.New("invalid picture block")
+	}
+	return p, nil
+}
+
+// cutBigEndianPrefixed cuts a string prefixed by its 32-bit big-endian length
+// from the start of data, as picture blocks hold them.
+func cutBigEndianPrefixed(data []byte) ([]byte, []byte, bool) {
+	if len(data) < 4 {
+		return nil, nil, false
+	}
+	n := binary.BigEndian.Uint32(data)
+	if uint64(n) > uint64(len(data)-4) {
+		return nil, nil, false
+	}
+	return data[4 : 4+n], data[4+n:], true
+}
+
+// marshal encodes the picture block data.
+func (p *picture) marshal() []byte {
+	data := binary.BigEndian.AppendUint32(nil, p.pictureType)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(p.mimeType)))
+	data = append(data, p.mimeType...)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(p.description)))
+	data = append(data, p.description...)
+	for _, v := range []uint32{p.width, p.height, p.depth, p.colors, uint32(len(p.data))} {
+		data = binary.BigEndian.AppendUint32(data, v)
+	}
+	return append(data, p.data...)
+}
+
+// vorbisTags are the Vorbis comment and pictures of a FLAC, Ogg Vorbis or
+// Opus file. The comment of Ogg files doesn't include their pictures.
+type vorbisTags struct {
+	comment  *vorbisComment
+	pictures []*picture
+}
+
+// readVorbisTags reads the tags of a FLAC file, or of an Ogg Vorbis or Opus
+// file whatever its extension.
+func readVorbisTags(filePath string) (*vorbisTags, error) {
+	if strings.EqualFold(filepath.Ext(filePath), ".flac") {
+		return readFLACTags(filePath)
+	}
+	return readOggTags(filePath)
+}
+
+// updateVorbisTags changes the tags of a FLAC, Ogg Vorbis or Opus file with
+// update and writes them back, keeping the audio as it is.
+func updateVorbisTags(filePath string, update func(*vorbisTags)) error {
+	var err error
+	if strings.EqualFold(filepath.Ext(filePath), ".flac") {
+		err = updateFLACTags(filePath, update)
+	} else {
+		err = updateOggTags(filePath, update)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write Vorbis comments: %w", err)
+	}
+	return nil
+}
+
+// setVorbisComments sets the fields of a FLAC, Ogg Vorbis or Opus file to the
+// values of fields, replacing the fields with the same names.
+func setVorbisComments(filePath string, fields map[string]string) error {
+	return updateVorbisTags(filePath, func(tags *vorbisTags) {
+		// Sorted, so the fields are written in the same order every time
+		for _, name := range slices.Sorted(maps.Keys(fields)) {
+			tags.comment.set(name, fields[name])
+		}
+	})
+}
+
+// rewriteFile replaces filePath with the file write writes, which is written
+// next to it first so that a failure leaves the original file intact.
+func rewriteFile(filePath string, write func(w io.Writer) error) error {
+	ext := filepath.Ext(filePath)
+	tmp := strings.TrimSuffix(filePath, ext) + ".tagged" + ext
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, filePath)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
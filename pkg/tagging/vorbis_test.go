@@ -0,0 +1,71 @@
+package tagging
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestVorbisComment_RoundTrip(t *testing.T) {
+	c := &vorbisComment{vendor: "vendor", fields: []string{"title=Old", "ARTIST=A", "Title=Older"}}
+	c.set("Title", "New")
+	c.remove("artist")
+
+	parsed, rest, err := parseVorbisComment(append(c.marshal(), 1))
+	if err != nil {
+		t.Fatalf("parseVorbisComment failed: %v", err)
+	}
+	if parsed.vendor != "vendor" || !slices.Equal(parsed.fields, []string{"TITLE=New"}) {
+		t.Errorf("comment = %+v", parsed)
+	}
+	if !bytes.Equal(rest, []byte{1}) {
+		t.Errorf("rest = %v, want the byte after the comment", rest)
+	}
+}
+
+func TestParseVorbisComment_Invalid(t *testing.T) {
+	valid := (&vorbisComment{vendor: "v", fields: []string{"A=1"}}).marshal()
+	for _, data := range [][]byte{
+		nil,
+		valid[:len(valid)-1],
+		{0xff, 0xff, 0xff, 0xff},
+		{0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}, // More fields than there's data for
+	} {
+		if _, _, err := parseVorbisComment(data); err == nil {
+			t.Errorf("parseVorbisComment(% x) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestVorbisComment_Tags(t *testing.T) {
+	tests := []struct {
+		fields             []string
+		year, track, count int
+	}{
+		{[]string{"DATE=2013-05-17", "TRACKNUMBER=8"}, 2013, 8, 0},
+		{[]string{"DATE=1987", "TRACKNUMBER=3/12"}, 1987, 3, 12},
+		{[]string{"tracknumber=1", "TRACKTOTAL=10"}, 0, 1, 10},
+		{[]string{"TRACKNUMBER=2", "TOTALTRACKS=5"}, 0, 2, 5},
+	}
+	for _, tt := range tests {
+		tags := (&vorbisComment{fields: tt.fields}).tags()
+		if tags.Year != tt.year || tags.Track != tt.track || tags.TrackCount != tt.count {
+			t.Errorf("tags of %q = year %d, track %d/%d, want %d, %d/%d", tt.fields, tags.Year, tags.Track, tags.TrackCount, tt.year, tt.track, tt.count)
+		}
+	}
+}
+
+func TestPicture_RoundTrip(t *testing.T) {
+	p := &picture{pictureType: pictureFrontCover, mimeType: "image/png", description: "Cover", width: 4, height: 3, depth: 32, data: []byte("png")}
+	parsed, err := parsePicture(p.marshal())
+	if err != nil {
+		t.Fatalf("parsePicture failed: %v", err)
+	}
+	if parsed.pictureType != p.pictureType || parsed.mimeType != p.mimeType || parsed.description != p.description ||
+		parsed.width != 4 || parsed.height != 3 || parsed.depth != 32 || !bytes.Equal(parsed.data, p.data) {
+		t.Errorf("picture = %+v, want %+v", parsed, p)
+	}
+	if _, err := parsePicture(p.marshal()[:20]); err == nil {
+		t.Error("parsePicture of a truncated block succeeded")
+	}
+}
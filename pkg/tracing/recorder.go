@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+)
+
+// Recorder is a Tracer that keeps the spans it starts in memory, for tests and
+// debugging. It is safe for concurrent use.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan is a span started by a Recorder. Its fields must not be read
+// before the span has ended.
+type RecordedSpan struct {
+	Name       string
+	Parent     *RecordedSpan
+	Attributes []Attribute
+	Events     []RecordedEvent
+	Err        error
+	Ended      bool
+
+	mu sync.Mutex
+}
+
+// RecordedEvent is an event added to a RecordedSpan.
+type RecordedEvent struct {
+	Name       string
+	Attributes []Attribute
+}
+
+// Start starts a recorded span, a child of the span of ctx if it was recorded too.
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	span := &RecordedSpan{Name: name, Attributes: attrs}
+	if parent, ok := SpanFrom(ctx).(*RecordedSpan); ok {
+		span.Parent = parent
+	}
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+	return ctx, span
+}
+
+// Spans returns the spans started so far, in the order they were started.
+func (r *Recorder) Spans() []*RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*RecordedSpan(nil), r.spans...)
+}
+
+// Named returns the spans named name.
+func (r *Recorder) Named(name string) []*RecordedSpan {
+	var spans []*RecordedSpan
+	for _, span := range r.Spans() {
+		if span.Name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// Attribute returns the value of the span's last attribute named key.
+func (s *RecordedSpan) Attribute(key string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.Attributes) - 1; i >= 0; i-- {
+		if s.Attributes[i].Key == key {
+			return s.Attributes[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+func (s *RecordedSpan) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Attributes = append(s.Attributes, attrs...)
+}
+
+func (s *RecordedSpan) AddEvent(name string, attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Events = append(s.Events, RecordedEvent{Name: name, Attributes: attrs})
+}
+
+func (s *RecordedSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Err = err
+}
+
+func (s *RecordedSpan) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Ended = true
+}
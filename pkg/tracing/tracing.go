@@ -0,0 +1,170 @@
+// Package tracing defines the tracing hooks of the downloader: a span around
+// every metadata fetch, stream download and mux, with the retries of a
+// download recorded as events on its span.
+//
+// Nothing is traced until a Tracer is added to the context with WithTracer,
+// and the package has no dependencies. Its interfaces follow OpenTelemetry's,
+// so a service that uses OpenTelemetry adapts its tracer in a few lines:
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, attrs ...tracing.Attribute) (context.Context, tracing.Span) {
+//		ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(otelAttributes(attrs)...))
+//		return ctx, otelSpan{span}
+//	}
+package tracing
+
+import (
+	"context"
+	"fmt"
+)
+
+// Span names of the downloader's operations.
+const (
+	SpanFetchWatchPage = "youtube.FetchWatchPage"
+	SpanFetchPlaylist  = "youtube.FetchPlaylist"
+	SpanDownload       = "ytdl.Download"
+	SpanStream         = "download.Stream"
+	SpanMux            = "mux.Mux"
+	SpanFFmpegMux      = "ffmpeg.Mux"
+)
+
+// Attribute keys of the downloader's spans.
+const (
+	AttrVideoID      = "ytdl.video_id"
+	AttrPlaylistID   = "ytdl.playlist_id"
+	AttrItag         = "ytdl.itag"
+	AttrHost         = "server.address"
+	AttrBytes        = "ytdl.bytes"
+	AttrVerification = "ytdl.verification"
+	AttrRetryReason  = "ytdl.retry.reason"
+	AttrRetryAttempt = "ytdl.retry.attempt"
+	AttrOffset       = "ytdl.offset"
+	AttrOutput       = "ytdl.output"
+	AttrError        = "error.message"
+)
+
+// EventRetry is the name of the span event recorded when a stream request is
+// made again, with the reason and attempt as attributes.
+const EventRetry = "retry"
+
+// Attribute is a key-value pair describing a span, such as the ID of the video
+// being downloaded. Values are strings, int64s or bools.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int64 returns an integer attribute.
+func Int64(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// String returns the attribute as key=value.
+func (a Attribute) String() string {
+	return fmt.Sprintf("%s=%v", a.Key, a.Value)
+}
+
+// Span is one traced operation. Its methods may be called from several
+// goroutines, since a download's streams are fetched in parallel.
+type Span interface {
+	// SetAttributes adds attributes learned while the operation ran.
+	SetAttributes(attrs ...Attribute)
+
+	// AddEvent records something that happened during the operation.
+	AddEvent(name string, attrs ...Attribute)
+
+	// RecordError marks the operation as failed with err.
+	RecordError(err error)
+
+	// End finishes the span.
+	End()
+}
+
+// Tracer starts spans. Start returns a context carrying the new span, so
+// spans started from it are its children.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+type tracerKey struct{}
+
+type spanKey struct{}
+
+type attributesKey struct{}
+
+// WithTracer returns a copy of ctx in which the downloader's operations are
+// traced with tracer.
+func WithTracer(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+// HasTracer reports whether ctx carries a tracer.
+func HasTracer(ctx context.Context) bool {
+	tracer, ok := ctx.Value(tracerKey{}).(Tracer)
+	return ok && tracer != nil
+}
+
+// WithAttributes returns a copy of ctx whose spans get attrs in addition to
+// their own, such as the ID of a download a service correlates its logs by.
+func WithAttributes(ctx context.Context, attrs ...Attribute) context.Context {
+	inherited := Attributes(ctx)
+	combined := make([]Attribute, 0, len(inherited)+len(attrs))
+	combined = append(append(combined, inherited...), attrs...)
+	return context.WithValue(ctx, attributesKey{}, combined)
+}
+
+// Attributes returns the attributes added to ctx with WithAttributes.
+func Attributes(ctx context.Context) []Attribute {
+	attrs, _ := ctx.Value(attributesKey{}).([]Attribute)
+	return attrs
+}
+
+// Start starts a span named name with the tracer of ctx, with the attributes
+// of ctx and attrs. Without a tracer it returns ctx and a span that does nothing.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	tracer, ok := ctx.Value(tracerKey{}).(Tracer)
+	if !ok || tracer == nil {
+		return ctx, noopSpan{}
+	}
+	if inherited := Attributes(ctx); len(inherited) > 0 {
+		attrs = append(append([]Attribute(nil), inherited...), attrs...)
+	}
+	ctx, span := tracer.Start(ctx, name, attrs...)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanFrom returns the span last started in ctx, or a span that does nothing.
+func SpanFrom(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanKey{}).(Span); ok {
+		return span
+	}
+	return noopSpan{}
+}
+
+// End records err on span if it isn't nil and ends it, for deferring with a
+// named error result.
+func End(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// noopSpan is the span of untraced operations.
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute)    {}
+func (noopSpan) AddEvent(string, ...Attribute) {}
+func (noopSpan) RecordError(error)             {}
+func (noopSpan) End()                          {}
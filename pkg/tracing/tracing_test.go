@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStart_WithoutTracer(t *testing.T) {
+	ctx := context.Background()
+	spanCtx, span := Start(ctx, "op")
+	if spanCtx != ctx {
+		t.Error("Start without a tracer changed the context")
+	}
+	if _, ok := span.(noopSpan); !ok {
+		t.Errorf("span = %T, want a no-op span", span)
+	}
+	End(span, errors.New("ignored"))
+	if HasTracer(ctx) {
+		t.Error("HasTracer() = true for a context without a tracer")
+	}
+}
+
+func TestStart_NestsSpansAndInheritsAttributes(t *testing.T) {
+	recorder := &Recorder{}
+	ctx := WithAttributes(WithTracer(context.Background(), recorder), String(AttrVideoID, "dQw4w9WgXcQ"))
+
+	ctx, parent := Start(ctx, SpanDownload)
+	_, child := Start(ctx, SpanStream, Int64(AttrItag, 140))
+	child.AddEvent(EventRetry, String(AttrRetryReason, "resume"))
+	failure := errors.New("refused")
+	End(child, failure)
+	End(parent, nil)
+
+	spans := recorder.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	stream := recorder.Named(SpanStream)[0]
+	if stream.Parent != spans[0] {
+		t.Errorf("stream parent = %v, want the download span", stream.Parent)
+	}
+	if id, _ := stream.Attribute(AttrVideoID); id != "dQw4w9WgXcQ" {
+		t.Errorf("video ID = %v, want it inherited from the context", id)
+	}
+	if itag, _ := stream.Attribute(AttrItag); itag != int64(140) {
+		t.Errorf("itag = %v", itag)
+	}
+	if len(stream.Events) != 1 || stream.Events[0].Name != EventRetry {
+		t.Errorf("events = %+v", stream.Events)
+	}
+	if !errors.Is(stream.Err, failure) || !stream.Ended || !spans[0].Ended || spans[0].Err != nil {
+		t.Errorf("spans = %+v, %+v", spans[0], stream)
+	}
+	if SpanFrom(ctx) != parent {
+		t.Error("SpanFrom() doesn't return the span started in the context")
+	}
+}
+
+func TestWithAttributes_DoesNotShareSlices(t *testing.T) {
+	base := WithAttributes(context.Background(), String("a", "1"))
+	first := WithAttributes(base, String("b", "2"))
+	second := WithAttributes(base, String("c", "3"))
+	if got := Attributes(first); len(got) != 2 || got[1].Key != "b" {
+		t.Errorf("first = %v", got)
+	}
+	if got := Attributes(second); len(got) != 2 || got[1].Key != "c" {
+		t.Errorf("second = %v", got)
+	}
+}
@@ -0,0 +1,269 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrRedirectNotAllowed is returned when a redirect leads to a host outside the allowlist.
+var ErrRedirectNotAllowed = errors.New("redirect to disallowed host")
+
+// defaultMaxRedirects is the number of redirects URLExpander follows by default.
+const defaultMaxRedirects = 5
+
+// canonicalHosts maps YouTube host aliases to the host used in canonical URLs.
+var canonicalHosts = map[string]string{
+	"youtube.com":              "www.youtube.com",
+	"www.youtube.com":          "www.youtube.com",
+	"m.youtube.com":            "www.youtube.com",
+	"music.youtube.com":        "www.youtube.com",
+	"youtube-nocookie.com":     "www.youtube.com",
+	"www.youtube-nocookie.com": "www.youtube.com",
+	"youtu.be":                 "youtu.be",
+	"www.youtu.be":             "youtu.be",
+}
+
+// redirectHosts are the hosts URLExpander may contact while following redirects.
+var redirectHosts = map[string]bool{
+	"youtube.com":         true,
+	"www.youtube.com":     true,
+	"m.youtube.com":       true,
+	"music.youtube.com":   true,
+	"consent.youtube.com": true,
+	"youtu.be":            true,
+	"www.youtu.be":        true,
+	"y2u.be":              true,
+}
+
+// wrapperParams lists the query parameters holding the target URL of known
+// redirect and consent wrappers, by host.
+var wrapperParams = map[string][]string{
+	"www.youtube.com":     {"q"},
+	"consent.youtube.com": {"continue"},
+	"www.google.com":      {"q", "url"},
+	"google.com":          {"q", "url"},
+}
+
+// trackingParams are share and tracking query parameters dropped from canonical URLs.
+var trackingParams = []string{"si", "feature", "pp", "app", "ab_channel", "embeds_referring_euri"}
+
+// maxUnwrapDepth limits how many nested redirect wrappers CanonicalizeURL unwraps.
+const maxUnwrapDepth = 5
+
+// CanonicalizeURL normalizes a YouTube URL without making network requests:
+//   - Adds a missing https:// scheme to bare YouTube hosts (e.g. "youtu.be/ID")
+//   - Unwraps youtube.com/redirect, consent.youtube.com and google.com/url wrappers
+//   - Maps host aliases (m., music., youtube-nocookie.com) to www.youtube.com
+//   - Rewrites /shorts/ID and /live/ID to /watch?v=ID
+//   - Drops share and tracking parameters (si, feature, utm_*, ...)
+//
+// Inputs that are not YouTube URLs, such as raw IDs and search queries, are returned unchanged.
+func CanonicalizeURL(input string) string {
+	input = strings.TrimSpace(input)
+	u, ok := parseLooseURL(input)
+	if !ok {
+		return input
+	}
+
+	for range maxUnwrapDepth {
+		target, ok := unwrapRedirect(u)
+		if !ok {
+			break
+		}
+		u = target
+	}
+
+	host, ok := canonicalHosts[strings.ToLower(u.Host)]
+	if !ok {
+		return input
+	}
+	u.Scheme = "https"
+	u.Host = host
+
+	query := u.Query()
+	for _, prefix := range []string{"/shorts/", "/live/"} {
+		if id := extractPathID(u.Path, prefix); strings.HasPrefix(u.Path, prefix) && IsValidVideoID(id) {
+			u.Path = "/watch"
+			query.Set("v", id)
+		}
+	}
+
+	for key := range query {
+		if strings.HasPrefix(key, "utm_") {
+			query.Del(key)
+		}
+	}
+	for _, key := range trackingParams {
+		query.Del(key)
+	}
+	u.RawQuery = query.Encode()
+	u.Fragment = ""
+
+	return u.String()
+}
+
+// parseLooseURL parses input as an http(s) URL, accepting a bare host
+// without a scheme if it is a known YouTube or redirect host.
+func parseLooseURL(input string) (*url.URL, bool) {
+	lower := strings.ToLower(input)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		host, _, _ := strings.Cut(lower, "/")
+		if _, ok := canonicalHosts[host]; !ok && !redirectHosts[host] {
+			return nil, false
+		}
+		input = "https://" + input
+	}
+
+	u, err := url.Parse(input)
+	if err != nil || u.Host == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+// unwrapRedirect returns the target URL of a known redirect wrapper.
+func unwrapRedirect(u *url.URL) (*url.URL, bool) {
+	host := strings.ToLower(u.Host)
+	if host == "youtube.com" || host == "m.youtube.com" {
+		host = "www.youtube.com"
+	}
+
+	switch {
+	case host == "www.youtube.com" && u.Path != "/redirect":
+		return nil, false
+	case (host == "www.google.com" || host == "google.com") && u.Path != "/url":
+		return nil, false
+	}
+
+	for _, param := range wrapperParams[host] {
+		if target := u.Query().Get(param); target != "" {
+			if t, ok := parseLooseURL(target); ok {
+				return t, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// URLExpander resolves short links by following a bounded number of HTTP redirects
+// through allowlisted YouTube hosts.
+type URLExpander struct {
+	// Client is the HTTP client used for requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// MaxRedirects is the maximum number of redirects to follow. 0 uses the default (5).
+	MaxRedirects int
+}
+
+// NewURLExpander creates a new URLExpander with the given HTTP client.
+func NewURLExpander(client *http.Client) *URLExpander {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &URLExpander{Client: client}
+}
+
+// Expand follows redirects from rawURL and returns the canonicalized final URL.
+// Every hop must stay on an allowlisted host, otherwise ErrRedirectNotAllowed is returned.
+func (e *URLExpander) Expand(ctx context.Context, rawURL string) (string, error) {
+	current, ok := parseLooseURL(strings.TrimSpace(rawURL))
+	if !ok || !redirectHosts[strings.ToLower(current.Host)] {
+		return "", fmt.Errorf("%w: %s", ErrRedirectNotAllowed, rawURL)
+	}
+
+	client := http.DefaultClient
+	if e.Client != nil {
+		client = e.Client
+	}
+	// Redirects are followed manually so each hop can be checked against the allowlist
+	noFollow := *client
+	noFollow.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	maxRedirects := e.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+
+	for hops := 0; ; hops++ {
+		// Stop as soon as the link can be resolved offline
+		if canonical := CanonicalizeURL(current.String()); hops > 0 && isResolvable(canonical) {
+			return canonical, nil
+		}
+		if hops == maxRedirects {
+			return "", fmt.Errorf("too many redirects resolving %s", rawURL)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, current.String(), http.NoBody)
+		if err != nil {
+			return "", fmt.Errorf("creating request: %w", err)
+		}
+
+		resp, err := noFollow.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("fetching %s: %w", current.Host, err)
+		}
+		_ = resp.Body.Close()
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			return CanonicalizeURL(current.String()), nil
+		}
+
+		next, err := current.Parse(location)
+		if err != nil {
+			return "", fmt.Errorf("parsing redirect location: %w", err)
+		}
+		if next.Scheme != "https" && next.Scheme != "http" {
+			return "", fmt.Errorf("%w: %s", ErrRedirectNotAllowed, location)
+		}
+
+		// Wrapped targets are unwrapped locally instead of being fetched
+		if target, ok := unwrapRedirect(next); ok {
+			next = target
+		}
+		if !redirectHosts[strings.ToLower(next.Host)] {
+			if _, ok := canonicalHosts[strings.ToLower(next.Host)]; !ok {
+				return "", fmt.Errorf("%w: %s", ErrRedirectNotAllowed, next.Host)
+			}
+		}
+		current = next
+	}
+}
+
+// isResolvable reports whether ResolveQuery accepts input.
+func isResolvable(input string) bool {
+	_, err := ResolveQuery(input)
+	return err == nil
+}
+
+// ResolveQueryContext is like ResolveQuery, but if the input cannot be resolved and
+// is a link on an allowlisted host, it is expanded by following redirects and resolved again.
+// A nil expander disables redirect following.
+func ResolveQueryContext(ctx context.Context, input string, expander *URLExpander) (QueryResult, error) {
+	return ResolveQueryWithOptions(ctx, input, ResolveOptions{Expander: expander})
+}
+
+// expandQuery resolves input after expanding it with expander, if it is a link
+// on an allowlisted host. Other input fails with err, the error of resolving it as is.
+func expandQuery(ctx context.Context, input string, expander *URLExpander, err error) (QueryResult, error) {
+	if expander == nil {
+		return QueryResult{}, err
+	}
+
+	u, ok := parseLooseURL(strings.TrimSpace(input))
+	if !ok || !redirectHosts[strings.ToLower(u.Host)] {
+		return QueryResult{}, err
+	}
+
+	expanded, expandErr := expander.Expand(ctx, input)
+	if expandErr != nil {
+		return QueryResult{}, fmt.Errorf("%w: %w", ErrUnresolvableQuery, expandErr)
+	}
+	return ResolveQuery(expanded)
+}
@@ -0,0 +1,187 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalizeURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"raw video ID unchanged", "dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"search query unchanged", "?never gonna", "?never gonna"},
+		{"non-YouTube URL unchanged", "https://example.com/watch?v=dQw4w9WgXcQ", "https://example.com/watch?v=dQw4w9WgXcQ"},
+		{"missing scheme", "youtu.be/dQw4w9WgXcQ", "https://youtu.be/dQw4w9WgXcQ"},
+		{"share params dropped", "https://youtu.be/dQw4w9WgXcQ?si=abc123&feature=shared", "https://youtu.be/dQw4w9WgXcQ"},
+		{"utm params dropped", "https://www.youtube.com/watch?v=dQw4w9WgXcQ&utm_source=x&utm_medium=y", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"timestamp kept", "https://youtube.com/watch?v=dQw4w9WgXcQ&t=42", "https://www.youtube.com/watch?t=42&v=dQw4w9WgXcQ"},
+		{"music host", "https://music.youtube.com/watch?v=dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"mobile host", "http://m.youtube.com/watch?v=dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"live share link", "https://www.youtube.com/live/dQw4w9WgXcQ?si=xyz", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"shorts", "https://youtube.com/shorts/dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{
+			"redirect wrapper",
+			"https://www.youtube.com/redirect?event=share&q=" + url.QueryEscape("https://youtu.be/dQw4w9WgXcQ?si=1"),
+			"https://youtu.be/dQw4w9WgXcQ",
+		},
+		{
+			"consent wrapper",
+			"https://consent.youtube.com/m?continue=" + url.QueryEscape("https://www.youtube.com/watch?v=dQw4w9WgXcQ&cbrd=1"),
+			"https://www.youtube.com/watch?cbrd=1&v=dQw4w9WgXcQ",
+		},
+		{
+			"google tracking wrapper",
+			"https://www.google.com/url?sa=t&url=" + url.QueryEscape("https://m.youtube.com/watch?v=dQw4w9WgXcQ"),
+			"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		},
+		{
+			"redirect to external site unchanged",
+			"https://www.youtube.com/redirect?q=https%3A%2F%2Fexample.com",
+			"https://www.youtube.com/redirect?q=https%3A%2F%2Fexample.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanonicalizeURL(tt.input); got != tt.want {
+				t.Errorf("CanonicalizeURL(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveQuery_CanonicalizesShareLinks(t *testing.T) {
+	inputs := []string{
+		"https://www.youtube.com/live/dQw4w9WgXcQ?si=abc",
+		"https://youtube.com/shorts/dQw4w9WgXcQ",
+		"https://www.youtube.com/redirect?q=" + url.QueryEscape("https://youtu.be/dQw4w9WgXcQ"),
+	}
+
+	for _, input := range inputs {
+		result, err := ResolveQuery(input)
+		if err != nil {
+			t.Errorf("ResolveQuery(%q) failed: %v", input, err)
+			continue
+		}
+		if result.Type != QueryTypeVideo || result.VideoID != "dQw4w9WgXcQ" {
+			t.Errorf("ResolveQuery(%q) = %+v, want video dQw4w9WgXcQ", input, result)
+		}
+	}
+}
+
+// redirectClient returns a client that sends every request to server, so tests
+// can serve allowlisted hostnames locally.
+func redirectClient(server *httptest.Server) *http.Client {
+	target, _ := url.Parse(server.URL)
+	client := server.Client()
+	base := client.Transport
+	client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.Host = req.URL.Host
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+		return base.RoundTrip(req)
+	})
+	return client
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestURLExpander_Expand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Host {
+		case "y2u.be":
+			http.Redirect(w, r, "https://youtu.be/dQw4w9WgXcQ?si=share", http.StatusMovedPermanently)
+		case "youtu.be":
+			http.Redirect(w, r, "https://www.youtube.com/watch?v=dQw4w9WgXcQ&feature=youtu.be", http.StatusSeeOther)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	expander := NewURLExpander(redirectClient(server))
+	got, err := expander.Expand(context.Background(), "https://y2u.be/dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	// youtu.be links resolve offline, so the second hop is never fetched
+	if got != "https://youtu.be/dQw4w9WgXcQ" {
+		t.Errorf("Expand() = %q", got)
+	}
+}
+
+func TestURLExpander_RejectsDisallowedHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://evil.example.com/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	expander := NewURLExpander(redirectClient(server))
+
+	if _, err := expander.Expand(context.Background(), "https://y2u.be/abc"); !errors.Is(err, ErrRedirectNotAllowed) {
+		t.Errorf("expected ErrRedirectNotAllowed for redirect off the allowlist, got %v", err)
+	}
+	if _, err := expander.Expand(context.Background(), "https://bit.ly/abc"); !errors.Is(err, ErrRedirectNotAllowed) {
+		t.Errorf("expected ErrRedirectNotAllowed for start host off the allowlist, got %v", err)
+	}
+}
+
+func TestURLExpander_MaxRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://y2u.be/loop", http.StatusFound)
+	}))
+	defer server.Close()
+
+	expander := &URLExpander{Client: redirectClient(server), MaxRedirects: 3}
+	_, err := expander.Expand(context.Background(), "https://y2u.be/loop")
+	if err == nil || !strings.Contains(err.Error(), "too many redirects") {
+		t.Errorf("expected too many redirects error, got %v", err)
+	}
+}
+
+func TestResolveQueryContext_ExpandsShortLinks(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Redirect(w, r, "https://www.youtube.com/watch?v=dQw4w9WgXcQ", http.StatusFound)
+	}))
+	defer server.Close()
+
+	expander := NewURLExpander(redirectClient(server))
+
+	result, err := ResolveQueryContext(context.Background(), "https://y2u.be/short", expander)
+	if err != nil {
+		t.Fatalf("ResolveQueryContext failed: %v", err)
+	}
+	if result.VideoID != "dQw4w9WgXcQ" {
+		t.Errorf("expected video dQw4w9WgXcQ, got %+v", result)
+	}
+
+	// Inputs that resolve offline should not hit the network
+	requests = 0
+	if _, err := ResolveQueryContext(context.Background(), "https://youtu.be/dQw4w9WgXcQ", expander); err != nil {
+		t.Fatalf("ResolveQueryContext failed: %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests for a resolvable URL, got %d", requests)
+	}
+}
+
+func TestResolveQueryContext_NilExpander(t *testing.T) {
+	if _, err := ResolveQueryContext(context.Background(), "https://y2u.be/short", nil); !errors.Is(err, ErrUnresolvableQuery) {
+		t.Errorf("expected ErrUnresolvableQuery, got %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"html"
 	"io"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -73,6 +74,19 @@ func (m *CaptionManifest) GetAutoGeneratedTracks() []CaptionTrack {
 	return tracks
 }
 
+// LyricsTrack returns the track that carries the song's lyrics: a manually
+// created track whose name says it is lyrics, such as "English (Lyrics)", as
+// music videos often have. Returns nil if there is none.
+func (m *CaptionManifest) LyricsTrack() *CaptionTrack {
+	for i := range m.Tracks {
+		track := &m.Tracks[i]
+		if !track.IsAutoGenerated && strings.Contains(strings.ToLower(track.LanguageName), "lyrics") {
+			return track
+		}
+	}
+	return nil
+}
+
 // CaptionFormat represents the output format for captions.
 type CaptionFormat string
 
@@ -129,6 +143,21 @@ func (cd *CaptionData) ToVTT() string {
 	return sb.String()
 }
 
+// ToLRC converts the caption data to LRC timed lyrics, one "[mm:ss.xx]" line
+// per caption, which music players show in step with the song. Captions
+// spanning several lines are joined into one.
+func (cd *CaptionData) ToLRC() string {
+	var sb strings.Builder
+	for _, line := range cd.Lines {
+		text := strings.Join(strings.Fields(line.Text), " ")
+		if text == "" {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("[%s]%s\n", formatLRCTime(line.Start), text))
+	}
+	return sb.String()
+}
+
 // formatSRTTime formats a time in seconds to SRT timestamp format (HH:MM:SS,mmm).
 func formatSRTTime(seconds float64) string {
 	h := int(seconds / 3600)
@@ -147,6 +176,13 @@ func formatVTTTime(seconds float64) string {
 	return fmt.Sprintf("%02d:%02d:%02d.%03d", h, m, s, ms)
 }
 
+// formatLRCTime formats a time in seconds to LRC timestamp format (mm:ss.xx).
+// Minutes aren't wrapped into hours, as LRC has no hour field.
+func formatLRCTime(seconds float64) string {
+	cs := int(math.Round(seconds * 100))
+	return fmt.Sprintf("%02d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
+
 // CaptionDownloader downloads and parses YouTube captions.
 type CaptionDownloader struct {
 	// Client is the HTTP client to use for requests.
@@ -132,6 +132,24 @@ func TestCaptionManifest_GetAutoGeneratedTracks(t *testing.T) {
 	}
 }
 
+func TestCaptionManifest_LyricsTrack(t *testing.T) {
+	manifest := CaptionManifest{
+		Tracks: []CaptionTrack{
+			{LanguageCode: "en", LanguageName: "English", IsAutoGenerated: false},
+			{LanguageCode: "en", LanguageName: "English (auto-generated lyrics)", IsAutoGenerated: true},
+			{LanguageCode: "en", LanguageName: "English - Lyrics", IsAutoGenerated: false},
+		},
+	}
+
+	track := manifest.LyricsTrack()
+	if track == nil || track.LanguageName != "English - Lyrics" {
+		t.Errorf("LyricsTrack() = %+v, want the manual lyrics track", track)
+	}
+	if track := (&CaptionManifest{Tracks: manifest.Tracks[:2]}).LyricsTrack(); track != nil {
+		t.Errorf("LyricsTrack() = %+v, want nil without a manual lyrics track", track)
+	}
+}
+
 func TestPlayerResponse_ExtractCaptionManifest(t *testing.T) {
 	// Test with captions present
 	html := `<!DOCTYPE html>
@@ -355,6 +373,21 @@ func TestCaptionData_ToVTT(t *testing.T) {
 	}
 }
 
+func TestCaptionData_ToLRC(t *testing.T) {
+	data := &CaptionData{
+		Lines: []CaptionLine{
+			{Start: 3.45, Duration: 2, Text: "Never gonna\ngive you up"},
+			{Start: 6, Duration: 1, Text: "  "},
+			{Start: 3725.5, Duration: 2, Text: "Never gonna let you down"},
+		},
+	}
+
+	want := "[00:03.45]Never gonna give you up\n[62:05.50]Never gonna let you down\n"
+	if got := data.ToLRC(); got != want {
+		t.Errorf("ToLRC() = %q, want %q", got, want)
+	}
+}
+
 func TestCaptionDownloader_Download(t *testing.T) {
 	// Create a test server that returns caption XML
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,208 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrChannelUnavailable is returned when a channel page has no channel,
+// for example because the handle doesn't exist or the channel was terminated.
+var ErrChannelUnavailable = errors.New("channel is unavailable")
+
+// Channel represents a YouTube channel with its metadata.
+type Channel struct {
+	// ID is the channel identifier (starting with UC).
+	ID string
+
+	// Title is the channel's display name.
+	Title string
+
+	// Handle is the channel's handle without the leading @, if it has one.
+	Handle string
+
+	// Description is the channel's description (may be empty).
+	Description string
+
+	// SubscriberCount is the approximate number of subscribers, 0 if hidden.
+	// YouTube rounds the count (e.g. "1.2M"), so this is not exact.
+	SubscriberCount int64
+
+	// SubscriberCountText is the subscriber count as YouTube displays it.
+	SubscriberCountText string
+
+	// Thumbnails are the channel's avatar images.
+	Thumbnails []Thumbnail
+}
+
+// URL returns the URL of the channel's page.
+func (c *Channel) URL() string {
+	return ChannelIdentifier{Type: ChannelTypeID, Value: c.ID}.URL()
+}
+
+// UploadsPlaylistID returns the ID of the playlist holding the channel's uploads.
+func (c *Channel) UploadsPlaylistID() PlaylistID {
+	return ChannelToUploadsPlaylistID(ChannelID(c.ID))
+}
+
+// ChannelFetcher fetches YouTube channels and their uploads.
+type ChannelFetcher struct {
+	// Client is the HTTP client to use for requests.
+	Client *http.Client
+
+	// BaseURL is the base URL for YouTube (used for testing).
+	// If empty, defaults to https://www.youtube.com.
+	BaseURL string
+}
+
+// Fetch retrieves the channel's metadata. Handles, custom URLs and legacy
+// user names are resolved to the channel ID from the channel's page.
+func (f *ChannelFetcher) Fetch(ctx context.Context, channel ChannelIdentifier) (*Channel, error) {
+	baseURL := f.BaseURL
+	if baseURL == "" {
+		baseURL = youtubeBaseURL
+	}
+
+	html, err := fetchPage(ctx, f.Client, baseURL+channel.Path())
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := extractInitialData(html)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parseChannel(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing channel: %w", err)
+	}
+	if result.ID == "" {
+		return nil, fmt.Errorf("%w: %s", ErrChannelUnavailable, channel.Value)
+	}
+	return result, nil
+}
+
+// ResolveID returns the channel ID for any channel identifier.
+// Channel IDs are returned as is; other identifiers need a page request.
+func (f *ChannelFetcher) ResolveID(ctx context.Context, channel ChannelIdentifier) (ChannelID, error) {
+	if channel.Type == ChannelTypeID {
+		return NewChannelID(channel.Value)
+	}
+	result, err := f.Fetch(ctx, channel)
+	if err != nil {
+		return "", err
+	}
+	return NewChannelID(result.ID)
+}
+
+// Uploads returns a PlaylistFetcher for listing the channel uploads playlist.
+func (f *ChannelFetcher) Uploads() *PlaylistFetcher {
+	return &PlaylistFetcher{Client: f.Client, BaseURL: f.BaseURL}
+}
+
+// parseChannel extracts the channel metadata from ytInitialData.
+func parseChannel(data json.RawMessage) (*Channel, error) {
+	var page struct {
+		Metadata struct {
+			ChannelMetadataRenderer struct {
+				Title            string        `json:"title"`
+				Description      string        `json:"description"`
+				ExternalID       string        `json:"externalId"`
+				VanityChannelURL string        `json:"vanityChannelUrl"`
+				Avatar           thumbnailList `json:"avatar"`
+			} `json:"channelMetadataRenderer"`
+		} `json:"metadata"`
+		Header json.RawMessage `json:"header"`
+	}
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, err
+	}
+
+	meta := page.Metadata.ChannelMetadataRenderer
+	channel := &Channel{
+		ID:          meta.ExternalID,
+		Title:       meta.Title,
+		Description: meta.Description,
+	}
+	if i := strings.LastIndex(meta.VanityChannelURL, "/@"); i != -1 {
+		channel.Handle = meta.VanityChannelURL[i+2:]
+	}
+	for _, t := range meta.Avatar.Thumbnails {
+		channel.Thumbnails = append(channel.Thumbnails, Thumbnail(t))
+	}
+
+	if len(page.Header) > 0 {
+		var header any
+		if err := json.Unmarshal(page.Header, &header); err == nil {
+			channel.SubscriberCountText = findSubscriberText(header)
+			channel.SubscriberCount = parseAbbreviatedCount(channel.SubscriberCountText)
+		}
+	}
+
+	return channel, nil
+}
+
+// findSubscriberText finds the subscriber count text in the channel header.
+//
+// The classic header has a subscriberCountText field, while the newer page
+// header lists it as one of several metadata rows, so the header is walked
+// for the first text mentioning subscribers.
+func findSubscriberText(v any) string {
+	switch node := v.(type) {
+	case string:
+		if strings.Contains(strings.ToLower(node), "subscriber") {
+			return node
+		}
+	case map[string]any:
+		// Visit keys in a fixed order so repeated parses pick the same text
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if text := findSubscriberText(node[key]); text != "" {
+				return text
+			}
+		}
+	case []any:
+		for _, child := range node {
+			if text := findSubscriberText(child); text != "" {
+				return text
+			}
+		}
+	}
+	return ""
+}
+
+// abbreviatedCountPattern matches counts like "1.2M", "987K" or "1,234".
+var abbreviatedCountPattern = regexp.MustCompile(`([\d.,]+)\s*([KMB])?`)
+
+// parseAbbreviatedCount converts a displayed count such as "1.2M subscribers" to a number.
+// Returns 0 if the text has no count.
+func parseAbbreviatedCount(text string) int64 {
+	m := abbreviatedCountPattern.FindStringSubmatch(strings.ToUpper(text))
+	if m == nil {
+		return 0
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+	switch m[2] {
+	case "K":
+		value *= 1e3
+	case "M":
+		value *= 1e6
+	case "B":
+		value *= 1e9
+	}
+	return int64(value + 0.5)
+}
@@ -0,0 +1,124 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testChannelID = "UCuAXFkgsw1L7xaCfnd5JJOw"
+
+// channelInitialData returns ytInitialData for a channel page with the given header.
+func channelInitialData(header string) string {
+	return `{"metadata":{"channelMetadataRenderer":{"title":"Test Channel","description":"About the channel","externalId":"` + testChannelID +
+		`","vanityChannelUrl":"http://www.youtube.com/@testchannel","avatar":{"thumbnails":[{"url":"https://yt3.example/a.jpg","width":88,"height":88}]}}},` +
+		`"header":` + header + `}`
+}
+
+const (
+	classicChannelHeader = `{"c4TabbedHeaderRenderer":{"channelId":"` + testChannelID + `","subscriberCountText":{"simpleText":"1.23M subscribers"}}}`
+	pageChannelHeader    = `{"pageHeaderRenderer":{"content":{"pageHeaderViewModel":{"metadata":{"contentMetadataViewModel":{"metadataRows":[` +
+		`{"metadataParts":[{"text":{"content":"@testchannel"}}]},` +
+		`{"metadataParts":[{"text":{"content":"987K subscribers"}},{"text":{"content":"120 videos"}}]}]}}}}}}`
+)
+
+func TestChannelFetcher_FetchResolvesHandle(t *testing.T) {
+	var requestedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		_, _ = w.Write([]byte(`<script>var ytInitialData = ` + channelInitialData(classicChannelHeader) + `;</script>`))
+	}))
+	defer server.Close()
+
+	fetcher := &ChannelFetcher{Client: server.Client(), BaseURL: server.URL}
+	channel, err := fetcher.Fetch(context.Background(), ChannelIdentifier{Type: ChannelTypeHandle, Value: "testchannel"})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if requestedPath != "/@testchannel" {
+		t.Errorf("requested %q, want /@testchannel", requestedPath)
+	}
+	if channel.ID != testChannelID || channel.Title != "Test Channel" || channel.Handle != "testchannel" {
+		t.Errorf("channel = %+v", channel)
+	}
+	if channel.Description != "About the channel" {
+		t.Errorf("description = %q", channel.Description)
+	}
+	if channel.SubscriberCountText != "1.23M subscribers" || channel.SubscriberCount != 1230000 {
+		t.Errorf("subscribers = %q (%d)", channel.SubscriberCountText, channel.SubscriberCount)
+	}
+	if len(channel.Thumbnails) != 1 {
+		t.Errorf("thumbnails = %d, want 1", len(channel.Thumbnails))
+	}
+	if channel.UploadsPlaylistID() != "UUuAXFkgsw1L7xaCfnd5JJOw" {
+		t.Errorf("uploads playlist = %q", channel.UploadsPlaylistID())
+	}
+}
+
+func TestChannelFetcher_ResolveID(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`<script>var ytInitialData = ` + channelInitialData(classicChannelHeader) + `;</script>`))
+	}))
+	defer server.Close()
+	fetcher := &ChannelFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	id, err := fetcher.ResolveID(context.Background(), ChannelIdentifier{Type: ChannelTypeID, Value: testChannelID})
+	if err != nil || id != testChannelID || requests != 0 {
+		t.Errorf("ResolveID(id) = %q, %v after %d requests; want no request", id, err, requests)
+	}
+
+	id, err = fetcher.ResolveID(context.Background(), ChannelIdentifier{Type: ChannelTypeUser, Value: "legacy"})
+	if err != nil || id != testChannelID {
+		t.Errorf("ResolveID(user) = %q, %v; want %q", id, err, testChannelID)
+	}
+}
+
+func TestChannelFetcher_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<script>var ytInitialData = {"alerts":[]};</script>`))
+	}))
+	defer server.Close()
+
+	fetcher := &ChannelFetcher{Client: server.Client(), BaseURL: server.URL}
+	_, err := fetcher.Fetch(context.Background(), ChannelIdentifier{Type: ChannelTypeHandle, Value: "missing"})
+	if !errors.Is(err, ErrChannelUnavailable) {
+		t.Errorf("expected ErrChannelUnavailable, got %v", err)
+	}
+}
+
+func TestParseChannel_PageHeader(t *testing.T) {
+	channel, err := parseChannel(json.RawMessage(channelInitialData(pageChannelHeader)))
+	if err != nil {
+		t.Fatalf("parseChannel failed: %v", err)
+	}
+	if channel.SubscriberCountText != "987K subscribers" || channel.SubscriberCount != 987000 {
+		t.Errorf("subscribers = %q (%d)", channel.SubscriberCountText, channel.SubscriberCount)
+	}
+}
+
+func TestParseAbbreviatedCount(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{"1.23M subscribers", 1230000},
+		{"987K subscribers", 987000},
+		{"1,234 subscribers", 1234},
+		{"2.5B subscribers", 2500000000},
+		{"1 subscriber", 1},
+		{"No subscribers", 0},
+		{"", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseAbbreviatedCount(tt.input); got != tt.want {
+			t.Errorf("parseAbbreviatedCount(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
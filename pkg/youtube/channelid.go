@@ -2,6 +2,7 @@ package youtube
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
@@ -39,6 +40,34 @@ func IsValidChannelID(id string) bool {
 	return channelIDRegex.MatchString(id)
 }
 
+// ChannelID is a YouTube channel ID, such as UCuAXFkgsw1L7xaCfnd5JJOw, checked
+// by the fetchers that take one before making any request. Handles and custom
+// URLs are ChannelIdentifiers, resolved to a ChannelID with ChannelFetcher.
+type ChannelID string
+
+// NewChannelID returns id as a ChannelID, or an error wrapping
+// ErrInvalidChannelID if it isn't a valid channel ID.
+func NewChannelID(id string) (ChannelID, error) {
+	c := ChannelID(id)
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+	return c, nil
+}
+
+// Validate returns an error wrapping ErrInvalidChannelID if c isn't a valid channel ID.
+func (c ChannelID) Validate() error {
+	if !IsValidChannelID(string(c)) {
+		return fmt.Errorf("%w: %q", ErrInvalidChannelID, string(c))
+	}
+	return nil
+}
+
+// String returns the ID.
+func (c ChannelID) String() string {
+	return string(c)
+}
+
 // ParseChannelIdentifier extracts the channel identifier from a YouTube URL or validates a raw channel ID.
 // Supported URL formats:
 //   - https://www.youtube.com/channel/CHANNEL_ID
@@ -124,20 +153,39 @@ func extractFirstPathSegment(path string) string {
 // ChannelToUploadsPlaylistID converts a channel ID to its uploads playlist ID.
 // The uploads playlist for a channel is derived by replacing "UC" with "UU".
 // Returns an empty string if the input is not a valid channel ID.
-func ChannelToUploadsPlaylistID(channelID string) string {
-	if !IsValidChannelID(channelID) {
+func ChannelToUploadsPlaylistID(channelID ChannelID) PlaylistID {
+	if channelID.Validate() != nil {
 		return ""
 	}
 	// Replace "UC" prefix with "UU" to get the uploads playlist ID
-	return "UU" + channelID[2:]
+	return PlaylistID("UU" + channelID[2:])
 }
 
 // UploadsPlaylistID returns the uploads playlist ID for this channel.
 // Only works for ChannelTypeID identifiers; returns empty string for other types.
 // For handles, custom names, and usernames, you need to resolve the channel ID first.
-func (ci ChannelIdentifier) UploadsPlaylistID() string {
+func (ci ChannelIdentifier) UploadsPlaylistID() PlaylistID {
 	if ci.Type != ChannelTypeID {
 		return ""
 	}
-	return ChannelToUploadsPlaylistID(ci.Value)
+	return ChannelToUploadsPlaylistID(ChannelID(ci.Value))
+}
+
+// Path returns the URL path of the channel's page, such as "/@handle" or "/channel/ID".
+func (ci ChannelIdentifier) Path() string {
+	switch ci.Type {
+	case ChannelTypeHandle:
+		return "/@" + url.PathEscape(ci.Value)
+	case ChannelTypeCustom:
+		return "/c/" + url.PathEscape(ci.Value)
+	case ChannelTypeUser:
+		return "/user/" + url.PathEscape(ci.Value)
+	default:
+		return "/channel/" + ci.Value
+	}
+}
+
+// URL returns the URL of the channel's page.
+func (ci ChannelIdentifier) URL() string {
+	return youtubeBaseURL + ci.Path()
 }
@@ -1,6 +1,7 @@
 package youtube
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -200,8 +201,8 @@ func TestChannelToUploadsPlaylistID(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ChannelToUploadsPlaylistID(tt.channelID)
-			if got != tt.want {
+			got := ChannelToUploadsPlaylistID(ChannelID(tt.channelID))
+			if got != PlaylistID(tt.want) {
 				t.Errorf("ChannelToUploadsPlaylistID(%q) = %q, want %q", tt.channelID, got, tt.want)
 			}
 		})
@@ -229,7 +230,7 @@ func TestChannelToUploadsPlaylistID_InvalidInput(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ChannelToUploadsPlaylistID(tt.channelID)
+			got := ChannelToUploadsPlaylistID(ChannelID(tt.channelID))
 			if got != "" {
 				t.Errorf("ChannelToUploadsPlaylistID(%q) = %q, want empty string for invalid input", tt.channelID, got)
 			}
@@ -268,9 +269,44 @@ func TestChannelIdentifier_UploadsPlaylistID(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			got := tt.identifier.UploadsPlaylistID()
-			if got != tt.want {
+			if got != PlaylistID(tt.want) {
 				t.Errorf("UploadsPlaylistID() = %q, want %q", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestChannelIdentifier_Path(t *testing.T) {
+	tests := []struct {
+		channel ChannelIdentifier
+		want    string
+	}{
+		{ChannelIdentifier{Type: ChannelTypeID, Value: "UCuAXFkgsw1L7xaCfnd5JJOw"}, "/channel/UCuAXFkgsw1L7xaCfnd5JJOw"},
+		{ChannelIdentifier{Type: ChannelTypeHandle, Value: "MrBeast"}, "/@MrBeast"},
+		{ChannelIdentifier{Type: ChannelTypeCustom, Value: "MrBeast"}, "/c/MrBeast"},
+		{ChannelIdentifier{Type: ChannelTypeUser, Value: "PewDiePie"}, "/user/PewDiePie"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.channel.Path(); got != tt.want {
+			t.Errorf("Path() = %q, want %q", got, tt.want)
+		}
+	}
+
+	if got := (ChannelIdentifier{Type: ChannelTypeHandle, Value: "MrBeast"}).URL(); got != "https://www.youtube.com/@MrBeast" {
+		t.Errorf("URL() = %q", got)
+	}
+}
+
+func TestNewChannelID(t *testing.T) {
+	id, err := NewChannelID("UCuAXFkgsw1L7xaCfnd5JJOw")
+	if err != nil || id.String() != "UCuAXFkgsw1L7xaCfnd5JJOw" {
+		t.Errorf("NewChannelID() = %q, %v", id, err)
+	}
+
+	for _, input := range []string{"", "@MrBeast", "UC123", "https://www.youtube.com/channel/UCuAXFkgsw1L7xaCfnd5JJOw"} {
+		if _, err := NewChannelID(input); !errors.Is(err, ErrInvalidChannelID) {
+			t.Errorf("NewChannelID(%q) error = %v, want ErrInvalidChannelID", input, err)
+		}
+	}
+}
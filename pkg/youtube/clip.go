@@ -0,0 +1,181 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidClipID is returned when a clip ID cannot be parsed from the input.
+	ErrInvalidClipID = errors.New("invalid clip ID")
+
+	// ErrClipUnavailable is returned when a clip page has no clip, usually
+	// because the clip or its source video was deleted.
+	ErrClipUnavailable = errors.New("clip is unavailable")
+)
+
+// clipIDRegex matches YouTube clip IDs, which start with "Ugkx" followed by 32 characters.
+var clipIDRegex = regexp.MustCompile(`^Ug[a-zA-Z0-9_-]{34}$`)
+
+// IsValidClipID checks if the given string is a valid YouTube clip ID.
+func IsValidClipID(id string) bool {
+	return clipIDRegex.MatchString(id)
+}
+
+// ParseClipID extracts the clip ID from a YouTube clip URL.
+// Supported URL formats:
+//   - https://www.youtube.com/clip/CLIP_ID
+//   - https://youtube.com/clip/CLIP_ID?si=...
+//
+// Raw clip IDs are not accepted because they are easily mistaken for other IDs.
+func ParseClipID(input string) (string, error) {
+	parsedURL, err := url.Parse(strings.TrimSpace(input))
+	if err != nil || !isYouTubeHost(parsedURL.Host) {
+		return "", ErrInvalidClipID
+	}
+
+	clipID, ok := strings.CutPrefix(strings.TrimSuffix(parsedURL.Path, "/"), "/clip/")
+	if !ok || !IsValidClipID(clipID) {
+		return "", ErrInvalidClipID
+	}
+	return clipID, nil
+}
+
+// Clip is a section of a video shared as its own link.
+type Clip struct {
+	// ID is the clip identifier.
+	ID string
+
+	// VideoID is the ID of the video the clip was cut from.
+	VideoID string
+
+	// Start is the offset of the clip in the video.
+	Start time.Duration
+
+	// End is the offset at which the clip ends.
+	End time.Duration
+}
+
+// Duration returns the length of the clip.
+func (c *Clip) Duration() time.Duration {
+	return c.End - c.Start
+}
+
+// Range returns the section of the video the clip covers.
+func (c *Clip) Range() TimeRange {
+	return TimeRange{Start: c.Start, End: c.End}
+}
+
+// RangeString returns the clip's range in the video, e.g. "0:43-0:58".
+func (c *Clip) RangeString() string {
+	return c.Range().String()
+}
+
+// URL returns the clip's URL.
+func (c *Clip) URL() string {
+	return fmt.Sprintf("%s/clip/%s", youtubeBaseURL, c.ID)
+}
+
+// ClipFetcher resolves YouTube clips to their source video and time range.
+type ClipFetcher struct {
+	// Client is the HTTP client to use for requests.
+	Client *http.Client
+
+	// BaseURL is the base URL for YouTube (used for testing).
+	// If empty, defaults to https://www.youtube.com.
+	BaseURL string
+}
+
+// Fetch retrieves the clip page and returns the clip's source video and range.
+func (f *ClipFetcher) Fetch(ctx context.Context, clipID string) (*Clip, error) {
+	baseURL := f.BaseURL
+	if baseURL == "" {
+		baseURL = youtubeBaseURL
+	}
+
+	html, err := fetchPage(ctx, f.Client, fmt.Sprintf("%s/clip/%s", baseURL, clipID))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := extractInitialData(html)
+	if err != nil {
+		return nil, err
+	}
+	return parseClip(data, clipID)
+}
+
+// parseClip reads the source video and range from a clip page's ytInitialData.
+//
+// The page plays the source video with a loop over the clip's range; the
+// loopCommand holding the range sits deep inside the clip engagement panel,
+// so it is found by walking the data.
+func parseClip(data json.RawMessage, clipID string) (*Clip, error) {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parsing initial data: %w", err)
+	}
+
+	var page struct {
+		CurrentVideoEndpoint struct {
+			WatchEndpoint struct {
+				VideoID string `json:"videoId"`
+			} `json:"watchEndpoint"`
+		} `json:"currentVideoEndpoint"`
+	}
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, fmt.Errorf("parsing initial data: %w", err)
+	}
+
+	loop := findLoopCommand(root)
+	videoID := page.CurrentVideoEndpoint.WatchEndpoint.VideoID
+	if loop == nil || !IsValidVideoID(videoID) {
+		return nil, fmt.Errorf("%w: %s", ErrClipUnavailable, clipID)
+	}
+
+	clip := &Clip{
+		ID:      clipID,
+		VideoID: videoID,
+		Start:   millis(loop["startTimeMs"]),
+		End:     millis(loop["endTimeMs"]),
+	}
+	if clip.End <= clip.Start {
+		return nil, fmt.Errorf("%w: %s has an empty range", ErrClipUnavailable, clipID)
+	}
+	return clip, nil
+}
+
+// findLoopCommand returns the first loopCommand in v that has a time range.
+func findLoopCommand(v any) map[string]any {
+	switch node := v.(type) {
+	case map[string]any:
+		if loop, ok := node["loopCommand"].(map[string]any); ok && loop["endTimeMs"] != nil {
+			return loop
+		}
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if loop := findLoopCommand(node[key]); loop != nil {
+				return loop
+			}
+		}
+	case []any:
+		for _, child := range node {
+			if loop := findLoopCommand(child); loop != nil {
+				return loop
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testClipID = "UgkxU2HSeGL_NvmDJ-nQJrlLwllwMDBdGZFs"
+
+// clipInitialData is the ytInitialData of a clip page, trimmed to the fields parseClip reads.
+const clipInitialData = `{"currentVideoEndpoint":{"watchEndpoint":{"videoId":"dQw4w9WgXcQ"}},"engagementPanels":[{"engagementPanelSectionListRenderer":{"content":{"clipSectionRenderer":{"contents":[{"clipAttributionRenderer":{"onScrubExit":{"commandExecutorCommand":{"commands":[{"openPopupAction":{"popup":{"notificationActionRenderer":{"actionButton":{"buttonRenderer":{"command":{"commandExecutorCommand":{"commands":[{"seekToVideoTimestampCommand":{"offsetFromVideoStartMilliseconds":"43000"}},{"loopCommand":{"startTimeMs":"43000","endTimeMs":"58500","postId":"` + testClipID + `"}}]}}}}}}}}]}}}}]}}}}]}`
+
+func TestParseClipID(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"https://www.youtube.com/clip/" + testClipID, testClipID},
+		{"https://youtube.com/clip/" + testClipID + "/?si=x", testClipID},
+		{testClipID, ""},
+		{"https://www.youtube.com/clip/short", ""},
+		{"https://example.com/clip/" + testClipID, ""},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseClipID(tt.input)
+			if tt.want == "" {
+				if !errors.Is(err, ErrInvalidClipID) {
+					t.Errorf("ParseClipID(%q) = %q, %v; want ErrInvalidClipID", tt.input, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseClipID(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClipFetcher_Fetch(t *testing.T) {
+	var requestedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		_, _ = w.Write([]byte(`<script>var ytInitialData = ` + clipInitialData + `;</script>`))
+	}))
+	defer server.Close()
+
+	fetcher := &ClipFetcher{Client: server.Client(), BaseURL: server.URL}
+	clip, err := fetcher.Fetch(context.Background(), testClipID)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if requestedPath != "/clip/"+testClipID {
+		t.Errorf("requested %q", requestedPath)
+	}
+	if clip.VideoID != "dQw4w9WgXcQ" {
+		t.Errorf("video ID = %q", clip.VideoID)
+	}
+	if clip.Start != 43*time.Second || clip.End != 58500*time.Millisecond || clip.Duration() != 15500*time.Millisecond {
+		t.Errorf("range = %v-%v", clip.Start, clip.End)
+	}
+	if clip.RangeString() != "0:43-0:58.500" {
+		t.Errorf("RangeString = %q", clip.RangeString())
+	}
+	if clip.URL() != "https://www.youtube.com/clip/"+testClipID {
+		t.Errorf("URL = %q", clip.URL())
+	}
+}
+
+func TestClipFetcher_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<script>var ytInitialData = {"contents":{}};</script>`))
+	}))
+	defer server.Close()
+
+	fetcher := &ClipFetcher{Client: server.Client(), BaseURL: server.URL}
+	if _, err := fetcher.Fetch(context.Background(), testClipID); !errors.Is(err, ErrClipUnavailable) {
+		t.Errorf("Fetch = %v, want ErrClipUnavailable", err)
+	}
+}
@@ -0,0 +1,417 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// ErrCommentsDisabled is returned when a video has no comment section,
+// because comments are turned off or the video is unavailable.
+var ErrCommentsDisabled = errors.New("comments are disabled for this video")
+
+// errCommentLimit stops a comment stream once the limit is reached.
+var errCommentLimit = errors.New("comment limit reached")
+
+// Comment is a comment on a video.
+type Comment struct {
+	// ID is the comment identifier.
+	ID string
+
+	// Text is the comment text.
+	Text string
+
+	// Author is the display name of the commenter.
+	Author string
+
+	// AuthorChannelID is the channel ID of the commenter.
+	AuthorChannelID string
+
+	// PublishedText is when the comment was posted as YouTube displays it (e.g. "2 years ago").
+	PublishedText string
+
+	// LikeCount is the approximate number of likes. YouTube rounds large counts.
+	LikeCount int64
+
+	// ReplyCount is the number of replies to a top-level comment.
+	ReplyCount int
+
+	// ParentID is the ID of the comment this one replies to, empty for top-level comments.
+	ParentID string
+}
+
+// IsReply reports whether the comment is a reply to another comment.
+func (c *Comment) IsReply() bool {
+	return c.ParentID != ""
+}
+
+// CommentOptions controls which comments are fetched.
+type CommentOptions struct {
+	// Limit is the maximum number of comments to fetch, including replies. 0 fetches all.
+	Limit int
+
+	// Replies fetches the replies of each top-level comment after it.
+	Replies bool
+}
+
+// CommentCallback is called for each fetched comment. Returning an error stops fetching.
+type CommentCallback func(Comment) error
+
+// CommentFetcher fetches the comments of YouTube videos.
+type CommentFetcher struct {
+	// Client is the HTTP client to use for requests.
+	Client *http.Client
+
+	// BaseURL is the base URL for YouTube (used for testing).
+	// If empty, defaults to https://www.youtube.com.
+	BaseURL string
+}
+
+// Fetch streams the comments of a video to callback in the order YouTube returns them.
+// Pages are requested as they are needed, so large comment sections are never held in memory.
+// With opts.Replies each top-level comment is followed by its replies. An
+// invalid ID fails with ErrInvalidVideoID without making a request.
+func (f *CommentFetcher) Fetch(ctx context.Context, videoID VideoID, opts CommentOptions, callback CommentCallback) error {
+	if err := videoID.Validate(); err != nil {
+		return err
+	}
+	baseURL := f.BaseURL
+	if baseURL == "" {
+		baseURL = youtubeBaseURL
+	}
+
+	html, err := fetchPage(ctx, f.Client, fmt.Sprintf("%s/watch?v=%s", baseURL, videoID))
+	if err != nil {
+		return err
+	}
+
+	data, err := extractInitialData(html)
+	if err != nil {
+		return err
+	}
+
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("parsing initial data: %w", err)
+	}
+	token := findCommentsToken(root, false)
+	if token == "" {
+		return fmt.Errorf("%w: %s", ErrCommentsDisabled, videoID)
+	}
+
+	stream := &commentStream{
+		client:   f.Client,
+		baseURL:  baseURL,
+		config:   extractInnertubeConfig(html),
+		opts:     opts,
+		callback: callback,
+	}
+	if err := stream.fetch(ctx, token, ""); err != nil && !errors.Is(err, errCommentLimit) {
+		return err
+	}
+	return nil
+}
+
+// commentStream walks comment pages and delivers comments to a callback.
+type commentStream struct {
+	client   *http.Client
+	baseURL  string
+	config   innertubeConfig
+	opts     CommentOptions
+	callback CommentCallback
+	fetched  int
+}
+
+// fetch delivers the comments for token and all following pages.
+// parentID is set when fetching the replies of a comment.
+func (s *commentStream) fetch(ctx context.Context, token, parentID string) error {
+	for token != "" {
+		data, err := callInnertube(ctx, s.client, s.baseURL, s.config, "next", map[string]any{"continuation": token})
+		if err != nil {
+			return fmt.Errorf("fetching comments: %w", err)
+		}
+
+		page, err := parseCommentPage(data)
+		if err != nil {
+			return fmt.Errorf("parsing comments: %w", err)
+		}
+
+		for _, item := range page.items {
+			item.comment.ParentID = parentID
+			if err := s.deliver(item.comment); err != nil {
+				return err
+			}
+			if s.opts.Replies && parentID == "" && item.repliesToken != "" {
+				if err := s.fetch(ctx, item.repliesToken, item.comment.ID); err != nil {
+					return err
+				}
+			}
+		}
+
+		token = page.next
+	}
+	return nil
+}
+
+// deliver passes a comment to the callback and enforces the limit.
+func (s *commentStream) deliver(c Comment) error {
+	if err := s.callback(c); err != nil {
+		return err
+	}
+	s.fetched++
+	if s.opts.Limit > 0 && s.fetched >= s.opts.Limit {
+		return errCommentLimit
+	}
+	return nil
+}
+
+// findCommentsToken finds the continuation token of the comment section in the
+// watch page's ytInitialData: the one inside the item section identified as
+// "comment-item-section".
+func findCommentsToken(v any, inSection bool) string {
+	switch node := v.(type) {
+	case map[string]any:
+		if node["sectionIdentifier"] == "comment-item-section" {
+			inSection = true
+		}
+		if inSection {
+			if token := continuationToken(node); token != "" {
+				return token
+			}
+		}
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if token := findCommentsToken(node[key], inSection); token != "" {
+				return token
+			}
+		}
+	case []any:
+		for _, child := range node {
+			if token := findCommentsToken(child, inSection); token != "" {
+				return token
+			}
+		}
+	}
+	return ""
+}
+
+// continuationToken returns the token of a continuationCommand directly on node.
+func continuationToken(node map[string]any) string {
+	command, ok := node["continuationCommand"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	token, _ := command["token"].(string)
+	return token
+}
+
+// commentPage is one page of comments from the next endpoint.
+type commentPage struct {
+	items []commentItem
+	next  string
+}
+
+// commentItem is a comment with the token for its replies, if it has any.
+type commentItem struct {
+	comment      Comment
+	repliesToken string
+}
+
+// commentRenderer is the classic comment layout.
+type commentRenderer struct {
+	CommentID      string     `json:"commentId"`
+	ContentText    runText    `json:"contentText"`
+	AuthorText     simpleText `json:"authorText"`
+	AuthorEndpoint struct {
+		BrowseEndpoint struct {
+			BrowseID string `json:"browseId"`
+		} `json:"browseEndpoint"`
+	} `json:"authorEndpoint"`
+	PublishedTimeText runText    `json:"publishedTimeText"`
+	VoteCount         simpleText `json:"voteCount"`
+	ReplyCount        int        `json:"replyCount"`
+}
+
+// toComment converts the renderer to a Comment.
+func (r *commentRenderer) toComment() Comment {
+	return Comment{
+		ID:              r.CommentID,
+		Text:            r.ContentText.fullText(),
+		Author:          r.AuthorText.SimpleText,
+		AuthorChannelID: r.AuthorEndpoint.BrowseEndpoint.BrowseID,
+		PublishedText:   r.PublishedTimeText.getText(),
+		LikeCount:       parseAbbreviatedCount(r.VoteCount.SimpleText),
+		ReplyCount:      r.ReplyCount,
+	}
+}
+
+// commentViewModel is the newer comment layout; the comment itself is an
+// entity in the response's framework updates, looked up by key.
+type commentViewModel struct {
+	CommentKey string `json:"commentKey"`
+}
+
+// commentEntityPayload is the comment entity referenced by a commentViewModel.
+type commentEntityPayload struct {
+	Properties struct {
+		CommentID string `json:"commentId"`
+		Content   struct {
+			Content string `json:"content"`
+		} `json:"content"`
+		PublishedTime string `json:"publishedTime"`
+	} `json:"properties"`
+	Author struct {
+		ChannelID   string `json:"channelId"`
+		DisplayName string `json:"displayName"`
+	} `json:"author"`
+	Toolbar struct {
+		LikeCountNotliked string `json:"likeCountNotliked"`
+		ReplyCount        string `json:"replyCount"`
+	} `json:"toolbar"`
+}
+
+// toComment converts the entity to a Comment.
+func (p *commentEntityPayload) toComment() Comment {
+	replies, _ := strconv.Atoi(p.Toolbar.ReplyCount)
+	return Comment{
+		ID:              p.Properties.CommentID,
+		Text:            p.Properties.Content.Content,
+		Author:          p.Author.DisplayName,
+		AuthorChannelID: p.Author.ChannelID,
+		PublishedText:   p.Properties.PublishedTime,
+		LikeCount:       parseAbbreviatedCount(p.Toolbar.LikeCountNotliked),
+		ReplyCount:      replies,
+	}
+}
+
+// continuationItemRenderer links to the next page of comments or replies.
+type continuationItemRenderer struct {
+	ContinuationEndpoint struct {
+		ContinuationCommand struct {
+			Token string `json:"token"`
+		} `json:"continuationCommand"`
+	} `json:"continuationEndpoint"`
+	Button struct {
+		ButtonRenderer struct {
+			Command struct {
+				ContinuationCommand struct {
+					Token string `json:"token"`
+				} `json:"continuationCommand"`
+			} `json:"command"`
+		} `json:"buttonRenderer"`
+	} `json:"button"`
+}
+
+// token returns the continuation token; "show more replies" buttons carry it on the button.
+func (r *continuationItemRenderer) token() string {
+	if token := r.ContinuationEndpoint.ContinuationCommand.Token; token != "" {
+		return token
+	}
+	return r.Button.ButtonRenderer.Command.ContinuationCommand.Token
+}
+
+// commentContent is an item of a comments or replies page.
+type commentContent struct {
+	CommentThreadRenderer *struct {
+		Comment struct {
+			CommentRenderer *commentRenderer `json:"commentRenderer"`
+		} `json:"comment"`
+		CommentViewModel struct {
+			CommentViewModel *commentViewModel `json:"commentViewModel"`
+		} `json:"commentViewModel"`
+		Replies struct {
+			CommentRepliesRenderer struct {
+				Contents []commentContent `json:"contents"`
+			} `json:"commentRepliesRenderer"`
+		} `json:"replies"`
+	} `json:"commentThreadRenderer"`
+	CommentRenderer          *commentRenderer          `json:"commentRenderer"`
+	CommentViewModel         *commentViewModel         `json:"commentViewModel"`
+	ContinuationItemRenderer *continuationItemRenderer `json:"continuationItemRenderer"`
+}
+
+// parseCommentPage extracts the comments and next page token from a next endpoint response.
+func parseCommentPage(data []byte) (*commentPage, error) {
+	type itemList struct {
+		ContinuationItems []commentContent `json:"continuationItems"`
+	}
+	var resp struct {
+		OnResponseReceivedEndpoints []struct {
+			ReloadContinuationItemsCommand itemList `json:"reloadContinuationItemsCommand"`
+			AppendContinuationItemsAction  itemList `json:"appendContinuationItemsAction"`
+		} `json:"onResponseReceivedEndpoints"`
+		FrameworkUpdates struct {
+			EntityBatchUpdate struct {
+				Mutations []struct {
+					EntityKey string `json:"entityKey"`
+					Payload   struct {
+						CommentEntityPayload *commentEntityPayload `json:"commentEntityPayload"`
+					} `json:"payload"`
+				} `json:"mutations"`
+			} `json:"entityBatchUpdate"`
+		} `json:"frameworkUpdates"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	entities := make(map[string]*commentEntityPayload)
+	for _, m := range resp.FrameworkUpdates.EntityBatchUpdate.Mutations {
+		if m.Payload.CommentEntityPayload != nil {
+			entities[m.EntityKey] = m.Payload.CommentEntityPayload
+		}
+	}
+
+	// comment resolves either layout to a Comment
+	comment := func(renderer *commentRenderer, viewModel *commentViewModel) (Comment, bool) {
+		if renderer != nil {
+			return renderer.toComment(), true
+		}
+		if viewModel != nil {
+			if entity, ok := entities[viewModel.CommentKey]; ok {
+				return entity.toComment(), true
+			}
+		}
+		return Comment{}, false
+	}
+
+	page := &commentPage{}
+	for _, endpoint := range resp.OnResponseReceivedEndpoints {
+		items := endpoint.ReloadContinuationItemsCommand.ContinuationItems
+		items = append(items, endpoint.AppendContinuationItemsAction.ContinuationItems...)
+		for i := range items {
+			item := &items[i]
+			switch {
+			case item.CommentThreadRenderer != nil:
+				thread := item.CommentThreadRenderer
+				c, ok := comment(thread.Comment.CommentRenderer, thread.CommentViewModel.CommentViewModel)
+				if !ok {
+					continue
+				}
+				entry := commentItem{comment: c}
+				for _, reply := range thread.Replies.CommentRepliesRenderer.Contents {
+					if reply.ContinuationItemRenderer != nil {
+						entry.repliesToken = reply.ContinuationItemRenderer.token()
+						break
+					}
+				}
+				page.items = append(page.items, entry)
+			case item.CommentRenderer != nil || item.CommentViewModel != nil:
+				if c, ok := comment(item.CommentRenderer, item.CommentViewModel); ok {
+					page.items = append(page.items, commentItem{comment: c})
+				}
+			case item.ContinuationItemRenderer != nil:
+				page.next = item.ContinuationItemRenderer.token()
+			}
+		}
+	}
+	return page, nil
+}
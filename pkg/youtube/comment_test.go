@@ -0,0 +1,210 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// classicCommentJSON returns a commentRenderer for tests.
+func classicCommentJSON(id, text string, likes string, replies int) string {
+	return fmt.Sprintf(`{"commentId":%q,"contentText":{"runs":[{"text":%q},{"text":"!"}]},"authorText":{"simpleText":"@author-%s"},`+
+		`"authorEndpoint":{"browseEndpoint":{"browseId":"UC%s"}},"publishedTimeText":{"runs":[{"text":"2 days ago"}]},`+
+		`"voteCount":{"simpleText":%q},"replyCount":%d}`, id, text, id, id, likes, replies)
+}
+
+// commentEntityJSON returns a framework update mutation with a comment entity for tests.
+func commentEntityJSON(key, id, text string) string {
+	return fmt.Sprintf(`{"entityKey":%q,"payload":{"commentEntityPayload":{"properties":{"commentId":%q,"content":{"content":%q},"publishedTime":"1 year ago"},`+
+		`"author":{"channelId":"UC%s","displayName":"@author-%s"},"toolbar":{"likeCountNotliked":"1.2K","replyCount":"0"}}}}`, key, id, text, id, id)
+}
+
+// newCommentsTestServer serves a watch page whose comment section has two pages,
+// mixing both comment layouts, and one thread with replies.
+func newCommentsTestServer(t *testing.T, tokens *[]string) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/watch":
+			if r.URL.Query().Get("v") != "commented01" {
+				_, _ = w.Write([]byte(`<script>var ytInitialData = {"contents":{}};</script>`))
+				return
+			}
+			data := `{"contents":{"twoColumnWatchNextResults":{"results":{"results":{"contents":[` +
+				`{"itemSectionRenderer":{"contents":[` + continuationItemJSON("related") + `]}},` +
+				`{"itemSectionRenderer":{"sectionIdentifier":"comment-item-section","contents":[` + continuationItemJSON("comments") + `]}}]}}}}}`
+			_, _ = w.Write([]byte(`<script>ytcfg.set({"INNERTUBE_API_KEY":"test-key"});</script><script>var ytInitialData = ` + data + `;</script>`))
+		case "/youtubei/v1/next":
+			var body struct {
+				Continuation string `json:"continuation"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			*tokens = append(*tokens, body.Continuation)
+			mu.Unlock()
+
+			switch body.Continuation {
+			case "comments":
+				_, _ = w.Write([]byte(`{"onResponseReceivedEndpoints":[{"reloadContinuationItemsCommand":{"continuationItems":[` +
+					`{"commentsHeaderRenderer":{}},` +
+					`{"commentThreadRenderer":{"comment":{"commentRenderer":` + classicCommentJSON("c1", "First", "15", 2) + `},` +
+					`"replies":{"commentRepliesRenderer":{"contents":[` + continuationItemJSON("replies-c1") + `]}}}},` +
+					`{"commentThreadRenderer":{"commentViewModel":{"commentViewModel":{"commentKey":"key-c2"}}}},` +
+					continuationItemJSON("comments2") + `]}}],` +
+					`"frameworkUpdates":{"entityBatchUpdate":{"mutations":[` + commentEntityJSON("key-c2", "c2", "Second") + `]}}}`))
+			case "comments2":
+				_, _ = w.Write([]byte(`{"onResponseReceivedEndpoints":[{"appendContinuationItemsAction":{"continuationItems":[` +
+					`{"commentThreadRenderer":{"comment":{"commentRenderer":` + classicCommentJSON("c3", "Third", "", 0) + `}}}]}}]}`))
+			case "replies-c1":
+				_, _ = w.Write([]byte(`{"onResponseReceivedEndpoints":[{"appendContinuationItemsAction":{"continuationItems":[` +
+					`{"commentRenderer":` + classicCommentJSON("c1.r1", "Reply", "3", 0) + `},` +
+					`{"commentViewModel":{"commentKey":"key-r2"}}]}}],` +
+					`"frameworkUpdates":{"entityBatchUpdate":{"mutations":[` + commentEntityJSON("key-r2", "c1.r2", "Another reply") + `]}}}`))
+			default:
+				http.Error(w, "unknown continuation", http.StatusBadRequest)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// collectComments fetches comments and returns them in order.
+func collectComments(t *testing.T, fetcher *CommentFetcher, videoID VideoID, opts CommentOptions) ([]Comment, error) {
+	t.Helper()
+	var comments []Comment
+	err := fetcher.Fetch(context.Background(), videoID, opts, func(c Comment) error {
+		comments = append(comments, c)
+		return nil
+	})
+	return comments, err
+}
+
+func TestCommentFetcher_FetchFollowsContinuations(t *testing.T) {
+	var tokens []string
+	server := newCommentsTestServer(t, &tokens)
+	fetcher := &CommentFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	comments, err := collectComments(t, fetcher, "commented01", CommentOptions{})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	var ids []string
+	for _, c := range comments {
+		ids = append(ids, c.ID)
+	}
+	if fmt.Sprint(ids) != "[c1 c2 c3]" {
+		t.Fatalf("comments = %v, want [c1 c2 c3]", ids)
+	}
+	if fmt.Sprint(tokens) != "[comments comments2]" {
+		t.Errorf("continuations = %v, want [comments comments2]", tokens)
+	}
+
+	first := comments[0]
+	if first.Text != "First!" || first.Author != "@author-c1" || first.AuthorChannelID != "UCc1" {
+		t.Errorf("first comment = %+v", first)
+	}
+	if first.PublishedText != "2 days ago" || first.LikeCount != 15 || first.ReplyCount != 2 || first.IsReply() {
+		t.Errorf("first comment = %+v", first)
+	}
+
+	second := comments[1]
+	if second.Text != "Second" || second.Author != "@author-c2" || second.LikeCount != 1200 || second.PublishedText != "1 year ago" {
+		t.Errorf("view model comment = %+v", second)
+	}
+	if comments[2].LikeCount != 0 {
+		t.Errorf("comment without votes has %d likes", comments[2].LikeCount)
+	}
+}
+
+func TestCommentFetcher_FetchReplies(t *testing.T) {
+	var tokens []string
+	server := newCommentsTestServer(t, &tokens)
+	fetcher := &CommentFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	comments, err := collectComments(t, fetcher, "commented01", CommentOptions{Replies: true})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	var ids []string
+	for _, c := range comments {
+		ids = append(ids, c.ID)
+	}
+	if fmt.Sprint(ids) != "[c1 c1.r1 c1.r2 c2 c3]" {
+		t.Fatalf("comments = %v, want replies after their thread", ids)
+	}
+	for _, reply := range comments[1:3] {
+		if !reply.IsReply() || reply.ParentID != "c1" {
+			t.Errorf("reply %s has parent %q, want c1", reply.ID, reply.ParentID)
+		}
+	}
+	if comments[2].Text != "Another reply" {
+		t.Errorf("view model reply text = %q", comments[2].Text)
+	}
+}
+
+func TestCommentFetcher_FetchLimit(t *testing.T) {
+	var tokens []string
+	server := newCommentsTestServer(t, &tokens)
+	fetcher := &CommentFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	comments, err := collectComments(t, fetcher, "commented01", CommentOptions{Limit: 2, Replies: true})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(comments) != 2 || comments[1].ID != "c1.r1" {
+		t.Errorf("got %d comments, want c1 and its first reply", len(comments))
+	}
+	if fmt.Sprint(tokens) != "[comments replies-c1]" {
+		t.Errorf("continuations = %v, want no requests after the limit", tokens)
+	}
+}
+
+func TestCommentFetcher_CallbackErrorStops(t *testing.T) {
+	var tokens []string
+	server := newCommentsTestServer(t, &tokens)
+	fetcher := &CommentFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err := fetcher.Fetch(context.Background(), "commented01", CommentOptions{}, func(Comment) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("Fetch = %v after %d calls, want the callback error after 1", err, calls)
+	}
+}
+
+func TestCommentFetcher_CommentsDisabled(t *testing.T) {
+	var tokens []string
+	server := newCommentsTestServer(t, &tokens)
+	fetcher := &CommentFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	_, err := collectComments(t, fetcher, "nocomments0", CommentOptions{})
+	if !errors.Is(err, ErrCommentsDisabled) {
+		t.Errorf("Fetch = %v, want ErrCommentsDisabled", err)
+	}
+}
+
+func TestFindCommentsToken_IgnoresOtherSections(t *testing.T) {
+	var root any
+	data := `{"a":{"continuationCommand":{"token":"outside"}},"b":[{"sectionIdentifier":"comment-item-section","contents":[` +
+		continuationItemJSON("inside") + `]}]}`
+	if err := json.Unmarshal([]byte(data), &root); err != nil {
+		t.Fatal(err)
+	}
+	if token := findCommentsToken(root, false); token != "inside" {
+		t.Errorf("token = %q, want inside", token)
+	}
+}
@@ -0,0 +1,195 @@
+package youtube
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Feed is the RSS (Atom) feed YouTube publishes for a channel or playlist.
+// Feeds are much cheaper to fetch than pages but only list the 15 most recent
+// videos, without durations.
+type Feed struct {
+	// Title is the channel's or playlist's title.
+	Title string
+
+	// Author is the channel that owns the channel or playlist.
+	Author Author
+
+	// Entries are the feed's videos, newest first for channels.
+	Entries []FeedEntry
+}
+
+// FeedEntry is a video listed in a feed.
+type FeedEntry struct {
+	// VideoID is the video's unique identifier.
+	VideoID string
+
+	// Title is the video's title.
+	Title string
+
+	// Author is the video's uploader.
+	Author Author
+
+	// Description is the video's description.
+	Description string
+
+	// Published is when the video was published.
+	Published time.Time
+
+	// Updated is when the video's metadata last changed.
+	Updated time.Time
+
+	// ViewCount is the number of views when the feed was generated.
+	ViewCount int64
+
+	// Thumbnail is the video's thumbnail, if the feed has one.
+	Thumbnail *Thumbnail
+}
+
+// PlaylistVideos returns the feed's entries as playlist videos, numbered in feed order.
+func (f *Feed) PlaylistVideos() []PlaylistVideo {
+	videos := make([]PlaylistVideo, len(f.Entries))
+	for i := range f.Entries {
+		e := &f.Entries[i]
+		videos[i] = PlaylistVideo{ID: e.VideoID, Title: e.Title, Author: e.Author, Index: i + 1}
+		if e.Thumbnail != nil {
+			videos[i].Thumbnails = []Thumbnail{*e.Thumbnail}
+		}
+	}
+	return videos
+}
+
+// FeedFetcher fetches channel and playlist feeds.
+type FeedFetcher struct {
+	// Client is the HTTP client to use for requests.
+	Client *http.Client
+
+	// BaseURL is the base URL for YouTube (used for testing).
+	// If empty, defaults to https://www.youtube.com.
+	BaseURL string
+}
+
+// ChannelFeedURL returns the URL of a channel's feed.
+func ChannelFeedURL(channelID string) string {
+	return feedURL(youtubeBaseURL, "channel_id", channelID)
+}
+
+// PlaylistFeedURL returns the URL of a playlist's feed.
+func PlaylistFeedURL(playlistID string) string {
+	return feedURL(youtubeBaseURL, "playlist_id", playlistID)
+}
+
+func feedURL(baseURL, param, id string) string {
+	return fmt.Sprintf("%s/feeds/videos.xml?%s=%s", baseURL, param, url.QueryEscape(id))
+}
+
+// FetchChannel fetches the feed of recent uploads of the channel with the given ID.
+// Handles and custom URLs must be resolved to an ID first, with ChannelFetcher.ResolveID.
+// An invalid ID fails with ErrInvalidChannelID without making a request.
+func (f *FeedFetcher) FetchChannel(ctx context.Context, channelID ChannelID) (*Feed, error) {
+	if err := channelID.Validate(); err != nil {
+		return nil, err
+	}
+	return f.fetch(ctx, "channel_id", channelID.String())
+}
+
+// FetchPlaylist fetches the feed of the playlist with the given ID. An invalid
+// ID fails with ErrInvalidPlaylistID without making a request.
+func (f *FeedFetcher) FetchPlaylist(ctx context.Context, playlistID PlaylistID) (*Feed, error) {
+	if err := playlistID.Validate(); err != nil {
+		return nil, err
+	}
+	return f.fetch(ctx, "playlist_id", playlistID.String())
+}
+
+func (f *FeedFetcher) fetch(ctx context.Context, param, id string) (*Feed, error) {
+	baseURL := f.BaseURL
+	if baseURL == "" {
+		baseURL = youtubeBaseURL
+	}
+
+	body, err := fetchPage(ctx, f.Client, feedURL(baseURL, param, id))
+	if err != nil {
+		return nil, err
+	}
+	feed, err := parseFeed(body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing feed: %w", err)
+	}
+	return feed, nil
+}
+
+// atomFeed is the XML of a feed. Elements are matched by local name, so the yt:
+// and media: namespaces don't need spelling out.
+type atomFeed struct {
+	Title     string      `xml:"title"`
+	ChannelID string      `xml:"channelId"`
+	Author    atomAuthor  `xml:"author"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri"`
+}
+
+type atomEntry struct {
+	VideoID   string     `xml:"videoId"`
+	ChannelID string     `xml:"channelId"`
+	Title     string     `xml:"title"`
+	Author    atomAuthor `xml:"author"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Group     struct {
+		Description string `xml:"description"`
+		Thumbnail   *struct {
+			URL    string `xml:"url,attr"`
+			Width  int    `xml:"width,attr"`
+			Height int    `xml:"height,attr"`
+		} `xml:"thumbnail"`
+		Community struct {
+			Statistics struct {
+				Views int64 `xml:"views,attr"`
+			} `xml:"statistics"`
+		} `xml:"community"`
+	} `xml:"group"`
+}
+
+// parseFeed parses the XML of a channel or playlist feed.
+func parseFeed(body string) (*Feed, error) {
+	var raw atomFeed
+	if err := xml.NewDecoder(strings.NewReader(body)).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	feed := &Feed{
+		Title:  raw.Title,
+		Author: Author{Name: raw.Author.Name, ChannelID: raw.ChannelID, URL: raw.Author.URI},
+	}
+	for i := range raw.Entries {
+		e := &raw.Entries[i]
+		if e.VideoID == "" {
+			continue
+		}
+		entry := FeedEntry{
+			VideoID:     e.VideoID,
+			Title:       e.Title,
+			Author:      Author{Name: e.Author.Name, ChannelID: e.ChannelID, URL: e.Author.URI},
+			Description: e.Group.Description,
+			ViewCount:   e.Group.Community.Statistics.Views,
+		}
+		// Timestamps that don't parse are left zero
+		entry.Published, _ = time.Parse(time.RFC3339, e.Published)
+		entry.Updated, _ = time.Parse(time.RFC3339, e.Updated)
+		if t := e.Group.Thumbnail; t != nil && t.URL != "" {
+			entry.Thumbnail = &Thumbnail{URL: t.URL, Width: t.Width, Height: t.Height}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed, nil
+}
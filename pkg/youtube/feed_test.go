@@ -0,0 +1,143 @@
+package youtube
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testFeedXML = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns:media="http://search.yahoo.com/mrss/" xmlns="http://www.w3.org/2005/Atom">
+ <link rel="self" href="http://www.youtube.com/feeds/videos.xml?channel_id=UCuAXFkgsw1L7xaCfnd5JJOw"/>
+ <id>yt:channel:uAXFkgsw1L7xaCfnd5JJOw</id>
+ <yt:channelId>UCuAXFkgsw1L7xaCfnd5JJOw</yt:channelId>
+ <title>Test Channel</title>
+ <author>
+  <name>Test Channel</name>
+  <uri>https://www.youtube.com/channel/UCuAXFkgsw1L7xaCfnd5JJOw</uri>
+ </author>
+ <published>2006-09-22T21:45:24+00:00</published>
+ <entry>
+  <id>yt:video:jNQXAC9IVRw</id>
+  <yt:videoId>jNQXAC9IVRw</yt:videoId>
+  <yt:channelId>UCuAXFkgsw1L7xaCfnd5JJOw</yt:channelId>
+  <title>Newest Video</title>
+  <link rel="alternate" href="https://www.youtube.com/watch?v=jNQXAC9IVRw"/>
+  <author>
+   <name>Test Channel</name>
+   <uri>https://www.youtube.com/channel/UCuAXFkgsw1L7xaCfnd5JJOw</uri>
+  </author>
+  <published>2024-01-10T12:00:00+00:00</published>
+  <updated>2024-01-11T08:30:00+00:00</updated>
+  <media:group>
+   <media:title>Newest Video</media:title>
+   <media:content url="https://www.youtube.com/v/jNQXAC9IVRw?version=3" type="application/x-shockwave-flash" width="640" height="390"/>
+   <media:thumbnail url="https://i1.ytimg.com/vi/jNQXAC9IVRw/hqdefault.jpg" width="480" height="360"/>
+   <media:description>The description</media:description>
+   <media:community>
+    <media:starRating count="100" average="5.00" min="1" max="5"/>
+    <media:statistics views="12345"/>
+   </media:community>
+  </media:group>
+ </entry>
+ <entry>
+  <id>yt:video:dQw4w9WgXcQ</id>
+  <yt:videoId>dQw4w9WgXcQ</yt:videoId>
+  <yt:channelId>UCuAXFkgsw1L7xaCfnd5JJOw</yt:channelId>
+  <title>Older Video</title>
+  <published>2024-01-01T12:00:00+00:00</published>
+ </entry>
+</feed>`
+
+func TestFeedFetcher_FetchChannel(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RequestURI()
+		_, _ = w.Write([]byte(testFeedXML))
+	}))
+	defer server.Close()
+
+	fetcher := &FeedFetcher{Client: server.Client(), BaseURL: server.URL}
+	feed, err := fetcher.FetchChannel(context.Background(), testChannelID)
+	if err != nil {
+		t.Fatalf("FetchChannel failed: %v", err)
+	}
+
+	if requested != "/feeds/videos.xml?channel_id="+testChannelID {
+		t.Errorf("requested %q", requested)
+	}
+	if feed.Title != "Test Channel" || feed.Author.ChannelID != testChannelID {
+		t.Errorf("feed = %+v", feed)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(feed.Entries))
+	}
+
+	e := feed.Entries[0]
+	if e.VideoID != "jNQXAC9IVRw" || e.Title != "Newest Video" || e.Author.Name != "Test Channel" {
+		t.Errorf("entry = %+v", e)
+	}
+	if !e.Published.Equal(time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)) || !e.Updated.Equal(time.Date(2024, time.January, 11, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("published = %v, updated = %v", e.Published, e.Updated)
+	}
+	if e.Description != "The description" || e.ViewCount != 12345 {
+		t.Errorf("description = %q, views = %d", e.Description, e.ViewCount)
+	}
+	if e.Thumbnail == nil || e.Thumbnail.Width != 480 {
+		t.Errorf("thumbnail = %+v", e.Thumbnail)
+	}
+	if feed.Entries[1].Thumbnail != nil {
+		t.Error("expected no thumbnail for an entry without one")
+	}
+
+	videos := feed.PlaylistVideos()
+	if len(videos) != 2 || videos[1].ID != "dQw4w9WgXcQ" || videos[1].Index != 2 || len(videos[0].Thumbnails) != 1 {
+		t.Errorf("PlaylistVideos() = %+v", videos)
+	}
+}
+
+func TestFeedFetcher_FetchPlaylist(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RequestURI()
+		_, _ = w.Write([]byte(testFeedXML))
+	}))
+	defer server.Close()
+
+	fetcher := &FeedFetcher{Client: server.Client(), BaseURL: server.URL}
+	if _, err := fetcher.FetchPlaylist(context.Background(), "PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"); err != nil {
+		t.Fatalf("FetchPlaylist failed: %v", err)
+	}
+	if requested != "/feeds/videos.xml?playlist_id=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf" {
+		t.Errorf("requested %q", requested)
+	}
+}
+
+func TestFeedFetcher_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("channel_id") == "UCbadbadbadbadbadbadbadb" {
+			_, _ = w.Write([]byte("<html>not a feed"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	fetcher := &FeedFetcher{Client: server.Client(), BaseURL: server.URL}
+	for _, id := range []ChannelID{"UCmissingmissingmissing0", "UCbadbadbadbadbadbadbadb"} {
+		if _, err := fetcher.FetchChannel(context.Background(), id); err == nil {
+			t.Errorf("FetchChannel(%q) expected an error", id)
+		}
+	}
+}
+
+func TestFeedURLs(t *testing.T) {
+	if got := ChannelFeedURL(testChannelID); got != "https://www.youtube.com/feeds/videos.xml?channel_id="+testChannelID {
+		t.Errorf("ChannelFeedURL() = %q", got)
+	}
+	if got := PlaylistFeedURL("PL1"); got != "https://www.youtube.com/feeds/videos.xml?playlist_id=PL1" {
+		t.Errorf("PlaylistFeedURL() = %q", got)
+	}
+}
@@ -0,0 +1,145 @@
+package youtube
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// HeatmapMarker is a segment of the "most replayed" graph shown over a video's progress bar.
+type HeatmapMarker struct {
+	// Start is the offset of the segment from the start of the video.
+	Start time.Duration
+
+	// Duration is the length of the segment.
+	Duration time.Duration
+
+	// Intensity is how often the segment is replayed relative to the
+	// most replayed segment, from 0 to 1.
+	Intensity float64
+}
+
+// End returns the offset at which the segment ends.
+func (m HeatmapMarker) End() time.Duration {
+	return m.Start + m.Duration
+}
+
+// String returns the time range of the segment, e.g. "1:20-1:25".
+func (m HeatmapMarker) String() string {
+	return fmt.Sprintf("%s-%s", formatDuration(m.Start), formatDuration(m.End()))
+}
+
+// ExtractHeatmap returns the "most replayed" markers of the page's video in time order,
+// or nil if YouTube shows no heatmap for it (usually because the video has too few views).
+func (p *WatchPage) ExtractHeatmap() []HeatmapMarker {
+	data, err := p.ExtractInitialData()
+	if err != nil {
+		return nil
+	}
+	return parseHeatmap(data)
+}
+
+// parseHeatmap finds the heatmap markers in ytInitialData.
+//
+// Current pages store them in a macroMarkersListEntity with the
+// MARKER_TYPE_HEATMAP type; older pages used heatMarkerRenderer items in the
+// player overlay. The data is walked generically to handle both.
+func parseHeatmap(data json.RawMessage) []HeatmapMarker {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil
+	}
+
+	var markers []HeatmapMarker
+	collectHeatmap(root, &markers)
+	sort.SliceStable(markers, func(i, j int) bool { return markers[i].Start < markers[j].Start })
+	return markers
+}
+
+// collectHeatmap appends the heatmap markers found in v to markers.
+// It stops at the first heatmap so a page listing one twice isn't doubled.
+func collectHeatmap(v any, markers *[]HeatmapMarker) {
+	if len(*markers) > 0 {
+		return
+	}
+
+	switch node := v.(type) {
+	case map[string]any:
+		if entity, ok := node["macroMarkersListEntity"].(map[string]any); ok && entity["markerType"] == "MARKER_TYPE_HEATMAP" {
+			list, _ := entity["markersList"].(map[string]any)
+			items, _ := list["markers"].([]any)
+			for _, item := range items {
+				if m, ok := item.(map[string]any); ok {
+					*markers = append(*markers, HeatmapMarker{
+						Start:     millis(m["startMillis"]),
+						Duration:  millis(m["durationMillis"]),
+						Intensity: number(m["intensityScoreNormalized"]),
+					})
+				}
+			}
+			return
+		}
+
+		if heatmap, ok := node["heatmapRenderer"].(map[string]any); ok {
+			items, _ := heatmap["heatMarkers"].([]any)
+			for _, item := range items {
+				wrapper, _ := item.(map[string]any)
+				if m, ok := wrapper["heatMarkerRenderer"].(map[string]any); ok {
+					*markers = append(*markers, HeatmapMarker{
+						Start:     millis(m["timeRangeStartMillis"]),
+						Duration:  millis(m["markerDurationMillis"]),
+						Intensity: number(m["heatMarkerIntensityScoreNormalized"]),
+					})
+				}
+			}
+			return
+		}
+
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			collectHeatmap(node[key], markers)
+		}
+	case []any:
+		for _, child := range node {
+			collectHeatmap(child, markers)
+		}
+	}
+}
+
+// millis converts a millisecond count, given as a JSON number or string, to a duration.
+func millis(v any) time.Duration {
+	return time.Duration(number(v) * float64(time.Millisecond))
+}
+
+// number returns a JSON number or numeric string as a float64, or 0.
+func number(v any) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
+
+// MostReplayed returns the n most replayed markers in time order.
+// Marker intensities are relative, so the result is the same whatever the view count.
+func MostReplayed(markers []HeatmapMarker, n int) []HeatmapMarker {
+	if n <= 0 {
+		return nil
+	}
+	ranked := append([]HeatmapMarker(nil), markers...)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Intensity > ranked[j].Intensity })
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Start < ranked[j].Start })
+	return ranked
+}
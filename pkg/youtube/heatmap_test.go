@@ -0,0 +1,80 @@
+package youtube
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseHeatmap_MacroMarkers(t *testing.T) {
+	data := json.RawMessage(`{"frameworkUpdates":{"entityBatchUpdate":{"mutations":[
+		{"payload":{"macroMarkersListEntity":{"markerType":"MARKER_TYPE_CHAPTERS","markersList":{"markers":[{"startMillis":"0","durationMillis":"60000"}]}}}},
+		{"payload":{"macroMarkersListEntity":{"markerType":"MARKER_TYPE_HEATMAP","markersList":{"markers":[
+			{"startMillis":"2500","durationMillis":"2500","intensityScoreNormalized":0.4},
+			{"startMillis":"0","durationMillis":"2500","intensityScoreNormalized":1}
+		]}}}}
+	]}}}`)
+
+	markers := parseHeatmap(data)
+	if len(markers) != 2 {
+		t.Fatalf("got %d markers, want 2 (chapters must be ignored)", len(markers))
+	}
+	if markers[0].Start != 0 || markers[0].Intensity != 1 {
+		t.Errorf("markers are not in time order: %+v", markers)
+	}
+	if markers[1].Start != 2500*time.Millisecond || markers[1].Duration != 2500*time.Millisecond || markers[1].Intensity != 0.4 {
+		t.Errorf("second marker = %+v", markers[1])
+	}
+}
+
+func TestParseHeatmap_HeatMarkerRenderer(t *testing.T) {
+	data := json.RawMessage(`{"playerOverlays":{"playerOverlayRenderer":{"decoratedPlayerBarRenderer":{"heatmap":{"heatmapRenderer":{"heatMarkers":[
+		{"heatMarkerRenderer":{"timeRangeStartMillis":0,"markerDurationMillis":4000,"heatMarkerIntensityScoreNormalized":0.25}},
+		{"heatMarkerRenderer":{"timeRangeStartMillis":4000,"markerDurationMillis":4000,"heatMarkerIntensityScoreNormalized":1}}
+	]}}}}}}`)
+
+	markers := parseHeatmap(data)
+	if len(markers) != 2 {
+		t.Fatalf("got %d markers, want 2", len(markers))
+	}
+	if markers[1].Start != 4*time.Second || markers[1].End() != 8*time.Second || markers[1].Intensity != 1 {
+		t.Errorf("second marker = %+v", markers[1])
+	}
+}
+
+func TestParseHeatmap_None(t *testing.T) {
+	if markers := parseHeatmap(json.RawMessage(`{"contents":{}}`)); markers != nil {
+		t.Errorf("got %v, want nil", markers)
+	}
+}
+
+func TestWatchPage_ExtractHeatmap(t *testing.T) {
+	page := &WatchPage{HTML: `<script>var ytInitialData = {"heatmapRenderer":{"heatMarkers":[` +
+		`{"heatMarkerRenderer":{"timeRangeStartMillis":80000,"markerDurationMillis":5000,"heatMarkerIntensityScoreNormalized":1}}]}};</script>`}
+
+	markers := page.ExtractHeatmap()
+	if len(markers) != 1 || markers[0].String() != "1:20-1:25" {
+		t.Errorf("ExtractHeatmap = %v, want [1:20-1:25]", markers)
+	}
+}
+
+func TestMostReplayed(t *testing.T) {
+	second := func(s int, intensity float64) HeatmapMarker {
+		return HeatmapMarker{Start: time.Duration(s) * time.Second, Duration: time.Second, Intensity: intensity}
+	}
+	markers := []HeatmapMarker{second(0, 0.1), second(1, 0.9), second(2, 0.3), second(3, 1), second(4, 0.2)}
+
+	top := MostReplayed(markers, 2)
+	if len(top) != 2 || top[0].Start != time.Second || top[1].Start != 3*time.Second {
+		t.Errorf("MostReplayed = %v, want the 0:01 and 0:03 markers in time order", top)
+	}
+	if markers[0].Start != 0 || markers[1].Intensity != 0.9 {
+		t.Error("MostReplayed must not reorder its input")
+	}
+	if len(MostReplayed(markers, 10)) != len(markers) {
+		t.Error("MostReplayed should return every marker when n exceeds the count")
+	}
+	if MostReplayed(markers, 0) != nil {
+		t.Error("MostReplayed(0) should return nil")
+	}
+}
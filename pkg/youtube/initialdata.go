@@ -0,0 +1,263 @@
+package youtube
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WatchData is the content of a watch page's ytInitialData that isn't part of
+// the player response: everything around the player.
+type WatchData struct {
+	// RelatedVideos are the videos suggested next to the video, in order.
+	RelatedVideos []RelatedVideo
+
+	// CommentsToken is the continuation token that loads the comment section,
+	// empty when comments are turned off.
+	CommentsToken string
+
+	// Chapters are the video's chapters in order, empty if it has none.
+	Chapters []Chapter
+
+	// LikeCount is the number of likes, 0 when the count is hidden.
+	LikeCount int64
+
+	// Heatmap is the "most replayed" graph, empty if the page has none.
+	Heatmap []HeatmapMarker
+
+	// RemixSource is the original video the video remixes, nil if it isn't a remix.
+	RemixSource *RemixSource
+}
+
+// RelatedVideo is a video suggested next to the watched one.
+type RelatedVideo struct {
+	// ID is the video's unique identifier.
+	ID string
+
+	// Title is the video's title.
+	Title string
+
+	// Author is the video's uploader.
+	Author Author
+
+	// Duration is the video's length, 0 for live streams.
+	Duration time.Duration
+
+	// ViewCountText is the view count as YouTube displays it, such as "1.2M views".
+	ViewCountText string
+
+	// Thumbnails are the available thumbnail images.
+	Thumbnails []Thumbnail
+}
+
+// Chapter is a titled section of a video.
+type Chapter struct {
+	// Title is the chapter's title.
+	Title string
+
+	// Start is where the chapter starts.
+	Start time.Duration
+}
+
+// ExtractWatchData parses the page's ytInitialData into related videos, the
+// comments token, chapters, the like count, the heatmap and the remix source.
+// Parts YouTube doesn't send for the video are left empty.
+func (p *WatchPage) ExtractWatchData() (*WatchData, error) {
+	data, err := p.ExtractInitialData()
+	if err != nil {
+		return nil, err
+	}
+	return parseWatchData(data, p.VideoID)
+}
+
+// parseWatchData parses the ytInitialData of a watch page for videoID.
+func parseWatchData(data json.RawMessage, videoID string) (*WatchData, error) {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parsing initial data: %w", err)
+	}
+
+	result := &WatchData{
+		CommentsToken: findCommentsToken(root, false),
+		LikeCount:     findLikeCount(root),
+		Heatmap:       parseHeatmap(data),
+		RemixSource:   findRemixSource(root, videoID, false),
+	}
+	walkInitialData(root, func(key string, node map[string]any) {
+		switch key {
+		case "compactVideoRenderer":
+			if v := parseCompactVideo(node); v.ID != "" && v.ID != videoID {
+				result.RelatedVideos = append(result.RelatedVideos, v)
+			}
+		case "chapterRenderer":
+			result.Chapters = append(result.Chapters, Chapter{
+				Title: text(node["title"]),
+				Start: millis(node["timeRangeStartMillis"]),
+			})
+		}
+	})
+	if len(result.Chapters) == 0 {
+		result.Chapters = findDescriptionChapters(root)
+	}
+	result.Chapters = sortChapters(result.Chapters)
+	return result, nil
+}
+
+// sortChapters orders chapters by start time, dropping repeats: pages list the
+// same chapters for several player layouts.
+func sortChapters(chapters []Chapter) []Chapter {
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start })
+	result := chapters[:0]
+	for i, c := range chapters {
+		if i > 0 && c == chapters[i-1] {
+			continue
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+// walkInitialData calls visit for every renderer object in v, with the key it is stored under.
+// Keys are visited in a fixed order so repeated parses return the same result.
+func walkInitialData(v any, visit func(key string, node map[string]any)) {
+	switch node := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if child, ok := node[key].(map[string]any); ok {
+				visit(key, child)
+			}
+			walkInitialData(node[key], visit)
+		}
+	case []any:
+		for _, child := range node {
+			walkInitialData(child, visit)
+		}
+	}
+}
+
+// parseCompactVideo converts a compactVideoRenderer from the related videos.
+func parseCompactVideo(node map[string]any) RelatedVideo {
+	v := RelatedVideo{
+		Title:         text(node["title"]),
+		ViewCountText: text(node["viewCountText"]),
+		Thumbnails:    thumbnails(node["thumbnail"]),
+	}
+	v.ID, _ = node["videoId"].(string)
+	if d, err := parseTimestamp(text(node["lengthText"])); err == nil {
+		v.Duration = d
+	}
+
+	if byline, ok := node["longBylineText"].(map[string]any); ok {
+		v.Author.Name = text(byline)
+		if runs, ok := byline["runs"].([]any); ok && len(runs) > 0 {
+			run, _ := runs[0].(map[string]any)
+			endpoint, _ := run["navigationEndpoint"].(map[string]any)
+			browse, _ := endpoint["browseEndpoint"].(map[string]any)
+			v.Author.ChannelID, _ = browse["browseId"].(string)
+		}
+	}
+	if v.Author.ChannelID != "" {
+		v.Author.URL = ChannelIdentifier{Type: ChannelTypeID, Value: v.Author.ChannelID}.URL()
+	}
+	return v
+}
+
+// findDescriptionChapters returns the chapters listed in the description's
+// engagement panel, used when the player bar has none.
+func findDescriptionChapters(root any) []Chapter {
+	var chapters []Chapter
+	walkInitialData(root, func(key string, node map[string]any) {
+		if key != "engagementPanelSectionListRenderer" {
+			return
+		}
+		if id, _ := node["panelIdentifier"].(string); !strings.Contains(id, "chapters") {
+			return
+		}
+		walkInitialData(node, func(key string, item map[string]any) {
+			if key != "macroMarkersListItemRenderer" {
+				return
+			}
+			chapter := Chapter{Title: text(item["title"])}
+			onTap, _ := item["onTap"].(map[string]any)
+			if endpoint, ok := onTap["watchEndpoint"].(map[string]any); ok {
+				chapter.Start = time.Duration(number(endpoint["startTimeSeconds"])) * time.Second
+			} else if d, err := parseTimestamp(text(item["timeDescription"])); err == nil {
+				chapter.Start = d
+			}
+			chapters = append(chapters, chapter)
+		})
+	})
+	return chapters
+}
+
+// likeCountTextPattern matches the like count in accessibility labels such as
+// "like this video along with 1,234 other people" and "1,234 likes".
+var likeCountTextPattern = regexp.MustCompile(`(?i)(?:along with ([\d,]+) other|([\d,]+) likes?\b)`)
+
+// findLikeCount returns the video's like count from the like button, or 0 if hidden.
+// The exact count comes from the like entity in newer pages and from the button's
+// accessibility label, nested at different depths depending on the layout, in older ones.
+func findLikeCount(root any) int64 {
+	var count int64
+	walkInitialData(root, func(key string, node map[string]any) {
+		if count != 0 {
+			return
+		}
+		if key == "likeCountEntity" {
+			count = int64(number(node["likeCountIfIndifferentNumber"]))
+			return
+		}
+		for _, field := range []string{"accessibilityText", "label"} {
+			label, _ := node[field].(string)
+			if m := likeCountTextPattern.FindStringSubmatch(label); m != nil {
+				count = parseAbbreviatedCount(m[1] + m[2])
+				return
+			}
+		}
+	})
+	return count
+}
+
+// text returns the text of a simpleText or runs field, or "".
+func text(v any) string {
+	node, ok := v.(map[string]any)
+	if !ok {
+		return ""
+	}
+	if s, ok := node["simpleText"].(string); ok {
+		return s
+	}
+	runs, _ := node["runs"].([]any)
+	var b strings.Builder
+	for _, r := range runs {
+		if run, ok := r.(map[string]any); ok {
+			s, _ := run["text"].(string)
+			b.WriteString(s)
+		}
+	}
+	return b.String()
+}
+
+// thumbnails returns the images of a {"thumbnails": [...]} field.
+func thumbnails(v any) []Thumbnail {
+	node, _ := v.(map[string]any)
+	items, _ := node["thumbnails"].([]any)
+	var result []Thumbnail
+	for _, item := range items {
+		t, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		url, _ := t["url"].(string)
+		result = append(result, Thumbnail{URL: url, Width: int(number(t["width"])), Height: int(number(t["height"]))})
+	}
+	return result
+}
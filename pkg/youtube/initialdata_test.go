@@ -0,0 +1,161 @@
+package youtube
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+const testWatchInitialData = `{
+	"contents":{"twoColumnWatchNextResults":{
+		"results":{"results":{"contents":[
+			{"videoPrimaryInfoRenderer":{"videoActions":{"menuRenderer":{"topLevelButtons":[
+				{"segmentedLikeDislikeButtonViewModel":{"likeButtonViewModel":{"likeButtonViewModel":{"toggleButtonViewModel":{"toggleButtonViewModel":{"defaultButtonViewModel":{"buttonViewModel":{
+					"accessibilityText":"like this video along with 12,345 other people"}}}}}}}}
+			]}}}},
+			{"itemSectionRenderer":{"sectionIdentifier":"comment-item-section","contents":[
+				{"continuationItemRenderer":{"continuationEndpoint":{"continuationCommand":{"token":"comments-token"}}}}
+			]}}
+		]}},
+		"secondaryResults":{"secondaryResults":{"results":[
+			{"compactVideoRenderer":{
+				"videoId":"related0001",
+				"title":{"simpleText":"First related"},
+				"longBylineText":{"runs":[{"text":"Some Channel","navigationEndpoint":{"browseEndpoint":{"browseId":"UCrelated"}}}]},
+				"lengthText":{"simpleText":"1:02:03"},
+				"viewCountText":{"simpleText":"1.2M views"},
+				"thumbnail":{"thumbnails":[{"url":"https://i.ytimg.com/vi/related0001/hq.jpg","width":168,"height":94}]}
+			}},
+			{"compactVideoRenderer":{"videoId":"related0002","title":{"runs":[{"text":"Second "},{"text":"related"}]}}}
+		]}}
+	}},
+	"playerOverlays":{"playerOverlayRenderer":{"decoratedPlayerBarRenderer":{"decoratedPlayerBarRenderer":{"playerBar":{"multiMarkersPlayerBarRenderer":{"markersMap":[{"value":{"chapters":[
+		{"chapterRenderer":{"title":{"simpleText":"Outro"},"timeRangeStartMillis":90000}},
+		{"chapterRenderer":{"title":{"simpleText":"Intro"},"timeRangeStartMillis":0}}
+	]}}]}}}}}}
+}`
+
+func TestParseWatchData(t *testing.T) {
+	data, err := parseWatchData(json.RawMessage(testWatchInitialData), "watched0001")
+	if err != nil {
+		t.Fatalf("parseWatchData: %v", err)
+	}
+
+	if len(data.RelatedVideos) != 2 {
+		t.Fatalf("got %d related videos, want 2", len(data.RelatedVideos))
+	}
+	first := data.RelatedVideos[0]
+	if first.ID != "related0001" || first.Title != "First related" {
+		t.Errorf("first related video = %+v", first)
+	}
+	if first.Author.Name != "Some Channel" || first.Author.ChannelID != "UCrelated" {
+		t.Errorf("first related author = %+v", first.Author)
+	}
+	if first.Duration != time.Hour+2*time.Minute+3*time.Second {
+		t.Errorf("first related duration = %v", first.Duration)
+	}
+	if first.ViewCountText != "1.2M views" || len(first.Thumbnails) != 1 || first.Thumbnails[0].Width != 168 {
+		t.Errorf("first related details = %+v", first)
+	}
+	if got := data.RelatedVideos[1].Title; got != "Second related" {
+		t.Errorf("second related title = %q, want joined runs", got)
+	}
+
+	if data.CommentsToken != "comments-token" {
+		t.Errorf("CommentsToken = %q", data.CommentsToken)
+	}
+	if data.LikeCount != 12345 {
+		t.Errorf("LikeCount = %d, want 12345", data.LikeCount)
+	}
+
+	want := []Chapter{{Title: "Intro"}, {Title: "Outro", Start: 90 * time.Second}}
+	if len(data.Chapters) != len(want) {
+		t.Fatalf("Chapters = %+v, want %+v", data.Chapters, want)
+	}
+	for i := range want {
+		if data.Chapters[i] != want[i] {
+			t.Errorf("Chapters[%d] = %+v, want %+v", i, data.Chapters[i], want[i])
+		}
+	}
+}
+
+func TestParseWatchData_EngagementPanelChapters(t *testing.T) {
+	data := json.RawMessage(`{"engagementPanels":[
+		{"engagementPanelSectionListRenderer":{"panelIdentifier":"engagement-panel-macro-markers-description-chapters","content":{"macroMarkersListRenderer":{"contents":[
+			{"macroMarkersListItemRenderer":{"title":{"simpleText":"Start"},"timeDescription":{"simpleText":"0:00"},"onTap":{"watchEndpoint":{"startTimeSeconds":0}}}},
+			{"macroMarkersListItemRenderer":{"title":{"simpleText":"Middle"},"timeDescription":{"simpleText":"2:30"}}}
+		]}}}},
+		{"engagementPanelSectionListRenderer":{"panelIdentifier":"engagement-panel-macro-markers-auto-chapters","content":{"macroMarkersListRenderer":{"contents":[
+			{"macroMarkersListItemRenderer":{"title":{"simpleText":"Start"},"onTap":{"watchEndpoint":{"startTimeSeconds":0}}}}
+		]}}}},
+		{"engagementPanelSectionListRenderer":{"panelIdentifier":"engagement-panel-searchable-transcript","content":{
+			"macroMarkersListItemRenderer":{"title":{"simpleText":"Not a chapter"}}
+		}}}
+	]}`)
+
+	result, err := parseWatchData(data, "watched0001")
+	if err != nil {
+		t.Fatalf("parseWatchData: %v", err)
+	}
+	want := []Chapter{{Title: "Start"}, {Title: "Middle", Start: 150 * time.Second}}
+	if len(result.Chapters) != len(want) {
+		t.Fatalf("Chapters = %+v, want %+v", result.Chapters, want)
+	}
+	for i := range want {
+		if result.Chapters[i] != want[i] {
+			t.Errorf("Chapters[%d] = %+v, want %+v", i, result.Chapters[i], want[i])
+		}
+	}
+}
+
+func TestFindLikeCount(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want int64
+	}{
+		{"entity", `{"mutations":[{"payload":{"likeCountEntity":{"likeCountIfIndifferentNumber":"4821"}}}]}`, 4821},
+		{"label", `{"toggleButtonRenderer":{"accessibilityData":{"accessibilityData":{"label":"1,024 likes"}}}}`, 1024},
+		{"hidden", `{"toggleButtonRenderer":{"accessibilityData":{"accessibilityData":{"label":"I like this"}}}}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var root any
+			if err := json.Unmarshal([]byte(tt.data), &root); err != nil {
+				t.Fatal(err)
+			}
+			if got := findLikeCount(root); got != tt.want {
+				t.Errorf("findLikeCount = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWatchData_SkipsWatchedVideo(t *testing.T) {
+	data := json.RawMessage(`{"results":[{"compactVideoRenderer":{"videoId":"watched0001"}},{"compactVideoRenderer":{"videoId":"other000001"}}]}`)
+
+	result, err := parseWatchData(data, "watched0001")
+	if err != nil {
+		t.Fatalf("parseWatchData: %v", err)
+	}
+	if len(result.RelatedVideos) != 1 || result.RelatedVideos[0].ID != "other000001" {
+		t.Errorf("RelatedVideos = %+v, want only other000001", result.RelatedVideos)
+	}
+}
+
+func TestWatchPage_ExtractWatchData(t *testing.T) {
+	page := &WatchPage{VideoID: "watched0001", HTML: `<script>var ytInitialData = ` + testWatchInitialData + `;</script>`}
+
+	data, err := page.ExtractWatchData()
+	if err != nil {
+		t.Fatalf("ExtractWatchData: %v", err)
+	}
+	if len(data.RelatedVideos) != 2 || data.CommentsToken != "comments-token" {
+		t.Errorf("ExtractWatchData = %+v", data)
+	}
+
+	if _, err := (&WatchPage{HTML: "<html></html>"}).ExtractWatchData(); !errors.Is(err, ErrInitialDataNotFound) {
+		t.Errorf("error = %v, want ErrInitialDataNotFound", err)
+	}
+}
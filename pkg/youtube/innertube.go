@@ -0,0 +1,178 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// defaultWebClientVersion is the WEB client version sent to InnerTube when the
+// page doesn't declare one.
+const defaultWebClientVersion = "2.20240726.00.00"
+
+// visitorIDHeader carries the visitor data of a session in YouTube requests.
+const visitorIDHeader = "X-Goog-Visitor-Id"
+
+var (
+	innertubeAPIKeyPattern        = regexp.MustCompile(`"INNERTUBE_API_KEY"\s*:\s*"([^"]+)"`)
+	innertubeClientVersionPattern = regexp.MustCompile(`"INNERTUBE_CLIENT_VERSION"\s*:\s*"([^"]+)"`)
+	innertubeRegionPattern        = regexp.MustCompile(`"INNERTUBE_CONTEXT_GL"\s*:\s*"([A-Z]{2})"`)
+	visitorDataPattern            = regexp.MustCompile(`"(?:VISITOR_DATA|visitorData)"\s*:\s*("(?:[^"\\]|\\.)*")`)
+)
+
+// innertubeConfig is the client configuration YouTube embeds in its pages (ytcfg),
+// needed to call the InnerTube API the pages themselves use.
+type innertubeConfig struct {
+	APIKey        string
+	ClientVersion string
+
+	// Region is the country YouTube served the page for. Sending it back keeps
+	// API responses consistent with the page, including under geo-bypass.
+	Region string
+
+	// VisitorData identifies the logged-out session YouTube served the page for.
+	// Sending it back ties API calls to that session, which PO tokens are bound to.
+	VisitorData string
+}
+
+// extractInnertubeConfig reads the InnerTube configuration from page HTML,
+// falling back to defaults for missing values.
+func extractInnertubeConfig(html string) innertubeConfig {
+	cfg := innertubeConfig{ClientVersion: defaultWebClientVersion}
+	if m := innertubeAPIKeyPattern.FindStringSubmatch(html); m != nil {
+		cfg.APIKey = m[1]
+	}
+	if m := innertubeClientVersionPattern.FindStringSubmatch(html); m != nil {
+		cfg.ClientVersion = m[1]
+	}
+	if m := innertubeRegionPattern.FindStringSubmatch(html); m != nil {
+		cfg.Region = m[1]
+	}
+	if m := visitorDataPattern.FindStringSubmatch(html); m != nil {
+		// The value is a JSON string; ids ending in "=" are often escaped as \u003d
+		_ = json.Unmarshal([]byte(m[1]), &cfg.VisitorData)
+	}
+	return cfg
+}
+
+// browse calls the InnerTube browse endpoint, used for playlist and channel pages.
+func browse(ctx context.Context, client *http.Client, baseURL string, cfg innertubeConfig, fields map[string]any) ([]byte, error) {
+	return callInnertube(ctx, client, baseURL, cfg, "browse", fields)
+}
+
+// callInnertube calls an InnerTube API endpoint with the given request fields and
+// returns the raw JSON response. The client context is added to the request.
+func callInnertube(ctx context.Context, client *http.Client, baseURL string, cfg innertubeConfig, endpoint string, fields map[string]any) ([]byte, error) {
+	clientContext := map[string]any{
+		"clientName":    "WEB",
+		"clientVersion": cfg.ClientVersion,
+		"hl":            "en",
+	}
+	if cfg.Region != "" {
+		clientContext["gl"] = cfg.Region
+	}
+	if cfg.VisitorData != "" {
+		clientContext["visitorData"] = cfg.VisitorData
+	}
+	payload := map[string]any{
+		"context": map[string]any{"client": clientContext},
+	}
+	for key, value := range fields {
+		payload[key] = value
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s request: %w", endpoint, err)
+	}
+
+	apiURL := baseURL + "/youtubei/v1/" + endpoint + "?prettyPrint=false"
+	if cfg.APIKey != "" {
+		apiURL += "&key=" + url.QueryEscape(cfg.APIKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.VisitorData != "" {
+		req.Header.Set(visitorIDHeader, cfg.VisitorData)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s data: %w", endpoint, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &RateLimitError{Message: "YouTube returned 429 Too Many Requests"}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	return data, nil
+}
+
+// fetchPage performs a GET request for a YouTube page and returns its HTML.
+// A 429 response is returned as *RateLimitError.
+func fetchPage(ctx context.Context, client *http.Client, pageURL string) (string, error) {
+	return fetchPageWithHeader(ctx, client, pageURL, nil)
+}
+
+// fetchPageWithHeader is fetchPage with extra request headers.
+func fetchPageWithHeader(ctx context.Context, client *http.Client, pageURL string, header http.Header) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, http.NoBody)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching page: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", &RateLimitError{Message: "YouTube returned 429 Too Many Requests"}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading response body: %w", err)
+	}
+	return string(body), nil
+}
+
+// setCookies adds cookies for baseURL to the client's cookie jar, if it has one.
+func setCookies(client *http.Client, baseURL string, cookies []*http.Cookie) {
+	if len(cookies) == 0 || client.Jar == nil {
+		return
+	}
+	if parsedURL, err := url.Parse(baseURL); err == nil {
+		client.Jar.SetCookies(parsedURL, cookies)
+	}
+}
@@ -0,0 +1,253 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultMixLimit is the number of videos fetched from a mix when no limit is given.
+// Mixes are generated on the fly and keep growing, so they are never fetched in full.
+const DefaultMixLimit = 25
+
+// ErrMixUnavailable is returned when a watch page has no mix panel,
+// for example because the mix ID is unknown or needs a signed-in account.
+var ErrMixUnavailable = errors.New("mix is unavailable")
+
+// mixSeedPrefixes are the mix ID prefixes that are followed by the seed video's ID,
+// longest first so "RDAMVM" is not mistaken for "RD".
+var mixSeedPrefixes = []string{"RDAMVM", "RDEM", "RD"}
+
+// IsMixPlaylistID reports whether the playlist ID names a mix (an auto-generated
+// radio playlist). Mix IDs start with "RD" and can't be browsed like other playlists.
+func IsMixPlaylistID(playlistID PlaylistID) bool {
+	return strings.HasPrefix(string(playlistID), "RD")
+}
+
+// MixSeedVideoID returns the video a mix was generated from, for mix IDs that embed it
+// (such as "RD" followed by the video ID), or "" if the ID doesn't name one.
+func MixSeedVideoID(mixID PlaylistID) VideoID {
+	for _, prefix := range mixSeedPrefixes {
+		if seed, ok := strings.CutPrefix(string(mixID), prefix); ok && IsValidVideoID(seed) {
+			return VideoID(seed)
+		}
+	}
+	return ""
+}
+
+// MixOptions controls how much of a mix is fetched.
+type MixOptions struct {
+	// VideoID is the video to open the mix with. If empty, the seed video
+	// embedded in the mix ID is used.
+	VideoID VideoID
+
+	// Limit is the maximum number of videos to fetch. Zero means DefaultMixLimit.
+	Limit int
+}
+
+// MixFetcher enumerates the videos of YouTube mixes.
+//
+// Mixes are only shown in the playlist panel of the watch page, so the fetcher
+// opens the watch page and then asks the "next" endpoint for the panel after the
+// last video it has seen until enough videos have been collected.
+type MixFetcher struct {
+	// Client is the HTTP client to use for requests.
+	Client *http.Client
+
+	// BaseURL is the base URL for YouTube (used for testing).
+	// If empty, defaults to https://www.youtube.com.
+	BaseURL string
+
+	// Cookies are the HTTP cookies to include with requests,
+	// needed for personal mixes such as "My Mix".
+	Cookies []*http.Cookie
+}
+
+// Fetch retrieves the mix's title and up to opts.Limit of its videos, numbered
+// from 1. Invalid mix or video IDs fail without making a request.
+func (f *MixFetcher) Fetch(ctx context.Context, id PlaylistID, opts MixOptions) (*Playlist, []PlaylistVideo, error) {
+	if err := id.Validate(); err != nil {
+		return nil, nil, err
+	}
+	mixID := id.String()
+
+	baseURL := f.BaseURL
+	if baseURL == "" {
+		baseURL = youtubeBaseURL
+	}
+	setCookies(f.Client, baseURL, f.Cookies)
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultMixLimit
+	}
+	videoID := opts.VideoID
+	if videoID == "" {
+		videoID = MixSeedVideoID(id)
+	}
+	if videoID == "" {
+		return nil, nil, fmt.Errorf("%w: %s needs a video to start from", ErrMixUnavailable, mixID)
+	}
+	if err := videoID.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	html, err := fetchPage(ctx, f.Client, fmt.Sprintf("%s/watch?v=%s&list=%s", baseURL, url.QueryEscape(videoID.String()), url.QueryEscape(mixID)))
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := extractInitialData(html)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	panel, err := parseMixPanel(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing mix: %w", err)
+	}
+	if len(panel.entries) == 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrMixUnavailable, mixID)
+	}
+
+	playlist := &Playlist{ID: mixID, Title: panel.title}
+	cfg := extractInnertubeConfig(html)
+
+	var videos []PlaylistVideo
+	seen := make(map[string]bool)
+	for {
+		added := 0
+		for _, entry := range panel.entries {
+			if len(videos) == limit {
+				break
+			}
+			if seen[entry.video.ID] {
+				continue
+			}
+			seen[entry.video.ID] = true
+			entry.video.Index = len(videos) + 1
+			videos = append(videos, entry.video)
+			added++
+		}
+		if len(videos) == limit || added == 0 {
+			break
+		}
+
+		last := panel.entries[len(panel.entries)-1]
+		next, err := callInnertube(ctx, f.Client, baseURL, cfg, "next", map[string]any{
+			"videoId":       last.video.ID,
+			"playlistId":    mixID,
+			"playlistIndex": last.index,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if panel, err = parseMixPanel(next); err != nil {
+			return nil, nil, fmt.Errorf("parsing mix: %w", err)
+		}
+	}
+
+	playlist.VideoCount = len(videos)
+	return playlist, videos, nil
+}
+
+// mixPanel is the playlist panel of a watch page playing a mix.
+type mixPanel struct {
+	title   string
+	entries []mixEntry
+}
+
+// mixEntry is a video in the mix panel with its position in the mix.
+type mixEntry struct {
+	video PlaylistVideo
+
+	// index is the 0-based position YouTube uses to request the panel around the video.
+	index int
+}
+
+// playlistPanelVideoRenderer is a video in the watch page's playlist panel.
+type playlistPanelVideoRenderer struct {
+	VideoID string `json:"videoId"`
+	Title   struct {
+		simpleText
+		runText
+	} `json:"title"`
+	LengthText         simpleText          `json:"lengthText"`
+	ShortBylineText    runTextWithEndpoint `json:"shortBylineText"`
+	Thumbnail          thumbnailList       `json:"thumbnail"`
+	NavigationEndpoint struct {
+		WatchEndpoint struct {
+			Index int `json:"index"`
+		} `json:"watchEndpoint"`
+	} `json:"navigationEndpoint"`
+}
+
+// parseMixPanel reads the playlist panel from watch page data or a "next" response,
+// which share the same layout.
+func parseMixPanel(data []byte) (*mixPanel, error) {
+	var page struct {
+		Contents struct {
+			TwoColumnWatchNextResults struct {
+				Playlist struct {
+					Playlist struct {
+						Title    string `json:"title"`
+						Contents []struct {
+							PlaylistPanelVideoRenderer *playlistPanelVideoRenderer `json:"playlistPanelVideoRenderer"`
+						} `json:"contents"`
+					} `json:"playlist"`
+				} `json:"playlist"`
+			} `json:"twoColumnWatchNextResults"`
+		} `json:"contents"`
+	}
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, err
+	}
+
+	list := page.Contents.TwoColumnWatchNextResults.Playlist.Playlist
+	panel := &mixPanel{title: list.Title}
+	for _, content := range list.Contents {
+		r := content.PlaylistPanelVideoRenderer
+		if r == nil || r.VideoID == "" {
+			continue
+		}
+		panel.entries = append(panel.entries, mixEntry{video: r.toPlaylistVideo(), index: r.NavigationEndpoint.WatchEndpoint.Index})
+	}
+	return panel, nil
+}
+
+// toPlaylistVideo converts a playlistPanelVideoRenderer to PlaylistVideo without an index.
+func (r *playlistPanelVideoRenderer) toPlaylistVideo() PlaylistVideo {
+	title := r.Title.SimpleText
+	if title == "" {
+		title = r.Title.fullText()
+	}
+
+	var duration int
+	if length, err := parseTimestamp(r.LengthText.SimpleText); err == nil {
+		duration = int(length.Seconds())
+	}
+
+	var author Author
+	if len(r.ShortBylineText.Runs) > 0 {
+		author = Author{
+			Name:      r.ShortBylineText.Runs[0].Text,
+			ChannelID: r.ShortBylineText.Runs[0].NavigationEndpoint.BrowseEndpoint.BrowseID,
+		}
+	}
+
+	thumbnails := make([]Thumbnail, len(r.Thumbnail.Thumbnails))
+	for i, t := range r.Thumbnail.Thumbnails {
+		thumbnails[i] = Thumbnail(t)
+	}
+
+	return PlaylistVideo{
+		ID:              r.VideoID,
+		Title:           title,
+		Author:          author,
+		DurationSeconds: duration,
+		Thumbnails:      thumbnails,
+	}
+}
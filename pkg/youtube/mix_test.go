@@ -0,0 +1,200 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mixVideoID returns the ID of the i-th video of the test mix.
+func mixVideoID(i int) string {
+	return fmt.Sprintf("mixvideo%03d", i)
+}
+
+// mixPanelJSON returns watch page data whose playlist panel holds videos from..to of the test mix.
+func mixPanelJSON(from, to int) string {
+	var items []string
+	for i := from; i <= to; i++ {
+		items = append(items, fmt.Sprintf(`{"playlistPanelVideoRenderer":{"videoId":%q,"title":{"simpleText":"Song %d"},`+
+			`"lengthText":{"simpleText":"3:%02d"},"shortBylineText":{"runs":[{"text":"Artist %d","navigationEndpoint":{"browseEndpoint":{"browseId":"UCartist%d"}}}]},`+
+			`"navigationEndpoint":{"watchEndpoint":{"videoId":%q,"playlistId":"RDmixvideo000","index":%d}}}}`,
+			mixVideoID(i), i, i, i, i, mixVideoID(i), i))
+	}
+	return `{"contents":{"twoColumnWatchNextResults":{"playlist":{"playlist":{"title":"Mix - Song 0","playlistId":"RDmixvideo000",` +
+		`"contents":[` + strings.Join(items, ",") + `]}}}}}`
+}
+
+// newMixTestServer serves a mix whose watch page shows videos 0-4 and whose
+// "next" responses show the five videos around the requested index, overlapping
+// the previous panel like YouTube does. The mix ends after video 11.
+func newMixTestServer(t *testing.T, requests *[]map[string]any) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/watch":
+			if r.URL.Query().Get("list") != "RDmixvideo000" {
+				_, _ = w.Write([]byte(`<script>var ytInitialData = {"contents":{}};</script>`))
+				return
+			}
+			_, _ = w.Write([]byte(`<script>ytcfg.set({"INNERTUBE_API_KEY":"test-key"});</script><script>var ytInitialData = ` + mixPanelJSON(0, 4) + `;</script>`))
+		case "/youtubei/v1/next":
+			var body map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decoding request: %v", err)
+			}
+			mu.Lock()
+			*requests = append(*requests, body)
+			mu.Unlock()
+
+			index, _ := body["playlistIndex"].(float64)
+			from := int(index) - 2
+			_, _ = w.Write([]byte(mixPanelJSON(from, min(from+5, 11))))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestIsMixPlaylistID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"RDdQw4w9WgXcQ", true},
+		{"RDMM", true},
+		{"RDCLAK5uy_kmPRjHDECIcuVwnKsx2Ng7fyNgFKWNJFs", true},
+		{"PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf", false},
+		{"UUuAXFkgsw1L7xaCfnd5JJOw", false},
+	}
+	for _, tt := range tests {
+		if got := IsMixPlaylistID(PlaylistID(tt.id)); got != tt.want {
+			t.Errorf("IsMixPlaylistID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestMixSeedVideoID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"RDdQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"RDAMVMdQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"RDEMdQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"RDMM", ""},
+		{"RDCLAK5uy_kmPRjHDECIcuVwnKsx2Ng7fyNgFKWNJFs", ""},
+	}
+	for _, tt := range tests {
+		if got := MixSeedVideoID(PlaylistID(tt.id)); got != VideoID(tt.want) {
+			t.Errorf("MixSeedVideoID(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestMixFetcher_FetchFollowsNextPanels(t *testing.T) {
+	var requests []map[string]any
+	server := newMixTestServer(t, &requests)
+
+	fetcher := &MixFetcher{Client: server.Client(), BaseURL: server.URL}
+	playlist, videos, err := fetcher.Fetch(context.Background(), "RDmixvideo000", MixOptions{Limit: 9})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if playlist.ID != "RDmixvideo000" || playlist.Title != "Mix - Song 0" || playlist.VideoCount != 9 {
+		t.Errorf("unexpected playlist %+v", playlist)
+	}
+	if len(videos) != 9 {
+		t.Fatalf("expected 9 videos, got %d", len(videos))
+	}
+	for i, v := range videos {
+		if v.ID != mixVideoID(i) || v.Index != i+1 {
+			t.Errorf("video %d = %s (index %d), want %s (index %d)", i, v.ID, v.Index, mixVideoID(i), i+1)
+		}
+	}
+
+	v := videos[3]
+	if v.Title != "Song 3" || v.DurationSeconds != 183 || v.Author.Name != "Artist 3" || v.Author.ChannelID != "UCartist3" {
+		t.Errorf("unexpected video %+v", v)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 next requests, got %d", len(requests))
+	}
+	if requests[0]["videoId"] != mixVideoID(4) || requests[0]["playlistId"] != "RDmixvideo000" {
+		t.Errorf("unexpected next request %v", requests[0])
+	}
+}
+
+func TestMixFetcher_FetchStopsAtEndOfMix(t *testing.T) {
+	var requests []map[string]any
+	server := newMixTestServer(t, &requests)
+
+	fetcher := &MixFetcher{Client: server.Client(), BaseURL: server.URL}
+	_, videos, err := fetcher.Fetch(context.Background(), "RDmixvideo000", MixOptions{Limit: 100})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(videos) != 12 {
+		t.Errorf("expected all 12 videos, got %d", len(videos))
+	}
+}
+
+func TestMixFetcher_FetchLimitWithinWatchPage(t *testing.T) {
+	var requests []map[string]any
+	server := newMixTestServer(t, &requests)
+
+	fetcher := &MixFetcher{Client: server.Client(), BaseURL: server.URL}
+	_, videos, err := fetcher.Fetch(context.Background(), "RDmixvideo000", MixOptions{Limit: 3})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(videos) != 3 || len(requests) != 0 {
+		t.Errorf("expected 3 videos from the watch page alone, got %d videos and %d requests", len(videos), len(requests))
+	}
+}
+
+func TestMixFetcher_FetchUnavailable(t *testing.T) {
+	var requests []map[string]any
+	server := newMixTestServer(t, &requests)
+
+	fetcher := &MixFetcher{Client: server.Client(), BaseURL: server.URL}
+	_, _, err := fetcher.Fetch(context.Background(), "RDunknownvid", MixOptions{VideoID: "dQw4w9WgXcQ"})
+	if !errors.Is(err, ErrMixUnavailable) {
+		t.Errorf("expected ErrMixUnavailable, got %v", err)
+	}
+}
+
+func TestMixFetcher_FetchNeedsSeed(t *testing.T) {
+	fetcher := &MixFetcher{Client: http.DefaultClient, BaseURL: "http://127.0.0.1:0"}
+	_, _, err := fetcher.Fetch(context.Background(), "RDMM", MixOptions{})
+	if !errors.Is(err, ErrMixUnavailable) {
+		t.Errorf("expected ErrMixUnavailable, got %v", err)
+	}
+}
+
+func TestParseMixPanel_RunsTitle(t *testing.T) {
+	data := `{"contents":{"twoColumnWatchNextResults":{"playlist":{"playlist":{"title":"Mix","contents":[` +
+		`{"playlistPanelVideoRenderer":{"videoId":"dQw4w9WgXcQ","title":{"runs":[{"text":"Never "},{"text":"Gonna"}]},` +
+		`"navigationEndpoint":{"watchEndpoint":{"index":7}}}},{"automixPreviewVideoRenderer":{}}]}}}}}`
+
+	panel, err := parseMixPanel([]byte(data))
+	if err != nil {
+		t.Fatalf("parseMixPanel failed: %v", err)
+	}
+	if len(panel.entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(panel.entries))
+	}
+	if e := panel.entries[0]; e.video.Title != "Never Gonna" || e.index != 7 {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
@@ -0,0 +1,244 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrPlayerURLNotFound is returned when a watch page doesn't reference the player JavaScript.
+	ErrPlayerURLNotFound = errors.New("player URL not found in page")
+
+	// ErrSignatureFunctionNotFound is returned when the signature transforms can't be
+	// found in the player JavaScript, usually because YouTube changed the player.
+	ErrSignatureFunctionNotFound = errors.New("signature function not found in player")
+)
+
+var (
+	// playerURLRegex matches the player JavaScript URL in the watch page's config,
+	// where slashes may be escaped.
+	playerURLRegex = regexp.MustCompile(`"(?:jsUrl|PLAYER_JS_URL)"\s*:\s*"((?:\\?/)s\\?/player\\?/[^"]+\.js)"`)
+
+	// playerVersionRegex matches the version segment of a player URL.
+	playerVersionRegex = regexp.MustCompile(`/s/player/([\w-]+)/`)
+
+	// signatureFunctionRegex matches the function that deciphers signatures: it splits
+	// the signature into characters, transforms them with helper object methods and
+	// joins them again.
+	signatureFunctionRegex = regexp.MustCompile(`function\(([\w$]+)\)\{[\w$]+=[\w$]+\.split\(""\);([^}]*?)return [\w$]+\.join\(""\)\}`)
+
+	// signatureCallRegex matches one transform call in the signature function,
+	// as obj.method(a,3) or obj["method"](a,3).
+	signatureCallRegex = regexp.MustCompile(`([\w$]+)(?:\.([\w$]+)|\["([\w$]+)"\])\([\w$]+,(\d+)\)`)
+
+	// helperMethodRegex matches one method of the transform helper object.
+	helperMethodRegex = regexp.MustCompile(`"?([\w$]+)"?:function\([\w$,]*\)\{([^}]*)\}`)
+)
+
+// Signature transform operations found in player JavaScript.
+const (
+	// CipherReverse reverses the signature.
+	CipherReverse = "reverse"
+
+	// CipherSplice removes the first Arg characters.
+	CipherSplice = "splice"
+
+	// CipherSwap swaps the first character with the one at Arg modulo the length.
+	CipherSwap = "swap"
+)
+
+// CipherOperation is one step of a signature transform.
+type CipherOperation struct {
+	Op  string `json:"op"`
+	Arg int    `json:"arg,omitempty"`
+}
+
+// Player holds what was extracted from a version of YouTube's player JavaScript
+// (base.js) to turn protected formats into playable URLs. Only signature
+// ciphers are handled; the n parameter transform needs a JavaScript interpreter,
+// so streams with a throttling n parameter may download slowly.
+type Player struct {
+	// Version is the player version from its URL, such as "3d3ba064".
+	Version string `json:"version"`
+
+	// SignatureOperations transform an encrypted signature, in order.
+	SignatureOperations []CipherOperation `json:"signature_operations"`
+}
+
+// DecipherSignature applies the player's transforms to an encrypted signature.
+func (p *Player) DecipherSignature(signature string) string {
+	s := []byte(signature)
+	for _, op := range p.SignatureOperations {
+		switch op.Op {
+		case CipherReverse:
+			for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+				s[i], s[j] = s[j], s[i]
+			}
+		case CipherSplice:
+			if op.Arg < len(s) {
+				s = s[op.Arg:]
+			} else {
+				s = s[:0]
+			}
+		case CipherSwap:
+			if len(s) > 0 {
+				j := op.Arg % len(s)
+				s[0], s[j] = s[j], s[0]
+			}
+		}
+	}
+	return string(s)
+}
+
+// ExtractPlayerURL returns the path of the player JavaScript the watch page uses,
+// such as /s/player/3d3ba064/player_ias.vflset/en_US/base.js.
+func (p *WatchPage) ExtractPlayerURL() (string, error) {
+	match := playerURLRegex.FindStringSubmatch(p.HTML)
+	if match == nil {
+		return "", ErrPlayerURLNotFound
+	}
+	return strings.ReplaceAll(match[1], `\/`, "/"), nil
+}
+
+// PlayerVersion returns the version segment of a player URL, or "" if it has none.
+func PlayerVersion(playerURL string) string {
+	if match := playerVersionRegex.FindStringSubmatch(playerURL); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// NeedsDecipher reports whether any format needs its signature deciphered.
+func (sd *StreamingDataResponse) NeedsDecipher() bool {
+	for _, formats := range [][]FormatResponse{sd.Formats, sd.AdaptiveFormats} {
+		for i := range formats {
+			if formats[i].NeedsCipherDecryption() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Decipher fills in the URLs of formats with signature ciphers using the player.
+func (sd *StreamingDataResponse) Decipher(player *Player) error {
+	for _, formats := range [][]FormatResponse{sd.Formats, sd.AdaptiveFormats} {
+		for i := range formats {
+			f := &formats[i]
+			if !f.NeedsCipherDecryption() {
+				continue
+			}
+			cipher, err := ParseSignatureCipher(f.SignatureCipher)
+			if err != nil {
+				return fmt.Errorf("format %d: %w", f.Itag, err)
+			}
+			cipher.Signature = url.QueryEscape(player.DecipherSignature(cipher.Signature))
+			f.URL = cipher.BuildURL()
+		}
+	}
+	return nil
+}
+
+// PlayerFetcher fetches and parses YouTube's player JavaScript.
+type PlayerFetcher struct {
+	// Client is the HTTP client to use for requests.
+	Client *http.Client
+
+	// BaseURL is the base URL for YouTube (used for testing).
+	// If empty, defaults to https://www.youtube.com.
+	BaseURL string
+
+	// Cache stores the parsed players by URL, if set. The URL contains the player
+	// version, so a new player is fetched as soon as YouTube ships one and each
+	// version is only downloaded and parsed once.
+	Cache Cache
+}
+
+// Fetch returns the player at playerURL, a path as returned by ExtractPlayerURL.
+func (f *PlayerFetcher) Fetch(ctx context.Context, playerURL string) (*Player, error) {
+	cacheKey := "player" + playerURL
+	if f.Cache != nil {
+		if data, ok := f.Cache.Get(cacheKey); ok {
+			var player Player
+			if json.Unmarshal(data, &player) == nil {
+				return &player, nil
+			}
+		}
+	}
+
+	baseURL := f.BaseURL
+	if baseURL == "" {
+		baseURL = youtubeBaseURL
+	}
+	js, err := fetchPage(ctx, f.Client, baseURL+playerURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching player: %w", err)
+	}
+
+	operations, err := parseSignatureOperations(js)
+	if err != nil {
+		return nil, err
+	}
+	player := &Player{Version: PlayerVersion(playerURL), SignatureOperations: operations}
+
+	if f.Cache != nil {
+		if data, err := json.Marshal(player); err == nil {
+			f.Cache.Set(cacheKey, data)
+		}
+	}
+	return player, nil
+}
+
+// parseSignatureOperations finds the signature function in the player JavaScript
+// and returns the transforms it applies.
+func parseSignatureOperations(js string) ([]CipherOperation, error) {
+	match := signatureFunctionRegex.FindStringSubmatch(js)
+	if match == nil {
+		return nil, ErrSignatureFunctionNotFound
+	}
+	calls := signatureCallRegex.FindAllStringSubmatch(match[2], -1)
+	if len(calls) == 0 {
+		return nil, ErrSignatureFunctionNotFound
+	}
+
+	// All calls go through one helper object defined elsewhere in the player
+	helperName := calls[0][1]
+	helperRegex := regexp.MustCompile(`(?s)var ` + regexp.QuoteMeta(helperName) + `=\{(.*?)\};`)
+	helper := helperRegex.FindStringSubmatch(js)
+	if helper == nil {
+		return nil, fmt.Errorf("%w: helper object %s not found", ErrSignatureFunctionNotFound, helperName)
+	}
+	methods := make(map[string]string)
+	for _, m := range helperMethodRegex.FindAllStringSubmatch(helper[1], -1) {
+		switch {
+		case strings.Contains(m[2], "reverse"):
+			methods[m[1]] = CipherReverse
+		case strings.Contains(m[2], "splice"):
+			methods[m[1]] = CipherSplice
+		default:
+			methods[m[1]] = CipherSwap
+		}
+	}
+
+	operations := make([]CipherOperation, 0, len(calls))
+	for _, call := range calls {
+		name := call[2]
+		if name == "" {
+			name = call[3]
+		}
+		op, ok := methods[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown helper method %s", ErrSignatureFunctionNotFound, name)
+		}
+		arg, _ := strconv.Atoi(call[4])
+		operations = append(operations, CipherOperation{Op: op, Arg: arg})
+	}
+	return operations, nil
+}
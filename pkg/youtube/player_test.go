@@ -0,0 +1,132 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testPlayerJS mimics the parts of base.js that decipher signatures.
+const testPlayerJS = `var foo=function(){};
+var Xy={
+ab:function(a){a.reverse()},
+cd:function(a,b){a.splice(0,b)},
+"ef":function(a,b){var c=a[0];a[0]=a[b%a.length];a[b%a.length]=c}};
+zz=function(a){a=a.split("");Xy.ab(a,17);Xy.cd(a,2);Xy["ef"](a,3);Xy.ab(a,1);return a.join("")};
+`
+
+const testPlayerPath = "/s/player/3d3ba064/player_ias.vflset/en_US/base.js"
+
+func TestParseSignatureOperations(t *testing.T) {
+	ops, err := parseSignatureOperations(testPlayerJS)
+	if err != nil {
+		t.Fatalf("parseSignatureOperations() error = %v", err)
+	}
+	want := []CipherOperation{{CipherReverse, 17}, {CipherSplice, 2}, {CipherSwap, 3}, {CipherReverse, 1}}
+	if !reflect.DeepEqual(ops, want) {
+		t.Errorf("operations = %v, want %v", ops, want)
+	}
+
+	if _, err := parseSignatureOperations("var a=1;"); !errors.Is(err, ErrSignatureFunctionNotFound) {
+		t.Errorf("expected ErrSignatureFunctionNotFound, got %v", err)
+	}
+}
+
+func TestPlayer_DecipherSignature(t *testing.T) {
+	player := &Player{SignatureOperations: []CipherOperation{{CipherReverse, 0}, {CipherSplice, 2}, {CipherSwap, 3}}}
+	// "abcdefgh" reversed is "hgfedcba", spliced "fedcba", swapped 0 and 3 "cedfba"
+	if got := player.DecipherSignature("abcdefgh"); got != "cedfba" {
+		t.Errorf("DecipherSignature() = %q, want %q", got, "cedfba")
+	}
+}
+
+func TestWatchPage_ExtractPlayerURL(t *testing.T) {
+	page := &WatchPage{HTML: `<script>ytcfg.set({"PLAYER_JS_URL":"\/s\/player\/3d3ba064\/player_ias.vflset\/en_US\/base.js"});</script>`}
+	playerURL, err := page.ExtractPlayerURL()
+	if err != nil {
+		t.Fatalf("ExtractPlayerURL() error = %v", err)
+	}
+	if playerURL != testPlayerPath {
+		t.Errorf("player URL = %q", playerURL)
+	}
+	if PlayerVersion(playerURL) != "3d3ba064" {
+		t.Errorf("PlayerVersion() = %q", PlayerVersion(playerURL))
+	}
+
+	if _, err := (&WatchPage{HTML: "<html>"}).ExtractPlayerURL(); !errors.Is(err, ErrPlayerURLNotFound) {
+		t.Errorf("expected ErrPlayerURLNotFound, got %v", err)
+	}
+}
+
+func TestPlayerFetcher_Cache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(testPlayerJS))
+	}))
+	defer server.Close()
+
+	cache := mapCache{}
+	fetcher := &PlayerFetcher{Client: server.Client(), BaseURL: server.URL, Cache: cache}
+	for range 2 {
+		player, err := fetcher.Fetch(context.Background(), testPlayerPath)
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if player.Version != "3d3ba064" || len(player.SignatureOperations) != 4 {
+			t.Errorf("player = %+v", player)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want the player parsed once", requests)
+	}
+
+	// A new player version is fetched again
+	if _, err := fetcher.Fetch(context.Background(), strings.Replace(testPlayerPath, "3d3ba064", "4e4cb175", 1)); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("requests = %d, want a new version to be fetched", requests)
+	}
+}
+
+func TestWatchPageFetcher_DecipherStreams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != testPlayerPath {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(testPlayerJS))
+	}))
+	defer server.Close()
+
+	cipher := "s=" + url.QueryEscape("abcdefghij") + "&sp=sig&url=" + url.QueryEscape("https://example.com/videoplayback?itag=18")
+	sd := &StreamingDataResponse{
+		Formats:         []FormatResponse{{Itag: 18, SignatureCipher: cipher}},
+		AdaptiveFormats: []FormatResponse{{Itag: 140, URL: "https://example.com/audio"}},
+	}
+	page := &WatchPage{HTML: `"jsUrl":"` + testPlayerPath + `"`}
+	fetcher := &WatchPageFetcher{Client: server.Client(), BaseURL: server.URL}
+	if err := fetcher.DecipherStreams(context.Background(), page, sd); err != nil {
+		t.Fatalf("DecipherStreams() error = %v", err)
+	}
+
+	player := &Player{SignatureOperations: []CipherOperation{{CipherReverse, 17}, {CipherSplice, 2}, {CipherSwap, 3}, {CipherReverse, 1}}}
+	want := "https://example.com/videoplayback?itag=18&sig=" + player.DecipherSignature("abcdefghij")
+	if sd.Formats[0].URL != want {
+		t.Errorf("URL = %q, want %q", sd.Formats[0].URL, want)
+	}
+	if sd.AdaptiveFormats[0].URL != "https://example.com/audio" || sd.NeedsDecipher() {
+		t.Error("expected formats with URLs to be left alone")
+	}
+
+	// Nothing to decipher doesn't need the player
+	if err := (&WatchPageFetcher{}).DecipherStreams(context.Background(), &WatchPage{}, sd); err != nil {
+		t.Errorf("DecipherStreams() without ciphers error = %v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Playlist represents a YouTube playlist with its metadata.
@@ -140,6 +142,16 @@ type PlaylistVideo struct {
 	Thumbnails []Thumbnail
 }
 
+// Duration returns the video duration.
+func (v *PlaylistVideo) Duration() time.Duration {
+	return time.Duration(v.DurationSeconds) * time.Second
+}
+
+// DurationString returns the duration as "M:SS" or "H:MM:SS".
+func (v *PlaylistVideo) DurationString() string {
+	return formatDuration(v.Duration())
+}
+
 // playlistVideoRenderer represents the JSON structure for a playlist video item.
 type playlistVideoRenderer struct {
 	VideoID         string              `json:"videoId"`
@@ -187,6 +199,15 @@ func (r runText) getText() string {
 	return ""
 }
 
+// fullText joins all runs of the text.
+func (r runText) fullText() string {
+	var b strings.Builder
+	for _, run := range r.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
 // toPlaylistVideo converts a playlistVideoRenderer to PlaylistVideo.
 func (pvr *playlistVideoRenderer) toPlaylistVideo() PlaylistVideo {
 	duration, _ := strconv.Atoi(pvr.LengthSeconds)
@@ -2,6 +2,7 @@ package youtube
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
@@ -25,12 +26,39 @@ func IsValidPlaylistID(id string) bool {
 	return playlistIDRegex.MatchString(id)
 }
 
+// PlaylistID is a YouTube playlist ID, checked by the fetchers that take one
+// before making any request.
+type PlaylistID string
+
+// NewPlaylistID returns id as a PlaylistID, or an error wrapping
+// ErrInvalidPlaylistID if it isn't a valid playlist ID.
+func NewPlaylistID(id string) (PlaylistID, error) {
+	p := PlaylistID(id)
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// Validate returns an error wrapping ErrInvalidPlaylistID if p isn't a valid playlist ID.
+func (p PlaylistID) Validate() error {
+	if !IsValidPlaylistID(string(p)) {
+		return fmt.Errorf("%w: %q", ErrInvalidPlaylistID, string(p))
+	}
+	return nil
+}
+
+// String returns the ID.
+func (p PlaylistID) String() string {
+	return string(p)
+}
+
 // ParsePlaylistID extracts the playlist ID from a YouTube URL or validates a raw playlist ID.
 // Supported URL formats:
 //   - https://www.youtube.com/playlist?list=PLAYLIST_ID
 //   - https://www.youtube.com/watch?v=VIDEO_ID&list=PLAYLIST_ID
 //   - PLAYLIST_ID (raw playlist ID)
-func ParsePlaylistID(input string) (string, error) {
+func ParsePlaylistID(input string) (PlaylistID, error) {
 	input = strings.TrimSpace(input)
 	if input == "" {
 		return "", ErrInvalidPlaylistID
@@ -38,7 +66,7 @@ func ParsePlaylistID(input string) (string, error) {
 
 	// Check if input is already a valid playlist ID
 	if IsValidPlaylistID(input) {
-		return input, nil
+		return PlaylistID(input), nil
 	}
 
 	// Try to parse as URL
@@ -62,14 +90,16 @@ func ParsePlaylistID(input string) (string, error) {
 		return "", ErrInvalidPlaylistID
 	}
 
-	return playlistID, nil
+	return PlaylistID(playlistID), nil
 }
 
-// isYouTubeHost checks if the host is a YouTube domain.
+// isYouTubeHost checks if the host is a YouTube domain: youtube.com and its
+// www., m. and music. subdomains, youtu.be, and the youtube-nocookie.com embed host.
 func isYouTubeHost(host string) bool {
-	host = strings.ToLower(host)
-	return host == "youtube.com" ||
-		host == "www.youtube.com" ||
-		host == "m.youtube.com" ||
-		host == "youtu.be"
+	switch strings.ToLower(host) {
+	case "youtube.com", "www.youtube.com", "m.youtube.com", "music.youtube.com",
+		"youtu.be", "youtube-nocookie.com", "www.youtube-nocookie.com":
+		return true
+	}
+	return false
 }
@@ -1,13 +1,14 @@
 package youtube
 
 import (
+	"errors"
 	"testing"
 )
 
 func TestParsePlaylistID_PlaylistURL(t *testing.T) {
 	tests := []struct {
 		url      string
-		expected string
+		expected PlaylistID
 	}{
 		{"https://www.youtube.com/playlist?list=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf", "PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"},
 		{"http://www.youtube.com/playlist?list=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf", "PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"},
@@ -30,7 +31,7 @@ func TestParsePlaylistID_PlaylistURL(t *testing.T) {
 func TestParsePlaylistID_WatchURLWithList(t *testing.T) {
 	tests := []struct {
 		url      string
-		expected string
+		expected PlaylistID
 	}{
 		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf", "PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"},
 		{"https://www.youtube.com/watch?list=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf&v=dQw4w9WgXcQ", "PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"},
@@ -52,7 +53,7 @@ func TestParsePlaylistID_WatchURLWithList(t *testing.T) {
 func TestParsePlaylistID_SpecialPlaylists(t *testing.T) {
 	tests := []struct {
 		url      string
-		expected string
+		expected PlaylistID
 	}{
 		// Watch Later
 		{"https://www.youtube.com/playlist?list=WL", "WL"},
@@ -78,7 +79,7 @@ func TestParsePlaylistID_SpecialPlaylists(t *testing.T) {
 func TestParsePlaylistID_RawID(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected string
+		expected PlaylistID
 	}{
 		{"PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf", "PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"},
 		{"WL", "WL"},
@@ -143,3 +144,16 @@ func TestIsValidPlaylistID(t *testing.T) {
 		})
 	}
 }
+
+func TestNewPlaylistID(t *testing.T) {
+	id, err := NewPlaylistID("PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf")
+	if err != nil || id.String() != "PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf" {
+		t.Errorf("NewPlaylistID() = %q, %v", id, err)
+	}
+
+	for _, input := range []string{"", "PLtest", "https://www.youtube.com/playlist?list=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"} {
+		if _, err := NewPlaylistID(input); !errors.Is(err, ErrInvalidPlaylistID) {
+			t.Errorf("NewPlaylistID(%q) error = %v, want ErrInvalidPlaylistID", input, err)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tracing"
+)
+
+// ErrPlaylistUnavailable is returned when a playlist page has no playlist,
+// for example because the playlist is private or was deleted.
+var ErrPlaylistUnavailable = errors.New("playlist is unavailable")
+
+// PlaylistPage is one page of a playlist's videos.
+type PlaylistPage struct {
+	// Playlist is the playlist's metadata.
+	Playlist Playlist
+
+	// Videos are the videos on this page.
+	Videos []PlaylistVideo
+
+	// Continuation is the token for the next page, empty on the last page.
+	Continuation string
+
+	// config is the InnerTube configuration used to request further pages.
+	config innertubeConfig
+}
+
+// HasMore reports whether there are more pages after this one.
+func (p *PlaylistPage) HasMore() bool {
+	return p.Continuation != ""
+}
+
+// PlaylistFetcher fetches YouTube playlists and their videos.
+type PlaylistFetcher struct {
+	// Client is the HTTP client to use for requests.
+	Client *http.Client
+
+	// BaseURL is the base URL for YouTube (used for testing).
+	// If empty, defaults to https://www.youtube.com.
+	BaseURL string
+
+	// Cookies are the HTTP cookies to include with requests,
+	// needed for private playlists.
+	Cookies []*http.Cookie
+
+	// Cache stores the listings returned by Fetch by playlist ID, if set.
+	// Listings fetched with cookies are never cached.
+	Cache Cache
+}
+
+// cachedPlaylist is a playlist listing as stored in the cache.
+type cachedPlaylist struct {
+	Playlist Playlist        `json:"playlist"`
+	Videos   []PlaylistVideo `json:"videos"`
+}
+
+// PlaylistURL returns the URL for a playlist's page.
+func PlaylistURL(playlistID string) string {
+	return fmt.Sprintf("%s/playlist?list=%s", youtubeBaseURL, url.QueryEscape(playlistID))
+}
+
+func (f *PlaylistFetcher) baseURL() string {
+	if f.BaseURL == "" {
+		return youtubeBaseURL
+	}
+	return f.BaseURL
+}
+
+// FetchPage retrieves the playlist's metadata and its first page of videos. An
+// invalid ID fails with ErrInvalidPlaylistID without making a request.
+func (f *PlaylistFetcher) FetchPage(ctx context.Context, id PlaylistID) (*PlaylistPage, error) {
+	if err := id.Validate(); err != nil {
+		return nil, err
+	}
+	playlistID := id.String()
+
+	baseURL := f.baseURL()
+	setCookies(f.Client, baseURL, f.Cookies)
+
+	html, err := fetchPage(ctx, f.Client, fmt.Sprintf("%s/playlist?list=%s", baseURL, url.QueryEscape(playlistID)))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := extractInitialData(html)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := parsePlaylistPage(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing playlist: %w", err)
+	}
+	if page.Playlist.Title == "" && len(page.Videos) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPlaylistUnavailable, playlistID)
+	}
+
+	page.Playlist.ID = playlistID
+	page.config = extractInnertubeConfig(html)
+	return page, nil
+}
+
+// NextPage retrieves the page of videos following page.
+func (f *PlaylistFetcher) NextPage(ctx context.Context, page *PlaylistPage) (*PlaylistPage, error) {
+	if !page.HasMore() {
+		return nil, errors.New("no more pages")
+	}
+	next, err := f.fetchContinuation(ctx, page.config, page.Continuation)
+	if err != nil {
+		return nil, err
+	}
+	next.Playlist = page.Playlist
+	return next, nil
+}
+
+// FetchContinuation retrieves the page of videos for a continuation token saved
+// from an earlier PlaylistPage. The returned page has no playlist metadata.
+func (f *PlaylistFetcher) FetchContinuation(ctx context.Context, token string) (*PlaylistPage, error) {
+	if token == "" {
+		return nil, errors.New("empty continuation token")
+	}
+	return f.fetchContinuation(ctx, innertubeConfig{ClientVersion: defaultWebClientVersion}, token)
+}
+
+// fetchContinuation requests the page for a continuation token from the browse endpoint.
+func (f *PlaylistFetcher) fetchContinuation(ctx context.Context, cfg innertubeConfig, token string) (*PlaylistPage, error) {
+	data, err := browse(ctx, f.Client, f.baseURL(), cfg, map[string]any{"continuation": token})
+	if err != nil {
+		return nil, err
+	}
+
+	videos, continuation, err := parsePlaylistContinuation(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing playlist continuation: %w", err)
+	}
+
+	return &PlaylistPage{
+		Videos:       videos,
+		Continuation: continuation,
+		config:       cfg,
+	}, nil
+}
+
+// Fetch retrieves the playlist's metadata and all of its videos, following every page.
+// Videos without an index are numbered by their position.
+func (f *PlaylistFetcher) Fetch(ctx context.Context, playlistID PlaylistID) (*Playlist, []PlaylistVideo, error) {
+	if err := playlistID.Validate(); err != nil {
+		return nil, nil, err
+	}
+	cache := f.Cache
+	if len(f.Cookies) > 0 {
+		cache = nil
+	}
+	cacheKey := "playlist/" + playlistID.String()
+	if cache != nil {
+		if data, ok := cache.Get(cacheKey); ok {
+			var cached cachedPlaylist
+			if json.Unmarshal(data, &cached) == nil {
+				return &cached.Playlist, cached.Videos, nil
+			}
+		}
+	}
+
+	spanCtx, span := tracing.Start(ctx, tracing.SpanFetchPlaylist, tracing.String(tracing.AttrPlaylistID, playlistID.String()))
+	playlist, videos, err := f.fetchAll(spanCtx, playlistID)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cache != nil {
+		if data, err := json.Marshal(cachedPlaylist{Playlist: *playlist, Videos: videos}); err == nil {
+			cache.Set(cacheKey, data)
+		}
+	}
+	return playlist, videos, nil
+}
+
+// Pages returns an iterator over the pages of the playlist, fetching each page
+// only when the previous one has been consumed. It stops after the last page or
+// yields a nil page and the error when a fetch fails. Pages aren't cached.
+func (f *PlaylistFetcher) Pages(ctx context.Context, playlistID PlaylistID) iter.Seq2[*PlaylistPage, error] {
+	return func(yield func(*PlaylistPage, error) bool) {
+		page, err := f.FetchPage(ctx, playlistID)
+		for {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(page, nil) || !page.HasMore() {
+				return
+			}
+			page, err = f.NextPage(ctx, page)
+		}
+	}
+}
+
+// Videos returns an iterator over the videos of the playlist, numbered by their
+// position like Fetch. Continuations are fetched only as the videos are
+// consumed, so breaking out of the loop stops fetching and only one page is
+// held in memory at a time. A failed fetch is yielded as the last element.
+func (f *PlaylistFetcher) Videos(ctx context.Context, playlistID PlaylistID) iter.Seq2[PlaylistVideo, error] {
+	return func(yield func(PlaylistVideo, error) bool) {
+		position := 0
+		for page, err := range f.Pages(ctx, playlistID) {
+			if err != nil {
+				yield(PlaylistVideo{}, err)
+				return
+			}
+			for _, video := range page.Videos {
+				position++
+				if video.Index == 0 {
+					video.Index = position
+				}
+				if !yield(video, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// fetchAll fetches every page of the playlist.
+func (f *PlaylistFetcher) fetchAll(ctx context.Context, playlistID PlaylistID) (*Playlist, []PlaylistVideo, error) {
+	var playlist Playlist
+	var videos []PlaylistVideo
+	for page, err := range f.Pages(ctx, playlistID) {
+		if err != nil {
+			return nil, nil, err
+		}
+		playlist = page.Playlist
+		videos = append(videos, page.Videos...)
+	}
+
+	for i := range videos {
+		if videos[i].Index == 0 {
+			videos[i].Index = i + 1
+		}
+	}
+	if playlist.VideoCount == 0 {
+		playlist.VideoCount = len(videos)
+	}
+
+	return &playlist, videos, nil
+}
+
+// parsePlaylistPage extracts the playlist metadata and first page of videos from ytInitialData.
+func parsePlaylistPage(jsonData string) (*PlaylistPage, error) {
+	title, err := parsePlaylistTitle(jsonData)
+	if err != nil {
+		return nil, err
+	}
+	count, err := parsePlaylistVideoCount(jsonData)
+	if err != nil {
+		return nil, err
+	}
+	author, err := parsePlaylistAuthor(jsonData)
+	if err != nil {
+		return nil, err
+	}
+	videos, continuation, err := parsePlaylistVideos(jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Newer layouts drop the playlist header; the metadata renderer is always present
+	var metadata struct {
+		Metadata struct {
+			PlaylistMetadataRenderer struct {
+				Title       string `json:"title"`
+				Description string `json:"description"`
+			} `json:"playlistMetadataRenderer"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal([]byte(jsonData), &metadata); err != nil {
+		return nil, err
+	}
+	if title == "" {
+		title = metadata.Metadata.PlaylistMetadataRenderer.Title
+	}
+
+	return &PlaylistPage{
+		Playlist: Playlist{
+			Title:       title,
+			Author:      author,
+			VideoCount:  count,
+			Description: metadata.Metadata.PlaylistMetadataRenderer.Description,
+		},
+		Videos:       videos,
+		Continuation: continuation,
+	}, nil
+}
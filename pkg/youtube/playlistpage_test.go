@@ -0,0 +1,294 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// playlistVideoJSON returns a playlistVideoRenderer item for tests.
+func playlistVideoJSON(id, title string, seconds, index int) string {
+	return fmt.Sprintf(`{"playlistVideoRenderer":{"videoId":%q,"title":{"runs":[{"text":%q}]},"lengthSeconds":"%d","index":{"simpleText":"%d"}}}`,
+		id, title, seconds, index)
+}
+
+// continuationItemJSON returns a continuationItemRenderer item for tests.
+func continuationItemJSON(token string) string {
+	return fmt.Sprintf(`{"continuationItemRenderer":{"continuationEndpoint":{"continuationCommand":{"token":%q}}}}`, token)
+}
+
+// newPlaylistTestServer serves a two-page playlist: the page has two videos and a
+// continuation, and the browse endpoint returns the third video.
+func newPlaylistTestServer(t *testing.T, browseRequests *[]map[string]any) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/playlist":
+			if r.URL.Query().Get("list") != "PLtest1230000000000000000000000000" {
+				_, _ = w.Write([]byte(`<script>var ytInitialData = {"alerts":[{"alertRenderer":{"type":"ERROR"}}]};</script>`))
+				return
+			}
+			data := `{"header":{"playlistHeaderRenderer":{"title":{"simpleText":"Test Playlist"},` +
+				`"numVideosText":{"runs":[{"text":"3 videos"}]},` +
+				`"ownerText":{"runs":[{"text":"Test Channel","navigationEndpoint":{"browseEndpoint":{"browseId":"UCtest"}}}]}}},` +
+				`"metadata":{"playlistMetadataRenderer":{"title":"Test Playlist","description":"A test playlist"}},` +
+				`"contents":{"twoColumnBrowseResultsRenderer":{"tabs":[{"tabRenderer":{"content":{"sectionListRenderer":{"contents":[{"itemSectionRenderer":{"contents":[{"playlistVideoListRenderer":{"contents":[` +
+				playlistVideoJSON("video1", "First", 61, 1) + `,` + playlistVideoJSON("video2", "Second", 3725, 2) + `,` + continuationItemJSON("page2") +
+				`]}}]}}]}}}}]}}}`
+			_, _ = w.Write([]byte(`<script>ytcfg.set({"INNERTUBE_API_KEY":"test-key","INNERTUBE_CLIENT_VERSION":"2.20250101.00.00"});</script>` +
+				`<script>var ytInitialData = ` + data + `;</script>`))
+		case "/youtubei/v1/browse":
+			if r.Method != http.MethodPost || r.URL.Query().Get("key") != "test-key" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			*browseRequests = append(*browseRequests, body)
+			_, _ = w.Write([]byte(`{"onResponseReceivedActions":[{"appendContinuationItemsAction":{"continuationItems":[` +
+				playlistVideoJSON("video3", "Third", 5, 0) + `]}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPlaylistFetcher_FetchFollowsContinuations(t *testing.T) {
+	var browseRequests []map[string]any
+	server := newPlaylistTestServer(t, &browseRequests)
+	fetcher := &PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	playlist, videos, err := fetcher.Fetch(context.Background(), "PLtest1230000000000000000000000000")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if playlist.ID != "PLtest1230000000000000000000000000" || playlist.Title != "Test Playlist" || playlist.VideoCount != 3 {
+		t.Errorf("playlist = %+v", playlist)
+	}
+	if playlist.Author.Name != "Test Channel" || playlist.Author.ChannelID != "UCtest" {
+		t.Errorf("author = %+v", playlist.Author)
+	}
+	if playlist.Description != "A test playlist" {
+		t.Errorf("description = %q", playlist.Description)
+	}
+
+	if len(videos) != 3 {
+		t.Fatalf("got %d videos, want 3", len(videos))
+	}
+	if videos[2].ID != "video3" || videos[2].Index != 3 {
+		t.Errorf("continuation video = %+v, want video3 numbered 3", videos[2])
+	}
+
+	if len(browseRequests) != 1 {
+		t.Fatalf("browse requests = %d, want 1", len(browseRequests))
+	}
+	if browseRequests[0]["continuation"] != "page2" {
+		t.Errorf("continuation = %v, want page2", browseRequests[0]["continuation"])
+	}
+	client := browseRequests[0]["context"].(map[string]any)["client"].(map[string]any)
+	if client["clientVersion"] != "2.20250101.00.00" {
+		t.Errorf("clientVersion = %v, want the page's version", client["clientVersion"])
+	}
+}
+
+func TestPlaylistFetcher_FetchCache(t *testing.T) {
+	var browseRequests []map[string]any
+	server := newPlaylistTestServer(t, &browseRequests)
+	fetcher := &PlaylistFetcher{Client: server.Client(), BaseURL: server.URL, Cache: mapCache{}}
+
+	for range 2 {
+		playlist, videos, err := fetcher.Fetch(context.Background(), "PLtest1230000000000000000000000000")
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if playlist.Title != "Test Playlist" || len(videos) != 3 || videos[2].Index != 3 {
+			t.Errorf("playlist = %+v, videos = %+v", playlist, videos)
+		}
+	}
+	if len(browseRequests) != 1 {
+		t.Errorf("browse requests = %d, want the second fetch served from the cache", len(browseRequests))
+	}
+}
+
+func TestPlaylistFetcher_FetchPage(t *testing.T) {
+	var browseRequests []map[string]any
+	server := newPlaylistTestServer(t, &browseRequests)
+	fetcher := &PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	page, err := fetcher.FetchPage(context.Background(), "PLtest1230000000000000000000000000")
+	if err != nil {
+		t.Fatalf("FetchPage failed: %v", err)
+	}
+	if len(page.Videos) != 2 || !page.HasMore() {
+		t.Errorf("first page has %d videos, more = %v; want 2 and more", len(page.Videos), page.HasMore())
+	}
+	if len(browseRequests) != 0 {
+		t.Error("FetchPage should not request further pages")
+	}
+
+	next, err := fetcher.NextPage(context.Background(), page)
+	if err != nil {
+		t.Fatalf("NextPage failed: %v", err)
+	}
+	if len(next.Videos) != 1 || next.HasMore() {
+		t.Errorf("second page has %d videos, more = %v; want 1 and no more", len(next.Videos), next.HasMore())
+	}
+	if next.Playlist.Title != "Test Playlist" {
+		t.Errorf("next page playlist title = %q", next.Playlist.Title)
+	}
+}
+
+func TestPlaylistFetcher_Videos(t *testing.T) {
+	var browseRequests []map[string]any
+	server := newPlaylistTestServer(t, &browseRequests)
+	fetcher := &PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	var ids []string
+	for video, err := range fetcher.Videos(context.Background(), "PLtest1230000000000000000000000000") {
+		if err != nil {
+			t.Fatalf("Videos failed: %v", err)
+		}
+		ids = append(ids, fmt.Sprintf("%s#%d", video.ID, video.Index))
+	}
+	if got := strings.Join(ids, ","); got != "video1#1,video2#2,video3#3" {
+		t.Errorf("videos = %s", got)
+	}
+	if len(browseRequests) != 1 {
+		t.Errorf("browse requests = %d, want 1", len(browseRequests))
+	}
+}
+
+func TestPlaylistFetcher_VideosStopsEarly(t *testing.T) {
+	var browseRequests []map[string]any
+	server := newPlaylistTestServer(t, &browseRequests)
+	fetcher := &PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	for video, err := range fetcher.Videos(context.Background(), "PLtest1230000000000000000000000000") {
+		if err != nil {
+			t.Fatalf("Videos failed: %v", err)
+		}
+		if video.ID == "video2" {
+			break
+		}
+	}
+	if len(browseRequests) != 0 {
+		t.Errorf("browse requests = %d, want the continuation left unfetched", len(browseRequests))
+	}
+}
+
+func TestPlaylistFetcher_VideosError(t *testing.T) {
+	var browseRequests []map[string]any
+	server := newPlaylistTestServer(t, &browseRequests)
+	fetcher := &PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	count := 0
+	for _, err := range fetcher.Videos(context.Background(), "PLprivate0000000000000000000000000") {
+		count++
+		if !errors.Is(err, ErrPlaylistUnavailable) {
+			t.Errorf("Videos error = %v, want ErrPlaylistUnavailable", err)
+		}
+	}
+	if count != 1 {
+		t.Errorf("yielded %d elements, want only the error", count)
+	}
+}
+
+func TestPlaylistFetcher_Unavailable(t *testing.T) {
+	var browseRequests []map[string]any
+	server := newPlaylistTestServer(t, &browseRequests)
+	fetcher := &PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	_, _, err := fetcher.Fetch(context.Background(), "PLprivate0000000000000000000000000")
+	if !errors.Is(err, ErrPlaylistUnavailable) {
+		t.Errorf("expected ErrPlaylistUnavailable, got %v", err)
+	}
+}
+
+func TestPlaylistFetcher_InvalidID(t *testing.T) {
+	var browseRequests []map[string]any
+	server := newPlaylistTestServer(t, &browseRequests)
+	fetcher := &PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	if _, _, err := fetcher.Fetch(context.Background(), "PLtest123"); !errors.Is(err, ErrInvalidPlaylistID) {
+		t.Errorf("Fetch() error = %v, want ErrInvalidPlaylistID", err)
+	}
+	for _, err := range fetcher.Videos(context.Background(), "") {
+		if !errors.Is(err, ErrInvalidPlaylistID) {
+			t.Errorf("Videos() error = %v, want ErrInvalidPlaylistID", err)
+		}
+	}
+}
+
+func TestPlaylistURL(t *testing.T) {
+	if got := PlaylistURL("PLtest1230000000000000000000000000"); got != "https://www.youtube.com/playlist?list=PLtest1230000000000000000000000000" {
+		t.Errorf("PlaylistURL() = %q", got)
+	}
+	if got := PlaylistURL("a b"); !strings.HasSuffix(got, "list=a+b") {
+		t.Errorf("PlaylistURL() should escape the ID, got %q", got)
+	}
+}
+
+func TestExtractInnertubeConfig(t *testing.T) {
+	cfg := extractInnertubeConfig(`ytcfg.set({"INNERTUBE_API_KEY": "abc", "INNERTUBE_CLIENT_VERSION": "2.1", "INNERTUBE_CONTEXT_GL": "DE"})`)
+	if cfg.APIKey != "abc" || cfg.ClientVersion != "2.1" || cfg.Region != "DE" {
+		t.Errorf("config = %+v", cfg)
+	}
+
+	cfg = extractInnertubeConfig(`ytcfg.set({"VISITOR_DATA": "CgtWaXNpdG9y\u003d\u003d"})`)
+	if cfg.VisitorData != "CgtWaXNpdG9y==" {
+		t.Errorf("visitor data = %q", cfg.VisitorData)
+	}
+
+	cfg = extractInnertubeConfig("<html></html>")
+	if cfg.APIKey != "" || cfg.ClientVersion != defaultWebClientVersion || cfg.Region != "" {
+		t.Errorf("default config = %+v", cfg)
+	}
+}
+
+func TestPlaylistVideo_DurationString(t *testing.T) {
+	tests := map[int]string{61: "1:01", 3725: "1:02:05", 0: "0:00"}
+	for seconds, want := range tests {
+		v := PlaylistVideo{DurationSeconds: seconds}
+		if got := v.DurationString(); got != want {
+			t.Errorf("DurationString(%ds) = %q, want %q", seconds, got, want)
+		}
+	}
+}
+
+func TestPlaylistFetcher_FetchContinuation(t *testing.T) {
+	// The saved token is used without the page, so no API key is known
+	var browseRequests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		browseRequests = append(browseRequests, body)
+		_, _ = w.Write([]byte(`{"onResponseReceivedActions":[{"appendContinuationItemsAction":{"continuationItems":[` +
+			playlistVideoJSON("video3", "Third", 5, 3) + `,` + continuationItemJSON("page3") + `]}}]}`))
+	}))
+	defer server.Close()
+	fetcher := &PlaylistFetcher{Client: server.Client(), BaseURL: server.URL}
+
+	page, err := fetcher.FetchContinuation(context.Background(), "page2")
+	if err != nil {
+		t.Fatalf("FetchContinuation failed: %v", err)
+	}
+	if len(page.Videos) != 1 || page.Continuation != "page3" {
+		t.Errorf("page = %d videos, continuation %q", len(page.Videos), page.Continuation)
+	}
+	client := browseRequests[0]["context"].(map[string]any)["client"].(map[string]any)
+	if client["clientVersion"] != defaultWebClientVersion {
+		t.Errorf("clientVersion = %v, want default", client["clientVersion"])
+	}
+
+	if _, err := fetcher.FetchContinuation(context.Background(), ""); err == nil {
+		t.Error("expected error for empty token")
+	}
+}
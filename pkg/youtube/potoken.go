@@ -0,0 +1,166 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// POTokenRequest describes the session a proof of origin (PO) token is needed for.
+type POTokenRequest struct {
+	// VideoID is the video whose streams the token is for.
+	VideoID string
+
+	// VisitorData identifies the session the watch page was served for, empty
+	// if the page didn't include it.
+	VisitorData string
+}
+
+// ContentBinding returns the value the token must be minted for: the visitor
+// data, or the video ID when the page had none.
+func (r POTokenRequest) ContentBinding() string {
+	if r.VisitorData != "" {
+		return r.VisitorData
+	}
+	return r.VideoID
+}
+
+// POTokenProvider supplies PO tokens. YouTube refuses stream requests from the
+// WEB client with 403 Forbidden, often partway through a download, unless the
+// URL carries a token minted by its BotGuard challenge for the session.
+// Running that challenge needs a JavaScript environment, so tokens come from an
+// external provider.
+type POTokenProvider interface {
+	POToken(ctx context.Context, req POTokenRequest) (string, error)
+}
+
+// StaticPOToken is a POTokenProvider that always returns the same token, such as
+// one copied from a browser session. It is only valid for the visitor data it was
+// minted for, so it should be used with WatchPageFetcher.VisitorData.
+type StaticPOToken string
+
+// POToken returns the token.
+func (t StaticPOToken) POToken(context.Context, POTokenRequest) (string, error) {
+	return string(t), nil
+}
+
+// HTTPPOTokenProvider gets PO tokens from a token server, such as
+// bgutil-ytdlp-pot-provider. The server is sent a POST request with a JSON body
+// of the form {"content_binding": "...", "video_id": "..."} and must answer with
+// {"poToken": "..."} or {"po_token": "..."}.
+type HTTPPOTokenProvider struct {
+	// Client is the HTTP client to use for requests.
+	Client *http.Client
+
+	// URL is the endpoint tokens are requested from, e.g. http://127.0.0.1:4416/get_pot.
+	URL string
+}
+
+// POToken requests a token for req from the server.
+func (p *HTTPPOTokenProvider) POToken(ctx context.Context, req POTokenRequest) (string, error) {
+	body, err := json.Marshal(map[string]string{
+		"content_binding": req.ContentBinding(),
+		"video_id":        req.VideoID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding token request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("requesting PO token: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token provider returned status code %d", resp.StatusCode)
+	}
+
+	var result struct {
+		POToken      string `json:"poToken"`
+		POTokenSnake string `json:"po_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", fmt.Errorf("decoding token response: %w", err)
+	}
+	token := result.POToken
+	if token == "" {
+		token = result.POTokenSnake
+	}
+	if token == "" {
+		return "", errors.New("token provider returned no token")
+	}
+	return token, nil
+}
+
+// VisitorData returns the visitor data YouTube served the page with, or "" if
+// the page doesn't include it.
+func (p *WatchPage) VisitorData() string {
+	return extractInnertubeConfig(p.HTML).VisitorData
+}
+
+// AddPOToken gets a PO token for the page from the fetcher's POTokenProvider and
+// adds it to the URLs of the streaming data. It does nothing if the fetcher has
+// no provider, so streams are requested without a token.
+func (f *WatchPageFetcher) AddPOToken(ctx context.Context, page *WatchPage, streamingData *StreamingDataResponse) error {
+	if f.POTokenProvider == nil {
+		return nil
+	}
+	visitorData := page.VisitorData()
+	if visitorData == "" {
+		visitorData = f.VisitorData
+	}
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+	token, err := f.POTokenProvider.POToken(ctx, POTokenRequest{VideoID: page.VideoID, VisitorData: visitorData})
+	if err := fetchTimeoutError(ctx, err); err != nil {
+		return fmt.Errorf("getting PO token: %w", err)
+	}
+	streamingData.SetPOToken(token)
+	return nil
+}
+
+// SetPOToken adds token as the pot parameter of every format URL, replacing any
+// token the URL already has. Formats still needing a signature are skipped, so
+// call it after Decipher.
+func (sd *StreamingDataResponse) SetPOToken(token string) {
+	for _, formats := range [][]FormatResponse{sd.Formats, sd.AdaptiveFormats} {
+		for i := range formats {
+			if formats[i].URL != "" {
+				formats[i].URL = withPOToken(formats[i].URL, token)
+			}
+		}
+	}
+}
+
+// withPOToken sets the pot parameter of a stream URL. Other parameters are kept
+// in order, since they are covered by the URL's signature.
+func withPOToken(streamURL, token string) string {
+	base, query, _ := strings.Cut(streamURL, "?")
+	var params []string
+	for _, param := range strings.Split(query, "&") {
+		if param != "" && !strings.HasPrefix(param, "pot=") {
+			params = append(params, param)
+		}
+	}
+	params = append(params, "pot="+url.QueryEscape(token))
+	return base + "?" + strings.Join(params, "&")
+}
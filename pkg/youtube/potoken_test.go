@@ -0,0 +1,170 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// recordingPOTokens returns token and remembers the requests it got.
+type recordingPOTokens struct {
+	token    string
+	err      error
+	requests []POTokenRequest
+}
+
+func (p *recordingPOTokens) POToken(_ context.Context, req POTokenRequest) (string, error) {
+	p.requests = append(p.requests, req)
+	return p.token, p.err
+}
+
+func TestPOTokenRequest_ContentBinding(t *testing.T) {
+	if got := (POTokenRequest{VideoID: "abc", VisitorData: "Cgt"}).ContentBinding(); got != "Cgt" {
+		t.Errorf("ContentBinding() = %q, want the visitor data", got)
+	}
+	if got := (POTokenRequest{VideoID: "abc"}).ContentBinding(); got != "abc" {
+		t.Errorf("ContentBinding() = %q, want the video ID", got)
+	}
+}
+
+func TestWithPOToken(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/videoplayback?itag=137&sig=x":       "https://example.com/videoplayback?itag=137&sig=x&pot=a%2Bb",
+		"https://example.com/videoplayback?pot=old&itag=137":     "https://example.com/videoplayback?itag=137&pot=a%2Bb",
+		"https://example.com/videoplayback":                      "https://example.com/videoplayback?pot=a%2Bb",
+		"https://example.com/videoplayback?potato=1&expire=1700": "https://example.com/videoplayback?potato=1&expire=1700&pot=a%2Bb",
+	}
+	for in, want := range tests {
+		if got := withPOToken(in, "a+b"); got != want {
+			t.Errorf("withPOToken(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestStreamingDataResponse_SetPOToken(t *testing.T) {
+	sd := &StreamingDataResponse{
+		Formats:         []FormatResponse{{Itag: 18, URL: "https://example.com/a?itag=18"}},
+		AdaptiveFormats: []FormatResponse{{Itag: 137, SignatureCipher: "s=x&url=y"}, {Itag: 140, URL: "https://example.com/a?itag=140"}},
+	}
+	sd.SetPOToken("tok")
+	if sd.Formats[0].URL != "https://example.com/a?itag=18&pot=tok" || sd.AdaptiveFormats[1].URL != "https://example.com/a?itag=140&pot=tok" {
+		t.Errorf("URLs = %q, %q", sd.Formats[0].URL, sd.AdaptiveFormats[1].URL)
+	}
+	if sd.AdaptiveFormats[0].URL != "" {
+		t.Errorf("format needing a signature got URL %q", sd.AdaptiveFormats[0].URL)
+	}
+}
+
+func TestWatchPageFetcher_AddPOToken(t *testing.T) {
+	page := &WatchPage{VideoID: "dQw4w9WgXcQ", HTML: `<script>ytcfg.set({"VISITOR_DATA":"CgtWaXNpdG9y"});</script>`}
+	sd := &StreamingDataResponse{AdaptiveFormats: []FormatResponse{{Itag: 140, URL: "https://example.com/a?itag=140"}}}
+	provider := &recordingPOTokens{token: "tok"}
+
+	fetcher := &WatchPageFetcher{POTokenProvider: provider, VisitorData: "ignored"}
+	if err := fetcher.AddPOToken(context.Background(), page, sd); err != nil {
+		t.Fatalf("AddPOToken: %v", err)
+	}
+	if len(provider.requests) != 1 || provider.requests[0] != (POTokenRequest{VideoID: "dQw4w9WgXcQ", VisitorData: "CgtWaXNpdG9y"}) {
+		t.Errorf("requests = %+v, want one for the page's visitor data", provider.requests)
+	}
+	if sd.AdaptiveFormats[0].URL != "https://example.com/a?itag=140&pot=tok" {
+		t.Errorf("URL = %q", sd.AdaptiveFormats[0].URL)
+	}
+
+	// Without page visitor data, the fetcher's is used
+	provider.requests = nil
+	if err := fetcher.AddPOToken(context.Background(), &WatchPage{VideoID: "abc"}, sd); err != nil {
+		t.Fatalf("AddPOToken: %v", err)
+	}
+	if provider.requests[0].VisitorData != "ignored" {
+		t.Errorf("visitor data = %q, want the fetcher's", provider.requests[0].VisitorData)
+	}
+}
+
+func TestWatchPageFetcher_AddPOTokenErrors(t *testing.T) {
+	sd := &StreamingDataResponse{AdaptiveFormats: []FormatResponse{{Itag: 140, URL: "https://example.com/a"}}}
+	if err := (&WatchPageFetcher{}).AddPOToken(context.Background(), &WatchPage{}, sd); err != nil || sd.AdaptiveFormats[0].URL != "https://example.com/a" {
+		t.Errorf("without provider: err = %v, URL = %q", err, sd.AdaptiveFormats[0].URL)
+	}
+
+	providerErr := errors.New("provider down")
+	fetcher := &WatchPageFetcher{POTokenProvider: &recordingPOTokens{err: providerErr}}
+	if err := fetcher.AddPOToken(context.Background(), &WatchPage{}, sd); !errors.Is(err, providerErr) {
+		t.Errorf("error = %v, want the provider error", err)
+	}
+}
+
+func TestStaticPOToken(t *testing.T) {
+	token, err := StaticPOToken("tok").POToken(context.Background(), POTokenRequest{VideoID: "abc"})
+	if err != nil || token != "tok" {
+		t.Errorf("POToken() = %q, %v", token, err)
+	}
+}
+
+func TestHTTPPOTokenProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		want     string
+		wantErr  bool
+	}{
+		{name: "camel case", status: http.StatusOK, response: `{"poToken": "tok1"}`, want: "tok1"},
+		{name: "snake case", status: http.StatusOK, response: `{"po_token": "tok2", "expires_at": "2026-01-01"}`, want: "tok2"},
+		{name: "no token", status: http.StatusOK, response: `{}`, wantErr: true},
+		{name: "invalid JSON", status: http.StatusOK, response: `not json`, wantErr: true},
+		{name: "server error", status: http.StatusInternalServerError, response: `{"poToken": "tok"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("request = %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+				}
+				_ = json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			provider := &HTTPPOTokenProvider{Client: server.Client(), URL: server.URL + "/get_pot"}
+			token, err := provider.POToken(context.Background(), POTokenRequest{VideoID: "abc", VisitorData: "Cgt"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("POToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if token != tt.want {
+				t.Errorf("POToken() = %q, want %q", token, tt.want)
+			}
+			if got["content_binding"] != "Cgt" || got["video_id"] != "abc" {
+				t.Errorf("request body = %v", got)
+			}
+		})
+	}
+}
+
+func TestCallInnertube_SendsVisitorData(t *testing.T) {
+	var header string
+	var payload struct {
+		Context struct {
+			Client map[string]any `json:"client"`
+		} `json:"context"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Goog-Visitor-Id")
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	cfg := innertubeConfig{ClientVersion: "2.1", VisitorData: "CgtWaXNpdG9y"}
+	if _, err := callInnertube(context.Background(), server.Client(), server.URL, cfg, "next", nil); err != nil {
+		t.Fatalf("callInnertube: %v", err)
+	}
+	if header != "CgtWaXNpdG9y" || payload.Context.Client["visitorData"] != "CgtWaXNpdG9y" {
+		t.Errorf("header %q, client context %v", header, payload.Context.Client)
+	}
+}
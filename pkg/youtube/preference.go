@@ -0,0 +1,99 @@
+package youtube
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultCodecPreference is the video codec order used when SelectionPreferences
+// has none: AV1 compresses best, then VP9, then H.264.
+var DefaultCodecPreference = []string{"av01", "vp9", "avc1"}
+
+// SelectionPreferences chooses between video streams of the same height.
+// The zero value prefers SDR, the highest framerate and DefaultCodecPreference.
+type SelectionPreferences struct {
+	// Codecs are video codec families in order of preference, such as "av01",
+	// "vp9" and "avc1" (aliases like "av1" and "h264" are accepted). Codecs not
+	// listed rank after the listed ones. Empty uses DefaultCodecPreference.
+	Codecs []string
+
+	// MaxFramerate ranks streams above this framerate after the others, 0 for no limit.
+	MaxFramerate int
+
+	// HDR prefers HDR streams over SDR ones; by default SDR is preferred, since
+	// HDR video looks washed out on displays and players without HDR support.
+	HDR bool
+}
+
+// codecAliases maps user-facing codec names to the family names in stream codecs.
+var codecAliases = map[string]string{
+	"av1":  "av01",
+	"vp09": "vp9",
+	"h264": "avc1",
+	"avc":  "avc1",
+	"h265": "hev1",
+	"hevc": "hev1",
+	"hvc1": "hev1",
+}
+
+// CodecFamily returns the family of a codec string, such as "av01" for
+// "av01.0.08M.08" or "vp9" for "vp09.00.51.08".
+func CodecFamily(codec string) string {
+	family, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(codec)), ".")
+	if alias, ok := codecAliases[family]; ok {
+		return alias
+	}
+	return family
+}
+
+// ParseCodecPreference parses a comma-separated codec order such as "vp9,avc1".
+func ParseCodecPreference(s string) ([]string, error) {
+	var codecs []string
+	for _, part := range strings.Split(s, ",") {
+		family := CodecFamily(part)
+		if family == "" {
+			return nil, fmt.Errorf("empty codec in %q", s)
+		}
+		codecs = append(codecs, family)
+	}
+	return codecs, nil
+}
+
+// codecRank returns the position of the stream's codec in the preference order,
+// or the number of preferred codecs if it isn't listed.
+func (p SelectionPreferences) codecRank(vs *VideoStreamInfo) int {
+	codecs := p.Codecs
+	if len(codecs) == 0 {
+		codecs = DefaultCodecPreference
+	}
+	family := CodecFamily(vs.VideoCodec)
+	for i, codec := range codecs {
+		if CodecFamily(codec) == family {
+			return i
+		}
+	}
+	return len(codecs)
+}
+
+// better reports whether a is preferred over b, two streams of the same height:
+// by dynamic range, then framerate, then codec, then bitrate.
+func (p SelectionPreferences) better(a, b *VideoStreamInfo) bool {
+	if a.IsHDR != b.IsHDR {
+		return a.IsHDR == p.HDR
+	}
+	if aOK, bOK := p.framerateAllowed(a), p.framerateAllowed(b); aOK != bOK {
+		return aOK
+	}
+	if a.Framerate != b.Framerate {
+		return a.Framerate > b.Framerate
+	}
+	if ra, rb := p.codecRank(a), p.codecRank(b); ra != rb {
+		return ra < rb
+	}
+	return a.Bitrate > b.Bitrate
+}
+
+// framerateAllowed reports whether the stream is within MaxFramerate.
+func (p SelectionPreferences) framerateAllowed(vs *VideoStreamInfo) bool {
+	return p.MaxFramerate <= 0 || vs.Framerate <= p.MaxFramerate
+}
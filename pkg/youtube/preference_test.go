@@ -0,0 +1,85 @@
+package youtube
+
+import (
+	"slices"
+	"testing"
+)
+
+// sameHeightOptions returns 1080p options differing in codec, framerate and dynamic range.
+func sameHeightOptions() []DownloadOption {
+	streams := []VideoStreamInfo{
+		{StreamInfo: StreamInfo{Itag: 137, Bitrate: 4000000}, Height: 1080, Framerate: 30, VideoCodec: "avc1.640028"},
+		{StreamInfo: StreamInfo{Itag: 299, Bitrate: 6000000}, Height: 1080, Framerate: 60, VideoCodec: "avc1.64002a"},
+		{StreamInfo: StreamInfo{Itag: 399, Bitrate: 3000000}, Height: 1080, Framerate: 60, VideoCodec: "av01.0.09M.08"},
+		{StreamInfo: StreamInfo{Itag: 699, Bitrate: 5000000}, Height: 1080, Framerate: 60, VideoCodec: "av01.0.09M.10.0.110.09.16.09.0", IsHDR: true},
+		{StreamInfo: StreamInfo{Itag: 136, Bitrate: 2000000}, Height: 720, Framerate: 30, VideoCodec: "avc1.4d401f"},
+	}
+	options := make([]DownloadOption, len(streams))
+	for i := range streams {
+		options[i] = DownloadOption{Container: ContainerMP4, VideoStream: &streams[i]}
+	}
+	return options
+}
+
+func TestSelectBestOptionWithPreferences(t *testing.T) {
+	tests := []struct {
+		name  string
+		prefs SelectionPreferences
+		want  int
+	}{
+		{"defaults prefer SDR, 60fps and AV1", SelectionPreferences{}, 399},
+		{"HDR", SelectionPreferences{HDR: true}, 699},
+		{"codec order", SelectionPreferences{Codecs: []string{"h264"}}, 299},
+		{"max framerate", SelectionPreferences{MaxFramerate: 30}, 137},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best := SelectBestOptionWithPreferences(sameHeightOptions(), QualityHighest, ContainerMP4, tt.prefs)
+			if best == nil {
+				t.Fatal("expected to find a best option")
+			}
+			if best.VideoStream.Itag != tt.want {
+				t.Errorf("selected itag %d, want %d", best.VideoStream.Itag, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectBestOptionWithPreferences_HeightFirst(t *testing.T) {
+	// Preferences only decide between options of the selected height
+	best := SelectBestOptionWithPreferences(sameHeightOptions(), QualityUpTo720p, ContainerMP4, SelectionPreferences{MaxFramerate: 24})
+	if best == nil || best.VideoStream.Itag != 136 {
+		t.Errorf("selected %+v, want the 720p option", best)
+	}
+}
+
+func TestCodecFamily(t *testing.T) {
+	tests := map[string]string{
+		"av01.0.08M.08": "av01",
+		"vp09.00.51.08": "vp9",
+		"vp9":           "vp9",
+		"avc1.640028":   "avc1",
+		"H264":          "avc1",
+		"av1":           "av01",
+		" hevc ":        "hev1",
+	}
+	for codec, want := range tests {
+		if got := CodecFamily(codec); got != want {
+			t.Errorf("CodecFamily(%q) = %q, want %q", codec, got, want)
+		}
+	}
+}
+
+func TestParseCodecPreference(t *testing.T) {
+	codecs, err := ParseCodecPreference("vp9, h264,av1")
+	if err != nil {
+		t.Fatalf("ParseCodecPreference: %v", err)
+	}
+	if want := []string{"vp9", "avc1", "av01"}; !slices.Equal(codecs, want) {
+		t.Errorf("codecs = %v, want %v", codecs, want)
+	}
+
+	if _, err := ParseCodecPreference("vp9,,avc1"); err == nil {
+		t.Error("expected an error for an empty codec")
+	}
+}
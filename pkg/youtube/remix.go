@@ -0,0 +1,149 @@
+package youtube
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrInitialDataNotFound is returned when ytInitialData is not found in the page.
+var ErrInitialDataNotFound = errors.New("ytInitialData not found in page")
+
+// initialDataPattern matches the assignment that precedes the ytInitialData JSON.
+var initialDataPattern = regexp.MustCompile(`(?:var\s+ytInitialData|window\["ytInitialData"\])\s*=\s*`)
+
+// RemixSource describes the original video a Short remixes or samples.
+type RemixSource struct {
+	// VideoID is the ID of the original video.
+	VideoID string
+
+	// Title is the title of the original video, if YouTube provides it.
+	Title string
+}
+
+// URL returns the watch URL of the original video.
+func (r *RemixSource) URL() string {
+	return fmt.Sprintf("%s/watch?v=%s", youtubeBaseURL, r.VideoID)
+}
+
+// ExtractInitialData extracts the raw ytInitialData JSON from the watch page HTML.
+func (p *WatchPage) ExtractInitialData() (json.RawMessage, error) {
+	return extractInitialData(p.HTML)
+}
+
+// extractInitialData extracts the raw ytInitialData JSON from any YouTube page HTML.
+func extractInitialData(html string) (json.RawMessage, error) {
+	loc := initialDataPattern.FindStringIndex(html)
+	if loc == nil {
+		return nil, ErrInitialDataNotFound
+	}
+
+	jsonStr, err := extractJSONObject(html[loc[1]:])
+	if err != nil {
+		return nil, fmt.Errorf("extracting JSON: %w", err)
+	}
+
+	return json.RawMessage(jsonStr), nil
+}
+
+// ExtractRemixSource returns the original video this page's video remixes,
+// or nil if the page has no remix attribution.
+func (p *WatchPage) ExtractRemixSource() *RemixSource {
+	data, err := p.ExtractInitialData()
+	if err != nil {
+		return nil
+	}
+	return parseRemixSource(data, p.VideoID)
+}
+
+// parseRemixSource finds the remix attribution in ytInitialData.
+//
+// YouTube renders the relationship as a "Remixed from"/"Original" link whose
+// renderer name changes between layouts, so the data is walked generically:
+// the first object whose key mentions remix or attribution and that links to
+// a video other than videoID is taken as the source.
+func parseRemixSource(data json.RawMessage, videoID string) *RemixSource {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil
+	}
+	return findRemixSource(root, videoID, false)
+}
+
+// findRemixSource walks v looking for a linked video inside an attribution node.
+func findRemixSource(v any, videoID string, inAttribution bool) *RemixSource {
+	switch node := v.(type) {
+	case map[string]any:
+		if inAttribution {
+			if id := linkedVideoID(node); id != "" && id != videoID {
+				return &RemixSource{VideoID: id, Title: nodeTitle(node)}
+			}
+		}
+		// Visit keys in a fixed order so repeated parses pick the same link
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if source := findRemixSource(node[key], videoID, inAttribution || isAttributionKey(key)); source != nil {
+				if source.Title == "" && inAttribution {
+					source.Title = nodeTitle(node)
+				}
+				return source
+			}
+		}
+	case []any:
+		for _, child := range node {
+			if source := findRemixSource(child, videoID, inAttribution); source != nil {
+				return source
+			}
+		}
+	}
+	return nil
+}
+
+// isAttributionKey reports whether a renderer key describes a remix relationship.
+func isAttributionKey(key string) bool {
+	key = strings.ToLower(key)
+	return strings.Contains(key, "remix") || strings.Contains(key, "attribution")
+}
+
+// linkedVideoID returns the video ID of a watch or reel endpoint directly on node.
+func linkedVideoID(node map[string]any) string {
+	for _, key := range []string{"watchEndpoint", "reelWatchEndpoint"} {
+		endpoint, ok := node[key].(map[string]any)
+		if !ok {
+			continue
+		}
+		if id, ok := endpoint["videoId"].(string); ok && IsValidVideoID(id) {
+			return id
+		}
+	}
+	return ""
+}
+
+// nodeTitle returns the text of a title field on node in any of YouTube's text formats.
+func nodeTitle(node map[string]any) string {
+	switch title := node["title"].(type) {
+	case string:
+		return title
+	case map[string]any:
+		if s, ok := title["simpleText"].(string); ok {
+			return s
+		}
+		if s, ok := title["content"].(string); ok {
+			return s
+		}
+		if runs, ok := title["runs"].([]any); ok && len(runs) > 0 {
+			if run, ok := runs[0].(map[string]any); ok {
+				s, _ := run["text"].(string)
+				return s
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package youtube
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWatchPage_ExtractInitialData(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+	}{
+		{"var assignment", `<script>var ytInitialData = {"contents":{}};</script>`},
+		{"window assignment", `<script>window["ytInitialData"] = {"contents":{}};</script>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := &WatchPage{VideoID: "dQw4w9WgXcQ", HTML: tt.html}
+			data, err := page.ExtractInitialData()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != `{"contents":{}}` {
+				t.Errorf("got %s", data)
+			}
+		})
+	}
+}
+
+func TestWatchPage_ExtractInitialData_NotFound(t *testing.T) {
+	page := &WatchPage{HTML: "<html></html>"}
+	if _, err := page.ExtractInitialData(); !errors.Is(err, ErrInitialDataNotFound) {
+		t.Errorf("expected ErrInitialDataNotFound, got %v", err)
+	}
+}
+
+func TestWatchPage_ExtractRemixSource(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantID    string
+		wantTitle string
+	}{
+		{
+			name: "reel watch endpoint in remix attribution",
+			data: `{"overlay":{"reelPlayerOverlayRenderer":{"metapanel":{"shortsRemixAttributionViewModel":{
+				"title":{"content":"Original Song"},
+				"onTap":{"innertubeCommand":{"reelWatchEndpoint":{"videoId":"9bZkp7q19f0"}}}}}}}}`,
+			wantID:    "9bZkp7q19f0",
+			wantTitle: "Original Song",
+		},
+		{
+			name: "watch endpoint with runs title",
+			data: `{"engagementPanels":[{"videoAttributionRenderer":{"items":[{
+				"title":{"runs":[{"text":"Source Video"}]},
+				"navigationEndpoint":{"watchEndpoint":{"videoId":"jNQXAC9IVRw"}}}]}}]}`,
+			wantID:    "jNQXAC9IVRw",
+			wantTitle: "Source Video",
+		},
+		{
+			name: "self link is ignored",
+			data: `{"remixRenderer":{"navigationEndpoint":{"watchEndpoint":{"videoId":"dQw4w9WgXcQ"}}}}`,
+		},
+		{
+			name: "links outside attribution are ignored",
+			data: `{"secondaryResults":[{"compactVideoRenderer":{"navigationEndpoint":{"watchEndpoint":{"videoId":"9bZkp7q19f0"}}}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := &WatchPage{
+				VideoID: "dQw4w9WgXcQ",
+				HTML:    `<script>var ytInitialData = ` + tt.data + `;</script>`,
+			}
+			source := page.ExtractRemixSource()
+			if tt.wantID == "" {
+				if source != nil {
+					t.Errorf("expected no remix source, got %+v", source)
+				}
+				return
+			}
+			if source == nil {
+				t.Fatal("expected a remix source")
+			}
+			if source.VideoID != tt.wantID {
+				t.Errorf("VideoID = %q, want %q", source.VideoID, tt.wantID)
+			}
+			if source.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", source.Title, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestRemixSource_URL(t *testing.T) {
+	source := &RemixSource{VideoID: "9bZkp7q19f0"}
+	if got := source.URL(); got != "https://www.youtube.com/watch?v=9bZkp7q19f0" {
+		t.Errorf("URL() = %q", got)
+	}
+}
@@ -1,6 +1,7 @@
 package youtube
 
 import (
+	"context"
 	"errors"
 	"net/url"
 	"strings"
@@ -19,6 +20,8 @@ const (
 	QueryTypePlaylist QueryType = "playlist"
 	// QueryTypeChannel indicates the query resolved to a channel.
 	QueryTypeChannel QueryType = "channel"
+	// QueryTypeClip indicates the query resolved to a clip of a video.
+	QueryTypeClip QueryType = "clip"
 	// QueryTypeSearch indicates the query should be treated as a search.
 	QueryTypeSearch QueryType = "search"
 )
@@ -26,20 +29,61 @@ const (
 // QueryResult contains the resolved query information.
 type QueryResult struct {
 	Type        QueryType
-	VideoID     string
-	PlaylistID  string
+	VideoID     VideoID
+	PlaylistID  PlaylistID
 	Channel     ChannelIdentifier
+	ClipID      string
 	SearchQuery string
 }
 
+// ResolveOptions controls how ResolveQueryWithOptions resolves input.
+type ResolveOptions struct {
+	// Expander expands short and share links that can't be resolved locally.
+	// If nil, redirects aren't followed.
+	Expander *URLExpander
+
+	// DefaultSearch treats input that isn't a URL and can't be resolved
+	// otherwise as a search query, as if it started with "?".
+	DefaultSearch bool
+}
+
+// ResolveQueryWithOptions is like ResolveQuery, with links expanded and raw
+// search terms accepted as opts says.
+func ResolveQueryWithOptions(ctx context.Context, input string, opts ResolveOptions) (QueryResult, error) {
+	result, err := ResolveQuery(input)
+	if err == nil {
+		return result, nil
+	}
+
+	if terms := strings.TrimSpace(input); opts.DefaultSearch && terms != "" && !looksLikeURL(terms) {
+		return QueryResult{Type: QueryTypeSearch, SearchQuery: terms}, nil
+	}
+	return expandQuery(ctx, input, opts.Expander, err)
+}
+
+// looksLikeURL reports whether input is meant as a URL: it has a scheme or
+// starts with a YouTube or redirect host.
+func looksLikeURL(input string) bool {
+	if strings.Contains(input, "://") {
+		return true
+	}
+	_, ok := parseLooseURL(input)
+	return ok
+}
+
 // ResolveQuery analyzes the input and determines what type of YouTube content it refers to.
 // It handles:
 //   - Video URLs and IDs
 //   - Playlist URLs and IDs
 //   - Channel URLs (all formats)
+//   - Clip URLs (youtube.com/clip/ID), resolved to the video with ClipFetcher
 //   - Search queries (prefixed with ?)
 //
-// Priority order: Search (?) > Video > Playlist > Channel
+// URLs are canonicalized with CanonicalizeURL first. Links that need a network
+// round trip to expand are handled by ResolveQueryContext, and search terms
+// without the ? prefix by ResolveQueryWithOptions.
+//
+// Priority order: Search (?) > Clip > Video > Playlist > Channel
 func ResolveQuery(input string) (QueryResult, error) {
 	input = strings.TrimSpace(input)
 	if input == "" {
@@ -58,6 +102,17 @@ func ResolveQuery(input string) (QueryResult, error) {
 		}, nil
 	}
 
+	// Normalize share links, aliases and redirect wrappers before parsing
+	input = CanonicalizeURL(input)
+
+	// Clip URLs name the clip, not the video it was cut from
+	if clipID, err := ParseClipID(input); err == nil {
+		return QueryResult{
+			Type:   QueryTypeClip,
+			ClipID: clipID,
+		}, nil
+	}
+
 	// Try to parse as URL to check for combined video+playlist
 	if parsedURL, err := url.Parse(input); err == nil && isYouTubeHost(parsedURL.Host) {
 		// Check for watch URL with both video and playlist
@@ -68,11 +123,11 @@ func ResolveQuery(input string) (QueryResult, error) {
 			if IsValidVideoID(videoID) {
 				result := QueryResult{
 					Type:    QueryTypeVideo,
-					VideoID: videoID,
+					VideoID: VideoID(videoID),
 				}
 				// Include playlist context if present
 				if IsValidPlaylistID(playlistID) {
-					result.PlaylistID = playlistID
+					result.PlaylistID = PlaylistID(playlistID)
 				}
 				return result, nil
 			}
@@ -1,6 +1,8 @@
 package youtube
 
 import (
+	"context"
+	"errors"
 	"testing"
 )
 
@@ -31,7 +33,7 @@ func TestResolveQuery_Video(t *testing.T) {
 func TestResolveQuery_Playlist(t *testing.T) {
 	tests := []struct {
 		input      string
-		playlistID string
+		playlistID PlaylistID
 	}{
 		{"https://www.youtube.com/playlist?list=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf", "PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"},
 		{"PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf", "PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"},
@@ -109,6 +111,43 @@ func TestResolveQuery_Search(t *testing.T) {
 	}
 }
 
+func TestResolveQueryWithOptions_DefaultSearch(t *testing.T) {
+	tests := []struct {
+		input       string
+		wantType    QueryType
+		searchQuery string
+	}{
+		{"  rick astley never gonna ", QueryTypeSearch, "rick astley never gonna"},
+		{"?lofi", QueryTypeSearch, "lofi"},
+		// IDs and URLs still resolve as before
+		{"dQw4w9WgXcQ", QueryTypeVideo, ""},
+		{"https://www.youtube.com/@MrBeast", QueryTypeChannel, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ResolveQueryWithOptions(context.Background(), tt.input, ResolveOptions{DefaultSearch: true})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Type != tt.wantType || result.SearchQuery != tt.searchQuery {
+				t.Errorf("result = %+v, want %s %q", result, tt.wantType, tt.searchQuery)
+			}
+		})
+	}
+
+	// Broken URLs aren't searched for
+	for _, input := range []string{"", "https://example.com/watch", "www.youtube.com/watch?v=short", "youtu.be/x"} {
+		if _, err := ResolveQueryWithOptions(context.Background(), input, ResolveOptions{DefaultSearch: true}); !errors.Is(err, ErrUnresolvableQuery) {
+			t.Errorf("ResolveQueryWithOptions(%q) error = %v, want ErrUnresolvableQuery", input, err)
+		}
+	}
+
+	// Without DefaultSearch, raw terms stay unresolvable
+	if _, err := ResolveQueryWithOptions(context.Background(), "rick astley", ResolveOptions{}); !errors.Is(err, ErrUnresolvableQuery) {
+		t.Errorf("error = %v, want ErrUnresolvableQuery", err)
+	}
+}
+
 func TestResolveQuery_VideoWithPlaylist(t *testing.T) {
 	// When a URL contains both video and playlist, prioritize video
 	input := "https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"
@@ -145,3 +184,58 @@ func TestResolveQuery_Invalid(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveQuery_Clip(t *testing.T) {
+	tests := []string{
+		"https://www.youtube.com/clip/UgkxU2HSeGL_NvmDJ-nQJrlLwllwMDBdGZFs",
+		"https://youtube.com/clip/UgkxU2HSeGL_NvmDJ-nQJrlLwllwMDBdGZFs?si=AbCdEf",
+		"m.youtube.com/clip/UgkxU2HSeGL_NvmDJ-nQJrlLwllwMDBdGZFs",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			result, err := ResolveQuery(tt)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Type != QueryTypeClip {
+				t.Errorf("expected QueryTypeClip, got %v", result.Type)
+			}
+			if result.ClipID != "UgkxU2HSeGL_NvmDJ-nQJrlLwllwMDBdGZFs" {
+				t.Errorf("expected clip ID, got %q", result.ClipID)
+			}
+		})
+	}
+}
+
+func TestResolveQuery_HostVariants(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantType QueryType
+	}{
+		{"https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ", QueryTypeVideo},
+		{"youtube-nocookie.com/embed/dQw4w9WgXcQ", QueryTypeVideo},
+		{"https://m.youtube.com/watch?v=dQw4w9WgXcQ", QueryTypeVideo},
+		{"https://m.youtube.com/shorts/dQw4w9WgXcQ", QueryTypeVideo},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ", QueryTypeVideo},
+		{"https://yewtu.be/watch?v=dQw4w9WgXcQ", QueryTypeVideo},
+		{"https://m.youtube.com/playlist?list=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf", QueryTypePlaylist},
+		{"https://music.youtube.com/playlist?list=PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf", QueryTypePlaylist},
+		{"https://m.youtube.com/@handle", QueryTypeChannel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ResolveQuery(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Type != tt.wantType {
+				t.Errorf("expected %v, got %v", tt.wantType, result.Type)
+			}
+			if tt.wantType == QueryTypeVideo && result.VideoID != "dQw4w9WgXcQ" {
+				t.Errorf("expected video ID 'dQw4w9WgXcQ', got %q", result.VideoID)
+			}
+		})
+	}
+}
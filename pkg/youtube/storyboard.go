@@ -0,0 +1,223 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidStoryboardSpec is returned when a storyboard spec can't be parsed.
+var ErrInvalidStoryboardSpec = errors.New("invalid storyboard spec")
+
+// StoryboardsResponse holds the storyboard spec of the player response.
+type StoryboardsResponse struct {
+	PlayerStoryboardSpecRenderer *struct {
+		Spec string `json:"spec"`
+	} `json:"playerStoryboardSpecRenderer,omitempty"`
+}
+
+// Storyboard is one resolution of the preview frames YouTube shows while
+// seeking. The frames are tiled in rows on sheets, images of Columns by Rows
+// frames each, read left to right and top to bottom.
+type Storyboard struct {
+	// Width and Height are the size of one frame in pixels.
+	Width  int
+	Height int
+
+	// Count is the number of frames.
+	Count int
+
+	// Columns and Rows are how many frames a sheet holds across and down.
+	Columns int
+	Rows    int
+
+	// Interval is the time between frames.
+	Interval time.Duration
+
+	// URLs are the sheets' URLs in order. The last sheet may be partly empty.
+	URLs []string
+}
+
+// FramesPerSheet returns how many frames a full sheet holds.
+func (s *Storyboard) FramesPerSheet() int {
+	return s.Columns * s.Rows
+}
+
+// FrameTime returns the time in the video that frame i, counting from 0 over
+// all sheets, shows.
+func (s *Storyboard) FrameTime(i int) time.Duration {
+	return time.Duration(i) * s.Interval
+}
+
+// frameRect returns where frame i of its sheet is in the sheet.
+func (s *Storyboard) frameRect(i int) image.Rectangle {
+	col, row := i%s.Columns, i/s.Columns
+	return image.Rect(col*s.Width, row*s.Height, (col+1)*s.Width, (row+1)*s.Height)
+}
+
+// ExtractStoryboards returns the video's storyboards from the lowest to the
+// highest resolution, or nil if it has none.
+func (pr *PlayerResponse) ExtractStoryboards(duration time.Duration) []Storyboard {
+	if pr.Storyboards == nil || pr.Storyboards.PlayerStoryboardSpecRenderer == nil {
+		return nil
+	}
+	storyboards, err := ParseStoryboardSpec(pr.Storyboards.PlayerStoryboardSpecRenderer.Spec, duration)
+	if err != nil {
+		return nil
+	}
+	return storyboards
+}
+
+// ParseStoryboardSpec parses the storyboard spec of a player response: a sheet
+// URL template followed by one "width#height#count#columns#rows#interval#name#sigh"
+// level per resolution, separated by "|". In the template $L is the level and
+// $N the sheet name, whose $M is the sheet number. Levels with an interval of 0
+// spread their frames evenly over duration.
+func ParseStoryboardSpec(spec string, duration time.Duration) ([]Storyboard, error) {
+	parts := strings.Split(spec, "|")
+	if len(parts) < 2 || !strings.Contains(parts[0], "$N") {
+		return nil, fmt.Errorf("%w: no levels", ErrInvalidStoryboardSpec)
+	}
+
+	storyboards := make([]Storyboard, 0, len(parts)-1)
+	for level, levelSpec := range parts[1:] {
+		fields := strings.Split(levelSpec, "#")
+		if len(fields) < 8 {
+			return nil, fmt.Errorf("%w: level %d has %d fields", ErrInvalidStoryboardSpec, level, len(fields))
+		}
+		var numbers [6]int
+		for i := range numbers {
+			n, err := strconv.Atoi(fields[i])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%w: level %d: %q is not a number", ErrInvalidStoryboardSpec, level, fields[i])
+			}
+			numbers[i] = n
+		}
+		s := Storyboard{
+			Width: numbers[0], Height: numbers[1], Count: numbers[2],
+			Columns: numbers[3], Rows: numbers[4],
+			Interval: time.Duration(numbers[5]) * time.Millisecond,
+		}
+		if s.Width == 0 || s.Height == 0 || s.Count == 0 || s.Columns == 0 || s.Rows == 0 {
+			// Placeholder levels list no frames
+			continue
+		}
+		if s.Interval == 0 {
+			s.Interval = duration / time.Duration(s.Count)
+		}
+
+		name, sigh := fields[6], fields[7]
+		sheets := 1
+		if strings.Contains(name, "$M") {
+			sheets = (s.Count + s.FramesPerSheet() - 1) / s.FramesPerSheet()
+		}
+		base := strings.ReplaceAll(parts[0], "$L", strconv.Itoa(level))
+		for sheet := range sheets {
+			sheetURL := strings.ReplaceAll(base, "$N", strings.ReplaceAll(name, "$M", strconv.Itoa(sheet)))
+			s.URLs = append(s.URLs, withQueryParam(sheetURL, "sigh", sigh))
+		}
+		storyboards = append(storyboards, s)
+	}
+	return storyboards, nil
+}
+
+// withQueryParam adds key=value to the query of rawURL, unless value is empty.
+func withQueryParam(rawURL, key, value string) string {
+	if value == "" {
+		return rawURL
+	}
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + key + "=" + url.QueryEscape(value)
+}
+
+// StoryboardFrame is one preview frame cut from a storyboard sheet.
+type StoryboardFrame struct {
+	// Index is the frame's number over all sheets, counting from 0.
+	Index int
+
+	// Time is the time in the video the frame shows.
+	Time time.Duration
+
+	// Image is the frame.
+	Image image.Image
+}
+
+// SplitStoryboardSheet cuts the frames out of sheet number n of a storyboard,
+// a JPEG image.
+func SplitStoryboardSheet(s *Storyboard, n int, sheet []byte) ([]StoryboardFrame, error) {
+	img, err := jpeg.Decode(bytes.NewReader(sheet))
+	if err != nil {
+		return nil, fmt.Errorf("decoding storyboard sheet: %w", err)
+	}
+	sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, fmt.Errorf("storyboard sheet is a %T, which can't be cut", img)
+	}
+
+	first := n * s.FramesPerSheet()
+	count := min(s.FramesPerSheet(), s.Count-first)
+	frames := make([]StoryboardFrame, 0, max(count, 0))
+	for i := range count {
+		rect := s.frameRect(i).Add(img.Bounds().Min)
+		if !rect.In(img.Bounds()) {
+			// Sheets of the last frames can be cropped short
+			break
+		}
+		frames = append(frames, StoryboardFrame{Index: first + i, Time: s.FrameTime(first + i), Image: sub.SubImage(rect)})
+	}
+	return frames, nil
+}
+
+// StoryboardDownloader downloads storyboard sheets.
+type StoryboardDownloader struct {
+	// Client is the HTTP client to use for requests.
+	Client *http.Client
+}
+
+// NewStoryboardDownloader creates a new StoryboardDownloader with the given HTTP client.
+func NewStoryboardDownloader(client *http.Client) *StoryboardDownloader {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &StoryboardDownloader{Client: client}
+}
+
+// Download fetches sheet number n of a storyboard.
+func (d *StoryboardDownloader) Download(ctx context.Context, s *Storyboard, n int) ([]byte, error) {
+	if n < 0 || n >= len(s.URLs) {
+		return nil, fmt.Errorf("storyboard has no sheet %d", n)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URLs[n], http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching storyboard sheet: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching storyboard sheet: unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading storyboard sheet: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,158 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testStoryboardSpec = "https://i.ytimg.com/sb/dQw4w9WgXcQ/storyboard3_L$L/$N.jpg?sqp=abc" +
+	"|48#27#100#10#10#0#default#rs$AOn4" +
+	"|80#45#30#5#5#2000#M$M#rs$AOn5" +
+	"|0#0#0#0#0#0#M$M#"
+
+func TestParseStoryboardSpec(t *testing.T) {
+	storyboards, err := ParseStoryboardSpec(testStoryboardSpec, 50*time.Second)
+	if err != nil {
+		t.Fatalf("ParseStoryboardSpec failed: %v", err)
+	}
+	if len(storyboards) != 2 {
+		t.Fatalf("got %d storyboards, want 2 (placeholder levels must be skipped)", len(storyboards))
+	}
+
+	low := storyboards[0]
+	if low.Width != 48 || low.Height != 27 || low.Count != 100 || low.FramesPerSheet() != 100 {
+		t.Errorf("low storyboard = %+v", low)
+	}
+	if low.Interval != 500*time.Millisecond {
+		t.Errorf("low interval = %v, want frames spread over the duration", low.Interval)
+	}
+	if len(low.URLs) != 1 || low.URLs[0] != "https://i.ytimg.com/sb/dQw4w9WgXcQ/storyboard3_L0/default.jpg?sqp=abc&sigh=rs%24AOn4" {
+		t.Errorf("low URLs = %q", low.URLs)
+	}
+
+	high := storyboards[1]
+	if high.Interval != 2*time.Second || high.FrameTime(3) != 6*time.Second {
+		t.Errorf("high interval = %v, frame 3 at %v", high.Interval, high.FrameTime(3))
+	}
+	want := []string{
+		"https://i.ytimg.com/sb/dQw4w9WgXcQ/storyboard3_L1/M0.jpg?sqp=abc&sigh=rs%24AOn5",
+		"https://i.ytimg.com/sb/dQw4w9WgXcQ/storyboard3_L1/M1.jpg?sqp=abc&sigh=rs%24AOn5",
+	}
+	if len(high.URLs) != len(want) || high.URLs[0] != want[0] || high.URLs[1] != want[1] {
+		t.Errorf("high URLs = %q, want %q", high.URLs, want)
+	}
+}
+
+func TestParseStoryboardSpec_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "https://i.ytimg.com/sb/x.jpg|48#27#100#10#10#0#default#", "https://i.ytimg.com/$N.jpg|48#27", "https://i.ytimg.com/$N.jpg|a#27#100#10#10#0#default#"} {
+		if _, err := ParseStoryboardSpec(spec, time.Minute); !errors.Is(err, ErrInvalidStoryboardSpec) {
+			t.Errorf("ParseStoryboardSpec(%q) error = %v, want ErrInvalidStoryboardSpec", spec, err)
+		}
+	}
+}
+
+func TestExtractStoryboards(t *testing.T) {
+	pr := &PlayerResponse{}
+	if got := pr.ExtractStoryboards(time.Minute); got != nil {
+		t.Errorf("ExtractStoryboards without a spec = %+v, want nil", got)
+	}
+	pr.Storyboards = &StoryboardsResponse{PlayerStoryboardSpecRenderer: &struct {
+		Spec string `json:"spec"`
+	}{Spec: testStoryboardSpec}}
+	if got := pr.ExtractStoryboards(time.Minute); len(got) != 2 {
+		t.Errorf("ExtractStoryboards returned %d storyboards, want 2", len(got))
+	}
+}
+
+// testSheet encodes a sheet of cols by rows frames of w by h pixels, each
+// filled with a gray level of its number times 20.
+func testSheet(t *testing.T, w, h, cols, rows int) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, w*cols, h*rows))
+	for y := range h * rows {
+		for x := range w * cols {
+			img.SetGray(x, y, color.Gray{Y: uint8((y/h*cols + x/w) * 20)})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSplitStoryboardSheet(t *testing.T) {
+	s := &Storyboard{Width: 16, Height: 16, Count: 7, Columns: 2, Rows: 2, Interval: time.Second}
+
+	frames, err := SplitStoryboardSheet(s, 1, testSheet(t, 16, 16, 2, 2))
+	if err != nil {
+		t.Fatalf("SplitStoryboardSheet failed: %v", err)
+	}
+	if len(frames) != 3 {
+		t.Fatalf("got %d frames, want the 3 left on the last sheet", len(frames))
+	}
+	for i, frame := range frames {
+		if frame.Index != 4+i || frame.Time != time.Duration(4+i)*time.Second {
+			t.Errorf("frame %d = index %d at %v", i, frame.Index, frame.Time)
+		}
+		if b := frame.Image.Bounds(); b.Dx() != 16 || b.Dy() != 16 {
+			t.Errorf("frame %d is %v", i, b)
+		}
+		center := frame.Image.Bounds().Min.Add(image.Pt(8, 8))
+		if y := int(color.GrayModel.Convert(frame.Image.At(center.X, center.Y)).(color.Gray).Y); y < i*20-4 || y > i*20+4 {
+			t.Errorf("frame %d has gray %d, want about %d", i, y, i*20)
+		}
+	}
+
+	if _, err := SplitStoryboardSheet(s, 0, []byte("not a jpeg")); err == nil {
+		t.Error("SplitStoryboardSheet of a non-JPEG sheet succeeded")
+	}
+}
+
+func TestSplitStoryboardSheet_Cropped(t *testing.T) {
+	s := &Storyboard{Width: 16, Height: 16, Count: 4, Columns: 2, Rows: 2}
+
+	frames, err := SplitStoryboardSheet(s, 0, testSheet(t, 16, 16, 2, 1))
+	if err != nil {
+		t.Fatalf("SplitStoryboardSheet failed: %v", err)
+	}
+	if len(frames) != 2 {
+		t.Errorf("got %d frames, want the 2 the cropped sheet holds", len(frames))
+	}
+}
+
+func TestStoryboardDownloader_Download(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/M1.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("sheet"))
+	}))
+	defer server.Close()
+
+	s := &Storyboard{URLs: []string{server.URL + "/M0.jpg", server.URL + "/M1.jpg"}}
+	d := NewStoryboardDownloader(server.Client())
+
+	data, err := d.Download(context.Background(), s, 1)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if string(data) != "sheet" {
+		t.Errorf("Download = %q, want %q", data, "sheet")
+	}
+	if _, err := d.Download(context.Background(), s, 0); err == nil {
+		t.Error("Download of a missing sheet succeeded")
+	}
+	if _, err := d.Download(context.Background(), s, 2); err == nil {
+		t.Error("Download past the last sheet succeeded")
+	}
+}
@@ -1,6 +1,11 @@
 package youtube
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Container represents a media container format (e.g., mp4, webm).
 type Container string
@@ -11,12 +16,24 @@ const (
 	ContainerWebM Container = "webm"
 	ContainerMP3  Container = "mp3"
 	ContainerOGG  Container = "ogg"
+	ContainerOpus Container = "opus"
+	ContainerFLAC Container = "flac"
+	ContainerWAV  Container = "wav"
 	ContainerMKV  Container = "mkv"
 	Container3GP  Container = "3gp"
 )
 
+// AcceptsAnyCodec reports whether the container can hold any combination of
+// video and audio codecs, so streams can be copied into it from any source container.
+func (c Container) AcceptsAnyCodec() bool {
+	return c == ContainerMKV
+}
+
 // StreamInfo contains common information about a media stream.
 type StreamInfo struct {
+	// Itag is YouTube's format identifier for the stream.
+	Itag int
+
 	// URL is the direct URL to download the stream.
 	URL string
 
@@ -40,6 +57,120 @@ type StreamInfo struct {
 
 	// ContentLength is the content length in bytes.
 	ContentLength int64
+
+	// AverageBitrate is the stream's average bitrate in bits per second
+	// (may be 0 if unknown). Bitrate is the peak rate.
+	AverageBitrate int64
+
+	// ApproxDuration is the stream's duration as YouTube reports it (may be 0 if unknown).
+	ApproxDuration time.Duration
+
+	// DRMProtected indicates the stream is encrypted with DRM, so a download of it won't play.
+	DRMProtected bool
+
+	// Premium indicates a high bitrate stream offered to YouTube Premium members.
+	// It only has a URL when the video was fetched as a member.
+	Premium bool
+
+	// InitRange is the byte range of the stream's initialization segment, nil
+	// if YouTube doesn't say, as it doesn't for muxed streams.
+	InitRange *ByteRange
+
+	// IndexRange is the byte range of the stream's segment index, the sidx box
+	// of MP4 streams or the Cues of WebM ones, nil if YouTube doesn't say.
+	IndexRange *ByteRange
+
+	// LastModified is when the stream was last modified (zero if unknown).
+	LastModified time.Time
+
+	// Raw is the format as YouTube describes it, for the fields the stream
+	// doesn't have. It is nil for streams that weren't parsed from a format.
+	Raw *FormatResponse
+}
+
+// ByteRange is an inclusive range of bytes of a stream, as in an HTTP Range header.
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+// Length returns the number of bytes in the range.
+func (r ByteRange) Length() int64 {
+	return r.End - r.Start + 1
+}
+
+// String formats the range as in an HTTP Range header, as in "0-740".
+func (r ByteRange) String() string {
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// InaccessibleReason returns why the stream can't be downloaded, or "" if it can.
+func (s *StreamInfo) InaccessibleReason() string {
+	switch {
+	case s.DRMProtected:
+		return "is DRM protected"
+	case s.Premium && s.URL == "":
+		return "requires YouTube Premium"
+	default:
+		return ""
+	}
+}
+
+// IsDownloadable reports whether the stream can be downloaded; see InaccessibleReason.
+func (s *StreamInfo) IsDownloadable() bool {
+	return s.InaccessibleReason() == ""
+}
+
+// AccessError returns an *InaccessibleFormatError when the stream can't be
+// downloaded, or nil if it can.
+func (s *StreamInfo) AccessError() error {
+	reason := s.InaccessibleReason()
+	if reason == "" {
+		return nil
+	}
+	return &InaccessibleFormatError{Quality: s.Quality, Itag: s.Itag, Reason: reason}
+}
+
+// InaccessibleFormatError is returned when the requested quality is only
+// offered in a format that can't be downloaded.
+type InaccessibleFormatError struct {
+	// Quality is the quality label of the format, such as "1080p Premium".
+	Quality string
+
+	// Itag is the format's itag.
+	Itag int
+
+	// Reason says why the format can't be downloaded, such as "requires YouTube Premium".
+	Reason string
+}
+
+func (e *InaccessibleFormatError) Error() string {
+	if e.Quality == "" {
+		return fmt.Sprintf("requested quality is only available in format %d, which %s", e.Itag, e.Reason)
+	}
+	return fmt.Sprintf("requested quality is only available in format %d (%s), which %s", e.Itag, e.Quality, e.Reason)
+}
+
+// EstimatedSize returns the stream's size in bytes: the content length when
+// YouTube reports one, which many adaptive formats omit, and otherwise the
+// average bitrate times duration. A zero duration uses the stream's ApproxDuration.
+func (s *StreamInfo) EstimatedSize(duration time.Duration) int64 {
+	if s.ContentLength > 0 {
+		return s.ContentLength
+	}
+	if duration <= 0 {
+		duration = s.ApproxDuration
+	}
+	bitrate := s.AverageBitrate
+	if bitrate <= 0 {
+		bitrate = s.Bitrate
+	}
+	return int64(float64(bitrate) / 8 * duration.Seconds())
+}
+
+// SizeIsExact reports whether the stream's size is known rather than estimated.
+func (s *StreamInfo) SizeIsExact() bool {
+	return s.ContentLength > 0
 }
 
 // VideoStreamInfo contains information about a video-only stream.
@@ -57,6 +188,13 @@ type VideoStreamInfo struct {
 
 	// VideoCodec is the video codec (e.g., "avc1.640028", "vp9").
 	VideoCodec string
+
+	// IsHDR indicates a high dynamic range (HDR10 or HLG) stream.
+	IsHDR bool
+
+	// ProjectionType is how the video is projected, "RECTANGULAR" for most
+	// and "EQUIRECTANGULAR" or "MESH" for 360° videos (may be empty).
+	ProjectionType string
 }
 
 // IsVideoOnly returns true (video streams are video-only by definition).
@@ -80,6 +218,10 @@ type AudioStreamInfo struct {
 	// AudioLanguage is the language of the audio track (may be empty).
 	AudioLanguage string
 
+	// AudioTrackName is the name YouTube shows for the audio track, such as
+	// "English (United States) original" (may be empty).
+	AudioTrackName string
+
 	// IsDefault indicates if this is the default audio track.
 	IsDefault bool
 }
@@ -129,36 +271,72 @@ type StreamManifest struct {
 	MuxedStreams []MuxedStreamInfo
 }
 
-// GetBestVideoStream returns the highest quality video stream.
+// GetBestVideoStream returns the highest quality video stream that can be
+// downloaded, or nil if the manifest has none.
 func (m *StreamManifest) GetBestVideoStream() *VideoStreamInfo {
-	if len(m.VideoStreams) == 0 {
-		return nil
-	}
-
-	best := &m.VideoStreams[0]
+	var best *VideoStreamInfo
 	for i := range m.VideoStreams {
-		if m.VideoStreams[i].Height > best.Height {
-			best = &m.VideoStreams[i]
+		vs := &m.VideoStreams[i]
+		if vs.IsDownloadable() && (best == nil || vs.Height > best.Height) {
+			best = vs
 		}
 	}
 	return best
 }
 
-// GetBestAudioStream returns the highest quality audio stream.
+// GetBestAudioStream returns the highest quality audio stream that can be
+// downloaded, or nil if the manifest has none.
 func (m *StreamManifest) GetBestAudioStream() *AudioStreamInfo {
-	if len(m.AudioStreams) == 0 {
-		return nil
+	var best *AudioStreamInfo
+	for i := range m.AudioStreams {
+		as := &m.AudioStreams[i]
+		if as.IsDownloadable() && (best == nil || as.Bitrate > best.Bitrate) {
+			best = as
+		}
 	}
+	return best
+}
 
-	best := &m.AudioStreams[0]
+// GetBestOpusAudioStream returns the highest quality Opus audio stream that
+// can be downloaded, or nil if the manifest has none.
+func (m *StreamManifest) GetBestOpusAudioStream() *AudioStreamInfo {
+	var best *AudioStreamInfo
 	for i := range m.AudioStreams {
-		if m.AudioStreams[i].Bitrate > best.Bitrate {
-			best = &m.AudioStreams[i]
+		as := &m.AudioStreams[i]
+		if as.IsDownloadable() && strings.EqualFold(as.AudioCodec, "opus") && (best == nil || as.Bitrate > best.Bitrate) {
+			best = as
 		}
 	}
 	return best
 }
 
+// VideoStreamByItag returns the video-only or muxed stream with the given itag,
+// or nil if the manifest has none.
+func (m *StreamManifest) VideoStreamByItag(itag int) *VideoStreamInfo {
+	for i := range m.VideoStreams {
+		if m.VideoStreams[i].Itag == itag {
+			return &m.VideoStreams[i]
+		}
+	}
+	for i := range m.MuxedStreams {
+		if m.MuxedStreams[i].VideoStreamInfo.Itag == itag {
+			return &m.MuxedStreams[i].VideoStreamInfo
+		}
+	}
+	return nil
+}
+
+// AudioStreamByItag returns the audio-only stream with the given itag,
+// or nil if the manifest has none.
+func (m *StreamManifest) AudioStreamByItag(itag int) *AudioStreamInfo {
+	for i := range m.AudioStreams {
+		if m.AudioStreams[i].Itag == itag {
+			return &m.AudioStreams[i]
+		}
+	}
+	return nil
+}
+
 // DownloadOption represents a single download option combining video and/or audio streams.
 type DownloadOption struct {
 	// Container is the output container format.
@@ -188,9 +366,67 @@ func (o *DownloadOption) QualityLabel() string {
 	return ""
 }
 
+// EstimatedSize returns the combined size of the option's streams in bytes;
+// see StreamInfo.EstimatedSize.
+func (o *DownloadOption) EstimatedSize(duration time.Duration) int64 {
+	var total int64
+	if o.VideoStream != nil {
+		total += o.VideoStream.EstimatedSize(duration)
+	}
+	if o.AudioStream != nil {
+		total += o.AudioStream.EstimatedSize(duration)
+	}
+	return total
+}
+
+// IsDownloadable reports whether all the option's streams can be downloaded.
+func (o *DownloadOption) IsDownloadable() bool {
+	return o.AccessError() == nil
+}
+
+// AccessError returns the *InaccessibleFormatError of the first of the
+// option's streams that can't be downloaded, or nil if they all can.
+func (o *DownloadOption) AccessError() error {
+	if o.VideoStream != nil {
+		if err := o.VideoStream.AccessError(); err != nil {
+			return err
+		}
+	}
+	if o.AudioStream != nil {
+		return o.AudioStream.AccessError()
+	}
+	return nil
+}
+
+// SizeIsExact reports whether the sizes of all the option's streams are known.
+func (o *DownloadOption) SizeIsExact() bool {
+	if o.VideoStream != nil && !o.VideoStream.SizeIsExact() {
+		return false
+	}
+	// The audio of a muxed option is inside the video stream
+	if o.AudioStream != nil && o.AudioStream.URL != "" && !o.AudioStream.SizeIsExact() {
+		return false
+	}
+	return true
+}
+
 // GetDownloadOptions generates all available download options from the stream manifest.
-// It creates video+audio combinations and audio-only options.
+// It creates video+audio combinations and audio-only options. Streams that
+// can't be downloaded, being DRM protected or for Premium members only, are left out.
 func (m *StreamManifest) GetDownloadOptions() []DownloadOption {
+	return m.downloadOptions(false)
+}
+
+// GetAllDownloadOptions generates the download options like GetDownloadOptions,
+// including those with streams that can't be downloaded.
+func (m *StreamManifest) GetAllDownloadOptions() []DownloadOption {
+	return m.downloadOptions(true)
+}
+
+// downloadOptions generates the download options, leaving out the streams
+// that can't be downloaded unless inaccessible is set. Videos are always
+// paired with audio that can be downloaded.
+func (m *StreamManifest) downloadOptions(inaccessible bool) []DownloadOption {
 	var options []DownloadOption
 
 	// Find the best audio stream for each container type
@@ -200,6 +436,9 @@ func (m *StreamManifest) GetDownloadOptions() []DownloadOption {
 	// Generate video+audio options from adaptive formats
 	for i := range m.VideoStreams {
 		vs := &m.VideoStreams[i]
+		if !inaccessible && !vs.IsDownloadable() {
+			continue
+		}
 
 		// Find compatible audio stream (prefer same container)
 		var audioStream *AudioStreamInfo
@@ -234,6 +473,9 @@ func (m *StreamManifest) GetDownloadOptions() []DownloadOption {
 	// Generate video+audio options from muxed streams
 	for i := range m.MuxedStreams {
 		ms := &m.MuxedStreams[i]
+		if !inaccessible && !ms.VideoStreamInfo.IsDownloadable() {
+			continue
+		}
 		options = append(options, DownloadOption{
 			Container:   ms.VideoStreamInfo.Container,
 			IsAudioOnly: false,
@@ -245,6 +487,9 @@ func (m *StreamManifest) GetDownloadOptions() []DownloadOption {
 	// Generate audio-only options
 	for i := range m.AudioStreams {
 		as := &m.AudioStreams[i]
+		if !inaccessible && !as.IsDownloadable() {
+			continue
+		}
 		options = append(options, DownloadOption{
 			Container:   as.Container,
 			IsAudioOnly: true,
@@ -256,12 +501,54 @@ func (m *StreamManifest) GetDownloadOptions() []DownloadOption {
 	return options
 }
 
-// findBestAudioByContainer finds the highest bitrate audio stream with the specified container.
+// AdaptForContainer returns a copy of the option targeting the given output container.
+// For containers that accept any codec (MKV), the best audio stream in the manifest
+// is used regardless of its source container. Muxed options are returned unchanged
+// apart from the container, since their audio track is embedded in the video stream.
+func (m *StreamManifest) AdaptForContainer(option *DownloadOption, container Container) *DownloadOption {
+	if option == nil {
+		return nil
+	}
+
+	adapted := *option
+	if !container.AcceptsAnyCodec() {
+		return &adapted
+	}
+
+	adapted.Container = container
+	if adapted.AudioStream != nil && adapted.AudioStream.URL != "" {
+		if best := m.GetBestAudioStream(); best != nil {
+			adapted.AudioStream = best
+		}
+	}
+	return &adapted
+}
+
+// InaccessibleBetterOption returns the InaccessibleFormatError of the option
+// SelectBestOptionWithPreferences picks for the quality when streams that can't
+// be downloaded are included, if it is one of them and taller than selected,
+// the option picked from the downloadable ones, which may be nil. Otherwise it
+// returns nil: the selected option is as good as the quality gets.
+func (m *StreamManifest) InaccessibleBetterOption(selected *DownloadOption, quality VideoQualityPreference, preferredContainer Container, prefs SelectionPreferences) *InaccessibleFormatError {
+	best := SelectBestOptionWithPreferences(m.GetAllDownloadOptions(), quality, preferredContainer, prefs)
+	if best == nil || best.IsDownloadable() {
+		return nil
+	}
+	if selected != nil && selected.VideoStream != nil && selected.VideoStream.Height >= best.VideoStream.Height {
+		return nil
+	}
+	var err *InaccessibleFormatError
+	errors.As(best.AccessError(), &err)
+	return err
+}
+
+// findBestAudioByContainer finds the highest bitrate audio stream with the
+// specified container that can be downloaded.
 func (m *StreamManifest) findBestAudioByContainer(container Container) *AudioStreamInfo {
 	var best *AudioStreamInfo
 	for i := range m.AudioStreams {
 		as := &m.AudioStreams[i]
-		if as.Container == container {
+		if as.Container == container && as.IsDownloadable() {
 			if best == nil || as.Bitrate > best.Bitrate {
 				best = as
 			}
@@ -323,6 +610,12 @@ func (p VideoQualityPreference) MaxHeight() int {
 // SelectBestOption selects the best download option based on quality and container preferences.
 // It returns nil if no suitable option is found.
 func SelectBestOption(options []DownloadOption, quality VideoQualityPreference, preferredContainer Container) *DownloadOption {
+	return SelectBestOptionWithPreferences(options, quality, preferredContainer, SelectionPreferences{})
+}
+
+// SelectBestOptionWithPreferences selects the best download option like
+// SelectBestOption, choosing between options of the same height by prefs.
+func SelectBestOptionWithPreferences(options []DownloadOption, quality VideoQualityPreference, preferredContainer Container, prefs SelectionPreferences) *DownloadOption {
 	if len(options) == 0 {
 		return nil
 	}
@@ -412,13 +705,26 @@ func SelectBestOption(options []DownloadOption, quality VideoQualityPreference,
 		return nil
 	}
 
-	// Prefer the specified container
-	for i := range filteredOptions {
-		if filteredOptions[i].Container == preferredContainer {
-			return &filteredOptions[i]
+	// Matroska can hold any codec pair, so it takes the best video regardless
+	// of which container the source stream comes in
+	candidates := filteredOptions
+	if !preferredContainer.AcceptsAnyCodec() {
+		var inContainer []DownloadOption
+		for i := range filteredOptions {
+			if filteredOptions[i].Container == preferredContainer {
+				inContainer = append(inContainer, filteredOptions[i])
+			}
+		}
+		if len(inContainer) > 0 {
+			candidates = inContainer
 		}
 	}
 
-	// Return first option if preferred container not found
-	return &filteredOptions[0]
+	best := &candidates[0]
+	for i := range candidates {
+		if prefs.better(candidates[i].VideoStream, best.VideoStream) {
+			best = &candidates[i]
+		}
+	}
+	return best
 }
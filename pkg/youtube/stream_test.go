@@ -2,6 +2,7 @@ package youtube
 
 import (
 	"testing"
+	"time"
 )
 
 func TestStreamInfo_HasRequiredFields(t *testing.T) {
@@ -273,6 +274,89 @@ func TestStreamingDataResponse_GetStreamManifest_VideoStream(t *testing.T) {
 	}
 }
 
+func TestStreamingDataResponse_GetStreamManifest_HDR(t *testing.T) {
+	sd := &StreamingDataResponse{
+		AdaptiveFormats: []FormatResponse{
+			{Itag: 248, MimeType: "video/webm; codecs=\"vp9\"", Height: 1080, QualityLabel: "1080p"},
+			{
+				Itag: 337, MimeType: "video/webm; codecs=\"vp09.02.51.10.01.09.16.09.00\"", Height: 2160, QualityLabel: "2160p60 HDR",
+				ColorInfo: &ColorInfoResponse{Primaries: "COLOR_PRIMARIES_BT2020", TransferCharacteristics: "COLOR_TRANSFER_CHARACTERISTICS_SMPTEST2084"},
+			},
+			{Itag: 336, MimeType: "video/webm; codecs=\"vp09.02.51.10\"", Height: 1440, QualityLabel: "1440p60 HDR"},
+		},
+	}
+
+	manifest := sd.GetStreamManifest()
+	for i, want := range []bool{false, true, true} {
+		if got := manifest.VideoStreams[i].IsHDR; got != want {
+			t.Errorf("itag %d IsHDR = %v, want %v", manifest.VideoStreams[i].Itag, got, want)
+		}
+	}
+}
+
+func TestStreamingDataResponse_GetStreamManifest_Inaccessible(t *testing.T) {
+	sd := &StreamingDataResponse{
+		AdaptiveFormats: []FormatResponse{
+			{Itag: 137, URL: "v1080", MimeType: "video/mp4; codecs=\"avc1.640028\"", Height: 1080, QualityLabel: "1080p"},
+			{Itag: 616, MimeType: "video/mp4; codecs=\"vp09.00.40.08\"", Height: 1080, QualityLabel: "1080p Premium"},
+			{Itag: 140, URL: "a128", MimeType: "audio/mp4; codecs=\"mp4a.40.2\"", DrmFamilies: []string{"WIDEVINE", "PLAYREADY"}},
+		},
+	}
+
+	manifest := sd.GetStreamManifest()
+	if v := manifest.VideoStreams[0]; v.Premium || v.DRMProtected || !v.IsDownloadable() {
+		t.Errorf("itag 137 = %+v, want a downloadable stream", v.StreamInfo)
+	}
+	if v := manifest.VideoStreams[1]; !v.Premium || v.InaccessibleReason() != "requires YouTube Premium" {
+		t.Errorf("itag 616 = %+v, want a Premium stream", v.StreamInfo)
+	}
+	if a := manifest.AudioStreams[0]; !a.DRMProtected || a.InaccessibleReason() != "is DRM protected" {
+		t.Errorf("itag 140 = %+v, want a DRM protected stream", a.StreamInfo)
+	}
+}
+
+func TestStreamingDataResponse_GetStreamManifest_RawFields(t *testing.T) {
+	sd := &StreamingDataResponse{
+		AdaptiveFormats: []FormatResponse{
+			{
+				Itag: 137, MimeType: "video/mp4; codecs=\"avc1.640028\"", Height: 1080, ProjectionType: "EQUIRECTANGULAR",
+				InitRange: &RangeResponse{Start: "0", End: "740"}, IndexRange: &RangeResponse{Start: "741", End: "1816"},
+				LastModified: "1700000000123456",
+			},
+			{
+				Itag: 251, MimeType: "audio/webm; codecs=\"opus\"", InitRange: &RangeResponse{Start: "x", End: "1"},
+				AudioTrack: &AudioTrackResponse{DisplayName: "Deutsch", ID: "de.3", AudioIsDefault: true},
+			},
+		},
+	}
+
+	manifest := sd.GetStreamManifest()
+	vs := manifest.VideoStreams[0]
+	if vs.InitRange == nil || vs.InitRange.String() != "0-740" || vs.InitRange.Length() != 741 {
+		t.Errorf("InitRange = %v", vs.InitRange)
+	}
+	if vs.IndexRange == nil || *vs.IndexRange != (ByteRange{Start: 741, End: 1816}) {
+		t.Errorf("IndexRange = %v", vs.IndexRange)
+	}
+	if !vs.LastModified.Equal(time.UnixMicro(1700000000123456)) {
+		t.Errorf("LastModified = %v", vs.LastModified)
+	}
+	if vs.ProjectionType != "EQUIRECTANGULAR" {
+		t.Errorf("ProjectionType = %q", vs.ProjectionType)
+	}
+	if vs.Raw == nil || vs.Raw.Itag != 137 || vs.Raw == &sd.AdaptiveFormats[0] {
+		t.Errorf("Raw = %p, want a copy of the format", vs.Raw)
+	}
+
+	as := manifest.AudioStreams[0]
+	if as.InitRange != nil || !as.LastModified.IsZero() {
+		t.Errorf("invalid range and missing time = %v, %v, want none", as.InitRange, as.LastModified)
+	}
+	if as.AudioLanguage != "de" || as.AudioTrackName != "Deutsch" || !as.IsDefault {
+		t.Errorf("audio track = %q %q %v", as.AudioLanguage, as.AudioTrackName, as.IsDefault)
+	}
+}
+
 func TestStreamingDataResponse_GetStreamManifest_AudioStream(t *testing.T) {
 	sd := &StreamingDataResponse{
 		AdaptiveFormats: []FormatResponse{
@@ -412,6 +496,57 @@ func TestStreamingDataResponse_GetStreamManifest_MultipleStreams(t *testing.T) {
 	}
 }
 
+func TestStreamManifest_StreamByItag(t *testing.T) {
+	sd := &StreamingDataResponse{
+		Formats: []FormatResponse{
+			{Itag: 18, MimeType: "video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"", Height: 360},
+		},
+		AdaptiveFormats: []FormatResponse{
+			{Itag: 137, MimeType: "video/mp4; codecs=\"avc1.640028\"", Height: 1080},
+			{Itag: 140, MimeType: "audio/mp4; codecs=\"mp4a.40.2\"", Bitrate: 128000},
+		},
+	}
+	manifest := sd.GetStreamManifest()
+
+	if vs := manifest.VideoStreamByItag(137); vs == nil || vs.Height != 1080 {
+		t.Errorf("VideoStreamByItag(137) = %+v, want the 1080p stream", vs)
+	}
+	if vs := manifest.VideoStreamByItag(18); vs == nil || vs.Height != 360 {
+		t.Errorf("VideoStreamByItag(18) = %+v, want the muxed 360p stream", vs)
+	}
+	if as := manifest.AudioStreamByItag(140); as == nil || as.Itag != 140 {
+		t.Errorf("AudioStreamByItag(140) = %+v", as)
+	}
+	if vs := manifest.VideoStreamByItag(140); vs != nil {
+		t.Error("audio itag should not match a video stream")
+	}
+	if as := manifest.AudioStreamByItag(999); as != nil {
+		t.Error("unknown itag should not match")
+	}
+}
+
+func TestStreamManifest_GetBestOpusAudioStream(t *testing.T) {
+	sd := &StreamingDataResponse{
+		AdaptiveFormats: []FormatResponse{
+			{Itag: 140, MimeType: "audio/mp4; codecs=\"mp4a.40.2\"", Bitrate: 256000},
+			{Itag: 250, MimeType: "audio/webm; codecs=\"opus\"", Bitrate: 70000},
+			{Itag: 251, MimeType: "audio/webm; codecs=\"opus\"", Bitrate: 160000},
+		},
+	}
+	manifest := sd.GetStreamManifest()
+
+	if as := manifest.GetBestOpusAudioStream(); as == nil || as.Itag != 251 {
+		t.Errorf("GetBestOpusAudioStream() = %+v, want itag 251", as)
+	}
+	if as := manifest.GetBestAudioStream(); as == nil || as.Itag != 140 {
+		t.Errorf("GetBestAudioStream() = %+v, want itag 140", as)
+	}
+	manifest.AudioStreams = manifest.AudioStreams[:1]
+	if as := manifest.GetBestOpusAudioStream(); as != nil {
+		t.Errorf("GetBestOpusAudioStream() = %+v without Opus streams, want nil", as)
+	}
+}
+
 func TestStreamingDataResponse_GetStreamManifest_VideoOnlyNoAudio(t *testing.T) {
 	sd := &StreamingDataResponse{
 		AdaptiveFormats: []FormatResponse{
@@ -627,6 +762,64 @@ func TestDownloadOption_QualityLabel_AudioOnly(t *testing.T) {
 	}
 }
 
+func TestStreamInfo_EstimatedSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     StreamInfo
+		duration time.Duration
+		want     int64
+		exact    bool
+	}{
+		{"content length", StreamInfo{ContentLength: 1000, Bitrate: 8000}, time.Minute, 1000, true},
+		{"average bitrate", StreamInfo{Bitrate: 16000, AverageBitrate: 8000}, 10 * time.Second, 10000, false},
+		{"peak bitrate", StreamInfo{Bitrate: 8000}, 10 * time.Second, 10000, false},
+		{"approximate duration", StreamInfo{AverageBitrate: 8000, ApproxDuration: 5 * time.Second}, 0, 5000, false},
+		{"unknown", StreamInfo{}, time.Minute, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.EstimatedSize(tt.duration); got != tt.want {
+				t.Errorf("EstimatedSize() = %d, want %d", got, tt.want)
+			}
+			if got := tt.info.SizeIsExact(); got != tt.exact {
+				t.Errorf("SizeIsExact() = %v, want %v", got, tt.exact)
+			}
+		})
+	}
+}
+
+func TestDownloadOption_EstimatedSize(t *testing.T) {
+	option := DownloadOption{
+		VideoStream: &VideoStreamInfo{StreamInfo: StreamInfo{URL: "v", ContentLength: 5000}},
+		AudioStream: &AudioStreamInfo{StreamInfo: StreamInfo{URL: "a", AverageBitrate: 8000}},
+	}
+	if got := option.EstimatedSize(4 * time.Second); got != 9000 {
+		t.Errorf("EstimatedSize() = %d, want 9000", got)
+	}
+	if option.SizeIsExact() {
+		t.Error("an option with an estimated stream should not have an exact size")
+	}
+
+	// The audio of muxed options is part of the video stream
+	muxed := DownloadOption{VideoStream: &VideoStreamInfo{StreamInfo: StreamInfo{URL: "m", ContentLength: 5000}}, AudioStream: &AudioStreamInfo{}}
+	if !muxed.SizeIsExact() || muxed.EstimatedSize(time.Minute) != 5000 {
+		t.Errorf("muxed option size = %d (exact %v), want 5000 exact", muxed.EstimatedSize(time.Minute), muxed.SizeIsExact())
+	}
+}
+
+func TestStreamingDataResponse_GetStreamManifest_ApproxDuration(t *testing.T) {
+	sd := &StreamingDataResponse{
+		AdaptiveFormats: []FormatResponse{
+			{Itag: 251, MimeType: "audio/webm; codecs=\"opus\"", Bitrate: 160000, AverageBitrate: 128000, ApproxDurationMs: "212091"},
+		},
+	}
+
+	as := sd.GetStreamManifest().AudioStreams[0]
+	if as.AverageBitrate != 128000 || as.ApproxDuration != 212091*time.Millisecond {
+		t.Errorf("AverageBitrate = %d, ApproxDuration = %v", as.AverageBitrate, as.ApproxDuration)
+	}
+}
+
 func TestStreamManifest_GetDownloadOptions_Basic(t *testing.T) {
 	manifest := &StreamManifest{
 		VideoStreams: []VideoStreamInfo{
@@ -730,6 +923,55 @@ func TestStreamManifest_GetDownloadOptions_MultipleQualities(t *testing.T) {
 	}
 }
 
+func TestStreamManifest_GetDownloadOptions_Inaccessible(t *testing.T) {
+	manifest := &StreamManifest{
+		VideoStreams: []VideoStreamInfo{
+			{StreamInfo: StreamInfo{Itag: 616, Quality: "1080p Premium", Container: ContainerMP4, Premium: true}, Height: 1080},
+			{StreamInfo: StreamInfo{Itag: 136, URL: "v720", Container: ContainerMP4}, Height: 720},
+		},
+		AudioStreams: []AudioStreamInfo{
+			{StreamInfo: StreamInfo{Itag: 141, URL: "a256", Container: ContainerMP4, Bitrate: 256000, DRMProtected: true}},
+			{StreamInfo: StreamInfo{Itag: 140, URL: "a128", Container: ContainerMP4, Bitrate: 128000}},
+		},
+	}
+
+	options := manifest.GetDownloadOptions()
+	if len(options) != 2 {
+		t.Fatalf("got %d options, want the 720p and 128k audio ones", len(options))
+	}
+	for _, option := range options {
+		if !option.IsDownloadable() || option.AudioStream.URL != "a128" {
+			t.Errorf("option %+v uses an inaccessible stream", option)
+		}
+	}
+	if best := manifest.GetBestAudioStream(); best.URL != "a128" {
+		t.Errorf("GetBestAudioStream() = %q, want the stream that isn't DRM protected", best.URL)
+	}
+	if best := manifest.GetBestVideoStream(); best.URL != "v720" {
+		t.Errorf("GetBestVideoStream() = %q, want the stream that isn't for Premium members", best.URL)
+	}
+	if got := len(manifest.GetAllDownloadOptions()); got != 4 {
+		t.Errorf("GetAllDownloadOptions() returned %d options, want 4", got)
+	}
+
+	err := manifest.InaccessibleBetterOption(&options[0], QualityHighest, ContainerMP4, SelectionPreferences{})
+	if err == nil || err.Itag != 616 || err.Reason != "requires YouTube Premium" {
+		t.Fatalf("InaccessibleBetterOption() = %v, want the Premium format", err)
+	}
+	if want := "requested quality is only available in format 616 (1080p Premium), which requires YouTube Premium"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err, want)
+	}
+	if err := manifest.InaccessibleBetterOption(&options[0], QualityUpTo720p, ContainerMP4, SelectionPreferences{}); err != nil {
+		t.Errorf("InaccessibleBetterOption() up to 720p = %v, want nil", err)
+	}
+
+	// Premium members get a URL for the Premium format
+	manifest.VideoStreams[0].URL = "v1080"
+	if got := len(manifest.GetDownloadOptions()); got != 3 {
+		t.Errorf("got %d options with a Premium URL, want 3", got)
+	}
+}
+
 func TestStreamManifest_GetDownloadOptions_Empty(t *testing.T) {
 	manifest := &StreamManifest{
 		VideoStreams: []VideoStreamInfo{},
@@ -887,3 +1129,66 @@ func TestSelectBestOption_AudioOnlyExcluded(t *testing.T) {
 		t.Error("should not select audio-only option when selecting video quality")
 	}
 }
+
+func TestSelectBestOption_MKVIgnoresSourceContainer(t *testing.T) {
+	options := []DownloadOption{
+		{Container: ContainerMP4, VideoStream: &VideoStreamInfo{StreamInfo: StreamInfo{Quality: "1080p", Bitrate: 4000000}, Height: 1080}},
+		{Container: ContainerWebM, VideoStream: &VideoStreamInfo{StreamInfo: StreamInfo{Quality: "1080p", Bitrate: 6000000}, Height: 1080}},
+	}
+
+	best := SelectBestOption(options, QualityHighest, ContainerMKV)
+	if best == nil {
+		t.Fatal("expected to find a best option")
+	}
+	if best.VideoStream.Bitrate != 6000000 {
+		t.Errorf("expected highest bitrate video (6000000), got %d", best.VideoStream.Bitrate)
+	}
+}
+
+func TestStreamManifest_AdaptForContainer_MKV(t *testing.T) {
+	manifest := &StreamManifest{
+		AudioStreams: []AudioStreamInfo{
+			{StreamInfo: StreamInfo{URL: "https://example.com/a-mp4", Bitrate: 128000, Container: ContainerMP4}},
+			{StreamInfo: StreamInfo{URL: "https://example.com/a-webm", Bitrate: 160000, Container: ContainerWebM}},
+		},
+	}
+	option := &DownloadOption{
+		Container:   ContainerMP4,
+		VideoStream: &VideoStreamInfo{StreamInfo: StreamInfo{URL: "https://example.com/v", Container: ContainerMP4}, Height: 1080},
+		AudioStream: &manifest.AudioStreams[0],
+	}
+
+	adapted := manifest.AdaptForContainer(option, ContainerMKV)
+	if adapted.Container != ContainerMKV {
+		t.Errorf("expected MKV container, got %s", adapted.Container)
+	}
+	if adapted.AudioStream.URL != "https://example.com/a-webm" {
+		t.Errorf("expected best audio regardless of container, got %s", adapted.AudioStream.URL)
+	}
+	if option.Container != ContainerMP4 {
+		t.Error("original option should not be modified")
+	}
+}
+
+func TestStreamManifest_AdaptForContainer_KeepsOtherContainers(t *testing.T) {
+	manifest := &StreamManifest{
+		AudioStreams: []AudioStreamInfo{
+			{StreamInfo: StreamInfo{URL: "https://example.com/a-mp4", Bitrate: 128000, Container: ContainerMP4}},
+			{StreamInfo: StreamInfo{URL: "https://example.com/a-webm", Bitrate: 160000, Container: ContainerWebM}},
+		},
+	}
+	option := &DownloadOption{
+		Container:   ContainerMP4,
+		VideoStream: &VideoStreamInfo{StreamInfo: StreamInfo{URL: "https://example.com/v", Container: ContainerMP4}, Height: 1080},
+		AudioStream: &manifest.AudioStreams[0],
+	}
+
+	adapted := manifest.AdaptForContainer(option, ContainerMP4)
+	if adapted.AudioStream.URL != "https://example.com/a-mp4" {
+		t.Errorf("expected audio stream to be unchanged, got %s", adapted.AudioStream.URL)
+	}
+
+	if manifest.AdaptForContainer(nil, ContainerMKV) != nil {
+		t.Error("expected nil for nil option")
+	}
+}
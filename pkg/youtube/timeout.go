@@ -0,0 +1,31 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrFetchTimeout is returned when fetching a video's info takes longer than
+// the WatchPageFetcher's Timeout.
+var ErrFetchTimeout = errors.New("fetching video info timed out")
+
+// withTimeout returns ctx bounded by the fetcher's timeout.
+func (f *WatchPageFetcher) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if f.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, f.Timeout, fmt.Errorf("%w after %s", ErrFetchTimeout, f.Timeout))
+}
+
+// fetchTimeoutError replaces err with the fetcher's timeout if that is what
+// ended the request, rather than the caller's context or the network.
+func fetchTimeoutError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if cause := context.Cause(ctx); errors.Is(cause, ErrFetchTimeout) {
+		return cause
+	}
+	return err
+}
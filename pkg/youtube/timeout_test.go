@@ -0,0 +1,52 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWatchPageFetcher_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	fetcher := &WatchPageFetcher{Client: server.Client(), BaseURL: server.URL, Timeout: 50 * time.Millisecond}
+	_, err := fetcher.Fetch(context.Background(), "dQw4w9WgXcQ")
+	if !errors.Is(err, ErrFetchTimeout) {
+		t.Fatalf("Fetch() error = %v, want ErrFetchTimeout", err)
+	}
+	if want := "fetching video info timed out after 50ms"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+
+	// The caller's own deadline is reported as such
+	fetcher.Timeout = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = fetcher.Fetch(ctx, "dQw4w9WgXcQ")
+	if errors.Is(err, ErrFetchTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Fetch() error = %v, want the caller's deadline", err)
+	}
+}
+
+// blockingPOTokens is a POTokenProvider that never answers before its context ends.
+type blockingPOTokens struct{}
+
+func (blockingPOTokens) POToken(ctx context.Context, _ POTokenRequest) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestWatchPageFetcher_TimeoutPOToken(t *testing.T) {
+	fetcher := &WatchPageFetcher{POTokenProvider: blockingPOTokens{}, Timeout: 20 * time.Millisecond}
+
+	err := fetcher.AddPOToken(context.Background(), &WatchPage{VideoID: "dQw4w9WgXcQ"}, &StreamingDataResponse{})
+	if !errors.Is(err, ErrFetchTimeout) {
+		t.Errorf("AddPOToken() error = %v, want ErrFetchTimeout", err)
+	}
+}
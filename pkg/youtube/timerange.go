@@ -0,0 +1,110 @@
+package youtube
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidTimeRange is returned when a time range cannot be parsed.
+var ErrInvalidTimeRange = errors.New("invalid time range")
+
+// TimeRange is a section of a video.
+type TimeRange struct {
+	// Start is the offset of the section from the start of the video.
+	Start time.Duration
+
+	// End is the offset at which the section ends.
+	End time.Duration
+}
+
+// ParseTimeRange parses a range like "1:30-3:00" or "00:01:30.5-00:03:00".
+// Each side is seconds, MM:SS or HH:MM:SS, with optional fractional seconds.
+// A leading "*" (as used by yt-dlp's --download-sections) is ignored.
+func ParseTimeRange(s string) (TimeRange, error) {
+	startStr, endStr, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(s), "*"), "-")
+	if !ok {
+		return TimeRange{}, fmt.Errorf("%w %q: expected START-END", ErrInvalidTimeRange, s)
+	}
+
+	start, err := parseTimestamp(startStr)
+	if err != nil {
+		return TimeRange{}, fmt.Errorf("%w %q: %w", ErrInvalidTimeRange, s, err)
+	}
+	end, err := parseTimestamp(endStr)
+	if err != nil {
+		return TimeRange{}, fmt.Errorf("%w %q: %w", ErrInvalidTimeRange, s, err)
+	}
+	if end <= start {
+		return TimeRange{}, fmt.Errorf("%w %q: end must be after start", ErrInvalidTimeRange, s)
+	}
+	return TimeRange{Start: start, End: end}, nil
+}
+
+// parseTimestamp parses seconds, MM:SS or HH:MM:SS with optional fractional seconds.
+func parseTimestamp(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+
+	var total time.Duration
+	for i, part := range parts {
+		last := i == len(parts)-1
+		var value float64
+		var err error
+		if last {
+			value, err = strconv.ParseFloat(part, 64)
+		} else {
+			var n int
+			n, err = strconv.Atoi(part)
+			value = float64(n)
+		}
+		if err != nil || value < 0 || (i > 0 && value >= 60) {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		unit := time.Second
+		switch len(parts) - 1 - i {
+		case 1:
+			unit = time.Minute
+		case 2:
+			unit = time.Hour
+		}
+		total += time.Duration(value * float64(unit))
+	}
+	return total, nil
+}
+
+// Duration returns the length of the section.
+func (r TimeRange) Duration() time.Duration {
+	return r.End - r.Start
+}
+
+// Clamp limits the range to a video of the given length.
+// It returns false if the range starts at or after the end of the video.
+func (r TimeRange) Clamp(length time.Duration) (TimeRange, bool) {
+	if length <= 0 {
+		return r, true
+	}
+	if r.Start >= length {
+		return r, false
+	}
+	r.End = min(r.End, length)
+	return r, true
+}
+
+// String returns the range in the format accepted by ParseTimeRange, e.g. "1:30-3:00".
+func (r TimeRange) String() string {
+	return formatTimestamp(r.Start) + "-" + formatTimestamp(r.End)
+}
+
+// formatTimestamp formats a duration like formatDuration, keeping milliseconds when present.
+func formatTimestamp(d time.Duration) string {
+	s := formatDuration(d)
+	if ms := d.Milliseconds() % 1000; ms != 0 {
+		s += fmt.Sprintf(".%03d", ms)
+	}
+	return s
+}
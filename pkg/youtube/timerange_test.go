@@ -0,0 +1,76 @@
+package youtube
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseTimeRange(t *testing.T) {
+	tests := []struct {
+		input string
+		start time.Duration
+		end   time.Duration
+	}{
+		{"00:01:30-00:03:00", 90 * time.Second, 3 * time.Minute},
+		{"1:30-3:00", 90 * time.Second, 3 * time.Minute},
+		{"90-180", 90 * time.Second, 3 * time.Minute},
+		{"*1:30-3:00", 90 * time.Second, 3 * time.Minute},
+		{"0-0:10.5", 0, 10500 * time.Millisecond},
+		{" 1:00:00-1:00:30 ", time.Hour, time.Hour + 30*time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			r, err := ParseTimeRange(tt.input)
+			if err != nil {
+				t.Fatalf("ParseTimeRange(%q) failed: %v", tt.input, err)
+			}
+			if r.Start != tt.start || r.End != tt.end {
+				t.Errorf("ParseTimeRange(%q) = %v-%v, want %v-%v", tt.input, r.Start, r.End, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestParseTimeRange_Invalid(t *testing.T) {
+	for _, input := range []string{"", "1:30", "3:00-1:30", "1:30-1:30", "1:75-2:00", "a-b", "1:2:3:4-5", "-1-5"} {
+		t.Run(input, func(t *testing.T) {
+			if _, err := ParseTimeRange(input); !errors.Is(err, ErrInvalidTimeRange) {
+				t.Errorf("ParseTimeRange(%q) = %v, want ErrInvalidTimeRange", input, err)
+			}
+		})
+	}
+}
+
+func TestTimeRange_String(t *testing.T) {
+	r := TimeRange{Start: 90 * time.Second, End: time.Hour + 1500*time.Millisecond}
+	if got := r.String(); got != "1:30-1:00:01.500" {
+		t.Errorf("String() = %q", got)
+	}
+
+	parsed, err := ParseTimeRange(r.String())
+	if err != nil || parsed != r {
+		t.Errorf("String() does not round-trip: %v, %v", parsed, err)
+	}
+	if r.Duration() != time.Hour-88500*time.Millisecond {
+		t.Errorf("Duration() = %v", r.Duration())
+	}
+}
+
+func TestTimeRange_Clamp(t *testing.T) {
+	r := TimeRange{Start: time.Minute, End: 5 * time.Minute}
+
+	if got, ok := r.Clamp(3 * time.Minute); !ok || got.End != 3*time.Minute || got.Start != time.Minute {
+		t.Errorf("Clamp(3m) = %v, %v", got, ok)
+	}
+	if got, ok := r.Clamp(10 * time.Minute); !ok || got != r {
+		t.Errorf("Clamp(10m) = %v, %v; want unchanged", got, ok)
+	}
+	if _, ok := r.Clamp(time.Minute); ok {
+		t.Error("Clamp should fail when the range starts at the end of the video")
+	}
+	if got, ok := r.Clamp(0); !ok || got != r {
+		t.Errorf("Clamp(0) = %v, %v; unknown length should leave the range unchanged", got, ok)
+	}
+}
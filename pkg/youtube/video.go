@@ -25,12 +25,18 @@ type Video struct {
 	// ViewCount is the number of views the video has.
 	ViewCount int64
 
-	// LikeCount is the number of likes (may be hidden by uploader).
+	// LikeCount is the number of likes (may be hidden by uploader). It is read
+	// from the watch page rather than the player response; see WatchPage.ExtractWatchData.
 	LikeCount int64
 
 	// UploadDate is when the video was uploaded.
 	UploadDate time.Time
 
+	// PublishDate is when the video was made public, which differs from
+	// UploadDate for videos that were scheduled or private first. Recent videos
+	// have the exact time; older ones only the date, at midnight UTC.
+	PublishDate time.Time
+
 	// Thumbnails are the available thumbnail images for the video.
 	Thumbnails []Thumbnail
 
@@ -45,6 +51,32 @@ type Video struct {
 
 	// IsPrivate indicates if the video is private.
 	IsPrivate bool
+
+	// IsFamilySafe indicates if the video is suitable for all ages. It is false
+	// for age-restricted videos.
+	IsFamilySafe bool
+
+	// ScheduledStart is when an upcoming premiere or live stream is scheduled
+	// to start, or zero for videos that are already available.
+	ScheduledStart time.Time
+
+	// RemixOf is the original video this Short remixes, or nil if it is not a remix.
+	// It is read from the watch page rather than the player response;
+	// see WatchPage.ExtractRemixSource.
+	RemixOf *RemixSource
+
+	// Heatmap is the "most replayed" graph of the video in time order, or nil
+	// if YouTube shows none. Like RemixOf it is read from the watch page;
+	// see WatchPage.ExtractHeatmap.
+	Heatmap []HeatmapMarker
+
+	// Chapters are the video's chapters in order, or nil if it has none. Like
+	// RemixOf they are read from the watch page; see WatchPage.ExtractWatchData.
+	Chapters []Chapter
+
+	// Storyboards are the preview frames shown while seeking, from the lowest
+	// to the highest resolution, or nil if the video has none.
+	Storyboards []Storyboard
 }
 
 // String returns a string representation of the video.
@@ -54,7 +86,11 @@ func (v *Video) String() string {
 
 // DurationString returns the duration formatted as HH:MM:SS or MM:SS.
 func (v *Video) DurationString() string {
-	d := v.Duration
+	return formatDuration(v.Duration)
+}
+
+// formatDuration formats a duration as "M:SS" or "H:MM:SS".
+func formatDuration(d time.Duration) string {
 	h := int(d.Hours())
 	m := int(d.Minutes()) % 60
 	s := int(d.Seconds()) % 60
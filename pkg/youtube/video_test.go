@@ -1,6 +1,7 @@
 package youtube
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 )
@@ -346,3 +347,37 @@ func TestPlayerResponse_ToVideo_AuthorURL(t *testing.T) {
 		t.Errorf("expected Author.URL %q, got %q", expectedURL, video.Author.URL)
 	}
 }
+
+func TestPlayerResponse_ToVideo_Microformat(t *testing.T) {
+	var pr PlayerResponse
+	err := json.Unmarshal([]byte(`{
+		"videoDetails":{"videoId":"test123","lengthSeconds":"60"},
+		"microformat":{"playerMicroformatRenderer":{"category":"Music","publishDate":"2024-01-15T08:00:00-08:00","uploadDate":"2024-01-14","isFamilySafe":true}}
+	}`), &pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	video, err := pr.ToVideo()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if video.Category != "Music" || !video.IsFamilySafe {
+		t.Errorf("Category = %q, IsFamilySafe = %v", video.Category, video.IsFamilySafe)
+	}
+	if want := time.Date(2024, 1, 15, 16, 0, 0, 0, time.UTC); !video.PublishDate.Equal(want) {
+		t.Errorf("PublishDate = %v, want %v", video.PublishDate, want)
+	}
+	if want := time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC); !video.UploadDate.Equal(want) {
+		t.Errorf("UploadDate = %v, want %v", video.UploadDate, want)
+	}
+}
+
+func TestParseMicroformatDate_Invalid(t *testing.T) {
+	for _, s := range []string{"", "January 2024"} {
+		if got := parseMicroformatDate(s); !got.IsZero() {
+			t.Errorf("parseMicroformatDate(%q) = %v, want zero", s, got)
+		}
+	}
+}
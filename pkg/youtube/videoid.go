@@ -3,6 +3,7 @@ package youtube
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
@@ -20,14 +21,45 @@ func IsValidVideoID(id string) bool {
 	return videoIDRegex.MatchString(id)
 }
 
+// VideoID is a YouTube video ID. The fetchers that take one check it before
+// making any request, so a malformed ID fails with ErrInvalidVideoID instead
+// of an error page from YouTube.
+type VideoID string
+
+// NewVideoID returns id as a VideoID, or an error wrapping ErrInvalidVideoID if
+// it isn't a valid video ID. Use ParseVideoID to accept URLs as well.
+func NewVideoID(id string) (VideoID, error) {
+	v := VideoID(id)
+	if err := v.Validate(); err != nil {
+		return "", err
+	}
+	return v, nil
+}
+
+// Validate returns an error wrapping ErrInvalidVideoID if v isn't a valid video ID.
+func (v VideoID) Validate() error {
+	if !IsValidVideoID(string(v)) {
+		return fmt.Errorf("%w: %q", ErrInvalidVideoID, string(v))
+	}
+	return nil
+}
+
+// String returns the ID.
+func (v VideoID) String() string {
+	return string(v)
+}
+
 // ParseVideoID extracts the video ID from a YouTube URL or validates a raw video ID.
 // Supported URL formats:
 //   - https://www.youtube.com/watch?v=VIDEO_ID
 //   - https://youtu.be/VIDEO_ID
 //   - https://www.youtube.com/embed/VIDEO_ID
 //   - https://www.youtube.com/v/VIDEO_ID
+//   - https://www.youtube-nocookie.com/embed/VIDEO_ID
+//   - https://m.youtube.com/watch?v=VIDEO_ID and https://music.youtube.com/watch?v=VIDEO_ID
+//   - https://MIRROR/watch?v=VIDEO_ID (Invidious and other front-ends that mirror YouTube's URLs)
 //   - VIDEO_ID (raw 11-character ID)
-func ParseVideoID(input string) (string, error) {
+func ParseVideoID(input string) (VideoID, error) {
 	input = strings.TrimSpace(input)
 	if input == "" {
 		return "", ErrInvalidVideoID
@@ -35,7 +67,7 @@ func ParseVideoID(input string) (string, error) {
 
 	// Check if input is already a valid video ID
 	if IsValidVideoID(input) {
-		return input, nil
+		return VideoID(input), nil
 	}
 
 	// Try to parse as URL
@@ -63,6 +95,10 @@ func ParseVideoID(input string) (string, error) {
 		// youtube.com/v/VIDEO_ID
 		videoID = extractPathID(parsedURL.Path, "/v/")
 
+	case isMirrorWatchURL(parsedURL):
+		// invidious.example/watch?v=VIDEO_ID
+		videoID = parsedURL.Query().Get("v")
+
 	default:
 		return "", ErrInvalidVideoID
 	}
@@ -72,13 +108,12 @@ func ParseVideoID(input string) (string, error) {
 		return "", ErrInvalidVideoID
 	}
 
-	return videoID, nil
+	return VideoID(videoID), nil
 }
 
 // isYouTubeWatchURL checks if the URL is a standard YouTube watch URL.
 func isYouTubeWatchURL(u *url.URL) bool {
-	host := strings.ToLower(u.Host)
-	return (host == "youtube.com" || host == "www.youtube.com" || host == "m.youtube.com") &&
+	return isYouTubeHost(u.Host) &&
 		u.Path == "/watch" &&
 		u.Query().Get("v") != ""
 }
@@ -89,18 +124,25 @@ func isYouTubeShortURL(u *url.URL) bool {
 	return host == "youtu.be" && len(u.Path) > 1
 }
 
-// isYouTubeEmbedURL checks if the URL is a YouTube embed URL.
+// isYouTubeEmbedURL checks if the URL is a YouTube embed URL, including privacy-enhanced
+// youtube-nocookie.com embeds.
 func isYouTubeEmbedURL(u *url.URL) bool {
-	host := strings.ToLower(u.Host)
-	return (host == "youtube.com" || host == "www.youtube.com") &&
-		strings.HasPrefix(u.Path, "/embed/")
+	return isYouTubeHost(u.Host) && strings.HasPrefix(u.Path, "/embed/")
 }
 
 // isYouTubeVURL checks if the URL is a YouTube /v/ URL.
 func isYouTubeVURL(u *url.URL) bool {
-	host := strings.ToLower(u.Host)
-	return (host == "youtube.com" || host == "www.youtube.com") &&
-		strings.HasPrefix(u.Path, "/v/")
+	return isYouTubeHost(u.Host) && strings.HasPrefix(u.Path, "/v/")
+}
+
+// isMirrorWatchURL checks if the URL is a watch URL on a host other than YouTube.
+// Invidious, Piped and similar front-ends keep YouTube's /watch?v= form, so any
+// http(s) host with that path is taken as a mirror of the video.
+func isMirrorWatchURL(u *url.URL) bool {
+	return (u.Scheme == "http" || u.Scheme == "https") &&
+		u.Host != "" &&
+		u.Path == "/watch" &&
+		u.Query().Get("v") != ""
 }
 
 // extractPathID extracts the video ID from a path with a given prefix.
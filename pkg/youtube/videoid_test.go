@@ -1,13 +1,14 @@
 package youtube
 
 import (
+	"errors"
 	"testing"
 )
 
 func TestParseVideoID_StandardWatchURL(t *testing.T) {
 	tests := []struct {
 		url      string
-		expected string
+		expected VideoID
 	}{
 		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ"},
 		{"http://www.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ"},
@@ -32,7 +33,7 @@ func TestParseVideoID_StandardWatchURL(t *testing.T) {
 func TestParseVideoID_ShortURL(t *testing.T) {
 	tests := []struct {
 		url      string
-		expected string
+		expected VideoID
 	}{
 		{"https://youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ"},
 		{"http://youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ"},
@@ -55,7 +56,7 @@ func TestParseVideoID_ShortURL(t *testing.T) {
 func TestParseVideoID_EmbedURL(t *testing.T) {
 	tests := []struct {
 		url      string
-		expected string
+		expected VideoID
 	}{
 		{"https://www.youtube.com/embed/dQw4w9WgXcQ", "dQw4w9WgXcQ"},
 		{"http://www.youtube.com/embed/dQw4w9WgXcQ", "dQw4w9WgXcQ"},
@@ -78,7 +79,7 @@ func TestParseVideoID_EmbedURL(t *testing.T) {
 func TestParseVideoID_VURL(t *testing.T) {
 	tests := []struct {
 		url      string
-		expected string
+		expected VideoID
 	}{
 		{"https://www.youtube.com/v/dQw4w9WgXcQ", "dQw4w9WgXcQ"},
 		{"http://www.youtube.com/v/dQw4w9WgXcQ", "dQw4w9WgXcQ"},
@@ -100,7 +101,7 @@ func TestParseVideoID_VURL(t *testing.T) {
 func TestParseVideoID_RawID(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected string
+		expected VideoID
 	}{
 		{"dQw4w9WgXcQ", "dQw4w9WgXcQ"},
 		{"_-AbCdEfGhI", "_-AbCdEfGhI"},
@@ -164,3 +165,62 @@ func TestIsValidVideoID(t *testing.T) {
 		})
 	}
 }
+
+func TestParseVideoID_HostVariants(t *testing.T) {
+	tests := []string{
+		"https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ",
+		"https://youtube-nocookie.com/embed/dQw4w9WgXcQ?start=30&rel=0",
+		"https://m.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://m.youtube.com/embed/dQw4w9WgXcQ",
+		"https://music.youtube.com/watch?v=dQw4w9WgXcQ&feature=share",
+		"https://WWW.YOUTUBE.COM/watch?v=dQw4w9WgXcQ",
+		"https://yewtu.be/watch?v=dQw4w9WgXcQ",
+		"https://invidious.example.org/watch?v=dQw4w9WgXcQ&listen=1",
+		"http://piped.example.net/watch?v=dQw4w9WgXcQ",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			id, err := ParseVideoID(tt)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if id != "dQw4w9WgXcQ" {
+				t.Errorf("expected %q, got %q", "dQw4w9WgXcQ", id)
+			}
+		})
+	}
+}
+
+func TestParseVideoID_MirrorRequiresWatchURL(t *testing.T) {
+	tests := []string{
+		"https://yewtu.be/dQw4w9WgXcQx",
+		"https://invidious.example.org/search?v=dQw4w9WgXcQ",
+		"https://invidious.example.org/watch?v=short",
+		"ftp://mirror.example.org/watch?v=dQw4w9WgXcQ",
+		"https://example.com/embed/dQw4w9WgXcQ",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			if _, err := ParseVideoID(tt); err == nil {
+				t.Errorf("expected error for input %q", tt)
+			}
+		})
+	}
+}
+
+func TestNewVideoID(t *testing.T) {
+	id, err := NewVideoID("dQw4w9WgXcQ")
+	if err != nil || id != "dQw4w9WgXcQ" || id.String() != "dQw4w9WgXcQ" {
+		t.Errorf("NewVideoID() = %q, %v", id, err)
+	}
+
+	// URLs are only accepted by ParseVideoID
+	for _, input := range []string{"", "dQw4w9WgXc", "dQw4w9WgXcQ ", "https://youtu.be/dQw4w9WgXcQ"} {
+		id, err := NewVideoID(input)
+		if !errors.Is(err, ErrInvalidVideoID) || id != "" {
+			t.Errorf("NewVideoID(%q) = %q, %v, want ErrInvalidVideoID", input, id, err)
+		}
+	}
+}
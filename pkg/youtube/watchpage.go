@@ -5,13 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tracing"
 )
 
 const (
@@ -45,6 +46,41 @@ type WatchPageFetcher struct {
 	// Use this to provide authentication cookies for age-restricted
 	// or private videos that require login.
 	Cookies []*http.Cookie
+
+	// Header holds extra headers for the watch page request, such as a custom
+	// User-Agent or Accept-Language. Stream URLs are bound to the client that
+	// fetched the page, so downloads should send the same User-Agent.
+	Header http.Header
+
+	// Cache stores fetched playable pages by video ID, if set. Keys don't include the
+	// headers or client, so a cache should only be shared by fetchers that make
+	// the same requests. Pages fetched with cookies depend on the account and
+	// are never cached.
+	Cache Cache
+
+	// Players fetches the player JavaScript that deciphers protected formats. If nil,
+	// one with the same client and base URL and no cache is used.
+	Players *PlayerFetcher
+
+	// VisitorData, if set, is sent with watch page requests so YouTube serves
+	// them in that session instead of starting a new one. A PO token copied from
+	// a browser only works for the visitor data it was minted for.
+	VisitorData string
+
+	// POTokenProvider supplies the PO tokens AddPOToken adds to stream URLs.
+	// If nil, streams are requested without one.
+	POTokenProvider POTokenProvider
+
+	// Timeout bounds each of Fetch, DecipherStreams and AddPOToken, which fail
+	// with ErrFetchTimeout when they take longer. 0 means no limit.
+	Timeout time.Duration
+}
+
+// Cache stores fetched data by key, such as "watch/<video ID>". Implementations
+// decide how long entries are kept; pkg/cache provides one with a time to live.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, data []byte)
 }
 
 // WatchPageURL returns the URL for a video's watch page.
@@ -53,8 +89,24 @@ func WatchPageURL(videoID string) string {
 	return fmt.Sprintf("%s/watch?v=%s&bpctr=%s", youtubeBaseURL, videoID, bpctrValue)
 }
 
-// Fetch retrieves the watch page HTML for a given video ID.
-func (f *WatchPageFetcher) Fetch(ctx context.Context, videoID string) (*WatchPage, error) {
+// Fetch retrieves the watch page HTML for a given video ID. An invalid ID fails
+// with ErrInvalidVideoID without making a request.
+func (f *WatchPageFetcher) Fetch(ctx context.Context, id VideoID) (*WatchPage, error) {
+	ctx, span := tracing.Start(ctx, tracing.SpanFetchWatchPage, tracing.String(tracing.AttrVideoID, id.String()))
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+	page, err := f.fetch(ctx, id)
+	err = fetchTimeoutError(ctx, err)
+	tracing.End(span, err)
+	return page, err
+}
+
+func (f *WatchPageFetcher) fetch(ctx context.Context, id VideoID) (*WatchPage, error) {
+	if err := id.Validate(); err != nil {
+		return nil, err
+	}
+	videoID := id.String()
+
 	baseURL := f.BaseURL
 	if baseURL == "" {
 		baseURL = youtubeBaseURL
@@ -62,44 +114,72 @@ func (f *WatchPageFetcher) Fetch(ctx context.Context, videoID string) (*WatchPag
 
 	watchURL := fmt.Sprintf("%s/watch?v=%s&bpctr=%s", baseURL, videoID, bpctrValue)
 
-	// If cookies are provided and client has a cookie jar, populate it
-	if len(f.Cookies) > 0 && f.Client.Jar != nil {
-		parsedURL, err := url.Parse(baseURL)
-		if err == nil {
-			f.Client.Jar.SetCookies(parsedURL, f.Cookies)
+	cache := f.Cache
+	if len(f.Cookies) > 0 {
+		cache = nil
+	}
+	cacheKey := "watch/" + videoID
+	if cache != nil {
+		if html, ok := cache.Get(cacheKey); ok {
+			return &WatchPage{VideoID: videoID, HTML: string(html)}, nil
 		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, watchURL, http.NoBody)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
+	// If cookies are provided and client has a cookie jar, populate it
+	setCookies(f.Client, baseURL, f.Cookies)
 
-	resp, err := f.Client.Do(req)
+	header := f.Header
+	if f.VisitorData != "" {
+		header = header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set(visitorIDHeader, f.VisitorData)
+	}
+	html, err := fetchPageWithHeader(ctx, f.Client, watchURL, header)
 	if err != nil {
-		return nil, fmt.Errorf("fetching watch page: %w", err)
+		return nil, err
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	if resp.StatusCode == http.StatusTooManyRequests {
-		return nil, &RateLimitError{Message: "YouTube returned 429 Too Many Requests"}
+	page := &WatchPage{
+		VideoID: videoID,
+		HTML:    html,
 	}
+	// Error and bot-check pages may be gone on the next try, so only playable pages are kept
+	if cache != nil {
+		if playerResponse, err := page.ExtractPlayerResponse(); err == nil && playerResponse.PlayabilityStatus.Status == "OK" {
+			cache.Set(cacheKey, []byte(html))
+		}
+	}
+	return page, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+// DecipherStreams fills in the URLs of the page's formats that have signature
+// ciphers, fetching the page's player to decipher them. Streaming data without
+// ciphers is left alone and no player is fetched.
+func (f *WatchPageFetcher) DecipherStreams(ctx context.Context, page *WatchPage, streamingData *StreamingDataResponse) error {
+	if !streamingData.NeedsDecipher() {
+		return nil
 	}
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+	return fetchTimeoutError(ctx, f.decipherStreams(ctx, page, streamingData))
+}
 
-	body, err := io.ReadAll(resp.Body)
+func (f *WatchPageFetcher) decipherStreams(ctx context.Context, page *WatchPage, streamingData *StreamingDataResponse) error {
+	playerURL, err := page.ExtractPlayerURL()
 	if err != nil {
-		return nil, fmt.Errorf("reading response body: %w", err)
+		return err
 	}
 
-	return &WatchPage{
-		VideoID: videoID,
-		HTML:    string(body),
-	}, nil
+	players := f.Players
+	if players == nil {
+		players = &PlayerFetcher{Client: f.Client, BaseURL: f.BaseURL}
+	}
+	player, err := players.Fetch(ctx, playerURL)
+	if err != nil {
+		return err
+	}
+	return streamingData.Decipher(player)
 }
 
 // RateLimitError is returned when YouTube rate limits the request.
@@ -121,6 +201,24 @@ func (e *VideoUnavailableError) Error() string {
 	return fmt.Sprintf("video '%s' is unavailable: %s", e.VideoID, e.Reason)
 }
 
+// UpcomingVideoError is returned when a video is a premiere or live stream
+// that hasn't started yet.
+type UpcomingVideoError struct {
+	VideoID string
+
+	// ScheduledStart is when the video is scheduled to start, or zero if YouTube doesn't say.
+	ScheduledStart time.Time
+
+	Reason string
+}
+
+func (e *UpcomingVideoError) Error() string {
+	if e.ScheduledStart.IsZero() {
+		return fmt.Sprintf("video '%s' has not started yet: %s", e.VideoID, e.Reason)
+	}
+	return fmt.Sprintf("video '%s' is scheduled to start at %s", e.VideoID, e.ScheduledStart.Local().Format("2006-01-02 15:04 MST"))
+}
+
 // PlayerResponse represents the ytInitialPlayerResponse JSON structure
 // embedded in YouTube watch pages.
 type PlayerResponse struct {
@@ -128,6 +226,32 @@ type PlayerResponse struct {
 	PlayabilityStatus PlayabilityStatusResponse `json:"playabilityStatus"`
 	StreamingData     *StreamingDataResponse    `json:"streamingData,omitempty"`
 	Captions          *CaptionsResponse         `json:"captions,omitempty"`
+	Microformat       *MicroformatResponse      `json:"microformat,omitempty"`
+	Storyboards       *StoryboardsResponse      `json:"storyboards,omitempty"`
+}
+
+// MicroformatResponse contains the page metadata YouTube renders for search
+// engines, which has details missing from the video details.
+type MicroformatResponse struct {
+	PlayerMicroformatRenderer struct {
+		Category     string `json:"category"`
+		PublishDate  string `json:"publishDate"`
+		UploadDate   string `json:"uploadDate"`
+		IsFamilySafe bool   `json:"isFamilySafe"`
+	} `json:"playerMicroformatRenderer"`
+}
+
+// parseMicroformatDate parses a microformat date, which is either a full
+// timestamp such as "2024-01-15T08:00:00-08:00" or just "2024-01-15" for older
+// videos. Returns the zero time if the date is missing or malformed.
+func parseMicroformatDate(s string) time.Time {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t
+	}
+	return time.Time{}
 }
 
 // CaptionsResponse contains caption track information from the player response.
@@ -207,9 +331,41 @@ type ThumbnailResponse struct {
 
 // PlayabilityStatusResponse contains information about video availability.
 type PlayabilityStatusResponse struct {
-	Status          string `json:"status"`
-	Reason          string `json:"reason,omitempty"`
-	PlayableInEmbed bool   `json:"playableInEmbed"`
+	Status            string                     `json:"status"`
+	Reason            string                     `json:"reason,omitempty"`
+	PlayableInEmbed   bool                       `json:"playableInEmbed"`
+	LiveStreamability *LiveStreamabilityResponse `json:"liveStreamability,omitempty"`
+}
+
+// LiveStreamabilityResponse describes a premiere or live stream that hasn't started.
+type LiveStreamabilityResponse struct {
+	LiveStreamabilityRenderer struct {
+		VideoID      string `json:"videoId"`
+		OfflineSlate struct {
+			LiveStreamOfflineSlateRenderer struct {
+				ScheduledStartTime string `json:"scheduledStartTime"`
+			} `json:"liveStreamOfflineSlateRenderer"`
+		} `json:"offlineSlate"`
+	} `json:"liveStreamabilityRenderer"`
+}
+
+// IsUpcoming reports whether the video is a premiere or live stream that hasn't started yet.
+func (s *PlayabilityStatusResponse) IsUpcoming() bool {
+	return s.Status == "LIVE_STREAM_OFFLINE"
+}
+
+// ScheduledStart returns when an upcoming video is scheduled to start,
+// or the zero time if it isn't upcoming or YouTube doesn't say.
+func (s *PlayabilityStatusResponse) ScheduledStart() time.Time {
+	if s.LiveStreamability == nil {
+		return time.Time{}
+	}
+	slate := s.LiveStreamability.LiveStreamabilityRenderer.OfflineSlate.LiveStreamOfflineSlateRenderer
+	seconds, err := strconv.ParseInt(slate.ScheduledStartTime, 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
 }
 
 // StreamingDataResponse contains the streaming formats and manifest URLs.
@@ -237,19 +393,13 @@ func (sd *StreamingDataResponse) GetStreamManifest() *StreamManifest {
 
 		if isVideoFormat(format.MimeType) {
 			vs := VideoStreamInfo{
-				StreamInfo: StreamInfo{
-					URL:           format.URL,
-					Quality:       format.QualityLabel,
-					Bitrate:       format.Bitrate,
-					Codec:         codec,
-					Container:     container,
-					MimeType:      format.MimeType,
-					ContentLength: parseContentLength(format.ContentLength),
-				},
-				Width:      format.Width,
-				Height:     format.Height,
-				Framerate:  format.Fps,
-				VideoCodec: codec,
+				StreamInfo:     newStreamInfo(format, format.QualityLabel, container, codec),
+				Width:          format.Width,
+				Height:         format.Height,
+				Framerate:      format.Fps,
+				VideoCodec:     codec,
+				IsHDR:          format.IsHDR(),
+				ProjectionType: format.ProjectionType,
 			}
 			// Use calculated quality if none provided
 			if vs.Quality == "" && format.Height > 0 {
@@ -258,19 +408,16 @@ func (sd *StreamingDataResponse) GetStreamManifest() *StreamManifest {
 			manifest.VideoStreams = append(manifest.VideoStreams, vs)
 		} else if isAudioFormat(format.MimeType) {
 			as := AudioStreamInfo{
-				StreamInfo: StreamInfo{
-					URL:           format.URL,
-					Quality:       format.AudioQuality,
-					Bitrate:       format.Bitrate,
-					Codec:         codec,
-					Container:     container,
-					MimeType:      format.MimeType,
-					ContentLength: parseContentLength(format.ContentLength),
-				},
+				StreamInfo:   newStreamInfo(format, format.AudioQuality, container, codec),
 				AudioCodec:   codec,
 				SampleRate:   parseSampleRate(format.AudioSampleRate),
 				ChannelCount: format.AudioChannels,
 			}
+			if track := format.AudioTrack; track != nil {
+				as.AudioLanguage, _, _ = strings.Cut(track.ID, ".")
+				as.AudioTrackName = track.DisplayName
+				as.IsDefault = track.AudioIsDefault
+			}
 			manifest.AudioStreams = append(manifest.AudioStreams, as)
 		}
 	}
@@ -283,15 +430,7 @@ func (sd *StreamingDataResponse) GetStreamManifest() *StreamManifest {
 
 		ms := MuxedStreamInfo{
 			VideoStreamInfo: VideoStreamInfo{
-				StreamInfo: StreamInfo{
-					URL:           format.URL,
-					Quality:       format.QualityLabel,
-					Bitrate:       format.Bitrate,
-					Codec:         codec,
-					Container:     container,
-					MimeType:      format.MimeType,
-					ContentLength: parseContentLength(format.ContentLength),
-				},
+				StreamInfo: newStreamInfo(format, format.QualityLabel, container, codec),
 				Width:      format.Width,
 				Height:     format.Height,
 				Framerate:  format.Fps,
@@ -307,6 +446,30 @@ func (sd *StreamingDataResponse) GetStreamManifest() *StreamManifest {
 	return manifest
 }
 
+// newStreamInfo returns the information common to all streams of a format,
+// whose MIME type gave container and codec.
+func newStreamInfo(format *FormatResponse, quality string, container Container, codec string) StreamInfo {
+	raw := *format
+	return StreamInfo{
+		Itag:           format.Itag,
+		URL:            format.URL,
+		Quality:        quality,
+		Bitrate:        format.Bitrate,
+		Codec:          codec,
+		Container:      container,
+		MimeType:       format.MimeType,
+		ContentLength:  parseContentLength(format.ContentLength),
+		AverageBitrate: format.AverageBitrate,
+		ApproxDuration: parseApproxDuration(format.ApproxDurationMs),
+		DRMProtected:   format.IsDRMProtected(),
+		Premium:        format.IsPremium(),
+		InitRange:      format.InitRange.byteRange(),
+		IndexRange:     format.IndexRange.byteRange(),
+		LastModified:   parseLastModified(format.LastModified),
+		Raw:            &raw,
+	}
+}
+
 // parseMimeType extracts the container and codec from a MIME type string.
 // Example: "video/mp4; codecs=\"avc1.640028\"" -> "mp4", "avc1.640028"
 func parseMimeType(mimeType string) (container Container, codec string) {
@@ -355,6 +518,25 @@ func isAudioFormat(mimeType string) bool {
 	return strings.HasPrefix(mimeType, "audio/")
 }
 
+// parseApproxDuration parses a format's approxDurationMs, returning 0 if it is missing.
+func parseApproxDuration(ms string) time.Duration {
+	val, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(val) * time.Millisecond
+}
+
+// parseLastModified parses the last modified time of a format, given in
+// microseconds since the Unix epoch.
+func parseLastModified(us string) time.Time {
+	val, err := strconv.ParseInt(us, 10, 64)
+	if err != nil || val <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(val)
+}
+
 // parseContentLength parses a content length string to int64.
 func parseContentLength(s string) int64 {
 	if s == "" {
@@ -375,22 +557,91 @@ func parseSampleRate(s string) int {
 
 // FormatResponse represents a single stream format.
 type FormatResponse struct {
-	Itag             int    `json:"itag"`
-	URL              string `json:"url,omitempty"`
-	MimeType         string `json:"mimeType"`
-	Bitrate          int64  `json:"bitrate"`
-	Width            int    `json:"width,omitempty"`
-	Height           int    `json:"height,omitempty"`
-	ContentLength    string `json:"contentLength,omitempty"`
-	Quality          string `json:"quality"`
-	QualityLabel     string `json:"qualityLabel,omitempty"`
-	Fps              int    `json:"fps,omitempty"`
-	AudioQuality     string `json:"audioQuality,omitempty"`
-	AudioSampleRate  string `json:"audioSampleRate,omitempty"`
-	AudioChannels    int    `json:"audioChannels,omitempty"`
-	SignatureCipher  string `json:"signatureCipher,omitempty"`
-	AverageBitrate   int64  `json:"averageBitrate,omitempty"`
-	ApproxDurationMs string `json:"approxDurationMs,omitempty"`
+	Itag             int                 `json:"itag"`
+	URL              string              `json:"url,omitempty"`
+	MimeType         string              `json:"mimeType"`
+	Bitrate          int64               `json:"bitrate"`
+	Width            int                 `json:"width,omitempty"`
+	Height           int                 `json:"height,omitempty"`
+	ContentLength    string              `json:"contentLength,omitempty"`
+	Quality          string              `json:"quality"`
+	QualityLabel     string              `json:"qualityLabel,omitempty"`
+	Fps              int                 `json:"fps,omitempty"`
+	AudioQuality     string              `json:"audioQuality,omitempty"`
+	AudioSampleRate  string              `json:"audioSampleRate,omitempty"`
+	AudioChannels    int                 `json:"audioChannels,omitempty"`
+	SignatureCipher  string              `json:"signatureCipher,omitempty"`
+	AverageBitrate   int64               `json:"averageBitrate,omitempty"`
+	ApproxDurationMs string              `json:"approxDurationMs,omitempty"`
+	ColorInfo        *ColorInfoResponse  `json:"colorInfo,omitempty"`
+	DrmFamilies      []string            `json:"drmFamilies,omitempty"`
+	InitRange        *RangeResponse      `json:"initRange,omitempty"`
+	IndexRange       *RangeResponse      `json:"indexRange,omitempty"`
+	LastModified     string              `json:"lastModified,omitempty"`
+	ProjectionType   string              `json:"projectionType,omitempty"`
+	AudioTrack       *AudioTrackResponse `json:"audioTrack,omitempty"`
+}
+
+// RangeResponse is a byte range of a format, such as its initialization segment.
+type RangeResponse struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// byteRange converts the range, returning nil for a missing or invalid one.
+func (r *RangeResponse) byteRange() *ByteRange {
+	if r == nil {
+		return nil
+	}
+	start, err := strconv.ParseInt(r.Start, 10, 64)
+	if err != nil {
+		return nil
+	}
+	end, err := strconv.ParseInt(r.End, 10, 64)
+	if err != nil || start < 0 || end < start {
+		return nil
+	}
+	return &ByteRange{Start: start, End: end}
+}
+
+// AudioTrackResponse describes the audio track of a format, for videos dubbed
+// in several languages.
+type AudioTrackResponse struct {
+	DisplayName    string `json:"displayName"`
+	ID             string `json:"id"`
+	AudioIsDefault bool   `json:"audioIsDefault"`
+}
+
+// ColorInfoResponse describes the color space of a video format.
+type ColorInfoResponse struct {
+	Primaries               string `json:"primaries,omitempty"`
+	TransferCharacteristics string `json:"transferCharacteristics,omitempty"`
+	MatrixCoefficients      string `json:"matrixCoefficients,omitempty"`
+}
+
+// IsHDR reports whether the format is HDR: the video uses the PQ (HDR10) or
+// HLG transfer function. Older responses without color info mark HDR formats
+// in the quality label only.
+func (f *FormatResponse) IsHDR() bool {
+	if f.ColorInfo != nil {
+		switch f.ColorInfo.TransferCharacteristics {
+		case "COLOR_TRANSFER_CHARACTERISTICS_SMPTEST2084", "COLOR_TRANSFER_CHARACTERISTICS_ARIB_STD_B67":
+			return true
+		}
+	}
+	return strings.Contains(f.QualityLabel, "HDR")
+}
+
+// IsDRMProtected reports whether the format is encrypted with DRM, as the
+// formats of purchased and rented movies are.
+func (f *FormatResponse) IsDRMProtected() bool {
+	return len(f.DrmFamilies) > 0
+}
+
+// IsPremium reports whether the format is a high bitrate one offered to
+// YouTube Premium members, which YouTube labels like "1080p Premium".
+func (f *FormatResponse) IsPremium() bool {
+	return strings.Contains(f.QualityLabel, "Premium")
 }
 
 // NeedsCipherDecryption returns true if this stream requires signature cipher decryption
@@ -482,22 +733,33 @@ func (pr *PlayerResponse) ToVideo() (*Video, error) {
 	// Build channel URL
 	channelURL := fmt.Sprintf("%s/channel/%s", youtubeBaseURL, vd.ChannelID)
 
-	return &Video{
-		ID:          vd.VideoID,
-		Title:       vd.Title,
-		Description: vd.ShortDescription,
-		Duration:    time.Duration(durationSeconds) * time.Second,
-		ViewCount:   viewCount,
-		Keywords:    vd.Keywords,
-		Thumbnails:  thumbnails,
-		IsLive:      vd.IsLiveContent,
-		IsPrivate:   vd.IsPrivate,
+	video := &Video{
+		ID:             vd.VideoID,
+		Title:          vd.Title,
+		Description:    vd.ShortDescription,
+		Duration:       time.Duration(durationSeconds) * time.Second,
+		ViewCount:      viewCount,
+		Keywords:       vd.Keywords,
+		Thumbnails:     thumbnails,
+		IsLive:         vd.IsLiveContent,
+		IsPrivate:      vd.IsPrivate,
+		ScheduledStart: pr.PlayabilityStatus.ScheduledStart(),
+		Storyboards:    pr.ExtractStoryboards(time.Duration(durationSeconds) * time.Second),
 		Author: Author{
 			Name:      vd.Author,
 			ChannelID: vd.ChannelID,
 			URL:       channelURL,
 		},
-	}, nil
+	}
+
+	if pr.Microformat != nil {
+		mf := pr.Microformat.PlayerMicroformatRenderer
+		video.Category = mf.Category
+		video.PublishDate = parseMicroformatDate(mf.PublishDate)
+		video.UploadDate = parseMicroformatDate(mf.UploadDate)
+		video.IsFamilySafe = mf.IsFamilySafe
+	}
+	return video, nil
 }
 
 // ExtractPlayerResponse extracts and parses the ytInitialPlayerResponse JSON
@@ -2,9 +2,13 @@ package youtube
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestWatchPageURL(t *testing.T) {
@@ -61,6 +65,106 @@ func TestFetchWatchPage_Success(t *testing.T) {
 	}
 }
 
+func TestFetchWatchPage_SendsHeader(t *testing.T) {
+	var userAgent, language string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent, language = r.Header.Get("User-Agent"), r.Header.Get("Accept-Language")
+		_, _ = w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	fetcher := &WatchPageFetcher{
+		Client:  server.Client(),
+		BaseURL: server.URL,
+		Header:  http.Header{"user-agent": {"Mozilla/5.0 Test"}, "Accept-Language": {"fr"}},
+	}
+	if _, err := fetcher.Fetch(context.Background(), "dQw4w9WgXcQ"); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if userAgent != "Mozilla/5.0 Test" || language != "fr" {
+		t.Errorf("unexpected headers: User-Agent %q, Accept-Language %q", userAgent, language)
+	}
+}
+
+func TestFetchWatchPage_SendsVisitorData(t *testing.T) {
+	var visitorID, userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		visitorID, userAgent = r.Header.Get("X-Goog-Visitor-Id"), r.Header.Get("User-Agent")
+		_, _ = w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	header := http.Header{"User-Agent": {"Mozilla/5.0 Test"}}
+	fetcher := &WatchPageFetcher{Client: server.Client(), BaseURL: server.URL, Header: header, VisitorData: "CgtWaXNpdG9y"}
+	if _, err := fetcher.Fetch(context.Background(), "dQw4w9WgXcQ"); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if visitorID != "CgtWaXNpdG9y" || userAgent != "Mozilla/5.0 Test" {
+		t.Errorf("unexpected headers: X-Goog-Visitor-Id %q, User-Agent %q", visitorID, userAgent)
+	}
+	if header.Get("X-Goog-Visitor-Id") != "" {
+		t.Error("Fetch modified the fetcher's header")
+	}
+}
+
+// mapCache is a Cache without expiry for tests.
+type mapCache map[string][]byte
+
+func (c mapCache) Get(key string) ([]byte, bool) {
+	data, ok := c[key]
+	return data, ok
+}
+
+func (c mapCache) Set(key string, data []byte) { c[key] = data }
+
+func TestFetchWatchPage_Cache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		status := "OK"
+		if r.URL.Query().Get("v") == "jNQXAC9IVRw" {
+			status = "LOGIN_REQUIRED"
+		}
+		_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = {"playabilityStatus":{"status":"` + status + `"}};</script>`))
+	}))
+	defer server.Close()
+
+	cache := mapCache{}
+	fetcher := &WatchPageFetcher{Client: server.Client(), BaseURL: server.URL, Cache: cache}
+	for range 2 {
+		page, err := fetcher.Fetch(context.Background(), "dQw4w9WgXcQ")
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if !strings.Contains(page.HTML, `"OK"`) || page.VideoID != "dQw4w9WgXcQ" {
+			t.Errorf("page = %+v", page)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want the second fetch served from the cache", requests)
+	}
+	if _, ok := cache["watch/dQw4w9WgXcQ"]; !ok {
+		t.Errorf("cache keys = %v", cache)
+	}
+
+	// Unplayable pages aren't cached
+	if _, err := fetcher.Fetch(context.Background(), "jNQXAC9IVRw"); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if _, ok := cache["watch/jNQXAC9IVRw"]; ok {
+		t.Error("expected an unplayable page not to be cached")
+	}
+
+	// Pages fetched with cookies bypass the cache
+	fetcher.Cookies = []*http.Cookie{{Name: "SID", Value: "secret"}}
+	if _, err := fetcher.Fetch(context.Background(), "dQw4w9WgXcQ"); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("requests = %d, want a fetch with cookies to skip the cache", requests)
+	}
+}
+
 func TestFetchWatchPage_NotFound(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
@@ -72,12 +176,30 @@ func TestFetchWatchPage_NotFound(t *testing.T) {
 		BaseURL: server.URL,
 	}
 
-	_, err := fetcher.Fetch(context.Background(), "invalidID123")
+	_, err := fetcher.Fetch(context.Background(), "missingID12")
 	if err == nil {
 		t.Error("expected error for 404 response")
 	}
 }
 
+func TestFetchWatchPage_InvalidID(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	fetcher := &WatchPageFetcher{Client: server.Client(), BaseURL: server.URL}
+	for _, id := range []VideoID{"", "invalidID123", "dQw4w9WgXc!"} {
+		if _, err := fetcher.Fetch(context.Background(), id); !errors.Is(err, ErrInvalidVideoID) {
+			t.Errorf("Fetch(%q) error = %v, want ErrInvalidVideoID", id, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("requests = %d, want none for invalid IDs", requests)
+	}
+}
+
 func TestFetchWatchPage_ServerError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -166,6 +288,52 @@ func TestVideoUnavailableError_Error(t *testing.T) {
 	}
 }
 
+func TestUpcomingVideoError_Error(t *testing.T) {
+	err := &UpcomingVideoError{VideoID: "dQw4w9WgXcQ", Reason: "Premieres soon"}
+	expected := "video 'dQw4w9WgXcQ' has not started yet: Premieres soon"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	err.ScheduledStart = time.Unix(1767225600, 0)
+	if !strings.Contains(err.Error(), "is scheduled to start at "+err.ScheduledStart.Local().Format("2006-01-02 15:04")) {
+		t.Errorf("expected the scheduled start in %q", err.Error())
+	}
+}
+
+func TestPlayerResponse_UpcomingPremiere(t *testing.T) {
+	data := `{"videoDetails":{"videoId":"dQw4w9WgXcQ","title":"Premiere","lengthSeconds":"0","isUpcoming":true},` +
+		`"playabilityStatus":{"status":"LIVE_STREAM_OFFLINE","reason":"Premieres in 2 hours","liveStreamability":{"liveStreamabilityRenderer":` +
+		`{"videoId":"dQw4w9WgXcQ","offlineSlate":{"liveStreamOfflineSlateRenderer":{"scheduledStartTime":"1767225600"}}}}}}`
+
+	var pr PlayerResponse
+	if err := json.Unmarshal([]byte(data), &pr); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if !pr.PlayabilityStatus.IsUpcoming() {
+		t.Error("expected the premiere to be upcoming")
+	}
+
+	video, err := pr.ToVideo()
+	if err != nil {
+		t.Fatalf("ToVideo failed: %v", err)
+	}
+	if !video.ScheduledStart.Equal(time.Unix(1767225600, 0)) {
+		t.Errorf("expected scheduled start 1767225600, got %v", video.ScheduledStart)
+	}
+}
+
+func TestPlayabilityStatusResponse_ScheduledStartMissing(t *testing.T) {
+	for _, status := range []PlayabilityStatusResponse{
+		{Status: "OK"},
+		{Status: "LIVE_STREAM_OFFLINE", LiveStreamability: &LiveStreamabilityResponse{}},
+	} {
+		if start := status.ScheduledStart(); !start.IsZero() {
+			t.Errorf("expected no scheduled start for %+v, got %v", status, start)
+		}
+	}
+}
+
 func TestWatchPage_ExtractPlayerResponse_Success(t *testing.T) {
 	// Sample HTML with ytInitialPlayerResponse embedded
 	html := `<!DOCTYPE html>
@@ -0,0 +1,375 @@
+// Package ytdl provides a high-level client for fetching and downloading YouTube
+// videos, for Go programs that embed the downloader instead of running the CLI.
+//
+// A minimal download of the best quality available:
+//
+//	client := ytdl.NewClient()
+//	result, err := client.Download(ctx, "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+//		ytdl.WithOutputDir("videos"),
+//		ytdl.WithProgress(func(p download.Progress) { fmt.Println(p) }),
+//	)
+package ytdl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"time"
+
+	ytdlhttp "github.com/SakuraBurst/golang-youtube-downloader/internal/http"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tracing"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// ErrUnsupportedURL is returned when a URL doesn't point to content the method handles,
+// such as a playlist passed to GetVideo.
+var ErrUnsupportedURL = errors.New("unsupported URL")
+
+// Client fetches video metadata and downloads videos.
+type Client struct {
+	httpClient   *http.Client
+	streamClient *http.Client
+	baseURL      string
+	cookies      []*http.Cookie
+	poTokens     youtube.POTokenProvider
+	visitorData  string
+	listeners    []download.EventListener
+
+	// videos, playlists and downloader replace the watch page fetcher, the
+	// playlist fetcher and the stream downloader when set.
+	videos     VideoFetcher
+	playlists  PlaylistFetcher
+	downloader download.StreamDownloader
+
+	// tracer traces fetches, downloads and muxes, if set.
+	tracer tracing.Tracer
+
+	// fetchTimeout and downloadTimeout bound each metadata request and stream
+	// download, 0 for no limit.
+	fetchTimeout    time.Duration
+	downloadTimeout time.Duration
+
+	// chunkTuner sets how many chunks of each stream are requested at once, if set.
+	chunkTuner *download.ConcurrencyTuner
+
+	// muxer combines video and audio streams, muxStreams unless replaced in tests.
+	muxer func(ctx context.Context, videoPath, audioPath, outputPath string, duration time.Duration) error
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the HTTP client used for pages and streams. By default pages
+// are fetched with a client that has a timeout, and streams with one that has none.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = client
+		c.streamClient = client
+	}
+}
+
+// WithCookies sets the cookies sent to YouTube, needed for age-restricted,
+// private and members-only videos.
+func WithCookies(cookies []*http.Cookie) ClientOption {
+	return func(c *Client) {
+		c.cookies = cookies
+	}
+}
+
+// WithPOTokenProvider sets the provider of the PO tokens added to stream URLs,
+// needed when YouTube refuses downloads with 403 Forbidden. visitorData, if set,
+// is the session the provider's tokens are minted for; pages are then fetched in it.
+func WithPOTokenProvider(provider youtube.POTokenProvider, visitorData string) ClientOption {
+	return func(c *Client) {
+		c.poTokens = provider
+		c.visitorData = visitorData
+	}
+}
+
+// WithEventListener adds a listener that receives the events of every stream
+// download, for example to collect metrics.
+func WithEventListener(listener download.EventListener) ClientOption {
+	return func(c *Client) {
+		c.listeners = append(c.listeners, listener)
+	}
+}
+
+// WithTimeouts bounds how long fetching a video's info and downloading each of
+// its streams may take; they fail with youtube.ErrFetchTimeout and
+// download.ErrTimeout. 0 means no limit.
+func WithTimeouts(fetch, stream time.Duration) ClientOption {
+	return func(c *Client) {
+		c.fetchTimeout = fetch
+		c.downloadTimeout = stream
+	}
+}
+
+// WithChunkTuner requests up to the tuner's concurrency level of chunks of each
+// stream at once, see download.WithChunkTuner.
+func WithChunkTuner(tuner *download.ConcurrencyTuner) ClientOption {
+	return func(c *Client) {
+		c.chunkTuner = tuner
+	}
+}
+
+// WithVideoFetcher sets the fetcher of video metadata and streams, which is
+// otherwise WatchPageVideos with the client's HTTP client, cookies and PO tokens.
+func WithVideoFetcher(fetcher VideoFetcher) ClientOption {
+	return func(c *Client) {
+		c.videos = fetcher
+	}
+}
+
+// WithPlaylistFetcher sets the fetcher of playlists, which is otherwise a
+// youtube.PlaylistFetcher with the client's HTTP client and cookies.
+func WithPlaylistFetcher(fetcher PlaylistFetcher) ClientOption {
+	return func(c *Client) {
+		c.playlists = fetcher
+	}
+}
+
+// WithStreamDownloader sets the downloader of streams, which is otherwise a
+// download.Downloader with the client's event listeners, download timeout and
+// chunk tuner.
+func WithStreamDownloader(downloader download.StreamDownloader) ClientOption {
+	return func(c *Client) {
+		c.downloader = downloader
+	}
+}
+
+// WithTracer traces the client's metadata fetches, stream downloads and muxes
+// with tracer, see the tracing package. Spans of one download share its video ID.
+func WithTracer(tracer tracing.Tracer) ClientOption {
+	return func(c *Client) {
+		c.tracer = tracer
+	}
+}
+
+// WithBaseURL sets the base URL for YouTube (used for testing).
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// NewClient creates a Client with the given options.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{muxer: muxStreams}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.httpClient == nil {
+		c.httpClient = ytdlhttp.DefaultClient()
+		c.streamClient = ytdlhttp.StreamClient()
+	}
+	return c
+}
+
+// Video is a video's metadata together with the streams it can be downloaded from.
+type Video struct {
+	youtube.Video
+
+	// Streams are the video's available streams.
+	Streams *youtube.StreamManifest
+
+	// Captions are the video's subtitle tracks, which can be downloaded with a
+	// youtube.CaptionDownloader.
+	Captions *youtube.CaptionManifest
+}
+
+// Playlist is a playlist's metadata together with all of its videos.
+type Playlist struct {
+	youtube.Playlist
+
+	// Videos are the playlist's videos in order.
+	Videos []youtube.PlaylistVideo
+}
+
+func (c *Client) watchPageFetcher() *youtube.WatchPageFetcher {
+	return &youtube.WatchPageFetcher{
+		Client:          c.httpClient,
+		BaseURL:         c.baseURL,
+		Cookies:         c.cookies,
+		VisitorData:     c.visitorData,
+		POTokenProvider: c.poTokens,
+		Timeout:         c.fetchTimeout,
+	}
+}
+
+// videoFetcher returns the fetcher set with WithVideoFetcher or the watch page one.
+func (c *Client) videoFetcher() VideoFetcher {
+	if c.videos != nil {
+		return c.videos
+	}
+	return WatchPageVideos{Fetcher: c.watchPageFetcher()}
+}
+
+func (c *Client) playlistFetcher() *youtube.PlaylistFetcher {
+	return &youtube.PlaylistFetcher{Client: c.httpClient, BaseURL: c.baseURL, Cookies: c.cookies}
+}
+
+// streamDownloader returns the downloader set with WithStreamDownloader or a
+// download.Downloader with the client's options.
+func (c *Client) streamDownloader() download.StreamDownloader {
+	if c.downloader != nil {
+		return c.downloader
+	}
+	opts := []download.Option{download.WithTimeout(c.downloadTimeout), download.WithChunkTuner(c.chunkTuner)}
+	for _, l := range c.listeners {
+		opts = append(opts, download.WithEventListener(l))
+	}
+	return download.NewDownloader(c.streamClient, opts...)
+}
+
+// traced returns ctx with the client's tracer, unless it has none or ctx
+// already carries one.
+func (c *Client) traced(ctx context.Context) context.Context {
+	if c.tracer == nil || tracing.HasTracer(ctx) {
+		return ctx
+	}
+	return tracing.WithTracer(ctx, c.tracer)
+}
+
+// resolve parses a URL or ID, expanding short links, and checks it is of the wanted type.
+func (c *Client) resolve(ctx context.Context, url string, want youtube.QueryType) (youtube.QueryResult, error) {
+	query, err := youtube.ResolveQueryContext(ctx, url, youtube.NewURLExpander(c.httpClient))
+	if err != nil {
+		return query, err
+	}
+	if query.Type != want {
+		return query, fmt.Errorf("%w: %s is a %s", ErrUnsupportedURL, url, query.Type)
+	}
+	return query, nil
+}
+
+// GetVideo fetches the metadata and streams of the video at url, which can be any
+// video URL or a video ID.
+func (c *Client) GetVideo(ctx context.Context, url string) (*Video, error) {
+	ctx = c.traced(ctx)
+	query, err := c.resolve(ctx, url, youtube.QueryTypeVideo)
+	if err != nil {
+		return nil, err
+	}
+	return c.videoFetcher().FetchVideo(ctx, query.VideoID)
+}
+
+// GetPlaylist fetches the metadata and all videos of the playlist at url, which can
+// be a playlist URL or a playlist ID.
+func (c *Client) GetPlaylist(ctx context.Context, url string) (*Playlist, error) {
+	ctx = c.traced(ctx)
+	query, err := c.resolve(ctx, url, youtube.QueryTypePlaylist)
+	if err != nil {
+		return nil, err
+	}
+	var fetcher PlaylistFetcher = c.playlistFetcher()
+	if c.playlists != nil {
+		fetcher = c.playlists
+	}
+	playlist, videos, err := fetcher.Fetch(ctx, query.PlaylistID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching playlist: %w", err)
+	}
+	return &Playlist{Playlist: *playlist, Videos: videos}, nil
+}
+
+// PlaylistVideos returns an iterator over the videos of the playlist at url,
+// fetching further pages only as the videos are consumed. A URL that isn't a
+// playlist or a failed fetch is yielded as the only or last element.
+func (c *Client) PlaylistVideos(ctx context.Context, url string) iter.Seq2[youtube.PlaylistVideo, error] {
+	return func(yield func(youtube.PlaylistVideo, error) bool) {
+		ctx := c.traced(ctx)
+		query, err := c.resolve(ctx, url, youtube.QueryTypePlaylist)
+		if err != nil {
+			yield(youtube.PlaylistVideo{}, err)
+			return
+		}
+		videos := c.playlistFetcher().Videos(ctx, query.PlaylistID)
+		if c.playlists != nil {
+			videos = fetchedVideos(ctx, c.playlists, query.PlaylistID)
+		}
+		for video, err := range videos {
+			if err != nil {
+				yield(video, fmt.Errorf("fetching playlist: %w", err))
+				return
+			}
+			if !yield(video, nil) {
+				return
+			}
+		}
+	}
+}
+
+// fetchedVideos fetches the playlist playlistID in one go with fetcher and
+// yields its videos, for fetchers that don't fetch page by page.
+func fetchedVideos(ctx context.Context, fetcher PlaylistFetcher, playlistID youtube.PlaylistID) iter.Seq2[youtube.PlaylistVideo, error] {
+	return func(yield func(youtube.PlaylistVideo, error) bool) {
+		_, videos, err := fetcher.Fetch(ctx, playlistID)
+		if err != nil {
+			yield(youtube.PlaylistVideo{}, err)
+			return
+		}
+		for _, video := range videos {
+			if !yield(video, nil) {
+				return
+			}
+		}
+	}
+}
+
+// FetchVideo fetches the watch page of videoID with fetcher and returns the video's
+// metadata and streams. It returns a *youtube.UpcomingVideoError for premieres and
+// streams that haven't started and a *youtube.VideoUnavailableError when the video
+// can't be played. An invalid videoID fails with youtube.ErrInvalidVideoID
+// before any request is made.
+func FetchVideo(ctx context.Context, fetcher *youtube.WatchPageFetcher, videoID youtube.VideoID) (*Video, error) {
+	watchPage, err := fetcher.Fetch(ctx, videoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch video page: %w", err)
+	}
+
+	playerResponse, err := watchPage.ExtractPlayerResponse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract video data: %w", err)
+	}
+
+	status := &playerResponse.PlayabilityStatus
+	if status.IsUpcoming() {
+		return nil, &youtube.UpcomingVideoError{VideoID: videoID.String(), ScheduledStart: status.ScheduledStart(), Reason: status.Reason}
+	}
+	if status.Status != "OK" {
+		reason := status.Reason
+		if reason == "" {
+			reason = "unknown reason"
+		}
+		return nil, &youtube.VideoUnavailableError{VideoID: videoID.String(), Reason: reason}
+	}
+
+	video, err := playerResponse.ToVideo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse video metadata: %w", err)
+	}
+	if data, err := watchPage.ExtractWatchData(); err == nil {
+		video.RemixOf = data.RemixSource
+		video.Heatmap = data.Heatmap
+		video.LikeCount = data.LikeCount
+		video.Chapters = data.Chapters
+	}
+
+	if playerResponse.StreamingData == nil {
+		return nil, errors.New("no streaming data available")
+	}
+	if err := fetcher.DecipherStreams(ctx, watchPage, playerResponse.StreamingData); err != nil {
+		return nil, fmt.Errorf("failed to decipher streams: %w", err)
+	}
+	if err := fetcher.AddPOToken(ctx, watchPage, playerResponse.StreamingData); err != nil {
+		return nil, fmt.Errorf("failed to add PO token: %w", err)
+	}
+	return &Video{
+		Video:    *video,
+		Streams:  playerResponse.StreamingData.GetStreamManifest(),
+		Captions: playerResponse.ExtractCaptionManifest(),
+	}, nil
+}
@@ -0,0 +1,131 @@
+package ytdl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// testPlayerResponse is a playable video with a muxed stream and separate video
+// and audio streams. STREAM_URL is replaced with the test server's URL.
+const testPlayerResponse = `{
+	"videoDetails": {
+		"videoId": "dQw4w9WgXcQ",
+		"title": "Test Video",
+		"author": "Test Channel",
+		"lengthSeconds": "120"
+	},
+	"playabilityStatus": {"status": "OK"},
+	"streamingData": {
+		"formats": [
+			{"itag": 18, "url": "STREAM_URL/muxed", "mimeType": "video/mp4; codecs=\"avc1.42001E, mp4a.40.2\"", "width": 640, "height": 360, "qualityLabel": "360p", "contentLength": "5"}
+		],
+		"adaptiveFormats": [
+			{"itag": 137, "url": "STREAM_URL/video", "mimeType": "video/mp4; codecs=\"avc1.640028\"", "width": 1920, "height": 1080, "qualityLabel": "1080p", "contentLength": "5"},
+			{"itag": 140, "url": "STREAM_URL/audio", "mimeType": "audio/mp4; codecs=\"mp4a.40.2\"", "bitrate": 128000, "contentLength": "5"}
+		]
+	}
+}`
+
+// newTestServer serves a watch page for playerResponse and answers any other path
+// with the path itself, so downloaded files show which stream they came from.
+func newTestServer(t *testing.T, playerResponse string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/watch" {
+			html := `<!DOCTYPE html><script>var ytInitialPlayerResponse = ` + playerResponse + `;</script>`
+			_, _ = w.Write([]byte(strings.ReplaceAll(html, "STREAM_URL", server.URL)))
+			return
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestClient(server *httptest.Server) *Client {
+	return NewClient(WithHTTPClient(server.Client()), WithBaseURL(server.URL))
+}
+
+func TestClient_GetVideo(t *testing.T) {
+	server := newTestServer(t, testPlayerResponse)
+
+	video, err := newTestClient(server).GetVideo(context.Background(), "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatalf("GetVideo() error = %v", err)
+	}
+	if video.Title != "Test Video" || video.Author.Name != "Test Channel" {
+		t.Errorf("video = %+v", video.Video)
+	}
+	if len(video.Streams.VideoStreams) != 1 || len(video.Streams.AudioStreams) != 1 || len(video.Streams.MuxedStreams) != 1 {
+		t.Errorf("streams = %+v", video.Streams)
+	}
+}
+
+func TestClient_GetVideoAddsPOToken(t *testing.T) {
+	server := newTestServer(t, testPlayerResponse)
+	client := NewClient(WithHTTPClient(server.Client()), WithBaseURL(server.URL),
+		WithPOTokenProvider(youtube.StaticPOToken("tok"), "CgtWaXNpdG9y"))
+
+	video, err := client.GetVideo(context.Background(), "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatalf("GetVideo() error = %v", err)
+	}
+	if got := video.Streams.AudioStreams[0].URL; got != server.URL+"/audio?pot=tok" {
+		t.Errorf("audio URL = %q, want the token added", got)
+	}
+}
+
+func TestClient_GetVideoCaptions(t *testing.T) {
+	withCaptions := strings.Replace(testPlayerResponse, `"playabilityStatus"`,
+		`"captions": {"playerCaptionsTracklistRenderer": {"captionTracks": [
+			{"baseUrl": "STREAM_URL/captions", "languageCode": "en", "name": {"simpleText": "English"}},
+			{"baseUrl": "STREAM_URL/captions?lang=de", "languageCode": "de", "kind": "asr", "name": {"simpleText": "German (auto-generated)"}}
+		]}},
+		"playabilityStatus"`, 1)
+	server := newTestServer(t, withCaptions)
+
+	video, err := newTestClient(server).GetVideo(context.Background(), "dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatalf("GetVideo() error = %v", err)
+	}
+	if len(video.Captions.Tracks) != 2 {
+		t.Fatalf("caption tracks = %+v, want 2", video.Captions.Tracks)
+	}
+	if track := video.Captions.GetTrackByLanguage("de"); track == nil || !track.IsAutoGenerated {
+		t.Errorf("German track = %+v, want auto-generated", track)
+	}
+}
+
+func TestClient_GetVideoUnavailable(t *testing.T) {
+	server := newTestServer(t, `{"playabilityStatus": {"status": "ERROR", "reason": "Video unavailable"}}`)
+
+	_, err := newTestClient(server).GetVideo(context.Background(), "dQw4w9WgXcQ")
+	var unavailable *youtube.VideoUnavailableError
+	if !errors.As(err, &unavailable) || unavailable.Reason != "Video unavailable" {
+		t.Errorf("GetVideo() error = %v, want VideoUnavailableError", err)
+	}
+}
+
+func TestClient_UnsupportedURL(t *testing.T) {
+	client := NewClient()
+	ctx := context.Background()
+
+	if _, err := client.GetVideo(ctx, "https://www.youtube.com/playlist?list=PLABCDEFGHIJKLMNOPQRSTUVWXYZ012345"); !errors.Is(err, ErrUnsupportedURL) {
+		t.Errorf("GetVideo(playlist) error = %v, want %v", err, ErrUnsupportedURL)
+	}
+	if _, err := client.GetPlaylist(ctx, "dQw4w9WgXcQ"); !errors.Is(err, ErrUnsupportedURL) {
+		t.Errorf("GetPlaylist(video) error = %v, want %v", err, ErrUnsupportedURL)
+	}
+	for _, err := range client.PlaylistVideos(ctx, "dQw4w9WgXcQ") {
+		if !errors.Is(err, ErrUnsupportedURL) {
+			t.Errorf("PlaylistVideos(video) error = %v, want %v", err, ErrUnsupportedURL)
+		}
+	}
+}
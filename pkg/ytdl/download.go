@@ -0,0 +1,287 @@
+package ytdl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/ffmpeg"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/filename"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/mux"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tracing"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// Option configures a download.
+type Option func(*options)
+
+// options holds the settings of a download.
+type options struct {
+	quality      youtube.VideoQualityPreference
+	preferences  youtube.SelectionPreferences
+	container    youtube.Container
+	audioOnly    bool
+	outputDir    string
+	outputFile   string
+	tempDir      string
+	template     string
+	filenameOpts filename.Options
+	progress     download.ProgressCallback
+	overwrite    download.OverwritePolicy
+}
+
+// WithQuality sets the highest video quality to download. The default is the best available.
+func WithQuality(quality youtube.VideoQualityPreference) Option {
+	return func(o *options) {
+		o.quality = quality
+	}
+}
+
+// WithSelectionPreferences sets how streams of the same quality are chosen
+// between, by codec, framerate and dynamic range.
+func WithSelectionPreferences(prefs youtube.SelectionPreferences) Option {
+	return func(o *options) {
+		o.preferences = prefs
+	}
+}
+
+// WithContainer sets the preferred output container. The default is MP4.
+func WithContainer(container youtube.Container) Option {
+	return func(o *options) {
+		o.container = container
+	}
+}
+
+// WithAudioOnly downloads the best audio stream alone, in its own container.
+func WithAudioOnly() Option {
+	return func(o *options) {
+		o.audioOnly = true
+	}
+}
+
+// WithOutputDir sets the directory files are saved to. The default is the current directory.
+func WithOutputDir(dir string) Option {
+	return func(o *options) {
+		o.outputDir = dir
+	}
+}
+
+// WithOutputFile sets the path of the downloaded file, replacing the output
+// directory and file name template.
+func WithOutputFile(path string) Option {
+	return func(o *options) {
+		o.outputFile = path
+	}
+}
+
+// WithTempDir sets the directory separate video and audio streams are downloaded
+// to before they are muxed. The default is the output file's directory, so the
+// muxed file is renamed into place rather than copied from another filesystem.
+func WithTempDir(dir string) Option {
+	return func(o *options) {
+		o.tempDir = dir
+	}
+}
+
+// WithFilenameTemplate sets the template file names are generated from, such as
+// "$author - $title". The default is filename.DefaultTemplate.
+func WithFilenameTemplate(template string) Option {
+	return func(o *options) {
+		o.template = template
+	}
+}
+
+// WithFilenameOptions sets how generated file names are sanitized.
+func WithFilenameOptions(opts filename.Options) Option {
+	return func(o *options) {
+		o.filenameOpts = opts
+	}
+}
+
+// WithProgress sets a callback receiving the combined progress of the streams
+// being downloaded. Streams download in parallel, so it may be called from
+// several goroutines at once.
+func WithProgress(progress download.ProgressCallback) Option {
+	return func(o *options) {
+		o.progress = progress
+	}
+}
+
+// WithOverwritePolicy sets what happens when the output file already exists.
+// The default overwrites it.
+func WithOverwritePolicy(policy download.OverwritePolicy) Option {
+	return func(o *options) {
+		o.overwrite = policy
+	}
+}
+
+// Result describes a finished download.
+type Result struct {
+	// Video is the downloaded video.
+	Video *Video
+
+	// Selection is the streams that were downloaded.
+	Selection *Selection
+
+	// FilePath is where the video was saved.
+	FilePath string
+
+	// Size is the size of the saved file in bytes.
+	Size int64
+
+	// Skipped reports that the file already existed and the overwrite policy kept it.
+	Skipped bool
+}
+
+// Download fetches the video at url and downloads it with the given options.
+// Separate video and audio streams are downloaded in parallel and muxed, natively
+// when possible and with FFmpeg otherwise.
+func (c *Client) Download(ctx context.Context, url string, opts ...Option) (*Result, error) {
+	video, err := c.GetVideo(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return c.DownloadVideo(ctx, video, opts...)
+}
+
+// DownloadVideo downloads a video fetched with GetVideo with the given options.
+func (c *Client) DownloadVideo(ctx context.Context, video *Video, opts ...Option) (_ *Result, err error) {
+	ctx = tracing.WithAttributes(c.traced(ctx), tracing.String(tracing.AttrVideoID, video.ID))
+	ctx, span := tracing.Start(ctx, tracing.SpanDownload)
+	defer func() { tracing.End(span, err) }()
+
+	o := &options{
+		quality:   youtube.QualityHighest,
+		container: youtube.ContainerMP4,
+		outputDir: ".",
+		template:  filename.DefaultTemplate,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	selection, err := SelectStreamsWithPreferences(video.Streams, o.quality, o.container, o.audioOnly, o.preferences)
+	if err != nil {
+		return nil, err
+	}
+
+	path := o.outputFile
+	if path == "" {
+		outputFilename := filename.ApplyTemplateWithOptions(o.template, &video.Video, string(outputContainer(selection)), "", o.filenameOpts)
+		path = filepath.Join(o.outputDir, outputFilename)
+	}
+	path, skip, err := download.ResolveTarget(path, o.overwrite)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Video: video, Selection: selection, FilePath: path, Skipped: skip}
+	if skip {
+		return result, nil
+	}
+
+	// The output needs room for the file and, when muxing, the temp directory for the streams
+	size := selection.EstimatedSize(video.Duration)
+	requirements := []download.SpaceRequirement{{Dir: filepath.Dir(path), Bytes: size}}
+	if selection.NeedsMux() {
+		requirements = append(requirements, download.SpaceRequirement{Dir: download.TempParent(o.tempDir, path), Bytes: size})
+	}
+	if err := download.CheckDiskSpace(requirements...); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+	if err := c.downloadSelection(ctx, video, selection, path, o.tempDir, o.progress); err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		result.Size = info.Size()
+	}
+	return result, nil
+}
+
+// outputContainer returns the container of the file a selection is saved as.
+func outputContainer(selection *Selection) youtube.Container {
+	switch {
+	case selection.NeedsMux():
+		return selection.Container
+	case selection.Video != nil:
+		return selection.Video.Container
+	default:
+		return selection.Audio.Container
+	}
+}
+
+// downloadSelection downloads the selected streams to path, muxing separate
+// video and audio streams downloaded to a directory in tempDir.
+func (c *Client) downloadSelection(ctx context.Context, video *Video, selection *Selection, path, tempDir string, progress download.ProgressCallback) error {
+	downloader := download.WithRefresher(c.streamDownloader(), FetcherRefresher(c.videoFetcher(), youtube.VideoID(video.ID)))
+	if !selection.NeedsMux() {
+		var stream *youtube.StreamInfo
+		if selection.Video != nil {
+			stream = &selection.Video.StreamInfo
+		} else {
+			stream = &selection.Audio.StreamInfo
+		}
+		if index := segmentIndex(stream); index != nil {
+			ctx = download.WithSegmentIndex(ctx, *index)
+		}
+		if err := downloader.DownloadStreamResult(ctx, stream.URL, path, progress).Error; err != nil {
+			return fmt.Errorf("downloading stream: %w", err)
+		}
+		return nil
+	}
+
+	tempDir, err := download.MkdirTemp(tempDir, path)
+	if err != nil {
+		return fmt.Errorf("creating temp directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	streams := []download.StreamDownload{
+		{URL: selection.Video.URL, FilePath: filepath.Join(tempDir, "video."+string(selection.Video.Container)), Index: segmentIndex(&selection.Video.StreamInfo)},
+		{URL: selection.Audio.URL, FilePath: filepath.Join(tempDir, "audio."+string(selection.Audio.Container)), Index: segmentIndex(&selection.Audio.StreamInfo)},
+	}
+	results := download.DownloadParallel(ctx, downloader, streams, progress)
+	if err := results[0].Error; err != nil {
+		return fmt.Errorf("downloading video stream: %w", err)
+	}
+	if err := results[1].Error; err != nil {
+		return fmt.Errorf("downloading audio stream: %w", err)
+	}
+
+	// Muxing next to the streams leaves no partial file at path if it fails
+	muxed := filepath.Join(tempDir, "muxed"+filepath.Ext(path))
+	if err := c.muxer(ctx, streams[0].FilePath, streams[1].FilePath, muxed, video.Duration); err != nil {
+		return fmt.Errorf("muxing streams: %w", err)
+	}
+	if err := download.MoveFile(muxed, path); err != nil {
+		return fmt.Errorf("moving muxed file into place: %w", err)
+	}
+	return nil
+}
+
+// segmentIndex returns the segment index YouTube reported for stream, so it's
+// downloaded in ranges, or nil for streams downloaded whole.
+func segmentIndex(stream *youtube.StreamInfo) *download.ByteRange {
+	if stream.IndexRange == nil {
+		return nil
+	}
+	return &download.ByteRange{Start: stream.IndexRange.Start, End: stream.IndexRange.End}
+}
+
+// muxStreams combines video and audio with the native muxer, falling back to
+// FFmpeg when the streams can't be muxed natively.
+func muxStreams(ctx context.Context, videoPath, audioPath, outputPath string, duration time.Duration) error {
+	err := mux.Mux(ctx, videoPath, audioPath, outputPath, nil)
+	if errors.Is(err, mux.ErrUnsupported) {
+		return ffmpeg.MuxStreamsWithProgress(ctx, videoPath, audioPath, outputPath, duration, nil)
+	}
+	return err
+}
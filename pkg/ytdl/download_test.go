@@ -0,0 +1,199 @@
+package ytdl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ytdlhttp "github.com/SakuraBurst/golang-youtube-downloader/internal/http"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/tracing"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+func TestClient_Download(t *testing.T) {
+	server := newTestServer(t, testPlayerResponse)
+	client := newTestClient(server)
+
+	var muxed [2]string
+	client.muxer = func(_ context.Context, videoPath, audioPath, outputPath string, duration time.Duration) error {
+		if duration != 2*time.Minute {
+			t.Errorf("duration = %v", duration)
+		}
+		video, _ := os.ReadFile(videoPath)
+		audio, _ := os.ReadFile(audioPath)
+		muxed = [2]string{string(video), string(audio)}
+		return os.WriteFile(outputPath, []byte("muxed"), 0o644)
+	}
+
+	dir := t.TempDir()
+	var progressed atomic.Bool
+	result, err := client.Download(context.Background(), "dQw4w9WgXcQ",
+		WithOutputDir(dir),
+		WithProgress(func(download.Progress) { progressed.Store(true) }),
+	)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if result.FilePath != filepath.Join(dir, "Test Video.mp4") || result.Size != int64(len("muxed")) {
+		t.Errorf("result = %+v", result)
+	}
+	if muxed != [2]string{"/video", "/audio"} {
+		t.Errorf("muxed %v, want the adaptive streams", muxed)
+	}
+	if !progressed.Load() {
+		t.Error("expected progress to be reported")
+	}
+}
+
+func TestClient_DownloadFromCassette(t *testing.T) {
+	cassette, err := ytdlhttp.LoadCassette(filepath.Join("testdata", "watch.cassette.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient, err := ytdlhttp.NewClientWithOptions(ytdlhttp.Options{Cassette: cassette})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "audio.m4a")
+
+	result, err := NewClient(WithHTTPClient(httpClient)).Download(context.Background(), "dQw4w9WgXcQ", WithAudioOnly(), WithOutputFile(output))
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if result.Video.Title != "Recorded Video" {
+		t.Errorf("title = %q", result.Video.Title)
+	}
+	if data, _ := os.ReadFile(result.FilePath); string(data) != "audio" {
+		t.Errorf("downloaded %q, want the recorded stream", data)
+	}
+}
+
+func TestClient_DownloadAudioOnly(t *testing.T) {
+	server := newTestServer(t, testPlayerResponse)
+	output := filepath.Join(t.TempDir(), "sub", "audio.m4a")
+
+	result, err := newTestClient(server).Download(context.Background(), "dQw4w9WgXcQ", WithAudioOnly(), WithOutputFile(output))
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if data, _ := os.ReadFile(result.FilePath); string(data) != "/audio" {
+		t.Errorf("downloaded %q, want the audio stream", data)
+	}
+}
+
+func TestClient_DownloadEventListener(t *testing.T) {
+	server := newTestServer(t, testPlayerResponse)
+	var started, finished atomic.Int32
+	client := NewClient(WithHTTPClient(server.Client()), WithBaseURL(server.URL), WithEventListener(download.EventListenerFunc(func(e download.Event) {
+		switch e.Type {
+		case download.EventStarted:
+			started.Add(1)
+		case download.EventCompleted:
+			finished.Add(1)
+		}
+	})))
+
+	if _, err := client.Download(context.Background(), "dQw4w9WgXcQ", WithAudioOnly(), WithOutputDir(t.TempDir())); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if started.Load() != 1 || finished.Load() != 1 {
+		t.Errorf("started %d, finished %d, want the audio stream's download", started.Load(), finished.Load())
+	}
+}
+
+func TestClient_DownloadOverwritePolicy(t *testing.T) {
+	server := newTestServer(t, testPlayerResponse)
+	client := newTestClient(server)
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "Test Video.mp4")
+	if err := os.WriteFile(existing, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	video, err := client.GetVideo(context.Background(), "dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatalf("GetVideo() error = %v", err)
+	}
+
+	result, err := client.DownloadVideo(context.Background(), video, WithOutputDir(dir), WithOverwritePolicy(download.SkipExisting))
+	if err != nil {
+		t.Fatalf("DownloadVideo() error = %v", err)
+	}
+	if !result.Skipped {
+		t.Error("expected the download to be skipped")
+	}
+
+	// The muxed stream at 360p needs no muxing
+	result, err = client.DownloadVideo(context.Background(), video, WithOutputDir(dir),
+		WithQuality(youtube.QualityUpTo360p), WithOverwritePolicy(download.RenameExisting))
+	if err != nil {
+		t.Fatalf("DownloadVideo() error = %v", err)
+	}
+	if result.FilePath != filepath.Join(dir, "Test Video (1).mp4") {
+		t.Errorf("FilePath = %q", result.FilePath)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "old" {
+		t.Error("existing file was overwritten")
+	}
+}
+
+func TestClient_DownloadInsufficientDiskSpace(t *testing.T) {
+	// The video stream has no content length, so its size is estimated from the bitrate
+	playerResponse := strings.Replace(testPlayerResponse,
+		`"qualityLabel": "1080p", "contentLength": "5"`, `"qualityLabel": "1080p", "averageBitrate": 1000000000000000`, 1)
+	server := newTestServer(t, playerResponse)
+	client := newTestClient(server)
+	client.muxer = func(context.Context, string, string, string, time.Duration) error {
+		t.Error("muxer should not run")
+		return nil
+	}
+
+	dir := t.TempDir()
+	_, err := client.Download(context.Background(), "dQw4w9WgXcQ", WithOutputDir(dir))
+
+	var spaceErr *download.InsufficientSpaceError
+	if !errors.As(err, &spaceErr) {
+		t.Fatalf("Download() error = %v, want InsufficientSpaceError", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Error("nothing should be downloaded when there isn't enough space")
+	}
+}
+
+func TestClient_DownloadTraced(t *testing.T) {
+	server := newTestServer(t, testPlayerResponse)
+	recorder := &tracing.Recorder{}
+	client := NewClient(WithHTTPClient(server.Client()), WithBaseURL(server.URL), WithTracer(recorder))
+	client.muxer = func(_ context.Context, _, _, outputPath string, _ time.Duration) error {
+		return os.WriteFile(outputPath, []byte("muxed"), 0o644)
+	}
+
+	if _, err := client.Download(context.Background(), "dQw4w9WgXcQ", WithOutputDir(t.TempDir())); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if spans := recorder.Named(tracing.SpanFetchWatchPage); len(spans) != 1 {
+		t.Errorf("watch page spans = %d, want 1", len(spans))
+	}
+	downloads := recorder.Named(tracing.SpanDownload)
+	if len(downloads) != 1 {
+		t.Fatalf("download spans = %d, want 1", len(downloads))
+	}
+	streams := recorder.Named(tracing.SpanStream)
+	if len(streams) != 2 {
+		t.Fatalf("stream spans = %d, want video and audio", len(streams))
+	}
+	for _, stream := range streams {
+		if stream.Parent != downloads[0] {
+			t.Errorf("stream span parent = %v, want the download", stream.Parent)
+		}
+		if id, _ := stream.Attribute(tracing.AttrVideoID); id != "dQw4w9WgXcQ" {
+			t.Errorf("stream span video ID = %v", id)
+		}
+	}
+}
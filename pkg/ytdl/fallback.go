@@ -0,0 +1,73 @@
+package ytdl
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// ShouldFallBack reports whether a failed download of a selection is worth
+// retrying with other streams: the stream was refused with 403 Forbidden, even
+// after its URL was refreshed, or its requests timed out. Other formats of the
+// same video are often served fine when one is throttled or blocked. Failures
+// caused by ctx ending are not.
+func ShouldFallBack(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var httpErr *download.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusForbidden
+	}
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// WithoutSelection returns a copy of the manifest without the streams of the
+// selection, so selecting from it again picks the next best streams: a lower
+// bitrate or height, or another codec.
+func WithoutSelection(manifest *youtube.StreamManifest, s *Selection) *youtube.StreamManifest {
+	var failed []*youtube.StreamInfo
+	if s.Video != nil {
+		failed = append(failed, &s.Video.StreamInfo)
+	}
+	if s.Audio != nil {
+		failed = append(failed, &s.Audio.StreamInfo)
+	}
+	isFailed := func(info *youtube.StreamInfo) bool {
+		return slices.ContainsFunc(failed, func(f *youtube.StreamInfo) bool { return sameStream(f, info) })
+	}
+
+	rest := &youtube.StreamManifest{}
+	for _, vs := range manifest.VideoStreams {
+		if !isFailed(&vs.StreamInfo) {
+			rest.VideoStreams = append(rest.VideoStreams, vs)
+		}
+	}
+	for _, as := range manifest.AudioStreams {
+		if !isFailed(&as.StreamInfo) {
+			rest.AudioStreams = append(rest.AudioStreams, as)
+		}
+	}
+	for _, ms := range manifest.MuxedStreams {
+		if !isFailed(&ms.VideoStreamInfo.StreamInfo) {
+			rest.MuxedStreams = append(rest.MuxedStreams, ms)
+		}
+	}
+	return rest
+}
+
+// sameStream reports whether two streams are the same format. Formats are told
+// apart by itag, since refreshing a stream changes its URL, and by URL when
+// the itags are unknown.
+func sameStream(a, b *youtube.StreamInfo) bool {
+	if a.Itag != 0 || b.Itag != 0 {
+		return a.Itag == b.Itag
+	}
+	return a.URL == b.URL
+}
@@ -0,0 +1,88 @@
+package ytdl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// timeoutError is a net.Error that timed out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestShouldFallBack(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"forbidden", context.Background(), fmt.Errorf("failed to download video: %w", &download.HTTPError{StatusCode: 403, Status: "403 Forbidden"}), true},
+		{"timeout", context.Background(), fmt.Errorf("download failed: %w", timeoutError{}), true},
+		{"deadline", context.Background(), context.DeadlineExceeded, true},
+		{"not found", context.Background(), &download.HTTPError{StatusCode: 404, Status: "404 Not Found"}, false},
+		{"other", context.Background(), errors.New("disk full"), false},
+		{"canceled", canceled, timeoutError{}, false},
+		{"nil", context.Background(), nil, false},
+	}
+	for _, tt := range tests {
+		if got := ShouldFallBack(tt.ctx, tt.err); got != tt.want {
+			t.Errorf("%s: ShouldFallBack() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWithoutSelection(t *testing.T) {
+	manifest := testManifest()
+
+	selection, err := SelectStreams(manifest, youtube.QualityHighest, youtube.ContainerMP4, false)
+	if err != nil {
+		t.Fatalf("SelectStreams() error = %v", err)
+	}
+	manifest = WithoutSelection(manifest, selection)
+	if selection, err = SelectStreams(manifest, youtube.QualityHighest, youtube.ContainerMP4, false); err != nil {
+		t.Fatalf("SelectStreams() after the first failed error = %v", err)
+	}
+	if selection.Video.URL != "v720" || selection.Audio.URL != "a160" {
+		t.Errorf("fallback selected video %q audio %q, want v720 and a160", selection.Video.URL, selection.Audio.URL)
+	}
+
+	manifest = WithoutSelection(manifest, selection)
+	if selection, err = SelectStreams(manifest, youtube.QualityHighest, youtube.ContainerMP4, false); err != nil {
+		t.Fatalf("SelectStreams() after the second failed error = %v", err)
+	}
+	if selection.Video.URL != "muxed" {
+		t.Errorf("fallback selected %+v, want the muxed stream", selection)
+	}
+
+	manifest = WithoutSelection(manifest, selection)
+	if _, err := SelectStreams(manifest, youtube.QualityHighest, youtube.ContainerMP4, false); err == nil {
+		t.Error("expected an error once every stream failed")
+	}
+}
+
+func TestWithoutSelection_ByItag(t *testing.T) {
+	manifest := &youtube.StreamManifest{AudioStreams: []youtube.AudioStreamInfo{
+		{StreamInfo: youtube.StreamInfo{Itag: 251, URL: "refreshed", Bitrate: 160000}},
+		{StreamInfo: youtube.StreamInfo{Itag: 140, URL: "a128", Bitrate: 128000}},
+	}}
+	failed := &Selection{Audio: &youtube.AudioStreamInfo{StreamInfo: youtube.StreamInfo{Itag: 251, URL: "stale"}}}
+
+	rest := WithoutSelection(manifest, failed)
+	if len(rest.AudioStreams) != 1 || rest.AudioStreams[0].Itag != 140 {
+		t.Errorf("WithoutSelection() audio = %+v, want only itag 140", rest.AudioStreams)
+	}
+	if len(manifest.AudioStreams) != 2 {
+		t.Error("WithoutSelection() changed the manifest")
+	}
+}
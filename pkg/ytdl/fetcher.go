@@ -0,0 +1,49 @@
+package ytdl
+
+import (
+	"context"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// VideoFetcher fetches the metadata and streams of videos. WatchPageVideos
+// fetches them from the watch page; other implementations can cache videos,
+// instrument fetches or return fakes in tests.
+type VideoFetcher interface {
+	// FetchVideo fetches the video videoID, failing like the package-level
+	// FetchVideo for upcoming and unavailable videos.
+	FetchVideo(ctx context.Context, videoID youtube.VideoID) (*Video, error)
+}
+
+// PlaylistFetcher fetches the metadata and videos of playlists.
+// *youtube.PlaylistFetcher implements it.
+type PlaylistFetcher interface {
+	// Fetch fetches the playlist playlistID and all of its videos.
+	Fetch(ctx context.Context, playlistID youtube.PlaylistID) (*youtube.Playlist, []youtube.PlaylistVideo, error)
+}
+
+var _ PlaylistFetcher = (*youtube.PlaylistFetcher)(nil)
+
+// WatchPageVideos is the VideoFetcher that fetches videos from their watch
+// pages with Fetcher.
+type WatchPageVideos struct {
+	Fetcher *youtube.WatchPageFetcher
+}
+
+// FetchVideo fetches videoID with FetchVideo.
+func (v WatchPageVideos) FetchVideo(ctx context.Context, videoID youtube.VideoID) (*Video, error) {
+	return FetchVideo(ctx, v.Fetcher, videoID)
+}
+
+// Uncached returns a copy of v that bypasses the watch page cache, which
+// FetcherRefresher uses since a cached page has the same expired stream URLs.
+func (v WatchPageVideos) Uncached() VideoFetcher {
+	uncached := *v.Fetcher
+	uncached.Cache = nil
+	return WatchPageVideos{Fetcher: &uncached}
+}
+
+// uncacheable is implemented by video fetchers that can bypass their cache.
+type uncacheable interface {
+	Uncached() VideoFetcher
+}
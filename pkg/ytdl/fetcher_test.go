@@ -0,0 +1,106 @@
+package ytdl
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// fakeVideos returns the same video for every ID and counts the fetches.
+type fakeVideos struct {
+	video   *Video
+	fetches int
+}
+
+func (f *fakeVideos) FetchVideo(_ context.Context, videoID youtube.VideoID) (*Video, error) {
+	f.fetches++
+	video := *f.video
+	video.ID = videoID.String()
+	return &video, nil
+}
+
+// fakeDownloader writes each stream's URL as its content.
+type fakeDownloader struct {
+	urls []string
+}
+
+func (f *fakeDownloader) DownloadStreamResult(_ context.Context, url, filePath string, _ download.ProgressCallback) download.DownloadResult {
+	f.urls = append(f.urls, url)
+	err := os.WriteFile(filePath, []byte(url), 0o644)
+	return download.DownloadResult{FilePath: filePath, Error: err, Size: int64(len(url))}
+}
+
+func (f *fakeDownloader) DownloadStreamTo(_ context.Context, url string, dst io.Writer, _ download.ProgressCallback) error {
+	f.urls = append(f.urls, url)
+	_, err := io.WriteString(dst, url)
+	return err
+}
+
+func TestClient_CustomFetcherAndDownloader(t *testing.T) {
+	videos := &fakeVideos{video: &Video{Video: youtube.Video{Title: "Fake Video"}, Streams: testManifest()}}
+	downloader := &fakeDownloader{}
+	client := NewClient(WithVideoFetcher(videos), WithStreamDownloader(downloader), WithBaseURL("http://127.0.0.1:0"))
+
+	dir := t.TempDir()
+	result, err := client.Download(context.Background(), "dQw4w9WgXcQ", WithAudioOnly(), WithOutputDir(dir))
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if videos.fetches != 1 {
+		t.Errorf("fetches = %d, want 1", videos.fetches)
+	}
+	if len(downloader.urls) != 1 || downloader.urls[0] != "a160" {
+		t.Errorf("downloaded %v, want the best audio stream", downloader.urls)
+	}
+	if result.FilePath != filepath.Join(dir, "Fake Video.webm") {
+		t.Errorf("FilePath = %q", result.FilePath)
+	}
+}
+
+// fakePlaylists returns two videos for any playlist.
+type fakePlaylists struct{}
+
+func (fakePlaylists) Fetch(_ context.Context, playlistID youtube.PlaylistID) (*youtube.Playlist, []youtube.PlaylistVideo, error) {
+	videos := []youtube.PlaylistVideo{{ID: "aaaaaaaaaaa"}, {ID: "bbbbbbbbbbb"}}
+	return &youtube.Playlist{ID: playlistID.String(), Title: "Fake Playlist"}, videos, nil
+}
+
+func TestClient_CustomPlaylistFetcher(t *testing.T) {
+	client := NewClient(WithPlaylistFetcher(fakePlaylists{}))
+	const url = "PLrAXtmErZgOeiKm4sgNOknGvNjby9efdf"
+
+	playlist, err := client.GetPlaylist(context.Background(), url)
+	if err != nil {
+		t.Fatalf("GetPlaylist() error = %v", err)
+	}
+	if playlist.Title != "Fake Playlist" || len(playlist.Videos) != 2 {
+		t.Errorf("playlist = %+v", playlist)
+	}
+
+	var ids []string
+	for video, err := range client.PlaylistVideos(context.Background(), url) {
+		if err != nil {
+			t.Fatalf("PlaylistVideos() error = %v", err)
+		}
+		ids = append(ids, video.ID)
+	}
+	if len(ids) != 2 || ids[1] != "bbbbbbbbbbb" {
+		t.Errorf("PlaylistVideos() = %v", ids)
+	}
+}
+
+func TestWatchPageVideos_Uncached(t *testing.T) {
+	fetcher := &youtube.WatchPageFetcher{Cache: &countingCache{}}
+	uncached, ok := WatchPageVideos{Fetcher: fetcher}.Uncached().(WatchPageVideos)
+	if !ok || uncached.Fetcher.Cache != nil {
+		t.Errorf("Uncached() = %+v, want a fetcher without a cache", uncached)
+	}
+	if fetcher.Cache == nil {
+		t.Error("Uncached() changed the original fetcher")
+	}
+}
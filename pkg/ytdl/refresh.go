@@ -0,0 +1,92 @@
+package ytdl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/download"
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// StreamRefresher returns a download.URLRefresher that fetches the watch page of
+// videoID again and returns the new URL of the format the stale URL was for,
+// identified by its itag parameter. The page cache is bypassed, since a cached
+// page has the same expired URLs. Streams of one video refreshed together, such
+// as video and audio downloaded in parallel, share one fetch.
+func StreamRefresher(fetcher *youtube.WatchPageFetcher, videoID youtube.VideoID) download.URLRefresher {
+	return FetcherRefresher(WatchPageVideos{Fetcher: fetcher}, videoID)
+}
+
+// FetcherRefresher is StreamRefresher for any VideoFetcher. A fetcher with an
+// Uncached method, such as WatchPageVideos, is refreshed through the copy it
+// returns.
+func FetcherRefresher(fetcher VideoFetcher, videoID youtube.VideoID) download.URLRefresher {
+	if u, ok := fetcher.(uncacheable); ok {
+		fetcher = u.Uncached()
+	}
+	r := &streamRefresher{fetcher: fetcher, videoID: videoID}
+	return r.refresh
+}
+
+// streamRefresher remembers the last manifest fetched for a video.
+type streamRefresher struct {
+	fetcher VideoFetcher
+	videoID youtube.VideoID
+
+	mu       sync.Mutex
+	manifest *youtube.StreamManifest
+}
+
+func (r *streamRefresher) refresh(ctx context.Context, staleURL string) (string, error) {
+	u, err := url.Parse(staleURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing stream URL: %w", err)
+	}
+	itag, err := strconv.Atoi(u.Query().Get("itag"))
+	if err != nil {
+		return "", fmt.Errorf("stream URL has no itag: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if info := streamByItag(r.manifest, itag); info != nil && info.URL != staleURL && !expiresSoon(info) {
+		return info.URL, nil
+	}
+
+	video, err := r.fetcher.FetchVideo(ctx, r.videoID)
+	if err != nil {
+		return "", err
+	}
+	r.manifest = video.Streams
+
+	info := streamByItag(r.manifest, itag)
+	if info == nil || info.URL == "" {
+		return "", fmt.Errorf("format %d is no longer available", itag)
+	}
+	return info.URL, nil
+}
+
+// streamByItag returns the stream with the given itag in manifest, or nil.
+func streamByItag(manifest *youtube.StreamManifest, itag int) *youtube.StreamInfo {
+	if manifest == nil {
+		return nil
+	}
+	if vs := manifest.VideoStreamByItag(itag); vs != nil {
+		return &vs.StreamInfo
+	}
+	if as := manifest.AudioStreamByItag(itag); as != nil {
+		return &as.StreamInfo
+	}
+	return nil
+}
+
+// expiresSoon reports whether the stream's URL expires within the next minute.
+func expiresSoon(info *youtube.StreamInfo) bool {
+	expiry := download.URLExpiry(info.URL)
+	return !expiry.IsZero() && time.Until(expiry) < time.Minute
+}
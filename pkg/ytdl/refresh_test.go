@@ -0,0 +1,66 @@
+package ytdl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+func TestStreamRefresher(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		playerResponse := strings.ReplaceAll(testPlayerResponse, "STREAM_URL/video", "https://cdn.example/new?itag=137")
+		playerResponse = strings.ReplaceAll(playerResponse, "STREAM_URL/audio", "https://cdn.example/new?itag=140")
+		_, _ = w.Write([]byte(`<script>var ytInitialPlayerResponse = ` + playerResponse + `;</script>`))
+	}))
+	t.Cleanup(server.Close)
+
+	cache := &countingCache{}
+	fetcher := &youtube.WatchPageFetcher{Client: server.Client(), BaseURL: server.URL, Cache: cache}
+	refresh := StreamRefresher(fetcher, "dQw4w9WgXcQ")
+
+	fresh, err := refresh(context.Background(), "https://cdn.example/old?itag=137&expire=1")
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if fresh != "https://cdn.example/new?itag=137" {
+		t.Errorf("refreshed URL = %q", fresh)
+	}
+
+	// The audio stream of the same video reuses the page just fetched
+	fresh, err = refresh(context.Background(), "https://cdn.example/old?itag=140&expire=1")
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if fresh != "https://cdn.example/new?itag=140" || fetches.Load() != 1 {
+		t.Errorf("refreshed URL = %q after %d fetches, want the new audio URL after 1", fresh, fetches.Load())
+	}
+	if cache.gets.Load() != 0 {
+		t.Error("refreshing should bypass the page cache")
+	}
+
+	if _, err := refresh(context.Background(), "https://cdn.example/old?itag=22"); err == nil {
+		t.Error("expected an error for a format that is no longer offered")
+	}
+	if _, err := refresh(context.Background(), "https://cdn.example/old"); err == nil {
+		t.Error("expected an error for a URL without an itag")
+	}
+}
+
+// countingCache is a youtube.Cache that stores nothing and counts lookups.
+type countingCache struct {
+	gets atomic.Int32
+}
+
+func (c *countingCache) Get(string) ([]byte, bool) {
+	c.gets.Add(1)
+	return nil, false
+}
+
+func (c *countingCache) Set(string, []byte) {}
@@ -0,0 +1,160 @@
+package ytdl
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+// Selection is the set of streams chosen for a download.
+type Selection struct {
+	// Quality is the selected quality label, empty when a fallback stream was used.
+	Quality string
+
+	// Container is the output container when video and audio are muxed.
+	Container youtube.Container
+
+	// Video is the video-only or muxed stream, nil for audio-only downloads.
+	Video *youtube.VideoStreamInfo
+
+	// Audio is a separate audio stream, nil when Video already carries the audio.
+	Audio *youtube.AudioStreamInfo
+
+	// Inaccessible, when set, is why a better quality wasn't selected: it is
+	// only offered in a format that can't be downloaded.
+	Inaccessible *youtube.InaccessibleFormatError
+}
+
+// NeedsMux reports whether separate video and audio streams must be muxed.
+func (s *Selection) NeedsMux() bool {
+	return s.Video != nil && s.Audio != nil
+}
+
+// EstimatedSize returns the combined size of the selected streams in bytes,
+// estimated from the bitrate and duration for streams without a reported length.
+func (s *Selection) EstimatedSize(duration time.Duration) int64 {
+	var total int64
+	if s.Video != nil {
+		total += s.Video.EstimatedSize(duration)
+	}
+	if s.Audio != nil {
+		total += s.Audio.EstimatedSize(duration)
+	}
+	return total
+}
+
+// SelectStreams picks the streams to download from the manifest: the best audio
+// stream when audioOnly is set, or the best Opus one for the Opus and Ogg
+// containers, otherwise the best video up to the quality in the container, with
+// a separate audio stream to mux when the video has none. Muxed streams are used
+// when there is no suitable adaptive stream. Streams that can't be downloaded are
+// never selected; when the quality is only offered in them, the error is an
+// *youtube.InaccessibleFormatError, or the selection's Inaccessible says so if
+// a lower quality could be selected instead.
+func SelectStreams(manifest *youtube.StreamManifest, quality youtube.VideoQualityPreference, container youtube.Container, audioOnly bool) (*Selection, error) {
+	return SelectStreamsWithPreferences(manifest, quality, container, audioOnly, youtube.SelectionPreferences{})
+}
+
+// SelectStreamsWithPreferences picks the streams to download like SelectStreams,
+// choosing between videos of the same height by prefs.
+func SelectStreamsWithPreferences(manifest *youtube.StreamManifest, quality youtube.VideoQualityPreference, container youtube.Container, audioOnly bool, prefs youtube.SelectionPreferences) (*Selection, error) {
+	if audioOnly {
+		opus := container == youtube.ContainerOpus || container == youtube.ContainerOGG
+		bestAudio := manifest.GetBestAudioStream()
+		if opus {
+			// The Opus stream is copied into the Ogg file as is, so no other codec will do
+			bestAudio = manifest.GetBestOpusAudioStream()
+		}
+		if bestAudio == nil {
+			if err := inaccessibleAudio(manifest, opus); err != nil {
+				return nil, err
+			}
+			if opus {
+				return nil, errors.New("no Opus audio stream available")
+			}
+			return nil, errors.New("no audio stream available")
+		}
+		if bestAudio.URL == "" {
+			return nil, errors.New("audio stream has no URL")
+		}
+		return &Selection{Audio: bestAudio}, nil
+	}
+
+	best := youtube.SelectBestOptionWithPreferences(manifest.GetDownloadOptions(), quality, container, prefs)
+	inaccessible := manifest.InaccessibleBetterOption(best, quality, container, prefs)
+	selectedOption := manifest.AdaptForContainer(best, container)
+
+	if selectedOption == nil {
+		// Try to use muxed stream if no adaptive option is available
+		if len(manifest.MuxedStreams) > 0 {
+			return muxedFallback(manifest, inaccessible)
+		}
+		if inaccessible != nil {
+			return nil, inaccessible
+		}
+		return nil, errors.New("no suitable stream found for the requested quality")
+	}
+
+	selection := &Selection{Quality: selectedOption.QualityLabel(), Container: selectedOption.Container, Inaccessible: inaccessible}
+
+	// Check if we need to mux separate streams
+	if selectedOption.VideoStream != nil && selectedOption.AudioStream != nil && selectedOption.VideoStream.URL != "" {
+		// Check if streams have separate URLs (need muxing)
+		if selectedOption.AudioStream.URL != "" && selectedOption.VideoStream.URL != selectedOption.AudioStream.URL {
+			selection.Video = selectedOption.VideoStream
+			selection.Audio = selectedOption.AudioStream
+			return selection, nil
+		}
+	}
+
+	// Download single stream (muxed or video-only)
+	if selectedOption.VideoStream != nil && selectedOption.VideoStream.URL != "" {
+		selection.Video = selectedOption.VideoStream
+		return selection, nil
+	}
+
+	// Fallback to first muxed stream
+	if len(manifest.MuxedStreams) > 0 && manifest.MuxedStreams[0].VideoStreamInfo.URL != "" {
+		return muxedFallback(manifest, inaccessible)
+	}
+
+	return nil, errors.New("no downloadable stream found")
+}
+
+// muxedFallback selects the first muxed stream. inaccessible is why a better
+// stream wasn't selected, if one wasn't.
+func muxedFallback(manifest *youtube.StreamManifest, inaccessible *youtube.InaccessibleFormatError) (*Selection, error) {
+	stream := &manifest.MuxedStreams[0]
+	if err := stream.VideoStreamInfo.AccessError(); err != nil {
+		if inaccessible != nil {
+			return nil, inaccessible
+		}
+		return nil, err
+	}
+	if stream.VideoStreamInfo.URL == "" {
+		return nil, errors.New("muxed stream has no URL")
+	}
+	return &Selection{Container: stream.VideoStreamInfo.Container, Video: &stream.VideoStreamInfo, Inaccessible: inaccessible}, nil
+}
+
+// inaccessibleAudio returns the *youtube.InaccessibleFormatError of the best audio
+// stream of the manifest that can't be downloaded, only counting Opus streams
+// when opus is set, or nil if there is none.
+func inaccessibleAudio(manifest *youtube.StreamManifest, opus bool) error {
+	var best *youtube.AudioStreamInfo
+	for i := range manifest.AudioStreams {
+		as := &manifest.AudioStreams[i]
+		if as.IsDownloadable() || (opus && !strings.EqualFold(as.AudioCodec, "opus")) {
+			continue
+		}
+		if best == nil || as.Bitrate > best.Bitrate {
+			best = as
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.AccessError()
+}
@@ -0,0 +1,143 @@
+package ytdl
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SakuraBurst/golang-youtube-downloader/pkg/youtube"
+)
+
+func testManifest() *youtube.StreamManifest {
+	return &youtube.StreamManifest{
+		VideoStreams: []youtube.VideoStreamInfo{
+			{StreamInfo: youtube.StreamInfo{URL: "v1080", Container: youtube.ContainerMP4}, Height: 1080},
+			{StreamInfo: youtube.StreamInfo{URL: "v720", Container: youtube.ContainerMP4}, Height: 720},
+		},
+		AudioStreams: []youtube.AudioStreamInfo{
+			{StreamInfo: youtube.StreamInfo{URL: "a128", Container: youtube.ContainerMP4, Bitrate: 128000}},
+			{StreamInfo: youtube.StreamInfo{URL: "a160", Container: youtube.ContainerWebM, Bitrate: 160000}, AudioCodec: "opus"},
+		},
+		MuxedStreams: []youtube.MuxedStreamInfo{
+			{VideoStreamInfo: youtube.VideoStreamInfo{StreamInfo: youtube.StreamInfo{URL: "muxed", Container: youtube.ContainerMP4}, Height: 360}},
+		},
+	}
+}
+
+func TestSelectStreams(t *testing.T) {
+	manifest := testManifest()
+
+	tests := []struct {
+		name      string
+		quality   youtube.VideoQualityPreference
+		container youtube.Container
+		audioOnly bool
+		wantVideo string
+		wantAudio string
+	}{
+		{"best mp4", youtube.QualityHighest, youtube.ContainerMP4, false, "v1080", "a128"},
+		{"up to 720p", youtube.QualityUpTo720p, youtube.ContainerMP4, false, "v720", "a128"},
+		{"mkv uses the best audio", youtube.QualityHighest, youtube.ContainerMKV, false, "v1080", "a160"},
+		{"audio only", youtube.QualityHighest, youtube.ContainerMP4, true, "", "a160"},
+		{"opus", youtube.QualityHighest, youtube.ContainerOpus, true, "", "a160"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection, err := SelectStreams(manifest, tt.quality, tt.container, tt.audioOnly)
+			if err != nil {
+				t.Fatalf("SelectStreams() error = %v", err)
+			}
+			var video, audio string
+			if selection.Video != nil {
+				video = selection.Video.URL
+			}
+			if selection.Audio != nil {
+				audio = selection.Audio.URL
+			}
+			if video != tt.wantVideo || audio != tt.wantAudio {
+				t.Errorf("selected video %q audio %q, want %q and %q", video, audio, tt.wantVideo, tt.wantAudio)
+			}
+		})
+	}
+}
+
+func TestSelectStreams_MuxedFallback(t *testing.T) {
+	manifest := testManifest()
+	manifest.VideoStreams = nil
+
+	selection, err := SelectStreams(manifest, youtube.QualityHighest, youtube.ContainerMP4, false)
+	if err != nil {
+		t.Fatalf("SelectStreams() error = %v", err)
+	}
+	if selection.NeedsMux() || selection.Video.URL != "muxed" {
+		t.Errorf("selection = %+v, want the muxed stream", selection)
+	}
+}
+
+func TestSelectStreams_NoOpusStream(t *testing.T) {
+	manifest := testManifest()
+	manifest.AudioStreams = manifest.AudioStreams[:1]
+
+	if _, err := SelectStreams(manifest, youtube.QualityHighest, youtube.ContainerOGG, true); err == nil || err.Error() != "no Opus audio stream available" {
+		t.Errorf("SelectStreams() error = %v, want no Opus stream", err)
+	}
+}
+
+func TestSelectStreams_NoStreams(t *testing.T) {
+	if _, err := SelectStreams(&youtube.StreamManifest{}, youtube.QualityHighest, youtube.ContainerMP4, false); err == nil {
+		t.Error("expected an error without streams")
+	}
+	if _, err := SelectStreams(&youtube.StreamManifest{}, youtube.QualityHighest, youtube.ContainerMP4, true); err == nil {
+		t.Error("expected an error without audio streams")
+	}
+}
+
+func TestSelectStreams_Inaccessible(t *testing.T) {
+	manifest := testManifest()
+	manifest.VideoStreams[0].Premium, manifest.VideoStreams[0].URL, manifest.VideoStreams[0].Itag = true, "", 616
+
+	selection, err := SelectStreams(manifest, youtube.QualityHighest, youtube.ContainerMP4, false)
+	if err != nil {
+		t.Fatalf("SelectStreams() error = %v", err)
+	}
+	if selection.Video.URL != "v720" || selection.Inaccessible == nil || selection.Inaccessible.Itag != 616 {
+		t.Errorf("selection = %+v, want 720p with the Premium format reported", selection)
+	}
+
+	for i := range manifest.VideoStreams {
+		manifest.VideoStreams[i].DRMProtected = true
+	}
+	manifest.MuxedStreams[0].VideoStreamInfo.DRMProtected = true
+	var inaccessible *youtube.InaccessibleFormatError
+	if _, err := SelectStreams(manifest, youtube.QualityHighest, youtube.ContainerMP4, false); !errors.As(err, &inaccessible) {
+		t.Errorf("SelectStreams() error = %v, want an InaccessibleFormatError", err)
+	}
+
+	for i := range manifest.AudioStreams {
+		manifest.AudioStreams[i].DRMProtected = true
+	}
+	if _, err := SelectStreams(manifest, youtube.QualityHighest, youtube.ContainerOpus, true); !errors.As(err, &inaccessible) || inaccessible.Reason != "is DRM protected" {
+		t.Errorf("SelectStreams() audio error = %v, want an InaccessibleFormatError", err)
+	}
+}
+
+func TestSelectStreamsWithPreferences(t *testing.T) {
+	manifest := testManifest()
+	manifest.VideoStreams = append(manifest.VideoStreams,
+		youtube.VideoStreamInfo{StreamInfo: youtube.StreamInfo{URL: "v1080-60", Container: youtube.ContainerMP4}, Height: 1080, Framerate: 60})
+
+	selection, err := SelectStreamsWithPreferences(manifest, youtube.QualityHighest, youtube.ContainerMP4, false, youtube.SelectionPreferences{})
+	if err != nil {
+		t.Fatalf("SelectStreamsWithPreferences() error = %v", err)
+	}
+	if selection.Video.URL != "v1080-60" {
+		t.Errorf("selected video %q, want the 60fps stream", selection.Video.URL)
+	}
+
+	selection, err = SelectStreamsWithPreferences(manifest, youtube.QualityHighest, youtube.ContainerMP4, false, youtube.SelectionPreferences{MaxFramerate: 30})
+	if err != nil {
+		t.Fatalf("SelectStreamsWithPreferences() error = %v", err)
+	}
+	if selection.Video.URL != "v1080" {
+		t.Errorf("selected video %q, want the stream within --max-fps", selection.Video.URL)
+	}
+}
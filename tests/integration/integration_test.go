@@ -118,7 +118,7 @@ func NewTestClient(t *testing.T) *TestClient {
 func (tc *TestClient) FetchVideo(ctx context.Context, t *testing.T, videoID string) *youtube.Video {
 	t.Helper()
 
-	page, err := tc.WatchPageFetcher.Fetch(ctx, videoID)
+	page, err := tc.WatchPageFetcher.Fetch(ctx, youtube.VideoID(videoID))
 	RequireNoError(t, err, "Failed to fetch watch page")
 
 	pr, err := page.ExtractPlayerResponse()
@@ -134,7 +134,7 @@ func (tc *TestClient) FetchVideo(ctx context.Context, t *testing.T, videoID stri
 func (tc *TestClient) FetchVideoWithStreams(ctx context.Context, t *testing.T, videoID string) (*youtube.Video, *youtube.StreamManifest) {
 	t.Helper()
 
-	page, err := tc.WatchPageFetcher.Fetch(ctx, videoID)
+	page, err := tc.WatchPageFetcher.Fetch(ctx, youtube.VideoID(videoID))
 	RequireNoError(t, err, "Failed to fetch watch page")
 
 	pr, err := page.ExtractPlayerResponse()
@@ -482,7 +482,7 @@ func TestIntegration_FetchVideoFromURL(t *testing.T) {
 				t.Errorf("Expected query type %v, got %v", youtube.QueryTypeVideo, result.Type)
 			}
 
-			if result.VideoID != fixtures.VideoID {
+			if result.VideoID.String() != fixtures.VideoID {
 				t.Errorf("Expected video ID %q, got %q", fixtures.VideoID, result.VideoID)
 			}
 		})
@@ -496,12 +496,12 @@ func TestIntegration_FetchVideoFromURL(t *testing.T) {
 		result, err := youtube.ResolveQuery(watchURL)
 		RequireNoError(t, err, "Failed to resolve query")
 
-		if result.VideoID != fixtures.VideoID {
+		if result.VideoID.String() != fixtures.VideoID {
 			t.Fatalf("Expected video ID %q, got %q", fixtures.VideoID, result.VideoID)
 		}
 
 		// Step 2: Fetch video info
-		video := client.FetchVideo(ctx, t, result.VideoID)
+		video := client.FetchVideo(ctx, t, result.VideoID.String())
 
 		// Step 3: Verify video details
 		AssertVideoValid(t, video)
@@ -839,11 +839,11 @@ func TestIntegration_ParsePlaylistURL(t *testing.T) {
 
 			switch result.Type {
 			case youtube.QueryTypePlaylist:
-				if result.PlaylistID != tc.wantID {
+				if result.PlaylistID.String() != tc.wantID {
 					t.Errorf("PlaylistID = %q, want %q", result.PlaylistID, tc.wantID)
 				}
 			case youtube.QueryTypeVideo:
-				if result.VideoID != tc.wantID {
+				if result.VideoID.String() != tc.wantID {
 					t.Errorf("VideoID = %q, want %q", result.VideoID, tc.wantID)
 				}
 			}
@@ -870,7 +870,7 @@ func TestIntegration_ValidatePlaylistID(t *testing.T) {
 	if result.Type != youtube.QueryTypePlaylist {
 		t.Errorf("Expected QueryTypePlaylist, got %v", result.Type)
 	}
-	if result.PlaylistID != fixtures.PlaylistID {
+	if result.PlaylistID.String() != fixtures.PlaylistID {
 		t.Errorf("PlaylistID = %q, want %q", result.PlaylistID, fixtures.PlaylistID)
 	}
 